@@ -951,7 +951,9 @@ func shouldSkipAndroidMkProcessing(module *ModuleBase) bool {
 		module.Os() == LinuxBionic ||
 		// Make does not understand LinuxMusl, except when we are building with USE_HOST_MUSL=true
 		// and all host binaries are LinuxMusl
-		(module.Os() == LinuxMusl && module.Target().HostCross)
+		(module.Os() == LinuxMusl && module.Target().HostCross) ||
+		// Make does not understand Linux as an additional host test runtime of a musl host
+		(module.Os() == Linux && module.Target().HostCross)
 }
 
 // A utility func to format LOCAL_TEST_DATA outputs. See the comments on DataPath to understand how
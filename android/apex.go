@@ -144,6 +144,20 @@ type ApexTestForInfo struct {
 
 var ApexTestForInfoProvider = blueprint.NewMutatorProvider(ApexTestForInfo{}, "apex_test_for")
 
+// ApexHashtreeInfo describes whether the payload of an APEX includes its dm-verity hashtree, so
+// that the filesystem modules that include the APEX can build its verity metadata when it does
+// not.
+type ApexHashtreeInfo struct {
+	// Whether the apex_payload.img of the APEX includes its dm-verity hashtree.
+	GenerateHashtree bool
+
+	// The dm-verity metadata of the payload, built on demand, or nil if the payload includes its
+	// hashtree.
+	VerityMetadata Path
+}
+
+var ApexHashtreeInfoProvider = blueprint.NewProvider(ApexHashtreeInfo{})
+
 // DepIsInSameApex defines an interface that should be used to determine whether a given dependency
 // should be considered as part of the same APEX as the current module or not. Note: this was
 // extracted from ApexModule to make it easier to define custom subsets of the ApexModule interface
@@ -494,7 +508,7 @@ func (m *ApexModuleBase) checkApexAvailableProperty(mctx BaseModuleContext) {
 		if n == AvailableToPlatform || n == AvailableToAnyApex || n == AvailableToGkiApex {
 			continue
 		}
-		if !mctx.OtherModuleExists(n) && !mctx.Config().AllowMissingDependencies() {
+		if !mctx.OtherModuleExists(n) && !mctx.AllowMissingDependencies() {
 			mctx.PropertyErrorf("apex_available", "%q is not a valid module name", n)
 		}
 	}
@@ -436,9 +436,14 @@ func osMutator(bpctx blueprint.BottomUpMutatorContext) {
 
 	// Collect a list of OSTypes supported by this module based on the HostOrDevice value
 	// passed to InitAndroidArchModule and the device_supported and host_supported properties.
+	// The targets of the additional host test runtimes are only supported by the modules that
+	// explicitly support them.
 	var moduleOSList []OsType
 	for _, os := range osTypeList {
 		for _, t := range mctx.Config().Targets[os] {
+			if mctx.Config().HostTestRuntime(t) != "" && !base.IsHostTestRuntimesSupported() {
+				continue
+			}
 			if base.supportsTarget(t) {
 				moduleOSList = append(moduleOSList, os)
 				break
@@ -1509,6 +1514,7 @@ func decodeTargetProductVariables(config *config) (map[OsType][]Target, error) {
 		nativeBridgeEnabled      NativeBridgeSupport
 		nativeBridgeHostArchName *string
 		nativeBridgeRelativePath *string
+		hostTestRuntime          bool
 	}
 
 	addTarget := func(target targetConfig) {
@@ -1554,7 +1560,9 @@ func decodeTargetProductVariables(config *config) (map[OsType][]Target, error) {
 			} else {
 				archSupported = false
 			}
-			if !osSupported || !archSupported {
+			// The additional host test runtimes are always HostCross so that they are not
+			// mistaken for the primary host runtime, e.g. by Make.
+			if !osSupported || !archSupported || target.hostTestRuntime {
 				hostCross = true
 			}
 		}
@@ -1602,6 +1610,20 @@ func decodeTargetProductVariables(config *config) (map[OsType][]Target, error) {
 		}
 	}
 
+	// Optional host targets for the additional runtimes that host tests are built against.
+	for _, runtime := range variables.HostTestRuntimes {
+		os, ok := hostTestRuntimeOsTypes[runtime]
+		if !ok {
+			return nil, fmt.Errorf("Unknown host test runtime %q, expected one of %q",
+				runtime, SortedKeys(hostTestRuntimeOsTypes))
+		}
+		if os == config.BuildOS || len(targets[os]) > 0 {
+			// The runtime is already built for the host.
+			continue
+		}
+		addTarget(targetConfig{os: os, archName: *variables.HostArch, nativeBridgeEnabled: NativeBridgeDisabled, hostTestRuntime: true})
+	}
+
 	// Optional device targets
 	if variables.DeviceArch != nil && *variables.DeviceArch != "" {
 		// The primary device target.
@@ -353,6 +353,34 @@ func loadFromConfigFile(configurable *productVariables, filename string) error {
 		return fmt.Errorf("GcovCoverage and ClangCoverage cannot both be set")
 	}
 
+	if style := configurable.BuildIdStyle; style != nil && !InList(*style, buildIdStyles) {
+		return fmt.Errorf("BuildIdStyle must be one of %q, got %q", buildIdStyles, *style)
+	}
+	for _, partition := range SortedKeys(configurable.PartitionBuildIdStyles) {
+		if style := configurable.PartitionBuildIdStyles[partition]; !InList(style, buildIdStyles) {
+			return fmt.Errorf("PartitionBuildIdStyles of %q must be one of %q, got %q",
+				partition, buildIdStyles, style)
+		}
+	}
+
+	for _, key := range SortedKeys(configurable.BootImageCompilerFilters) {
+		if filter := configurable.BootImageCompilerFilters[key]; !InList(filter, dex2oatCompilerFilters) {
+			return fmt.Errorf("BootImageCompilerFilters of %q must be one of %q, got %q",
+				key, dex2oatCompilerFilters, filter)
+		}
+	}
+
+	for _, alias := range SortedKeys(configurable.AppCertificateAliases) {
+		if configurable.AppCertificateAliases[alias] == "" {
+			return fmt.Errorf("AppCertificateAliases: certificate alias %q has no certificate", alias)
+		}
+	}
+	for _, alias := range SortedKeys(configurable.AppCertificateAliasLineages) {
+		if _, ok := configurable.AppCertificateAliases[alias]; !ok {
+			return fmt.Errorf("AppCertificateAliasLineages: certificate alias %q is not in AppCertificateAliases", alias)
+		}
+	}
+
 	configurable.Native_coverage = proptools.BoolPtr(
 		Bool(configurable.GcovCoverage) ||
 			Bool(configurable.ClangCoverage))
@@ -809,6 +837,18 @@ func (c *config) KatiEnabled() bool {
 	return c.katiEnabled
 }
 
+// The styles of build ids supported by the linker.
+var buildIdStyles = []string{"md5", "sha1", "uuid"}
+
+// DeviceBuildIdStyle returns the style of the build ids of the device ELF files installed in the
+// partition, e.g. "md5" or "sha1". An empty partition returns the default of the product.
+func (c *config) DeviceBuildIdStyle(partition string) string {
+	if style, ok := c.productVariables.PartitionBuildIdStyles[partition]; ok {
+		return style
+	}
+	return StringDefault(c.productVariables.BuildIdStyle, "md5")
+}
+
 func (c *config) BuildId() string {
 	return String(c.productVariables.BuildId)
 }
@@ -1046,6 +1086,37 @@ func (c *config) AllowMissingDependencies() bool {
 	return Bool(c.productVariables.Allow_missing_dependencies)
 }
 
+// AllowMissingDependenciesDirs returns the directories whose modules are allowed to have missing
+// dependencies even if AllowMissingDependencies is false.
+func (c *config) AllowMissingDependenciesDirs() []string {
+	return c.productVariables.Allow_missing_dependencies_dirs
+}
+
+// AllowMissingDependenciesForDir returns true if the modules in dir are allowed to have missing
+// dependencies, i.e. if AllowMissingDependencies is true or if dir is one of the
+// AllowMissingDependenciesDirs or is below one of them.
+func (c *config) AllowMissingDependenciesForDir(dir string) bool {
+	if c.AllowMissingDependencies() {
+		return true
+	}
+	for _, allowedDir := range c.AllowMissingDependenciesDirs() {
+		allowedDir = strings.TrimSuffix(allowedDir, "/")
+		if dir == allowedDir || strings.HasPrefix(dir, allowedDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// DeferMissingModuleErrors returns true if the errors about missing modules that are not reported
+// by a module, e.g. by a singleton, must be deferred to the build of the outputs that need the
+// missing modules, i.e. if AllowMissingDependencies is true or if there are
+// AllowMissingDependenciesDirs. The modules outside of the AllowMissingDependenciesDirs that use
+// these outputs still fail to build.
+func (c *config) DeferMissingModuleErrors() bool {
+	return c.AllowMissingDependencies() || len(c.AllowMissingDependenciesDirs()) > 0
+}
+
 // Returns true if a full platform source tree cannot be assumed.
 func (c *config) UnbundledBuild() bool {
 	return Bool(c.productVariables.Unbundled_build)
@@ -1057,6 +1128,32 @@ func (c *config) UnbundledBuildApps() bool {
 	return len(c.productVariables.Unbundled_build_apps) > 0
 }
 
+// FrozenInterfaces returns true if the versioned interfaces, e.g. the APIs of sysprop_library and
+// java_sdk_library modules, are frozen on this branch. Their current versions must then be
+// identical to their latest frozen versions, the build fails on any change and update-api does
+// not regenerate them. The clients of the interfaces use their latest frozen versions, e.g. the
+// prebuilt stubs of the java_sdk_library modules, and cannot depend on the unfrozen versions of
+// the interface modules registered with RegisterInterfaceBackends.
+func (c *config) FrozenInterfaces() bool {
+	return Bool(c.productVariables.Frozen_interfaces)
+}
+
+// LintSeverityPolicies returns the severity overrides of the Android Lint checks for the modules
+// in a tree, as "<dir>:<severity>:<check>[,<check>...]" entries.
+func (c *config) LintSeverityPolicies() []string {
+	return c.productVariables.Lint_severity_policies
+}
+
+// ProductVariableValue returns the field of the product variable with the given name, e.g. to
+// forward its value to a tool, or false if there is no such product variable.
+func (c *config) ProductVariableValue(name string) (reflect.Value, bool) {
+	field, ok := reflect.TypeOf(c.productVariables).FieldByName(name)
+	if !ok || !field.IsExported() || len(field.Index) != 1 {
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(c.productVariables).FieldByIndex(field.Index), true
+}
+
 // Returns true if building image that aren't bundled with the platform.
 // UnbundledBuild() is always true when this is true.
 func (c *config) UnbundledBuildImage() bool {
@@ -1214,6 +1311,63 @@ func (c *config) ArtUseReadBarrier() bool {
 	return Bool(c.productVariables.ArtUseReadBarrier)
 }
 
+// The compiler filters supported by dex2oat.
+var dex2oatCompilerFilters = []string{"assume-verified", "extract", "verify", "space-profile", "space",
+	"speed-profile", "speed", "everything-profile", "everything"}
+
+// BootImageCompilerFilter returns the dex2oat compiler filter of the device variant of the boot
+// image for the architecture, and whether the product overrides the filter of the boot image.
+func (c *config) BootImageCompilerFilter(image string, arch ArchType) (string, bool) {
+	if filter, ok := c.productVariables.BootImageCompilerFilters[image+":"+arch.String()]; ok {
+		return filter, true
+	}
+	filter, ok := c.productVariables.BootImageCompilerFilters[arch.String()]
+	return filter, ok
+}
+
+// BootImageDex2oatXms returns the initial heap size of dex2oat when it compiles the boot image, or
+// an empty string if the product does not override it for the boot image.
+func (c *config) BootImageDex2oatXms(image string) string {
+	return c.productVariables.BootImageDex2oatXms[image]
+}
+
+// BootImageDex2oatXmx returns the maximum heap size of dex2oat when it compiles the boot image, or
+// an empty string if the product does not override it for the boot image.
+func (c *config) BootImageDex2oatXmx(image string) string {
+	return c.productVariables.BootImageDex2oatXmx[image]
+}
+
+// BootImageDex2oatHeapSizeImages returns the names of the boot images for which the product
+// overrides the heap sizes of dex2oat.
+func (c *config) BootImageDex2oatHeapSizeImages() []string {
+	return SortedUniqueStrings(append(SortedKeys(c.productVariables.BootImageDex2oatXms),
+		SortedKeys(c.productVariables.BootImageDex2oatXmx)...))
+}
+
+// BootImageDex2oatConfig returns the name of the dex2oat_config module whose flags are passed to
+// dex2oat when it compiles the boot image, or an empty string if the product does not set one.
+func (c *config) BootImageDex2oatConfig(image string) string {
+	return c.productVariables.BootImageDex2oatConfigs[image]
+}
+
+// BootImageDex2oatConfigImages returns the names of the boot images for which the product sets a
+// dex2oat_config module.
+func (c *config) BootImageDex2oatConfigImages() []string {
+	return SortedKeys(c.productVariables.BootImageDex2oatConfigs)
+}
+
+// BootImageProfileModules returns the names of the boot_image_profile modules whose profiles are
+// merged into the framework boot image profile.
+func (c *config) BootImageProfileModules() []string {
+	return c.productVariables.BootImageProfileModules
+}
+
+// ProductPackages returns the modules in the PRODUCT_PACKAGES of the product, or nil if Make did
+// not set them, e.g. in tests.
+func (c *config) ProductPackages() []string {
+	return c.productVariables.ProductPackages
+}
+
 // Enforce Runtime Resource Overlays for a module. RROs supersede static RROs,
 // but some modules still depend on it.
 //
@@ -1486,6 +1640,30 @@ func (c *deviceConfig) AfdoProfile(name string) (*string, error) {
 	return nil, nil
 }
 
+// AfdoProfileDir returns the directory of the sampled AFDO profiles of the product, or an empty
+// string if it does not have one.
+func (c *config) AfdoProfileDir() string {
+	return String(c.productVariables.AfdoProfileDir)
+}
+
+// WarningOverridesFiles returns the files with the warning overrides of the projects of the
+// product, see cc/config/warning_overrides.go.
+func (c *config) WarningOverridesFiles() []string {
+	return c.productVariables.WarningOverridesFiles
+}
+
+// ThinLTOCache returns true if the product enables the ThinLTO cache, see
+// cc/config/thinlto_cache.go.
+func (c *config) ThinLTOCache() bool {
+	return Bool(c.productVariables.ThinLTOCache)
+}
+
+// ClangConfigFile returns the clang configuration file of the product, see
+// cc/config/clang_config_file.go.
+func (c *config) ClangConfigFile() string {
+	return String(c.productVariables.ClangConfigFile)
+}
+
 func (c *deviceConfig) VendorSepolicyDirs() []string {
 	return c.config.productVariables.BoardVendorSepolicyDirs
 }
@@ -1516,6 +1694,16 @@ func (c *deviceConfig) OverrideCertificateFor(name string) (certificatePath stri
 		"invalid override rule %q in PRODUCT_CERTIFICATE_OVERRIDES should be <module_name>:<certificate_module_name>")
 }
 
+// AppCertificateAlias returns the certificate and the signing certificate lineage file, if any, of
+// a certificate alias of the product, and whether the alias exists.
+func (c *deviceConfig) AppCertificateAlias(alias string) (certificate string, lineage string, ok bool) {
+	certificate, ok = c.config.productVariables.AppCertificateAliases[alias]
+	if !ok {
+		return "", "", false
+	}
+	return certificate, c.config.productVariables.AppCertificateAliasLineages[alias], true
+}
+
 func (c *deviceConfig) OverridePackageNameFor(name string) string {
 	newName, overridden := findOverrideValue(
 		c.config.productVariables.PackageNameOverrides,
@@ -1548,6 +1736,12 @@ func (c *deviceConfig) ApexGlobalMinSdkVersionOverride() string {
 	return String(c.config.productVariables.ApexGlobalMinSdkVersionOverride)
 }
 
+// EnforceUpdatableConsistency returns true if updatable modules must pass the checks in
+// CheckUpdatableConsistency.
+func (c *deviceConfig) EnforceUpdatableConsistency() bool {
+	return Bool(c.config.productVariables.EnforceUpdatableConsistency)
+}
+
 func (c *config) IntegerOverflowDisabledForPath(path string) bool {
 	if len(c.productVariables.IntegerOverflowExcludePaths) == 0 {
 		return false
@@ -1611,6 +1805,52 @@ func (c *config) MemtagHeapSyncEnabledForPath(path string) bool {
 	return HasAnyPrefix(path, c.productVariables.MemtagHeapSyncIncludePaths) && !c.MemtagHeapDisabledForPath(path)
 }
 
+// MemtagHeapDefaultMode returns the memory tagging mode, "sync", "async" or "off", of the modules
+// that neither set sanitize.memtag_heap_mode nor are covered by the memtag heap path lists.
+func (c *config) MemtagHeapDefaultMode() string {
+	return String(c.productVariables.MemtagHeapDefaultMode)
+}
+
+// ThreadSafetyAnalysisEnforcedForPath returns true if clang's thread-safety analysis is enabled as
+// an error for the modules in the path.
+func (c *config) ThreadSafetyAnalysisEnforcedForPath(path string) bool {
+	if len(c.productVariables.ThreadSafetyAnalysisIncludePaths) == 0 {
+		return false
+	}
+	return HasAnyPrefix(path, c.productVariables.ThreadSafetyAnalysisIncludePaths) &&
+		!HasAnyPrefix(path, c.productVariables.ThreadSafetyAnalysisExcludePaths)
+}
+
+// ThreadSafetyReportEnabledForPath returns true if the modules in the path are included in the
+// thread-safety annotation coverage report, which always includes the modules that enforce the
+// analysis.
+func (c *config) ThreadSafetyReportEnabledForPath(path string) bool {
+	return HasAnyPrefix(path, c.productVariables.ThreadSafetyReportPaths) ||
+		c.ThreadSafetyAnalysisEnforcedForPath(path)
+}
+
+// BranchProtectionPolicies returns the branch protection policies of the product, as
+// <partition>:<mode>[:<min_sdk_version>].
+func (c *config) BranchProtectionPolicies() []string {
+	return c.productVariables.BranchProtectionPolicies
+}
+
+// UbsanMinimalRuntimePolicies returns the UBSan minimal runtime policies of the product, as
+// <partition>:<check>[,<check>...].
+func (c *config) UbsanMinimalRuntimePolicies() []string {
+	return c.productVariables.UbsanMinimalRuntimePolicies
+}
+
+// CcFlagExperiment returns the toolchain flag experiment of the product, or nil if there is none.
+func (c *config) CcFlagExperiment() *CcFlagExperiment {
+	return c.productVariables.CcFlagExperiment
+}
+
+// SoongPackagedTestSuites returns the test suites that are packaged by Soong instead of Make.
+func (c *config) SoongPackagedTestSuites() []string {
+	return c.productVariables.SoongPackagedTestSuites
+}
+
 func (c *config) HWASanEnabledForPath(path string) bool {
 	if len(c.productVariables.HWASanIncludePaths) == 0 {
 		return false
@@ -1622,6 +1862,36 @@ func (c *config) VendorConfig(name string) VendorConfig {
 	return soongconfig.Config(c.productVariables.VendorVars[name])
 }
 
+// ProductVariable returns the value of the product variable with the given name in soong.variables,
+// e.g. "Platform_sdk_version" or "DeviceName", or nil if it is not set. It returns false if there is
+// no such product variable.
+func (c *config) ProductVariable(name string) (interface{}, bool) {
+	t := reflect.TypeOf(c.productVariables)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || productVariableJsonName(field) != name {
+			continue
+		}
+		v := reflect.ValueOf(c.productVariables).Field(i)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, true
+			}
+			v = v.Elem()
+		}
+		return v.Interface(), true
+	}
+	return nil, false
+}
+
+// productVariableJsonName returns the name of the product variable in soong.variables.
+func productVariableJsonName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
 func (c *config) NdkAbis() bool {
 	return Bool(c.productVariables.Ndk_abis)
 }
@@ -1983,10 +2253,33 @@ func (c *config) ApexBootJars() ConfiguredJarList {
 	return c.productVariables.ApexBootJars
 }
 
+// BootImageExtensions returns the names of the boot_image_extension modules whose boot images are
+// built for the product.
+func (c *config) BootImageExtensions() []string {
+	return c.productVariables.BootImageExtensions
+}
+
 func (c *config) RBEWrapper() string {
 	return c.GetenvWithDefault("RBE_WRAPPER", remoteexec.DefaultWrapperPath)
 }
 
+// UseRBEGenruleNsjail returns true if the genrules that run their command inside nsjail are run
+// with RBE.
+func (c *config) UseRBEGenruleNsjail() bool {
+	return c.UseRBE() && c.IsEnvTrue("RBE_GENRULE_NSJAIL")
+}
+
+// RBEGenruleNsjailExecStrategy returns the RBE exec strategy of the genrules that run their
+// command inside nsjail.
+func (c *config) RBEGenruleNsjailExecStrategy() string {
+	return c.GetenvWithDefault("RBE_GENRULE_NSJAIL_EXEC_STRATEGY", remoteexec.RemoteLocalFallbackExecStrategy)
+}
+
+// RBEGenruleNsjailPool returns the RBE pool of the genrules that run their command inside nsjail.
+func (c *config) RBEGenruleNsjailPool() string {
+	return c.GetenvWithDefault("RBE_GENRULE_NSJAIL_POOL", remoteexec.DefaultPool)
+}
+
 // UseHostMusl returns true if the host target has been configured to build against musl libc.
 func (c *config) UseHostMusl() bool {
 	return Bool(c.productVariables.HostMusl)
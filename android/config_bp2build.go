@@ -46,6 +46,9 @@ type ExportedVariables struct {
 	/// Maps containing variables that are dependent on the build config.
 	exportedConfigDependingVars ExportedConfigDependingVariables
 
+	// The names of the exported variables that are also declared in Soong.
+	soongVars map[string]bool
+
 	pctx PackageContext
 }
 
@@ -57,6 +60,7 @@ func NewExportedVariables(pctx PackageContext) ExportedVariables {
 		exportedStringListDictVars:        ExportedStringListDictVariables{},
 		exportedVariableReferenceDictVars: ExportedVariableReferenceDictVariables{},
 		exportedConfigDependingVars:       ExportedConfigDependingVariables{},
+		soongVars:                         map[string]bool{},
 		pctx:                              pctx,
 	}
 }
@@ -73,9 +77,16 @@ func (ev ExportedVariables) asBazel(config Config,
 	return ret
 }
 
+// SoongNames returns the sorted names of the exported variables that are also declared in Soong,
+// without the ones that are only exported to Bazel.
+func (ev ExportedVariables) SoongNames() []string {
+	return SortedStringKeys(ev.soongVars)
+}
+
 // ExportStringStaticVariable declares a static string variable and exports it to
 // Bazel's toolchain.
 func (ev ExportedVariables) ExportStringStaticVariable(name string, value string) {
+	ev.soongVars[name] = true
 	ev.pctx.StaticVariable(name, value)
 	ev.exportedStringVars.set(name, value)
 }
@@ -83,6 +94,7 @@ func (ev ExportedVariables) ExportStringStaticVariable(name string, value string
 // ExportStringListStaticVariable declares a static variable and exports it to
 // Bazel's toolchain.
 func (ev ExportedVariables) ExportStringListStaticVariable(name string, value []string) {
+	ev.soongVars[name] = true
 	ev.pctx.StaticVariable(name, strings.Join(value, " "))
 	ev.exportedStringListVars.set(name, value)
 }
@@ -91,6 +103,7 @@ func (ev ExportedVariables) ExportStringListStaticVariable(name string, value []
 // runtime via a function with access to the Config and exports it to Bazel's
 // toolchain.
 func (ev ExportedVariables) ExportVariableConfigMethod(name string, method interface{}) blueprint.Variable {
+	ev.soongVars[name] = true
 	ev.exportedConfigDependingVars.set(name, method)
 	return ev.pctx.VariableConfigMethod(name, method)
 }
@@ -107,6 +120,7 @@ func (ev ExportedVariables) ExportStringStaticVariableWithEnvOverride(name, envV
 // ExportSourcePathVariable declares a static "source path" variable and exports
 // it to Bazel's toolchain.
 func (ev ExportedVariables) ExportSourcePathVariable(name string, value string) {
+	ev.soongVars[name] = true
 	ev.pctx.SourcePathVariable(name, value)
 	ev.exportedStringVars.set(name, value)
 }
@@ -114,6 +128,7 @@ func (ev ExportedVariables) ExportSourcePathVariable(name string, value string)
 // ExportVariableFuncVariable declares a variable whose value is evaluated at
 // runtime via a function and exports it to Bazel's toolchain.
 func (ev ExportedVariables) ExportVariableFuncVariable(name string, f func() string) {
+	ev.soongVars[name] = true
 	ev.exportedConfigDependingVars.set(name, func(config Config) string {
 		return f()
 	})
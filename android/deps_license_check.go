@@ -0,0 +1,89 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"github.com/google/blueprint"
+)
+
+var (
+	_ = pctx.HostBinToolVariable("depsLicenseCheckCmd", "deps_license_check")
+
+	depsLicenseCheckRule = pctx.AndroidStaticRule("depsLicenseCheckRule", blueprint.RuleParams{
+		Command:        "${depsLicenseCheckCmd} -o $out -d $out.d @$out.rsp",
+		CommandDeps:    []string{"${depsLicenseCheckCmd}"},
+		Rspfile:        "$out.rsp",
+		RspfileContent: "$in",
+		Depfile:        "$out.d",
+		Deps:           blueprint.DepsGCC,
+	})
+)
+
+func init() {
+	RegisterDepsLicenseCheckBuildComponents(InitRegistrationContext)
+}
+
+func RegisterDepsLicenseCheckBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("deps_license_check", depsLicenseCheckSingletonFactory)
+}
+
+var PrepareForTestWithDepsLicenseCheck = FixtureRegisterWithContext(RegisterDepsLicenseCheckBuildComponents)
+
+func depsLicenseCheckSingletonFactory() Singleton {
+	return &depsLicenseCheckSingleton{}
+}
+
+// depsLicenseCheckSingleton checks the license conditions of the dependency closure of the modules
+// that install files against the distribution that the modules declare, e.g. that a proprietary
+// vendor APEX does not contain GPL libraries, from their license metadata files. The modules are
+// checked by one rule per partition, with the host modules in a "host" partition of their own. The
+// report of a partition is a JSON list of the reports of its modules, each of which lists the
+// conflicting dependencies of the module with their dependency paths, and is built by
+// `m deps-license-check-<partition>`. `m deps-license-check` builds the reports of all the
+// partitions.
+type depsLicenseCheckSingleton struct{}
+
+func (depsLicenseCheckSingleton) GenerateBuildActions(ctx SingletonContext) {
+	metadataByPartition := make(map[string]Paths)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() || len(module.base().installFiles) == 0 {
+			return
+		}
+		if !ctx.ModuleHasProvider(module, LicenseMetadataProvider) {
+			return
+		}
+		info := ctx.ModuleProvider(module, LicenseMetadataProvider).(*LicenseMetadataInfo)
+
+		partition := "host"
+		if module.Target().Os.Class != Host {
+			partition = module.base().PartitionTag(ctx.DeviceConfig())
+		}
+		metadataByPartition[partition] = append(metadataByPartition[partition], info.LicenseMetadataPath)
+	})
+
+	var reports Paths
+	for _, partition := range SortedKeys(metadataByPartition) {
+		report := PathForOutput(ctx, "deps_license_check", partition+".json")
+		ctx.Build(pctx, BuildParams{
+			Rule:        depsLicenseCheckRule,
+			Inputs:      SortedUniquePaths(metadataByPartition[partition]),
+			Output:      report,
+			Description: "deps license check " + partition,
+		})
+		ctx.Phony("deps-license-check-"+partition, report)
+		reports = append(reports, report)
+	}
+	ctx.Phony("deps-license-check", reports...)
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint/proptools"
+)
+
+type depsLicenseCheckTestModule struct {
+	ModuleBase
+	props struct {
+		Installable *bool
+	}
+}
+
+func (m *depsLicenseCheckTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	if proptools.BoolDefault(m.props.Installable, true) {
+		ctx.InstallFile(PathForModuleInstall(ctx, "bin"), ctx.ModuleName(), outputFile)
+	}
+}
+
+func depsLicenseCheckTestModuleFactory() Module {
+	m := &depsLicenseCheckTestModule{}
+	m.AddProperties(&m.props)
+	InitAndroidModule(m)
+	return m
+}
+
+func TestDepsLicenseCheck(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithDepsLicenseCheck,
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("deps_license_check_test_module", depsLicenseCheckTestModuleFactory)
+		}),
+	).RunTestWithBp(t, `
+		deps_license_check_test_module {
+			name: "foo",
+		}
+
+		deps_license_check_test_module {
+			name: "bar",
+			installable: false,
+		}
+
+		deps_license_check_test_module {
+			name: "baz",
+		}
+
+		deps_license_check_test_module {
+			name: "qux",
+			vendor: true,
+		}
+	`)
+
+	singleton := result.SingletonForTests("deps_license_check")
+	check := singleton.Output("out/soong/deps_license_check/system.json")
+	AssertPathsRelativeToTopEquals(t, "license metadata",
+		[]string{"out/soong/.intermediates/baz/meta_lic", "out/soong/.intermediates/foo/meta_lic"},
+		check.Inputs)
+
+	// The modules of the other partitions are checked by their own rules, and only the modules that
+	// install files are checked.
+	vendor := singleton.Output("out/soong/deps_license_check/vendor.json")
+	AssertPathsRelativeToTopEquals(t, "vendor license metadata",
+		[]string{"out/soong/.intermediates/qux/meta_lic"}, vendor.Inputs)
+}
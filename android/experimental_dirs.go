@@ -0,0 +1,139 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The Experimental_dirs product variable lists the directories, e.g. the staging directories of
+// a SoC under bring-up, whose module errors are reported as warnings instead. A module in one of
+// these directories that reports an error, or that depends on a module that does not exist, is
+// disabled instead of failing the build. The modules outside of these directories still fail the
+// build if they depend on a disabled module. The warnings are printed to stderr, and written to
+// $OUT_DIR/soong/experimental_dirs_warnings.txt.
+
+// ExperimentalDirs returns the directories whose module errors are reported as warnings.
+func (c *config) ExperimentalDirs() []string {
+	return c.productVariables.Experimental_dirs
+}
+
+// IsInExperimentalDir returns true if dir is one of the experimental directories or is below one
+// of them.
+func (c *config) IsInExperimentalDir(dir string) bool {
+	for _, experimentalDir := range c.ExperimentalDirs() {
+		experimentalDir = strings.TrimSuffix(experimentalDir, "/")
+		if dir == experimentalDir || strings.HasPrefix(dir, experimentalDir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowMissingDependenciesInDir returns true if the modules in dir may depend on modules that do not
+// exist while AllowMissingDependencies is false, i.e. if dir is one of the experimental directories,
+// where the missing dependencies disable the module instead, or one of the
+// AllowMissingDependenciesDirs.
+func (c *config) allowMissingDependenciesInDir(dir string) bool {
+	return !c.AllowMissingDependencies() &&
+		(c.IsInExperimentalDir(dir) || c.AllowMissingDependenciesForDir(dir))
+}
+
+type experimentalDirWarnings struct {
+	sync.Mutex
+	warnings []string
+}
+
+var experimentalDirWarningsKey = NewOnceKey("experimentalDirWarnings")
+
+func getExperimentalDirWarnings(config Config) *experimentalDirWarnings {
+	return config.Once(experimentalDirWarningsKey, func() interface{} {
+		return &experimentalDirWarnings{}
+	}).(*experimentalDirWarnings)
+}
+
+func init() {
+	registerExperimentalDirsBuildComponents(InitRegistrationContext)
+}
+
+func registerExperimentalDirsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("experimental_dirs", experimentalDirsSingletonFactory)
+}
+
+func experimentalDirsSingletonFactory() Singleton {
+	return &experimentalDirsSingleton{}
+}
+
+// experimentalDirsSingleton writes the module errors that were reported as warnings to
+// $OUT_DIR/soong/experimental_dirs_warnings.txt.
+type experimentalDirsSingleton struct{}
+
+func (s *experimentalDirsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if len(ctx.Config().ExperimentalDirs()) == 0 {
+		return
+	}
+
+	w := getExperimentalDirWarnings(ctx.Config())
+	w.Lock()
+	warnings := SortedUniqueStrings(w.warnings)
+	w.Unlock()
+
+	output := PathForOutput(ctx, "experimental_dirs_warnings.txt")
+	WriteFileRule(ctx, output, strings.Join(warnings, "\n"))
+	ctx.Phony("experimental_dirs_warnings", output)
+}
+
+// reportExperimentalDirError reports the module error as a warning and disables the module if the
+// module is in one of the experimental directories. It returns false if the error must be reported
+// as usual.
+func (e *earlyModuleContext) reportExperimentalDirError(format string, args ...interface{}) bool {
+	if len(e.config.ExperimentalDirs()) == 0 || !e.config.IsInExperimentalDir(e.ModuleDir()) {
+		return false
+	}
+
+	warning := fmt.Sprintf("//%s:%s: %s (module disabled, it is in an experimental directory)",
+		e.ModuleDir(), e.ModuleName(), fmt.Sprintf(format, args...))
+	w := getExperimentalDirWarnings(e.config)
+	w.Lock()
+	if !InList(warning, w.warnings) {
+		w.warnings = append(w.warnings, warning)
+		fmt.Fprintf(os.Stderr, "warning: %s\n", warning)
+	}
+	w.Unlock()
+
+	if m, ok := e.EarlyModuleContext.Module().(Module); ok {
+		m.base().Disable()
+	}
+	return true
+}
+
+// ModuleErrorf reports an error, or a warning for the modules in the experimental directories.
+func (e *earlyModuleContext) ModuleErrorf(format string, args ...interface{}) {
+	if e.reportExperimentalDirError(format, args...) {
+		return
+	}
+	e.EarlyModuleContext.ModuleErrorf(format, args...)
+}
+
+// PropertyErrorf reports an error, or a warning for the modules in the experimental directories.
+func (e *earlyModuleContext) PropertyErrorf(property, format string, args ...interface{}) {
+	if e.reportExperimentalDirError("%s: "+format, append([]interface{}{property}, args...)...) {
+		return
+	}
+	e.EarlyModuleContext.PropertyErrorf(property, format, args...)
+}
@@ -0,0 +1,154 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+type experimentalDirsTestModule struct {
+	ModuleBase
+	properties struct {
+		Deps  []string
+		Error *string
+	}
+}
+
+func experimentalDirsTestModuleFactory() Module {
+	m := &experimentalDirsTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+var experimentalDirsTestDepTag = struct{ blueprint.BaseDependencyTag }{}
+
+func (m *experimentalDirsTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), experimentalDirsTestDepTag, m.properties.Deps...)
+}
+
+func (m *experimentalDirsTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	if m.properties.Error != nil {
+		ctx.PropertyErrorf("error", "%s", *m.properties.Error)
+	}
+}
+
+var prepareForExperimentalDirsTest = GroupFixturePreparers(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("test_module", experimentalDirsTestModuleFactory)
+		registerExperimentalDirsBuildComponents(ctx)
+	}),
+	FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+		variables.Experimental_dirs = []string{"vendor/staging/"}
+	}),
+	FixtureAddTextFile("vendor/staging/Android.bp", `
+		test_module {
+			name: "staging_missing_dep",
+			deps: ["missing"],
+		}
+
+		test_module {
+			name: "staging_error",
+			error: "not supported yet",
+		}
+
+		test_module {
+			name: "staging_disabled_dep",
+			deps: ["staging_error"],
+		}
+	`),
+)
+
+func TestExperimentalDirs(t *testing.T) {
+	result := prepareForExperimentalDirsTest.RunTest(t)
+
+	for _, name := range []string{"staging_missing_dep", "staging_error", "staging_disabled_dep"} {
+		AssertBoolEquals(t, name+" enabled", false, result.ModuleForTests(name, "").Module().Enabled())
+	}
+
+	warnings := ContentFromFileRuleForTests(t,
+		result.SingletonForTests("experimental_dirs").Output("experimental_dirs_warnings.txt"))
+	AssertStringEquals(t, "warnings", strings.Join([]string{
+		`//vendor/staging:staging_disabled_dep: depends on disabled module "staging_error" (module disabled, it is in an experimental directory)`,
+		`//vendor/staging:staging_error: error: not supported yet (module disabled, it is in an experimental directory)`,
+		`//vendor/staging:staging_missing_dep: depends on undefined module(s) ["missing"] (module disabled, it is in an experimental directory)`,
+	}, "\n"), warnings)
+}
+
+func TestExperimentalDirsErrorsOutside(t *testing.T) {
+	prepareForExperimentalDirsTest.
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "platform_experimental_dep": depends on disabled module "staging_error"`,
+			`module "platform_error": error: broken`,
+		})).
+		RunTestWithBp(t, `
+			test_module {
+				name: "platform_experimental_dep",
+				deps: ["staging_error"],
+			}
+
+			test_module {
+				name: "platform_error",
+				error: "broken",
+			}
+		`)
+}
+
+func TestAllowMissingDependenciesDirs(t *testing.T) {
+	GroupFixturePreparers(
+		prepareForExperimentalDirsTest,
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.Allow_missing_dependencies_dirs = []string{"vendor/unbundled/"}
+		}),
+		FixtureAddTextFile("vendor/unbundled/app/Android.bp", `
+			test_module {
+				name: "unbundled_missing_dep",
+				deps: ["missing"],
+			}
+		`),
+	).
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`"platform_missing_dep" depends on undefined module "missing"`,
+		})).
+		RunTestWithBp(t, `
+			test_module {
+				name: "platform_missing_dep",
+				deps: ["missing"],
+			}
+		`)
+}
+
+func TestAllowMissingDependenciesForDir(t *testing.T) {
+	config := TestConfig(t.TempDir(), nil, "", nil)
+	config.productVariables.Allow_missing_dependencies_dirs = []string{"vendor/unbundled/", "external/staging"}
+
+	for dir, want := range map[string]bool{
+		"vendor/unbundled":        true,
+		"vendor/unbundled/app":    true,
+		"vendor/unbundled_other":  false,
+		"external/staging/lib":    true,
+		"external":                false,
+		"frameworks/base/library": false,
+	} {
+		AssertBoolEquals(t, dir, want, config.AllowMissingDependenciesForDir(dir))
+	}
+
+	config.productVariables.Allow_missing_dependencies = proptools.BoolPtr(true)
+	AssertBoolEquals(t, "global", true, config.AllowMissingDependenciesForDir("frameworks/base"))
+}
@@ -15,9 +15,12 @@
 package android
 
 import (
+	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"android/soong/bazel"
 	"android/soong/bazel/cquery"
@@ -36,6 +39,8 @@ var PrepareForTestWithFilegroup = FixtureRegisterWithContext(func(ctx Registrati
 func RegisterFilegroupBuildComponents(ctx RegistrationContext) {
 	ctx.RegisterModuleType("filegroup", FileGroupFactory)
 	ctx.RegisterModuleType("filegroup_defaults", FileGroupDefaultsFactory)
+	ctx.PreArchMutators(RegisterFilegroupClaimsMapper)
+	ctx.RegisterSingletonType("filegroup_claims", filegroupClaimsSingletonFactory)
 }
 
 var convertedProtoLibrarySuffix = "_bp2build_converted"
@@ -193,6 +198,17 @@ type fileGroupProperties struct {
 	// filegroup, relative to the root of the source tree.
 	Export_to_make_var *string
 
+	// If true, the filegroup claims the files in srcs, including the generated files referenced
+	// with ":module{.tag}". A file may be claimed by a single filegroup, and the claimed files are
+	// excluded from the filegroups with exclude_claimed_srcs in the same or a parent directory.
+	Claim_srcs *bool
+
+	// If true, the files claimed by the filegroups in this directory and its subdirectories are
+	// excluded from srcs, so that e.g. a glob of a source tree does not include the files of more
+	// specific filegroups a second time. Filegroups that set exclude_claimed_srcs themselves are
+	// ignored.
+	Exclude_claimed_srcs *bool
+
 	// aidl is explicitly provided for implicit aidl dependencies
 	// TODO(b/278298615): aidl prop is a no-op in Soong and is an escape hatch
 	// to include implicit aidl dependencies for bazel migration compatibility
@@ -244,8 +260,25 @@ func (fg *fileGroup) JSONActions() []blueprint.JSONAction {
 	}
 }
 
+func (fg *fileGroup) DepsMutator(ctx BottomUpMutatorContext) {
+	if Bool(fg.properties.Exclude_claimed_srcs) {
+		ctx.AddDependency(ctx.Module(), claimedSrcsTag, filegroupClaimantsInDir(ctx, ctx.ModuleDir())...)
+	}
+}
+
 func (fg *fileGroup) GenerateAndroidBuildActions(ctx ModuleContext) {
 	fg.srcs = PathsForModuleSrcExcludes(ctx, fg.properties.Srcs, fg.properties.Exclude_srcs)
+	if Bool(fg.properties.Exclude_claimed_srcs) {
+		// Compare the paths as strings, the relative paths of the same file differ between a
+		// glob in a parent directory and the srcs of the claimant.
+		claimed := make(map[string]bool)
+		ctx.VisitDirectDepsWithTag(claimedSrcsTag, func(m Module) {
+			for _, src := range m.(*fileGroup).srcs {
+				claimed[src.String()] = true
+			}
+		})
+		fg.srcs, _ = FilterPathListPredicate(fg.srcs, func(p Path) bool { return claimed[p.String()] })
+	}
 	if fg.properties.Path != nil {
 		fg.srcs = PathsWithModuleSrcSubDir(ctx, fg.srcs, String(fg.properties.Path))
 	}
@@ -369,3 +402,102 @@ func FileGroupDefaultsFactory() Module {
 
 	return module
 }
+
+type claimedSrcsDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+// The claimants are not necessarily visible to the filegroups that exclude their files.
+func (claimedSrcsDependencyTag) ExcludeFromVisibilityEnforcement() {}
+
+var claimedSrcsTag = claimedSrcsDependencyTag{}
+
+// filegroupClaimants records the directories of the filegroups with claim_srcs, as the
+// filegroups with exclude_claimed_srcs depend on them without naming them. The filegroups are
+// recorded by their fully qualified names, e.g. "//vendor/acme:foo", as the filegroups that depend
+// on them may be in other namespaces.
+type filegroupClaimants struct {
+	sync.Mutex
+	byDir map[string][]string
+}
+
+var filegroupClaimantsKey = NewOnceKey("filegroupClaimants")
+
+func getFilegroupClaimants(config Config) *filegroupClaimants {
+	return config.Once(filegroupClaimantsKey, func() interface{} {
+		return &filegroupClaimants{byDir: make(map[string][]string)}
+	}).(*filegroupClaimants)
+}
+
+// Registers the mutator that records the filegroups with claim_srcs.
+//
+// This goes after defaults expansion so that claim_srcs can be set in filegroup_defaults.
+func RegisterFilegroupClaimsMapper(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("filegroupClaimsMapper", filegroupClaimsMapper).Parallel()
+}
+
+func filegroupClaimsMapper(ctx BottomUpMutatorContext) {
+	fg, ok := ctx.Module().(*fileGroup)
+	if !ok || !Bool(fg.properties.Claim_srcs) || Bool(fg.properties.Exclude_claimed_srcs) {
+		return
+	}
+	claimants := getFilegroupClaimants(ctx.Config())
+	claimants.Lock()
+	defer claimants.Unlock()
+	claimants.byDir[ctx.ModuleDir()] = append(claimants.byDir[ctx.ModuleDir()], fullyQualifiedModuleName(ctx))
+}
+
+// fullyQualifiedModuleName returns the name of the module qualified by its namespace, which
+// resolves from any namespace.
+func fullyQualifiedModuleName(ctx BaseModuleContext) string {
+	return "//" + ctx.Namespace().Path + ":" + ctx.ModuleName()
+}
+
+// filegroupClaimantsInDir returns the names of the filegroups with claim_srcs in dir and its
+// subdirectories.
+func filegroupClaimantsInDir(ctx BaseModuleContext, dir string) []string {
+	claimants := getFilegroupClaimants(ctx.Config())
+	claimants.Lock()
+	defer claimants.Unlock()
+	var names []string
+	for _, d := range SortedKeys(claimants.byDir) {
+		if dir == "." || d == dir || strings.HasPrefix(d, dir+"/") {
+			names = append(names, SortedUniqueStrings(claimants.byDir[d])...)
+		}
+	}
+	return names
+}
+
+func filegroupClaimsSingletonFactory() Singleton {
+	return &filegroupClaimsSingleton{}
+}
+
+// filegroupClaimsSingleton reports the files that are claimed by more than one filegroup.
+type filegroupClaimsSingleton struct{}
+
+func (filegroupClaimsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	claimants := make(map[string][]*fileGroup)
+	ctx.VisitAllModules(func(module Module) {
+		fg, ok := module.(*fileGroup)
+		if !ok || !fg.Enabled() || !Bool(fg.properties.Claim_srcs) {
+			return
+		}
+		for _, src := range FirstUniquePaths(fg.srcs) {
+			claimants[src.String()] = append(claimants[src.String()], fg)
+		}
+	})
+
+	// Report the conflicts in a stable order, each one on the first claimant by name.
+	for _, src := range SortedKeys(claimants) {
+		fgs := claimants[src]
+		if len(fgs) < 2 {
+			continue
+		}
+		sort.Slice(fgs, func(i, j int) bool { return fgs[i].Name() < fgs[j].Name() })
+		var names []string
+		for _, fg := range fgs {
+			names = append(names, fmt.Sprintf("%q", fg.Name()))
+		}
+		ctx.ModuleErrorf(fgs[0], "claim_srcs: %q is claimed by both %s", src, strings.Join(names, " and "))
+	}
+}
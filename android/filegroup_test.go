@@ -79,3 +79,97 @@ func TestFilegroupDefaults(t *testing.T) {
 	rules := effectiveVisibilityRules(result.Config, qualifiedModuleName{pkg: "p", name: "foo"})
 	AssertDeepEquals(t, "visibility", []string{"//x", "//y"}, rules.Strings())
 }
+
+func TestFilegroupExcludeClaimedSrcs(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureAddTextFile("src/Android.bp", `
+			filegroup {
+				name: "all",
+				srcs: ["**/*.java"],
+				exclude_claimed_srcs: true,
+			}
+		`),
+		FixtureAddTextFile("src/foo/Android.bp", `
+			filegroup {
+				name: "foo",
+				srcs: ["*.java"],
+				claim_srcs: true,
+			}
+		`),
+		FixtureAddTextFile("other/Android.bp", `
+			filegroup {
+				name: "other",
+				srcs: ["*.java"],
+				claim_srcs: true,
+			}
+		`),
+		FixtureMergeMockFs(MockFS{
+			"src/a.java":     nil,
+			"src/bar/b.java": nil,
+			"src/foo/c.java": nil,
+			"src/foo/d.java": nil,
+			"other/e.java":   nil,
+		}),
+	).RunTest(t)
+
+	all := result.Module("all", "").(*fileGroup)
+	AssertPathsRelativeToTopEquals(t, "all srcs", []string{"src/a.java", "src/bar/b.java"}, all.Srcs())
+
+	foo := result.Module("foo", "").(*fileGroup)
+	AssertPathsRelativeToTopEquals(t, "foo srcs", []string{"src/foo/c.java", "src/foo/d.java"}, foo.Srcs())
+}
+
+func TestFilegroupExcludeClaimedSrcsInNamespace(t *testing.T) {
+	result := GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		PrepareForTestWithNamespace,
+		FixtureAddTextFile("src/Android.bp", `
+			filegroup {
+				name: "all",
+				srcs: ["**/*.java"],
+				exclude_claimed_srcs: true,
+			}
+		`),
+		FixtureAddTextFile("src/foo/Android.bp", `
+			soong_namespace {
+			}
+
+			filegroup {
+				name: "foo",
+				srcs: ["*.java"],
+				claim_srcs: true,
+			}
+		`),
+		FixtureMergeMockFs(MockFS{
+			"src/a.java":     nil,
+			"src/foo/c.java": nil,
+		}),
+	).RunTest(t)
+
+	all := result.Module("all", "").(*fileGroup)
+	AssertPathsRelativeToTopEquals(t, "all srcs", []string{"src/a.java"}, all.Srcs())
+}
+
+func TestFilegroupClaimSrcsConflict(t *testing.T) {
+	GroupFixturePreparers(
+		PrepareForTestWithFilegroup,
+		FixtureMergeMockFs(MockFS{
+			"a.java": nil,
+		}),
+	).ExtendWithErrorHandler(FixtureExpectsOneErrorPattern(
+		`claim_srcs: ".*a.java" is claimed by both "bar" and "foo"`)).
+		RunTestWithBp(t, `
+			filegroup {
+				name: "foo",
+				srcs: ["a.java"],
+				claim_srcs: true,
+			}
+
+			filegroup {
+				name: "bar",
+				srcs: [":foo"],
+				claim_srcs: true,
+			}
+		`)
+}
@@ -122,7 +122,7 @@ func (m *genNoticeModule) DepsMutator(ctx BottomUpMutatorContext) {
 	if proptools.Bool(m.properties.Html) && proptools.Bool(m.properties.Xml) {
 		ctx.ModuleErrorf("can be html or xml but not both")
 	}
-	if !ctx.Config().AllowMissingDependencies() {
+	if !ctx.AllowMissingDependencies() {
 		var missing []string
 		// Verify the modules for which to generate notices exist.
 		for _, otherMod := range m.properties.For {
@@ -164,7 +164,7 @@ func (m *genNoticeModule) getSuffix() string {
 }
 
 func (m *genNoticeModule) GenerateAndroidBuildActions(ctx ModuleContext) {
-	if ctx.Config().AllowMissingDependencies() {
+	if ctx.AllowMissingDependencies() {
 		// Verify the modules for which to generate notices exist.
 		for _, otherMod := range m.properties.For {
 			if !ctx.OtherModuleExists(otherMod) {
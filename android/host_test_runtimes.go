@@ -0,0 +1,44 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+// The HostTestRuntimes product variable lists the host runtimes that host modules are built
+// against in addition to the primary host runtime, so that host tests can be run against each
+// of them in the same build, e.g. HostTestRuntimes: ["glibc", "musl"] builds a linux_glibc and a
+// linux_musl variant of the host tests. The variants for the additional runtimes are HostCross
+// targets, and are not exported to Make. They are only created for the modules that set
+// host_test_runtimes_supported: true, i.e. the host tests and their dependencies, so that the
+// other host modules are not affected.
+
+// hostTestRuntimeOsTypes maps the names of the host runtimes to their OsType.
+var hostTestRuntimeOsTypes = map[string]OsType{
+	"glibc":  Linux,
+	"musl":   LinuxMusl,
+	"bionic": LinuxBionic,
+}
+
+// HostTestRuntime returns the name of the host runtime, e.g. "musl", if the target was added for
+// the HostTestRuntimes product variable, or an empty string otherwise.
+func (c *config) HostTestRuntime(target Target) string {
+	if target.Os.Class != Host || !target.HostCross || target.Os.Name == String(c.productVariables.CrossHost) {
+		return ""
+	}
+	for _, runtime := range c.productVariables.HostTestRuntimes {
+		if hostTestRuntimeOsTypes[runtime] == target.Os {
+			return runtime
+		}
+	}
+	return ""
+}
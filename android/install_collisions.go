@@ -0,0 +1,73 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+)
+
+func init() {
+	RegisterInstallCollisionsBuildComponents(InitRegistrationContext)
+}
+
+func RegisterInstallCollisionsBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("install_collisions", installCollisionsSingletonFactory)
+}
+
+var PrepareForTestWithInstallCollisions = FixtureRegisterWithContext(RegisterInstallCollisionsBuildComponents)
+
+func installCollisionsSingletonFactory() Singleton {
+	return &installCollisionsSingleton{}
+}
+
+// installCollisionsSingleton reports the install paths, i.e. the files and the symlinks that are
+// installed in a partition, that are installed by more than one module or by more than one variant
+// of a module, e.g. by the 32 and 64 bit variants of a binary with the same relative_install_path,
+// which would otherwise overwrite each other in an order that depends on the build.
+type installCollisionsSingleton struct{}
+
+// installer is a variant of a module that installs a path.
+type installer struct {
+	name, variant string
+}
+
+func (i installer) String() string {
+	if i.variant == "" {
+		return fmt.Sprintf("%q", i.name)
+	}
+	return fmt.Sprintf("%q (variant %q)", i.name, i.variant)
+}
+
+func (installCollisionsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	installedBy := make(map[string]installer)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() {
+			return
+		}
+		current := installer{ctx.ModuleName(module), ctx.ModuleSubDir(module)}
+		for _, path := range module.base().installFiles {
+			other, exists := installedBy[path.String()]
+			if !exists {
+				installedBy[path.String()] = current
+				continue
+			}
+			if other == current {
+				continue
+			}
+			ctx.ModuleErrorf(module, "%s in partition %q is installed by both %s and %s", path,
+				path.partition, other, current)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+type installCollisionsTestModule struct {
+	ModuleBase
+	props struct {
+		Stem    *string
+		Symlink *string
+	}
+}
+
+func (m *installCollisionsTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	outputFile := PathForModuleOut(ctx, ctx.ModuleName())
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: outputFile,
+	})
+	installFile := ctx.InstallFile(PathForModuleInstall(ctx, "bin"),
+		StringDefault(m.props.Stem, ctx.ModuleName()), outputFile)
+	if m.props.Symlink != nil {
+		ctx.InstallSymlink(PathForModuleInstall(ctx, "bin"), *m.props.Symlink, installFile)
+	}
+}
+
+func installCollisionsTestModuleFactory() Module {
+	m := &installCollisionsTestModule{}
+	m.AddProperties(&m.props)
+	InitAndroidArchModule(m, DeviceSupported, MultilibBoth)
+	return m
+}
+
+var prepareForInstallCollisionsTest = GroupFixturePreparers(
+	PrepareForTestWithArchMutator,
+	PrepareForTestWithInstallCollisions,
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("install_collisions_test_module", installCollisionsTestModuleFactory)
+	}),
+)
+
+func TestInstallCollisions(t *testing.T) {
+	t.Run("no collisions", func(t *testing.T) {
+		prepareForInstallCollisionsTest.RunTestWithBp(t, `
+			install_collisions_test_module {
+				name: "foo",
+				compile_multilib: "first",
+				symlink: "foo_link",
+			}
+
+			install_collisions_test_module {
+				name: "bar",
+				compile_multilib: "first",
+			}
+		`)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		prepareForInstallCollisionsTest.
+			ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`module "foo" variant "android_arm64_armv8-a": out/soong/target/product/test_device/system/bin/bar in partition "system" is installed by both "bar" \(variant "android_arm64_armv8-a"\) and "foo" \(variant "android_arm64_armv8-a"\)`)).
+			RunTestWithBp(t, `
+				install_collisions_test_module {
+					name: "foo",
+					compile_multilib: "first",
+					stem: "bar",
+				}
+
+				install_collisions_test_module {
+					name: "bar",
+					compile_multilib: "first",
+				}
+			`)
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		prepareForInstallCollisionsTest.
+			ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`bin/bar in partition "system" is installed by both`)).
+			RunTestWithBp(t, `
+				install_collisions_test_module {
+					name: "foo",
+					compile_multilib: "first",
+					symlink: "bar",
+				}
+
+				install_collisions_test_module {
+					name: "bar",
+					compile_multilib: "first",
+				}
+			`)
+	})
+
+	t.Run("multilib", func(t *testing.T) {
+		prepareForInstallCollisionsTest.
+			ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+				`bin/foo in partition "system" is installed by both "foo" \(variant "android_arm64_armv8-a"\) and "foo" \(variant "android_arm_armv7-a-neon"\)`)).
+			RunTestWithBp(t, `
+				install_collisions_test_module {
+					name: "foo",
+					compile_multilib: "both",
+				}
+			`)
+	})
+}
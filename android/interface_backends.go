@@ -0,0 +1,138 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+	"sync"
+)
+
+// Interface modules, e.g. aidl_interface, generate a library for each backend, named after the
+// interface, its version and the backend, e.g. "foo-V2-ndk". An interface module that registers
+// its backend libraries with RegisterInterfaceBackends can be listed by name in the dependencies
+// of its clients, e.g. in shared_libs: ["foo"] of a cc module. The clients replace it with the
+// library of the backend that matches their own type with ResolveInterfaceBackends.
+
+// The backends of the interface modules.
+const (
+	InterfaceBackendJava = "java"
+	InterfaceBackendCpp  = "cpp"
+	InterfaceBackendNdk  = "ndk"
+	InterfaceBackendRust = "rust"
+)
+
+// InterfaceBackends describes the libraries generated by an interface module for the default
+// version of the interface.
+type InterfaceBackends struct {
+	// The names of the libraries of the default version for each backend, e.g.
+	// {"ndk": "foo-V2-ndk"}.
+	Libraries map[string]string
+
+	// Whether the default version is unfrozen, i.e. it can still change.
+	Unfrozen bool
+}
+
+type interfaceBackendsMap struct {
+	sync.Mutex
+	// The backends of the interface modules, by the path of their namespace and their name.
+	interfaces map[string]map[string]InterfaceBackends
+}
+
+var interfaceBackendsKey = NewOnceKey("interfaceBackends")
+
+func getInterfaceBackendsMap(config Config) *interfaceBackendsMap {
+	return config.Once(interfaceBackendsKey, func() interface{} {
+		return &interfaceBackendsMap{interfaces: make(map[string]map[string]InterfaceBackends)}
+	}).(*interfaceBackendsMap)
+}
+
+// RegisterInterfaceBackends records the backend libraries of an interface module. It must be
+// called from a load hook of the interface module so that the libraries are known before the
+// dependencies of the clients are added. The libraries must be defined in the namespace of the
+// interface module.
+func RegisterInterfaceBackends(ctx LoadHookContext, backends InterfaceBackends) {
+	m := getInterfaceBackendsMap(ctx.Config())
+	m.Lock()
+	defer m.Unlock()
+	namespace := ctx.Namespace().Path
+	if m.interfaces[namespace] == nil {
+		m.interfaces[namespace] = make(map[string]InterfaceBackends)
+	}
+	m.interfaces[namespace][ctx.ModuleName()] = backends
+}
+
+// lookup returns the backends of the interface module that dep refers to from the namespace of the
+// module of ctx, following the same rules as the dependencies: a fully qualified name, e.g.
+// "//vendor/foo:foo", is looked up in its namespace, and other names are looked up in the visible
+// namespaces in order. It also returns the name to use for the libraries of the interface module,
+// qualified with the namespace if dep was.
+func (m *interfaceBackendsMap) lookup(ctx BaseModuleContext, dep string) (InterfaceBackends, func(string) string, bool) {
+	if strings.HasPrefix(dep, "//") {
+		namespace, name, ok := strings.Cut(strings.TrimPrefix(dep, "//"), ":")
+		if !ok {
+			return InterfaceBackends{}, nil, false
+		}
+		backends, ok := m.interfaces[namespace][name]
+		return backends, func(lib string) string { return "//" + namespace + ":" + lib }, ok
+	}
+
+	namespaces := []*Namespace{ctx.Namespace()}
+	if visible := ctx.Namespace().visibleNamespaces; visible != nil {
+		namespaces = visible
+	}
+	for _, namespace := range namespaces {
+		if backends, ok := m.interfaces[namespace.Path][dep]; ok {
+			return backends, func(lib string) string { return lib }, true
+		}
+	}
+	return InterfaceBackends{}, nil, false
+}
+
+// ResolveInterfaceBackends replaces the names of the interface modules in deps with the names of
+// their libraries for the backend, and returns the resulting list. The other names are kept
+// unchanged. If requireFrozen is true, e.g. for the vendor variants, or if the interfaces are
+// frozen on this branch, an error is reported for the interfaces whose default version is unfrozen,
+// as the clients must use a frozen version.
+func ResolveInterfaceBackends(ctx BaseModuleContext, backend string, requireFrozen bool, deps []string) []string {
+	m := getInterfaceBackendsMap(ctx.Config())
+	m.Lock()
+	defer m.Unlock()
+	if len(m.interfaces) == 0 {
+		return deps
+	}
+	requireFrozen = requireFrozen || ctx.Config().FrozenInterfaces()
+
+	var ret []string
+	for _, dep := range deps {
+		backends, libName, ok := m.lookup(ctx, dep)
+		if !ok {
+			ret = append(ret, dep)
+			continue
+		}
+		lib, ok := backends.Libraries[backend]
+		if !ok {
+			ctx.ModuleErrorf("interface %q does not have a %s backend, enabled backends are %q",
+				dep, backend, SortedKeys(backends.Libraries))
+			continue
+		}
+		if requireFrozen && backends.Unfrozen {
+			ctx.ModuleErrorf("cannot depend on the unfrozen version %q of interface %q, depend on "+
+				"the library of a frozen version instead, e.g. %q", lib, dep, dep+"-V<version>-"+backend)
+			continue
+		}
+		ret = append(ret, libName(lib))
+	}
+	return ret
+}
@@ -0,0 +1,219 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type testInterfaceModule struct {
+	ModuleBase
+	properties struct {
+		Version  string
+		Backends []string
+		Unfrozen bool
+	}
+}
+
+func testInterfaceFactory() Module {
+	m := &testInterfaceModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	AddLoadHook(m, func(ctx LoadHookContext) {
+		libraries := make(map[string]string)
+		for _, backend := range m.properties.Backends {
+			libraries[backend] = ctx.ModuleName() + "-V" + m.properties.Version + "-" + backend
+		}
+		RegisterInterfaceBackends(ctx, InterfaceBackends{
+			Libraries: libraries,
+			Unfrozen:  m.properties.Unfrozen,
+		})
+	})
+	return m
+}
+
+func (m *testInterfaceModule) GenerateAndroidBuildActions(ModuleContext) {}
+
+var testInterfaceClientDepTag = struct{ blueprint.BaseDependencyTag }{}
+
+type testInterfaceClientModule struct {
+	ModuleBase
+	properties struct {
+		Backend        string
+		Require_frozen bool
+		Libs           []string
+	}
+	deps []string
+}
+
+func testInterfaceClientFactory() Module {
+	m := &testInterfaceClientModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *testInterfaceClientModule) DepsMutator(ctx BottomUpMutatorContext) {
+	libs := ResolveInterfaceBackends(ctx, m.properties.Backend, m.properties.Require_frozen, m.properties.Libs)
+	ctx.AddDependency(ctx.Module(), testInterfaceClientDepTag, libs...)
+}
+
+func (m *testInterfaceClientModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	ctx.VisitDirectDepsWithTag(testInterfaceClientDepTag, func(dep Module) {
+		m.deps = append(m.deps, ctx.OtherModuleName(dep))
+	})
+}
+
+var prepareForInterfaceBackendsTest = GroupFixturePreparers(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("test_interface", testInterfaceFactory)
+		ctx.RegisterModuleType("test_interface_client", testInterfaceClientFactory)
+	}),
+)
+
+const interfaceBackendsTestBp = `
+	test_interface {
+		name: "foo",
+		version: "2",
+		backends: ["java", "ndk"],
+	}
+
+	test_interface {
+		name: "bar",
+		version: "3",
+		backends: ["ndk"],
+		unfrozen: true,
+	}
+
+	test_interface_client { name: "foo-V2-java" }
+	test_interface_client { name: "foo-V2-ndk" }
+	test_interface_client { name: "bar-V3-ndk" }
+	test_interface_client { name: "libother" }
+`
+
+func TestResolveInterfaceBackends(t *testing.T) {
+	result := prepareForInterfaceBackendsTest.RunTestWithBp(t, interfaceBackendsTestBp+`
+		test_interface_client {
+			name: "java_client",
+			backend: "java",
+			libs: ["foo", "libother"],
+		}
+
+		test_interface_client {
+			name: "ndk_client",
+			backend: "ndk",
+			libs: ["foo", "bar"],
+		}
+	`)
+
+	javaClient := result.Module("java_client", "").(*testInterfaceClientModule)
+	AssertArrayString(t, "java client deps", []string{"foo-V2-java", "libother"}, javaClient.deps)
+
+	ndkClient := result.Module("ndk_client", "").(*testInterfaceClientModule)
+	AssertArrayString(t, "ndk client deps", []string{"foo-V2-ndk", "bar-V3-ndk"}, ndkClient.deps)
+}
+
+func TestResolveInterfaceBackendsErrors(t *testing.T) {
+	prepareForInterfaceBackendsTest.
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "rust_client": interface "foo" does not have a rust backend, enabled backends are \["java" "ndk"\]`,
+			`module "vendor_client": cannot depend on the unfrozen version "bar-V3-ndk" of interface "bar"`,
+		})).
+		RunTestWithBp(t, interfaceBackendsTestBp+`
+			test_interface_client {
+				name: "rust_client",
+				backend: "rust",
+				libs: ["foo"],
+			}
+
+			test_interface_client {
+				name: "vendor_client",
+				backend: "ndk",
+				require_frozen: true,
+				libs: ["foo", "bar"],
+			}
+		`)
+}
+
+func TestResolveInterfaceBackendsInNamespace(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForInterfaceBackendsTest,
+		PrepareForTestWithNamespace,
+		FixtureAddTextFile("a/Android.bp", `
+			soong_namespace {
+			}
+
+			test_interface {
+				name: "foo",
+				version: "2",
+				backends: ["ndk"],
+			}
+
+			test_interface_client { name: "foo-V2-ndk" }
+
+			test_interface_client {
+				name: "a_client",
+				backend: "ndk",
+				libs: ["foo"],
+			}
+		`),
+		FixtureAddTextFile("b/Android.bp", `
+			soong_namespace {
+			}
+
+			test_interface {
+				name: "foo",
+				version: "3",
+				backends: ["ndk"],
+			}
+
+			test_interface_client { name: "foo-V3-ndk" }
+		`),
+		FixtureAddTextFile("Android.bp", `
+			test_interface_client {
+				name: "root_client",
+				backend: "ndk",
+				libs: ["//b:foo"],
+			}
+		`),
+	).RunTest(t)
+
+	aClient := result.Module("a_client", "").(*testInterfaceClientModule)
+	AssertArrayString(t, "a client deps", []string{"foo-V2-ndk"}, aClient.deps)
+
+	rootClient := result.Module("root_client", "").(*testInterfaceClientModule)
+	AssertArrayString(t, "root client deps", []string{"foo-V3-ndk"}, rootClient.deps)
+}
+
+func TestResolveInterfaceBackendsFrozenInterfaces(t *testing.T) {
+	GroupFixturePreparers(
+		prepareForInterfaceBackendsTest,
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.Frozen_interfaces = boolPtr(true)
+		}),
+	).
+		ExtendWithErrorHandler(FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`module "platform_client": cannot depend on the unfrozen version "bar-V3-ndk" of interface "bar"`,
+		})).
+		RunTestWithBp(t, interfaceBackendsTestBp+`
+			test_interface_client {
+				name: "platform_client",
+				backend: "ndk",
+				libs: ["foo", "bar"],
+			}
+		`)
+}
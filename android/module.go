@@ -138,6 +138,11 @@ type EarlyModuleContext interface {
 	// has prevented the module from creating necessary data it can return early when Failed returns true.
 	Failed() bool
 
+	// AllowMissingDependencies returns true if the module is allowed to depend on modules that do not exist, i.e. if
+	// Config.AllowMissingDependencies is true or if the module is in one of the AllowMissingDependenciesDirs. It should
+	// be used instead of Config.AllowMissingDependencies by the modules.
+	AllowMissingDependencies() bool
+
 	// AddNinjaFileDeps adds dependencies on the specified files to the rule that creates the ninja manifest.  The
 	// primary builder will be rerun whenever the specified files are modified.
 	AddNinjaFileDeps(deps ...string)
@@ -851,6 +856,11 @@ type commonProperties struct {
 	// Whether this module is built for non-native architectures (also known as native bridge binary)
 	Native_bridge_supported *bool `android:"arch_variant"`
 
+	// Whether this module is built against the additional host runtimes listed in the
+	// HostTestRuntimes product variable. It must be set on the host tests to run against them and
+	// on all of their dependencies.
+	Host_test_runtimes_supported *bool
+
 	// init.rc files to be installed if this module is installed
 	Init_rc []string `android:"arch_variant,path"`
 
@@ -866,6 +876,12 @@ type commonProperties struct {
 	// names of other modules to install on target if this module is installed
 	Target_required []string `android:"arch_variant"`
 
+	// List of paths, typically outputs of checker modules such as genrules running schema checks
+	// or linters, that are ninja validations of the build actions of this module: they are built
+	// whenever the module is built and must succeed, but they are not inputs of the actions of the
+	// module, so changes to them do not cause the module to be rebuilt.
+	Validations []string `android:"path"`
+
 	// The OsType of artifacts that this module variant is responsible for creating.
 	//
 	// Set by osMutator
@@ -2348,7 +2364,15 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 	// Temporarily continue to call blueprintCtx.GetMissingDependencies() to maintain the previous behavior of never
 	// reporting missing dependency errors in Blueprint when AllowMissingDependencies == true.
 	// TODO: This will be removed once defaults modules handle missing dependency errors
-	blueprintCtx.GetMissingDependencies()
+	if missingDeps := blueprintCtx.GetMissingDependencies(); len(missingDeps) > 0 && !ctx.AllowMissingDependencies() {
+		// Blueprint only allows the missing dependencies because of the experimental directories or
+		// of the AllowMissingDependenciesDirs, report them as errors, or as warnings for the modules
+		// in the experimental directories.
+		ctx.ModuleErrorf("depends on undefined module(s) %q", missingDeps)
+		if ctx.Failed() {
+			return
+		}
+	}
 
 	// For the final GenerateAndroidBuildActions pass, require that all visited dependencies Soong modules and
 	// are enabled. Unless the module is a CommonOS variant which may have dependencies on disabled variants
@@ -2400,6 +2424,8 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			return
 		}
 
+		ctx.validations = PathsForModuleSrc(ctx, m.commonProperties.Validations)
+
 		if mixedBuildMod, handled := m.isHandledByBazel(ctx); handled {
 			mixedBuildMod.ProcessBazelQueryResponse(ctx)
 		} else {
@@ -2430,12 +2456,14 @@ func (m *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 			return
 		}
 
+		setModuleOutputsProvider(ctx, m.module)
+
 		m.installFiles = append(m.installFiles, ctx.installFiles...)
 		m.checkbuildFiles = append(m.checkbuildFiles, ctx.checkbuildFiles...)
 		m.packagingSpecs = append(m.packagingSpecs, ctx.packagingSpecs...)
 		m.katiInstalls = append(m.katiInstalls, ctx.katiInstalls...)
 		m.katiSymlinks = append(m.katiSymlinks, ctx.katiSymlinks...)
-	} else if ctx.Config().AllowMissingDependencies() {
+	} else if ctx.AllowMissingDependencies() {
 		// If the module is not enabled it will not create any build rules, nothing will call
 		// ctx.GetMissingDependencies(), and blueprint will consider the missing dependencies to be unhandled
 		// and report them as an error even when AllowMissingDependencies = true.  Call
@@ -2563,6 +2591,10 @@ func (e *earlyModuleContext) SystemExtSpecific() bool {
 	return e.kind == systemExtSpecificModule
 }
 
+func (e *earlyModuleContext) AllowMissingDependencies() bool {
+	return e.config.AllowMissingDependenciesForDir(e.ModuleDir())
+}
+
 func (e *earlyModuleContext) Namespace() *Namespace {
 	return e.EarlyModuleContext.Namespace().(*Namespace)
 }
@@ -2646,6 +2678,9 @@ type moduleContext struct {
 	katiInstalls []katiInstall
 	katiSymlinks []katiInstall
 
+	// Validations added to every build action of the module, from the validations property.
+	validations Paths
+
 	// For tests
 	buildParams []BuildParams
 	ruleParams  map[blueprint.Rule]blueprint.RuleParams
@@ -2827,6 +2862,10 @@ func (m *moduleContext) Build(pctx PackageContext, params BuildParams) {
 			m.ModuleName(), strings.Join(missingDeps, ", ")))
 	}
 
+	if len(m.validations) > 0 {
+		params.Validations = append(append(Paths(nil), params.Validations...), m.validations...)
+	}
+
 	if m.config.captureBuild {
 		m.buildParams = append(m.buildParams, params)
 	}
@@ -2881,7 +2920,7 @@ func (b *baseModuleContext) validateAndroidModule(module blueprint.Module, tag b
 
 	if !aModule.Enabled() {
 		if t, ok := tag.(AllowDisabledModuleDependency); !ok || !t.AllowDisabledModuleDependency(aModule) {
-			if b.Config().AllowMissingDependencies() {
+			if b.AllowMissingDependencies() {
 				b.AddMissingDependencies([]string{b.OtherModuleName(aModule)})
 			} else {
 				b.ModuleErrorf("depends on disabled module %q", b.OtherModuleName(aModule))
@@ -3211,6 +3250,12 @@ func (m *ModuleBase) IsNativeBridgeSupported() bool {
 	return proptools.Bool(m.commonProperties.Native_bridge_supported)
 }
 
+// IsHostTestRuntimesSupported returns true if "host_test_runtimes_supported" is explicitly set as
+// "true"
+func (m *ModuleBase) IsHostTestRuntimesSupported() bool {
+	return proptools.Bool(m.commonProperties.Host_test_runtimes_supported)
+}
+
 func (m *moduleContext) InstallInData() bool {
 	return m.module.InstallInData()
 }
@@ -3611,6 +3656,22 @@ type OutputFileProducer interface {
 	OutputFiles(tag string) (Paths, error)
 }
 
+// OutputFileTagsProducer can be implemented by an OutputFileProducer to list the tags that it supports in addition
+// to the default "" tag, so that `m module-outputs <module>` can list them and references with unsupported tags can report
+// the supported ones.
+type OutputFileTagsProducer interface {
+	OutputFileTags() []string
+}
+
+// supportedOutputFileTags returns the tags supported by the module, to be appended to the error returned by the
+// module for an unsupported tag, or an empty string if the module does not list its tags.
+func supportedOutputFileTags(module blueprint.Module, tag string) string {
+	if tagsProducer, ok := module.(OutputFileTagsProducer); ok && tag != "" && !InList(tag, tagsProducer.OutputFileTags()) {
+		return fmt.Sprintf(", supported tags are %q", append([]string{""}, tagsProducer.OutputFileTags()...))
+	}
+	return ""
+}
+
 // OutputFilesForModule returns the paths from an OutputFileProducer with the given tag.  On error, including if the
 // module produced zero paths, it reports errors to the ctx and returns nil.
 func OutputFilesForModule(ctx PathContext, module blueprint.Module, tag string) Paths {
@@ -3635,7 +3696,7 @@ func OutputFileForModule(ctx PathContext, module blueprint.Module, tag string) P
 			AddMissingDependencies([]string)
 			OtherModuleName(blueprint.Module) string
 		}
-		if mctx, ok := ctx.(addMissingDependenciesIntf); ok && ctx.Config().AllowMissingDependencies() {
+		if mctx, ok := ctx.(addMissingDependenciesIntf); ok && AllowMissingDependencies(ctx) {
 			mctx.AddMissingDependencies([]string{mctx.OtherModuleName(module)})
 		} else {
 			ReportPathErrorf(ctx, "failed to get output files from module %q", pathContextName(ctx, module))
@@ -3661,8 +3722,8 @@ func outputFilesForModule(ctx PathContext, module blueprint.Module, tag string)
 	if outputFileProducer, ok := module.(OutputFileProducer); ok {
 		paths, err := outputFileProducer.OutputFiles(tag)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get output file from module %q: %s",
-				pathContextName(ctx, module), err.Error())
+			return nil, fmt.Errorf("failed to get output file from module %q: %s%s",
+				pathContextName(ctx, module), err.Error(), supportedOutputFileTags(module, tag))
 		}
 		return paths, nil
 	} else if sourceFileProducer, ok := module.(SourceFileProducer); ok {
@@ -0,0 +1,156 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/blueprint"
+)
+
+// This singleton writes the output file tags that the modules support, and the paths that they
+// refer to, to $OUT_DIR/soong/module_outputs.json. It is read by `m module-outputs <module>` to show
+// which ":module{.tag}" references can be used in the path properties of other modules, e.g. in the
+// srcs of a genrule. As it queries the output files of every module, it only runs when
+// SOONG_MODULE_OUTPUTS=true, which `m module-outputs` sets.
+
+func init() {
+	RegisterSingletonType("module_outputs", moduleOutputsSingletonFactory)
+}
+
+const moduleOutputsFileName = "module_outputs.json"
+
+// ModuleOutputsEnabled returns whether the modules publish their output files with
+// ModuleOutputsProvider and module_outputs.json is written.
+func (c *config) ModuleOutputsEnabled() bool {
+	return c.IsEnvTrue("SOONG_MODULE_OUTPUTS")
+}
+
+// ModuleOutputs contains the output files of a variant of a module, as listed in
+// module_outputs.json.
+type ModuleOutputs struct {
+	// The variant of the module, e.g. "android_arm64_armv8-a_shared".
+	Variant string
+
+	// The output files for each tag, starting with the default "" tag.
+	Tags []ModuleOutputTag
+}
+
+// ModuleOutputTag contains the output files of a module for a tag, or the error returned by the
+// module for the tag.
+type ModuleOutputTag struct {
+	Tag   string
+	Paths []string `json:",omitempty"`
+	Error string   `json:",omitempty"`
+}
+
+// ModuleOutputsInfo contains the output files of an OutputFileProducer for each of the tags that
+// it supports.
+type ModuleOutputsInfo struct {
+	Tags []ModuleOutputTag
+}
+
+var ModuleOutputsProvider = blueprint.NewProvider(ModuleOutputsInfo{})
+
+// setModuleOutputsProvider sets ModuleOutputsProvider for the module if it is an
+// OutputFileProducer and module_outputs.json is enabled. It must be called after
+// GenerateAndroidBuildActions, which sets the output files.
+func setModuleOutputsProvider(ctx ModuleContext, module Module) {
+	if !ctx.Config().ModuleOutputsEnabled() {
+		return
+	}
+	if _, ok := module.(OutputFileProducer); !ok {
+		return
+	}
+	ctx.SetProvider(ModuleOutputsProvider, ModuleOutputsInfo{Tags: moduleOutputTags(module)})
+}
+
+func moduleOutputsSingletonFactory() Singleton {
+	return &moduleOutputsSingleton{}
+}
+
+type moduleOutputsSingleton struct{}
+
+func (s *moduleOutputsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().ModuleOutputsEnabled() {
+		return
+	}
+
+	outputs := make(map[string][]ModuleOutputs)
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() || !ctx.ModuleHasProvider(module, ModuleOutputsProvider) {
+			return
+		}
+		info := ctx.ModuleProvider(module, ModuleOutputsProvider).(ModuleOutputsInfo)
+		name := ctx.ModuleName(module)
+		outputs[name] = append(outputs[name], ModuleOutputs{
+			Variant: ctx.ModuleSubDir(module),
+			Tags:    info.Tags,
+		})
+	})
+	for _, variants := range outputs {
+		sort.SliceStable(variants, func(i, j int) bool { return variants[i].Variant < variants[j].Variant })
+	}
+
+	path := PathForOutput(ctx, moduleOutputsFileName)
+	if err := writeModuleOutputs(outputs, path); err != nil {
+		ctx.Errorf(err.Error())
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: path,
+	})
+}
+
+// moduleOutputTags returns the output files of the module for the default tag and for the tags
+// listed by the module if it is an OutputFileTagsProducer.
+func moduleOutputTags(module Module) []ModuleOutputTag {
+	tags := []string{""}
+	if tagsProducer, ok := module.(OutputFileTagsProducer); ok {
+		tags = append(tags, tagsProducer.OutputFileTags()...)
+	}
+
+	var ret []ModuleOutputTag
+	for _, tag := range FirstUniqueStrings(tags) {
+		paths, err := module.(OutputFileProducer).OutputFiles(tag)
+		if err != nil {
+			ret = append(ret, ModuleOutputTag{Tag: tag, Error: err.Error()})
+		} else {
+			outputTag := ModuleOutputTag{Tag: tag}
+			for _, path := range paths {
+				if path != nil {
+					outputTag.Paths = append(outputTag.Paths, path.String())
+				}
+			}
+			ret = append(ret, outputTag)
+		}
+	}
+	return ret
+}
+
+func writeModuleOutputs(outputs map[string][]ModuleOutputs, path WritablePath) error {
+	buf, err := json.MarshalIndent(outputs, "", "\t")
+	if err != nil {
+		return fmt.Errorf("JSON marshal of module outputs failed: %s", err)
+	}
+	if err := WriteFileToOutputDir(path, buf, 0666); err != nil {
+		return fmt.Errorf("Writing module outputs to %s failed: %s", path.String(), err)
+	}
+	return nil
+}
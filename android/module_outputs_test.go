@@ -0,0 +1,125 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type taggedOutputsTestModule struct {
+	ModuleBase
+
+	outs   Paths
+	tagged Paths
+}
+
+func taggedOutputsTestModuleFactory() Module {
+	module := &taggedOutputsTestModule{}
+	InitAndroidModule(module)
+	return module
+}
+
+func (m *taggedOutputsTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.outs = Paths{PathForModuleOut(ctx, "a.out")}
+	m.tagged = Paths{PathForModuleOut(ctx, "b.out"), PathForModuleOut(ctx, "c.out")}
+}
+
+func (m *taggedOutputsTestModule) OutputFiles(tag string) (Paths, error) {
+	switch tag {
+	case "":
+		return m.outs, nil
+	case ".tagged":
+		return m.tagged, nil
+	case ".broken":
+		return nil, fmt.Errorf("no broken output")
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
+
+func (m *taggedOutputsTestModule) OutputFileTags() []string {
+	return []string{".tagged", ".broken"}
+}
+
+var prepareForModuleOutputsTest = GroupFixturePreparers(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("tagged_outputs", taggedOutputsTestModuleFactory)
+		ctx.RegisterSingletonType("module_outputs", moduleOutputsSingletonFactory)
+	}),
+	PrepareForTestWithFilegroup,
+)
+
+func TestModuleOutputs(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForModuleOutputsTest,
+		FixtureMergeEnv(map[string]string{"SOONG_MODULE_OUTPUTS": "true"}),
+	).RunTestWithBp(t, `
+		tagged_outputs {
+			name: "foo",
+		}
+	`)
+
+	content, err := ioutil.ReadFile(filepath.Join(result.Config.SoongOutDir(), moduleOutputsFileName))
+	if err != nil {
+		t.Fatalf("%s has not been generated: %s", moduleOutputsFileName, err)
+	}
+	var outputs map[string][]ModuleOutputs
+	if err := json.Unmarshal(content, &outputs); err != nil {
+		t.Fatalf("failed to parse %s: %s", moduleOutputsFileName, err)
+	}
+
+	outDir := result.Config.SoongOutDir() + "/.intermediates/foo"
+	AssertDeepEquals(t, "foo outputs", []ModuleOutputs{
+		{
+			Variant: "",
+			Tags: []ModuleOutputTag{
+				{Tag: "", Paths: []string{outDir + "/a.out"}},
+				{Tag: ".tagged", Paths: []string{outDir + "/b.out", outDir + "/c.out"}},
+				{Tag: ".broken", Error: "no broken output"},
+			},
+		},
+	}, outputs["foo"])
+}
+
+func TestModuleOutputsDisabled(t *testing.T) {
+	result := prepareForModuleOutputsTest.RunTestWithBp(t, `
+		tagged_outputs {
+			name: "foo",
+		}
+	`)
+
+	AssertBoolEquals(t, "module_outputs.json rule", false,
+		result.SingletonForTests("module_outputs").MaybeOutput(moduleOutputsFileName).Rule != nil)
+}
+
+func TestUnsupportedOutputFileTagError(t *testing.T) {
+	prepareForModuleOutputsTest.
+		ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`path dependency ":foo\{\.missing\}": unsupported module reference tag "\.missing", `+
+				`supported tags are \["" ".tagged" ".broken"\]`)).
+		RunTestWithBp(t, `
+			tagged_outputs {
+				name: "foo",
+			}
+			filegroup {
+				name: "bar",
+				srcs: [":foo{.missing}"],
+			}
+		`)
+}
@@ -244,6 +244,30 @@ func TestErrorDependsOnDisabledModule(t *testing.T) {
 		RunTestWithBp(t, bp)
 }
 
+func TestValidationsProperty(t *testing.T) {
+	bp := `
+		deps {
+			name: "foo",
+			validations: ["foo.schema_check"],
+		}
+		deps {
+			name: "bar",
+		}
+	`
+
+	result := GroupFixturePreparers(
+		prepareForModuleTests,
+		PrepareForTestWithArchMutator,
+		FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	foo := result.ModuleForTests("foo", "android_common").Output("foo")
+	AssertPathsRelativeToTopEquals(t, "foo validations", []string{"foo.schema_check"}, foo.Validations)
+
+	bar := result.ModuleForTests("bar", "android_common").Output("bar")
+	AssertPathsRelativeToTopEquals(t, "bar validations", nil, bar.Validations)
+}
+
 func TestValidateCorrectBuildParams(t *testing.T) {
 	config := TestConfig(t.TempDir(), nil, "", nil)
 	pathContext := PathContextForTesting(config)
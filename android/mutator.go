@@ -646,11 +646,23 @@ func (mutator *mutator) register(ctx *Context) {
 	blueprintCtx := ctx.Context
 	var handle blueprint.MutatorHandle
 	if mutator.bottomUpMutator != nil {
-		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, mutator.bottomUpMutator)
+		bottomUpMutator := mutator.bottomUpMutator
+		if ctx.mutatorProfile != nil {
+			bottomUpMutator = ctx.mutatorProfile.bottomUp(mutator.name, bottomUpMutator)
+		}
+		handle = blueprintCtx.RegisterBottomUpMutator(mutator.name, bottomUpMutator)
 	} else if mutator.topDownMutator != nil {
-		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, mutator.topDownMutator)
+		topDownMutator := mutator.topDownMutator
+		if ctx.mutatorProfile != nil {
+			topDownMutator = ctx.mutatorProfile.topDown(mutator.name, topDownMutator)
+		}
+		handle = blueprintCtx.RegisterTopDownMutator(mutator.name, topDownMutator)
 	} else if mutator.transitionMutator != nil {
-		blueprintCtx.RegisterTransitionMutator(mutator.name, mutator.transitionMutator)
+		transitionMutator := mutator.transitionMutator
+		if ctx.mutatorProfile != nil {
+			transitionMutator = ctx.mutatorProfile.transition(mutator.name, transitionMutator)
+		}
+		blueprintCtx.RegisterTransitionMutator(mutator.name, transitionMutator)
 	}
 	if mutator.parallel {
 		handle.Parallel()
@@ -826,7 +838,45 @@ func (b *bottomUpMutatorContext) Rename(name string) {
 }
 
 func (b *bottomUpMutatorContext) AddDependency(module blueprint.Module, tag blueprint.DependencyTag, name ...string) []blueprint.Module {
-	return b.bp.AddDependency(module, tag, name...)
+	return b.addMissingDependencies(name, func(names []string) []blueprint.Module {
+		return b.bp.AddDependency(module, tag, names...)
+	})
+}
+
+// addMissingDependencies calls addDeps with the names of the modules that exist, and records the
+// other names as missing dependencies if the module is allowed to depend on modules that do not
+// exist because of its directory. Blueprint only allows missing dependencies for all the modules
+// at once, so it is only enabled in Blueprint when AllowMissingDependencies is true. It returns
+// the dependencies in the order of names, with nil for the missing ones.
+func (b *bottomUpMutatorContext) addMissingDependencies(names []string,
+	addDeps func(names []string) []blueprint.Module) []blueprint.Module {
+
+	if !b.Config().allowMissingDependenciesInDir(b.ModuleDir()) {
+		return addDeps(names)
+	}
+
+	var existing, missing []string
+	for _, name := range names {
+		if b.OtherModuleExists(name) {
+			existing = append(existing, name)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return addDeps(names)
+	}
+	b.AddMissingDependencies(missing)
+
+	deps := addDeps(existing)
+	ret := make([]blueprint.Module, len(names))
+	for i, j := 0, 0; i < len(names) && j < len(deps); i++ {
+		if names[i] == existing[j] {
+			ret[i] = deps[j]
+			j++
+		}
+	}
+	return ret
 }
 
 func (b *bottomUpMutatorContext) AddReverseDependency(module blueprint.Module, tag blueprint.DependencyTag, name string) {
@@ -879,13 +929,17 @@ func (b *bottomUpMutatorContext) SetDefaultDependencyVariation(variation *string
 
 func (b *bottomUpMutatorContext) AddVariationDependencies(variations []blueprint.Variation, tag blueprint.DependencyTag,
 	names ...string) []blueprint.Module {
-	return b.bp.AddVariationDependencies(variations, tag, names...)
+	return b.addMissingDependencies(names, func(names []string) []blueprint.Module {
+		return b.bp.AddVariationDependencies(variations, tag, names...)
+	})
 }
 
 func (b *bottomUpMutatorContext) AddFarVariationDependencies(variations []blueprint.Variation,
 	tag blueprint.DependencyTag, names ...string) []blueprint.Module {
 
-	return b.bp.AddFarVariationDependencies(variations, tag, names...)
+	return b.addMissingDependencies(names, func(names []string) []blueprint.Module {
+		return b.bp.AddFarVariationDependencies(variations, tag, names...)
+	})
 }
 
 func (b *bottomUpMutatorContext) AddInterVariantDependency(tag blueprint.DependencyTag, from, to blueprint.Module) {
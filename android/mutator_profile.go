@@ -0,0 +1,207 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/blueprint"
+)
+
+// The mutator profile records the time spent running each bottom up and top down mutator on each
+// module, so that the soong_build analysis time can be attributed to the mutators and to the
+// packages, i.e. the directories of the Android.bp files, of the modules. The times of the calls
+// of a mutator are summed, so for a parallel mutator they may add up to more than the wall time
+// of the mutator. It is enabled with the --mutator_profile and --mutator_profile_top flags of
+// soong_build, which soong_ui passes with the SOONG_PROFILE_MUTATORS and
+// SOONG_PROFILE_MUTATORS_TOP environment variables. While it is enabled the samples of the
+// --cpuprofile CPU profile are labeled with the mutator and the package, so that they can be
+// filtered with e.g. go tool pprof -tagfocus mutator=apex.
+
+// mutatorProfile collects the times of the mutator calls.
+type mutatorProfile struct {
+	sync.Mutex
+	mutators map[string]*mutatorProfileEntry
+	packages map[string]*mutatorProfileEntry
+}
+
+// mutatorProfileEntry is the total time of the calls of a mutator, or of the calls of the mutators
+// on the modules of a package.
+type mutatorProfileEntry struct {
+	Name  string
+	Calls int
+	Time  time.Duration
+}
+
+func newMutatorProfile() *mutatorProfile {
+	return &mutatorProfile{
+		mutators: make(map[string]*mutatorProfileEntry),
+		packages: make(map[string]*mutatorProfileEntry),
+	}
+}
+
+func (p *mutatorProfile) record(mutator, pkg string, d time.Duration) {
+	p.Lock()
+	defer p.Unlock()
+	add := func(entries map[string]*mutatorProfileEntry, name string) {
+		entry := entries[name]
+		if entry == nil {
+			entry = &mutatorProfileEntry{Name: name}
+			entries[name] = entry
+		}
+		entry.Calls++
+		entry.Time += d
+	}
+	add(p.mutators, mutator)
+	add(p.packages, pkg)
+}
+
+// run runs a call of a mutator on a module of a package, records its time and labels the samples
+// of the CPU profile taken during the call.
+func (p *mutatorProfile) run(mutator, pkg string, f func()) {
+	start := time.Now()
+	pprof.Do(context.Background(), pprof.Labels("mutator", mutator, "package", pkg), func(context.Context) {
+		f()
+	})
+	p.record(mutator, pkg, time.Since(start))
+}
+
+func (p *mutatorProfile) bottomUp(name string, m blueprint.BottomUpMutator) blueprint.BottomUpMutator {
+	return func(ctx blueprint.BottomUpMutatorContext) {
+		p.run(name, ctx.ModuleDir(), func() { m(ctx) })
+	}
+}
+
+func (p *mutatorProfile) topDown(name string, m blueprint.TopDownMutator) blueprint.TopDownMutator {
+	return func(ctx blueprint.TopDownMutatorContext) {
+		p.run(name, ctx.ModuleDir(), func() { m(ctx) })
+	}
+}
+
+func (p *mutatorProfile) transition(name string, m blueprint.TransitionMutator) blueprint.TransitionMutator {
+	return &profiledTransitionMutator{TransitionMutator: m, profile: p, name: name}
+}
+
+// profiledTransitionMutator times the Split and Mutate calls of a transition mutator. The
+// OutgoingTransition and IncomingTransition calls, which are made for each dependency and only
+// select a variation, are not timed.
+type profiledTransitionMutator struct {
+	blueprint.TransitionMutator
+	profile *mutatorProfile
+	name    string
+}
+
+func (t *profiledTransitionMutator) Split(ctx blueprint.BaseModuleContext) []string {
+	var variations []string
+	t.profile.run(t.name, ctx.ModuleDir(), func() {
+		variations = t.TransitionMutator.Split(ctx)
+	})
+	return variations
+}
+
+func (t *profiledTransitionMutator) Mutate(ctx blueprint.BottomUpMutatorContext, variation string) {
+	t.profile.run(t.name, ctx.ModuleDir(), func() {
+		t.TransitionMutator.Mutate(ctx, variation)
+	})
+}
+
+// sortedMutatorProfileEntries returns the entries sorted from the slowest to the fastest.
+func sortedMutatorProfileEntries(entries map[string]*mutatorProfileEntry) []mutatorProfileEntry {
+	ret := make([]mutatorProfileEntry, 0, len(entries))
+	for _, entry := range entries {
+		ret = append(ret, *entry)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Time != ret[j].Time {
+			return ret[i].Time > ret[j].Time
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func topMutatorProfileEntries(entries map[string]*mutatorProfileEntry, n int) []mutatorProfileEntry {
+	sorted := sortedMutatorProfileEntries(entries)
+	if n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+var mutatorProfileTemplate = template.Must(template.New("mutator_profile").Parse(`<!DOCTYPE html>
+<html>
+<head><title>soong_build mutator profile</title></head>
+<body>
+{{range .}}<h2>{{.Title}}</h2>
+<table>
+<tr><th>{{.Column}}</th><th>Calls</th><th>Time</th></tr>
+{{range .Entries}}<tr><td>{{.Name}}</td><td>{{.Calls}}</td><td>{{.Time}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// EnableMutatorProfile makes the context record the time spent in each mutator. It must be called
+// before the mutators are registered.
+func (ctx *Context) EnableMutatorProfile() {
+	ctx.mutatorProfile = newMutatorProfile()
+}
+
+// WriteMutatorProfile writes the HTML report of the mutator profile, with the mutators and the
+// packages sorted from the slowest to the fastest.
+func (ctx *Context) WriteMutatorProfile(w io.Writer) error {
+	if ctx.mutatorProfile == nil {
+		return fmt.Errorf("the mutator profile is not enabled")
+	}
+	p := ctx.mutatorProfile
+	p.Lock()
+	defer p.Unlock()
+	return mutatorProfileTemplate.Execute(w, []struct {
+		Title   string
+		Column  string
+		Entries []mutatorProfileEntry
+	}{
+		{"Mutators", "Mutator", sortedMutatorProfileEntries(p.mutators)},
+		{"Packages", "Package", sortedMutatorProfileEntries(p.packages)},
+	})
+}
+
+// MutatorProfileSummary returns the n slowest mutators and packages of the mutator profile.
+func (ctx *Context) MutatorProfileSummary(n int) string {
+	if ctx.mutatorProfile == nil {
+		return ""
+	}
+	p := ctx.mutatorProfile
+	p.Lock()
+	defer p.Unlock()
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "Slowest mutators:\n")
+	for _, entry := range topMutatorProfileEntries(p.mutators, n) {
+		fmt.Fprintf(sb, "  %-40s %8d calls %12s\n", entry.Name, entry.Calls, entry.Time)
+	}
+	fmt.Fprintf(sb, "Slowest packages:\n")
+	for _, entry := range topMutatorProfileEntries(p.packages, n) {
+		fmt.Fprintf(sb, "  %-40s %8d calls %12s\n", entry.Name, entry.Calls, entry.Time)
+	}
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMutatorProfile(t *testing.T) {
+	result := GroupFixturePreparers(
+		FixtureModifyContext(func(ctx *TestContext) {
+			ctx.EnableMutatorProfile()
+		}),
+		FixtureRegisterWithContext(func(ctx RegistrationContext) {
+			ctx.RegisterModuleType("test", mutatorTestModuleFactory)
+			ctx.PreDepsMutators(func(ctx RegisterMutatorsContext) {
+				ctx.BottomUp("slow_mutator", func(ctx BottomUpMutatorContext) {
+					time.Sleep(time.Millisecond)
+				})
+				ctx.TopDown("fast_mutator", func(ctx TopDownMutatorContext) {})
+				ctx.Transition("transition_mutator", mutatorProfileTestTransitionMutator{})
+			})
+		}),
+		FixtureAddTextFile("foo/Android.bp", `test { name: "foo" }`),
+		FixtureAddTextFile("bar/Android.bp", `test { name: "bar" }`),
+	).RunTest(t)
+
+	summary := result.MutatorProfileSummary(1)
+	AssertStringDoesContain(t, "slowest mutator", summary, "slow_mutator")
+	AssertStringDoesNotContain(t, "slowest mutator", summary, "fast_mutator")
+
+	mutators := sortedMutatorProfileEntries(result.mutatorProfile.mutators)
+	var mutatorNames []string
+	for _, entry := range mutators {
+		mutatorNames = append(mutatorNames, entry.Name)
+		if entry.Name == "slow_mutator" {
+			AssertIntEquals(t, "slow_mutator calls", 2, entry.Calls)
+		}
+	}
+	AssertStringListContains(t, "mutators", mutatorNames, "transition_mutator")
+	packages := sortedMutatorProfileEntries(result.mutatorProfile.packages)
+	var names []string
+	for _, entry := range packages {
+		names = append(names, entry.Name)
+	}
+	AssertStringListContains(t, "packages", names, "foo")
+	AssertStringListContains(t, "packages", names, "bar")
+
+	report := &strings.Builder{}
+	if err := result.WriteMutatorProfile(report); err != nil {
+		t.Fatal(err)
+	}
+	AssertStringDoesContain(t, "report", report.String(), "<td>slow_mutator</td><td>2</td>")
+}
+
+type mutatorProfileTestTransitionMutator struct{}
+
+func (mutatorProfileTestTransitionMutator) Split(ctx BaseModuleContext) []string {
+	return []string{""}
+}
+
+func (mutatorProfileTestTransitionMutator) OutgoingTransition(ctx OutgoingTransitionContext, sourceVariation string) string {
+	return sourceVariation
+}
+
+func (mutatorProfileTestTransitionMutator) IncomingTransition(ctx IncomingTransitionContext, incomingVariation string) string {
+	return incomingVariation
+}
+
+func (mutatorProfileTestTransitionMutator) Mutate(ctx BottomUpMutatorContext, variation string) {
+}
@@ -58,6 +58,16 @@ func (p *PackagingSpec) RelPathInPackage() string {
 	return p.relPathInPackage
 }
 
+// The path to the built artifact that is installed. It is nil for symlinks.
+func (p *PackagingSpec) SrcPath() Path {
+	return p.srcPath
+}
+
+// If this is not empty, the packaged file is a symlink to this target.
+func (p *PackagingSpec) SymlinkTarget() string {
+	return p.symlinkTarget
+}
+
 func (p *PackagingSpec) SetRelPathInPackage(relPathInPackage string) {
 	p.relPathInPackage = relPathInPackage
 }
@@ -398,7 +398,7 @@ func ExistentPathsForSources(ctx PathGlobContext, paths []string) Paths {
 // `android:"path"` so that dependencies on SourceFileProducer modules will have already been handled by the
 // path_deps mutator.
 // If a requested module is not found as a dependency:
-//   - if ctx.Config().AllowMissingDependencies() is true, this module to be marked as having
+//   - if the module is allowed to have missing dependencies, this module to be marked as having
 //     missing dependencies
 //   - otherwise, a ModuleError is thrown.
 func PathsForModuleSrc(ctx ModuleMissingDepsPathContext, paths []string) Paths {
@@ -427,7 +427,7 @@ type SourceInput struct {
 // `android:"path"` so that dependencies on SourceFileProducer modules will have already been handled by the
 // path_deps mutator.
 // If a requested module is not found as a dependency:
-//   - if ctx.Config().AllowMissingDependencies() is true, this module to be marked as having
+//   - if the module is allowed to have missing dependencies, this module to be marked as having
 //     missing dependencies
 //   - otherwise, a ModuleError is thrown.
 func PathsForModuleSrcExcludes(ctx ModuleMissingDepsPathContext, paths, excludes []string) Paths {
@@ -439,9 +439,19 @@ func PathsForModuleSrcExcludes(ctx ModuleMissingDepsPathContext, paths, excludes
 	})
 }
 
+// AllowMissingDependencies returns true if the module of the context, if any, is allowed to have
+// missing dependencies, see EarlyModuleContext.AllowMissingDependencies. For the other contexts it
+// returns Config.AllowMissingDependencies.
+func AllowMissingDependencies(ctx PathContext) bool {
+	if mctx, ok := ctx.(interface{ AllowMissingDependencies() bool }); ok {
+		return mctx.AllowMissingDependencies()
+	}
+	return ctx.Config().AllowMissingDependencies()
+}
+
 func PathsRelativeToModuleSourceDir(input SourceInput) Paths {
 	ret, missingDeps := PathsAndMissingDepsRelativeToModuleSourceDir(input)
-	if input.Context.Config().AllowMissingDependencies() {
+	if AllowMissingDependencies(input.Context) {
 		input.Context.AddMissingDependencies(missingDeps)
 	} else {
 		for _, m := range missingDeps {
@@ -499,7 +509,7 @@ func getPathsFromModuleDep(ctx ModuleWithDepsPathContext, path, moduleName, tag
 	if outProducer, ok := module.(OutputFileProducer); ok {
 		outputFiles, err := outProducer.OutputFiles(tag)
 		if err != nil {
-			return nil, fmt.Errorf("path dependency %q: %s", path, err)
+			return nil, fmt.Errorf("path dependency %q: %s%s", path, err, supportedOutputFileTags(module, tag))
 		}
 		return outputFiles, nil
 	} else if tag != "" {
@@ -1110,7 +1120,7 @@ func PathForSource(ctx PathContext, pathComponents ...string) SourcePath {
 		ReportPathErrorf(ctx, "path may not contain a glob: %s", path.String())
 	}
 
-	if modCtx, ok := ctx.(ModuleMissingDepsPathContext); ok && ctx.Config().AllowMissingDependencies() {
+	if modCtx, ok := ctx.(ModuleMissingDepsPathContext); ok && AllowMissingDependencies(ctx) {
 		exists, err := existsWithDependencies(modCtx, path)
 		if err != nil {
 			reportPathError(ctx, err)
@@ -1371,7 +1381,7 @@ func PathForModuleSrc(ctx ModuleMissingDepsPathContext, pathComponents ...string
 	paths, err := expandOneSrcPath(sourcePathInput{context: ctx, path: p, includeDirs: true})
 	if err != nil {
 		if depErr, ok := err.(missingDependencyError); ok {
-			if ctx.Config().AllowMissingDependencies() {
+			if AllowMissingDependencies(ctx) {
 				ctx.AddMissingDependencies(depErr.missingDeps)
 			} else {
 				ctx.ModuleErrorf(`%s, is the property annotated with android:"path"?`, depErr.Error())
@@ -1715,8 +1725,9 @@ func pathForInstall(ctx PathContext, os OsType, arch ArchType, partition string,
 		partitionPaths = []string{"target", "product", ctx.Config().DeviceName(), partition}
 	} else {
 		osName := os.String()
-		if os == Linux {
-			// instead of linux_glibc
+		if os == Linux && !ctx.Config().UseHostMusl() {
+			// instead of linux_glibc.  When using musl, glibc is only built as an additional host test
+			// runtime and keeps "linux_glibc" so that it does not collide with musl.
 			osName = "linux"
 		}
 		if os == LinuxMusl && ctx.Config().UseHostMusl() {
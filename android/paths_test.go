@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
 	"testing"
@@ -738,6 +739,44 @@ func TestPathForModuleInstall(t *testing.T) {
 	}
 }
 
+func TestPathForModuleInstallHostTestRuntimes(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("host test runtimes are only supported on linux hosts")
+	}
+
+	testConfig := pathTestConfig("")
+	testConfig.TestProductVariables.HostArch = proptools.StringPtr("x86_64")
+	testConfig.TestProductVariables.HostMusl = proptools.BoolPtr(true)
+	testConfig.TestProductVariables.HostTestRuntimes = []string{"glibc"}
+	determineBuildOS(testConfig.config)
+	targets, err := decodeTargetProductVariables(testConfig.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The glibc runtime is added as a HostCross target of the musl host, and is installed in its own
+	// directory so that it does not overwrite the musl host modules.
+	expected := map[OsType]string{
+		LinuxMusl: "host/linux-x86/bin/my_test",
+		Linux:     "host/linux_glibc-x86/bin/my_test",
+	}
+	for _, os := range []OsType{LinuxMusl, Linux} {
+		AssertIntEquals(t, os.String()+" targets", 1, len(targets[os]))
+		target := targets[os][0]
+		AssertBoolEquals(t, os.String()+" HostCross", os == Linux, target.HostCross)
+
+		ctx := &testModuleInstallPathContext{
+			baseModuleContext: baseModuleContext{
+				os:     target.Os,
+				target: target,
+			},
+		}
+		ctx.baseModuleContext.config = testConfig
+		output := PathForModuleInstall(ctx, "bin", "my_test")
+		AssertStringEquals(t, os.String()+" install path", expected[os], output.basePath.path)
+	}
+}
+
 func TestPathForModuleInstallRecoveryAsBoot(t *testing.T) {
 	testConfig := pathTestConfig("")
 	testConfig.TestProductVariables.BoardUsesRecoveryAsBoot = proptools.BoolPtr(true)
@@ -156,10 +156,13 @@ func RegisterPreSingletonType(name string, factory SingletonFactory) {
 type Context struct {
 	*blueprint.Context
 	config Config
+
+	// The mutator profile, if enabled with EnableMutatorProfile.
+	mutatorProfile *mutatorProfile
 }
 
 func NewContext(config Config) *Context {
-	ctx := &Context{blueprint.NewContext(), config}
+	ctx := &Context{Context: blueprint.NewContext(), config: config}
 	ctx.SetSrcDir(absSrcDir)
 	ctx.AddIncludeTags(config.IncludeTags()...)
 	ctx.AddSourceRootDirs(config.SourceRootDirs()...)
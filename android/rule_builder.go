@@ -55,8 +55,10 @@ type RuleBuilder struct {
 	outDir           WritablePath
 	sboxTools        bool
 	sboxInputs       bool
+	nsjail           bool
 	sboxManifestPath WritablePath
 	missingDeps      []string
+	ninjaVariables   []string
 }
 
 // NewRuleBuilder returns a newly created RuleBuilder.
@@ -131,6 +133,17 @@ func (r *RuleBuilder) Rewrapper(params *remoteexec.REParams) *RuleBuilder {
 	return r
 }
 
+// NinjaVariable returns a reference to the ninja variable with the given name, e.g.
+// "config.REJavaPool", that is not escaped in the command line of the rule, so that ninja expands
+// it.  The package of the variable must be imported by the PackageContext passed to
+// NewRuleBuilder.  The reference is only expanded in the ninja command line of the rule, so it
+// cannot be used in the commands of rules that use sbox, except in the params passed to Rewrapper.
+func (r *RuleBuilder) NinjaVariable(name string) string {
+	ref := "${" + name + "}"
+	r.ninjaVariables = append(r.ninjaVariables, ref)
+	return ref
+}
+
 // Sbox marks the rule as needing to be wrapped by sbox. The outputDir should point to the output
 // directory that sbox will wipe. It should not be written to by any other rule. manifestPath should
 // point to a location where sbox's manifest will be written and must be outside outputDir. sbox
@@ -181,6 +194,23 @@ func (r *RuleBuilder) SandboxInputs() *RuleBuilder {
 	return r
 }
 
+// Nsjail runs the command of the rule inside nsjail, in addition to sandboxing its inputs with
+// sbox.  The command can only access the sbox sandbox directory, which contains the tools and
+// inputs declared to the RuleBuilder, and the read-only system directories that are needed to run
+// host tools.  It has no access to the rest of the source and output directories, nor to the
+// network, so the rule is hermetic and its outputs can be cached remotely, e.g. with Rewrapper.
+// It must be called after SandboxInputs().
+func (r *RuleBuilder) Nsjail() *RuleBuilder {
+	if !r.sboxInputs {
+		panic("Nsjail() must be called after SandboxInputs()")
+	}
+	if len(r.commands) > 0 {
+		panic("Nsjail() may not be called after Command()")
+	}
+	r.nsjail = true
+	return r
+}
+
 // Install associates an output of the rule with an install location, which can be retrieved later using
 // RuleBuilder.Installs.
 func (r *RuleBuilder) Install(from Path, to string) {
@@ -515,7 +545,19 @@ func (r *RuleBuilder) Build(name string, desc string) {
 
 	commandString := strings.Join(commands, " && ")
 
+	if r.ctx.Config().RuleProvenanceEnabled() {
+		r.recordProvenance(name, commands, tools, outputs)
+	}
+
 	if r.sbox {
+		if r.nsjail {
+			// The nsjail binary is copied into the sandbox like the other tools of the rule.
+			nsjail := r.ctx.Config().PrebuiltBuildTool(r.ctx, "nsjail")
+			tools = append(tools, nsjail)
+			commandString = nsjailCommand(filepath.Join(sboxSandboxBaseDir, sboxPathForToolRel(r.ctx, nsjail)),
+				sandboxedNsjailFlags, commandString)
+		}
+
 		// If running the command inside sbox, write the rule data out to an sbox
 		// manifest.textproto.
 		manifest := sbox_proto.Manifest{}
@@ -676,6 +718,12 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		commandString += " # hash of input list: " + hashSrcFiles(inputs)
 	}
 
+	if r.noNetwork(name) {
+		nsjail := r.ctx.Config().PrebuiltBuildTool(r.ctx, "nsjail")
+		tools = append(tools, nsjail)
+		commandString = noNetworkCommand(nsjail.String(), r.noNetworkOwner(name), commandString)
+	}
+
 	// Ninja doesn't like multiple outputs when depfiles are enabled, move all but the first output to
 	// ImplicitOutputs.  RuleBuilder doesn't use "$out", so the distinction between Outputs and
 	// ImplicitOutputs doesn't matter.
@@ -715,9 +763,20 @@ func (r *RuleBuilder) Build(name string, desc string) {
 		pool = localPool
 	}
 
+	command := proptools.NinjaEscape(commandString)
+	rulePctx := pctx
+	if len(r.ninjaVariables) > 0 {
+		// Undo the escaping of the references to ninja variables, and define the rule in the package
+		// that imports the packages of the variables.
+		for _, ref := range FirstUniqueStrings(r.ninjaVariables) {
+			command = strings.ReplaceAll(command, proptools.NinjaEscape(ref), ref)
+		}
+		rulePctx = r.pctx
+	}
+
 	r.ctx.Build(r.pctx, BuildParams{
-		Rule: r.ctx.Rule(pctx, name, blueprint.RuleParams{
-			Command:        proptools.NinjaEscape(commandString),
+		Rule: r.ctx.Rule(rulePctx, name, blueprint.RuleParams{
+			Command:        command,
 			CommandDeps:    proptools.NinjaEscapeList(tools.Strings()),
 			Restat:         r.restat,
 			Rspfile:        proptools.NinjaEscape(rspFile),
@@ -824,6 +883,37 @@ func (c *RuleBuilderCommand) PathForOutput(path WritablePath) string {
 	return path.String()
 }
 
+// sandboxedNsjailFlags are the nsjail flags of the rules that run their command inside nsjail.  sbox
+// runs the command in the sandbox directory, which is mounted read-write at the same path inside
+// nsjail and is the only directory of the build that the command can access.
+var sandboxedNsjailFlags = []string{
+	`-B "$PWD"`,
+	`--cwd "$PWD"`,
+	// These directories are needed to run host tools like /bin/bash.
+	"-R /bin",
+	"-R /lib",
+	"-R /lib64",
+	"-R /usr",
+	"-R /dev",
+	"-m none:/tmp:tmpfs:size=1073741824",
+}
+
+// nsjailCommand returns the command line that runs command with bash inside nsjail, with the
+// given nsjail flags that set up the file system of the jail.  It is shared by the rules that run
+// their command inside nsjail and by the rules that run without network access.
+func nsjailCommand(nsjail string, flags []string, command string) string {
+	args := append([]string{nsjail}, flags...)
+	args = append(args,
+		// nsjail kills the command after 600 seconds by default.
+		"--time_limit 0",
+		"--disable_rlimits",
+		"--skip_setsid",
+		"-q",
+		"--",
+		"/bin/bash -c "+proptools.ShellEscape(command))
+	return strings.Join(args, " ")
+}
+
 func sboxPathForToolRel(ctx BuilderContext, path Path) string {
 	// Errors will be handled in RuleBuilder.Build where we have a context to report them
 	toolDir := pathForInstall(ctx, ctx.Config().BuildOS, ctx.Config().BuildArch, "", false)
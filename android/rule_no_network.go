@@ -0,0 +1,104 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+)
+
+// With SOONG_ENFORCE_NO_NETWORK=true, RuleBuilder runs the commands of the actions in nsjail with
+// a network namespace that only has the loopback interface, so that the tools that download
+// artifacts at build time fail instead of making the build depend on the network. When an action
+// fails in that mode with one of the errors of noNetworkErrors, it prints the module that built it,
+// as the tools usually don't report that they failed to access the network.
+//
+// The actions of the modules, or of the singletons, listed in noNetworkAllowlist, and the actions
+// that are executed remotely, keep access to the network.
+
+// noNetworkAllowlist is the names of the modules, or of the rules of the singletons, whose actions
+// can access the network. Each entry must have a bug to remove it.
+var noNetworkAllowlist = []string{}
+
+// noNetworkErrors are the messages of the failures to access the network, from the C library and
+// the common tools and runtimes.
+var noNetworkErrors = []string{
+	"Network is unreachable",
+	"Temporary failure in name resolution",
+	"Name or service not known",
+	"No address associated with hostname",
+	"Could not resolve host",
+	"UnknownHostException",
+	"getaddrinfo",
+}
+
+// NoNetworkEnforced returns whether RuleBuilder runs the actions without network access.
+func (c *config) NoNetworkEnforced() bool {
+	return c.IsEnvTrue("SOONG_ENFORCE_NO_NETWORK") && c.BuildOS == Linux
+}
+
+// noNetworkOwner returns the name of the module that builds the rule, or the name of the rule if
+// it is built by a singleton.
+func (r *RuleBuilder) noNetworkOwner(name string) string {
+	if m, ok := r.ctx.(interface{ ModuleName() string }); ok {
+		return m.ModuleName()
+	}
+	return name
+}
+
+// noNetwork returns whether the action of the rule runs without network access.
+func (r *RuleBuilder) noNetwork(name string) bool {
+	config := r.ctx.Config()
+	if !config.NoNetworkEnforced() {
+		return false
+	}
+	// nsjail already runs the command in a network namespace.
+	if r.nsjail {
+		return false
+	}
+	// The remote actions need the network to reach the remote execution service.
+	if r.rbeParams != nil || (config.UseGoma() && r.remoteable.Goma) || (config.UseRBE() && r.remoteable.RBE) {
+		return false
+	}
+	return !InList(r.noNetworkOwner(name), noNetworkAllowlist)
+}
+
+// noNetworkCommand returns the command line that runs command with the nsjail binary, in a new
+// network namespace but with the same file system. The standard error of the command is kept in a
+// temporary file, so that the owner of the action is reported if it fails with one of the errors of
+// noNetworkErrors.
+func noNetworkCommand(nsjail string, owner string, command string) string {
+	violation := fmt.Sprintf("%s: the action failed without network access, "+
+		"if it needs the network add %s to noNetworkAllowlist in build/soong/android/rule_no_network.go",
+		owner, owner)
+	flags := []string{
+		"--chroot /",
+		"--rw",
+		`--cwd "$PWD"`,
+		"--keep_env",
+	}
+	grep := []string{"grep", "-q", "-F"}
+	for _, e := range noNetworkErrors {
+		grep = append(grep, "-e", proptools.ShellEscape(e))
+	}
+	return `log=$(mktemp) && ` +
+		nsjailCommand(nsjail, flags, command) + ` 2>"$log"; ` +
+		`status=$?; cat "$log" >&2; ` +
+		`if [ $status -ne 0 ] && ` + strings.Join(grep, " ") + ` "$log"; then ` +
+		`echo ` + proptools.ShellEscape(violation) + ` >&2; fi; ` +
+		`rm -f "$log"; exit $status`
+}
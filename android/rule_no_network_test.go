@@ -0,0 +1,71 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+func TestRuleNoNetwork(t *testing.T) {
+	bp := `
+		rule_builder_test {
+			name: "foo",
+			srcs: ["in"],
+		}
+
+		rule_builder_test {
+			name: "foo_allowed",
+			srcs: ["in"],
+		}
+	`
+
+	savedAllowlist := noNetworkAllowlist
+	defer func() { noNetworkAllowlist = savedAllowlist }()
+	noNetworkAllowlist = []string{"foo_allowed"}
+
+	for _, enforced := range []bool{false, true} {
+		preparers := []FixturePreparer{
+			prepareForRuleBuilderTest,
+			FixtureWithRootAndroidBp(bp),
+			MockFS{"in": nil, "cp": nil}.AddToFixture(),
+		}
+		if enforced {
+			preparers = append(preparers, FixtureMergeEnv(map[string]string{"SOONG_ENFORCE_NO_NETWORK": "true"}))
+		}
+		result := GroupFixturePreparers(preparers...).RunTest(t)
+		if result.Config.BuildOS != Linux {
+			t.Skip("the actions run without network access on Linux only")
+		}
+
+		nsjail := "prebuilts/build-tools/linux-x86/bin/nsjail"
+		foo := result.ModuleForTests("foo", "").Rule("rule")
+		allowed := result.ModuleForTests("foo_allowed", "").Rule("rule")
+		AssertStringDoesNotContain(t, "allowed module runs in nsjail", allowed.RuleParams.Command, nsjail)
+
+		if !enforced {
+			AssertStringDoesNotContain(t, "module runs in nsjail without SOONG_ENFORCE_NO_NETWORK",
+				foo.RuleParams.Command, nsjail)
+			continue
+		}
+		AssertStringDoesContain(t, "module runs in nsjail", foo.RuleParams.Command, nsjail+" --chroot /")
+		AssertStringListContains(t, "command deps", foo.RuleParams.CommandDeps, nsjail)
+		// The owner of the action is only reported for the failures to access the network.
+		AssertStringDoesContain(t, "command", foo.RuleParams.Command,
+			`if [ $$status -ne 0 ] && grep -q -F -e 'Network is unreachable'`)
+		AssertStringDoesContain(t, "command", foo.RuleParams.Command,
+			"then echo 'foo: the action failed without network access, if it needs the network add foo to noNetworkAllowlist")
+		AssertStringDoesContain(t, "command", foo.RuleParams.Command, `exit $$status`)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// With SOONG_RULE_PROVENANCE=true, RuleBuilder records the tools and the sanitized environment of
+// each action that it builds. The rule_provenance singleton writes the records of the actions of
+// the modules and of the singletons registered before it to $OUT_DIR/soong/rule_provenance.json,
+// and adds a slsa_provenance goal that aggregates them into an SLSA provenance document for the
+// files installed on the device, with the digests of the files and of the tools computed when the
+// document is built.
+
+func init() {
+	registerRuleProvenanceBuildComponents(InitRegistrationContext)
+}
+
+func registerRuleProvenanceBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("rule_provenance", ruleProvenanceSingletonFactory)
+}
+
+const ruleProvenanceFileName = "rule_provenance.json"
+
+// RuleProvenance is the provenance of an action built by RuleBuilder.
+type RuleProvenance struct {
+	// The name of the rule, and the name of the module that built it if any.
+	Rule   string
+	Module string `json:",omitempty"`
+
+	// The outputs of the action.
+	Outputs []string
+
+	// The tools run by the action.
+	Tools []string `json:",omitempty"`
+
+	// The environment variables set by the commands of the action, with the values of the
+	// variables that may contain credentials redacted.
+	Env map[string]string `json:",omitempty"`
+}
+
+// RuleProvenanceFile is the content of rule_provenance.json.
+type RuleProvenanceFile struct {
+	// The parameters of the build, e.g. the product and the build id.
+	Parameters map[string]string
+
+	Actions []RuleProvenance
+}
+
+// RuleProvenanceEnabled returns whether RuleBuilder records the provenance of the actions.
+func (c *config) RuleProvenanceEnabled() bool {
+	return c.IsEnvTrue("SOONG_RULE_PROVENANCE")
+}
+
+type ruleProvenanceRecords struct {
+	sync.Mutex
+	records []RuleProvenance
+
+	// The tools run by the actions, which gen_slsa_provenance hashes.
+	tools Paths
+}
+
+var ruleProvenanceRecordsKey = NewOnceKey("ruleProvenanceRecords")
+
+func getRuleProvenanceRecords(config Config) *ruleProvenanceRecords {
+	return config.Once(ruleProvenanceRecordsKey, func() interface{} {
+		return &ruleProvenanceRecords{}
+	}).(*ruleProvenanceRecords)
+}
+
+var (
+	envAssignmentRegexp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	sensitiveEnvRegexp  = regexp.MustCompile(`(?i)(token|secret|password|passwd|credential|cookie|_key$)`)
+)
+
+// ruleProvenanceEnv returns the environment variables assigned at the start of the commands, with
+// the values of the variables that may contain credentials redacted.
+func ruleProvenanceEnv(commands []string) map[string]string {
+	env := make(map[string]string)
+	for _, command := range commands {
+		for _, field := range strings.Fields(command) {
+			match := envAssignmentRegexp.FindStringSubmatch(field)
+			if match == nil {
+				break
+			}
+			name, value := match[1], match[2]
+			if sensitiveEnvRegexp.MatchString(name) {
+				value = "<redacted>"
+			}
+			env[name] = value
+		}
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return env
+}
+
+// recordProvenance records the provenance of the action built by the RuleBuilder.
+func (r *RuleBuilder) recordProvenance(name string, commands []string, tools Paths, outputs WritablePaths) {
+	record := RuleProvenance{
+		Rule:    name,
+		Outputs: outputs.Strings(),
+		Tools:   tools.Strings(),
+		Env:     ruleProvenanceEnv(commands),
+	}
+	if m, ok := r.ctx.(interface{ ModuleName() string }); ok {
+		record.Module = m.ModuleName()
+	}
+
+	records := getRuleProvenanceRecords(r.ctx.Config())
+	records.Lock()
+	defer records.Unlock()
+	records.records = append(records.records, record)
+	records.tools = append(records.tools, tools...)
+}
+
+func ruleProvenanceSingletonFactory() Singleton {
+	return &ruleProvenanceSingleton{}
+}
+
+type ruleProvenanceSingleton struct{}
+
+func (s *ruleProvenanceSingleton) GenerateBuildActions(ctx SingletonContext) {
+	if !ctx.Config().RuleProvenanceEnabled() {
+		return
+	}
+
+	// The files installed on the device are the subjects of the provenance document.
+	var subjects Paths
+	ctx.VisitAllModules(func(module Module) {
+		if !module.Enabled() || module.Target().Os.Class != Device {
+			return
+		}
+		for _, installed := range module.FilesToInstall() {
+			if installed.Partition() != "" {
+				subjects = append(subjects, installed)
+			}
+		}
+	})
+
+	records := getRuleProvenanceRecords(ctx.Config())
+	records.Lock()
+	actions := append([]RuleProvenance(nil), records.records...)
+	tools := SortedUniquePaths(records.tools)
+	records.Unlock()
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].Outputs[0] < actions[j].Outputs[0] })
+
+	path := PathForOutput(ctx, ruleProvenanceFileName)
+	err := writeRuleProvenance(RuleProvenanceFile{
+		Parameters: map[string]string{
+			"product":  ctx.Config().DeviceProduct(),
+			"device":   ctx.Config().DeviceName(),
+			"build_id": ctx.Config().BuildId(),
+		},
+		Actions: actions,
+	}, path)
+	if err != nil {
+		ctx.Errorf(err.Error())
+	}
+
+	// This is necessary to satisfy the dangling rules check as this file is written by Soong rather than a rule.
+	ctx.Build(pctx, BuildParams{
+		Rule:   Touch,
+		Output: path,
+	})
+
+	provenance := PathForOutput(ctx, "slsa_provenance.json")
+	rule := NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("gen_slsa_provenance").
+		FlagWithInput("--actions ", path).
+		FlagWithOutput("--output ", provenance).
+		FlagWithRspFileInputList("--subjects ", PathForOutput(ctx, "slsa_provenance.rsp"), SortedUniquePaths(subjects)).
+		// The digests of the tools are part of the document, so they must be built before it.
+		Implicits(tools)
+	rule.Build("slsa_provenance", "generate SLSA provenance")
+
+	ctx.Phony("slsa_provenance", provenance)
+}
+
+func writeRuleProvenance(provenance RuleProvenanceFile, path WritablePath) error {
+	buf, err := json.MarshalIndent(provenance, "", "\t")
+	if err != nil {
+		return fmt.Errorf("JSON marshal of rule provenance failed: %s", err)
+	}
+	if err := WriteFileToOutputDir(path, buf, 0666); err != nil {
+		return fmt.Errorf("Writing rule provenance to %s failed: %s", path.String(), err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleProvenanceEnv(t *testing.T) {
+	env := ruleProvenanceEnv([]string{
+		"FOO=1 BAR=b tool FOO=2",
+		"rm -f out",
+		"API_TOKEN=abc GITHUB_KEY=def PATH=/bin tool",
+	})
+	expected := map[string]string{
+		"FOO":        "1",
+		"BAR":        "b",
+		"API_TOKEN":  "<redacted>",
+		"GITHUB_KEY": "<redacted>",
+		"PATH":       "/bin",
+	}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("expected %q, got %q", expected, env)
+	}
+
+	if env := ruleProvenanceEnv([]string{"tool FOO=1"}); env != nil {
+		t.Errorf("expected no environment, got %q", env)
+	}
+}
+
+func TestRuleProvenance(t *testing.T) {
+	bp := `
+		rule_builder_test {
+			name: "foo",
+			srcs: ["in"],
+		}
+	`
+
+	for _, enabled := range []bool{false, true} {
+		preparers := []FixturePreparer{
+			prepareForRuleBuilderTest,
+			FixtureRegisterWithContext(registerRuleProvenanceBuildComponents),
+			FixtureWithRootAndroidBp(bp),
+			MockFS{"in": nil, "cp": nil}.AddToFixture(),
+		}
+		if enabled {
+			preparers = append(preparers, FixtureMergeEnv(map[string]string{"SOONG_RULE_PROVENANCE": "true"}))
+		}
+		result := GroupFixturePreparers(preparers...).RunTest(t)
+
+		var records []RuleProvenance
+		for _, record := range getRuleProvenanceRecords(result.Config).records {
+			if record.Module == "foo" {
+				records = append(records, record)
+			}
+		}
+
+		if !enabled {
+			AssertIntEquals(t, "records without SOONG_RULE_PROVENANCE", 0, len(records))
+			continue
+		}
+		AssertIntEquals(t, "records", 1, len(records))
+		AssertStringEquals(t, "rule", "rule", records[0].Rule)
+		AssertArrayString(t, "tools", []string{"cp"}, records[0].Tools)
+		AssertArrayString(t, "outputs", []string{"out/soong/.intermediates/foo/gen/foo"},
+			StringPathsRelativeToTop(result.Config.SoongOutDir(), records[0].Outputs))
+
+		// The tools are hashed in the provenance document, so they are inputs of the rule.
+		rule := result.SingletonForTests("rule_provenance").Rule("slsa_provenance")
+		AssertPathsRelativeToTopEquals(t, "implicits", []string{"cp", "out/soong/rule_provenance.json"},
+			rule.Implicits)
+	}
+}
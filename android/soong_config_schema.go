@@ -0,0 +1,373 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+// This file provides the soong_config_schema module type, which declares the Soong config
+// variables of a tree.
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	RegisterSoongConfigSchemaBuildComponents(InitRegistrationContext)
+}
+
+func RegisterSoongConfigSchemaBuildComponents(ctx RegistrationContext) {
+	ctx.RegisterModuleType("soong_config_schema", SoongConfigSchemaFactory)
+	ctx.RegisterSingletonType("soong_config_schema", soongConfigSchemaSingletonFactory)
+}
+
+var PrepareForTestWithSoongConfigSchemaBuildComponents = FixtureRegisterWithContext(RegisterSoongConfigSchemaBuildComponents)
+
+const (
+	soongConfigBoolType   = "bool"
+	soongConfigStringType = "string"
+	soongConfigValueType  = "value"
+)
+
+// soongConfigBoolValues are the values of a bool Soong config variable, in lower case. The empty
+// string is the value of a variable that is set without a value.
+var soongConfigBoolValues = []string{"", "0", "1", "n", "y", "no", "yes", "off", "on", "false", "true"}
+
+type soongConfigSchemaProperties struct {
+	// The Soong config namespaces declared by the schema.
+	Namespaces []soongConfigSchemaNamespaceProperties
+}
+
+type soongConfigSchemaNamespaceProperties struct {
+	// The name of the namespace, as in SOONG_CONFIG_NAMESPACES.
+	Name *string
+
+	// A description of the namespace for the documentation.
+	Description *string
+
+	// The variables of the namespace.
+	Variables []soongConfigSchemaVariableProperties
+}
+
+type soongConfigSchemaVariableProperties struct {
+	// The name of the variable.
+	Name *string
+
+	// The type of the variable: "bool" for the bool_variables of soong_config_module_type,
+	// "string" for the variables with a fixed set of values, or "value" for the value_variables.
+	// Defaults to "string".
+	Type *string
+
+	// The values of a string variable.
+	Values []string
+
+	// The value of the variable when the product does not set it, for the documentation.
+	Default *string
+
+	// A description of the variable for the documentation.
+	Description *string
+}
+
+type soongConfigSchemaModule struct {
+	ModuleBase
+
+	properties soongConfigSchemaProperties
+
+	// The declared variables by namespace, only set when the schema is valid.
+	namespaces map[string]map[string]*soongConfigSchemaVariableProperties
+
+	docs OutputPath
+}
+
+// soong_config_schema declares the Soong config namespaces of a tree with the names, types and
+// values of their variables. Once a namespace is declared by a schema, the build fails when a
+// soong_config_module_type, or the product configuration, uses a variable of the namespace that
+// the schema does not declare, or with a different type or value, including the values of the
+// soong_config_string_variable modules used by the module types. The namespaces that are not
+// declared by any schema are not checked. The schema also generates the documentation of the
+// variables in <name>.md.
+//
+// For example, an Android.bp file could have:
+//
+//	soong_config_schema {
+//	    name: "acme_soong_config_schema",
+//	    namespaces: [
+//	        {
+//	            name: "acme",
+//	            description: "Configuration of the acme boards.",
+//	            variables: [
+//	                {
+//	                    name: "board",
+//	                    values: ["soc_a", "soc_b"],
+//	                    default: "soc_a",
+//	                    description: "The SoC of the board.",
+//	                },
+//	                {
+//	                    name: "feature",
+//	                    type: "bool",
+//	                    default: "false",
+//	                },
+//	                {
+//	                    name: "width",
+//	                    type: "value",
+//	                },
+//	            ],
+//	        },
+//	    ],
+//	}
+func SoongConfigSchemaFactory() Module {
+	module := &soongConfigSchemaModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+func (m *soongConfigSchemaModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	namespaces := make(map[string]map[string]*soongConfigSchemaVariableProperties)
+	for i := range m.properties.Namespaces {
+		namespace := &m.properties.Namespaces[i]
+		name := proptools.String(namespace.Name)
+		if name == "" {
+			ctx.PropertyErrorf("namespaces", "namespace name must be set")
+			continue
+		}
+		if _, exists := namespaces[name]; exists {
+			ctx.PropertyErrorf("namespaces", "duplicate namespace %q", name)
+			continue
+		}
+		variables := make(map[string]*soongConfigSchemaVariableProperties)
+		for j := range namespace.Variables {
+			variable := &namespace.Variables[j]
+			if err := checkSoongConfigSchemaVariable(variable); err != nil {
+				ctx.PropertyErrorf("namespaces", "namespace %q: %s", name, err)
+				continue
+			}
+			if _, exists := variables[*variable.Name]; exists {
+				ctx.PropertyErrorf("namespaces", "namespace %q: duplicate variable %q", name, *variable.Name)
+				continue
+			}
+			variables[*variable.Name] = variable
+		}
+		namespaces[name] = variables
+	}
+	if ctx.Failed() {
+		return
+	}
+	m.namespaces = namespaces
+
+	m.docs = PathForModuleOut(ctx, ctx.ModuleName()+".md").OutputPath
+	WriteFileRule(ctx, m.docs, m.documentation())
+}
+
+// checkSoongConfigSchemaVariable returns an error if the declaration of the variable is invalid.
+func checkSoongConfigSchemaVariable(variable *soongConfigSchemaVariableProperties) error {
+	name := proptools.String(variable.Name)
+	if name == "" {
+		return fmt.Errorf("variable name must be set")
+	}
+	if name == "conditions_default" {
+		return fmt.Errorf("%q is a reserved variable name", name)
+	}
+	switch soongConfigSchemaType(variable) {
+	case soongConfigStringType:
+		if len(variable.Values) == 0 {
+			return fmt.Errorf("string variable %q must set values", name)
+		}
+		if dup := FirstUniqueStrings(variable.Values); len(dup) != len(variable.Values) {
+			return fmt.Errorf("string variable %q has duplicate values", name)
+		}
+	case soongConfigBoolType, soongConfigValueType:
+		if len(variable.Values) > 0 {
+			return fmt.Errorf("%s variable %q can not set values", soongConfigSchemaType(variable), name)
+		}
+	default:
+		return fmt.Errorf("variable %q has invalid type %q, expected %q, %q or %q", name,
+			soongConfigSchemaType(variable), soongConfigBoolType, soongConfigStringType, soongConfigValueType)
+	}
+	if variable.Default != nil {
+		if err := checkSoongConfigSchemaValue(variable, *variable.Default); err != nil {
+			return fmt.Errorf("invalid default of variable %q: %s", name, err)
+		}
+	}
+	return nil
+}
+
+func soongConfigSchemaType(variable *soongConfigSchemaVariableProperties) string {
+	return proptools.StringDefault(variable.Type, soongConfigStringType)
+}
+
+// checkSoongConfigSchemaValue returns an error if the value is not a value of the variable.
+func checkSoongConfigSchemaValue(variable *soongConfigSchemaVariableProperties, value string) error {
+	switch soongConfigSchemaType(variable) {
+	case soongConfigBoolType:
+		if !InList(strings.ToLower(value), soongConfigBoolValues) {
+			return fmt.Errorf("%q is not a bool", value)
+		}
+	case soongConfigStringType:
+		if value != "" && !InList(value, variable.Values) {
+			return fmt.Errorf("%q is not one of %q", value, variable.Values)
+		}
+	}
+	return nil
+}
+
+// documentation returns the documentation of the variables of the schema in markdown.
+func (m *soongConfigSchemaModule) documentation() string {
+	var sb strings.Builder
+	for i, namespace := range m.properties.Namespaces {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "# %s\n\n", *namespace.Name)
+		if namespace.Description != nil {
+			fmt.Fprintf(&sb, "%s\n\n", *namespace.Description)
+		}
+		sb.WriteString("| Variable | Type | Values | Default | Description |\n")
+		sb.WriteString("|---|---|---|---|---|\n")
+		for _, variable := range namespace.Variables {
+			fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n",
+				*variable.Name,
+				soongConfigSchemaType(&variable),
+				escapeMarkdownTableCell(strings.Join(variable.Values, ", ")),
+				escapeMarkdownTableCell(proptools.String(variable.Default)),
+				escapeMarkdownTableCell(proptools.String(variable.Description)))
+		}
+	}
+	return sb.String()
+}
+
+// escapeMarkdownTableCell escapes the pipes of a cell of a markdown table.
+func escapeMarkdownTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+var _ OutputFileProducer = (*soongConfigSchemaModule)(nil)
+
+// Implements OutputFileProducer
+func (m *soongConfigSchemaModule) OutputFiles(tag string) (Paths, error) {
+	if tag == "" {
+		return Paths{m.docs}, nil
+	}
+	return nil, fmt.Errorf("unrecognized tag %q", tag)
+}
+
+func soongConfigSchemaSingletonFactory() Singleton {
+	return &soongConfigSchemaSingleton{}
+}
+
+type soongConfigSchemaSingleton struct{}
+
+// GenerateBuildActions checks the soong_config_module_type modules and the Soong config variables
+// of the product against the namespaces declared by the soong_config_schema modules.
+func (s *soongConfigSchemaSingleton) GenerateBuildActions(ctx SingletonContext) {
+	schemas := make(map[string]*soongConfigSchemaModule)
+	var docs Paths
+	ctx.VisitAllModules(func(module Module) {
+		m, ok := module.(*soongConfigSchemaModule)
+		if !ok || m.namespaces == nil {
+			return
+		}
+		for namespace := range m.namespaces {
+			if other, exists := schemas[namespace]; exists {
+				ctx.ModuleErrorf(m, "namespace %q is already declared by %q", namespace, other.Name())
+				continue
+			}
+			schemas[namespace] = m
+		}
+		docs = append(docs, m.docs)
+	})
+	if len(schemas) == 0 {
+		return
+	}
+
+	// The soong_config_string_variable modules by Android.bp file, as the soong_config_module_type
+	// modules use the string variables of their file.
+	stringVariables := make(map[string]map[string]*soongConfigStringVariableDummyModule)
+	ctx.VisitAllModules(func(module Module) {
+		if m, ok := module.(*soongConfigStringVariableDummyModule); ok {
+			file := ctx.BlueprintFile(m)
+			if stringVariables[file] == nil {
+				stringVariables[file] = make(map[string]*soongConfigStringVariableDummyModule)
+			}
+			stringVariables[file][m.properties.Name] = m
+		}
+	})
+
+	ctx.VisitAllModules(func(module Module) {
+		m, ok := module.(*soongConfigModuleTypeModule)
+		if !ok {
+			return
+		}
+		schema, ok := schemas[m.properties.Config_namespace]
+		if !ok {
+			return
+		}
+		variables := schema.namespaces[m.properties.Config_namespace]
+		check := func(names []string, typ string) {
+			for _, name := range names {
+				variable, declared := variables[name]
+				if !declared {
+					ctx.ModuleErrorf(m, "variable %q is not declared in the namespace %q of %q",
+						name, m.properties.Config_namespace, schema.Name())
+				} else if soongConfigSchemaType(variable) != typ {
+					ctx.ModuleErrorf(m, "variable %q is used as a %s variable, but %q declares it as a %s variable",
+						name, typ, schema.Name(), soongConfigSchemaType(variable))
+				}
+			}
+		}
+		check(m.properties.Variables, soongConfigStringType)
+		check(m.properties.Bool_variables, soongConfigBoolType)
+		check(m.properties.Value_variables, soongConfigValueType)
+
+		// The conditions of the modules of the module type can only use the values of the string
+		// variables, so checking the values also checks the conditions.
+		for _, name := range m.properties.Variables {
+			variable, declared := variables[name]
+			stringVariable := stringVariables[ctx.BlueprintFile(m)][name]
+			if !declared || soongConfigSchemaType(variable) != soongConfigStringType || stringVariable == nil {
+				continue
+			}
+			for _, value := range stringVariable.stringProperties.Values {
+				if err := checkSoongConfigSchemaValue(variable, value); err != nil {
+					ctx.ModuleErrorf(m, "value of the string variable %q: %s", name, err)
+				}
+			}
+		}
+	})
+
+	vendorVars := ctx.Config().productVariables.VendorVars
+	for _, namespace := range SortedKeys(vendorVars) {
+		schema, ok := schemas[namespace]
+		if !ok {
+			continue
+		}
+		variables := schema.namespaces[namespace]
+		for _, name := range SortedKeys(vendorVars[namespace]) {
+			variable, declared := variables[name]
+			if !declared {
+				ctx.Errorf("soong config variable %s_%s is not declared in the namespace %q of %q",
+					namespace, name, namespace, schema.Name())
+				continue
+			}
+			if err := checkSoongConfigSchemaValue(variable, vendorVars[namespace][name]); err != nil {
+				ctx.Errorf("soong config variable %s_%s: %s", namespace, name, err)
+			}
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].String() < docs[j].String() })
+	ctx.Phony("soong_config_schema_docs", docs...)
+}
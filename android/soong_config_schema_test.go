@@ -0,0 +1,257 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"regexp"
+	"testing"
+)
+
+const soongConfigSchemaTestBp = `
+	soong_config_schema {
+		name: "acme_schema",
+		namespaces: [
+			{
+				name: "acme",
+				description: "Configuration of the acme boards.",
+				variables: [
+					{
+						name: "board",
+						values: ["soc_a", "soc_b"],
+						default: "soc_a",
+						description: "The SoC of the board.",
+					},
+					{
+						name: "feature",
+						type: "bool",
+						default: "false",
+					},
+					{
+						name: "width",
+						type: "value",
+					},
+				],
+			},
+		],
+	}
+`
+
+var prepareForSoongConfigSchemaTest = GroupFixturePreparers(
+	PrepareForTestWithSoongConfigModuleBuildComponents,
+	PrepareForTestWithSoongConfigSchemaBuildComponents,
+	prepareForSoongConfigTestModule,
+)
+
+func TestSoongConfigSchema(t *testing.T) {
+	result := GroupFixturePreparers(
+		prepareForSoongConfigSchemaTest,
+		FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+			variables.VendorVars = map[string]map[string]string{
+				"acme": {
+					"board":   "soc_b",
+					"feature": "true",
+					"width":   "200",
+				},
+				"other": {
+					"anything": "goes",
+				},
+			}
+		}),
+	).RunTestWithBp(t, soongConfigSchemaTestBp+`
+		soong_config_module_type {
+			name: "acme_test",
+			module_type: "test",
+			config_namespace: "acme",
+			variables: ["board"],
+			bool_variables: ["feature"],
+			value_variables: ["width"],
+			properties: ["cflags"],
+		}
+
+		soong_config_string_variable {
+			name: "board",
+			values: ["soc_a", "soc_b"],
+		}
+
+		soong_config_module_type {
+			name: "other_test",
+			module_type: "test",
+			config_namespace: "other",
+			bool_variables: ["undeclared"],
+			properties: ["cflags"],
+		}
+	`)
+
+	docs := result.ModuleForTests("acme_schema", "").Output("acme_schema.md")
+	AssertStringEquals(t, "documentation", `# acme
+
+Configuration of the acme boards.
+
+| Variable | Type | Values | Default | Description |
+|---|---|---|---|---|
+| board | string | soc_a, soc_b | soc_a | The SoC of the board. |
+| feature | bool |  | false |  |
+| width | value |  |  |  |
+`, ContentFromFileRuleForTests(t, docs))
+}
+
+func TestSoongConfigSchemaErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		bp            string
+		vendorVars    map[string]string
+		expectedError string
+	}{
+		{
+			name: "undeclared module type variable",
+			bp: `
+				soong_config_module_type {
+					name: "acme_test",
+					module_type: "test",
+					config_namespace: "acme",
+					bool_variables: ["unknown"],
+					properties: ["cflags"],
+				}
+			`,
+			expectedError: `variable "unknown" is not declared in the namespace "acme" of "acme_schema"`,
+		},
+		{
+			name: "module type variable of another type",
+			bp: `
+				soong_config_module_type {
+					name: "acme_test",
+					module_type: "test",
+					config_namespace: "acme",
+					value_variables: ["feature"],
+					properties: ["cflags"],
+				}
+			`,
+			expectedError: `variable "feature" is used as a value variable, but "acme_schema" declares it as a bool variable`,
+		},
+		{
+			name: "invalid string variable value",
+			bp: `
+				soong_config_module_type {
+					name: "acme_test",
+					module_type: "test",
+					config_namespace: "acme",
+					variables: ["board"],
+					properties: ["cflags"],
+				}
+
+				soong_config_string_variable {
+					name: "board",
+					values: ["soc_a", "soc_c"],
+				}
+			`,
+			expectedError: `value of the string variable "board": "soc_c" is not one of ["soc_a" "soc_b"]`,
+		},
+		{
+			name:          "undeclared product variable",
+			vendorVars:    map[string]string{"unknown": "true"},
+			expectedError: `soong config variable acme_unknown is not declared in the namespace "acme" of "acme_schema"`,
+		},
+		{
+			name:          "invalid string value",
+			vendorVars:    map[string]string{"board": "soc_c"},
+			expectedError: `soong config variable acme_board: "soc_c" is not one of ["soc_a" "soc_b"]`,
+		},
+		{
+			name:          "invalid bool value",
+			vendorVars:    map[string]string{"feature": "maybe"},
+			expectedError: `soong config variable acme_feature: "maybe" is not a bool`,
+		},
+		{
+			name: "duplicate namespace",
+			bp: `
+				soong_config_schema {
+					name: "other_schema",
+					namespaces: [{ name: "acme" }],
+				}
+			`,
+			expectedError: `namespace "acme" is already declared by "acme_schema"`,
+		},
+		{
+			name: "invalid type",
+			bp: `
+				soong_config_schema {
+					name: "other_schema",
+					namespaces: [{ name: "other", variables: [{ name: "size", type: "int" }] }],
+				}
+			`,
+			expectedError: `namespace "other": variable "size" has invalid type "int"`,
+		},
+		{
+			name: "string variable without values",
+			bp: `
+				soong_config_schema {
+					name: "other_schema",
+					namespaces: [{ name: "other", variables: [{ name: "board" }] }],
+				}
+			`,
+			expectedError: `namespace "other": string variable "board" must set values`,
+		},
+		{
+			name: "invalid default",
+			bp: `
+				soong_config_schema {
+					name: "other_schema",
+					namespaces: [{ name: "other", variables: [{ name: "board", values: ["a"], default: "b" }] }],
+				}
+			`,
+			expectedError: `namespace "other": invalid default of variable "board": "b" is not one of ["a"]`,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			GroupFixturePreparers(
+				prepareForSoongConfigSchemaTest,
+				FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+					variables.VendorVars = map[string]map[string]string{"acme": test.vendorVars}
+				}),
+			).ExtendWithErrorHandler(FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(test.expectedError))).
+				RunTestWithBp(t, soongConfigSchemaTestBp+test.bp)
+		})
+	}
+}
+
+func TestSoongConfigSchemaDocumentationEscapesPipes(t *testing.T) {
+	result := prepareForSoongConfigSchemaTest.RunTestWithBp(t, `
+		soong_config_schema {
+			name: "acme_schema",
+			namespaces: [
+				{
+					name: "acme",
+					variables: [
+						{
+							name: "flags",
+							values: ["a|b", "c"],
+							default: "a|b",
+							description: "Either a|b or c.",
+						},
+					],
+				},
+			],
+		}
+	`)
+
+	docs := result.ModuleForTests("acme_schema", "").Output("acme_schema.md")
+	AssertStringEquals(t, "documentation", `# acme
+
+| Variable | Type | Values | Default | Description |
+|---|---|---|---|---|
+| flags | string | a\|b, c | a\|b | Either a\|b or c. |
+`, ContentFromFileRuleForTests(t, docs))
+}
@@ -14,16 +14,53 @@
 
 package android
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 func init() {
 	RegisterSingletonType("testsuites", testSuiteFilesFactory)
 }
 
+// PrepareForTestWithTestSuites registers the singleton that packages the test suites.
+var PrepareForTestWithTestSuites = FixtureRegisterWithContext(func(ctx RegistrationContext) {
+	ctx.RegisterSingletonType("testsuites", testSuiteFilesFactory)
+})
+
 func testSuiteFilesFactory() Singleton {
 	return &testSuiteFiles{}
 }
 
 type testSuiteFiles struct {
 	robolectric WritablePath
+
+	// The packages of the test suites that are packaged by Soong instead of Make.
+	packages []testSuitePackage
+}
+
+// testSuitePackage is the package of a test suite packaged by Soong.
+type testSuitePackage struct {
+	name string
+
+	// The zip file of the test cases of the suite, e.g. general-tests.zip.
+	zip WritablePath
+
+	// The zip file of the test configs of the suite, e.g. general-tests_configs.zip.
+	configsZip WritablePath
+
+	// The zip file of the list of the test configs of the suite, e.g. general-tests_list.zip.
+	listZip WritablePath
+}
+
+// testSuitePackageFiles are the files of a variant of a module that are packaged in the same
+// directory of a test suite packaged by Soong, e.g. target/testcases/<module>/arm64.
+type testSuitePackageFiles struct {
+	module   string
+	dir      string
+	installs InstallPaths
+	config   Path
+	enabled  bool
 }
 
 type TestSuiteModule interface {
@@ -31,8 +68,16 @@ type TestSuiteModule interface {
 	TestSuites() []string
 }
 
+// TestConfigModule is implemented by the test modules that have a Tradefed test config, it is
+// packaged as <module>.config in the test suites that are packaged by Soong.
+type TestConfigModule interface {
+	Module
+	TestConfig() Path
+}
+
 func (t *testSuiteFiles) GenerateBuildActions(ctx SingletonContext) {
 	files := make(map[string]map[string]InstallPaths)
+	packaged := make(map[string]map[string]*testSuitePackageFiles)
 
 	ctx.VisitAllModules(func(m Module) {
 		if tsm, ok := m.(TestSuiteModule); ok {
@@ -42,6 +87,30 @@ func (t *testSuiteFiles) GenerateBuildActions(ctx SingletonContext) {
 				}
 				name := ctx.ModuleName(m)
 				files[testSuite][name] = append(files[testSuite][name], tsm.FilesToInstall()...)
+
+				if packaged[testSuite] == nil {
+					packaged[testSuite] = make(map[string]*testSuitePackageFiles)
+				}
+				// Like Make, package the files of the variants of the modules that are not
+				// architecture independent in a subdirectory named after their architecture.
+				prefix := "host"
+				if m.Target().Os.Class == Device {
+					prefix = "target"
+				}
+				dir := filepath.Join(prefix, "testcases", name)
+				if arch := m.Target().Arch.ArchType; arch != Common {
+					dir = filepath.Join(dir, arch.String())
+				}
+				pkgFiles := packaged[testSuite][dir]
+				if pkgFiles == nil {
+					pkgFiles = &testSuitePackageFiles{module: name, dir: filepath.Join(prefix, "testcases", name)}
+					packaged[testSuite][dir] = pkgFiles
+				}
+				pkgFiles.installs = append(pkgFiles.installs, tsm.FilesToInstall()...)
+				pkgFiles.enabled = pkgFiles.enabled || m.Enabled()
+				if tcm, ok := m.(TestConfigModule); ok && pkgFiles.config == nil {
+					pkgFiles.config = tcm.TestConfig()
+				}
 			}
 		}
 	})
@@ -49,10 +118,26 @@ func (t *testSuiteFiles) GenerateBuildActions(ctx SingletonContext) {
 	t.robolectric = robolectricTestSuite(ctx, files["robolectric-tests"])
 
 	ctx.Phony("robolectric-tests", t.robolectric)
+
+	for _, suite := range SortedUniqueStrings(ctx.Config().SoongPackagedTestSuites()) {
+		if suite == "robolectric-tests" {
+			continue
+		}
+		pkg := packageTestSuite(ctx, suite, packaged[suite])
+		ctx.Phony(suite, pkg.zip, pkg.configsZip, pkg.listZip)
+		t.packages = append(t.packages, pkg)
+	}
 }
 
 func (t *testSuiteFiles) MakeVars(ctx MakeVarsContext) {
 	ctx.DistForGoal("robolectric-tests", t.robolectric)
+
+	// The test suites packaged by Soong must not be packaged by Make, as they are written to the
+	// same zip files.
+	ctx.Strict("SOONG_PACKAGED_TEST_SUITES", strings.Join(SortedUniqueStrings(ctx.Config().SoongPackagedTestSuites()), " "))
+	for _, pkg := range t.packages {
+		ctx.DistForGoal(pkg.name, pkg.zip, pkg.configsZip, pkg.listZip)
+	}
 }
 
 func robolectricTestSuite(ctx SingletonContext, files map[string]InstallPaths) WritablePath {
@@ -73,3 +158,120 @@ func robolectricTestSuite(ctx SingletonContext, files map[string]InstallPaths) W
 
 	return outputFile
 }
+
+// packageTestSuite generates the rules that package the files installed by the modules of a test
+// suite that is packaged by Soong, in the same zip files and with the same layout as Make packages
+// the compatibility suites. <suite>.zip, in the product out directory, packages the files of each
+// variant of a module in host/testcases/<module>/<arch> or target/testcases/<module>/<arch>, or
+// directly in the directory of the module if it is architecture independent, at their path
+// relative to the directory that contains all of them, together with the test config of the module
+// as <module>.config. <suite>_configs.zip packages the test configs with the same layout, and
+// <suite>_list.zip packages the list of the test configs. An enabled module of the suite that
+// installs no files makes the packaging fail with an error that names the module.
+func packageTestSuite(ctx SingletonContext, suite string, files map[string]*testSuitePackageFiles) testSuitePackage {
+	productOut := pathForInstall(ctx, Android, Common, "", false)
+	pkg := testSuitePackage{
+		name:       suite,
+		zip:        productOut.Join(ctx, suite+".zip"),
+		configsZip: productOut.Join(ctx, suite+"_configs.zip"),
+		listZip:    productOut.Join(ctx, suite+"_list.zip"),
+	}
+
+	installs := make(map[string]InstallPaths)
+	enabled := make(map[string]bool)
+	for _, dir := range SortedKeys(files) {
+		installs[files[dir].module] = append(installs[files[dir].module], files[dir].installs...)
+		enabled[files[dir].module] = enabled[files[dir].module] || files[dir].enabled
+	}
+
+	var missing Paths
+	for _, module := range SortedKeys(installs) {
+		if len(installs[module]) > 0 || !enabled[module] {
+			continue
+		}
+		errorFile := PathForOutput(ctx, "packaging", suite+"_missing", module)
+		ctx.Build(pctx, BuildParams{
+			Rule:        ErrorRule,
+			Output:      errorFile,
+			Description: suite + " missing " + module,
+			Args: map[string]string{
+				"error": "module " + module + " is in the " + suite + " test suite but installs no files",
+			},
+		})
+		missing = append(missing, errorFile)
+	}
+
+	// The test configs are copied to <module>.config in a directory that has the layout of the
+	// configs zip, as soong_zip cannot rename the files it packages. The variants of a module share
+	// its test config.
+	configsDir := PathForOutput(ctx, "packaging", suite+"_configs")
+	configs := make(map[string]WritablePath)
+	for _, dir := range SortedKeys(files) {
+		moduleDir := files[dir].dir
+		if files[dir].config == nil || configs[moduleDir] != nil {
+			continue
+		}
+		config := configsDir.Join(ctx, moduleDir, files[dir].module+".config")
+		ctx.Build(pctx, BuildParams{
+			Rule:   Cp,
+			Input:  files[dir].config,
+			Output: config,
+		})
+		configs[moduleDir] = config
+	}
+
+	rule := NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().BuiltTool("soong_zip").
+		FlagWithOutput("-o ", pkg.zip).
+		Flag("-d")
+	for _, dir := range SortedKeys(files) {
+		paths := SortedUniquePaths(files[dir].installs.Paths())
+		if len(paths) > 0 {
+			cmd.FlagWithArg("-P ", dir).
+				FlagWithArg("-C ", testSuiteCommonDir(paths)).
+				FlagWithRspFileInputList("-r ", PathForOutput(ctx, "packaging", suite+"_rsp", dir+".rsp"), paths)
+		}
+	}
+	var configPaths Paths
+	var configsList []string
+	for _, dir := range SortedKeys(configs) {
+		cmd.FlagWithArg("-P ", dir).
+			FlagWithArg("-C ", filepath.Dir(configs[dir].String())).
+			FlagWithInput("-f ", configs[dir])
+		configPaths = append(configPaths, configs[dir])
+		configsList = append(configsList, filepath.Join(dir, configs[dir].Base()))
+	}
+	cmd.Implicits(missing)
+	rule.Build(suite+"_zip", suite+".zip")
+
+	rule = NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("soong_zip").
+		FlagWithOutput("-o ", pkg.configsZip).
+		Flag("-d").
+		FlagWithArg("-C ", configsDir.String()).
+		FlagWithRspFileInputList("-r ", PathForOutput(ctx, "packaging", suite+"_configs.rsp"), configPaths).
+		Implicits(missing)
+	rule.Build(suite+"_configs_zip", suite+"_configs.zip")
+
+	list := PathForOutput(ctx, "packaging", suite+"_list", suite+"_list")
+	WriteFileRule(ctx, list, strings.Join(configsList, "\n"))
+	rule = NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("soong_zip").
+		FlagWithOutput("-o ", pkg.listZip).
+		FlagWithArg("-C ", filepath.Dir(list.String())).
+		FlagWithInput("-f ", list)
+	rule.Build(suite+"_list_zip", suite+"_list.zip")
+
+	return pkg
+}
+
+// testSuiteCommonDir returns the deepest directory that contains all the paths.
+func testSuiteCommonDir(paths Paths) string {
+	dir := filepath.Dir(paths[0].String())
+	for _, path := range paths[1:] {
+		for dir != "." && dir != "/" && !strings.HasPrefix(path.String(), dir+"/") {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"testing"
+)
+
+type testSuiteTestModule struct {
+	ModuleBase
+
+	properties struct {
+		Test_suites []string
+		Installs    []string
+	}
+}
+
+func testSuiteTestModuleFactory() Module {
+	module := &testSuiteTestModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidArchModule(module, DeviceSupported, MultilibFirst)
+	return module
+}
+
+func (m *testSuiteTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	for _, install := range m.properties.Installs {
+		out := PathForModuleOut(ctx, install)
+		WriteFileRule(ctx, out, "")
+		ctx.InstallFile(PathForModuleInstall(ctx, "testcases", ctx.ModuleName()), install, out)
+	}
+}
+
+func (m *testSuiteTestModule) TestSuites() []string {
+	return m.properties.Test_suites
+}
+
+var prepareForTestSuitesTest = GroupFixturePreparers(
+	FixtureRegisterWithContext(func(ctx RegistrationContext) {
+		ctx.RegisterModuleType("test_suite_module", testSuiteTestModuleFactory)
+	}),
+	PrepareForTestWithTestSuites,
+	FixtureModifyProductVariables(func(variables FixtureProductVariables) {
+		variables.SoongPackagedTestSuites = []string{"general-tests"}
+	}),
+)
+
+// The layout of the packages of the test suites is tested with the real test module types in
+// cc/test_suites_test.go.
+func TestSoongPackagedTestSuitesMissingFiles(t *testing.T) {
+	result := prepareForTestSuitesTest.RunTestWithBp(t, `
+		test_suite_module {
+			name: "foo",
+			test_suites: ["general-tests"],
+			installs: ["foo"],
+		}
+
+		test_suite_module {
+			name: "bar",
+			test_suites: ["general-tests"],
+		}
+
+		test_suite_module {
+			name: "baz",
+			test_suites: ["device-tests"],
+			installs: ["baz"],
+		}
+
+		test_suite_module {
+			name: "qux",
+			test_suites: ["general-tests"],
+			enabled: false,
+		}
+	`)
+
+	singleton := result.SingletonForTests("testsuites")
+
+	zip := singleton.Output("out/soong/target/product/test_device/general-tests.zip")
+	AssertStringListContains(t, "general-tests.zip depends on the error of bar",
+		PathsRelativeToTop(zip.Implicits), "out/soong/packaging/general-tests_missing/bar")
+	AssertStringDoesNotContain(t, "general-tests.zip does not package baz", zip.RuleParams.Command,
+		"testcases/baz")
+
+	missing := singleton.Output("packaging/general-tests_missing/bar")
+	AssertStringEquals(t, "error of bar",
+		"module bar is in the general-tests test suite but installs no files", missing.Args["error"])
+
+	if singleton.MaybeOutput("packaging/general-tests_missing/qux").Rule != nil {
+		t.Errorf("qux is disabled, but installing no files was reported as an error")
+	}
+
+	if singleton.MaybeOutput("out/soong/target/product/test_device/device-tests.zip").Rule != nil {
+		t.Errorf("device-tests is not packaged by Soong, but device-tests.zip was generated")
+	}
+}
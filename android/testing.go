@@ -32,7 +32,7 @@ import (
 
 func newTestContextForFixture(config Config) *TestContext {
 	ctx := &TestContext{
-		Context: &Context{blueprint.NewContext(), config},
+		Context: &Context{Context: blueprint.NewContext(), config: config},
 	}
 
 	ctx.postDeps = append(ctx.postDeps, registerPathDepsMutator)
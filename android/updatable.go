@@ -0,0 +1,62 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android
+
+import (
+	"github.com/google/blueprint"
+)
+
+// UpdatableDependency is implemented by modules that can be used by updatable modules, i.e.
+// updatable APEXes and apps, and that know whether they are used through a stable API or ABI.
+type UpdatableDependency interface {
+	// CheckStableForUpdatable returns an error if the module is not used through a stable API or
+	// ABI. externalDep is true if the dependency crosses the boundary of the updatable module.
+	CheckStableForUpdatable(ctx BaseModuleContext, externalDep bool) error
+}
+
+// CheckUpdatableConsistency checks that an updatable module has a sane min_sdk_version and only
+// depends on stable ABIs, e.g. stubs or sdk variants, outside of the module. Violations are reported
+// together with the dependency path from the updatable module. The SDK of the java dependencies is
+// checked by CheckStableSdkVersion.
+//
+// The checks are only enforced when the EnforceUpdatableConsistency product variable is set, so
+// that they can be rolled out one product at a time.
+func CheckUpdatableConsistency(ctx ModuleContext, minSdkVersion ApiLevel, walk WalkPayloadDepsFunc) {
+	if !ctx.DeviceConfig().EnforceUpdatableConsistency() || ctx.Host() {
+		return
+	}
+
+	if !minSdkVersion.IsNone() {
+		if minSdkVersion.IsPreview() && ctx.Config().PlatformSdkFinal() {
+			ctx.PropertyErrorf("min_sdk_version", "updatable modules cannot use the unreleased API level %q in a finalized platform", minSdkVersion)
+		} else if !minSdkVersion.IsPreview() && minSdkVersion.GreaterThan(ctx.Config().PlatformSdkVersion()) {
+			ctx.PropertyErrorf("min_sdk_version", "%q is later than the platform SDK version %q", minSdkVersion, ctx.Config().PlatformSdkVersion())
+		}
+	}
+
+	walk(ctx, func(ctx ModuleContext, from blueprint.Module, to ApexModule, externalDep bool) bool {
+		if d, ok := to.(UpdatableDependency); ok {
+			if err := d.CheckStableForUpdatable(ctx, externalDep); err != nil {
+				ctx.OtherModuleErrorf(to, "cannot be used by updatable module %q: %v."+
+					"\n\nDependency path: %s\n",
+					ctx.ModuleName(), err.Error(), ctx.GetPathString(false))
+				return false
+			}
+		}
+		// External deps are only used through their stable interface, so their own dependencies
+		// are not part of the updatable module.
+		return !externalDep
+	})
+}
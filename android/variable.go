@@ -281,6 +281,8 @@ type productVariables struct {
 	HostSecondaryArch *string `json:",omitempty"`
 	HostMusl          *bool   `json:",omitempty"`
 
+	HostTestRuntimes []string `json:",omitempty"`
+
 	CrossHost              *string `json:",omitempty"`
 	CrossHostArch          *string `json:",omitempty"`
 	CrossHostSecondaryArch *string `json:",omitempty"`
@@ -305,12 +307,15 @@ type productVariables struct {
 	Device_support_hwfde       *bool `json:",omitempty"`
 	Device_support_hwfde_perf  *bool `json:",omitempty"`
 	Allow_missing_dependencies       *bool    `json:",omitempty"`
+	Experimental_dirs                []string `json:",omitempty"`
 	Unbundled_build                  *bool    `json:",omitempty"`
 	Unbundled_build_apps             []string `json:",omitempty"`
 	Unbundled_build_image            *bool    `json:",omitempty"`
 	Unbundled_build_sdks_from_source *bool    `json:",omitempty"`
 	Always_use_prebuilt_sdks         *bool    `json:",omitempty"`
 	Skip_boot_jars_check             *bool    `json:",omitempty"`
+	Frozen_interfaces                *bool    `json:",omitempty"`
+	Lint_severity_policies           []string `json:",omitempty"`
 	Malloc_use_scudo                 *bool    `json:",omitempty"`
 	Malloc_not_svelte                *bool    `json:",omitempty"`
 	Malloc_zero_contents             *bool    `json:",omitempty"`
@@ -340,6 +345,9 @@ type productVariables struct {
 	BootJars     ConfiguredJarList `json:",omitempty"`
 	ApexBootJars ConfiguredJarList `json:",omitempty"`
 
+	// The names of the boot_image_extension modules whose boot images are built for the product.
+	BootImageExtensions []string `json:",omitempty"`
+
 	IntegerOverflowExcludePaths []string `json:",omitempty"`
 	IntegerOverflowIncludePaths []string `json:",omitempty"`
 
@@ -354,6 +362,34 @@ type productVariables struct {
 	MemtagHeapAsyncIncludePaths []string `json:",omitempty"`
 	MemtagHeapSyncIncludePaths  []string `json:",omitempty"`
 
+	MemtagHeapDefaultMode *string `json:",omitempty"`
+
+	ThreadSafetyAnalysisIncludePaths []string `json:",omitempty"`
+	ThreadSafetyAnalysisExcludePaths []string `json:",omitempty"`
+	ThreadSafetyReportPaths          []string `json:",omitempty"`
+
+	// The branch protection policies of the arm64 device modules, as
+	// <partition>:<mode>[:<min_sdk_version>], where the mode is standard, pac-ret, bti or none, and
+	// the partition is * for all the partitions.
+	BranchProtectionPolicies []string `json:",omitempty"`
+
+	// The UBSan checks compiled with the minimal runtime into the device modules, as
+	// <partition>:<check>[,<check>...], where the checks are bounds or integer-overflow, and the
+	// partition is * for all the partitions.
+	UbsanMinimalRuntimePolicies []string `json:",omitempty"`
+
+	// The toolchain flag experiment that a deterministic fraction of the device cc modules are
+	// built with, the other modules being the control group.
+	CcFlagExperiment *CcFlagExperiment `json:",omitempty"`
+
+	// The test suites, e.g. general-tests or device-tests, that are packaged by Soong instead of
+	// Make.
+	SoongPackagedTestSuites []string `json:",omitempty"`
+
+	// The directories, e.g. unbundled or staging directories, whose modules are allowed to depend
+	// on modules that do not exist, as if Allow_missing_dependencies was set for these modules only.
+	Allow_missing_dependencies_dirs []string `json:",omitempty"`
+
 	HWASanIncludePaths []string `json:",omitempty"`
 
 	VendorPath    *string `json:",omitempty"`
@@ -383,10 +419,39 @@ type productVariables struct {
 
 	ArtUseReadBarrier *bool `json:",omitempty"`
 
+	// The dex2oat compiler filters of the boot images of an architecture, keyed by the architecture,
+	// e.g. {"arm": "verify"}, or by <boot image>:<architecture> to override the filter of a single
+	// boot image, e.g. {"boot:arm64": "speed-profile"}.
+	BootImageCompilerFilters map[string]string `json:",omitempty"`
+
+	// The initial and the maximum heap sizes of dex2oat when it compiles a boot image, keyed by the
+	// name of the boot image, e.g. {"mainline": "64m"}. They default to the Dex2oatImageXms and
+	// Dex2oatImageXmx of the global dexpreopt config.
+	BootImageDex2oatXms map[string]string `json:",omitempty"`
+	BootImageDex2oatXmx map[string]string `json:",omitempty"`
+
+	// The dex2oat_config modules whose flags are passed to dex2oat when it compiles a boot image,
+	// keyed by the name of the boot image, e.g. {"boot": "boot-dex2oat-flags"}.
+	BootImageDex2oatConfigs map[string]string `json:",omitempty"`
+
+	// The boot_image_profile modules whose profiles are merged into the framework boot image profile.
+	// The modules in a namespace are referenced by their fully qualified names, e.g.
+	// "//vendor/oem:oem-boot-image-profile".
+	BootImageProfileModules []string `json:",omitempty"`
+
+	// The modules in the PRODUCT_PACKAGES of the product, for the Soong outputs that only describe
+	// the modules that the product installs, e.g. the install state of the preinstalled apps.
+	ProductPackages []string `json:",omitempty"`
+
 	BtConfigIncludeDir *string `json:",omitempty"`
 
 	Override_rs_driver *string `json:",omitempty"`
 
+	// The style of the build ids of the device ELF files, "md5", "sha1" or "uuid", and its
+	// overrides for partitions, e.g. {"vendor": "sha1"}.
+	BuildIdStyle           *string           `json:",omitempty"`
+	PartitionBuildIdStyles map[string]string `json:",omitempty"`
+
 	DeviceKernelHeaders []string `json:",omitempty"`
 
 	ExtraVndkVersions []string `json:",omitempty"`
@@ -456,8 +521,20 @@ type productVariables struct {
 	CertificateOverrides         []string `json:",omitempty"`
 	PackageNameOverrides         []string `json:",omitempty"`
 
+	// The certificates of the certificate aliases that android_app modules can use in their
+	// certificate property in the form @<alias>, so that the same Android.bp can be signed with
+	// different keys on different products. The certificates are either names of certificates in
+	// the default certificate directory or android_app_certificate modules in the form ":module".
+	AppCertificateAliases map[string]string `json:",omitempty"`
+
+	// The signing certificate lineage files of the certificate aliases, relative to the top of the
+	// source tree, used by the apps that do not set a lineage.
+	AppCertificateAliasLineages map[string]string `json:",omitempty"`
+
 	ApexGlobalMinSdkVersionOverride *string `json:",omitempty"`
 
+	EnforceUpdatableConsistency *bool `json:",omitempty"`
+
 	EnforceSystemCertificate          *bool    `json:",omitempty"`
 	EnforceSystemCertificateAllowList []string `json:",omitempty"`
 
@@ -523,6 +600,23 @@ type productVariables struct {
 
 	AfdoProfiles []string `json:",omitempty"`
 
+	// Directory of sampled AFDO profiles named <module>.afdo, which are used by the device binaries
+	// and shared libraries that have one without setting afdo in their Android.bp.
+	AfdoProfileDir *string `json:",omitempty"`
+
+	// JSON files with the warning overrides of the projects of the product, in addition to
+	// build/soong/cc/config/warning_overrides.json.
+	WarningOverridesFiles []string `json:",omitempty"`
+
+	// Whether the ThinLTO links of the product use the ThinLTO cache of the out directory, see
+	// cc/config/thinlto_cache.go.
+	ThinLTOCache *bool `json:",omitempty"`
+
+	// Clang configuration file, relative to the top of the source tree, whose options are passed
+	// to the compiles of the device modules after the global flags, see
+	// cc/config/clang_config_file.go.
+	ClangConfigFile *string `json:",omitempty"`
+
 	ProductManufacturer string   `json:",omitempty"`
 	ProductBrand        string   `json:",omitempty"`
 	BuildVersionTags    []string `json:",omitempty"`
@@ -531,6 +625,20 @@ type productVariables struct {
 
 }
 
+// CcFlagExperiment is a set of experimental compiler and linker flags, e.g. to evaluate a new MLGO
+// model, that the device cc modules whose hash falls in the given percentage are built with.
+type CcFlagExperiment struct {
+	// The name of the experiment, which is also the seed of the selection of the modules.
+	Name string
+
+	// The percentage of the modules, from 0 to 100, that are built with the experimental flags.
+	Percent int
+
+	// The flags added to the cflags and the ldflags of the selected modules.
+	Cflags  []string `json:",omitempty"`
+	Ldflags []string `json:",omitempty"`
+}
+
 func boolPtr(v bool) *bool {
 	return &v
 }
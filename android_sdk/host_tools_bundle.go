@@ -0,0 +1,187 @@
+// Copyright (C) 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android_sdk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+type hostToolsBundle struct {
+	android.ModuleBase
+
+	properties hostToolsBundleProperties
+
+	outputFile android.OptionalPath
+}
+
+type hostToolsBundleProperties struct {
+	// Host tool modules to include in the bundle, e.g. adb or fastboot. Each module must provide
+	// the path to its tool, as cc_binary_host and rust_binary_host modules do.
+	Tools []string `android:"arch_variant"`
+
+	// List of other files to include in the bundle, e.g. documentation. They are copied to their
+	// path relative to the module directory.
+	Srcs []string `android:"arch_variant,path"`
+
+	// The top level directory of the bundle. Defaults to "platform-tools".
+	Base_dir *string
+
+	// The revision of the bundle, recorded as Pkg.Revision in the source.properties file of the
+	// bundle. Defaults to the platform version name.
+	Revision *string
+}
+
+// android_host_tools_bundle creates a zip of host tools in the layout of the SDK platform-tools,
+// i.e. the tools in a single directory alongside a NOTICE.txt file built from the license metadata
+// of the tools and a source.properties file with the revision of the bundle.
+//
+// The output zip is dist with `dist` or `dists`, e.g. for a custom tools bundle for vendors.
+func HostToolsBundleFactory() android.Module {
+	b := &hostToolsBundle{}
+	b.AddProperties(&b.properties)
+	android.InitAndroidArchModule(b, android.HostSupported, android.MultilibFirst)
+	return b
+}
+
+type hostToolsBundleDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var hostToolsBundleDepTag = hostToolsBundleDependencyTag{}
+
+func (b *hostToolsBundle) DepsMutator(ctx android.BottomUpMutatorContext) {
+	ctx.AddVariationDependencies(nil, hostToolsBundleDepTag, b.properties.Tools...)
+}
+
+func (b *hostToolsBundle) baseDir() string {
+	return proptools.StringDefault(b.properties.Base_dir, "platform-tools")
+}
+
+func (b *hostToolsBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	dir := android.PathForModuleOut(ctx, "bundle")
+	baseDir := filepath.Join(dir.String(), b.baseDir())
+	outputZipFile := android.PathForModuleOut(ctx, ctx.ModuleName()+".zip")
+
+	builder := android.NewRuleBuilder(pctx, ctx)
+	builder.Command().Text("rm -rf").Text(dir.String())
+	builder.Command().Text("mkdir -p").Text(baseDir)
+
+	// Copy the tools with the files they need at runtime, e.g. their shared libraries and data, as
+	// installed by their modules. The tools are copied to the top level directory, and the other
+	// files to their path relative to the host output directory, e.g. lib64/libc++.so.
+	var tools []android.Module
+	copied := make(map[string]string)
+	sources := make(map[string]string)
+	ctx.VisitDirectDepsWithTag(hostToolsBundleDepTag, func(dep android.Module) {
+		h, ok := dep.(android.HostToolProvider)
+		if !ok || !h.HostToolPath().Valid() {
+			ctx.PropertyErrorf("tools", "module %q is not a host tool", ctx.OtherModuleName(dep))
+			return
+		}
+		tools = append(tools, dep)
+		for _, spec := range dep.TransitivePackagingSpecs() {
+			rel := strings.TrimPrefix(spec.RelPathInPackage(), "bin/")
+			src := spec.SymlinkTarget()
+			if spec.SrcPath() != nil {
+				src = spec.SrcPath().String()
+			}
+			if other, exists := copied[rel]; exists {
+				if sources[rel] == src {
+					// A file needed by several tools, e.g. a shared library.
+					continue
+				}
+				ctx.PropertyErrorf("tools", "%q and %q both provide %q", other, ctx.OtherModuleName(dep), rel)
+				continue
+			}
+			copied[rel] = ctx.OtherModuleName(dep)
+			sources[rel] = src
+
+			dest := filepath.Join(baseDir, rel)
+			builder.Command().Text("mkdir -p").Text(filepath.Dir(dest))
+			if target := spec.SymlinkTarget(); target != "" {
+				builder.Command().Text("ln -sf").Text(proptools.ShellEscape(target)).Text(dest)
+			} else {
+				builder.Command().Text("cp -f").Input(spec.SrcPath()).Text(dest)
+			}
+		}
+	})
+
+	// Copy the other files.
+	for _, src := range android.PathsForModuleSrc(ctx, b.properties.Srcs) {
+		dest := filepath.Join(baseDir, src.Rel())
+		builder.Command().Text("mkdir -p").Text(filepath.Dir(dest))
+		builder.Command().Text("cp -f").Input(src).Text(dest)
+	}
+
+	// Add the version metadata.
+	revision := proptools.StringDefault(b.properties.Revision, ctx.Config().PlatformVersionName())
+	sourceProperties := android.PathForModuleOut(ctx, "source.properties")
+	android.WriteFileRule(ctx, sourceProperties, strings.Join([]string{
+		"Pkg.UserSrc=false",
+		"Pkg.Revision=" + revision,
+	}, "\n"))
+	builder.Command().Text("cp -f").Input(sourceProperties).Text(filepath.Join(baseDir, "source.properties"))
+
+	// Add the notices of the tools, built from their license metadata.
+	noticeFile := android.PathForModuleOut(ctx, "NOTICE.txt")
+	android.BuildNoticeTextOutputFromLicenseMetadata(ctx, noticeFile, "", "",
+		[]string{outputZipFile.String()}, tools...)
+	builder.Command().Text("cp -f").Input(noticeFile).Text(filepath.Join(baseDir, "NOTICE.txt"))
+
+	builder.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", outputZipFile).
+		FlagWithArg("-C ", dir.String()).
+		FlagWithArg("-D ", dir.String())
+	builder.Command().Text("rm -rf").Text(dir.String())
+
+	builder.Build("host_tools_bundle", fmt.Sprintf("Creating host tools bundle %s", ctx.ModuleName()))
+
+	b.outputFile = android.OptionalPathForPath(outputZipFile)
+}
+
+func (b *hostToolsBundle) OutputFiles(tag string) (android.Paths, error) {
+	switch tag {
+	case "":
+		if b.outputFile.Valid() {
+			return android.Paths{b.outputFile.Path()}, nil
+		}
+		return android.Paths{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+}
+
+var _ android.OutputFileProducer = (*hostToolsBundle)(nil)
+
+func (b *hostToolsBundle) AndroidMkEntries() []android.AndroidMkEntries {
+	return []android.AndroidMkEntries{{
+		Class:      "ETC",
+		OutputFile: b.outputFile,
+		DistFiles:  android.MakeDefaultDistFiles(b.outputFile.Path()),
+		ExtraEntries: []android.AndroidMkExtraEntriesFunc{
+			func(ctx android.AndroidMkExtraEntriesContext, entries *android.AndroidMkEntries) {
+				entries.SetBool("LOCAL_UNINSTALLABLE_MODULE", true)
+			},
+		},
+	}}
+}
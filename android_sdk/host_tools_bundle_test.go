@@ -0,0 +1,78 @@
+// Copyright (C) 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package android_sdk
+
+import (
+	"runtime"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestHostToolsBundle(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("Skipping host tools bundle testing that is only supported on linux not %s", runtime.GOOS)
+	}
+
+	result := fixture.RunTestWithBp(t, `
+		cc_binary_host {
+			name: "adb",
+			stl: "none",
+			shared_libs: ["libadb"],
+		}
+
+		cc_library_host_shared {
+			name: "libadb",
+			stl: "none",
+		}
+
+		cc_binary_host {
+			name: "fastboot",
+			stl: "none",
+		}
+
+		android_host_tools_bundle {
+			name: "my-platform-tools",
+			tools: ["adb", "fastboot"],
+			revision: "34.0.4",
+		}
+	`)
+
+	bundle := result.ModuleForTests("my-platform-tools", "linux_glibc_x86_64")
+	rule := bundle.Output("my-platform-tools.zip")
+	android.AssertStringDoesContain(t, "copies adb", rule.RuleParams.Command, "bundle/platform-tools/adb")
+	android.AssertStringDoesContain(t, "copies fastboot", rule.RuleParams.Command, "bundle/platform-tools/fastboot")
+	android.AssertStringDoesContain(t, "copies the shared libraries of adb", rule.RuleParams.Command,
+		"bundle/platform-tools/lib64/libadb.so")
+	android.AssertStringDoesContain(t, "copies source.properties", rule.RuleParams.Command, "bundle/platform-tools/source.properties")
+
+	sourceProperties := android.ContentFromFileRuleForTests(t, bundle.Output("source.properties"))
+	android.AssertStringDoesContain(t, "revision", sourceProperties, "Pkg.Revision=34.0.4")
+}
+
+func TestHostToolsBundleNotAHostTool(t *testing.T) {
+	fixture.ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`tools: module "libfoo" is not a host tool`)).RunTestWithBp(t, `
+		cc_library_host_shared {
+			name: "libfoo",
+			stl: "none",
+		}
+
+		android_host_tools_bundle {
+			name: "my-platform-tools",
+			tools: ["libfoo"],
+		}
+	`)
+}
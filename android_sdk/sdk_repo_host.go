@@ -36,6 +36,7 @@ func init() {
 
 func registerBuildComponents(ctx android.RegistrationContext) {
 	ctx.RegisterModuleType("android_sdk_repo_host", SdkRepoHostFactory)
+	ctx.RegisterModuleType("android_host_tools_bundle", HostToolsBundleFactory)
 }
 
 type sdkRepoHost struct {
@@ -182,7 +182,8 @@ type apexBundleProperties struct {
 	Ignore_system_library_special_case *bool
 
 	// Whenever apex_payload.img of the APEX should include dm-verity hashtree.
-	// Default value is true.
+	// Default value is true. When false, the dm-verity metadata of the payload is built separately
+	// by the <apex>-verity-metadata target and by the filesystem images that include the APEX.
 	Generate_hashtree *bool
 
 	// Whenever apex_payload.img of the APEX should not be dm-verity signed. Should be only
@@ -456,6 +457,12 @@ type apexBundle struct {
 	// The built uncompressed .apex file.
 	outputApexFile android.WritablePath
 
+	// Whether the apex_payload.img of the APEX includes its dm-verity hashtree.
+	generatesHashtree bool
+
+	// The dm-verity metadata of the payload, if it does not include its hashtree.
+	verityMetadataFile android.WritablePath
+
 	// The built APEX file in app bundle format. This file is not directly installed to the
 	// device. For an APEX, multiple app bundles are created each of which is for a specific ABI
 	// like arm, arm64, x86, etc. Then they are processed again (outside of the Android build
@@ -2621,6 +2628,14 @@ func (a *apexBundle) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	}
 	a.buildApexDependencyInfo(ctx)
 	a.buildLintReports(ctx)
+	a.buildStagingDir(ctx)
+	if a.properties.ApexType == imageApex {
+		info := android.ApexHashtreeInfo{GenerateHashtree: a.generatesHashtree}
+		if a.verityMetadataFile != nil {
+			info.VerityMetadata = a.verityMetadataFile
+		}
+		ctx.SetProvider(android.ApexHashtreeInfoProvider, info)
+	}
 
 	// Append meta-files to the filesInfo list so that they are reflected in Android.mk as well.
 	if a.installable() {
@@ -3022,6 +3037,9 @@ func (a *apexBundle) checkUpdatable(ctx android.ModuleContext) {
 		}
 		a.checkJavaStableSdkVersion(ctx)
 		a.checkClasspathFragments(ctx)
+		if !a.testApex && !a.vndkApex {
+			android.CheckUpdatableConsistency(ctx, a.minSdkVersion(ctx), a.WalkPayloadDeps)
+		}
 	}
 }
 
@@ -4780,6 +4780,47 @@ func TestApexWithShBinary(t *testing.T) {
 	ensureContains(t, copyCmds, "image.apex/bin/script/myscript.sh")
 }
 
+func TestApexStagingDir(t *testing.T) {
+	bp := `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			sh_binaries: ["myscript"],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		sh_binary {
+			name: "myscript",
+			src: "mylib.cpp",
+			filename: "myscript.sh",
+			sub_dir: "script",
+		}
+	`
+
+	ctx := testApex(t, bp)
+	module := ctx.ModuleForTests("myapex", "android_common_myapex_image")
+	if rule := module.MaybeOutput("staging/myapex/bin/script/myscript.sh"); rule.Rule != nil {
+		t.Errorf("expected no staging directory without SOONG_APEX_STAGING")
+	}
+
+	ctx = testApex(t, bp, android.FixtureMergeEnv(map[string]string{"SOONG_APEX_STAGING": "myapex"}))
+	module = ctx.ModuleForTests("myapex", "android_common_myapex_image")
+	module.Output("staging/myapex/bin/script/myscript.sh")
+	module.Output("staging/myapex/apex_manifest.pb")
+	module.Output("staging/myapex-push.sh")
+
+	script := android.ContentFromFileRuleForTests(t, module.Output("staging/myapex-push.sh.tmp"))
+	ensureContains(t, script, `DEST="${1:-/system/apex/myapex}"`)
+	ensureContains(t, script, `adb push --sync "${STAGING_DIR}/." "${DEST}"`)
+	ensureContains(t, script, `if ! adb shell test -d "${DEST}"; then`)
+}
+
 func TestApexInVariousPartition(t *testing.T) {
 	testcases := []struct {
 		propName, parition, flattenedPartition string
@@ -5602,7 +5643,7 @@ func TestBootDexJarsFromSourcesAndPrebuilts(t *testing.T) {
 		// prebuilt_apex module always depends on the prebuilt, and so it doesn't
 		// find the dex boot jar in it. We either need to disable the source libfoo
 		// or make the prebuilt libfoo preferred.
-		testDexpreoptWithApexes(t, bp, "module libfoo does not provide a dex boot jar", preparer, fragment)
+		testDexpreoptWithApexes(t, bp, `module libfoo does not provide a dex boot jar for PRODUCT_APEX_BOOT_JARS as "myapex:libfoo", needed by .*: the "myapex" apex contains "prebuilt_libfoo", which is not the active module: set "prefer: true" on it, or disable "libfoo"`, preparer, fragment)
 		// dexbootjar check is skipped if AllowMissingDependencies is true
 		preparerAllowMissingDeps := android.GroupFixturePreparers(
 			preparer,
@@ -6935,6 +6976,50 @@ func TestLegacyAndroid10Support(t *testing.T) {
 	}
 }
 
+func TestApexWithoutHashtree(t *testing.T) {
+	ctx := testApex(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			updatable: false,
+			generate_hashtree: false,
+		}
+
+		apex {
+			name: "otherapex",
+			key: "myapex.key",
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+	`)
+
+	module := ctx.ModuleForTests("myapex", "android_common_myapex_image")
+	ensureContains(t, module.Rule("apexRule").Args["opt_flags"], "--no_hashtree")
+	verityMetadata := module.Rule("apexVerityMetadataRule")
+	ensureContains(t, verityMetadata.Input.String(), "myapex.apex.unsigned")
+	ensureContains(t, verityMetadata.Output.String(), "myapex.verity_metadata.img")
+	android.AssertStringEquals(t, "key", "testkey.pem", verityMetadata.Args["key"])
+	android.AssertStringEquals(t, "key name", "testkey", verityMetadata.Args["key_name"])
+	android.AssertPathsRelativeToTopEquals(t, "implicits", []string{"testkey.pem"}, verityMetadata.Implicits)
+
+	info := ctx.ModuleProvider(module.Module(), android.ApexHashtreeInfoProvider).(android.ApexHashtreeInfo)
+	android.AssertBoolEquals(t, "myapex generates hashtree", false, info.GenerateHashtree)
+	android.AssertStringEquals(t, "myapex verity metadata", verityMetadata.Output.String(), info.VerityMetadata.String())
+
+	other := ctx.ModuleForTests("otherapex", "android_common_otherapex_image")
+	ensureNotContains(t, other.Rule("apexRule").Args["opt_flags"], "--no_hashtree")
+	if other.MaybeRule("apexVerityMetadataRule").Rule != nil {
+		t.Errorf("otherapex includes its hashtree, but its verity metadata is built")
+	}
+	otherInfo := ctx.ModuleProvider(other.Module(), android.ApexHashtreeInfoProvider).(android.ApexHashtreeInfo)
+	android.AssertBoolEquals(t, "otherapex generates hashtree", true, otherInfo.GenerateHashtree)
+}
+
 var filesForSdkLibrary = android.MockFS{
 	"api/current.txt":        nil,
 	"api/removed.txt":        nil,
@@ -8203,6 +8288,35 @@ func Test_use_vndk_as_stable_shouldnt_be_used_for_non_vendor_apexes(t *testing.T
 	`)
 }
 
+func TestUpdatable_min_sdk_version_later_than_platform(t *testing.T) {
+	bp := `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			updatable: true,
+			min_sdk_version: "31",
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+	`
+	platformSdkVersion := android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.Platform_sdk_version = proptools.IntPtr(30)
+	})
+
+	// The check is not enforced unless the product opts in.
+	testApex(t, bp, platformSdkVersion)
+
+	testApexError(t, `"myapex" .*: min_sdk_version: "31" is later than the platform SDK version "30"`, bp,
+		platformSdkVersion,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.EnforceUpdatableConsistency = proptools.BoolPtr(true)
+		}))
+}
+
 func TestUpdatable_should_not_set_generate_classpaths_proto(t *testing.T) {
 	testApexError(t, `"mysystemserverclasspathfragment" .* it must not set generate_classpaths_proto to false`, `
 		apex {
@@ -447,6 +447,35 @@ func TestBootclasspathFragmentInArtApex(t *testing.T) {
 		}
 	})
 
+	t.Run("boot image profile with sampled profiles", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			commonPreparer,
+
+			// Configure some libraries in the art bootclasspath_fragment that match the source
+			// bootclasspath_fragment's contents property.
+			java.FixtureConfigureBootJars("com.android.art:foo", "com.android.art:bar"),
+			addSource("foo", "bar"),
+			dexpreopt.FixtureSetBootImageSampledProfiles("art/build/boot/sampled.prof"),
+		).RunTest(t)
+
+		ensureExactContents(t, result.TestContext, "com.android.art", "android_common_com.android.art_image", []string{
+			"etc/boot-image.prof",
+			"etc/classpaths/bootclasspath.pb",
+			"javalib/bar.jar",
+			"javalib/foo.jar",
+		})
+
+		// The text profile is converted by profman and then merged with the sampled profile.
+		rule := result.ModuleForTests("mybootclasspathfragment", "android_common_apex10000").Rule("bootJarsProfile")
+		command := rule.RuleParams.Command
+		android.AssertStringDoesContain(t, "text profile", command,
+			"dex_artjars/boot-image-profile.txt --apk=")
+		android.AssertStringDoesContain(t, "merged profiles", command,
+			"--boot-image-merge --force-merge --profile-file=")
+		android.AssertStringDoesContain(t, "merged profiles", command,
+			"dex_artjars/boot-text.prof --profile-file=art/build/boot/sampled.prof --apk=")
+	})
+
 	t.Run("boot image files with preferred prebuilt", func(t *testing.T) {
 		result := android.GroupFixturePreparers(
 			commonPreparer,
@@ -638,6 +667,99 @@ func TestBootclasspathFragmentInPrebuiltArtApex(t *testing.T) {
 	})
 }
 
+func TestBootclasspathFragmentInPrebuiltArtApex_PrebuiltBootImage(t *testing.T) {
+	bp := `
+		prebuilt_apex {
+			name: "com.android.art",
+			arch: {
+				arm64: {
+					src: "com.android.art-arm64.apex",
+				},
+				arm: {
+					src: "com.android.art-arm.apex",
+				},
+			},
+			exported_bootclasspath_fragments: ["mybootclasspathfragment"],
+		}
+
+		java_import {
+			name: "foo",
+			jars: ["foo.jar"],
+			apex_available: [
+				"com.android.art",
+			],
+		}
+
+		prebuilt_bootclasspath_fragment {
+			name: "mybootclasspathfragment",
+			image_name: "art",
+			contents: ["foo"],
+			apex_available: [
+				"com.android.art",
+			],
+			hidden_api: {
+				annotation_flags: "mybootclasspathfragment/annotation-flags.csv",
+				metadata: "mybootclasspathfragment/metadata.csv",
+				index: "mybootclasspathfragment/index.csv",
+				stub_flags: "mybootclasspathfragment/stub-flags.csv",
+				all_flags: "mybootclasspathfragment/all-flags.csv",
+			},
+			%s
+		}
+	`
+
+	preparers := android.GroupFixturePreparers(
+		prepareForTestWithBootclasspathFragment,
+		prepareForTestWithArtApex,
+
+		android.FixtureMergeMockFs(android.MockFS{
+			"com.android.art-arm64.apex": nil,
+			"com.android.art-arm.apex":   nil,
+			"art-boot-image.zip":         nil,
+			"other-boot-image.zip":       nil,
+		}),
+
+		java.FixtureConfigureBootJars("com.android.art:foo"),
+		java.FixtureSetBootImageInstallDirOnDevice("art", "apex/com.android.art/javalib"),
+	)
+
+	checkPrebuiltBootImage := func(t *testing.T, result *android.TestResult, zip string) {
+		t.Helper()
+		module := result.ModuleForTests("mybootclasspathfragment", "android_common_com.android.art")
+		rule := module.Output("out/soong/dexpreopt_arm64/dex_artjars/android/boot/apex/com.android.art/javalib/arm64/boot.art")
+		command := rule.RuleParams.Command
+		android.AssertStringDoesContain(t, "extracts the prebuilt boot image", command,
+			"rm -rf out/soong/dexpreopt_arm64/dex_artjars/android/boot/apex/com.android.art/javalib/arm64 && "+
+				"unzip -qoDD -d out/soong/dexpreopt_arm64/dex_artjars/android/boot "+zip+" 'apex/com.android.art/javalib/arm64/*'")
+		check := module.Output("prebuilt_boot_image_check/art/arm64.oatdump.txt")
+		android.AssertStringDoesContain(t, "checks the prebuilt boot image", check.RuleParams.Command,
+			"oatdump --header-only --runtime-arg -Xbootclasspath:out/soong/dexpreopt_arm64/dex_artjars_input/foo.jar")
+		android.AssertPathsRelativeToTopEquals(t, "validations", []string{check.Output.String()}, rule.Validations)
+		android.AssertStringDoesNotContain(t, "does not compile the boot image", command, "dex2oat")
+		android.AssertPathsRelativeToTopEquals(t, "outputs", []string{
+			"out/soong/dexpreopt_arm64/dex_artjars/android/boot/apex/com.android.art/javalib/arm64/boot.art",
+			"out/soong/dexpreopt_arm64/dex_artjars/android/boot/apex/com.android.art/javalib/arm64/boot.oat",
+			"out/soong/dexpreopt_arm64/dex_artjars/android/boot/apex/com.android.art/javalib/arm64/boot.vdex",
+		}, rule.ImplicitOutputs.Paths())
+	}
+
+	t.Run("dexpreopt config", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparers,
+			dexpreopt.FixtureSetPrebuiltArtBootImage("art-boot-image.zip"),
+		).RunTestWithBp(t, fmt.Sprintf(bp, ""))
+		checkPrebuiltBootImage(t, result, "art-boot-image.zip")
+	})
+
+	t.Run("property", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparers,
+			dexpreopt.FixtureSetPrebuiltArtBootImage("art-boot-image.zip"),
+		).RunTestWithBp(t, fmt.Sprintf(bp, `prebuilt_boot_image: "other-boot-image.zip",`))
+		checkPrebuiltBootImage(t, result, "other-boot-image.zip")
+	})
+}
+
 // checkCopiesToPredefinedLocationForArt checks that the supplied modules are copied to the
 // predefined locations of boot dex jars used as inputs for the ART boot image.
 func checkCopiesToPredefinedLocationForArt(t *testing.T, config android.Config, module android.TestingModule, modules ...string) {
@@ -758,6 +880,18 @@ func TestBootclasspathFragmentContentsNoName(t *testing.T) {
 
 	checkFragmentExportedDexJar("foo", "out/soong/.intermediates/mybootclasspathfragment/android_common_apex10000/hiddenapi-modular/encoded/foo.jar")
 	checkFragmentExportedDexJar("bar", "out/soong/.intermediates/mybootclasspathfragment/android_common_apex10000/hiddenapi-modular/encoded/bar.jar")
+
+	// Make sure that the dex jars are encoded by a single rule with a single hiddenapi pass, that only
+	// updates the encoded dex jars that changed.
+	encodeRule := result.ModuleForTests("mybootclasspathfragment", "android_common_apex10000").Rule("hiddenAPIEncodeBootDexJars")
+	encodedDexJars := android.StringPathsRelativeToTop(result.Config.SoongOutDir(), encodeRule.AllOutputs())
+	sort.Strings(encodedDexJars)
+	android.AssertArrayString(t, "encoded dex jars", []string{
+		"out/soong/.intermediates/mybootclasspathfragment/android_common_apex10000/hiddenapi-modular/encoded/bar.jar",
+		"out/soong/.intermediates/mybootclasspathfragment/android_common_apex10000/hiddenapi-modular/encoded/foo.jar",
+	}, encodedDexJars)
+	android.AssertIntEquals(t, "hiddenapi passes", 1, strings.Count(encodeRule.RuleParams.Command, "hiddenapi encode"))
+	android.AssertBoolEquals(t, "restat", true, encodeRule.RuleParams.Restat)
 }
 
 func getDexJarPath(result *android.TestResult, name string) string {
@@ -1248,4 +1382,64 @@ func TestBootclasspathFragment_AndroidNonUpdatable_AlwaysUsePrebuiltSdks(t *test
 	android.AssertStringDoesContain(t, "test", command, "--test-stub-classpath="+nonUpdatableTestStubs)
 }
 
+func TestBootJarsConfigCheck_OverrideApex(t *testing.T) {
+	// The jars of the apexes may be listed under the name of the apex variation, or under the name
+	// of an override_apex module.
+	result := android.GroupFixturePreparers(
+		prepareForTestWithBootclasspathFragment,
+		prepareForTestWithMyapex,
+		java.PrepareForTestWithBootJarsConfigCheck,
+		dexpreopt.FixtureSetApexBootJars("myapex:foo", "override_myapex:bar"),
+	).RunTestWithBp(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			bootclasspath_fragments: [
+				"mybootclasspathfragment",
+			],
+			updatable: false,
+		}
+
+		override_apex {
+			name: "override_myapex",
+			base: "myapex",
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_library {
+			name: "foo",
+			srcs: ["b.java"],
+			installable: true,
+			apex_available: ["myapex"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			installable: true,
+			apex_available: ["myapex"],
+		}
+
+		bootclasspath_fragment {
+			name: "mybootclasspathfragment",
+			contents: [
+				"foo",
+				"bar",
+			],
+			apex_available: ["myapex"],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+	`)
+
+	report := result.SingletonForTests("boot_jars_config_check").Output("boot_jars_config_check.txt")
+	android.AssertStringEquals(t, "report", "", android.ContentFromFileRuleForTests(t, report))
+}
+
 // TODO(b/177892522) - add test for host apex.
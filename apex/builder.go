@@ -235,8 +235,54 @@ var (
 		CommandDeps: []string{"${apex_sepolicy_tests}", "${deapexer}", "${debugfs_static}"},
 		Description: "run apex_sepolicy_tests",
 	})
+
+	// Adds the dm-verity hashtree to a copy of the apex_payload.img of an APEX built without it,
+	// and writes the vbmeta image with the hashtree descriptor to ${out}, signed with the key of the
+	// APEX. apexer still adds a vbmeta without hashtree to the payload, so the partition name, i.e.
+	// the apex_name of the manifest, the salt and the signing algorithm are read from it, for the
+	// metadata to match the one apexer would have generated. The metadata is then verified against
+	// the payload and the key.
+	apexVerityMetadataRule = pctx.StaticRule("apexVerityMetadataRule", blueprint.RuleParams{
+		Command: `rm -rf ${out}.tmp && mkdir -p ${out}.tmp && ` +
+			`unzip -q ${in} apex_payload.img -d ${out}.tmp && ` +
+			`${avbtool} info_image --image ${out}.tmp/apex_payload.img > ${out}.tmp/info.txt && ` +
+			`name=$$(sed -n 's/^ *Partition Name: *//p' ${out}.tmp/info.txt) && ` +
+			`salt=$$(sed -n 's/^ *Salt: *//p' ${out}.tmp/info.txt) && ` +
+			`algorithm=$$(sed -n 's/^Algorithm: *//p' ${out}.tmp/info.txt) && ` +
+			`mv ${out}.tmp/apex_payload.img ${out}.tmp/$$name.img && ` +
+			`${avbtool} add_hashtree_footer --image ${out}.tmp/$$name.img ` +
+			`--key ${key} --algorithm $$algorithm --prop apex.key:${key_name} ` +
+			`--partition_name $$name --salt $$salt --hash_algorithm sha256 --do_not_generate_fec ` +
+			`--output_vbmeta_image ${out}.tmp/vbmeta.img --do_not_append_vbmeta_image && ` +
+			`${avbtool} verify_image --image ${out}.tmp/vbmeta.img --key ${key} && ` +
+			`mv ${out}.tmp/vbmeta.img ${out} && rm -rf ${out}.tmp`,
+		CommandDeps: []string{"${avbtool}"},
+		Description: "APEX verity metadata ${in} => ${out}",
+	}, "key", "key_name")
 )
 
+// buildVerityMetadata creates the build rule of the dm-verity metadata of an APEX whose payload is
+// built without its hashtree, see the generate_hashtree property. The metadata is not built with
+// the APEX, so that iterating on the APEX locally does not pay for the hashtree, but by the
+// <apex>-verity-metadata target, e.g. later in the release pipeline, and by the filesystem images
+// that include the APEX, which validate it.
+func (a *apexBundle) buildVerityMetadata(ctx android.ModuleContext, apexFile android.Path) {
+	verityMetadata := android.PathForModuleOut(ctx, a.Name()+".verity_metadata.img")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:     apexVerityMetadataRule,
+		Input:    apexFile,
+		Implicit: a.privateKeyFile,
+		Output:   verityMetadata,
+		Args: map[string]string{
+			"key": a.privateKeyFile.String(),
+			// apexer names the key of the payload after the private key file.
+			"key_name": strings.TrimSuffix(a.privateKeyFile.Base(), filepath.Ext(a.privateKeyFile.Base())),
+		},
+	})
+	ctx.Phony(a.Name()+"-verity-metadata", verityMetadata)
+	a.verityMetadataFile = verityMetadata
+}
+
 // buildManifest creates buile rules to modify the input apex_manifest.json to add information
 // gathered by the build system such as provided/required native libraries. Two output files having
 // different formats are generated. a.manifestJsonOut is JSON format for Q devices, and
@@ -697,6 +743,7 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext) {
 		if !needHashTree {
 			optFlags = append(optFlags, "--no_hashtree")
 		}
+		a.generatesHashtree = needHashTree
 
 		if a.testOnlyShouldSkipPayloadSign() {
 			optFlags = append(optFlags, "--unsigned_payload")
@@ -763,6 +810,11 @@ func (a *apexBundle) buildUnflattenedApex(ctx android.ModuleContext) {
 			})
 		}
 
+		// An unsigned payload has no vbmeta to take the metadata of the hashtree from.
+		if !needHashTree && !a.testOnlyShouldSkipPayloadSign() {
+			a.buildVerityMetadata(ctx, unsignedOutputFile)
+		}
+
 		// TODO(jiyong): make the two rules below as separate functions
 		apexProtoFile := android.PathForModuleOut(ctx, a.Name()+".pb"+suffix)
 		bundleModuleFile := android.PathForModuleOut(ctx, a.Name()+suffix+"-base.zip")
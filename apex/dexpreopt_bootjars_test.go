@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"android/soong/android"
+	"android/soong/dexpreopt"
 	"android/soong/java"
 )
 
@@ -155,35 +156,23 @@ func testDexpreoptBoot(t *testing.T, ruleFile string, expectedInputs, expectedOu
 }
 
 func TestDexpreoptBootJarsWithSourceArtApex(t *testing.T) {
+	// The jars of the framework boot image are compiled one by one, boot.art is the component of
+	// core-oj.
 	ruleFile := "boot.art"
 
 	expectedInputs := []string{
 		"out/soong/dexpreopt_arm64/dex_bootjars_input/core-oj.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/bar.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/baz.jar",
 		"out/soong/dexpreopt_arm64/dex_artjars/boot.prof",
 		"out/soong/dexpreopt_arm64/dex_bootjars/boot.prof",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64.sbox.textproto",
 	}
 
 	expectedOutputs := []string{
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.invocation",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-foo.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-bar.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-baz.oat",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.invocation",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.oat",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.vdex",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/symbols/boot.oat",
 	}
 
 	testDexpreoptBoot(t, ruleFile, expectedInputs, expectedOutputs, false)
@@ -196,36 +185,292 @@ func TestDexpreoptBootJarsWithPrebuiltArtApex(t *testing.T) {
 
 	expectedInputs := []string{
 		"out/soong/dexpreopt_arm64/dex_bootjars_input/core-oj.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/bar.jar",
-		"out/soong/dexpreopt_arm64/dex_bootjars_input/baz.jar",
 		"out/soong/.intermediates/com.android.art.deapexer/android_common/deapexer/etc/boot-image.prof",
 		"out/soong/dexpreopt_arm64/dex_bootjars/boot.prof",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64.sbox.textproto",
 	}
 
 	expectedOutputs := []string{
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.invocation",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.art",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-foo.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-bar.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars/android/system/framework/arm64/boot-baz.vdex",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-foo.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-bar.oat",
-		"out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/boot-baz.oat",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.invocation",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.oat",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.vdex",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/symbols/boot.oat",
 	}
 
 	testDexpreoptBoot(t, ruleFile, expectedInputs, expectedOutputs, true)
 }
 
+// The ART profile is only merged into the framework boot image profile if the ART
+// bootclasspath_fragment exports it, which it does by default.
+func TestDexpreoptBootJarsWithoutArtProfileExport(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		java.PrepareForTestWithDexpreopt,
+		java.FixtureConfigureBootJars("com.android.art:core-oj", "platform:foo"),
+		PrepareForTestWithApexBuildComponents,
+		prepareForTestWithArtApex,
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			installable: true,
+		}
+
+		platform_bootclasspath {
+			name: "platform-bootclasspath",
+			fragments: [
+				{
+					apex: "com.android.art",
+					module: "art-bootclasspath-fragment",
+				},
+			],
+		}
+
+		java_library {
+			name: "core-oj",
+			srcs: ["core-oj.java"],
+			installable: true,
+			apex_available: [
+				"com.android.art",
+			],
+		}
+
+		bootclasspath_fragment {
+			name: "art-bootclasspath-fragment",
+			image_name: "art",
+			export_profile: false,
+			contents: ["core-oj"],
+			apex_available: [
+				"com.android.art",
+			],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+
+		apex_key {
+			name: "com.android.art.key",
+			public_key: "com.android.art.avbpubkey",
+			private_key: "com.android.art.pem",
+		}
+
+		apex {
+			name: "com.android.art",
+			key: "com.android.art.key",
+			bootclasspath_fragments: ["art-bootclasspath-fragment"],
+			updatable: false,
+		}
+	`)
+
+	rule := result.ModuleForTests("platform-bootclasspath", "android_common").Output("boot.art")
+	inputs := android.StringPathsRelativeToTop(result.Config.SoongOutDir(), rule.Implicits.Strings())
+	android.AssertStringListContains(t, "inputs", inputs, "out/soong/dexpreopt_arm64/dex_bootjars/boot.prof")
+	android.AssertStringListDoesNotContain(t, "inputs", inputs, "out/soong/dexpreopt_arm64/dex_artjars/boot.prof")
+}
+
+// A bootclasspath_fragment without an image_name can export a profile, which is converted against
+// the dex jars of its contents and merged into the framework boot image profile.
+func TestDexpreoptBootJarsWithFragmentProfileExport(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		java.PrepareForTestWithDexpreopt,
+		java.FixtureConfigureBootJars("platform:foo"),
+		java.FixtureConfigureApexBootJars("myapex:bar"),
+		PrepareForTestWithApexBuildComponents,
+		prepareForTestWithMyapex,
+		android.FixtureMergeMockFs(android.MockFS{
+			"bar-profile.txt": nil,
+		}),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			installable: true,
+		}
+
+		platform_bootclasspath {
+			name: "platform-bootclasspath",
+			fragments: [
+				{
+					apex: "myapex",
+					module: "mybootclasspathfragment",
+				},
+			],
+		}
+
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			bootclasspath_fragments: ["mybootclasspathfragment"],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			installable: true,
+			apex_available: ["myapex"],
+		}
+
+		bootclasspath_fragment {
+			name: "mybootclasspathfragment",
+			contents: ["bar"],
+			export_profile: true,
+			profile: "bar-profile.txt",
+			apex_available: ["myapex"],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+	`)
+
+	fragment := result.ModuleForTests("mybootclasspathfragment", "android_common_apex10000")
+	profile := fragment.Output("profile/boot.prof")
+	android.AssertStringDoesContain(t, "profman command", profile.RuleParams.Command,
+		"--create-profile-from=bar-profile.txt")
+	android.AssertStringDoesContain(t, "profman command", profile.RuleParams.Command,
+		"--dex-location=/apex/myapex/javalib/bar.jar")
+
+	dex2oat := java.BootImageDex2oatCommandForTests(t, result.ModuleForTests("platform-bootclasspath", "android_common"),
+		"bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--profile-file=__SBOX_SANDBOX_DIR__/out/.intermediates/mybootclasspathfragment/android_common_apex10000/profile/boot.prof")
+}
+
+// A bootclasspath_fragment without an image_name must set the profile property to export a profile.
+func TestDexpreoptBootJarsWithFragmentProfileExportWithoutProfile(t *testing.T) {
+	android.GroupFixturePreparers(
+		java.PrepareForTestWithDexpreopt,
+		java.FixtureConfigureBootJars("platform:foo"),
+		java.FixtureConfigureApexBootJars("myapex:bar"),
+		PrepareForTestWithApexBuildComponents,
+		prepareForTestWithMyapex,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`export_profile: requires the profile property to be set for a bootclasspath_fragment without an image_name`,
+	)).RunTestWithBp(t, `
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			bootclasspath_fragments: ["mybootclasspathfragment"],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			installable: true,
+			apex_available: ["myapex"],
+		}
+
+		bootclasspath_fragment {
+			name: "mybootclasspathfragment",
+			contents: ["bar"],
+			export_profile: true,
+			apex_available: ["myapex"],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+	`)
+}
+
+func TestDexpreoptBootJarsUpdatableBcpPackages(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		java.PrepareForTestWithDexpreopt,
+		java.FixtureConfigureBootJars("platform:foo"),
+		java.FixtureConfigureApexBootJars("myapex:bar"),
+		dexpreopt.FixtureSetApexSystemServerJars("myapex:baz"),
+		PrepareForTestWithApexBuildComponents,
+		prepareForTestWithMyapex,
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			installable: true,
+		}
+
+		platform_bootclasspath {
+			name: "platform-bootclasspath",
+			fragments: [
+				{
+					apex: "myapex",
+					module: "mybootclasspathfragment",
+				},
+			],
+		}
+
+		apex {
+			name: "myapex",
+			key: "myapex.key",
+			bootclasspath_fragments: ["mybootclasspathfragment"],
+			systemserverclasspath_fragments: ["mysystemserverclasspathfragment"],
+			updatable: false,
+		}
+
+		apex_key {
+			name: "myapex.key",
+			public_key: "testkey.avbpubkey",
+			private_key: "testkey.pem",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+			installable: true,
+			permitted_packages: ["com.android.bar"],
+			apex_available: ["myapex"],
+		}
+
+		bootclasspath_fragment {
+			name: "mybootclasspathfragment",
+			contents: ["bar"],
+			apex_available: ["myapex"],
+			hidden_api: {
+				split_packages: ["*"],
+			},
+		}
+
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+			installable: true,
+			permitted_packages: ["com.android.baz"],
+			apex_available: ["myapex"],
+		}
+
+		systemserverclasspath_fragment {
+			name: "mysystemserverclasspathfragment",
+			contents: ["baz"],
+			apex_available: ["myapex"],
+		}
+	`)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	packages := platformBootclasspath.Output("updatable-bcp-packages.txt")
+	android.AssertStringEquals(t, "updatable-bcp-packages.txt", "com.android.bar\ncom.android.baz\n",
+		android.ContentFromFileRuleForTests(t, packages))
+
+	dex2oat := java.BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--updatable-bcp-packages-file=__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_bootjars/updatable-bcp-packages.txt")
+
+	// The boot image extension of the apex boot jars is compiled without it.
+	mainline := platformBootclasspath.Output("boot-bar.art")
+	android.AssertStringDoesNotContain(t, "mainline dex2oat command", mainline.RuleParams.Command,
+		"--updatable-bcp-packages-file")
+}
+
 // Changes to the boot.zip structure may break the ART APK scanner.
 func TestDexpreoptBootZip(t *testing.T) {
 	ruleFile := "boot.zip"
@@ -543,7 +543,7 @@ func (p *ApexFileProperties) prebuiltApexSelector(ctx android.BaseModuleContext,
 	}
 
 	if src == "" {
-		if ctx.Config().AllowMissingDependencies() {
+		if ctx.AllowMissingDependencies() {
 			ctx.AddMissingDependencies([]string{ctx.OtherModuleName(prebuilt)})
 		} else {
 			ctx.OtherModuleErrorf(prebuilt, "prebuilt_apex does not support %q", multiTargets[0].Arch.String())
@@ -0,0 +1,114 @@
+// Copyright (C) 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apex
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"android/soong/android"
+)
+
+// The APEXes listed in the SOONG_APEX_STAGING environment variable get a <apex>-staging phony
+// target for local iteration. It builds an unsigned and uncompressed staging directory, laid out
+// like a flattened APEX, and a script that pushes the staging directory to a device. Each file is
+// copied into the staging directory by its own rule and the script pushes with adb push --sync, so
+// that changing one member of the APEX only copies and pushes that member, without re-packaging
+// and re-signing the whole APEX.
+
+// stagingApexes returns the names of the APEXes listed in SOONG_APEX_STAGING.
+func stagingApexes(config android.Config) []string {
+	return strings.Fields(strings.ReplaceAll(config.Getenv("SOONG_APEX_STAGING"), ",", " "))
+}
+
+// buildStagingDir generates the rules for the <apex>-staging phony target if the APEX is listed in
+// SOONG_APEX_STAGING.
+func (a *apexBundle) buildStagingDir(ctx android.ModuleContext) {
+	if a.properties.ApexType != imageApex || !android.InList(a.Name(), stagingApexes(ctx.Config())) {
+		return
+	}
+
+	apexName := a.BaseModuleName()
+	stagingDir := android.PathForModuleOut(ctx, "staging", apexName)
+	var staged android.Paths
+	stage := func(src android.Path, rel string) {
+		dest := stagingDir.Join(ctx, rel)
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Cp,
+			Input:  src,
+			Output: dest,
+		})
+		staged = append(staged, dest)
+	}
+
+	var symlinks []string
+	for _, fi := range a.filesInfo {
+		if a.linkToSystemLib && fi.transitiveDep && fi.availableToPlatform() {
+			symlinks = append(symlinks, fi.path()+" "+filepath.Join("/", fi.partition, fi.path()))
+			continue
+		}
+		stage(fi.builtFile, fi.path())
+		for _, symlinkPath := range fi.symlinkPaths() {
+			symlinks = append(symlinks, symlinkPath+" "+fi.stem())
+		}
+		for _, d := range fi.dataPaths {
+			stage(d.SrcPath, filepath.Join(fi.apexRelativePath(d.SrcPath.Rel()), d.RelativeInstallPath))
+		}
+	}
+	stage(a.manifestPbOut, "apex_manifest.pb")
+	stage(a.publicKeyFile, "apex_pubkey")
+
+	script := android.PathForModuleOut(ctx, "staging", apexName+"-push.sh")
+	scriptSrc := android.PathForModuleOut(ctx, "staging", apexName+"-push.sh.tmp")
+	android.WriteFileRule(ctx, scriptSrc, stagingPushScript(apexName, symlinks))
+	ctx.Build(pctx, android.BuildParams{
+		Rule:   android.CpExecutable,
+		Input:  scriptSrc,
+		Output: script,
+	})
+
+	ctx.Phony(a.Name()+"-staging", append(staged, script)...)
+}
+
+// stagingPushScript returns the script that pushes the staging directory of the APEX to the
+// flattened APEX directory on the device, or to the directory given as its argument. The script
+// fails if the device does not have the flattened APEX directory, i.e. if the APEX is installed as
+// an .apex file, as the files pushed there would not be used. Each symlink is given as
+// "<path in the apex> <target>".
+func stagingPushScript(apexName string, symlinks []string) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "#!/bin/bash -e")
+	fmt.Fprintf(&b, "# Pushes the unsigned staging directory of %s to the device for local iteration.\n", apexName)
+	fmt.Fprintln(&b, "# The device must allow remounting its partitions, e.g. after adb disable-verity.")
+	fmt.Fprintf(&b, "STAGING_DIR=\"$(dirname \"${BASH_SOURCE[0]}\")/%s\"\n", apexName)
+	fmt.Fprintf(&b, "DEST=\"${1:-/system/apex/%s}\"\n", apexName)
+	fmt.Fprintln(&b, "adb root")
+	fmt.Fprintln(&b, "adb wait-for-device")
+	fmt.Fprintln(&b, "adb remount")
+	fmt.Fprintln(&b, "if ! adb shell test -d \"${DEST}\"; then")
+	fmt.Fprintln(&b, "  echo \"error: ${DEST} is not a directory on the device, the device must use flattened APEXes.\" >&2")
+	fmt.Fprintln(&b, "  echo \"Install the APEX with adb install instead, or pass the directory to push to.\" >&2")
+	fmt.Fprintln(&b, "  exit 1")
+	fmt.Fprintln(&b, "fi")
+	fmt.Fprintln(&b, "adb push --sync \"${STAGING_DIR}/.\" \"${DEST}\"")
+	for _, symlink := range symlinks {
+		fields := strings.Fields(symlink)
+		fmt.Fprintf(&b, "adb shell ln -sfn %s \"${DEST}/%s\"\n", fields[1], fields[0])
+	}
+	fmt.Fprintln(&b, "adb shell stop")
+	fmt.Fprint(&b, "adb shell start")
+	return b.String()
+}
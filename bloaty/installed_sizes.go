@@ -0,0 +1,152 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bloaty
+
+import (
+	"path/filepath"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+const (
+	// Environment variable that enables measuring of all installed ELF files.
+	installedSizesEnvVar = "DIST_INSTALLED_BINARY_SIZES"
+
+	installedSizesDir           = "installed_binary_sizes"
+	installedSizesCsvFilename   = "installed_binary_sizes.csv"
+	installedSizesProtoFilename = "installed_binary_sizes.pb.gz"
+	installedSizesCsvHeader     = "module,partition,file,sections,vmsize,filesize"
+)
+
+var (
+	// bloatyInstalled measures the sections of an installed file. In addition to the bloaty
+	// csv, it writes the rows prefixed by the module, the partition and the installed path so
+	// that they can be concatenated into a single table.
+	bloatyInstalled = pctx.AndroidStaticRule("bloatyInstalled",
+		blueprint.RuleParams{
+			Command: "${bloaty} -n 0 --csv ${in} > ${out} && " +
+				"tail -n +2 ${out} | sed -e 's|^|${module},${partition},${file},|' > ${rows}",
+			CommandDeps: []string{"${bloaty}"},
+		}, "module", "partition", "file", "rows")
+
+	// bloatyInstalledCsv concatenates the rows of all the installed files.
+	bloatyInstalledCsv = pctx.AndroidStaticRule("bloatyInstalledCsv",
+		blueprint.RuleParams{
+			Command:        "echo '" + installedSizesCsvHeader + "' > ${out} && xargs cat < ${out}.rsp >> ${out}",
+			Rspfile:        "${out}.rsp",
+			RspfileContent: "${in}",
+		})
+)
+
+func init() {
+	android.RegisterSingletonType("installed_file_metrics", installedFileSizesSingleton)
+}
+
+// unstrippedOutputFileProducer is implemented by the modules that link ELF files, i.e. cc and
+// rust modules.
+type unstrippedOutputFileProducer interface {
+	UnstrippedOutputFile() android.Path
+}
+
+type installedSizesSingleton struct {
+	csv   android.Path
+	proto android.Path
+}
+
+func installedFileSizesSingleton() android.Singleton {
+	return &installedSizesSingleton{}
+}
+
+// GenerateBuildActions measures the section sizes of every ELF file installed on a device
+// partition, and aggregates the results in a csv table grouped by module and partition and in a
+// protobuf. It is a no-op unless DIST_INSTALLED_BINARY_SIZES is set, as it runs bloaty on every
+// installed binary and shared library.
+func (s *installedSizesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !ctx.Config().IsEnvTrue(installedSizesEnvVar) {
+		return
+	}
+
+	measured := make(map[string]android.WritablePath)
+	var rows android.Paths
+	ctx.VisitAllModules(func(m android.Module) {
+		if !m.Enabled() || m.IsSkipInstall() || m.Target().Os.Class != android.Device {
+			return
+		}
+		if p, ok := m.(unstrippedOutputFileProducer); !ok || p.UnstrippedOutputFile() == nil {
+			return
+		}
+		for _, spec := range m.PackagingSpecs() {
+			if spec.SymlinkTarget() != "" || spec.SrcPath() == nil {
+				continue
+			}
+			partition := spec.Partition()
+			file := filepath.Join(partition, spec.RelPathInPackage())
+			if _, exists := measured[file]; exists {
+				continue
+			}
+			sizeFile := android.PathForOutput(ctx, installedSizesDir, file+bloatyDescriptorExt)
+			rowsFile := android.PathForOutput(ctx, installedSizesDir, file+".rows.csv")
+			ctx.Build(pctx, android.BuildParams{
+				Rule:           bloatyInstalled,
+				Description:    "bloaty " + file,
+				Input:          spec.SrcPath(),
+				Output:         sizeFile,
+				ImplicitOutput: rowsFile,
+				Args: map[string]string{
+					"module":    ctx.ModuleName(m),
+					"partition": partition,
+					"file":      file,
+					"rows":      rowsFile.String(),
+				},
+			})
+			measured[file] = sizeFile
+			rows = append(rows, rowsFile)
+		}
+	})
+
+	var sizeFiles android.Paths
+	for _, file := range android.SortedKeys(measured) {
+		sizeFiles = append(sizeFiles, measured[file])
+	}
+
+	csv := android.PathForOutput(ctx, installedSizesCsvFilename)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bloatyInstalledCsv,
+		Description: "installed binary sizes csv",
+		Inputs:      android.SortedUniquePaths(rows),
+		Output:      csv,
+	})
+
+	proto := android.PathForOutput(ctx, installedSizesProtoFilename)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        bloatyMerger,
+		Description: "installed binary sizes protobuf",
+		Inputs:      sizeFiles,
+		Output:      proto,
+	})
+
+	s.csv = csv
+	s.proto = proto
+}
+
+func (s *installedSizesSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.csv == nil {
+		return
+	}
+	ctx.DistForGoalWithFilename("checkbuild", s.csv, installedSizesCsvFilename)
+	ctx.DistForGoalWithFilename("checkbuild", s.proto, installedSizesProtoFilename)
+}
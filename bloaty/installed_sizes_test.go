@@ -0,0 +1,111 @@
+// Copyright 2023 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bloaty
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+// installedSizesTestModule installs an ELF-like file, with an unstripped output when elf is set.
+type installedSizesTestModule struct {
+	android.ModuleBase
+	properties struct {
+		Elf *bool
+	}
+	unstripped android.Path
+}
+
+func installedSizesTestModuleFactory() android.Module {
+	m := &installedSizesTestModule{}
+	m.AddProperties(&m.properties)
+	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibFirst)
+	return m
+}
+
+func (m *installedSizesTestModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	out := android.PathForModuleOut(ctx, ctx.ModuleName()+".so")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:   android.Touch,
+		Output: out,
+	})
+	if android.Bool(m.properties.Elf) {
+		m.unstripped = out
+	}
+	ctx.InstallFile(android.PathForModuleInstall(ctx, "lib64"), out.Base(), out)
+}
+
+func (m *installedSizesTestModule) UnstrippedOutputFile() android.Path {
+	return m.unstripped
+}
+
+const installedSizesTestBp = `
+	installed_sizes_test_module {
+		name: "libfoo",
+		elf: true,
+	}
+
+	installed_sizes_test_module {
+		name: "notelf",
+	}
+`
+
+var prepareForInstalledSizesTest = android.GroupFixturePreparers(
+	android.PrepareForTestWithArchMutator,
+	PrepareForTestWithInstalledFileMetrics,
+	android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+		ctx.RegisterModuleType("installed_sizes_test_module", installedSizesTestModuleFactory)
+	}),
+)
+
+func TestInstalledFileMetrics(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForInstalledSizesTest,
+		android.FixtureMergeEnv(map[string]string{
+			installedSizesEnvVar: "true",
+		}),
+	).RunTestWithBp(t, installedSizesTestBp)
+
+	metrics := result.SingletonForTests("installed_file_metrics")
+
+	bloaty := metrics.Output("installed_binary_sizes/system/lib64/libfoo.so.bloaty.csv")
+	android.AssertPathRelativeToTopEquals(t, "measured file",
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a/libfoo.so", bloaty.Input)
+	android.AssertStringEquals(t, "module", "libfoo", bloaty.Args["module"])
+	android.AssertStringEquals(t, "partition", "system", bloaty.Args["partition"])
+	android.AssertStringEquals(t, "file", "system/lib64/libfoo.so", bloaty.Args["file"])
+
+	if notelf := metrics.MaybeOutput("installed_binary_sizes/system/lib64/notelf.so.bloaty.csv"); notelf.Rule != nil {
+		t.Errorf("unexpected measure of a file without unstripped output")
+	}
+
+	csv := metrics.Output("installed_binary_sizes.csv")
+	android.AssertPathsRelativeToTopEquals(t, "csv rows",
+		[]string{"out/soong/installed_binary_sizes/system/lib64/libfoo.so.rows.csv"}, csv.Inputs)
+
+	proto := metrics.Output("installed_binary_sizes.pb.gz")
+	android.AssertPathsRelativeToTopEquals(t, "proto inputs",
+		[]string{"out/soong/installed_binary_sizes/system/lib64/libfoo.so.bloaty.csv"}, proto.Inputs)
+}
+
+func TestInstalledFileMetricsDisabled(t *testing.T) {
+	result := prepareForInstalledSizesTest.RunTestWithBp(t, installedSizesTestBp)
+
+	metrics := result.SingletonForTests("installed_file_metrics")
+	if csv := metrics.MaybeOutput("installed_binary_sizes.csv"); csv.Rule != nil {
+		t.Errorf("unexpected installed binary sizes without %s", installedSizesEnvVar)
+	}
+}
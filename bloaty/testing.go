@@ -23,3 +23,8 @@ var PrepareForTestWithBloatyDefaultModules = android.GroupFixturePreparers(
 	android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
 		ctx.RegisterSingletonType("file_metrics", fileSizesSingleton)
 	}))
+
+// Preparer that will define the singleton measuring the installed files.
+var PrepareForTestWithInstalledFileMetrics = android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("installed_file_metrics", installedFileSizesSingleton)
+})
@@ -108,7 +108,7 @@ func CreateSoongInjectionDirFiles(ctx *CodegenContext, metrics CodegenMetrics) (
 		return nil, err
 	}
 	ret = append(ret, productConfigFiles...)
-	injectionFiles, err := soongInjectionFiles(ctx.Config(), metrics)
+	injectionFiles, err := soongInjectionFiles(ctx, metrics)
 	if err != nil {
 		return nil, err
 	}
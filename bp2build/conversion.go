@@ -11,6 +11,7 @@ import (
 	"android/soong/android"
 	"android/soong/cc"
 	cc_config "android/soong/cc/config"
+	"android/soong/java"
 	java_config "android/soong/java/config"
 
 	"android/soong/apex"
@@ -25,7 +26,8 @@ type BazelFile struct {
 }
 
 // PRIVATE: Use CreateSoongInjectionDirFiles instead
-func soongInjectionFiles(cfg android.Config, metrics CodegenMetrics) ([]BazelFile, error) {
+func soongInjectionFiles(ctx android.PathContext, metrics CodegenMetrics) ([]BazelFile, error) {
+	cfg := ctx.Config()
 	var files []BazelFile
 
 	files = append(files, newFile("android", GeneratedBuildFileName, "")) // Creates a //cc_toolchain package.
@@ -45,6 +47,18 @@ func soongInjectionFiles(cfg android.Config, metrics CodegenMetrics) ([]BazelFil
 	}
 	files = append(files, newFile("apex_toolchain", "constants.bzl", apexToolchainVars))
 
+	bootImageConfigs, err := java.BazelBootImageConfigsJson(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bootImageConfigVars, err := java.BazelBootImageConfigVars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, newFile("dexpreopt", GeneratedBuildFileName, `exports_files(["boot_image_configs.json"])`))
+	files = append(files, newFile("dexpreopt", "boot_image_configs.json", string(bootImageConfigs)))
+	files = append(files, newFile("dexpreopt", "boot_image_configs.bzl", bootImageConfigVars))
+
 	files = append(files, newFile("metrics", "converted_modules.txt", strings.Join(metrics.Serialize().ConvertedModules, "\n")))
 
 	convertedModulePathMap, err := json.MarshalIndent(metrics.convertedModulePathMap, "", "\t")
@@ -84,7 +84,7 @@ func TestCreateBazelFiles_QueryView_AddsTopLevelFiles(t *testing.T) {
 
 func TestCreateBazelFiles_Bp2Build_CreatesDefaultFiles(t *testing.T) {
 	testConfig := android.TestConfig("", make(map[string]string), "", make(map[string][]byte))
-	files, err := soongInjectionFiles(testConfig, CreateCodegenMetrics())
+	files, err := soongInjectionFiles(android.PathContextForTesting(testConfig), CreateCodegenMetrics())
 	if err != nil {
 		t.Error(err)
 	}
@@ -125,6 +125,18 @@ func TestCreateBazelFiles_Bp2Build_CreatesDefaultFiles(t *testing.T) {
 			dir:      "apex_toolchain",
 			basename: "constants.bzl",
 		},
+		{
+			dir:      "dexpreopt",
+			basename: GeneratedBuildFileName,
+		},
+		{
+			dir:      "dexpreopt",
+			basename: "boot_image_configs.json",
+		},
+		{
+			dir:      "dexpreopt",
+			basename: "boot_image_configs.bzl",
+		},
 		{
 			dir:      "metrics",
 			basename: "converted_modules.txt",
@@ -16,6 +16,7 @@ package cc
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"android/soong/android"
@@ -51,6 +52,10 @@ type AfdoProperties struct {
 	// automatic feedback-directed optimization using profile data.
 	Afdo bool
 
+	// Whether to use the sampled profile of the module from the AFDO profile directory of the
+	// product, if it has one, when afdo is not set. Defaults to true.
+	Afdo_product_profile *bool
+
 	FdoProfilePath *string `blueprint:"mutated"`
 
 	AfdoRDeps []afdoRdep `blueprint:"mutated"`
@@ -135,16 +140,61 @@ func (c *Module) fdoProfileMutator(ctx android.BottomUpMutatorContext) {
 		return
 	}
 
-	if !c.afdo.afdoEnabled() {
+	if c.afdo == nil {
+		return
+	}
+
+	if c.afdo.afdoEnabled() {
+		ctx.VisitDirectDepsWithTag(FdoProfileTag, func(m android.Module) {
+			if ctx.OtherModuleHasProvider(m, FdoProfileProvider) {
+				info := ctx.OtherModuleProvider(m, FdoProfileProvider).(FdoProfileInfo)
+				c.afdo.Properties.FdoProfilePath = proptools.StringPtr(info.Path.String())
+			}
+		})
 		return
 	}
 
-	ctx.VisitDirectDepsWithTag(FdoProfileTag, func(m android.Module) {
-		if ctx.OtherModuleHasProvider(m, FdoProfileProvider) {
-			info := ctx.OtherModuleProvider(m, FdoProfileProvider).(FdoProfileInfo)
-			c.afdo.Properties.FdoProfilePath = proptools.StringPtr(info.Path.String())
+	if path := c.productAfdoProfile(ctx); path.Valid() {
+		c.afdo.Properties.Afdo = true
+		c.afdo.Properties.FdoProfilePath = proptools.StringPtr(path.String())
+	}
+}
+
+// productAfdoProfile returns the sampled profile of the module from the AFDO profile directory of
+// the product, i.e. <dir>/<module>.afdo, if it exists. Like the afdo property, it only applies to
+// device binaries and shared libraries, and is propagated to their static dependencies.
+func (c *Module) productAfdoProfile(ctx android.BottomUpMutatorContext) android.OptionalPath {
+	dir := ctx.Config().AfdoProfileDir()
+	if dir == "" || ctx.Host() || (c.static() && !c.staticBinary()) {
+		return android.OptionalPath{}
+	}
+	if !proptools.BoolDefault(c.afdo.Properties.Afdo_product_profile, true) {
+		return android.OptionalPath{}
+	}
+	if path, ok := productAfdoProfiles(ctx, dir)[ctx.ModuleName()]; ok {
+		return android.OptionalPathForPath(path)
+	}
+	return android.OptionalPath{}
+}
+
+var productAfdoProfilesKey = android.NewOnceKey("ProductAfdoProfiles")
+
+// productAfdoProfiles returns the sampled profiles in the AFDO profile directory of the product by
+// module name. The directory is globbed once per config, rather than looking up the profile of
+// each module, which would add a glob to the Soong regeneration for each of them.
+func productAfdoProfiles(ctx android.BaseModuleContext, dir string) map[string]android.Path {
+	return ctx.Config().Once(productAfdoProfilesKey, func() interface{} {
+		profiles := make(map[string]android.Path)
+		files, err := ctx.GlobWithDeps(filepath.Join(dir, "*.afdo"), nil)
+		if err != nil {
+			ctx.ModuleErrorf("glob of the AFDO profile directory %q failed: %s", dir, err)
+			return profiles
+		}
+		for _, file := range files {
+			profiles[strings.TrimSuffix(filepath.Base(file), ".afdo")] = android.PathForSource(ctx, file)
 		}
-	})
+		return profiles
+	}).(map[string]android.Path)
 }
 
 var _ FdoProfileMutatorInterface = (*Module)(nil)
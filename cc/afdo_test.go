@@ -21,6 +21,7 @@ import (
 	"android/soong/android"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 type visitDirectDepsInterface interface {
@@ -461,3 +462,57 @@ func TestAfdoDepsWithoutProfile(t *testing.T) {
 		t.Errorf("libFoo missing dependency on non-afdo variant of libBar")
 	}
 }
+
+func TestAfdoProductProfileDir(t *testing.T) {
+	t.Parallel()
+	bp := `
+	cc_library_shared {
+		name: "libTest",
+		srcs: ["test.c"],
+		static_libs: ["libFoo"],
+	}
+
+	cc_library_shared {
+		name: "libOptOut",
+		srcs: ["test.c"],
+		afdo_product_profile: false,
+	}
+
+	cc_library_shared {
+		name: "libNoProfile",
+		srcs: ["test.c"],
+	}
+
+	cc_library_static {
+		name: "libFoo",
+		srcs: ["foo.c"],
+	}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithFdoProfile,
+		prepareForCcTest,
+		android.FixtureAddTextFile("vendor/afdo/libTest.afdo", ""),
+		android.FixtureAddTextFile("vendor/afdo/libOptOut.afdo", ""),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.AfdoProfileDir = proptools.StringPtr("vendor/afdo")
+		}),
+	).RunTestWithBp(t, bp)
+
+	expectedCFlag := "-fprofile-sample-use=vendor/afdo/libTest.afdo"
+
+	// The profile applies to the module and to the afdo variant of its static deps.
+	libTest := result.ModuleForTests("libTest", "android_arm64_armv8-a_shared")
+	android.AssertStringDoesContain(t, "libTest cflags", libTest.Rule("cc").Args["cFlags"], expectedCFlag)
+	android.AssertStringDoesContain(t, "libTest cflags", libTest.Rule("cc").Args["cFlags"],
+		"-funique-internal-linkage-names")
+	libFooAfdoVariant := result.ModuleForTests("libFoo", "android_arm64_armv8-a_static_afdo-libTest")
+	android.AssertStringDoesContain(t, "libFoo cflags", libFooAfdoVariant.Rule("cc").Args["cFlags"], expectedCFlag)
+
+	libOptOut := result.ModuleForTests("libOptOut", "android_arm64_armv8-a_shared")
+	android.AssertStringDoesNotContain(t, "libOptOut cflags", libOptOut.Rule("cc").Args["cFlags"],
+		"-fprofile-sample-use")
+	libNoProfile := result.ModuleForTests("libNoProfile", "android_arm64_armv8-a_shared")
+	android.AssertStringDoesNotContain(t, "libNoProfile cflags", libNoProfile.Rule("cc").Args["cFlags"],
+		"-fprofile-sample-use")
+}
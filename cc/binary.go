@@ -356,7 +356,7 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 	if ctx.Darwin() && deps.DarwinSecondArchOutput.Valid() {
 		fatOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "pre-fat", fileName)
-		transformDarwinUniversalBinary(ctx, fatOutputFile, outputFile, deps.DarwinSecondArchOutput.Path())
+		TransformDarwinUniversalBinary(ctx, fatOutputFile, outputFile, deps.DarwinSecondArchOutput.Path())
 	}
 
 	builderFlags := flagsToBuilderFlags(flags)
@@ -372,6 +372,15 @@ func (binary *binaryDecorator) link(ctx ModuleContext,
 
 	binary.unstrippedOutputFile = outputFile
 
+	// The linker wrapper runs on the unstripped output, so that both the stripped output and the
+	// symbols contain its changes.
+	if deps.LinkerWrapper.Valid() {
+		wrappedOutputFile := outputFile
+		outputFile = android.PathForModuleOut(ctx, "prewrapped", fileName)
+		transformLinkerWrapper(ctx, deps.LinkerWrapper.Path(), &binary.baseLinker.Properties.Linker_wrapper,
+			outputFile, wrappedOutputFile)
+	}
+
 	if String(binary.Properties.Prefix_symbols) != "" {
 		afterPrefixSymbols := outputFile
 		outputFile = android.PathForModuleOut(ctx, "unprefixed", fileName)
@@ -513,6 +522,7 @@ func (binary *binaryDecorator) install(ctx ModuleContext, file android.Path) {
 		binary.baseInstaller.subDir = "bootstrap"
 	}
 	binary.baseInstaller.install(ctx, file)
+	binary.stripper.installSplitDebugFile(ctx, binary.baseInstaller.installDir(ctx))
 
 	var preferredArchSymlinkPath android.OptionalPath
 	for _, symlink := range binary.symlinks {
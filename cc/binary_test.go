@@ -20,6 +20,8 @@ import (
 	"android/soong/bazel/cquery"
 
 	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func TestCcBinaryWithBazel(t *testing.T) {
@@ -120,3 +122,113 @@ func TestBinaryLinkerScripts(t *testing.T) {
 	android.AssertStringDoesContain(t, "missing flag for linker_scripts",
 		binFoo.Args["ldFlags"], "-Wl,--script,bar.ld")
 }
+
+func TestBinaryBuildIdStyle(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BuildIdStyle = proptools.StringPtr("sha1")
+			variables.PartitionBuildIdStyles = map[string]string{"vendor": "uuid"}
+		}),
+	).RunTestWithBp(t, `
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.cc"],
+		}
+
+		cc_binary {
+			name: "bar",
+			srcs: ["bar.cc"],
+			vendor: true,
+		}`)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a").Rule("ld")
+	android.AssertStringDoesContain(t, "build id of foo", foo.Args["ldFlags"], "-Wl,--build-id=sha1")
+
+	bar := result.ModuleForTests("bar", "android_arm64_armv8-a").Rule("ld")
+	android.AssertStringDoesContain(t, "build id of bar", bar.Args["ldFlags"], "-Wl,--build-id=uuid")
+}
+
+func TestBinaryLinkerWrapper(t *testing.T) {
+	t.Parallel()
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_binary_host {
+			name: "wrapper_tool",
+			stl: "none",
+		}
+
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.cc"],
+			linker_wrapper: {
+				tool: "wrapper_tool",
+				args: ["--config", "$(location wrapper.cfg)", "$(in)", "-o", "$(out)"],
+				tool_files: ["wrapper.cfg"],
+			},
+		}`)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a")
+	ld := foo.Rule("ld")
+	android.AssertPathRelativeToTopEquals(t, "link output",
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/prewrapped/foo", ld.Output)
+
+	wrapper := foo.Rule("linker_wrapper")
+	android.AssertStringListContains(t, "missing dependency on linked output",
+		wrapper.Implicits.RelativeToTop().Strings(), ld.Output.RelativeToTop().String())
+	android.AssertPathsRelativeToTopEquals(t, "wrapper output",
+		[]string{"out/soong/.intermediates/foo/android_arm64_armv8-a/unstripped/foo"},
+		wrapper.ImplicitOutputs.Paths())
+
+	// The wrapper runs before strip, so the symbols and the stripped output contain its changes.
+	strip := foo.Rule("strip")
+	android.AssertPathRelativeToTopEquals(t, "strip input",
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/unstripped/foo", strip.Input)
+	android.AssertPathRelativeToTopEquals(t, "strip output",
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/foo", strip.Output)
+	android.AssertPathRelativeToTopEquals(t, "symbols file",
+		"out/soong/.intermediates/foo/android_arm64_armv8-a/unstripped/foo",
+		foo.Module().(*Module).UnstrippedOutputFile())
+	android.AssertStringListContains(t, "missing dependency on tool_files",
+		wrapper.Implicits.Strings(), "wrapper.cfg")
+	android.AssertStringDoesContain(t, "missing expanded $(location)",
+		wrapper.RuleParams.Command, "--config wrapper.cfg")
+}
+
+func TestBinarySplitDebug(t *testing.T) {
+	t.Parallel()
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_binary_host {
+			name: "foo",
+			srcs: ["foo.cc"],
+			strip: {
+				split_debug: true,
+			},
+		}`)
+
+	foo := result.ModuleForTests("foo", result.Config.BuildOSTarget.String())
+	strip := foo.Rule("strip")
+	android.AssertStringDoesContain(t, "strip args", strip.Args["args"], "--split-debug")
+	android.AssertStringDoesNotContain(t, "strip args", strip.Args["args"], "--keep-mini-debug-info")
+	android.AssertPathsRelativeToTopEquals(t, "split debug file",
+		[]string{"out/soong/.intermediates/foo/" + result.Config.BuildOSTarget.String() + "/foo.debug"},
+		strip.ImplicitOutputs.Paths())
+
+	foo.Output("out/soong/host/linux-x86/bin/foo")
+	foo.Output("out/soong/host/linux-x86/debug/bin/foo.debug")
+}
+
+func TestBinarySplitDebugDevice(t *testing.T) {
+	t.Parallel()
+	PrepareForIntegrationTestWithCc.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`strip.split_debug: split debug files are only supported for host modules`)).
+		RunTestWithBp(t, `
+			cc_binary {
+				name: "foo",
+				srcs: ["foo.cc"],
+				strip: {
+					split_debug: true,
+				},
+			}`)
+}
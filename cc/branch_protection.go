@@ -0,0 +1,206 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains support for rolling out the PAC/BTI branch protection of the arm64 device
+// modules with the BranchProtectionPolicies of the product, which select the -mbranch-protection
+// mode per partition and, optionally, only for the modules whose min_sdk_version is at least a
+// given API level, instead of adding the flag to the cflags of each module. The
+// branch_protection_report target lists the modules covered by a policy, with the mode they are
+// compiled with or the reason they are excluded from it.
+
+var branchProtectionModes = []string{"standard", "pac-ret", "bti", "none"}
+
+func init() {
+	registerBranchProtectionBuildComponents(android.InitRegistrationContext)
+}
+
+func registerBranchProtectionBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("branch_protection_report", branchProtectionReportSingletonFactory)
+}
+
+var prepareForTestWithBranchProtectionReport = android.FixtureRegisterWithContext(registerBranchProtectionBuildComponents)
+
+// branchProtectionPolicy is a parsed entry of the BranchProtectionPolicies of the product.
+type branchProtectionPolicy struct {
+	// The partition that the policy applies to, or * for all the partitions.
+	partition string
+
+	// The -mbranch-protection mode.
+	mode string
+
+	// The lowest min_sdk_version of the modules that the policy applies to, or 0 for all of them.
+	minSdkVersion int
+}
+
+// parseBranchProtectionPolicies parses the <partition>:<mode>[:<min_sdk_version>] entries of the
+// BranchProtectionPolicies of the product.
+func parseBranchProtectionPolicies(entries []string) ([]branchProtectionPolicy, error) {
+	var policies []branchProtectionPolicy
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		fields := strings.Split(entry, ":")
+		if len(fields) < 2 || len(fields) > 3 || fields[0] == "" {
+			return nil, fmt.Errorf("invalid branch protection policy %q, expected <partition>:<mode>[:<min_sdk_version>]", entry)
+		}
+		policy := branchProtectionPolicy{partition: fields[0], mode: fields[1]}
+		if !android.InList(policy.mode, branchProtectionModes) {
+			return nil, fmt.Errorf("invalid mode %q in branch protection policy %q, expected one of %s",
+				policy.mode, entry, strings.Join(branchProtectionModes, ", "))
+		}
+		if len(fields) == 3 {
+			minSdkVersion, err := strconv.Atoi(fields[2])
+			if err != nil || minSdkVersion <= 0 {
+				return nil, fmt.Errorf("invalid min_sdk_version %q in branch protection policy %q", fields[2], entry)
+			}
+			policy.minSdkVersion = minSdkVersion
+		}
+		if seen[policy.partition] {
+			return nil, fmt.Errorf("more than one branch protection policy for partition %q", policy.partition)
+		}
+		seen[policy.partition] = true
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// branchProtectionPolicyForPartition returns the policy of the partition, or the policy of all the
+// partitions if the partition has none.
+func branchProtectionPolicyForPartition(policies []branchProtectionPolicy, partition string) *branchProtectionPolicy {
+	var ret *branchProtectionPolicy
+	for i := range policies {
+		if policies[i].partition == partition {
+			return &policies[i]
+		} else if policies[i].partition == "*" {
+			ret = &policies[i]
+		}
+	}
+	return ret
+}
+
+// branchProtectionStatus is the line of a module in the branch protection report.
+type branchProtectionStatus struct {
+	partition string
+
+	// The mode the module is compiled with, empty if it is excluded from the policy.
+	mode string
+
+	// The reason why the module is excluded from the policy.
+	excluded string
+}
+
+// branchProtectionFlags adds the -mbranch-protection flag of the policy of the partition of the
+// module, unless the module is excluded from it, and returns the status of the module for the
+// report, or nil if no policy applies to the module.
+func branchProtectionFlags(ctx ModuleContext, flags Flags) (Flags, *branchProtectionStatus) {
+	if ctx.Host() || ctx.Arch().ArchType != android.Arm64 {
+		return flags, nil
+	}
+	// Invalid policies are reported by the branch_protection_report singleton.
+	policies, err := parseBranchProtectionPolicies(ctx.Config().BranchProtectionPolicies())
+	if err != nil {
+		return flags, nil
+	}
+	partition := buildIdPartition(ctx)
+	policy := branchProtectionPolicyForPartition(policies, partition)
+	if policy == nil {
+		return flags, nil
+	}
+
+	status := &branchProtectionStatus{partition: partition}
+	moduleFlags := append(android.CopyOf(flags.Local.CommonFlags), flags.Local.CFlags...)
+	for _, flag := range append(moduleFlags, flags.Local.CppFlags...) {
+		if strings.HasPrefix(flag, "-mbranch-protection=") {
+			status.excluded = "sets " + flag + " in its cflags"
+			return flags, status
+		}
+	}
+	if v := ctx.minSdkVersion(); policy.minSdkVersion > 0 && v != "" {
+		if level, err := android.ApiLevelFromUser(ctx, v); err == nil && level.FinalOrFutureInt() < policy.minSdkVersion {
+			status.excluded = fmt.Sprintf("min_sdk_version %s is lower than %d", v, policy.minSdkVersion)
+			return flags, status
+		}
+	}
+
+	status.mode = policy.mode
+	flags.Local.CommonFlags = append(flags.Local.CommonFlags, "-mbranch-protection="+policy.mode)
+	return flags, status
+}
+
+func branchProtectionReportSingletonFactory() android.Singleton {
+	return &branchProtectionReportSingleton{}
+}
+
+type branchProtectionReportSingleton struct {
+	report android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*branchProtectionReportSingleton)(nil)
+
+// GenerateBuildActions validates the branch protection policies of the product and writes the list
+// of the modules covered by a policy, with their directory, partition and mode, or the reason why
+// they are excluded from the policy, to out/soong/branch_protection_report.txt.
+func (s *branchProtectionReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	entries := ctx.Config().BranchProtectionPolicies()
+	if len(entries) == 0 {
+		return
+	}
+	if _, err := parseBranchProtectionPolicies(entries); err != nil {
+		ctx.Errorf("BranchProtectionPolicies: %s", err)
+		return
+	}
+
+	lines := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() {
+			return
+		}
+		compiler, ok := c.compiler.(interface {
+			branchProtectionStatus() *branchProtectionStatus
+		})
+		if !ok || compiler.branchProtectionStatus() == nil {
+			return
+		}
+		status := compiler.branchProtectionStatus()
+		if status.mode != "" {
+			lines[fmt.Sprintf("%s %s %s %s", ctx.ModuleDir(module), ctx.ModuleName(module), status.partition,
+				status.mode)] = true
+		} else {
+			lines[fmt.Sprintf("%s %s %s excluded: %s", ctx.ModuleDir(module), ctx.ModuleName(module),
+				status.partition, status.excluded)] = true
+		}
+	})
+
+	report := android.PathForOutput(ctx, "branch_protection_report.txt")
+	android.WriteFileRule(ctx, report, strings.Join(android.SortedKeys(lines), "\n"))
+	ctx.Phony("branch_protection_report", report)
+	s.report = report
+}
+
+func (s *branchProtectionReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report == nil {
+		return
+	}
+	ctx.DistForGoal("droidcore", s.report)
+}
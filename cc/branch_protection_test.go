@@ -0,0 +1,106 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestBranchProtectionPolicies(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithBranchProtectionReport,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BranchProtectionPolicies = []string{"*:standard", "vendor:bti:30"}
+		}),
+		android.FixtureAddTextFile("foo/Android.bp", `
+			cc_library_shared {
+				name: "libsystem",
+				srcs: ["foo.cpp"],
+			}
+
+			cc_library_shared {
+				name: "libvendor",
+				srcs: ["foo.cpp"],
+				vendor: true,
+			}
+
+			cc_library_shared {
+				name: "libvendor_old",
+				srcs: ["foo.cpp"],
+				vendor: true,
+				min_sdk_version: "29",
+			}
+
+			cc_library_shared {
+				name: "libexplicit",
+				srcs: ["foo.cpp"],
+				cflags: ["-mbranch-protection=pac-ret"],
+			}
+		`),
+	).RunTest(t)
+
+	checkCflags := func(name, variant, expected, unexpected string) {
+		t.Helper()
+		cFlags := result.ModuleForTests(name, variant).Rule("cc").Args["cFlags"]
+		if expected != "" {
+			android.AssertStringDoesContain(t, name+" cflags", cFlags, expected)
+		}
+		if unexpected != "" {
+			android.AssertStringDoesNotContain(t, name+" cflags", cFlags, unexpected)
+		}
+	}
+
+	checkCflags("libsystem", "android_arm64_armv8-a_shared", "-mbranch-protection=standard", "")
+	checkCflags("libvendor", vendorVariant, "-mbranch-protection=bti", "-mbranch-protection=standard")
+	checkCflags("libvendor_old", vendorVariant, "", "-mbranch-protection=")
+	checkCflags("libexplicit", "android_arm64_armv8-a_shared", "-mbranch-protection=pac-ret", "-mbranch-protection=standard")
+	checkCflags("libsystem", "android_arm_armv7-a-neon_shared", "", "-mbranch-protection=")
+
+	report := result.SingletonForTests("branch_protection_report").Output("branch_protection_report.txt")
+	android.AssertStringEquals(t, "report",
+		"foo libexplicit system excluded: sets -mbranch-protection=pac-ret in its cflags\n"+
+			"foo libsystem system standard\n"+
+			"foo libvendor vendor bti\n"+
+			"foo libvendor_old vendor excluded: min_sdk_version 29 is lower than 30",
+		android.ContentFromFileRuleForTests(t, report))
+}
+
+func TestBranchProtectionPoliciesErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		policy string
+		err    string
+	}{
+		{"system", `invalid branch protection policy "system"`},
+		{"system:full", `invalid mode "full" in branch protection policy "system:full"`},
+		{"system:bti:S", `invalid min_sdk_version "S" in branch protection policy "system:bti:S"`},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			android.GroupFixturePreparers(
+				prepareForCcTest,
+				prepareForTestWithBranchProtectionReport,
+				android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+					variables.BranchProtectionPolicies = []string{tc.policy}
+				}),
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				"BranchProtectionPolicies: "+tc.err)).
+				RunTestWithBp(t, "")
+		})
+	}
+}
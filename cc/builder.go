@@ -50,6 +50,20 @@ var (
 		},
 		"ccCmd", "cFlags")
 
+	// Rule to precompile a header with given command and flags for the C or C++ sources that
+	// include it with -include-pch. Outputs a .d depfile, so that the precompiled header is rebuilt
+	// when any of the headers that it includes changes. The timestamps of the input headers are not
+	// recorded, so that the precompiled header can be used where the headers have other timestamps,
+	// e.g. on RBE workers.
+	ccPch = pctx.AndroidRemoteStaticRule("ccPch", android.RemoteRuleSupports{Goma: true, RBE: true},
+		blueprint.RuleParams{
+			Depfile:     "${out}.d",
+			Deps:        blueprint.DepsGCC,
+			Command:     "$relPwd ${config.CcWrapper}$ccCmd -x $pchLang $cFlags -Xclang -fno-pch-timestamp -MD -MF ${out}.d -o $out $in",
+			CommandDeps: []string{"$ccCmd"},
+		},
+		"ccCmd", "cFlags", "pchLang")
+
 	// Rule to invoke gcc with given command and flags, but no dependencies.
 	ccNoDeps = pctx.AndroidStaticRule("ccNoDeps",
 		blueprint.RuleParams{
@@ -385,6 +399,8 @@ type builderFlags struct {
 
 	assemblerWithCpp bool // True if .s files should be processed with the c preprocessor.
 
+	precompiledHeader android.OptionalPath // The header to precompile for the C and C++ sources.
+
 	systemIncludeFlags string
 
 	proto            android.ProtoFlags
@@ -528,6 +544,12 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 	}
 
 	modulePath := android.PathForModuleSrc(ctx).String()
+	if config.IsVendorProjectPath(modulePath) {
+		cflags += " ${config.NoOverrideVendorGlobalCflags}"
+		toolingCflags += " ${config.NoOverrideVendorGlobalCflags}"
+		cppflags += " ${config.NoOverrideVendorGlobalCflags}"
+		toolingCppflags += " ${config.NoOverrideVendorGlobalCflags}"
+	}
 	if android.IsThirdPartyPath(modulePath) {
 		cflags += " ${config.NoOverrideExternalGlobalCflags}"
 		toolingCflags += " ${config.NoOverrideExternalGlobalCflags}"
@@ -535,6 +557,16 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		toolingCppflags += " ${config.NoOverrideExternalGlobalCflags}"
 	}
 
+	// The exceptions to the warning policy of the projects come last, so that they win.
+	overrides, _ := warningOverrides(ctx.Config())
+	if exceptions := config.WarningPolicyExceptionCflags(modulePath, overrides); len(exceptions) > 0 {
+		exceptionFlags := " " + strings.Join(exceptions, " ")
+		cflags += exceptionFlags
+		toolingCflags += exceptionFlags
+		cppflags += exceptionFlags
+		toolingCppflags += exceptionFlags
+	}
+
 	// Multiple source files have build rules usually share the same cFlags or tidyFlags.
 	// Define only one version in this module and share it in multiple build rules.
 	// To simplify the code, the shared variables are all named as $flags<nnn>.
@@ -560,6 +592,9 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		return "$" + kind + n
 	}
 
+	// The precompiled headers of the C and C++ sources, built on first use.
+	pchFiles := make(map[string]android.Path)
+
 	for i, srcFile := range srcFiles {
 		objFile := android.ObjPathWithExt(ctx, subdir, srcFile, "o")
 
@@ -589,6 +624,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 		var moduleToolingFlags string
 
 		var ccCmd string
+		var pchLang, pchDir string
 		tidy := flags.tidy
 		coverage := flags.gcovCoverage
 		dump := flags.sAbiDump
@@ -612,7 +648,13 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 			ccCmd = "clang"
 			moduleFlags = cflags
 			moduleToolingFlags = toolingCflags
-		case ".cpp", ".cc", ".cxx", ".mm":
+			pchLang, pchDir = "c-header", "pch_c"
+		case ".cpp", ".cc", ".cxx":
+			ccCmd = "clang++"
+			moduleFlags = cppflags
+			moduleToolingFlags = toolingCppflags
+			pchLang, pchDir = "c++-header", "pch_cpp"
+		case ".mm":
 			ccCmd = "clang++"
 			moduleFlags = cppflags
 			moduleToolingFlags = toolingCppflags
@@ -642,16 +684,52 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 			coverageFiles = append(coverageFiles, gcnoFile)
 		}
 
+		// The precompiled header is only used by the compile itself, the other tools that parse the
+		// sources include the header with -include instead, so that they see the same declarations.
+		toolFlags := moduleFlags
+		if pchLang != "" && flags.precompiledHeader.Valid() {
+			include := " -include " + flags.precompiledHeader.String()
+			toolFlags += include
+			moduleToolingFlags += include
+		}
+
+		compileFlags := moduleFlags + extraFlags
+		compileDeps := cFlagsDeps
+		if pchLang != "" && flags.precompiledHeader.Valid() {
+			pch, ok := pchFiles[pchLang]
+			if !ok {
+				header := flags.precompiledHeader.Path()
+				pchFile := android.PathForModuleObj(ctx, subdir, pchDir, header.Rel()+".pch")
+				ctx.Build(pctx, android.BuildParams{
+					Rule:        ccPch,
+					Description: ccDesc + " pch " + header.Rel(),
+					Output:      pchFile,
+					Input:       header,
+					Implicits:   cFlagsDeps,
+					OrderOnly:   pathDeps,
+					Args: map[string]string{
+						"cFlags":  shareFlags("cFlags", compileFlags),
+						"ccCmd":   ccCmd,
+						"pchLang": pchLang,
+					},
+				})
+				pch = pchFile
+				pchFiles[pchLang] = pch
+			}
+			compileFlags += " -include-pch " + pch.String()
+			compileDeps = append(android.CopyOfPaths(cFlagsDeps), pch)
+		}
+
 		ctx.Build(pctx, android.BuildParams{
 			Rule:            rule,
 			Description:     ccDesc + " " + srcFile.Rel(),
 			Output:          objFile,
 			ImplicitOutputs: implicitOutputs,
 			Input:           srcFile,
-			Implicits:       cFlagsDeps,
+			Implicits:       compileDeps,
 			OrderOnly:       pathDeps,
 			Args: map[string]string{
-				"cFlags": shareFlags("cFlags", moduleFlags+extraFlags),
+				"cFlags": shareFlags("cFlags", compileFlags),
 				"ccCmd":  ccCmd, // short and not shared
 			},
 		})
@@ -667,7 +745,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 				Implicits:   cFlagsDeps,
 				OrderOnly:   pathDeps,
 				Args: map[string]string{
-					"cFlags": shareFlags("cFlags", moduleFlags),
+					"cFlags": shareFlags("cFlags", toolFlags),
 				},
 			})
 			kytheFiles = append(kytheFiles, kytheFile)
@@ -680,7 +758,7 @@ func transformSourceToObj(ctx ModuleContext, subdir string, srcFiles, noTidySrcs
 			tidyCmd := "${config.ClangBin}/clang-tidy"
 
 			rule := clangTidy
-			reducedCFlags := moduleFlags
+			reducedCFlags := toolFlags
 			if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_CLANG_TIDY") {
 				rule = clangTidyRE
 				// b/248371171, work around RBE input processor problem
@@ -851,6 +929,8 @@ func transformObjToDynamicBinary(ctx android.ModuleContext,
 	deps = append(deps, wholeStaticLibs...)
 	deps = append(deps, crtBegin...)
 	deps = append(deps, crtEnd...)
+	// Relink when an environment variable that affects the toolchain changes.
+	deps = append(deps, config.ToolchainEnvManifestPath(ctx))
 
 	rule := ld
 	args := map[string]string{
@@ -949,7 +1029,8 @@ func transformAbiDumpToAbiDiff(ctx android.ModuleContext, inputDump, referenceDu
 		"libvixl-arm64",
 		"libvixl-arm",
 	}
-	if config.SDClang && !inList("-advice-only", extraFlags) &&
+	sdclangConfig, _ := config.SDClang(ctx.Config())
+	if sdclangConfig.SDClang && !inList("-advice-only", extraFlags) &&
 		inList(ctx.ModuleName(), sdclangAbiCheckIgnoreList) {
 		extraFlags = append(extraFlags, "-advice-only")
 	}
@@ -1047,8 +1128,10 @@ func transformBinaryPrefixSymbols(ctx android.ModuleContext, prefix string, inpu
 }
 
 // Registers a build statement to invoke `strip` (to discard symbols and data from object files).
+// If splitDebugFile is not nil the debug info is moved to it, and the stripped file gets a
+// .gnu_debuglink section that points to it.
 func transformStrip(ctx android.ModuleContext, inputFile android.Path,
-	outputFile android.WritablePath, flags StripFlags) {
+	outputFile android.WritablePath, flags StripFlags, splitDebugFile android.WritablePath) {
 
 	args := ""
 	if flags.StripAddGnuDebuglink {
@@ -1066,12 +1149,18 @@ func transformStrip(ctx android.ModuleContext, inputFile android.Path,
 	if flags.StripKeepSymbolsAndDebugFrame {
 		args += " --keep-symbols-and-debug-frame"
 	}
+	var implicitOutputs android.WritablePaths
+	if splitDebugFile != nil {
+		args += " --split-debug"
+		implicitOutputs = append(implicitOutputs, splitDebugFile)
+	}
 
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        strip,
-		Description: "strip " + outputFile.Base(),
-		Output:      outputFile,
-		Input:       inputFile,
+		Rule:            strip,
+		Description:     "strip " + outputFile.Base(),
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Input:           inputFile,
 		Args: map[string]string{
 			"args": args,
 		},
@@ -1090,7 +1179,7 @@ func transformDarwinStrip(ctx android.ModuleContext, inputFile android.Path,
 	})
 }
 
-func transformDarwinUniversalBinary(ctx android.ModuleContext, outputFile android.WritablePath, inputFiles ...android.Path) {
+func TransformDarwinUniversalBinary(ctx android.ModuleContext, outputFile android.WritablePath, inputFiles ...android.Path) {
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        darwinLipo,
 		Description: "lipo " + outputFile.Base(),
@@ -83,6 +83,7 @@ func RegisterCCBuildComponents(ctx android.RegistrationContext) {
 	})
 
 	ctx.RegisterSingletonType("kythe_extract_all", kytheExtractAllFactory)
+	ctx.RegisterSingletonType("host_test_runtimes", hostTestRuntimesSingletonFactory)
 }
 
 // Deps is a struct containing module names of dependencies, separated by the kind of dependency.
@@ -128,6 +129,9 @@ type Deps struct {
 	// Used for host bionic
 	DynamicLinker string
 
+	// Host tool that transforms the linked output, see linker_wrapper.
+	LinkerWrapper string
+
 	// List of libs that need to be excluded for APEX variant
 	ExcludeLibsForApex []string
 }
@@ -179,6 +183,9 @@ type PathDeps struct {
 	// Path to the dynamic linker binary
 	DynamicLinker android.OptionalPath
 
+	// Path to the host tool that transforms the linked output
+	LinkerWrapper android.OptionalPath
+
 	// For Darwin builds, the path to the second architecture's output that should
 	// be combined with this architectures's output into a FAT MachO file.
 	DarwinSecondArchOutput android.OptionalPath
@@ -226,6 +233,9 @@ type Flags struct {
 	SAbiDump      bool // True if header abi dumps should be generated.
 	EmitXrefs     bool // If true, generate Ninja rules to generate emitXrefs input files for Kythe
 
+	// The header to precompile for the C and C++ sources, if any.
+	PrecompiledHeader android.OptionalPath
+
 	// The instruction set required for clang ("arm" or "thumb").
 	RequiredInstructionSet string
 	// The target-device system path to the dynamic linker.
@@ -250,9 +260,14 @@ type BaseProperties struct {
 	// Deprecated. true is the default, false is invalid.
 	Clang *bool `android:"arch_variant"`
 
-	// compile module with SDLLVM instead of AOSP LLVM
+	// compile module with SDLLVM instead of AOSP LLVM. Deprecated, use use_sdclang instead.
 	Sdclang *bool `android:"arch_variant"`
 
+	// Whether to compile the module with SDClang rather than with the clang of prebuilts/clang.
+	// Defaults to the SDCLANG_ALLOW_LIST and SDCLANG_DENY_LIST of the SD Clang config file, and then
+	// to its SDCLANG setting.
+	Use_sdclang *bool `android:"arch_variant"`
+
 	// The API level that this module is built against. The APIs of this API level will be
 	// visible at build time, but use of any APIs newer than min_sdk_version will render the
 	// module unloadable on older devices.  In the future it will be possible to weakly-link new
@@ -891,6 +906,9 @@ type Module struct {
 	apexSdkVersion android.ApiLevel
 
 	hideApexVariantFromMake bool
+
+	// The group of the module in the toolchain flag experiment of the product, if it applies to it.
+	flagExperimentGroup string
 }
 
 func (c *Module) AddJSONData(d *map[string]interface{}) {
@@ -1414,6 +1432,20 @@ func (c *Module) HasStubsVariants() bool {
 	return false
 }
 
+// CheckStableForUpdatable implements android.UpdatableDependency. Shared libraries outside of an
+// updatable module must be used through their stubs, the LLNDK or the NDK.
+func (c *Module) CheckStableForUpdatable(ctx android.BaseModuleContext, externalDep bool) error {
+	if !externalDep || !c.Shared() {
+		return nil
+	}
+	if c.HasStubsVariants() || c.IsLlndk() || c.IsSdkVariant() || c.UseSdk() {
+		return nil
+	}
+	return fmt.Errorf("shared library without stubs does not provide a stable ABI")
+}
+
+var _ android.UpdatableDependency = (*Module)(nil)
+
 func (c *Module) IsStubsImplementationRequired() bool {
 	if lib := c.library; lib != nil {
 		return lib.isStubsImplementationRequired()
@@ -1811,6 +1843,29 @@ func (c *Module) DataPaths() []android.DataPath {
 	return nil
 }
 
+// TestSuites returns the test suites of the test and benchmark modules.
+func (c *Module) TestSuites() []string {
+	if p, ok := c.installer.(interface {
+		testSuites() []string
+	}); ok {
+		return p.testSuites()
+	}
+	return nil
+}
+
+// TestConfig returns the test config of the test and benchmark modules.
+func (c *Module) TestConfig() android.Path {
+	if p, ok := c.installer.(interface {
+		testConfigPath() android.Path
+	}); ok {
+		return p.testConfigPath()
+	}
+	return nil
+}
+
+var _ android.TestSuiteModule = (*Module)(nil)
+var _ android.TestConfigModule = (*Module)(nil)
+
 func getNameSuffixWithVndkVersion(ctx android.ModuleContext, c LinkableInterface) string {
 	// Returns the name suffix for product and vendor variants. If the VNDK version is not
 	// "current", it will append the VNDK version to the name suffix.
@@ -2050,10 +2105,17 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 	for _, feature := range c.features {
 		flags = feature.flags(ctx, flags)
 	}
+	flags, c.flagExperimentGroup = flagExperimentFlags(ctx, flags)
 	if ctx.Failed() {
 		return
 	}
 
+	// Recompile when an environment variable that affects the toolchain changes.
+	flags.CFlagsDeps = append(flags.CFlagsDeps, config.ToolchainEnvManifestPath(ctx))
+	if clangConfigFile := config.ClangConfigFile(ctx); ctx.Device() && clangConfigFile.Valid() {
+		flags.CFlagsDeps = append(flags.CFlagsDeps, clangConfigFile.Path())
+	}
+
 	flags.Local.CFlags, _ = filterList(flags.Local.CFlags, config.IllegalFlags)
 	flags.Local.CppFlags, _ = filterList(flags.Local.CppFlags, config.IllegalFlags)
 	flags.Local.ConlyFlags, _ = filterList(flags.Local.ConlyFlags, config.IllegalFlags)
@@ -2212,6 +2274,18 @@ func (c *Module) deps(ctx DepsContext) Deps {
 		deps = c.coverage.deps(ctx, deps)
 	}
 
+	// Depend on the backend of the interfaces, e.g. aidl_interface, listed by name: the ndk backend
+	// for the modules that use a stable API, and the cpp backend for the platform modules. The
+	// vendor and product variants must use a frozen version of the interfaces.
+	backend := android.InterfaceBackendCpp
+	if ctx.useSdk() || ctx.useVndk() {
+		backend = android.InterfaceBackendNdk
+	}
+	for _, libs := range []*[]string{&deps.SharedLibs, &deps.StaticLibs,
+		&deps.ReexportSharedLibHeaders, &deps.ReexportStaticLibHeaders} {
+		*libs = android.ResolveInterfaceBackends(ctx, backend, ctx.useVndk(), *libs)
+	}
+
 	deps.WholeStaticLibs = android.LastUniqueStrings(deps.WholeStaticLibs)
 	deps.StaticLibs = android.LastUniqueStrings(deps.StaticLibs)
 	deps.LateStaticLibs = android.LastUniqueStrings(deps.LateStaticLibs)
@@ -2667,6 +2741,11 @@ func (c *Module) DepsMutator(actx android.BottomUpMutatorContext) {
 		actx.AddDependency(c, dynamicLinkerDepTag, deps.DynamicLinker)
 	}
 
+	if deps.LinkerWrapper != "" {
+		actx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(),
+			linkerWrapperDepTag, deps.LinkerWrapper)
+	}
+
 	version := ctx.sdkVersion()
 
 	ndkStubDepTag := libraryDependencyTag{Kind: sharedLibraryDependency, ndk: true, makeSuffix: "." + version}
@@ -2928,18 +3007,50 @@ func findApexSdkVersion(ctx android.BaseModuleContext, apexInfo android.ApexInfo
 }
 
 func (c *Module) sdclang(ctx BaseModuleContext) bool {
-	sdclang := Bool(c.Properties.Sdclang)
-
 	// SDLLVM is not for host build
-	if ctx.Host() || config.ForceSDClangOff {
+	if ctx.Host() {
 		return false
 	}
 
-	if c.Properties.Sdclang == nil && config.SDClang {
-		return true
+	useSdclang := c.Properties.Use_sdclang
+	if useSdclang == nil {
+		useSdclang = c.Properties.Sdclang
 	}
 
-	return sdclang
+	// The error of an invalid SD Clang config file is reported by sdclangMakeVars.
+	sdclangConfig, err := config.SDClang(ctx.Config())
+	if err != nil {
+		return false
+	}
+	if sdclangConfig.ForceOff {
+		return false
+	}
+	return useSdclangForModule(useSdclang, sdclangConfig.SDClang, sdclangConfig.AllowList, sdclangConfig.DenyList,
+		ctx.ModuleDir(), ctx.ModuleName())
+}
+
+// useSdclangForModule returns whether a device module is compiled with SDClang, from its
+// use_sdclang property, and otherwise from the deny and allow lists of the SD Clang config file,
+// and then from its global setting.
+func useSdclangForModule(useSdclang *bool, global bool, allowList, denyList []string, dir, name string) bool {
+	if useSdclang != nil {
+		return *useSdclang
+	}
+	listed := func(list []string) bool {
+		for _, entry := range list {
+			if entry == name || (strings.HasSuffix(entry, "/") && strings.HasPrefix(dir+"/", entry)) {
+				return true
+			}
+		}
+		return false
+	}
+	if listed(denyList) {
+		return false
+	}
+	if listed(allowList) {
+		return true
+	}
+	return global
 }
 
 // Convert dependencies to paths.  Returns a PathDeps containing paths
@@ -2949,6 +3060,10 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	var directStaticDeps []StaticLibraryInfo
 	var directSharedDeps []SharedLibraryInfo
 
+	// The generated headers on the include path, see checkGeneratedHeaderConflicts.
+	var generatedHeaderSources []generatedHeaderSource
+	var sourceIncludeDirs android.Paths
+
 	reexportExporter := func(exporter FlagExporterInfo) {
 		depPaths.ReexportedDirs = append(depPaths.ReexportedDirs, exporter.IncludeDirs...)
 		depPaths.ReexportedSystemDirs = append(depPaths.ReexportedSystemDirs, exporter.SystemIncludeDirs...)
@@ -3017,6 +3132,11 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			return
 		}
 
+		if depTag == linkerWrapperDepTag {
+			depPaths.LinkerWrapper = linkerWrapperToolPath(ctx, dep)
+			return
+		}
+
 		ccDep, ok := dep.(LinkableInterface)
 		if !ok {
 
@@ -3037,6 +3157,11 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 						genRule.GeneratedDeps()...)
 					dirs := genRule.GeneratedHeaderDirs()
 					depPaths.IncludeDirs = append(depPaths.IncludeDirs, dirs...)
+					generatedHeaderSources = append(generatedHeaderSources, generatedHeaderSource{
+						provider:    depName,
+						headers:     genRule.GeneratedSourceFiles(),
+						includeDirs: dirs,
+					})
 					if depTag == genHeaderExportDepTag {
 						depPaths.ReexportedDirs = append(depPaths.ReexportedDirs, dirs...)
 						depPaths.ReexportedGeneratedHeaders = append(depPaths.ReexportedGeneratedHeaders,
@@ -3108,7 +3233,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			switch {
 			case libDepTag.header():
 				if !ctx.OtherModuleHasProvider(dep, HeaderLibraryInfoProvider) {
-					if !ctx.Config().AllowMissingDependencies() {
+					if !ctx.AllowMissingDependencies() {
 						ctx.ModuleErrorf("module %q is not a header library", depName)
 					} else {
 						ctx.AddMissingDependencies([]string{depName})
@@ -3117,7 +3242,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 				}
 			case libDepTag.shared():
 				if !ctx.OtherModuleHasProvider(dep, SharedLibraryInfoProvider) {
-					if !ctx.Config().AllowMissingDependencies() {
+					if !ctx.AllowMissingDependencies() {
 						ctx.ModuleErrorf("module %q is not a shared library", depName)
 					} else {
 						ctx.AddMissingDependencies([]string{depName})
@@ -3154,7 +3279,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 				}
 			case libDepTag.static():
 				if !ctx.OtherModuleHasProvider(dep, StaticLibraryInfoProvider) {
-					if !ctx.Config().AllowMissingDependencies() {
+					if !ctx.AllowMissingDependencies() {
 						ctx.ModuleErrorf("module %q is not a static library", depName)
 					} else {
 						ctx.AddMissingDependencies([]string{depName})
@@ -3234,7 +3359,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 
 			if ptr != nil {
 				if !linkFile.Valid() {
-					if !ctx.Config().AllowMissingDependencies() {
+					if !ctx.AllowMissingDependencies() {
 						ctx.ModuleErrorf("module %q missing output file", depName)
 					} else {
 						ctx.AddMissingDependencies([]string{depName})
@@ -3255,6 +3380,16 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			depPaths.IncludeDirs = append(depPaths.IncludeDirs, depExporterInfo.IncludeDirs...)
 			depPaths.SystemIncludeDirs = append(depPaths.SystemIncludeDirs, depExporterInfo.SystemIncludeDirs...)
 			depPaths.GeneratedDeps = append(depPaths.GeneratedDeps, depExporterInfo.Deps...)
+			sourceIncludeDirs = append(sourceIncludeDirs, sourceDirs(depExporterInfo.IncludeDirs)...)
+			sourceIncludeDirs = append(sourceIncludeDirs, sourceDirs(depExporterInfo.SystemIncludeDirs)...)
+			if len(depExporterInfo.GeneratedHeaders) > 0 {
+				generatedHeaderSources = append(generatedHeaderSources, generatedHeaderSource{
+					provider:    depName,
+					exported:    true,
+					headers:     depExporterInfo.GeneratedHeaders,
+					includeDirs: append(android.CopyOfPaths(depExporterInfo.IncludeDirs), depExporterInfo.SystemIncludeDirs...),
+				})
+			}
 			depPaths.Flags = append(depPaths.Flags, depExporterInfo.Flags...)
 
 			if libDepTag.reexportFlags {
@@ -3329,6 +3464,13 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 	depPaths.TranstiveStaticLibrariesForOrdering = transitiveStaticLibs
 	depPaths.StaticLibs = append(depPaths.StaticLibs, orderedStaticPaths...)
 
+	if compiler, ok := c.compiler.(interface {
+		localIncludeDirs(ctx android.ModuleContext) android.Paths
+	}); ok && len(generatedHeaderSources) > 0 {
+		checkGeneratedHeaderConflicts(ctx, generatedHeaderSources,
+			append(compiler.localIncludeDirs(ctx), android.FirstUniquePaths(sourceIncludeDirs)...))
+	}
+
 	// Dedup exported flags from dependencies
 	depPaths.Flags = android.FirstUniqueStrings(depPaths.Flags)
 	depPaths.IncludeDirs = android.FirstUniquePaths(depPaths.IncludeDirs)
@@ -3601,6 +3743,12 @@ func (c *Module) OutputFiles(tag string) (android.Paths, error) {
 	}
 }
 
+func (c *Module) OutputFileTags() []string {
+	return []string{"unstripped"}
+}
+
+var _ android.OutputFileTagsProducer = (*Module)(nil)
+
 func (c *Module) static() bool {
 	if static, ok := c.linker.(interface {
 		static() bool
@@ -856,6 +856,117 @@ func TestTestBinaryTestSuites(t *testing.T) {
 	}
 }
 
+func TestHostTestRuntimes(t *testing.T) {
+	t.Parallel()
+	bp := `
+		cc_defaults {
+			name: "host_test_runtimes_defaults",
+			host_supported: true,
+			host_test_runtimes_supported: true,
+			nocrt: true,
+			no_libcrt: true,
+			system_shared_libs: [],
+			stl: "none",
+		}
+
+		cc_test {
+			name: "main_test",
+			defaults: ["host_test_runtimes_defaults"],
+			srcs: ["main_test.cpp"],
+			shared_libs: ["libfoo"],
+			gtest: false,
+		}
+
+		cc_library {
+			name: "libfoo",
+			defaults: ["host_test_runtimes_defaults"],
+			srcs: ["foo.cpp"],
+		}
+
+		cc_library {
+			name: "libother",
+			host_supported: true,
+			srcs: ["foo.cpp"],
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.PrepareForSkipTestOnMac,
+		android.FixtureModifyConfig(func(config android.Config) {
+			config.TestProductVariables.HostTestRuntimes = []string{"musl"}
+			config.Targets[android.LinuxMusl] = []android.Target{{
+				Os:        android.LinuxMusl,
+				Arch:      android.Arch{ArchType: android.X86_64},
+				HostCross: true,
+			}}
+		}),
+	).RunTestWithBp(t, bp)
+
+	glibcConfig := result.ModuleForTests("main_test", "linux_glibc_x86_64").Output("main_test.config")
+	android.AssertStringDoesNotContain(t, "glibc test config", glibcConfig.Args["extraConfigs"], "host-runtime")
+
+	musl := result.ModuleForTests("main_test", "linux_musl_x86_64")
+	muslConfig := musl.Output("main_test.config")
+	android.AssertStringDoesContain(t, "musl test config", muslConfig.Args["extraConfigs"], `key="host-runtime" value="musl"`)
+	android.AssertBoolEquals(t, "musl variant hidden from Make", true, android.ShouldSkipAndroidMkProcessing(musl.Module()))
+
+	// Only the modules that support the host test runtimes are built against them.
+	android.AssertStringListContains(t, "libfoo variants", result.ModuleVariantsForTests("libfoo"), "linux_musl_x86_64_shared")
+	android.AssertStringListDoesNotContain(t, "libother variants", result.ModuleVariantsForTests("libother"), "linux_musl_x86_64_shared")
+
+	zip := result.SingletonForTests("host_test_runtimes").Output("host_test_runtimes/host-tests-musl.zip")
+	android.AssertStringDoesContain(t, "musl tests zip", zip.RuleParams.Command, "-e musl/main_test/main_test -f ")
+	android.AssertStringDoesContain(t, "musl tests zip", zip.RuleParams.Command, "-e musl/main_test/main_test.config -f ")
+}
+
+func TestBenchmarkGoldenRanges(t *testing.T) {
+	t.Parallel()
+	bp := `
+		cc_benchmark {
+			name: "main_benchmark",
+			srcs: ["main_benchmark.cpp"],
+			host_supported: true,
+			golden_ranges: "main_benchmark_golden.json",
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.PrepareForSkipTestOnMac,
+		android.FixtureAddTextFile("main_benchmark_golden.json", "{}"),
+	).RunTestWithBp(t, bp)
+
+	for _, variant := range []string{"linux_glibc_x86_64", "android_arm64_armv8-a"} {
+		module := result.ModuleForTests("main_benchmark", variant)
+		if module.MaybeRule("benchmarkGoldenCheck").Rule != nil || module.MaybeRule("benchmarkDeviceGoldenCheck").Rule != nil {
+			t.Errorf("%s: the benchmark is run by the build", variant)
+		}
+
+		schema := module.Rule("benchmarkResultsSchema")
+		android.AssertPathRelativeToTopEquals(t, "results schema",
+			"out/soong/.intermediates/main_benchmark/"+variant+"/benchmark_results_schema.json", schema.Output)
+		android.AssertStringEquals(t, "golden ranges validated with the schema",
+			"-golden main_benchmark_golden.json", schema.Args["goldenFlag"])
+
+		config := module.Output("main_benchmark.config")
+		android.AssertStringDoesContain(t, "test config", config.Args["extraConfigs"],
+			`<object type="metric_post_processor" class="com.android.tradefed.postprocessor.BenchmarkResultsPostProcessor">`)
+		android.AssertStringDoesContain(t, "test config", config.Args["extraConfigs"],
+			`<option name="golden-ranges" value="main_benchmark_golden.json" />`)
+		android.AssertStringDoesContain(t, "test config", config.Args["extraConfigs"],
+			`<option name="results-schema" value="benchmark_results_schema.json" />`)
+
+		entries := android.AndroidMkEntriesForTest(t, result.TestContext, module.Module())[0]
+		var data []string
+		for _, entry := range entries.EntryMap["LOCAL_TEST_DATA"] {
+			data = append(data, entry[strings.LastIndex(entry, ":")+1:])
+		}
+		android.AssertArrayString(t, "test data",
+			[]string{"main_benchmark_golden.json", "benchmark_results_schema.json"}, data)
+	}
+}
+
 func TestTestLibraryTestSuites(t *testing.T) {
 	t.Parallel()
 	bp := `
@@ -5215,3 +5326,128 @@ func TestDclaLibraryInApex(t *testing.T) {
 	expectedOutputFiles := []string{"outputbase/execroot/__main__/foo.so"}
 	android.AssertDeepEquals(t, "output files", expectedOutputFiles, outputFiles.Strings())
 }
+
+func TestToolchainEnvManifestDeps(t *testing.T) {
+	t.Parallel()
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_binary {
+			name: "foo",
+			srcs: ["foo.cc"],
+		}`)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a")
+	manifest := "out/soong/toolchain_env.json"
+	android.AssertStringListContains(t, "compile depends on the toolchain environment",
+		foo.Rule("cc").Implicits.RelativeToTop().Strings(), manifest)
+	android.AssertStringListContains(t, "link depends on the toolchain environment",
+		foo.Rule("ld").Implicits.RelativeToTop().Strings(), manifest)
+}
+
+func TestUseSdclangForModule(t *testing.T) {
+	t.Parallel()
+	allowList := []string{"libfoo", "vendor/acme/"}
+	denyList := []string{"libbar", "vendor/acme/legacy/"}
+	for _, tc := range []struct {
+		name       string
+		useSdclang *bool
+		global     bool
+		dir        string
+		module     string
+		expected   bool
+	}{
+		{"default", nil, false, "frameworks/foo", "libbaz", false},
+		{"global", nil, true, "frameworks/foo", "libbaz", true},
+		{"allowed module", nil, false, "frameworks/foo", "libfoo", true},
+		{"allowed directory", nil, false, "vendor/acme/foo", "libbaz", true},
+		{"denied module", nil, true, "vendor/acme/foo", "libbar", false},
+		{"denied directory", nil, true, "vendor/acme/legacy", "libbaz", false},
+		{"property", BoolPtr(true), false, "vendor/acme/legacy", "libbar", true},
+		{"property false", BoolPtr(false), true, "frameworks/foo", "libfoo", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			android.AssertBoolEquals(t, "use SDClang", tc.expected,
+				useSdclangForModule(tc.useSdclang, tc.global, allowList, denyList, tc.dir, tc.module))
+		})
+	}
+}
+
+func TestSDClangConfigError(t *testing.T) {
+	t.Parallel()
+	configPath := filepath.Join(t.TempDir(), "sdclang.json")
+	if err := os.WriteFile(configPath, []byte(`{}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The error is reported once, not by each module that requests SDClang.
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.PrepareForTestWithMakevars,
+		android.FixtureMergeEnv(map[string]string{"SDCLANG_CONFIG": configPath}),
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+		`SDCLANG_CONFIG .*sdclang.json: missing the default block, found no block`)).
+		RunTestWithBp(t, `
+			cc_library {
+				name: "libfoo",
+				srcs: ["foo.c"],
+				use_sdclang: true,
+			}
+
+			cc_library {
+				name: "libbar",
+				srcs: ["bar.c"],
+			}
+		`)
+}
+
+func TestCheckStableForUpdatable(t *testing.T) {
+	t.Parallel()
+	ctx := testCc(t, `
+		cc_library {
+			name: "libstubs",
+			stubs: { versions: ["1"] },
+		}
+
+		cc_library {
+			name: "libnostubs",
+		}
+
+		cc_library {
+			name: "libsdk",
+			sdk_version: "current",
+		}
+	`)
+
+	check := func(name, variant string, externalDep bool) error {
+		t.Helper()
+		module := ctx.ModuleForTests(name, variant).Module().(*Module)
+		return module.CheckStableForUpdatable(nil, externalDep)
+	}
+
+	const shared = "android_arm64_armv8-a_shared"
+	const static = "android_arm64_armv8-a_static"
+	android.AssertDeepEquals(t, "external lib with stubs", nil, check("libstubs", shared, true))
+	android.AssertDeepEquals(t, "external sdk variant", nil, check("libsdk", "android_arm64_armv8-a_sdk_shared", true))
+	android.AssertDeepEquals(t, "internal lib without stubs", nil, check("libnostubs", shared, false))
+	android.AssertDeepEquals(t, "external static lib", nil, check("libnostubs", static, true))
+	android.AssertErrorMessageEquals(t, "external lib without stubs",
+		"shared library without stubs does not provide a stable ABI", check("libnostubs", shared, true))
+}
+
+func TestToolchainEnvManifestContents(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithCc,
+		prepareForTestWithToolchainEnv,
+		android.FixtureMergeEnv(map[string]string{
+			"LLVM_NEXT":  "true",
+			"USE_RBE":    "true",
+			"USE_CCACHE": "1",
+		}),
+	).RunTest(t)
+
+	manifest := result.SingletonForTests("toolchain_env").Output("toolchain_env.json")
+	content := android.ContentFromFileRuleForTests(t, manifest)
+	android.AssertStringDoesContain(t, "compiler variable", content, `"LLVM_NEXT": "true"`)
+	android.AssertStringDoesContain(t, "RBE variable", content, `"USE_RBE": "true"`)
+	android.AssertStringDoesContain(t, "ccache variable", content, `"USE_CCACHE": "1"`)
+}
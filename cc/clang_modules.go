@@ -0,0 +1,78 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+// This file contains the experimental support for building libraries with Clang header modules.
+// A library that is built with Clang header modules generates a module map of its exported
+// headers, and exports it to the libraries that depend on it. Each variant of a library has its own
+// module cache in its intermediates directory, where Clang builds the modules of the module maps
+// of its dependencies.
+
+// clangModulesEnabled returns true if the library is built with Clang header modules.
+func (library *libraryDecorator) clangModulesEnabled(ctx ModuleContext) bool {
+	if !config.ClangModulesEnabled(ctx.Config()) {
+		return false
+	}
+	return Bool(library.Properties.Clang_modules) ||
+		config.ClangModulesEnabledForDir(ctx.Config(), ctx.ModuleDir())
+}
+
+// clangModulesFlags adds the flags to build the library with Clang header modules, and generates
+// the module map of the exported include directories.
+func (library *libraryDecorator) clangModulesFlags(ctx ModuleContext, flags Flags, exportIncludeDirs android.Paths) Flags {
+	if !library.clangModulesEnabled(ctx) {
+		return flags
+	}
+
+	flags.Local.CFlags = append(flags.Local.CFlags, config.ClangModulesCflags...)
+	flags.Local.CFlags = append(flags.Local.CFlags,
+		"-fmodules-cache-path="+android.PathForModuleOut(ctx, "clang_module_cache").String(),
+		// The headers of the library are included textually by its own sources.
+		"-fmodule-name="+ctx.ModuleName())
+
+	if len(exportIncludeDirs) > 0 {
+		library.clangModuleMap = buildClangModuleMap(ctx, exportIncludeDirs)
+		flags.Local.CFlags = append(flags.Local.CFlags, "-fmodule-map-file="+library.clangModuleMap.String())
+		flags.CFlagsDeps = append(flags.CFlagsDeps, library.clangModuleMap)
+	}
+	return flags
+}
+
+// buildClangModuleMap writes the module map of the library, with a submodule for each of the
+// exported include directories and a submodule for each header in them.
+func buildClangModuleMap(ctx ModuleContext, dirs android.Paths) android.Path {
+	var b strings.Builder
+	fmt.Fprintf(&b, "module %q {\n", ctx.ModuleName())
+	for i, dir := range dirs {
+		fmt.Fprintf(&b, "  module \"include%d\" {\n", i)
+		fmt.Fprintf(&b, "    umbrella %q\n", dir.String())
+		b.WriteString("    module * { export * }\n")
+		b.WriteString("  }\n")
+	}
+	b.WriteString("  export *\n")
+	b.WriteString("}\n")
+
+	moduleMap := android.PathForModuleOut(ctx, "clang_modules", "module.modulemap")
+	android.WriteFileRule(ctx, moduleMap, b.String())
+	return moduleMap
+}
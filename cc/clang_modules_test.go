@@ -0,0 +1,94 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestClangModules(t *testing.T) {
+	t.Parallel()
+	variant := "android_arm64_armv8-a_shared"
+
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			export_include_dirs: ["include"],
+			clang_modules: true,
+		}
+
+		cc_library_shared {
+			name: "libbar",
+			srcs: ["bar.cpp"],
+			shared_libs: ["libfoo"],
+			clang_modules: true,
+		}
+	`
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		result := android.GroupFixturePreparers(
+			prepareForCcTest,
+			android.FixtureMergeEnv(map[string]string{
+				"SOONG_CLANG_MODULES": "true",
+			}),
+		).RunTestWithBp(t, bp)
+
+		libfoo := result.ModuleForTests("libfoo", variant)
+		cFlags := android.StringRelativeToTop(result.Config, libfoo.Rule("cc").Args["cFlags"])
+		android.AssertStringDoesContain(t, "libfoo cflags", cFlags, "-fmodules")
+		android.AssertStringDoesContain(t, "libfoo cflags", cFlags, "-fmodule-name=libfoo")
+		android.AssertStringDoesContain(t, "libfoo cflags", cFlags,
+			"-fmodules-cache-path=out/soong/.intermediates/libfoo/"+variant+"/clang_module_cache")
+		android.AssertStringDoesContain(t, "libfoo cflags", cFlags,
+			"-fmodule-map-file=out/soong/.intermediates/libfoo/"+variant+"/clang_modules/module.modulemap")
+
+		moduleMap := libfoo.Output("clang_modules/module.modulemap")
+		android.AssertStringEquals(t, "libfoo module map",
+			"module \"libfoo\" {\n"+
+				"  module \"include0\" {\n"+
+				"    umbrella \"include\"\n"+
+				"    module * { export * }\n"+
+				"  }\n"+
+				"  export *\n"+
+				"}\n",
+			android.ContentFromFileRuleForTests(t, moduleMap))
+
+		libbar := result.ModuleForTests("libbar", variant)
+		cFlags = android.StringRelativeToTop(result.Config, libbar.Rule("cc").Args["cFlags"])
+		android.AssertStringDoesContain(t, "libbar cflags", cFlags, "-fmodule-name=libbar")
+		android.AssertStringDoesContain(t, "libbar cflags", cFlags,
+			"-fmodule-map-file=out/soong/.intermediates/libfoo/"+variant+"/clang_modules/module.modulemap")
+		android.AssertStringDoesContain(t, "libbar cflags", cFlags,
+			"-fmodules-cache-path=out/soong/.intermediates/libbar/"+variant+"/clang_module_cache")
+		if libbar.MaybeOutput("clang_modules/module.modulemap").Rule != nil {
+			t.Errorf("unexpected module map for libbar without exported include directories")
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		result := prepareForCcTest.RunTestWithBp(t, bp)
+
+		for _, name := range []string{"libfoo", "libbar"} {
+			cFlags := result.ModuleForTests(name, variant).Rule("cc").Args["cFlags"]
+			android.AssertStringDoesNotContain(t, name+" cflags", cFlags, "-fmodules")
+			android.AssertStringDoesNotContain(t, name+" cflags", cFlags, "-fmodule-map-file=")
+		}
+	})
+}
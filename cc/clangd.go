@@ -0,0 +1,130 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This singleton generates the clangd configuration of a subset of the source tree, so that clangd
+// works on it without indexing the compile_commands.json file of the whole tree. For each directory
+// listed in ${SOONG_GEN_CLANGD_CONFIG} it generates, in
+// ${OUT_DIR}/soong/development/ide/clangd/<directory>:
+//   - compile_commands.json, with the actual flags of each source file in the directory, including
+//     the include paths of the generated headers.
+//   - .clangd, a fragment that points clangd to that compile_commands.json.
+//
+// The files are built by the clangd-config goal, e.g.:
+//   m clangd-config frameworks/native system/core
+// which sets SOONG_GEN_CLANGD_CONFIG to the directories that follow it, and then copies the .clangd
+// fragment of each directory to <directory>/.clangd.
+
+func init() {
+	registerClangdConfigBuildComponents(android.InitRegistrationContext)
+}
+
+func registerClangdConfigBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("clangd_config_generator", clangdConfigGeneratorSingletonFactory)
+}
+
+var prepareForTestWithClangdConfig = android.FixtureRegisterWithContext(registerClangdConfigBuildComponents)
+
+func clangdConfigGeneratorSingletonFactory() android.Singleton {
+	return &clangdConfigGeneratorSingleton{}
+}
+
+type clangdConfigGeneratorSingleton struct{}
+
+const (
+	clangdOutputDirectory = "development/ide/clangd"
+	clangdConfigFilename  = ".clangd"
+
+	// Environment variable with the space separated list of the directories to generate the clangd
+	// configuration of, set by soong_ui for the clangd-config goal.
+	envVariableGenerateClangdConfig = "SOONG_GEN_CLANGD_CONFIG"
+)
+
+func (c *clangdConfigGeneratorSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	dirs := strings.Fields(ctx.Config().Getenv(envVariableGenerateClangdConfig))
+	if len(dirs) == 0 {
+		return
+	}
+	for _, dir := range dirs {
+		if clean := filepath.Clean(dir); filepath.IsAbs(dir) || clean == ".." || strings.HasPrefix(clean, "../") {
+			ctx.Errorf("%s: %q is not a directory relative to the top of the source tree",
+				envVariableGenerateClangdConfig, dir)
+			return
+		}
+	}
+
+	// As in compile_commands.json, there is only one entry per file, whatever module or arch it is
+	// from.
+	entries := make(map[string]compDbEntry)
+	ctx.VisitAllModules(func(module android.Module) {
+		if ccModule, ok := module.(*Module); ok {
+			if compiledModule, ok := ccModule.compiler.(CompiledInterface); ok {
+				generateCompdbProject(compiledModule, ctx, ccModule, entries)
+			}
+		}
+	})
+
+	var outputs android.Paths
+	for _, dir := range android.SortedUniqueStrings(dirs) {
+		dir = filepath.Clean(dir)
+		outputDir := android.PathForOutput(ctx, clangdOutputDirectory, dir)
+
+		compdb := outputDir.Join(ctx, compdbFilename)
+		data, err := json.MarshalIndent(clangdCompdbEntries(entries, dir), "", " ")
+		if err != nil {
+			ctx.Errorf("failed to marshal the compile commands of %s: %s", dir, err)
+			return
+		}
+		android.WriteFileRule(ctx, compdb, string(data))
+
+		// clangd resolves the relative paths of a .clangd fragment from the directory it is in, and
+		// the fragment is copied to the source directory.
+		compdbDir := outputDir.String()
+		if !filepath.IsAbs(compdbDir) {
+			compdbDir = filepath.Join(android.AbsSrcDirForExistingUseCases(), compdbDir)
+		}
+		config := outputDir.Join(ctx, clangdConfigFilename)
+		android.WriteFileRule(ctx, config, strings.Join([]string{
+			fmt.Sprintf("# Generated by `m clangd-config %s`.", dir),
+			"CompileFlags:",
+			"  CompilationDatabase: " + compdbDir,
+		}, "\n"))
+
+		outputs = append(outputs, compdb, config)
+	}
+	ctx.Phony("clangd-config", outputs...)
+}
+
+// clangdCompdbEntries returns the entries of the source files in the directory, sorted by file.
+func clangdCompdbEntries(entries map[string]compDbEntry, dir string) []compDbEntry {
+	ret := []compDbEntry{}
+	for file, entry := range entries {
+		if dir == "." || strings.HasPrefix(file, dir+"/") {
+			ret = append(ret, entry)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File < ret[j].File })
+	return ret
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestClangdConfig(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithClangdConfig,
+		android.FixtureMergeEnv(map[string]string{
+			"SOONG_GEN_CLANGD_CONFIG": "foo",
+		}),
+		android.FixtureAddTextFile("foo/Android.bp", `
+			genrule {
+				name: "foo_headers",
+				cmd: "generate-foo",
+				out: ["foo/config.h"],
+				export_include_dirs: ["."],
+			}
+
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["foo.cpp"],
+				generated_headers: ["foo_headers"],
+			}
+		`),
+		android.FixtureAddTextFile("bar/Android.bp", `
+			cc_library_shared {
+				name: "libbar",
+				srcs: ["bar.cpp"],
+			}
+		`),
+	).RunTest(t)
+
+	singleton := result.SingletonForTests("clangd_config_generator")
+	compdb := singleton.Output("development/ide/clangd/foo/compile_commands.json")
+	var entries []compDbEntry
+	if err := json.Unmarshal([]byte(android.ContentFromFileRuleForTests(t, compdb)), &entries); err != nil {
+		t.Fatalf("invalid compile_commands.json: %s", err)
+	}
+
+	// Only the files of the directory are listed, with the include paths of the generated headers.
+	android.AssertIntEquals(t, "entries", 1, len(entries))
+	android.AssertStringEquals(t, "file", "foo/foo.cpp", entries[0].File)
+	android.AssertStringDoesContain(t, "arguments",
+		android.StringRelativeToTop(result.Config, strings.Join(entries[0].Arguments, " ")),
+		"-Iout/soong/.intermediates/foo/foo_headers/gen")
+
+	config := singleton.Output("development/ide/clangd/foo/.clangd")
+	android.AssertStringDoesContain(t, ".clangd",
+		android.StringRelativeToTop(result.Config, android.ContentFromFileRuleForTests(t, config)),
+		"CompileFlags:\n  CompilationDatabase: ")
+	android.AssertStringDoesContain(t, ".clangd", android.ContentFromFileRuleForTests(t, config),
+		"/development/ide/clangd/foo\n")
+}
+
+func TestClangdConfigInvalidDirectory(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithClangdConfig,
+		android.FixtureMergeEnv(map[string]string{
+			"SOONG_GEN_CLANGD_CONFIG": "../foo",
+		}),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`SOONG_GEN_CLANGD_CONFIG: "../foo" is not a directory relative to the top of the source tree`)).
+		RunTestWithBp(t, "")
+}
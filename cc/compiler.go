@@ -100,6 +100,11 @@ type BaseCompilerProperties struct {
 	// of genrule modules.
 	Generated_headers []string `android:"arch_variant,variant_prepend"`
 
+	// a header to precompile for the C and C++ sources of the module, e.g. a header that includes
+	// the expensive template headers used by most of them. It is implicitly included first in each
+	// C and C++ source, as with -include. Each variant precompiles the header with its own flags.
+	Precompiled_header *string `android:"path,arch_variant"`
+
 	// pass -frtti instead of -fno-rtti
 	Rtti *bool
 
@@ -250,11 +255,25 @@ type baseCompiler struct {
 	// C/C++ (.aidl, .proto, etc.)
 	srcsBeforeGen android.Paths
 
+	// The line of the module in the thread-safety annotation coverage report, if any.
+	threadSafetyReportFile android.Path
+
+	// The status of the module in the branch protection report, if a policy applies to it.
+	branchProtection *branchProtectionStatus
+
 	generatedSourceInfo
 }
 
 var _ compiler = (*baseCompiler)(nil)
 
+func (compiler *baseCompiler) threadSafetyReport() android.Path {
+	return compiler.threadSafetyReportFile
+}
+
+func (compiler *baseCompiler) branchProtectionStatus() *branchProtectionStatus {
+	return compiler.branchProtection
+}
+
 type CompiledInterface interface {
 	Srcs() android.Paths
 }
@@ -302,12 +321,6 @@ func (compiler *baseCompiler) compilerDeps(ctx DepsContext, deps Deps) Deps {
 	return deps
 }
 
-// Return true if the module is in the WarningAllowedProjects.
-func warningsAreAllowed(subdir string) bool {
-	subdir += "/"
-	return android.HasAnyPrefix(subdir, config.WarningAllowedProjects)
-}
-
 func addToModuleList(ctx ModuleContext, key android.OnceKey, module string) {
 	getNamedMapForConfig(ctx.Config(), key).Store(module, true)
 }
@@ -379,6 +392,16 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	flags.Yacc = compiler.Properties.Yacc
 	flags.Lex = compiler.Properties.Lex
 
+	if compiler.Properties.Precompiled_header != nil {
+		header := android.PathForModuleSrc(ctx, *compiler.Properties.Precompiled_header)
+		switch header.Ext() {
+		case ".h", ".hh", ".hpp", ".hxx":
+			flags.PrecompiledHeader = android.OptionalPathForPath(header)
+		default:
+			ctx.PropertyErrorf("precompiled_header", "expected a .h, .hh, .hpp or .hxx header, got %q", header)
+		}
+	}
+
 	// Include dir cflags
 	localIncludeDirs := android.PathsForModuleSrc(ctx, compiler.Properties.Local_include_dirs)
 	if len(localIncludeDirs) > 0 {
@@ -530,6 +553,11 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 	flags.Global.YasmFlags = append(flags.Global.YasmFlags, tc.YasmFlags())
 
 	flags.Global.CommonFlags = append(flags.Global.CommonFlags, tc.ToolchainCflags())
+	// The options of the clang configuration file of the product come after the flags of the
+	// toolchain, so that they can override its tuning, see cc/config/clang_config_file.go.
+	if ctx.Device() {
+		flags.Global.CommonFlags = append(flags.Global.CommonFlags, "${config.DeviceClangConfigFlags}")
+	}
 
 	cStd := parseCStd(compiler.Properties.C_std)
 	cppStd := parseCppStd(compiler.Properties.Cpp_std)
@@ -621,12 +649,16 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 			"-I"+android.PathForModuleGen(ctx, "sysprop", "include").String())
 	}
 
+	// The extra cflags of the warning overrides of the project come after the cflags of the module.
+	warningOverrides := warningOverridesForDir(ctx.Config(), ctx.ModuleDir())
+	flags.Local.CFlags = append(flags.Local.CFlags, warningOverridesCflags(warningOverrides)...)
+
 	if len(compiler.Properties.Srcs) > 0 {
 		module := ctx.ModuleDir() + "/Android.bp:" + ctx.ModuleName()
 		if inList("-Wno-error", flags.Local.CFlags) || inList("-Wno-error", flags.Local.CppFlags) {
 			addToModuleList(ctx, modulesUsingWnoErrorKey, module)
 		} else if !inList("-Werror", flags.Local.CFlags) && !inList("-Werror", flags.Local.CppFlags) {
-			if warningsAreAllowed(ctx.ModuleDir()) {
+			if warningsAreAllowed(warningOverrides) {
 				addToModuleList(ctx, modulesWarningsAllowedKey, module)
 			} else {
 				flags.Local.CFlags = append([]string{"-Werror"}, flags.Local.CFlags...)
@@ -634,6 +666,9 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags, deps
 		}
 	}
 
+	flags = threadSafetyFlags(ctx, flags)
+	flags, compiler.branchProtection = branchProtectionFlags(ctx, flags)
+
 	if Bool(compiler.Properties.Openmp) {
 		flags.Local.CFlags = append(flags.Local.CFlags, "-fopenmp")
 	}
@@ -704,6 +739,8 @@ func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathD
 	// Save src, buildFlags and context
 	compiler.srcs = srcs
 
+	compiler.threadSafetyReportFile = buildThreadSafetyReport(ctx, compiler.srcsBeforeGen)
+
 	// Compile files listed in c.Properties.Srcs into objects
 	objs := compileObjs(ctx, buildFlags, "", srcs,
 		android.PathsForModuleSrc(ctx, compiler.Properties.Tidy_disabled_srcs),
@@ -15,6 +15,7 @@
 package cc
 
 import (
+	"strings"
 	"testing"
 
 	"android/soong/android"
@@ -44,3 +45,103 @@ func TestIsThirdParty(t *testing.T) {
 		}
 	}
 }
+
+func TestPrecompiledHeader(t *testing.T) {
+	t.Parallel()
+	variant := "android_arm64_armv8-a_shared"
+	result := prepareForCcTest.RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: [
+				"a.cpp",
+				"b.c",
+				"c.S",
+			],
+			precompiled_header: "include/pch.h",
+		}
+	`)
+
+	libfoo := result.ModuleForTests("libfoo", variant)
+	pchDir := "out/soong/.intermediates/libfoo/" + variant + "/obj/"
+
+	cppPch := libfoo.Output("obj/pch_cpp/include/pch.h.pch")
+	android.AssertStringEquals(t, "c++ pch language", "c++-header", cppPch.Args["pchLang"])
+	android.AssertPathRelativeToTopEquals(t, "c++ pch input", "include/pch.h", cppPch.Input)
+	cPch := libfoo.Output("obj/pch_c/include/pch.h.pch")
+	android.AssertStringEquals(t, "c pch language", "c-header", cPch.Args["pchLang"])
+
+	a := libfoo.Output("obj/a.o")
+	android.AssertStringDoesContain(t, "a.cpp cflags",
+		android.StringRelativeToTop(result.Config, a.Args["cFlags"]),
+		"-include-pch "+pchDir+"pch_cpp/include/pch.h.pch")
+	android.AssertStringListContains(t, "a.cpp implicits", android.PathsRelativeToTop(a.Implicits),
+		pchDir+"pch_cpp/include/pch.h.pch")
+
+	b := libfoo.Output("obj/b.o")
+	android.AssertStringDoesContain(t, "b.c cflags",
+		android.StringRelativeToTop(result.Config, b.Args["cFlags"]),
+		"-include-pch "+pchDir+"pch_c/include/pch.h.pch")
+
+	c := libfoo.Output("obj/c.o")
+	android.AssertStringDoesNotContain(t, "c.S cflags", c.Args["cFlags"], "-include-pch")
+
+	// The tools that parse the sources include the header itself.
+	tidy := libfoo.Output("obj/a.tidy")
+	android.AssertStringDoesContain(t, "a.cpp tidy cflags", tidy.Args["cFlags"], "-include include/pch.h")
+	android.AssertStringDoesNotContain(t, "a.cpp tidy cflags", tidy.Args["cFlags"], "-include-pch")
+}
+
+func TestPrecompiledHeaderNotAHeader(t *testing.T) {
+	t.Parallel()
+	prepareForCcTest.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`precompiled_header: expected a .h, .hh, .hpp or .hxx header, got "pch.cpp"`)).
+		RunTestWithBp(t, `
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["a.cpp"],
+				precompiled_header: "pch.cpp",
+			}
+		`)
+}
+
+func TestClangConfigFile(t *testing.T) {
+	t.Parallel()
+	bp := `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			host_supported: true,
+		}
+	`
+	setClangConfigFile := android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.ClangConfigFile = StringPtr("vendor/acme/clang.cfg")
+	})
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		setClangConfigFile,
+		android.FixtureAddFile("vendor/acme/clang.cfg", nil),
+	).RunTestWithBp(t, bp)
+
+	device := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("cc")
+	android.AssertStringDoesContain(t, "device cflags", device.Args["cFlags"], "${config.DeviceClangConfigFlags}")
+	if cflags := device.Args["cFlags"]; strings.Index(cflags, "${config.DeviceClangConfigFlags}") < strings.Index(cflags, "${config.Arm64Armv8ACflags}") {
+		t.Errorf("expected the clang config file flags after the toolchain flags, got %q", cflags)
+	}
+	android.AssertStringListContains(t, "device cflags deps", device.Implicits.Strings(), "vendor/acme/clang.cfg")
+
+	host := result.ModuleForTests("libfoo", result.Config.BuildOSTarget.String()+"_shared").Rule("cc")
+	android.AssertStringDoesNotContain(t, "host cflags", host.Args["cFlags"], "${config.DeviceClangConfigFlags}")
+	android.AssertStringListDoesNotContain(t, "host cflags deps", host.Implicits.Strings(), "vendor/acme/clang.cfg")
+
+	t.Run("missing", func(t *testing.T) {
+		android.GroupFixturePreparers(
+			prepareForCcTest,
+			android.PrepareForTestWithMakevars,
+			setClangConfigFile,
+		).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`ClangConfigFile: vendor/acme/clang.cfg does not exist`)).
+			RunTestWithBp(t, "")
+	})
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains support for the clang configuration file of the product, set with the
+// ClangConfigFile product variable, which adds flags to the compiles of the device modules, e.g.
+// to tune the code generation for the CPU of the product, without patching the global flags of
+// cc/config. The host modules are built for the build machine and don't use it.
+//
+// Clang inserts the options of a file passed with --config= at the start of the command line, so
+// the global flags and the flags of the toolchain, e.g. -march and -mcpu, would win over them.
+// Instead, the options of the file are read by Soong and passed after the global flags and the
+// flags of the toolchain, as clang does with the tail options of a configuration file, which are
+// prefixed with $. The file has one or more options per line, and comment lines starting with #.
+// Quoting, escaping and nested configuration files (@file) are not supported.
+
+func init() {
+	exportedVars.ExportVariableConfigMethod("DeviceClangConfigFlags", deviceClangConfigFlags)
+}
+
+func deviceClangConfigFlags(config android.Config) string {
+	flags, _ := ClangConfigFileFlags(config)
+	return strings.Join(flags, " ")
+}
+
+type clangConfigFileResult struct {
+	flags []string
+	err   error
+}
+
+var clangConfigFileKey = android.NewOnceKey("clangConfigFile")
+
+// ClangConfigFileFlags returns the options of the clang configuration file of the product, or an
+// error if it can't be read or if it is not valid, in which case there are no options.
+func ClangConfigFileFlags(config android.Config) ([]string, error) {
+	result := config.Once(clangConfigFileKey, func() interface{} {
+		file := config.ClangConfigFile()
+		if file == "" {
+			return clangConfigFileResult{}
+		}
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(android.AbsSrcDirForExistingUseCases(), path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return clangConfigFileResult{nil, fmt.Errorf("ClangConfigFile: %s", err)}
+		}
+		flags, err := parseClangConfigFile(string(data))
+		if err != nil {
+			return clangConfigFileResult{nil, fmt.Errorf("ClangConfigFile %s: %s", file, err)}
+		}
+		return clangConfigFileResult{flags, nil}
+	}).(clangConfigFileResult)
+	return result.flags, result.err
+}
+
+// parseClangConfigFile returns the options of the contents of a clang configuration file, without
+// the $ prefix of the tail options.
+func parseClangConfigFile(data string) ([]string, error) {
+	var flags []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.ContainsAny(line, `"'\`) {
+			return nil, fmt.Errorf("quoting and escaping are not supported, found %q", line)
+		}
+		for _, option := range strings.Fields(line) {
+			option = strings.TrimPrefix(option, "$")
+			if strings.HasPrefix(option, "@") {
+				return nil, fmt.Errorf("nested configuration files are not supported, found %q", option)
+			}
+			flags = append(flags, option)
+		}
+	}
+	return flags, nil
+}
+
+// ClangConfigFile returns the clang configuration file of the product, or an invalid path if the
+// product doesn't set one or if it doesn't exist.
+func ClangConfigFile(ctx android.PathGlobContext) android.OptionalPath {
+	file := ctx.Config().ClangConfigFile()
+	if file == "" {
+		return android.OptionalPath{}
+	}
+	return android.ExistentPathForSource(ctx, file)
+}
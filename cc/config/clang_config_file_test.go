@@ -0,0 +1,72 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseClangConfigFile(t *testing.T) {
+	testCases := []struct {
+		name     string
+		data     string
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "empty",
+			data:     "",
+			expected: nil,
+		},
+		{
+			name:     "options",
+			data:     "# Tuning for the acme CPU.\n-mcpu=cortex-a78 -mtune=cortex-a78\n\n  -fno-vectorize\n",
+			expected: []string{"-mcpu=cortex-a78", "-mtune=cortex-a78", "-fno-vectorize"},
+		},
+		{
+			name:     "tail options",
+			data:     "$-mcpu=cortex-a78\n",
+			expected: []string{"-mcpu=cortex-a78"},
+		},
+		{
+			name: "quoted",
+			data: "-DFOO=\"bar\"\n",
+			err:  true,
+		},
+		{
+			name: "nested file",
+			data: "@other.cfg\n",
+			err:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, err := parseClangConfigFile(tc.data)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %q", flags)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(flags, tc.expected) {
+				t.Errorf("expected %q, got %q", tc.expected, flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"android/soong/android"
+)
+
+// Experimental support for building C++ libraries with Clang header modules, to evaluate the
+// compile time wins. It is disabled by default, and only applies to the libraries that opt in with
+// the clang_modules property or that are in the SOONG_CLANG_MODULES_PATHS directories when it is
+// enabled with SOONG_CLANG_MODULES=true.
+
+var (
+	// Flags added to the compilation of the libraries that are built with Clang header modules.
+	// Only the module maps passed explicitly are used, so that the modules that are built only
+	// depend on the module maps generated for the dependencies. The paths in the generated module
+	// maps are relative to the root of the source tree rather than to the module maps.
+	ClangModulesCflags = []string{
+		"-fmodules",
+		"-fno-implicit-module-maps",
+		"-Xclang -fmodule-map-file-home-is-cwd",
+	}
+)
+
+// ClangModulesEnabled returns true if the experimental support for Clang header modules is enabled
+// for the build.
+func ClangModulesEnabled(config android.Config) bool {
+	return config.IsEnvTrue("SOONG_CLANG_MODULES")
+}
+
+// ClangModulesEnabledForDir returns true if the libraries in the directory are built with Clang
+// header modules regardless of their clang_modules property, because the directory is in the
+// comma or space separated SOONG_CLANG_MODULES_PATHS list, or in a subdirectory of one of them.
+func ClangModulesEnabledForDir(config android.Config, dir string) bool {
+	if !ClangModulesEnabled(config) {
+		return false
+	}
+	paths := strings.FieldsFunc(config.Getenv("SOONG_CLANG_MODULES_PATHS"), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if dir == path || strings.HasPrefix(dir, path+"/") {
+			return true
+		}
+	}
+	return false
+}
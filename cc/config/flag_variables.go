@@ -0,0 +1,39 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+// flagVariablesNotExported are the flag variables of this package that are not exported to Bazel.
+var flagVariablesNotExported = []string{
+	"SDClangFlags",
+}
+
+// FlagVariableNames returns the sorted names of the flag variables of this package that are
+// declared in Soong, the exported ones and the ones that are only used by Soong. The variables that
+// are only exported to Bazel are not declared as ninja variables, and are not included.
+func FlagVariableNames() []string {
+	var names []string
+	for _, name := range append(exportedVars.SoongNames(), flagVariablesNotExported...) {
+		if strings.Contains(strings.ToLower(name), "flags") {
+			names = append(names, name)
+		}
+	}
+	return android.SortedUniqueStrings(names)
+}
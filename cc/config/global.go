@@ -15,16 +15,13 @@
 package config
 
 import (
-	"encoding/json"
 	"encoding/xml"
-	"fmt"
 	"io/ioutil"
 	"os"
 
 	//"path"
 	//"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 
 	"android/soong/android"
@@ -178,7 +175,6 @@ var (
 		"-Wl,-z,noexecstack",
 		"-Wl,-z,relro",
 		"-Wl,-z,now",
-		"-Wl,--build-id=md5",
 		"-Wl,--fatal-warnings",
 		"-Wl,--no-undefined-version",
 		// TODO: Eventually we should link against a libunwind.a with hidden symbols, and then these
@@ -214,156 +210,23 @@ var (
 		"-Wno-gnu-include-next",
 	}
 
-	noOverrideGlobalCflags = []string{
-		"-Werror=bool-operation",
-		"-Werror=format-insufficient-args",
-		"-Werror=implicit-int-float-conversion",
-		"-Werror=int-in-bool-context",
-		"-Werror=int-to-pointer-cast",
-		"-Werror=pointer-to-int-cast",
-		"-Werror=xor-used-as-pow",
-		// http://b/161386391 for -Wno-void-pointer-to-enum-cast
-		"-Wno-void-pointer-to-enum-cast",
-		// http://b/161386391 for -Wno-void-pointer-to-int-cast
-		"-Wno-void-pointer-to-int-cast",
-		// http://b/161386391 for -Wno-pointer-to-int-cast
-		"-Wno-pointer-to-int-cast",
-		// SDClang does not support -Werror=fortify-source.
-		// TODO: b/142476859
-		// "-Werror=fortify-source",
-
-		"-Werror=address-of-temporary",
-		"-Werror=null-dereference",
-		"-Werror=return-type",
-
-		// http://b/72331526 Disable -Wtautological-* until the instances detected by these
-		// new warnings are fixed.
-		"-Wno-tautological-constant-compare",
-		"-Wno-tautological-type-limit-compare",
-		// http://b/145210666
-		"-Wno-reorder-init-list",
-		// http://b/145211066
-		"-Wno-implicit-int-float-conversion",
-		// New warnings to be fixed after clang-r377782.
-		"-Wno-tautological-overlap-compare", // http://b/148815696
-		// New warnings to be fixed after clang-r383902.
-		"-Wno-deprecated-copy",                      // http://b/153746672
-		"-Wno-range-loop-construct",                 // http://b/153747076
-		"-Wno-zero-as-null-pointer-constant",        // http://b/68236239
-		"-Wno-deprecated-anon-enum-enum-conversion", // http://b/153746485
-		"-Wno-pessimizing-move",                     // http://b/154270751
-		// New warnings to be fixed after clang-r399163
-		"-Wno-non-c-typedef-for-linkage", // http://b/161304145
-		// New warnings to be fixed after clang-r428724
-		"-Wno-align-mismatch", // http://b/193679946
-		// New warnings to be fixed after clang-r433403
-		"-Wno-error=unused-but-set-variable",  // http://b/197240255
-		"-Wno-error=unused-but-set-parameter", // http://b/197240255
-		// New warnings to be fixed after clang-r468909
-		"-Wno-error=deprecated-builtins", // http://b/241601211
-		"-Wno-error=deprecated",          // in external/googletest/googletest
-		// New warnings to be fixed after clang-r475365
-		"-Wno-error=single-bit-bitfield-constant-conversion", // http://b/243965903
-		"-Wno-error=enum-constexpr-conversion",               // http://b/243964282
-
-		//Android T Vendor Compilation
-		"-Wno-reorder-init-list",
-		"-Wno-implicit-fallthrough",
-		"-Wno-c99-designator",
-		"-Wno-implicit-int-float-conversion",
-		"-Wno-int-in-bool-context",
-		"-Wno-alloca",
-		"-Wno-dangling-gsl",
-		"-Wno-pointer-compare",
-		"-Wno-final-dtor-non-final-class",
-		"-Wno-incomplete-setjmp-declaration",
-		"-Wno-sizeof-array-div",
-		"-Wno-xor-used-as-pow",
-		//"-fsplit-lto-unit",
-		"-Wno-c++17-extensions",
-		"-flax-vector-conversions=all",
-		"-Wno-tautological-overlap-compare",
-		"-Wno-range-loop-analysis",
-		"-Wno-invalid-partial-specialization",
-		"-Wno-deprecated-copy",
-		"-Wno-misleading-indentation",
-		"-Wno-zero-as-null-pointer-constant",
-		"-Wno-deprecated-enum-enum-conversion",
-		"-Wno-deprecated-anon-enum-enum-conversion",
-		"-Wno-bool-operation",
-		"-Wno-unused-comparison",
-		"-Wno-string-compare",
-		"-Wno-wrong-info",
-		"-Wno-unsequenced",
-		"-Wno-unknown-warning-option",
-		"-Wno-unused-variable",
-		"-Wno-unused-value",
-		"-Wno-unused-parameter",
-		"-Wno-non-c-typedef-for-linkage",
-		"-Wno-typedef-redefinition",
-		"-Wno-format",
-		"-Wno-void-pointer-to-int-cast",
-		"-Wno-pointer-to-int-cast",
-		"-Wno-string-concatenation",
-		"-Wno-void-pointer-to-enum-cast",
-		"-Wno-incompatible-pointer-types",
-		"-Wno-format-invalid-specifier-fcommon",
-		" -Wno-self-assign",
-		"-Wno-format",
-		"-Wno-unused-label",
-		"-Wno-pointer-sign",
-		"-Wno-writable-strings",
-		"-Wno-missing-declarations",
-		"-Wno-reorder-ctor",
-		"-Wno-unused-function",
-	}
+	// The flags of the warning policy that apply to all the projects, see warning_policy.go.
+	noOverrideGlobalCflags = warningPolicyCflags(platformWarningPolicies)
 
 	noOverride64GlobalCflags = []string{}
 
-	noOverrideExternalGlobalCflags = []string{
-		// http://b/191699019
-		"-Wno-format-insufficient-args",
-		"-Wno-sizeof-array-div",
-		"-Wno-incompatible-function-pointer-types",
-		"-Wno-unused-but-set-variable",
-		"-Wno-unused-but-set-parameter",
-		"-Wno-unqualified-std-cast-call",
-		"-Wno-bitwise-instead-of-logical",
-		"-Wno-misleading-indentation",
-		"-Wno-array-parameter",
-		"-Wno-gnu-offsetof-extensions",
-	}
-
-	// Extra cflags for external third-party projects to disable warnings that
-	// are infeasible to fix in all the external projects and their upstream repos.
-	extraExternalCflags = []string{
-		"-Wno-enum-compare",
-		"-Wno-enum-compare-switch",
-
-		// http://b/72331524 Allow null pointer arithmetic until the instances detected by
-		// this new warning are fixed.
-		"-Wno-null-pointer-arithmetic",
-
-		// Bug: http://b/29823425 Disable -Wnull-dereference until the
-		// new instances detected by this warning are fixed.
-		"-Wno-null-dereference",
-
-		// http://b/145211477
-		"-Wno-pointer-compare",
-		"-Wno-final-dtor-non-final-class",
-
-		// http://b/165945989
-		"-Wno-psabi",
-
-		// http://b/199369603
-		"-Wno-null-pointer-subtraction",
+	// The flags of the warning policy that apply to the projects under device/ and vendor/, along
+	// with -flax-vector-conversions=all, which is not a warning, for the implicit conversions
+	// between vector types that their code relies on.
+	noOverrideVendorGlobalCflags = append(warningPolicyCflags(vendorWarningPolicies),
+		"-flax-vector-conversions=all")
 
-		// http://b/175068488
-		"-Wno-string-concatenation",
+	// The flags of the warning policy that apply to the third-party projects.
+	noOverrideExternalGlobalCflags = warningPolicyCflags(externalWarningPolicies)
 
-		// http://b/239661264
-		"-Wno-deprecated-non-prototype",
-	}
+	// The flags of the warning policy that apply to the third-party projects, and that the modules
+	// can override with their own cflags.
+	extraExternalCflags = warningPolicyCflags(externalOverridableWarningPolicies)
 
 	llvmNextExtraCommonGlobalCflags = []string{
 		// Do not report warnings when testing with the top of trunk LLVM.
@@ -379,20 +242,11 @@ var (
 	ExperimentalCStdVersion   = "gnu17"
 	ExperimentalCppStdVersion = "gnu++2a"
 
-	SDClang         = false
-	SDClangPath     = ""
-	ForceSDClangOff = false
-
 	// prebuilts/clang default settings.
 	ClangDefaultBase         = "prebuilts/clang/host"
 	ClangDefaultVersion      = "clang-r498229b"
 	ClangDefaultShortVersion = "17"
 
-	// Directories with warnings from Android.bp files.
-	WarningAllowedProjects = []string{
-		"device/",
-		"vendor/",
-	}
 	QiifaAbiLibraryList = []string{}
 
 	VersionScriptFlagPrefix = "-Wl,--version-script,"
@@ -431,6 +285,14 @@ func init() {
 	exportedVars.ExportStringListStaticVariable("DeviceGlobalCppflags", deviceGlobalCppflags)
 	exportedVars.ExportStringListStaticVariable("DeviceGlobalLdflags", deviceGlobalLdflags)
 	exportedVars.ExportStringListStaticVariable("DeviceGlobalLldflags", deviceGlobalLldflags)
+
+	// The build id flag of the product, cc modules use the override of their partition if any.
+	exportedVars.ExportVariableConfigMethod("DeviceBuildIdLdflags", func(config android.Config) string {
+		return DeviceBuildIdLdflag(config, "")
+	})
+
+	// The directory of the sampled AFDO profiles of the product, see cc/afdo.go.
+	exportedVars.ExportVariableConfigMethod("AfdoProfileDir", android.Config.AfdoProfileDir)
 	exportedVars.ExportStringListStaticVariable("HostGlobalCppflags", hostGlobalCppflags)
 	exportedVars.ExportStringListStaticVariable("HostGlobalLdflags", hostGlobalLdflags)
 	exportedVars.ExportStringListStaticVariable("HostGlobalLldflags", hostGlobalLldflags)
@@ -479,13 +341,14 @@ func init() {
 	pctx.VariableFunc("NoOverrideGlobalCflags", func(ctx android.PackageVarContext) string {
 		flags := noOverrideGlobalCflags
 		if ctx.Config().IsEnvTrue("LLVM_NEXT") {
-			flags = append(noOverrideGlobalCflags, llvmNextExtraCommonGlobalCflags...)
+			flags = append(android.CopyOf(noOverrideGlobalCflags), llvmNextExtraCommonGlobalCflags...)
 		}
 		return strings.Join(flags, " ")
 	})
 
 	exportedVars.ExportStringListStaticVariable("NoOverride64GlobalCflags", noOverride64GlobalCflags)
 	exportedVars.ExportStringListStaticVariable("HostGlobalCflags", hostGlobalCflags)
+	exportedVars.ExportStringListStaticVariable("NoOverrideVendorGlobalCflags", noOverrideVendorGlobalCflags)
 	exportedVars.ExportStringListStaticVariable("NoOverrideExternalGlobalCflags", noOverrideExternalGlobalCflags)
 	exportedVars.ExportStringListStaticVariable("CommonGlobalCppflags", commonGlobalCppflags)
 	exportedVars.ExportStringListStaticVariable("ExternalCflags", extraExternalCflags)
@@ -516,8 +379,6 @@ func init() {
 	exportedVars.ExportStringList("CommonGlobalIncludes", commonGlobalIncludes)
 	pctx.PrefixedExistentPathsForSourcesVariable("CommonGlobalIncludes", "-I", commonGlobalIncludes)
 
-	setSdclangVars()
-
 	pctx.StaticVariableWithEnvOverride("ClangBase", "LLVM_PREBUILTS_BASE", ClangDefaultBase)
 	exportedVars.ExportStringStaticVariableWithEnvOverride("ClangVersion", "LLVM_PREBUILTS_VERSION", ClangDefaultVersion)
 	pctx.StaticVariable("ClangPath", "${ClangBase}/${HostPrebuiltTag}/${ClangVersion}")
@@ -556,152 +417,10 @@ func init() {
 	pctx.StaticVariableWithEnvOverride("REAbiLinkerExecStrategy", "RBE_ABI_LINKER_EXEC_STRATEGY", remoteexec.LocalExecStrategy)
 }
 
-func setSdclangVars() {
-	sdclangPath := ""
-	sdclangAEFlag := ""
-	sdclangFlags := ""
-
-	product := os.Getenv("TARGET_BOARD_PLATFORM")
-	aeConfigPath := os.Getenv("SDCLANG_AE_CONFIG")
-	sdclangConfigPath := os.Getenv("SDCLANG_CONFIG")
-	sdclangSA := os.Getenv("SDCLANG_SA_ENABLED")
-
-	// Bail out if SDCLANG_CONFIG isn't set
-	if sdclangConfigPath == "" {
-		return
-	}
-
-	type sdclangAEConfig struct {
-		SDCLANG_AE_FLAG string
-	}
-
-	// Load AE config file and set AE flag
-	if file, err := os.Open(aeConfigPath); err == nil {
-		decoder := json.NewDecoder(file)
-		aeConfig := sdclangAEConfig{}
-		if err := decoder.Decode(&aeConfig); err == nil {
-			sdclangAEFlag = aeConfig.SDCLANG_AE_FLAG
-		} else {
-			panic(err)
-		}
-	}
-
-	// Load SD Clang config file and set SD Clang variables
-	var sdclangConfig interface{}
-	if file, err := os.Open(sdclangConfigPath); err == nil {
-		decoder := json.NewDecoder(file)
-		// Parse the config file
-		if err := decoder.Decode(&sdclangConfig); err == nil {
-			config := sdclangConfig.(map[string]interface{})
-			// Retrieve the default block
-			if dev, ok := config["default"]; ok {
-				devConfig := dev.(map[string]interface{})
-				// FORCE_SDCLANG_OFF is required in the default block
-				if _, ok := devConfig["FORCE_SDCLANG_OFF"]; ok {
-					ForceSDClangOff = devConfig["FORCE_SDCLANG_OFF"].(bool)
-				}
-				// SDCLANG is optional in the default block
-				if _, ok := devConfig["SDCLANG"]; ok {
-					SDClang = devConfig["SDCLANG"].(bool)
-				}
-				// SDCLANG_PATH is required in the default block
-				if _, ok := devConfig["SDCLANG_PATH"]; ok {
-					sdclangPath = devConfig["SDCLANG_PATH"].(string)
-				} else {
-					panic("SDCLANG_PATH is required in the default block")
-				}
-				// SDCLANG_FLAGS is optional in the default block
-				if _, ok := devConfig["SDCLANG_FLAGS"]; ok {
-					sdclangFlags = devConfig["SDCLANG_FLAGS"].(string)
-				}
-			} else {
-				panic("Default block is required in the SD Clang config file")
-			}
-			// Retrieve the device specific block if it exists in the config file
-			if dev, ok := config[product]; ok {
-				devConfig := dev.(map[string]interface{})
-				// SDCLANG is optional in the device specific block
-				if _, ok := devConfig["SDCLANG"]; ok {
-					SDClang = devConfig["SDCLANG"].(bool)
-				}
-				// SDCLANG_PATH is optional in the device specific block
-				if _, ok := devConfig["SDCLANG_PATH"]; ok {
-					sdclangPath = devConfig["SDCLANG_PATH"].(string)
-				}
-				// SDCLANG_FLAGS is optional in the device specific block
-				if _, ok := devConfig["SDCLANG_FLAGS"]; ok {
-					sdclangFlags = devConfig["SDCLANG_FLAGS"].(string)
-				}
-			}
-			b, _ := strconv.ParseBool(sdclangSA)
-			if b {
-				llvmsa_loc := "llvmsa"
-				s := []string{sdclangFlags, "--compile-and-analyze", llvmsa_loc}
-				sdclangFlags = strings.Join(s, " ")
-				fmt.Println("Clang SA is enabled: ", sdclangFlags)
-			} else {
-				fmt.Println("Clang SA is not enabled")
-			}
-		} else {
-			panic(err)
-		}
-	} else {
-		fmt.Println(err)
-	}
-
-	// Override SDCLANG if the varialbe is set in the environment
-	if sdclang := os.Getenv("SDCLANG"); sdclang != "" {
-		if override, err := strconv.ParseBool(sdclang); err == nil {
-			SDClang = override
-		}
-	}
-
-	// Sanity check SDCLANG_PATH
-	if envPath := os.Getenv("SDCLANG_PATH"); SDClang && sdclangPath == "" && envPath == "" {
-		panic("SDCLANG_PATH can not be empty")
-	}
-
-	// Override SDCLANG_PATH if the variable is set in the environment
-	pctx.VariableFunc("SDClangBin", func(ctx android.PackageVarContext) string {
-		if override := ctx.Config().Getenv("SDCLANG_PATH"); override != "" {
-			return override
-		}
-		return sdclangPath
-	})
-
-	// Override SDCLANG_COMMON_FLAGS if the variable is set in the environment
-	pctx.VariableFunc("SDClangFlags", func(ctx android.PackageVarContext) string {
-		if override := ctx.Config().Getenv("SDCLANG_COMMON_FLAGS"); override != "" {
-			return override
-		}
-		return sdclangAEFlag + " " + sdclangFlags
-	})
-
-	SDClangPath = sdclangPath
-	// Find the path to SDLLVM's ASan libraries
-	// TODO (b/117846004): Disable setting SDClangAsanLibDir due to unit test path issues
-	//absPath := sdclangPath
-	//if envPath := android.SdclangEnv["SDCLANG_PATH"]; envPath != "" {
-	//	absPath = envPath
-	//}
-	//if !filepath.IsAbs(absPath) {
-	//	absPath = path.Join(androidRoot, absPath)
-	//}
-	//
-	//libDirPrefix := "../lib/clang"
-	//libDir, err := ioutil.ReadDir(path.Join(absPath, libDirPrefix))
-	//if err != nil {
-	//	libDirPrefix = "../lib64/clang"
-	//	libDir, err = ioutil.ReadDir(path.Join(absPath, libDirPrefix))
-	//}
-	//if err != nil {
-	//	panic(err)
-	//}
-	//if len(libDir) != 1 || !libDir[0].IsDir() {
-	//	panic("Failed to find sanitizer libraries")
-	//}
-	//
-	//pctx.StaticVariable("SDClangAsanLibDir", path.Join(absPath, libDirPrefix, libDir[0].Name(), "lib/linux"))
+// DeviceBuildIdLdflag returns the linker flag selecting the style of the build ids of the device
+// ELF files installed in the partition.
+func DeviceBuildIdLdflag(config android.Config, partition string) string {
+	return "-Wl,--build-id=" + config.DeviceBuildIdStyle(partition)
 }
 
 var HostPrebuiltTag = exportedVars.ExportVariableConfigMethod("HostPrebuiltTag", android.Config.PrebuiltOS)
@@ -0,0 +1,55 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+// The platform shared libraries in the IdentityNoteDirs directories are stamped with a
+// .note.android.ident note that identifies the module that built them, for the on-device tooling
+// that attributes crashes to modules, e.g. when a vendor overlays a platform library. It can be
+// disabled with SOONG_DISABLE_IDENTITY_NOTES=true.
+
+var (
+	// The directories whose platform shared libraries are stamped with an identity note.
+	IdentityNoteDirs = []string{
+		"art/",
+		"bionic/",
+		"external/",
+		"frameworks/",
+		"hardware/interfaces/",
+		"libcore/",
+		"packages/modules/",
+		"system/",
+	}
+)
+
+// IdentityNoteEnabledForDir returns true if the platform shared libraries in the directory are
+// stamped with an identity note.
+func IdentityNoteEnabledForDir(config android.Config, dir string) bool {
+	if config.IsEnvTrue("SOONG_DISABLE_IDENTITY_NOTES") {
+		return false
+	}
+	dir = dir + "/"
+	for _, prefix := range IdentityNoteDirs {
+		if strings.HasPrefix(dir, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains the SD Clang configuration of the build, read from the file of
+// ${SDCLANG_CONFIG}, which looks like:
+//
+//	{
+//	    "default": {
+//	        "SDCLANG": true,
+//	        "SDCLANG_PATH": "prebuilts/sdclang/bin",
+//	        "SDCLANG_FLAGS": "-fno-vectorize",
+//	        "FORCE_SDCLANG_OFF": false
+//	    },
+//	    "<TARGET_BOARD_PLATFORM>": {
+//	        "SDCLANG_ALLOW_LIST": ["libfoo", "vendor/acme/"],
+//	        "SDCLANG_DENY_LIST": ["libbar"]
+//	    }
+//	}
+//
+// The default block is required, the block of the board platform overrides its settings and
+// extends its lists. The configuration is read once per build, and its errors are reported once
+// by the make vars of the cc package rather than by panicking when loading Soong. Without a valid
+// configuration, the modules are built with the clang of prebuilts/clang.
+
+// SDClangConfig is the SD Clang configuration of the build.
+type SDClangConfig struct {
+	// Whether the device modules are built with SDClang by default.
+	SDClang bool
+
+	// Whether SDClang is disabled for all the modules, even the ones that set use_sdclang.
+	ForceOff bool
+
+	// The directory of the SDClang binaries.
+	Path string
+
+	// The extra flags of the compile and link commands that use SDClang.
+	Flags string
+
+	// Modules, or directories of modules when ending with /, that are built with SDClang, or with
+	// the clang of prebuilts/clang, when they don't set use_sdclang. The deny list wins over the
+	// allow list, which wins over SDClang.
+	AllowList []string
+	DenyList  []string
+}
+
+// sdclangConfigBlock is a block of the SD Clang config file.
+type sdclangConfigBlock struct {
+	FORCE_SDCLANG_OFF  *bool
+	SDCLANG            *bool
+	SDCLANG_PATH       *string
+	SDCLANG_FLAGS      *string
+	SDCLANG_ALLOW_LIST []string
+	SDCLANG_DENY_LIST  []string
+}
+
+type sdclangAEConfig struct {
+	SDCLANG_AE_FLAG string
+}
+
+type sdclangConfigResult struct {
+	config SDClangConfig
+	err    error
+}
+
+var sdclangConfigKey = android.NewOnceKey("sdclangConfig")
+
+// SDClang returns the SD Clang configuration of the build, or an error if it is not valid, in which
+// case the configuration disables SDClang.
+func SDClang(config android.Config) (SDClangConfig, error) {
+	result := config.Once(sdclangConfigKey, func() interface{} {
+		c, err := loadSDClangConfig(config.Getenv)
+		if err != nil {
+			return sdclangConfigResult{SDClangConfig{}, err}
+		}
+		return sdclangConfigResult{c, nil}
+	}).(sdclangConfigResult)
+	return result.config, result.err
+}
+
+// loadSDClangConfig returns the SD Clang configuration of the environment.
+func loadSDClangConfig(getenv func(string) string) (SDClangConfig, error) {
+	var c SDClangConfig
+
+	configPath := getenv("SDCLANG_CONFIG")
+	if configPath == "" {
+		return c, nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return c, fmt.Errorf("SDCLANG_CONFIG: %s", err)
+	}
+	c, err = parseSDClangConfig(data, getenv("TARGET_BOARD_PLATFORM"))
+	if err != nil {
+		return c, fmt.Errorf("SDCLANG_CONFIG %s: %s", configPath, err)
+	}
+
+	if aeConfigPath := getenv("SDCLANG_AE_CONFIG"); aeConfigPath != "" {
+		data, err := os.ReadFile(aeConfigPath)
+		if err != nil {
+			return c, fmt.Errorf("SDCLANG_AE_CONFIG: %s", err)
+		}
+		var aeConfig sdclangAEConfig
+		if err := decodeSDClangJSON(data, &aeConfig); err != nil {
+			return c, fmt.Errorf("SDCLANG_AE_CONFIG %s: %s", aeConfigPath, err)
+		}
+		c.Flags = strings.TrimSpace(aeConfig.SDCLANG_AE_FLAG + " " + c.Flags)
+	}
+
+	if sa, _ := strconv.ParseBool(getenv("SDCLANG_SA_ENABLED")); sa {
+		c.Flags = strings.TrimSpace(c.Flags + " --compile-and-analyze llvmsa")
+	}
+
+	// Override SDCLANG if the variable is set in the environment
+	if sdclang := getenv("SDCLANG"); sdclang != "" {
+		override, err := strconv.ParseBool(sdclang)
+		if err != nil {
+			return c, fmt.Errorf("SDCLANG: invalid value %q, expected true or false", sdclang)
+		}
+		c.SDClang = override
+	}
+
+	// Override SDCLANG_PATH if the variable is set in the environment
+	if path := getenv("SDCLANG_PATH"); path != "" {
+		c.Path = path
+	}
+
+	// Override SDCLANG_COMMON_FLAGS if the variable is set in the environment
+	if flags := getenv("SDCLANG_COMMON_FLAGS"); flags != "" {
+		c.Flags = flags
+	}
+
+	if c.SDClang && c.Path == "" {
+		return c, fmt.Errorf("SDCLANG_CONFIG %s: SDCLANG_PATH can not be empty", configPath)
+	}
+	return c, nil
+}
+
+// parseSDClangConfig returns the SD Clang configuration of the board platform from the contents of
+// the SD Clang config file.
+func parseSDClangConfig(data []byte, product string) (SDClangConfig, error) {
+	var c SDClangConfig
+
+	var blocks map[string]json.RawMessage
+	if err := decodeSDClangJSON(data, &blocks); err != nil {
+		return c, err
+	}
+	if _, ok := blocks["default"]; !ok {
+		return c, fmt.Errorf("missing the default block, found %s", sdclangBlockNames(blocks))
+	}
+
+	apply := func(name string) error {
+		var block sdclangConfigBlock
+		if err := decodeSDClangJSON(blocks[name], &block); err != nil {
+			return fmt.Errorf("block %q: %s", name, err)
+		}
+		if name == "default" {
+			if block.SDCLANG_PATH == nil {
+				return fmt.Errorf("block %q: SDCLANG_PATH is required", name)
+			}
+			c.ForceOff = block.FORCE_SDCLANG_OFF != nil && *block.FORCE_SDCLANG_OFF
+		} else if block.FORCE_SDCLANG_OFF != nil {
+			return fmt.Errorf("block %q: FORCE_SDCLANG_OFF is only supported in the default block", name)
+		}
+		if block.SDCLANG != nil {
+			c.SDClang = *block.SDCLANG
+		}
+		if block.SDCLANG_PATH != nil {
+			c.Path = *block.SDCLANG_PATH
+		}
+		if block.SDCLANG_FLAGS != nil {
+			c.Flags = *block.SDCLANG_FLAGS
+		}
+		for _, entry := range append(android.CopyOf(block.SDCLANG_ALLOW_LIST), block.SDCLANG_DENY_LIST...) {
+			if entry == "" || strings.HasPrefix(entry, "/") {
+				return fmt.Errorf("block %q: invalid entry %q in SDCLANG_ALLOW_LIST or SDCLANG_DENY_LIST, "+
+					"expected a module name or a directory ending with /", name, entry)
+			}
+		}
+		c.AllowList = append(c.AllowList, block.SDCLANG_ALLOW_LIST...)
+		c.DenyList = append(c.DenyList, block.SDCLANG_DENY_LIST...)
+		return nil
+	}
+
+	if err := apply("default"); err != nil {
+		return c, err
+	}
+	if _, ok := blocks[product]; ok && product != "default" {
+		if err := apply(product); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// decodeSDClangJSON decodes the JSON data into v, and returns an error with the field and the
+// expected type on a mismatch. The unknown fields are ignored, as the SD Clang config files of the
+// vendors may have settings for other tools.
+func decodeSDClangJSON(data []byte, v interface{}) error {
+	err := json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return fmt.Errorf("%s must be a %s, found a %s", typeErr.Field, sdclangJSONType(typeErr.Type.String()), typeErr.Value)
+	}
+	return err
+}
+
+func sdclangJSONType(goType string) string {
+	switch goType {
+	case "bool":
+		return "bool"
+	case "string":
+		return "string"
+	case "[]string":
+		return "list of strings"
+	}
+	return "object"
+}
+
+func sdclangBlockNames(blocks map[string]json.RawMessage) string {
+	var names []string
+	for name := range blocks {
+		names = append(names, strconv.Quote(name))
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "no block"
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	pctx.VariableFunc("SDClangBin", func(ctx android.PackageVarContext) string {
+		c, _ := SDClang(ctx.Config())
+		return c.Path
+	})
+	pctx.VariableFunc("SDClangFlags", func(ctx android.PackageVarContext) string {
+		c, _ := SDClang(ctx.Config())
+		return c.Flags
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestParseSDClangConfig(t *testing.T) {
+	c, err := parseSDClangConfig([]byte(`{
+		"default": {
+			"SDCLANG": false,
+			"SDCLANG_PATH": "prebuilts/sdclang/bin",
+			"SDCLANG_FLAGS": "-fno-vectorize",
+			"SDCLANG_DENY_LIST": ["libbar"]
+		},
+		"kona": {
+			"SDCLANG": true,
+			"SDCLANG_ALLOW_LIST": ["libfoo", "vendor/acme/"]
+		},
+		"lahaina": {
+			"SDCLANG_PATH": "prebuilts/sdclang-14/bin"
+		}
+	}`), "kona")
+	if err != nil {
+		t.Fatal(err)
+	}
+	android.AssertBoolEquals(t, "SDClang", true, c.SDClang)
+	android.AssertBoolEquals(t, "ForceOff", false, c.ForceOff)
+	android.AssertStringEquals(t, "Path", "prebuilts/sdclang/bin", c.Path)
+	android.AssertStringEquals(t, "Flags", "-fno-vectorize", c.Flags)
+	android.AssertArrayString(t, "AllowList", []string{"libfoo", "vendor/acme/"}, c.AllowList)
+	android.AssertArrayString(t, "DenyList", []string{"libbar"}, c.DenyList)
+}
+
+func TestLoadSDClangConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "sdclang.json")
+	aeConfigPath := filepath.Join(dir, "sdclang_ae.json")
+	if err := os.WriteFile(configPath, []byte(`{
+		"default": {"SDCLANG": true, "SDCLANG_PATH": "prebuilts/sdclang/bin", "SDCLANG_FLAGS": "-O3"}
+	}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(aeConfigPath, []byte(`{"SDCLANG_AE_FLAG": "-fae"}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	emptyPathConfigPath := filepath.Join(dir, "sdclang_empty_path.json")
+	if err := os.WriteFile(emptyPathConfigPath, []byte(`{
+		"default": {"SDCLANG": true, "SDCLANG_PATH": "", "SDCLANG_VERSION": "14.0"}
+	}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		env   map[string]string
+		check func(t *testing.T, c SDClangConfig)
+		err   string
+	}{
+		{
+			name: "no config",
+			env:  map[string]string{},
+			check: func(t *testing.T, c SDClangConfig) {
+				android.AssertBoolEquals(t, "SDClang", false, c.SDClang)
+			},
+		},
+		{
+			name: "ae config and static analysis",
+			env: map[string]string{
+				"SDCLANG_CONFIG":     configPath,
+				"SDCLANG_AE_CONFIG":  aeConfigPath,
+				"SDCLANG_SA_ENABLED": "true",
+			},
+			check: func(t *testing.T, c SDClangConfig) {
+				android.AssertStringEquals(t, "Flags", "-fae -O3 --compile-and-analyze llvmsa", c.Flags)
+			},
+		},
+		{
+			name: "environment overrides",
+			env: map[string]string{
+				"SDCLANG_CONFIG":       configPath,
+				"SDCLANG":              "false",
+				"SDCLANG_PATH":         "out/sdclang/bin",
+				"SDCLANG_COMMON_FLAGS": "-O2",
+			},
+			check: func(t *testing.T, c SDClangConfig) {
+				android.AssertBoolEquals(t, "SDClang", false, c.SDClang)
+				android.AssertStringEquals(t, "Path", "out/sdclang/bin", c.Path)
+				android.AssertStringEquals(t, "Flags", "-O2", c.Flags)
+			},
+		},
+		{
+			name: "path from the environment",
+			env: map[string]string{
+				"SDCLANG_CONFIG": emptyPathConfigPath,
+				"SDCLANG_PATH":   "out/sdclang/bin",
+			},
+			check: func(t *testing.T, c SDClangConfig) {
+				android.AssertBoolEquals(t, "SDClang", true, c.SDClang)
+				android.AssertStringEquals(t, "Path", "out/sdclang/bin", c.Path)
+			},
+		},
+		{
+			name: "empty path",
+			env:  map[string]string{"SDCLANG_CONFIG": emptyPathConfigPath},
+			err:  "SDCLANG_CONFIG " + emptyPathConfigPath + ": SDCLANG_PATH can not be empty",
+		},
+		{
+			name: "missing config",
+			env:  map[string]string{"SDCLANG_CONFIG": filepath.Join(dir, "missing.json")},
+			err:  "SDCLANG_CONFIG: open " + filepath.Join(dir, "missing.json") + ": no such file or directory",
+		},
+		{
+			name: "invalid SDCLANG",
+			env:  map[string]string{"SDCLANG_CONFIG": configPath, "SDCLANG": "yes"},
+			err:  `SDCLANG: invalid value "yes", expected true or false`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := loadSDClangConfig(func(key string) string { return tc.env[key] })
+			if tc.err != "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				android.AssertStringEquals(t, "error", tc.err, err.Error())
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t, c)
+		})
+	}
+}
+
+func TestParseSDClangConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "syntax error",
+			data: `{"default": {"SDCLANG": true,}}`,
+			err:  `invalid character '}' looking for beginning of object key string`,
+		},
+		{
+			name: "missing default",
+			data: `{"kona": {"SDCLANG": true}}`,
+			err:  `missing the default block, found "kona"`,
+		},
+		{
+			name: "wrong type",
+			data: `{"default": {"SDCLANG": "true", "SDCLANG_PATH": "bin"}}`,
+			err:  `block "default": SDCLANG must be a bool, found a string`,
+		},
+		{
+			name: "wrong list type",
+			data: `{"default": {"SDCLANG_PATH": "bin", "SDCLANG_DENY_LIST": "libbar"}}`,
+			err:  `block "default": SDCLANG_DENY_LIST must be a list of strings, found a string`,
+		},
+		{
+			name: "missing path",
+			data: `{"default": {"SDCLANG": false}}`,
+			err:  `block "default": SDCLANG_PATH is required`,
+		},
+		{
+			name: "force off in product block",
+			data: `{"default": {"SDCLANG_PATH": "bin"}, "kona": {"FORCE_SDCLANG_OFF": true}}`,
+			err:  `block "kona": FORCE_SDCLANG_OFF is only supported in the default block`,
+		},
+		{
+			name: "absolute directory",
+			data: `{"default": {"SDCLANG_PATH": "bin"}, "kona": {"SDCLANG_ALLOW_LIST": ["/vendor/"]}}`,
+			err: `block "kona": invalid entry "/vendor/" in SDCLANG_ALLOW_LIST or SDCLANG_DENY_LIST, ` +
+				`expected a module name or a directory ending with /`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseSDClangConfig([]byte(tc.data), "kona")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			android.AssertStringEquals(t, "error", tc.err, err.Error())
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains the configuration of the incremental ThinLTO cache of lld, which reuses the
+// native objects of the ThinLTO backends whose inputs didn't change since a previous link, e.g.
+// when a module is relinked after a change in one of its static libraries. The cache is shared by
+// all the modules of the out directory, and is enabled per product with the ThinLTOCache product
+// variable, or with USE_THINLTO_CACHE=true|false in the environment.
+
+var (
+	// The pruning policy of the ThinLTO cache, which limits its size to the lesser of 10% of the
+	// available disk space and 10GB, and drops the entries unused for a week.
+	thinLTOCachePolicy = []string{
+		"cache_size=10%",
+		"cache_size_bytes=10g",
+		"prune_after=168h",
+	}
+)
+
+// thinLTOCacheDirName is the directory of the ThinLTO cache in the Soong out directory.
+const thinLTOCacheDirName = "thinlto-cache"
+
+func init() {
+	exportedVars.ExportStringListStaticVariable("ThinLTOCachePolicyLdflags", []string{
+		"-Wl,--thinlto-cache-policy=" + strings.Join(thinLTOCachePolicy, ":"),
+	})
+	exportedVars.ExportVariableConfigMethod("ThinLTOCacheDir", func(config android.Config) string {
+		return filepath.Join(config.SoongOutDir(), thinLTOCacheDirName)
+	})
+	exportedVars.ExportStringStaticVariable("ThinLTOCacheLdflags",
+		"-Wl,--thinlto-cache-dir=${ThinLTOCacheDir} ${ThinLTOCachePolicyLdflags}")
+}
+
+// ThinLTOCacheDir returns the directory of the ThinLTO cache in the out directory.
+func ThinLTOCacheDir(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, thinLTOCacheDirName)
+}
+
+// ThinLTOCacheEnabled returns true if the ThinLTO links use the ThinLTO cache.
+func ThinLTOCacheEnabled(config android.Config) bool {
+	if config.IsEnvTrue("USE_THINLTO_CACHE") {
+		return true
+	}
+	if config.IsEnvFalse("USE_THINLTO_CACHE") {
+		return false
+	}
+	return config.ThinLTOCache()
+}
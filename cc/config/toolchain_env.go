@@ -0,0 +1,65 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"android/soong/android"
+)
+
+// ToolchainEnvVars lists the environment variables that change the compiler, its flags or the
+// way it is invoked. Their values are recorded in the toolchain environment manifest.
+var ToolchainEnvVars = []string{
+	"LLVM_PREBUILTS_BASE",
+	"LLVM_PREBUILTS_VERSION",
+	"LLVM_RELEASE_VERSION",
+	"LLVM_NEXT",
+
+	"SDCLANG",
+	"SDCLANG_AE_CONFIG",
+	"SDCLANG_COMMON_FLAGS",
+	"SDCLANG_CONFIG",
+	"SDCLANG_PATH",
+	"SDCLANG_SA_ENABLED",
+
+	"CC_WRAPPER",
+	"USE_CCACHE",
+
+	"AUTO_ZERO_INITIALIZE",
+	"AUTO_PATTERN_INITIALIZE",
+	"AUTO_UNINITIALIZE",
+	"ALLOW_UNKNOWN_WARNING_OPTION",
+
+	"USE_RBE",
+	"RBE_WRAPPER",
+	"RBE_CXX_POOL",
+	"RBE_CXX_LINKS",
+	"RBE_CXX_LINKS_POOL",
+	"RBE_CXX_LINKS_EXEC_STRATEGY",
+	"RBE_CLANG_TIDY",
+	"RBE_CLANG_TIDY_POOL",
+	"RBE_CLANG_TIDY_EXEC_STRATEGY",
+	"RBE_ABI_DUMPER",
+	"RBE_ABI_DUMPER_EXEC_STRATEGY",
+	"RBE_ABI_LINKER",
+	"RBE_ABI_LINKER_EXEC_STRATEGY",
+}
+
+const toolchainEnvManifestFileName = "toolchain_env.json"
+
+// ToolchainEnvManifestPath returns the path to the manifest of the values of ToolchainEnvVars,
+// i.e. out/soong/toolchain_env.json.
+func ToolchainEnvManifestPath(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, toolchainEnvManifestFileName)
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+)
+
+// WarningOverridesFile is the checked-in file with the warning overrides of the projects, relative
+// to the top of the source tree. Products can add their own files with the WarningOverridesFiles
+// product variable. A file looks like:
+//
+//	{
+//	    "overrides": [
+//	        {
+//	            "path_prefix": "vendor/acme/",
+//	            "allow_warnings": true,
+//	            "cflags": ["-Wshadow"],
+//	            "warnings": {"unused-parameter": "error"},
+//	            "bug": "b/123456"
+//	        }
+//	    ]
+//	}
+const WarningOverridesFile = "build/soong/cc/config/warning_overrides.json"
+
+// WarningOverride is the warning settings of the modules in the directories under a path prefix.
+type WarningOverride struct {
+	// The path prefix of the directories of the modules, e.g. "vendor/acme/".
+	PathPrefix string `json:"path_prefix"`
+
+	// Whether the modules can build with warnings, rather than with -Werror added to their cflags
+	// when they don't set -Werror or -Wno-error.
+	AllowWarnings *bool `json:"allow_warnings,omitempty"`
+
+	// Extra cflags of the modules.
+	Cflags []string `json:"cflags,omitempty"`
+
+	// The action on warnings in the modules, by the name of the warning. They are exceptions to the
+	// warning policy, like the ones registered with RegisterWarningPolicyException, and come after
+	// its flags.
+	Warnings map[string]WarningAction `json:"warnings,omitempty"`
+
+	// The bug that tracks the removal of the exceptions of the warnings, required with warnings.
+	Bug string `json:"bug,omitempty"`
+}
+
+// Exceptions returns the exceptions to the warning policy of the warnings of the override, sorted
+// by warning.
+func (o WarningOverride) Exceptions() []WarningPolicyException {
+	var exceptions []WarningPolicyException
+	for _, warning := range android.SortedKeys(o.Warnings) {
+		exceptions = append(exceptions, WarningPolicyException{
+			Dir:     strings.TrimSuffix(o.PathPrefix, "/"),
+			Warning: warning,
+			Action:  o.Warnings[warning],
+			Bug:     o.Bug,
+		})
+	}
+	return exceptions
+}
+
+// ParseWarningOverrides returns the warning overrides of the contents of a warning overrides file.
+func ParseWarningOverrides(data []byte) ([]WarningOverride, error) {
+	var file struct {
+		Overrides []WarningOverride `json:"overrides"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	for i, o := range file.Overrides {
+		if o.PathPrefix == "" || strings.HasPrefix(o.PathPrefix, "/") {
+			return nil, fmt.Errorf("invalid path_prefix %q, expected a path relative to the top of the source tree",
+				o.PathPrefix)
+		}
+		for _, flag := range o.Cflags {
+			if !strings.HasPrefix(flag, "-") {
+				return nil, fmt.Errorf("invalid cflag %q for %q, expected a flag starting with -", flag, o.PathPrefix)
+			}
+		}
+		for _, e := range o.Exceptions() {
+			if err := e.validate(); err != nil {
+				return nil, err
+			}
+		}
+		// A prefix ending with a directory name also matches the directory itself.
+		if !strings.HasSuffix(o.PathPrefix, "/") {
+			file.Overrides[i].PathPrefix += "/"
+		}
+	}
+	return file.Overrides, nil
+}
+
+// WarningOverridesForDir returns the overrides that apply to the modules in the directory, the
+// ones with the shortest path prefixes first, so that the flags of the most specific ones come last.
+func WarningOverridesForDir(overrides []WarningOverride, dir string) []WarningOverride {
+	var ret []WarningOverride
+	for _, o := range overrides {
+		if strings.HasPrefix(dir+"/", o.PathPrefix) {
+			ret = append(ret, o)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool { return len(ret[i].PathPrefix) < len(ret[j].PathPrefix) })
+	return ret
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestCheckedInWarningOverrides(t *testing.T) {
+	data, err := os.ReadFile("warning_overrides.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseWarningOverrides(data); err != nil {
+		t.Errorf("warning_overrides.json: %s", err)
+	}
+}
+
+func TestWarningOverrides(t *testing.T) {
+	overrides, err := ParseWarningOverrides([]byte(`{
+		"overrides": [
+			{
+				"path_prefix": "vendor/acme/foo",
+				"allow_warnings": false,
+				"warnings": {"unused-parameter": "error", "format": "warn"},
+				"bug": "b/1"
+			},
+			{
+				"path_prefix": "vendor/",
+				"allow_warnings": true,
+				"cflags": ["-Wshadow"]
+			}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prefixes []string
+	for _, o := range WarningOverridesForDir(overrides, "vendor/acme/foo/bar") {
+		prefixes = append(prefixes, o.PathPrefix)
+	}
+	android.AssertArrayString(t, "overrides of vendor/acme/foo/bar", []string{"vendor/", "vendor/acme/foo/"}, prefixes)
+	android.AssertDeepEquals(t, "exceptions of vendor/acme/foo", []WarningPolicyException{
+		{Dir: "vendor/acme/foo", Warning: "format", Action: WarningWarn, Bug: "b/1"},
+		{Dir: "vendor/acme/foo", Warning: "unused-parameter", Action: WarningError, Bug: "b/1"},
+	}, overrides[0].Exceptions())
+	android.AssertArrayString(t, "cflags of vendor/", []string{"-Wshadow"}, overrides[1].Cflags)
+
+	android.AssertIntEquals(t, "overrides of vendor/acme/foo", 2,
+		len(WarningOverridesForDir(overrides, "vendor/acme/foo")))
+	android.AssertIntEquals(t, "overrides of vendor/acme/foobar", 1,
+		len(WarningOverridesForDir(overrides, "vendor/acme/foobar")))
+	android.AssertIntEquals(t, "overrides of frameworks/av", 0,
+		len(WarningOverridesForDir(overrides, "frameworks/av")))
+}
+
+func TestWarningOverridesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		err  string
+	}{
+		{
+			name: "unknown field",
+			data: `{"overrides": [{"path_prefix": "vendor/", "allow_warning": true}]}`,
+			err:  `json: unknown field "allow_warning"`,
+		},
+		{
+			name: "absolute path",
+			data: `{"overrides": [{"path_prefix": "/vendor/"}]}`,
+			err:  `invalid path_prefix "/vendor/", expected a path relative to the top of the source tree`,
+		},
+		{
+			name: "invalid cflag",
+			data: `{"overrides": [{"path_prefix": "vendor/", "cflags": ["Wshadow"]}]}`,
+			err:  `invalid cflag "Wshadow" for "vendor/", expected a flag starting with -`,
+		},
+		{
+			name: "invalid action",
+			data: `{"overrides": [{"path_prefix": "vendor/", "warnings": {"format": "ignore"}, "bug": "b/1"}]}`,
+			err:  `warning policy exception for "format" in "vendor": invalid action "ignore", expected one of error, warn, off`,
+		},
+		{
+			name: "missing bug",
+			data: `{"overrides": [{"path_prefix": "vendor/", "warnings": {"format": "off"}}]}`,
+			err:  `warning policy exception for "format" in "vendor": missing bug`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseWarningOverrides([]byte(tc.data))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			android.AssertStringEquals(t, "error", tc.err, err.Error())
+		})
+	}
+}
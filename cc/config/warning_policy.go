@@ -0,0 +1,315 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains the warning policy of the tree: the action on each warning that is turned
+// into an error or disabled globally, for each class of projects, along with the bug that tracks
+// it. There is at most one action on a warning for a class of projects. The flags of the
+// NoOverrideGlobalCflags, NoOverrideVendorGlobalCflags, NoOverrideExternalGlobalCflags and
+// ExternalCflags variables are generated from it, in the order of the policies.
+//
+// The projects can have their own exceptions, in the warnings of a WarningOverride, or for the
+// projects under device/ and vendor/, registered from their Soong plugins with
+// RegisterWarningPolicyException, rather than adding them to the policy of all the projects of
+// their class.
+
+// WarningAction is the action on a warning.
+type WarningAction string
+
+const (
+	// The warning is an error, -Werror=<warning>.
+	WarningError WarningAction = "error"
+	// The warning is not turned into an error by -Werror, -Wno-error=<warning>.
+	WarningWarn WarningAction = "warn"
+	// The warning is disabled, -Wno-<warning>.
+	WarningOff WarningAction = "off"
+)
+
+func (action WarningAction) cflag(warning string) string {
+	switch action {
+	case WarningError:
+		return "-Werror=" + warning
+	case WarningWarn:
+		return "-Wno-error=" + warning
+	case WarningOff:
+		return "-Wno-" + warning
+	}
+	panic(fmt.Errorf("unknown action %q on warning %q", action, warning))
+}
+
+// warningPolicy is the action on a warning.
+type warningPolicy struct {
+	// The name of the warning, e.g. "return-type" for -Wreturn-type.
+	warning string
+
+	action WarningAction
+
+	// The bug that tracks the removal of the exception, if any.
+	bug string
+}
+
+// platformWarningPolicies is the policy of all the projects, which can't be overridden by the
+// cflags of the modules.
+var platformWarningPolicies = []warningPolicy{
+	{warning: "bool-operation", action: WarningError},
+	{warning: "format-insufficient-args", action: WarningError},
+	{warning: "int-in-bool-context", action: WarningError},
+	{warning: "int-to-pointer-cast", action: WarningError},
+	{warning: "xor-used-as-pow", action: WarningError},
+	{warning: "void-pointer-to-enum-cast", action: WarningOff, bug: "b/161386391"},
+	{warning: "void-pointer-to-int-cast", action: WarningOff, bug: "b/161386391"},
+	{warning: "pointer-to-int-cast", action: WarningOff, bug: "b/161386391"},
+	// SDClang does not support -Werror=fortify-source (b/142476859), so it is not in the policy.
+
+	{warning: "address-of-temporary", action: WarningError},
+	{warning: "null-dereference", action: WarningError},
+	{warning: "return-type", action: WarningError},
+
+	{warning: "tautological-constant-compare", action: WarningOff, bug: "b/72331526"},
+	{warning: "tautological-type-limit-compare", action: WarningOff, bug: "b/72331526"},
+	{warning: "reorder-init-list", action: WarningOff, bug: "b/145210666"},
+	{warning: "implicit-int-float-conversion", action: WarningOff, bug: "b/145211066"},
+	// New warnings to be fixed after clang-r377782.
+	{warning: "tautological-overlap-compare", action: WarningOff, bug: "b/148815696"},
+	// New warnings to be fixed after clang-r383902.
+	{warning: "deprecated-copy", action: WarningOff, bug: "b/153746672"},
+	{warning: "range-loop-construct", action: WarningOff, bug: "b/153747076"},
+	{warning: "zero-as-null-pointer-constant", action: WarningOff, bug: "b/68236239"},
+	{warning: "deprecated-anon-enum-enum-conversion", action: WarningOff, bug: "b/153746485"},
+	{warning: "pessimizing-move", action: WarningOff, bug: "b/154270751"},
+	// New warnings to be fixed after clang-r399163.
+	{warning: "non-c-typedef-for-linkage", action: WarningOff, bug: "b/161304145"},
+	// New warnings to be fixed after clang-r428724.
+	{warning: "align-mismatch", action: WarningOff, bug: "b/193679946"},
+	// New warnings to be fixed after clang-r433403.
+	{warning: "unused-but-set-variable", action: WarningWarn, bug: "b/197240255"},
+	{warning: "unused-but-set-parameter", action: WarningWarn, bug: "b/197240255"},
+	// New warnings to be fixed after clang-r468909.
+	{warning: "deprecated-builtins", action: WarningWarn, bug: "b/241601211"},
+	// In external/googletest/googletest.
+	{warning: "deprecated", action: WarningWarn},
+	// New warnings to be fixed after clang-r475365.
+	{warning: "single-bit-bitfield-constant-conversion", action: WarningWarn, bug: "b/243965903"},
+	{warning: "enum-constexpr-conversion", action: WarningWarn, bug: "b/243964282"},
+}
+
+// vendorWarningPolicies is the policy of the projects under device/ and vendor/ (see
+// IsVendorProjectPath), with the warnings that the vendor compilation of Android T disabled, which
+// comes after the platform policy and can't be overridden by the cflags of the modules. The
+// warnings that the platform policy already disables are not repeated.
+var vendorWarningPolicies = []warningPolicy{
+	{warning: "implicit-fallthrough", action: WarningOff},
+	{warning: "c99-designator", action: WarningOff},
+	{warning: "int-in-bool-context", action: WarningOff},
+	{warning: "alloca", action: WarningOff},
+	{warning: "dangling-gsl", action: WarningOff},
+	{warning: "pointer-compare", action: WarningOff},
+	{warning: "final-dtor-non-final-class", action: WarningOff},
+	{warning: "incomplete-setjmp-declaration", action: WarningOff},
+	{warning: "sizeof-array-div", action: WarningOff},
+	{warning: "xor-used-as-pow", action: WarningOff},
+	{warning: "c++17-extensions", action: WarningOff},
+	{warning: "range-loop-analysis", action: WarningOff},
+	{warning: "invalid-partial-specialization", action: WarningOff},
+	{warning: "misleading-indentation", action: WarningOff},
+	{warning: "deprecated-enum-enum-conversion", action: WarningOff},
+	{warning: "bool-operation", action: WarningOff},
+	{warning: "unused-comparison", action: WarningOff},
+	{warning: "string-compare", action: WarningOff},
+	{warning: "wrong-info", action: WarningOff},
+	{warning: "unsequenced", action: WarningOff},
+	{warning: "unknown-warning-option", action: WarningOff},
+	{warning: "unused-variable", action: WarningOff},
+	{warning: "unused-value", action: WarningOff},
+	{warning: "unused-parameter", action: WarningOff},
+	{warning: "typedef-redefinition", action: WarningOff},
+	{warning: "format", action: WarningOff},
+	{warning: "string-concatenation", action: WarningOff},
+	{warning: "incompatible-pointer-types", action: WarningOff},
+	{warning: "format-invalid-specifier-fcommon", action: WarningOff},
+	{warning: "self-assign", action: WarningOff},
+	{warning: "unused-label", action: WarningOff},
+	{warning: "pointer-sign", action: WarningOff},
+	{warning: "writable-strings", action: WarningOff},
+	{warning: "missing-declarations", action: WarningOff},
+	{warning: "reorder-ctor", action: WarningOff},
+	{warning: "unused-function", action: WarningOff},
+}
+
+// externalWarningPolicies is the policy of the third-party projects (see android.IsThirdPartyPath),
+// which comes after the platform policy and can't be overridden by the cflags of the modules.
+var externalWarningPolicies = []warningPolicy{
+	{warning: "format-insufficient-args", action: WarningOff, bug: "b/191699019"},
+	{warning: "sizeof-array-div", action: WarningOff},
+	{warning: "incompatible-function-pointer-types", action: WarningOff},
+	{warning: "unused-but-set-variable", action: WarningOff},
+	{warning: "unused-but-set-parameter", action: WarningOff},
+	{warning: "unqualified-std-cast-call", action: WarningOff},
+	{warning: "bitwise-instead-of-logical", action: WarningOff},
+	{warning: "misleading-indentation", action: WarningOff},
+	{warning: "array-parameter", action: WarningOff},
+	{warning: "gnu-offsetof-extensions", action: WarningOff},
+}
+
+// externalOverridableWarningPolicies is the policy of the third-party projects for the warnings
+// that are infeasible to fix in all the external projects and their upstream repos, which the
+// cflags of the modules can override.
+var externalOverridableWarningPolicies = []warningPolicy{
+	{warning: "enum-compare", action: WarningOff},
+	{warning: "enum-compare-switch", action: WarningOff},
+	{warning: "null-pointer-arithmetic", action: WarningOff, bug: "b/72331524"},
+	{warning: "null-dereference", action: WarningOff, bug: "b/29823425"},
+	{warning: "pointer-compare", action: WarningOff, bug: "b/145211477"},
+	{warning: "final-dtor-non-final-class", action: WarningOff, bug: "b/145211477"},
+	{warning: "psabi", action: WarningOff, bug: "b/165945989"},
+	{warning: "null-pointer-subtraction", action: WarningOff, bug: "b/199369603"},
+	{warning: "string-concatenation", action: WarningOff, bug: "b/175068488"},
+	{warning: "deprecated-non-prototype", action: WarningOff, bug: "b/239661264"},
+}
+
+// warningPolicyCflags returns the flags of the policies of a class of projects, in their order. It
+// panics if there is more than one action on a warning, as only the last one would take effect.
+func warningPolicyCflags(policies []warningPolicy) []string {
+	actions := make(map[string]WarningAction)
+	var flags []string
+	for _, policy := range policies {
+		if action, ok := actions[policy.warning]; ok {
+			panic(fmt.Errorf("warning policy for %q is listed more than once, with the actions %q and %q",
+				policy.warning, action, policy.action))
+		}
+		actions[policy.warning] = policy.action
+		flags = append(flags, policy.action.cflag(policy.warning))
+	}
+	return flags
+}
+
+// IsVendorProjectPath returns true if the path is in a project under device/ or vendor/, which
+// vendorWarningPolicies apply to.
+func IsVendorProjectPath(path string) bool {
+	return android.HasAnyPrefix(path, []string{"device/", "vendor/"})
+}
+
+// WarningPolicyException is an exception to the warning policy for the modules in a directory,
+// registered with RegisterWarningPolicyException by a project under device/ or vendor/, or read from
+// the warnings of a WarningOverride.
+type WarningPolicyException struct {
+	// The directory of the modules, e.g. "vendor/qcom/opensource/audio-hal". The exception also
+	// applies to the modules in its subdirectories.
+	Dir string
+
+	// The name of the warning, e.g. "unused-parameter" for -Wunused-parameter.
+	Warning string
+
+	Action WarningAction
+
+	// The bug that tracks the removal of the exception, required so that all the exceptions can be
+	// audited.
+	Bug string
+}
+
+// validate returns an error if the warning, the action or the bug of the exception is not valid.
+func (e WarningPolicyException) validate() error {
+	if e.Warning == "" || strings.HasPrefix(e.Warning, "-") || strings.ContainsAny(e.Warning, " =") {
+		return fmt.Errorf("warning policy exception in %q: invalid warning %q, expected the name of a warning without -W",
+			e.Dir, e.Warning)
+	}
+	switch e.Action {
+	case WarningError, WarningWarn, WarningOff:
+	default:
+		return fmt.Errorf("warning policy exception for %q in %q: invalid action %q, expected one of %s, %s, %s",
+			e.Warning, e.Dir, e.Action, WarningError, WarningWarn, WarningOff)
+	}
+	if e.Bug == "" {
+		return fmt.Errorf("warning policy exception for %q in %q: missing bug", e.Warning, e.Dir)
+	}
+	return nil
+}
+
+var warningPolicyExceptions []WarningPolicyException
+
+// RegisterWarningPolicyException registers an exception to the warning policy. It is meant to be
+// called from the init function of the Soong plugin of a project under device/ or vendor/, and
+// panics if the exception is not valid.
+func RegisterWarningPolicyException(exception WarningPolicyException) {
+	dir := strings.TrimSuffix(exception.Dir, "/")
+	if !android.HasAnyPrefix(dir+"/", []string{"device/", "vendor/"}) || dir == "device" || dir == "vendor" {
+		panic(fmt.Errorf("warning policy exception for %q: %q is not a project directory under device/ or vendor/",
+			exception.Warning, exception.Dir))
+	}
+	exception.Dir = dir
+	if err := exception.validate(); err != nil {
+		panic(err)
+	}
+	for _, e := range warningPolicyExceptions {
+		if e.Dir == dir && e.Warning == exception.Warning {
+			panic(fmt.Errorf("warning policy exception for %q in %q is registered more than once",
+				exception.Warning, dir))
+		}
+	}
+	warningPolicyExceptions = append(warningPolicyExceptions, exception)
+}
+
+// allWarningPolicyExceptions returns the registered exceptions to the warning policy, followed by
+// the ones of the warning overrides.
+func allWarningPolicyExceptions(overrides []WarningOverride) []WarningPolicyException {
+	exceptions := append([]WarningPolicyException(nil), warningPolicyExceptions...)
+	for _, o := range overrides {
+		exceptions = append(exceptions, o.Exceptions()...)
+	}
+	return exceptions
+}
+
+// WarningPolicyExceptionCflags returns the flags of the exceptions to the warning policy that apply
+// to the modules in the directory, the registered ones and the ones of the warning overrides, which
+// come after the flags of the policy. The exceptions of the most specific directories come last,
+// so that they win, and the ones of the warning overrides come after the registered ones for the
+// same directory and warning.
+func WarningPolicyExceptionCflags(dir string, overrides []WarningOverride) []string {
+	var exceptions []WarningPolicyException
+	for _, e := range allWarningPolicyExceptions(overrides) {
+		if dir == e.Dir || strings.HasPrefix(dir, e.Dir+"/") {
+			exceptions = append(exceptions, e)
+		}
+	}
+	sort.SliceStable(exceptions, func(i, j int) bool {
+		if len(exceptions[i].Dir) != len(exceptions[j].Dir) {
+			return len(exceptions[i].Dir) < len(exceptions[j].Dir)
+		}
+		return exceptions[i].Warning < exceptions[j].Warning
+	})
+	var flags []string
+	for _, e := range exceptions {
+		flags = append(flags, e.Action.cflag(e.Warning))
+	}
+	return flags
+}
+
+// WarningPolicyExceptions returns the exceptions to the warning policy, the registered ones and the
+// ones of the warning overrides, one per line, for the audit of the exceptions.
+func WarningPolicyExceptions(overrides []WarningOverride) []string {
+	var lines []string
+	for _, e := range allWarningPolicyExceptions(overrides) {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s", e.Dir, e.Warning, e.Action, e.Bug))
+	}
+	return android.SortedUniqueStrings(lines)
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestWarningPolicies(t *testing.T) {
+	for _, policies := range [][]warningPolicy{
+		platformWarningPolicies,
+		vendorWarningPolicies,
+		externalWarningPolicies,
+		externalOverridableWarningPolicies,
+	} {
+		for _, policy := range policies {
+			if policy.warning == "" || strings.HasPrefix(policy.warning, "-") || strings.ContainsAny(policy.warning, " =") {
+				t.Errorf("policy %+v must have the name of a warning without -W", policy)
+			}
+		}
+	}
+
+	// The vendor policy doesn't repeat the actions of the platform policy.
+	platformActions := make(map[string]WarningAction)
+	for _, policy := range platformWarningPolicies {
+		platformActions[policy.warning] = policy.action
+	}
+	for _, policy := range vendorWarningPolicies {
+		if platformActions[policy.warning] == policy.action {
+			t.Errorf("vendor policy %+v is the same as the platform policy", policy)
+		}
+	}
+}
+
+func TestWarningPolicyCflags(t *testing.T) {
+	policies := []warningPolicy{
+		{warning: "return-type", action: WarningError},
+		{warning: "deprecated", action: WarningWarn},
+		{warning: "format", action: WarningOff},
+	}
+
+	android.AssertArrayString(t, "flags",
+		[]string{"-Werror=return-type", "-Wno-error=deprecated", "-Wno-format"},
+		warningPolicyCflags(policies))
+}
+
+func TestWarningPolicyCflagsDuplicate(t *testing.T) {
+	policies := []warningPolicy{
+		{warning: "pointer-to-int-cast", action: WarningError},
+		{warning: "format", action: WarningOff},
+		{warning: "pointer-to-int-cast", action: WarningOff, bug: "b/1"},
+	}
+
+	android.AssertPanicMessageContains(t, "duplicate warning",
+		`warning policy for "pointer-to-int-cast" is listed more than once, with the actions "error" and "off"`,
+		func() { warningPolicyCflags(policies) })
+}
+
+func TestWarningPolicyGlobalCflags(t *testing.T) {
+	android.AssertArrayString(t, "noOverrideGlobalCflags", []string{
+		"-Werror=bool-operation",
+		"-Werror=format-insufficient-args",
+		"-Werror=int-in-bool-context",
+		"-Werror=int-to-pointer-cast",
+		"-Werror=xor-used-as-pow",
+		"-Wno-void-pointer-to-enum-cast",
+		"-Wno-void-pointer-to-int-cast",
+		"-Wno-pointer-to-int-cast",
+		"-Werror=address-of-temporary",
+		"-Werror=null-dereference",
+		"-Werror=return-type",
+		"-Wno-tautological-constant-compare",
+		"-Wno-tautological-type-limit-compare",
+		"-Wno-reorder-init-list",
+		"-Wno-implicit-int-float-conversion",
+		"-Wno-tautological-overlap-compare",
+		"-Wno-deprecated-copy",
+		"-Wno-range-loop-construct",
+		"-Wno-zero-as-null-pointer-constant",
+		"-Wno-deprecated-anon-enum-enum-conversion",
+		"-Wno-pessimizing-move",
+		"-Wno-non-c-typedef-for-linkage",
+		"-Wno-align-mismatch",
+		"-Wno-error=unused-but-set-variable",
+		"-Wno-error=unused-but-set-parameter",
+		"-Wno-error=deprecated-builtins",
+		"-Wno-error=deprecated",
+		"-Wno-error=single-bit-bitfield-constant-conversion",
+		"-Wno-error=enum-constexpr-conversion",
+	}, noOverrideGlobalCflags)
+
+	android.AssertArrayString(t, "noOverrideVendorGlobalCflags", []string{
+		"-Wno-implicit-fallthrough",
+		"-Wno-c99-designator",
+		"-Wno-int-in-bool-context",
+		"-Wno-alloca",
+		"-Wno-dangling-gsl",
+		"-Wno-pointer-compare",
+		"-Wno-final-dtor-non-final-class",
+		"-Wno-incomplete-setjmp-declaration",
+		"-Wno-sizeof-array-div",
+		"-Wno-xor-used-as-pow",
+		"-Wno-c++17-extensions",
+		"-Wno-range-loop-analysis",
+		"-Wno-invalid-partial-specialization",
+		"-Wno-misleading-indentation",
+		"-Wno-deprecated-enum-enum-conversion",
+		"-Wno-bool-operation",
+		"-Wno-unused-comparison",
+		"-Wno-string-compare",
+		"-Wno-wrong-info",
+		"-Wno-unsequenced",
+		"-Wno-unknown-warning-option",
+		"-Wno-unused-variable",
+		"-Wno-unused-value",
+		"-Wno-unused-parameter",
+		"-Wno-typedef-redefinition",
+		"-Wno-format",
+		"-Wno-string-concatenation",
+		"-Wno-incompatible-pointer-types",
+		"-Wno-format-invalid-specifier-fcommon",
+		"-Wno-self-assign",
+		"-Wno-unused-label",
+		"-Wno-pointer-sign",
+		"-Wno-writable-strings",
+		"-Wno-missing-declarations",
+		"-Wno-reorder-ctor",
+		"-Wno-unused-function",
+		"-flax-vector-conversions=all",
+	}, noOverrideVendorGlobalCflags)
+
+	android.AssertArrayString(t, "noOverrideExternalGlobalCflags", []string{
+		"-Wno-format-insufficient-args",
+		"-Wno-sizeof-array-div",
+		"-Wno-incompatible-function-pointer-types",
+		"-Wno-unused-but-set-variable",
+		"-Wno-unused-but-set-parameter",
+		"-Wno-unqualified-std-cast-call",
+		"-Wno-bitwise-instead-of-logical",
+		"-Wno-misleading-indentation",
+		"-Wno-array-parameter",
+		"-Wno-gnu-offsetof-extensions",
+	}, noOverrideExternalGlobalCflags)
+
+	android.AssertArrayString(t, "extraExternalCflags", []string{
+		"-Wno-enum-compare",
+		"-Wno-enum-compare-switch",
+		"-Wno-null-pointer-arithmetic",
+		"-Wno-null-dereference",
+		"-Wno-pointer-compare",
+		"-Wno-final-dtor-non-final-class",
+		"-Wno-psabi",
+		"-Wno-null-pointer-subtraction",
+		"-Wno-string-concatenation",
+		"-Wno-deprecated-non-prototype",
+	}, extraExternalCflags)
+
+	android.AssertBoolEquals(t, "vendor/qcom/opensource/audio-hal", true,
+		IsVendorProjectPath("vendor/qcom/opensource/audio-hal"))
+	android.AssertBoolEquals(t, "external/zlib", false, IsVendorProjectPath("external/zlib"))
+}
+
+func TestWarningPolicyExceptions(t *testing.T) {
+	saved := warningPolicyExceptions
+	defer func() { warningPolicyExceptions = saved }()
+	warningPolicyExceptions = nil
+
+	RegisterWarningPolicyException(WarningPolicyException{
+		Dir: "vendor/foo/bar", Warning: "unused-parameter", Action: WarningError, Bug: "b/1"})
+	RegisterWarningPolicyException(WarningPolicyException{
+		Dir: "vendor/foo/", Warning: "unused-parameter", Action: WarningWarn, Bug: "b/2"})
+	RegisterWarningPolicyException(WarningPolicyException{
+		Dir: "vendor/foo", Warning: "format", Action: WarningOff, Bug: "b/3"})
+
+	android.AssertArrayString(t, "vendor/foo/bar/baz",
+		[]string{"-Wno-format", "-Wno-error=unused-parameter", "-Werror=unused-parameter"},
+		WarningPolicyExceptionCflags("vendor/foo/bar/baz", nil))
+	android.AssertArrayString(t, "vendor/foo",
+		[]string{"-Wno-format", "-Wno-error=unused-parameter"},
+		WarningPolicyExceptionCflags("vendor/foo", nil))
+	android.AssertArrayString(t, "vendor/foobar", nil, WarningPolicyExceptionCflags("vendor/foobar", nil))
+	android.AssertArrayString(t, "exceptions", []string{
+		"vendor/foo format off b/3",
+		"vendor/foo unused-parameter warn b/2",
+		"vendor/foo/bar unused-parameter error b/1",
+	}, WarningPolicyExceptions(nil))
+
+	// The warnings of the warning overrides are exceptions too, after the registered ones.
+	overrides := []WarningOverride{
+		{PathPrefix: "vendor/foo/", Warnings: map[string]WarningAction{"format": WarningError}, Bug: "b/4"},
+		{PathPrefix: "external/foo/", Warnings: map[string]WarningAction{"format": WarningWarn}, Bug: "b/5"},
+	}
+	android.AssertArrayString(t, "vendor/foo with overrides",
+		[]string{"-Wno-format", "-Werror=format", "-Wno-error=unused-parameter"},
+		WarningPolicyExceptionCflags("vendor/foo", overrides))
+	android.AssertArrayString(t, "external/foo/bar with overrides",
+		[]string{"-Wno-error=format"},
+		WarningPolicyExceptionCflags("external/foo/bar", overrides))
+	android.AssertArrayString(t, "exceptions with overrides", []string{
+		"external/foo format warn b/5",
+		"vendor/foo format error b/4",
+		"vendor/foo format off b/3",
+		"vendor/foo unused-parameter warn b/2",
+		"vendor/foo/bar unused-parameter error b/1",
+	}, WarningPolicyExceptions(overrides))
+}
+
+func TestWarningPolicyExceptionErrors(t *testing.T) {
+	saved := warningPolicyExceptions
+	defer func() { warningPolicyExceptions = saved }()
+
+	for _, tc := range []struct {
+		name      string
+		exception WarningPolicyException
+		err       string
+	}{
+		{
+			name:      "not a vendor project",
+			exception: WarningPolicyException{Dir: "frameworks/av", Warning: "format", Action: WarningOff, Bug: "b/1"},
+			err:       `warning policy exception for "format": "frameworks/av" is not a project directory under device/ or vendor/`,
+		},
+		{
+			name:      "whole vendor directory",
+			exception: WarningPolicyException{Dir: "vendor/", Warning: "format", Action: WarningOff, Bug: "b/1"},
+			err:       `warning policy exception for "format": "vendor/" is not a project directory under device/ or vendor/`,
+		},
+		{
+			name:      "flag",
+			exception: WarningPolicyException{Dir: "vendor/foo", Warning: "-Wformat", Action: WarningOff, Bug: "b/1"},
+			err:       `warning policy exception in "vendor/foo": invalid warning "-Wformat", expected the name of a warning without -W`,
+		},
+		{
+			name:      "invalid action",
+			exception: WarningPolicyException{Dir: "vendor/foo", Warning: "format", Action: "ignore", Bug: "b/1"},
+			err:       `warning policy exception for "format" in "vendor/foo": invalid action "ignore", expected one of error, warn, off`,
+		},
+		{
+			name:      "missing bug",
+			exception: WarningPolicyException{Dir: "vendor/foo", Warning: "format", Action: WarningOff},
+			err:       `warning policy exception for "format" in "vendor/foo": missing bug`,
+		},
+		{
+			name:      "duplicate",
+			exception: WarningPolicyException{Dir: "vendor/bar", Warning: "format", Action: WarningOff, Bug: "b/1"},
+			err:       `warning policy exception for "format" in "vendor/bar" is registered more than once`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			warningPolicyExceptions = []WarningPolicyException{
+				{Dir: "vendor/bar", Warning: "format", Action: WarningWarn, Bug: "b/2"},
+			}
+			android.AssertPanicMessageContains(t, "RegisterWarningPolicyException", tc.err, func() {
+				RegisterWarningPolicyException(tc.exception)
+			})
+		})
+	}
+}
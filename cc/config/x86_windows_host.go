@@ -83,8 +83,8 @@ var (
 		"-static-libgcc",
 
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/bin",
-		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3/32",
-		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3/32",
+		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/${WindowsGccVersion}/32",
+		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/${WindowsGccVersion}/32",
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/lib32",
 	}
 
@@ -95,8 +95,8 @@ var (
 		"-static-libgcc",
 
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/bin",
-		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3",
-		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/4.8.3",
+		"-B${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/${WindowsGccVersion}",
+		"-L${WindowsGccRoot}/lib/gcc/${WindowsGccTriple}/${WindowsGccVersion}",
 		"-B${WindowsGccRoot}/${WindowsGccTriple}/lib64",
 	}
 
@@ -120,16 +120,24 @@ var (
 )
 
 const (
-	windowsGccVersion = "4.8"
+	windowsGccVersion = "4.8.3"
 )
 
 func init() {
-	pctx.StaticVariable("WindowsGccVersion", windowsGccVersion)
+	exportedVars.ExportStringStaticVariable("WindowsGccVersion", windowsGccVersion)
+
+	// The libgcc directories use the full GCC version, the prebuilts directory only the first two
+	// numbers.
+	if p := strings.Split(windowsGccVersion, "."); len(p) > 2 {
+		exportedVars.ExportStringStaticVariable("ShortWindowsGccVersion", strings.Join(p[:2], "."))
+	} else {
+		exportedVars.ExportStringStaticVariable("ShortWindowsGccVersion", windowsGccVersion)
+	}
 
-	pctx.SourcePathVariable("WindowsGccRoot",
-		"prebuilts/gcc/${HostPrebuiltTag}/host/x86_64-w64-mingw32-${WindowsGccVersion}")
+	exportedVars.ExportSourcePathVariable("WindowsGccRoot",
+		"prebuilts/gcc/${HostPrebuiltTag}/host/x86_64-w64-mingw32-${ShortWindowsGccVersion}")
 
-	pctx.StaticVariable("WindowsGccTriple", "x86_64-w64-mingw32")
+	exportedVars.ExportStringStaticVariable("WindowsGccTriple", "x86_64-w64-mingw32")
 
 	pctx.StaticVariable("WindowsCflags", strings.Join(windowsCflags, " "))
 	pctx.StaticVariable("WindowsLdflags", strings.Join(windowsLdflags, " "))
@@ -0,0 +1,205 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/genrule"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	android.RegisterModuleType("cc_config_header", ConfigHeaderFactory)
+}
+
+type configHeaderProperties struct {
+	// Name of the generated header, defaults to the name of the module with a .h extension.
+	Header *string
+
+	// Macros to define in the header, as NAME or NAME=VALUE. A macro without a value is defined
+	// to 1. Can be selected with soong_config_module_type.
+	Defines []string
+
+	// Soong config variables to define in the header, as namespace:variable. The variable
+	// "variable" of the namespace "namespace" is defined as NAMESPACE_VARIABLE.
+	Soong_config_variables []string
+
+	// Product variables to define in the header, by their name in soong.variables, e.g.
+	// "Platform_sdk_version" or "DeviceName". The product variable "DeviceName" is defined as
+	// PRODUCT_DEVICENAME.
+	Product_variables []string
+}
+
+// cc_config_header generates a header with the #defines of the feature selections of the build,
+// for the modules that list it in their generated_headers. Unlike -D flags in cflags, a change to
+// the selections only rebuilds the sources that include the header.
+//
+// Boolean product variables are defined to 1 or 0, numbers as they are and strings as string
+// literals. Soong config variables are strings, they are defined as string literals, except for
+// "true" and "false", the values of boolean soong config variables, which are defined to 1 and 0.
+// Unset variables are defined to 0.
+//
+// Example:
+//
+//	cc_config_header {
+//	    name: "libfoo_config",
+//	    header: "foo_config.h",
+//	    defines: ["FOO_ENABLE_TRACING"],
+//	    soong_config_variables: ["acme:board"],
+//	    product_variables: ["Debuggable"],
+//	}
+func ConfigHeaderFactory() android.Module {
+	module := &ConfigHeader{}
+	module.AddProperties(&module.properties)
+	android.InitAndroidModule(module)
+	return module
+}
+
+type ConfigHeader struct {
+	android.ModuleBase
+
+	properties configHeaderProperties
+
+	header    android.WritablePath
+	headerDir android.Path
+}
+
+var _ genrule.SourceFileGenerator = (*ConfigHeader)(nil)
+
+var (
+	configHeaderMacroRegexp   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	configHeaderInvalidRegexp = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// configHeaderMacroName returns the name of the macro for a variable, e.g. ACME_BOARD for
+// acme_board.
+func configHeaderMacroName(name string) string {
+	return strings.ToUpper(configHeaderInvalidRegexp.ReplaceAllString(name, "_"))
+}
+
+// configHeaderValue returns the value of a macro for the value of a product variable.
+func configHeaderValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case nil:
+		return "0", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case string:
+		return strconv.Quote(v), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+// configHeaderSoongConfigValue returns the value of a macro for the value of a soong config
+// variable.
+func configHeaderSoongConfigValue(config android.VendorConfig, variable string) string {
+	if !config.IsSet(variable) {
+		return "0"
+	}
+	switch value := config.String(variable); value {
+	case "true":
+		return "1"
+	case "false":
+		return "0"
+	default:
+		return strconv.Quote(value)
+	}
+}
+
+func (h *ConfigHeader) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	var defines []string
+	macros := make(map[string]bool)
+	define := func(property, name, value string) {
+		if !configHeaderMacroRegexp.MatchString(name) {
+			ctx.PropertyErrorf(property, "invalid macro name %q", name)
+			return
+		}
+		if macros[name] {
+			ctx.PropertyErrorf(property, "macro %q is defined more than once", name)
+			return
+		}
+		macros[name] = true
+		defines = append(defines, fmt.Sprintf("#define %s %s", name, value))
+	}
+
+	for _, d := range h.properties.Defines {
+		parts := strings.SplitN(d, "=", 2)
+		value := "1"
+		if len(parts) == 2 {
+			value = parts[1]
+		}
+		define("defines", parts[0], value)
+	}
+
+	for _, v := range h.properties.Soong_config_variables {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			ctx.PropertyErrorf("soong_config_variables", "%q must be namespace:variable", v)
+			continue
+		}
+		namespace, variable := parts[0], parts[1]
+		value := configHeaderSoongConfigValue(ctx.Config().VendorConfig(namespace), variable)
+		define("soong_config_variables", configHeaderMacroName(namespace+"_"+variable), value)
+	}
+
+	for _, v := range h.properties.Product_variables {
+		productValue, ok := ctx.Config().ProductVariable(v)
+		if !ok {
+			ctx.PropertyErrorf("product_variables", "unknown product variable %q", v)
+			continue
+		}
+		value, err := configHeaderValue(productValue)
+		if err != nil {
+			ctx.PropertyErrorf("product_variables", "product variable %q: %s", v, err)
+			continue
+		}
+		define("product_variables", "PRODUCT_"+configHeaderMacroName(v), value)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by the cc_config_header module %q. Do not edit.\n", ctx.ModuleName())
+	b.WriteString("#pragma once\n\n")
+	for _, d := range defines {
+		b.WriteString(d + "\n")
+	}
+
+	h.headerDir = android.PathForModuleGen(ctx)
+	h.header = android.PathForModuleGen(ctx, proptools.StringDefault(h.properties.Header, ctx.ModuleName()+".h"))
+	android.WriteFileRule(ctx, h.header, b.String())
+}
+
+func (h *ConfigHeader) GeneratedSourceFiles() android.Paths {
+	return android.Paths{h.header}
+}
+
+func (h *ConfigHeader) GeneratedHeaderDirs() android.Paths {
+	return android.Paths{h.headerDir}
+}
+
+func (h *ConfigHeader) GeneratedDeps() android.Paths {
+	return android.Paths{h.header}
+}
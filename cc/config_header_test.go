@@ -0,0 +1,98 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func TestConfigHeader(t *testing.T) {
+	t.Parallel()
+	bp := `
+		cc_config_header {
+			name: "libfoo_config",
+			header: "foo_config.h",
+			defines: ["FOO_TRACING", "FOO_LEVEL=3"],
+			soong_config_variables: ["acme:board", "acme:feature_x", "acme:mode", "acme:unset"],
+			product_variables: ["Debuggable", "Platform_sdk_version", "DeviceName"],
+		}
+
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.cpp"],
+			generated_headers: ["libfoo_config"],
+		}
+	`
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.VendorVars = map[string]map[string]string{
+				"acme": {
+					"board":     "falcon",
+					"feature_x": "true",
+					"mode":      "no",
+				},
+			}
+			variables.Debuggable = proptools.BoolPtr(true)
+			variables.Platform_sdk_version = proptools.IntPtr(34)
+			variables.DeviceName = proptools.StringPtr("generic")
+		}),
+	).RunTestWithBp(t, bp)
+
+	header := result.ModuleForTests("libfoo_config", "").Output("foo_config.h")
+	android.AssertStringEquals(t, "header",
+		"// Generated by the cc_config_header module \"libfoo_config\". Do not edit.\n"+
+			"#pragma once\n"+
+			"\n"+
+			"#define FOO_TRACING 1\n"+
+			"#define FOO_LEVEL 3\n"+
+			"#define ACME_BOARD \"falcon\"\n"+
+			"#define ACME_FEATURE_X 1\n"+
+			"#define ACME_MODE \"no\"\n"+
+			"#define ACME_UNSET 0\n"+
+			"#define PRODUCT_DEBUGGABLE 1\n"+
+			"#define PRODUCT_PLATFORM_SDK_VERSION 34\n"+
+			"#define PRODUCT_DEVICENAME \"generic\"\n",
+		android.ContentFromFileRuleForTests(t, header))
+
+	cc := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("cc")
+	android.AssertStringDoesContain(t, "include dirs", cc.Args["cFlags"],
+		"-Iout/soong/.intermediates/libfoo_config/gen")
+	android.AssertStringListContains(t, "order only deps", android.PathsRelativeToTop(cc.OrderOnly),
+		"out/soong/.intermediates/libfoo_config/gen/foo_config.h")
+}
+
+func TestConfigHeaderErrors(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`defines: invalid macro name "FOO-BAR"`,
+		`defines: macro "FOO" is defined more than once`,
+		`soong_config_variables: "acme" must be namespace:variable`,
+		`product_variables: unknown product variable "not_a_variable"`,
+	})).RunTestWithBp(t, `
+		cc_config_header {
+			name: "libfoo_config",
+			defines: ["FOO-BAR", "FOO", "FOO=2"],
+			soong_config_variables: ["acme"],
+			product_variables: ["not_a_variable"],
+		}
+	`)
+}
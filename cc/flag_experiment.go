@@ -0,0 +1,138 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains support for the CcFlagExperiment of the product, which builds a fraction of
+// the device modules with a set of experimental compiler and linker flags, e.g. a new MLGO model,
+// while the other modules are built with the default flags, so that the flags can be evaluated at
+// scale within a single build. The modules are selected from the hash of the name of the
+// experiment and of their directory and name, so that the selection is stable across builds and
+// all the variants of a module are in the same group. The cc_flag_experiment_report target lists
+// the modules with the group they are in.
+
+const (
+	flagExperimentSelectedGroup = "experiment"
+	flagExperimentControlGroup  = "control"
+)
+
+func init() {
+	registerFlagExperimentBuildComponents(android.InitRegistrationContext)
+}
+
+func registerFlagExperimentBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("cc_flag_experiment_report", flagExperimentReportSingletonFactory)
+}
+
+var prepareForTestWithFlagExperimentReport = android.FixtureRegisterWithContext(registerFlagExperimentBuildComponents)
+
+// validateFlagExperiment returns an error if the experiment is not valid.
+func validateFlagExperiment(experiment *android.CcFlagExperiment) error {
+	if experiment.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if experiment.Percent < 0 || experiment.Percent > 100 {
+		return fmt.Errorf("invalid percent %d in experiment %q, expected a value from 0 to 100",
+			experiment.Percent, experiment.Name)
+	}
+	for _, flag := range append(android.CopyOf(experiment.Cflags), experiment.Ldflags...) {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("invalid flag %q in experiment %q, expected a flag starting with -",
+				flag, experiment.Name)
+		}
+	}
+	return nil
+}
+
+// inFlagExperiment returns true if the module is selected for the experiment.
+func inFlagExperiment(experiment *android.CcFlagExperiment, moduleDir, moduleName string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(experiment.Name + "/" + moduleDir + ":" + moduleName))
+	return int(h.Sum32()%100) < experiment.Percent
+}
+
+// flagExperimentFlags adds the flags of the experiment of the product to the module if it is
+// selected for it, and returns the group of the module for the report, or an empty string if the
+// experiment does not apply to the module.
+func flagExperimentFlags(ctx ModuleContext, flags Flags) (Flags, string) {
+	experiment := ctx.Config().CcFlagExperiment()
+	if experiment == nil || ctx.Host() {
+		return flags, ""
+	}
+	// Invalid experiments are reported by the cc_flag_experiment_report singleton.
+	if validateFlagExperiment(experiment) != nil {
+		return flags, ""
+	}
+	if !inFlagExperiment(experiment, ctx.ModuleDir(), ctx.ModuleName()) {
+		return flags, flagExperimentControlGroup
+	}
+	flags.Local.CFlags = append(flags.Local.CFlags, experiment.Cflags...)
+	flags.Local.LdFlags = append(flags.Local.LdFlags, experiment.Ldflags...)
+	return flags, flagExperimentSelectedGroup
+}
+
+func flagExperimentReportSingletonFactory() android.Singleton {
+	return &flagExperimentReportSingleton{}
+}
+
+type flagExperimentReportSingleton struct {
+	report android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*flagExperimentReportSingleton)(nil)
+
+// GenerateBuildActions validates the flag experiment of the product and writes the list of the
+// modules it applies to, with their directory and group, to
+// out/soong/cc_flag_experiment_report.txt.
+func (s *flagExperimentReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	experiment := ctx.Config().CcFlagExperiment()
+	if experiment == nil {
+		return
+	}
+	if err := validateFlagExperiment(experiment); err != nil {
+		ctx.Errorf("CcFlagExperiment: %s", err)
+		return
+	}
+
+	lines := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() || c.flagExperimentGroup == "" {
+			return
+		}
+		lines[fmt.Sprintf("%s %s %s", ctx.ModuleDir(module), ctx.ModuleName(module), c.flagExperimentGroup)] = true
+	})
+
+	header := fmt.Sprintf("# %s %d%% cflags: %s ldflags: %s", experiment.Name, experiment.Percent,
+		strings.Join(experiment.Cflags, " "), strings.Join(experiment.Ldflags, " "))
+	report := android.PathForOutput(ctx, "cc_flag_experiment_report.txt")
+	android.WriteFileRule(ctx, report, strings.Join(append([]string{header}, android.SortedKeys(lines)...), "\n"))
+	ctx.Phony("cc_flag_experiment_report", report)
+	s.report = report
+}
+
+func (s *flagExperimentReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report == nil {
+		return
+	}
+	ctx.DistForGoal("droidcore", s.report)
+}
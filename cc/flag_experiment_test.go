@@ -0,0 +1,111 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestFlagExperiment(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		percent int
+		header  string
+		group   string
+	}{
+		{100, "# mlgo-regalloc 100%", "experiment"},
+		{0, "# mlgo-regalloc 0%", "control"},
+	} {
+		result := android.GroupFixturePreparers(
+			prepareForCcTest,
+			prepareForTestWithFlagExperimentReport,
+			android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.CcFlagExperiment = &android.CcFlagExperiment{
+					Name:    "mlgo-regalloc",
+					Percent: tc.percent,
+					Cflags:  []string{"-mllvm", "-regalloc-enable-advisor=release"},
+					Ldflags: []string{"-Wl,-mllvm,-regalloc-enable-advisor=release"},
+				}
+			}),
+			android.FixtureAddTextFile("foo/Android.bp", `
+				cc_library_shared {
+					name: "libfoo",
+					srcs: ["foo.cpp"],
+					host_supported: true,
+				}
+			`),
+		).RunTest(t)
+
+		check := android.AssertStringDoesNotContain
+		if tc.group == "experiment" {
+			check = android.AssertStringDoesContain
+		}
+		device := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+		check(t, "device cflags", device.Rule("cc").Args["cFlags"], "-mllvm -regalloc-enable-advisor=release")
+		check(t, "device ldflags", device.Rule("ld").Args["ldFlags"], "-Wl,-mllvm,-regalloc-enable-advisor=release")
+
+		host := result.ModuleForTests("libfoo", "linux_glibc_x86_64_shared")
+		android.AssertStringDoesNotContain(t, "host cflags", host.Rule("cc").Args["cFlags"], "-regalloc-enable-advisor")
+
+		report := result.SingletonForTests("cc_flag_experiment_report").Output("cc_flag_experiment_report.txt")
+		android.AssertStringEquals(t, "report",
+			tc.header+" cflags: -mllvm -regalloc-enable-advisor=release "+
+				"ldflags: -Wl,-mllvm,-regalloc-enable-advisor=release\n"+
+				"foo libfoo "+tc.group,
+			android.ContentFromFileRuleForTests(t, report))
+	}
+}
+
+func TestInFlagExperiment(t *testing.T) {
+	t.Parallel()
+	experiment := &android.CcFlagExperiment{Name: "mlgo-regalloc", Percent: 50}
+	var selected []string
+	for _, name := range []string{"liba", "libb", "libc", "libd", "libe", "libf", "libg", "libh"} {
+		if inFlagExperiment(experiment, "foo", name) {
+			selected = append(selected, name)
+		}
+	}
+	android.AssertArrayString(t, "selected modules", []string{"liba", "libb", "libd", "libe"}, selected)
+}
+
+func TestFlagExperimentErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name       string
+		experiment android.CcFlagExperiment
+		err        string
+	}{
+		{"missing name", android.CcFlagExperiment{Percent: 10}, `missing name`},
+		{"invalid percent", android.CcFlagExperiment{Name: "exp", Percent: 101},
+			`invalid percent 101 in experiment "exp"`},
+		{"invalid flag", android.CcFlagExperiment{Name: "exp", Percent: 10, Cflags: []string{"O3"}},
+			`invalid flag "O3" in experiment "exp"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			experiment := tc.experiment
+			android.GroupFixturePreparers(
+				prepareForCcTest,
+				prepareForTestWithFlagExperimentReport,
+				android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+					variables.CcFlagExperiment = &experiment
+				}),
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				"CcFlagExperiment: "+tc.err)).
+				RunTestWithBp(t, "")
+		})
+	}
+}
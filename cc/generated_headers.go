@@ -0,0 +1,144 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"android/soong/android"
+)
+
+// This file contains the validation of the generated headers on the include path of a module, from
+// its generated_headers dependencies and from the libraries it depends on. When a generated header
+// has the same include path as another generated header, or as a source header in the local
+// include directories of the module or in the include directories exported by its dependencies,
+// only the one found first on the include path is used, which leads to compile errors that are
+// hard to track down. Such conflicts are reported as errors that list where each of the headers
+// comes from.
+
+// generatedHeaderSource is a set of generated headers on the include path of a module, along with
+// the module they come from.
+type generatedHeaderSource struct {
+	// The module that generates the headers, or the library that exports them.
+	provider string
+
+	// Whether the headers are exported by a library, rather than generated by a generated_headers
+	// dependency of the module.
+	exported bool
+
+	headers android.Paths
+
+	// The include directories that the headers are found in.
+	includeDirs android.Paths
+}
+
+// generatedHeader is a generated header along with the source it comes from.
+type generatedHeader struct {
+	path   android.Path
+	source *generatedHeaderSource
+}
+
+func (h generatedHeader) String() string {
+	if h.source.exported {
+		return fmt.Sprintf("%s (exported by %s)", h.path, h.source.provider)
+	}
+	return fmt.Sprintf("%s (generated by %s)", h.path, h.source.provider)
+}
+
+func isHeaderFile(path android.Path) bool {
+	for _, ext := range HeaderExts {
+		if strings.HasSuffix(path.Base(), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedHeadersByIncludePath returns the distinct generated headers of the sources by the path
+// they are included with, e.g. foo/bar.h for #include <foo/bar.h>. A header is listed under each
+// of the include directories of its source that contain it.
+func generatedHeadersByIncludePath(sources []generatedHeaderSource) map[string][]generatedHeader {
+	ret := make(map[string][]generatedHeader)
+	for i := range sources {
+		source := &sources[i]
+		for _, header := range source.headers {
+			if !isHeaderFile(header) {
+				continue
+			}
+		dirs:
+			for _, dir := range source.includeDirs {
+				includePath, err := filepath.Rel(dir.String(), header.String())
+				if err != nil || includePath == ".." || strings.HasPrefix(includePath, "../") {
+					continue
+				}
+				for _, h := range ret[includePath] {
+					if h.path.String() == header.String() {
+						continue dirs
+					}
+				}
+				ret[includePath] = append(ret[includePath], generatedHeader{header, source})
+			}
+		}
+	}
+	return ret
+}
+
+// checkGeneratedHeaderConflicts reports the generated headers of the sources that have the same
+// include path as another generated header, or as a source header in one of the source include
+// directories. The existence of each candidate source header is checked with a glob, so that Soong
+// reruns when a conflicting source header is added or removed.
+func checkGeneratedHeaderConflicts(ctx android.ModuleContext, sources []generatedHeaderSource, sourceIncludeDirs android.Paths) {
+	headers := generatedHeadersByIncludePath(sources)
+	for _, includePath := range android.SortedKeys(headers) {
+		conflicts := headers[includePath]
+		if len(conflicts) > 1 {
+			var lines []string
+			for _, h := range conflicts {
+				lines = append(lines, "\n    "+h.String())
+			}
+			ctx.ModuleErrorf("generated header %q is provided more than once on the include path, "+
+				"only the first one is used:%s", includePath, strings.Join(lines, ""))
+		}
+		for _, dir := range sourceIncludeDirs {
+			if src := android.ExistentPathForSource(ctx, dir.String(), includePath); src.Valid() {
+				ctx.ModuleErrorf("generated header %q has the same include path as the source header %s:\n    %s",
+					includePath, src, conflicts[0])
+			}
+		}
+	}
+}
+
+// sourceDirs returns the include directories that are in the source tree, rather than generated.
+func sourceDirs(dirs android.Paths) android.Paths {
+	var ret android.Paths
+	for _, dir := range dirs {
+		if _, generated := dir.(android.WritablePath); !generated {
+			ret = append(ret, dir)
+		}
+	}
+	return ret
+}
+
+// localIncludeDirs returns the source directories that the module includes headers from before
+// the include directories of its dependencies.
+func (compiler *baseCompiler) localIncludeDirs(ctx android.ModuleContext) android.Paths {
+	dirs := android.PathsForModuleSrc(ctx, compiler.Properties.Local_include_dirs)
+	if compiler.includeBuildDirectory() {
+		dirs = append(dirs, android.PathForModuleSrc(ctx))
+	}
+	return dirs
+}
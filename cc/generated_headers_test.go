@@ -0,0 +1,169 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestGeneratedHeaderConflicts(t *testing.T) {
+	t.Parallel()
+	genRuleModules := `
+		genrule {
+			name: "genrule_foo",
+			cmd: "generate-foo",
+			out: ["generated_headers/config/config.h"],
+			export_include_dirs: ["generated_headers"],
+		}
+
+		genrule {
+			name: "genrule_bar",
+			cmd: "generate-bar",
+			out: ["include/config/config.h"],
+			export_include_dirs: ["include"],
+		}
+
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			generated_headers: ["genrule_foo"],
+			export_generated_headers: ["genrule_foo"],
+		}
+	`
+
+	for _, tc := range []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "same header from the same genrule",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					shared_libs: ["libfoo"],
+					generated_headers: ["genrule_foo"],
+				}`,
+		},
+		{
+			name: "generated by two genrules",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					generated_headers: ["genrule_foo", "genrule_bar"],
+				}`,
+			err: `generated header "config/config.h" is provided more than once on the include path, ` +
+				`only the first one is used:\n` +
+				`    .*/genrule_foo/gen/generated_headers/config/config.h \(generated by genrule_foo\)\n` +
+				`    .*/genrule_bar/gen/include/config/config.h \(generated by genrule_bar\)`,
+		},
+		{
+			name: "generated and exported by a library",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					shared_libs: ["libfoo"],
+					generated_headers: ["genrule_bar"],
+				}`,
+			err: `generated header "config/config.h" is provided more than once on the include path` +
+				`(?s:.*)/genrule_foo/gen/generated_headers/config/config.h \(exported by libfoo\)`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			errorHandler := android.FixtureExpectsNoErrors
+			if tc.err != "" {
+				errorHandler = android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.err)
+			}
+			prepareForCcTest.ExtendWithErrorHandler(errorHandler).
+				RunTestWithBp(t, genRuleModules+tc.bp)
+		})
+	}
+}
+
+func TestGeneratedHeaderShadowedBySourceHeader(t *testing.T) {
+	t.Parallel()
+	bp := `
+		genrule {
+			name: "genrule_bar",
+			cmd: "generate-bar",
+			out: ["include/config/config.h"],
+			export_include_dirs: ["include"],
+		}
+
+		cc_library {
+			name: "libbaz",
+			srcs: ["baz.c"],
+			export_include_dirs: ["baz/include"],
+		}
+	`
+
+	for _, tc := range []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "no source header",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					local_include_dirs: ["other"],
+					generated_headers: ["genrule_bar"],
+				}`,
+		},
+		{
+			name: "local include dir",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					local_include_dirs: ["include"],
+					generated_headers: ["genrule_bar"],
+				}`,
+			err: `generated header "config/config.h" has the same include path as the source header include/config/config.h:\n` +
+				`    .*/genrule_bar/gen/include/config/config.h \(generated by genrule_bar\)`,
+		},
+		{
+			name: "include dir exported by a library",
+			bp: `
+				cc_library {
+					name: "libbar",
+					srcs: ["bar.c"],
+					shared_libs: ["libbaz"],
+					generated_headers: ["genrule_bar"],
+				}`,
+			err: `generated header "config/config.h" has the same include path as the source header baz/include/config/config.h`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			errorHandler := android.FixtureExpectsNoErrors
+			if tc.err != "" {
+				errorHandler = android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.err)
+			}
+			android.GroupFixturePreparers(
+				prepareForCcTest,
+				android.FixtureAddFile("include/config/config.h", nil),
+				android.FixtureAddFile("baz/include/config/config.h", nil),
+			).ExtendWithErrorHandler(errorHandler).
+				RunTestWithBp(t, bp+tc.bp)
+		})
+	}
+}
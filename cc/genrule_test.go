@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"android/soong/android"
+	"android/soong/genrule"
 )
 
 func testGenruleContext(config android.Config) *android.TestContext {
@@ -186,3 +187,40 @@ func TestCmdPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestCodegenModuleSources(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		genrule.PrepareForTestWithCodegenPlugin,
+	).RunTestWithBp(t, `
+		test_codegen {
+			name: "foo_idl",
+			interfaces: ["foo"],
+		}
+
+		test_codegen {
+			name: "bar_idl",
+			interfaces: ["bar"],
+		}
+
+		cc_library_static {
+			name: "libfoo",
+			generated_sources: ["foo_idl"],
+			generated_headers: ["bar_idl"],
+		}
+	`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_static")
+	cc := libfoo.Rule("cc")
+	android.AssertPathRelativeToTopEquals(t, "generated source", "out/soong/.intermediates/foo_idl/gen/foo.c", cc.Input)
+	android.AssertStringListContains(t, "generated header of generated_sources",
+		android.PathsRelativeToTop(cc.OrderOnly), "out/soong/.intermediates/foo_idl/gen/include/foo.h")
+	android.AssertStringListContains(t, "generated header of generated_headers",
+		android.PathsRelativeToTop(cc.OrderOnly), "out/soong/.intermediates/bar_idl/gen/include/bar.h")
+
+	flags := libfoo.Module().(*Module).flags.Local.CommonFlags
+	android.AssertStringListContains(t, "include dir of generated_sources",
+		android.StringsRelativeToTop(result.Config, flags), "-Iout/soong/.intermediates/foo_idl/gen")
+	android.AssertStringListContains(t, "include dir of generated_headers",
+		android.StringsRelativeToTop(result.Config, flags), "-Iout/soong/.intermediates/bar_idl/gen")
+}
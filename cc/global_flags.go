@@ -0,0 +1,189 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+// This file writes out/soong/cc_global_flags.json, the effective values of the global flags of the
+// compile and link commands, after the environment overrides (LLVM_NEXT, SDCLANG_*, ...) are
+// applied. It is written by soong_build, so it exists after any build. It contains the values of
+// the flag variables of cc/config, and the global flags of each os and arch in the order they are
+// passed to clang:
+//
+//	{
+//	    "variables": {
+//	        "CommonGlobalCflags": ["-DANDROID", ...],
+//	        ...
+//	    },
+//	    "targets": [
+//	        {
+//	            "os": "android",
+//	            "arch": "arm64",
+//	            "arch_variant": "armv8-a",
+//	            "cpu_variant": "generic",
+//	            "triple": "aarch64-linux-android",
+//	            "cflags": [...],
+//	            ...
+//	        }
+//	    ]
+//	}
+
+func init() {
+	registerGlobalFlagsBuildComponents(android.InitRegistrationContext)
+}
+
+func registerGlobalFlagsBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("cc_global_flags", globalFlagsSingletonFactory)
+}
+
+var prepareForTestWithGlobalFlags = android.FixtureRegisterWithContext(registerGlobalFlagsBuildComponents)
+
+// GlobalFlagsPath returns the path of the dump of the global flags.
+func GlobalFlagsPath(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, "cc_global_flags.json")
+}
+
+type globalFlags struct {
+	Variables map[string][]string `json:"variables"`
+	Targets   []globalFlagsTarget `json:"targets"`
+}
+
+type globalFlagsTarget struct {
+	Os          string `json:"os"`
+	Arch        string `json:"arch"`
+	ArchVariant string `json:"arch_variant,omitempty"`
+	CpuVariant  string `json:"cpu_variant,omitempty"`
+	Triple      string `json:"triple"`
+
+	Cflags           []string `json:"cflags"`
+	Conlyflags       []string `json:"conlyflags"`
+	Cppflags         []string `json:"cppflags"`
+	Asflags          []string `json:"asflags"`
+	NoOverrideCflags []string `json:"no_override_cflags"`
+	Ldflags          []string `json:"ldflags"`
+	Lldflags         []string `json:"lldflags"`
+}
+
+func globalFlagsSingletonFactory() android.Singleton {
+	return &globalFlagsSingleton{}
+}
+
+type globalFlagsSingleton struct{}
+
+func (s *globalFlagsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	flags, err := collectGlobalFlags(ctx)
+	if err != nil {
+		ctx.Errorf("cc_global_flags.json: %s", err)
+		return
+	}
+	data, err := json.MarshalIndent(flags, "", "  ")
+	if err != nil {
+		ctx.Errorf("cc_global_flags.json: %s", err)
+		return
+	}
+	if err := android.WriteFileToOutputDir(GlobalFlagsPath(ctx), data, 0666); err != nil {
+		ctx.Errorf("cc_global_flags.json: %s", err)
+	}
+}
+
+// collectGlobalFlags returns the evaluated global flags of the build.
+func collectGlobalFlags(ctx android.SingletonContext) (*globalFlags, error) {
+	eval := func(ninjaStr string) ([]string, error) {
+		value, err := ctx.Eval(pctx, ninjaStr)
+		if err != nil {
+			return nil, err
+		}
+		// Eval escapes the $ of the value for ninja.
+		return strings.Fields(strings.ReplaceAll(value, "$$", "$")), nil
+	}
+
+	flags := &globalFlags{Variables: make(map[string][]string)}
+	for _, name := range config.FlagVariableNames() {
+		value, err := eval("${config." + name + "}")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+		flags.Variables[name] = value
+	}
+
+	// The targets of the build machine and of the device, like the make variables.
+	var targets []android.Target
+	targets = append(targets, ctx.Config().Targets[ctx.Config().BuildOS]...)
+	targets = append(targets, ctx.Config().Targets[android.Android]...)
+	for _, target := range targets {
+		if target.NativeBridge == android.NativeBridgeEnabled {
+			continue
+		}
+		t, err := globalFlagsForTarget(target, eval)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %s", target.Os, target.Arch, err)
+		}
+		flags.Targets = append(flags.Targets, t)
+	}
+	return flags, nil
+}
+
+// globalFlagsForTarget returns the global flags of the compile and link commands of the target,
+// like the CLANG_*_GLOBAL_*FLAGS make variables.
+func globalFlagsForTarget(target android.Target, eval func(string) ([]string, error)) (globalFlagsTarget, error) {
+	toolchain := config.FindToolchain(target.Os, target.Arch)
+
+	hod := "Host"
+	clangConfigFlags := ""
+	if target.Os.Class == android.Device {
+		hod = "Device"
+		clangConfigFlags = "${config.DeviceClangConfigFlags}"
+	}
+
+	t := globalFlagsTarget{
+		Os:          target.Os.String(),
+		Arch:        target.Arch.ArchType.String(),
+		ArchVariant: target.Arch.ArchVariant,
+		CpuVariant:  target.Arch.CpuVariant,
+		Triple:      toolchain.ClangTriple(),
+	}
+
+	fields := []struct {
+		dst   *[]string
+		ninja []string
+	}{
+		{&t.Cflags, []string{toolchain.Cflags(), "${config.CommonGlobalCflags}",
+			fmt.Sprintf("${config.%sGlobalCflags}", hod), toolchain.ToolchainCflags(), clangConfigFlags}},
+		{&t.Conlyflags, []string{"${config.CommonGlobalConlyflags}"}},
+		{&t.Cppflags, []string{"${config.CommonGlobalCppflags}", fmt.Sprintf("${config.%sGlobalCppflags}", hod),
+			toolchain.Cppflags()}},
+		{&t.Asflags, []string{"${config.CommonGlobalAsflags}", toolchain.Asflags()}},
+		{&t.NoOverrideCflags, []string{"${config.NoOverrideGlobalCflags}"}},
+		{&t.Ldflags, []string{fmt.Sprintf("${config.%sGlobalLdflags}", hod), toolchain.Ldflags(),
+			toolchain.ToolchainLdflags()}},
+		{&t.Lldflags, []string{fmt.Sprintf("${config.%sGlobalLldflags}", hod), toolchain.Lldflags(),
+			toolchain.ToolchainLdflags()}},
+	}
+	for _, f := range fields {
+		value, err := eval(strings.Join(f.ninja, " "))
+		if err != nil {
+			return t, err
+		}
+		*f.dst = value
+	}
+	return t, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestGlobalFlags(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithGlobalFlags,
+		android.FixtureMergeEnv(map[string]string{
+			"LLVM_NEXT": "true",
+		}),
+	).RunTest(t)
+
+	data, err := os.ReadFile(GlobalFlagsPath(android.PathContextForTesting(result.Config)).String())
+	if err != nil {
+		t.Fatalf("missing cc_global_flags.json: %s", err)
+	}
+	var flags globalFlags
+	if err := json.Unmarshal(data, &flags); err != nil {
+		t.Fatalf("invalid cc_global_flags.json: %s", err)
+	}
+
+	android.AssertStringListContains(t, "CommonGlobalCflags", flags.Variables["CommonGlobalCflags"], "-DANDROID")
+	android.AssertStringListContains(t, "NoOverrideGlobalCflags with LLVM_NEXT",
+		flags.Variables["NoOverrideGlobalCflags"], "-Wno-error")
+	if _, ok := flags.Variables["SDClangFlags"]; !ok {
+		t.Errorf("missing SDClangFlags in %q", android.SortedStringKeys(flags.Variables))
+	}
+
+	var targets []string
+	for _, target := range flags.Targets {
+		targets = append(targets, target.Os+"_"+target.Arch)
+		if target.Os == "android" && target.Arch == "arm64" {
+			android.AssertStringEquals(t, "arm64 triple", "aarch64-linux-android", target.Triple)
+			android.AssertStringListContains(t, "arm64 cflags", target.Cflags, "-DANDROID")
+			android.AssertStringListContains(t, "arm64 no_override_cflags", target.NoOverrideCflags, "-Wno-error")
+		}
+	}
+	android.AssertArrayString(t, "targets",
+		[]string{"linux_glibc_x86_64", "linux_glibc_x86", "android_arm64", "android_arm"}, targets)
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+// HostTestRuntimeInfo is provided by the variants of the host tests that are built against one of
+// the additional host runtimes listed in the HostTestRuntimes product variable.
+type HostTestRuntimeInfo struct {
+	// The name of the host runtime, e.g. "musl".
+	Runtime string
+
+	// The test binary.
+	Binary android.Path
+
+	// The test config of the variant, which records the host runtime.
+	TestConfig android.Path
+}
+
+var HostTestRuntimeInfoProvider = blueprint.NewProvider(HostTestRuntimeInfo{})
+
+func hostTestRuntimesSingletonFactory() android.Singleton {
+	return &hostTestRuntimesSingleton{}
+}
+
+// hostTestRuntimesSingleton packages the host tests built against each of the additional host
+// runtimes into a zip per runtime, so that they can be run alongside the tests built against the
+// primary host runtime.
+type hostTestRuntimesSingleton struct {
+	outputPaths android.Paths
+}
+
+var _ android.SingletonMakeVarsProvider = (*hostTestRuntimesSingleton)(nil)
+
+func (s *hostTestRuntimesSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	tests := make(map[string][]HostTestRuntimeInfo)
+	ctx.VisitAllModules(func(module android.Module) {
+		if !module.Enabled() || !android.IsModulePreferred(module) {
+			return
+		}
+		if ctx.ModuleHasProvider(module, HostTestRuntimeInfoProvider) {
+			info := ctx.ModuleProvider(module, HostTestRuntimeInfoProvider).(HostTestRuntimeInfo)
+			tests[info.Runtime] = append(tests[info.Runtime], info)
+		}
+	})
+
+	for _, runtime := range android.SortedKeys(tests) {
+		output := android.PathForOutput(ctx, "host_test_runtimes", "host-tests-"+runtime+".zip")
+		rule := android.NewRuleBuilder(pctx, ctx)
+		cmd := rule.Command().
+			BuiltTool("soong_zip").
+			FlagWithOutput("-o ", output)
+		for _, info := range tests[runtime] {
+			dir := runtime + "/" + info.Binary.Base() + "/"
+			cmd.FlagWithArg("-e ", dir+info.Binary.Base()).
+				FlagWithInput("-f ", info.Binary)
+			if info.TestConfig != nil {
+				cmd.FlagWithArg("-e ", dir+info.Binary.Base()+".config").
+					FlagWithInput("-f ", info.TestConfig)
+			}
+		}
+		rule.Build("host_test_runtimes_"+runtime, "host tests built against "+runtime)
+		s.outputPaths = append(s.outputPaths, output)
+	}
+
+	if len(s.outputPaths) > 0 {
+		ctx.Phony("host_test_runtimes", s.outputPaths...)
+	}
+}
+
+func (s *hostTestRuntimesSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if len(s.outputPaths) == 0 {
+		return
+	}
+
+	ctx.DistForGoal("host_test_runtimes", s.outputPaths...)
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+// This file contains support for stamping the platform shared libraries with an identity note, in
+// the .note.android.ident section next to the ABI note of crtbrand. The note is an ELF note with
+// the name "Android" and the type identityNoteType, whose descriptor is a sequence of NUL
+// terminated key=value strings:
+//
+//	module=<name of the module>
+//	apexes=<comma separated names of the APEXes that the library is built for, if any>
+//	min_sdk=<min_sdk_version of the library>
+//
+// It is read by the on-device tooling that attributes crashes to modules, which cannot tell a
+// platform library apart from a vendor overlay of it from its file name alone.
+
+// The type of the identity note, outside of the range of the types of the notes defined by bionic,
+// e.g. NT_ANDROID_TYPE_IDENT for the ABI note.
+const identityNoteType = 0x100
+
+// identityNoteEnabled returns true if the shared library is stamped with an identity note, i.e. if
+// it is a platform library in one of the directories selected by cc/config.
+func identityNoteEnabled(ctx ModuleContext) bool {
+	if !ctx.Device() || ctx.useSdk() || ctx.useVndk() || ctx.isNDKStubLibrary() {
+		return false
+	}
+	return config.IdentityNoteEnabledForDir(ctx.Config(), ctx.ModuleDir())
+}
+
+// identityNoteDescriptor returns the key=value strings of the identity note of the library.
+func identityNoteDescriptor(ctx ModuleContext) []string {
+	apexInfo := ctx.Provider(android.ApexInfoProvider).(android.ApexInfo)
+	minSdk := ctx.minSdkVersion()
+	if minSdk == "" {
+		minSdk = "current"
+	}
+	return []string{
+		"module=" + ctx.baseModuleName(),
+		"apexes=" + strings.Join(android.SortedUniqueStrings(apexInfo.InApexVariants), ","),
+		"min_sdk=" + minSdk,
+	}
+}
+
+// identityNoteAssembly returns the assembly source of the identity note with the given descriptor.
+func identityNoteAssembly(descriptor []string) string {
+	var b strings.Builder
+	fmt.Fprintln(&b, "// Identity note of the library, generated by Soong.")
+	fmt.Fprintln(&b, `  .section .note.android.ident,"a",%note`)
+	fmt.Fprintln(&b, "  .balign 4")
+	fmt.Fprintln(&b, "  .long 2f-1f  // namesz")
+	fmt.Fprintln(&b, "  .long 3f-2f  // descsz")
+	fmt.Fprintf(&b, "  .long %#x  // type\n", identityNoteType)
+	fmt.Fprintln(&b, "1:")
+	fmt.Fprintln(&b, `  .ascii "Android\0"`)
+	fmt.Fprintln(&b, "2:")
+	for _, s := range descriptor {
+		fmt.Fprintf(&b, "  .ascii \"%s\\0\"\n", s)
+	}
+	fmt.Fprintln(&b, "3:")
+	fmt.Fprint(&b, "  .balign 4")
+	return b.String()
+}
+
+// identityNoteObject generates the rules to build the object of the identity note of the shared
+// library, and returns it, or nil if the library is not stamped with an identity note.
+func identityNoteObject(ctx ModuleContext, flags builderFlags) android.Path {
+	if !identityNoteEnabled(ctx) {
+		return nil
+	}
+
+	src := android.PathForModuleOut(ctx, "identity_note", "identity_note.S")
+	android.WriteFileRule(ctx, src, identityNoteAssembly(identityNoteDescriptor(ctx)))
+	objs := compileObjs(ctx, flags, "", android.Paths{src}, nil, nil, nil, nil)
+	return objs.objFiles[0]
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// This file contains the validation of the ifunc resolvers of the platform shared libraries. The
+// bionic linker calls the resolvers while relocating the library, before its PLT is resolved, so a
+// resolver that calls a function of another library, or an exported function of its own library,
+// crashes the process at startup depending on the order of the relocations. ifunc_verify fails
+// the build instead when a resolver calls a PLT entry.
+
+func init() {
+	pctx.HostBinToolVariable("ifuncVerifyCmd", "ifunc_verify")
+}
+
+var verifyIfunc = pctx.AndroidStaticRule("verifyIfunc",
+	blueprint.RuleParams{
+		Command:     "$ifuncVerifyCmd -i $in && touch $out",
+		CommandDeps: []string{"$ifuncVerifyCmd"},
+	})
+
+// ifuncVerifyEnabled returns true if the ifunc resolvers of the shared library are validated, i.e.
+// if it is a platform library for a device architecture decoded by ifunc_verify.
+func ifuncVerifyEnabled(ctx ModuleContext) bool {
+	if ctx.Os() != android.Android || ctx.useSdk() {
+		return false
+	}
+	switch ctx.Arch().ArchType {
+	case android.Arm64, android.Riscv64:
+		return true
+	}
+	return false
+}
+
+// verifyIfuncResolvers returns the stamp file of the validation of the ifunc resolvers of the
+// linked shared library, or nil if they are not validated.
+func verifyIfuncResolvers(ctx ModuleContext, in android.Path) android.Paths {
+	if !ifuncVerifyEnabled(ctx) {
+		return nil
+	}
+	out := android.PathForModuleOut(ctx, "ifunc_verify.stamp")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        verifyIfunc,
+		Description: "verify ifunc resolvers " + in.Base(),
+		Input:       in,
+		Output:      out,
+	})
+	return android.Paths{out}
+}
@@ -45,6 +45,9 @@ type LibraryProperties struct {
 	// rename host libraries to prevent overlap with system installed libraries
 	Unique_host_soname *bool
 
+	// Properties to build and install a versioned shared library.
+	Library_version LibraryVersionProperties
+
 	Aidl struct {
 		// export headers generated from .aidl sources
 		Export_aidl_headers *bool
@@ -62,6 +65,11 @@ type LibraryProperties struct {
 
 	Static_ndk_lib *bool
 
+	// Experimental: build the library with Clang header modules, and generate a module map of its
+	// exported headers for the libraries that depend on it and are also built with Clang header
+	// modules. Only takes effect when the experiment is enabled with SOONG_CLANG_MODULES=true.
+	Clang_modules *bool
+
 	// Generate stubs to make this library accessible to APEXes.
 	Stubs struct {
 		// Relative path to the symbol map. The symbol map provides the list of
@@ -828,6 +836,13 @@ type libraryDecorator struct {
 
 	versionScriptPath android.OptionalPath
 
+	// The version and the soname version of a versioned shared library, set by linkerFlags.
+	version       string
+	sonameVersion string
+
+	// Module map of the exported headers, when the library is built with Clang header modules
+	clangModuleMap android.Path
+
 	postInstallCmds []string
 
 	// If useCoreVariant is true, the vendor variant of a VNDK library is
@@ -1170,7 +1185,12 @@ func (library *libraryDecorator) linkerFlags(ctx ModuleContext, flags Flags) Fla
 		} else {
 			f = append(f, "-shared")
 			if !ctx.Windows() {
-				f = append(f, "-Wl,-soname,"+libName+flags.Toolchain.ShlibSuffix())
+				soname := libName + flags.Toolchain.ShlibSuffix()
+				library.version, library.sonameVersion = library.libraryVersion(ctx)
+				if library.sonameVersion != "" {
+					soname += "." + library.sonameVersion
+				}
+				f = append(f, "-Wl,-soname,"+soname)
 			}
 		}
 
@@ -1192,6 +1212,7 @@ func (library *libraryDecorator) compilerFlags(ctx ModuleContext, flags Flags, d
 	}
 
 	flags = library.baseCompiler.compilerFlags(ctx, flags, deps)
+	flags = library.clangModulesFlags(ctx, flags, exportIncludeDirs)
 	if ctx.IsLlndk() {
 		// LLNDK libraries ignore most of the properties on the cc_library and use the
 		// LLNDK-specific properties instead.
@@ -1735,7 +1756,7 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	if ctx.Darwin() && deps.DarwinSecondArchOutput.Valid() {
 		fatOutputFile := outputFile
 		outputFile = android.PathForModuleOut(ctx, "pre-fat", fileName)
-		transformDarwinUniversalBinary(ctx, fatOutputFile, outputFile, deps.DarwinSecondArchOutput.Path())
+		TransformDarwinUniversalBinary(ctx, fatOutputFile, outputFile, deps.DarwinSecondArchOutput.Path())
 	}
 
 	// Optimize out relinking against shared libraries whose interface hasn't changed by
@@ -1760,6 +1781,15 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	}
 	library.unstrippedOutputFile = outputFile
 
+	// The linker wrapper runs on the unstripped output, so that both the stripped output and the
+	// symbols contain its changes.
+	if deps.LinkerWrapper.Valid() {
+		wrappedOutputFile := outputFile
+		outputFile = android.PathForModuleOut(ctx, "prewrapped", fileName)
+		transformLinkerWrapper(ctx, deps.LinkerWrapper.Path(), &library.baseLinker.Properties.Linker_wrapper,
+			outputFile, wrappedOutputFile)
+	}
+
 	outputFile = maybeInjectBoringSSLHash(ctx, outputFile, library.Properties.Inject_bssl_hash, fileName)
 
 	if Bool(library.baseLinker.Properties.Use_version_lib) {
@@ -1788,9 +1818,20 @@ func (library *libraryDecorator) linkShared(ctx ModuleContext,
 	linkerDeps = append(linkerDeps, deps.EarlySharedLibsDeps...)
 	linkerDeps = append(linkerDeps, deps.SharedLibsDeps...)
 	linkerDeps = append(linkerDeps, deps.LateSharedLibsDeps...)
-	transformObjToDynamicBinary(ctx, objs.objFiles, sharedLibs,
+	objFiles := objs.objFiles
+	if !library.buildStubs() {
+		if identityNote := identityNoteObject(ctx, builderFlags); identityNote != nil {
+			objFiles = android.Concat(objFiles, android.Paths{identityNote})
+		}
+	}
+	var validations android.Paths
+	validations = append(validations, objs.tidyDepFiles...)
+	if !library.buildStubs() {
+		validations = append(validations, verifyIfuncResolvers(ctx, outputFile)...)
+	}
+	transformObjToDynamicBinary(ctx, objFiles, sharedLibs,
 		deps.StaticLibs, deps.LateStaticLibs, deps.WholeStaticLibs,
-		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs, objs.tidyDepFiles)
+		linkerDeps, deps.CrtBegin, deps.CrtEnd, false, builderFlags, outputFile, implicitOutputs, validations)
 
 	objs.coverageFiles = append(objs.coverageFiles, deps.StaticLibObjs.coverageFiles...)
 	objs.coverageFiles = append(objs.coverageFiles, deps.WholeStaticLibObjs.coverageFiles...)
@@ -2122,6 +2163,12 @@ func (library *libraryDecorator) link(ctx ModuleContext,
 	library.reexportDeps(deps.ReexportedDeps...)
 	library.addExportedGeneratedHeaders(deps.ReexportedGeneratedHeaders...)
 
+	// Export the module map of the headers when built with Clang header modules.
+	if library.clangModuleMap != nil {
+		library.reexportFlags("-fmodule-map-file=" + library.clangModuleMap.String())
+		library.reexportDeps(library.clangModuleMap)
+	}
+
 	// Optionally export aidl headers.
 	if Bool(library.Properties.Aidl.Export_aidl_headers) {
 		if library.baseCompiler.hasSrcExt(".aidl") {
@@ -2299,7 +2346,10 @@ func (library *libraryDecorator) install(ctx ModuleContext, file android.Path) {
 			ctx.Module().HideFromMake()
 		}
 
-		library.baseInstaller.install(ctx, file)
+		if !library.installVersioned(ctx, file) {
+			library.baseInstaller.install(ctx, file)
+		}
+		library.stripper.installSplitDebugFile(ctx, library.baseInstaller.installDir(ctx))
 	}
 
 	if Bool(library.Properties.Static_ndk_lib) && library.static() &&
@@ -395,6 +395,67 @@ func TestLibraryVersionScript(t *testing.T) {
 
 }
 
+func TestLibraryVersion(t *testing.T) {
+	t.Parallel()
+	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			host_supported: true,
+			srcs: ["foo.c"],
+			library_version: {
+				version: "1.2.3",
+			},
+		}
+		cc_library {
+			name: "libbar",
+			host_supported: true,
+			srcs: ["bar.c"],
+			library_version: {
+				version: "1.2.3",
+				soname_version: "1.2",
+				dev_symlink: false,
+			},
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "linux_glibc_x86_64_shared")
+	android.AssertStringDoesContain(t, "libfoo soname",
+		libfoo.Rule("ld").Args["ldFlags"], "-Wl,-soname,libfoo.so.1")
+	libfoo.Output("lib64/libfoo.so.1.2.3")
+	libfoo.Output("lib64/libfoo.so.1")
+	libfoo.Output("lib64/libfoo.so")
+
+	libbar := result.ModuleForTests("libbar", "linux_glibc_x86_64_shared")
+	android.AssertStringDoesContain(t, "libbar soname",
+		libbar.Rule("ld").Args["ldFlags"], "-Wl,-soname,libbar.so.1.2")
+	libbar.Output("lib64/libbar.so.1.2.3")
+	libbar.Output("lib64/libbar.so.1.2")
+	if dev := libbar.MaybeOutput("lib64/libbar.so"); dev.Rule != nil {
+		t.Errorf("unexpected development symlink for libbar")
+	}
+
+	// The core device variant is not versioned.
+	device := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	android.AssertStringDoesNotContain(t, "device soname",
+		device.Rule("ld").Args["ldFlags"], "-Wl,-soname,libfoo.so.")
+}
+
+func TestLibraryVersionErrors(t *testing.T) {
+	t.Parallel()
+	PrepareForIntegrationTestWithCc.
+		ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+			`library_version.soname_version: "2" is not a prefix of the version "1.2.3"`)).
+		RunTestWithBp(t, `
+		cc_library_host_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			compile_multilib: "64",
+			library_version: {
+				version: "1.2.3",
+				soname_version: "2",
+			},
+		}`)
+}
+
 func TestLibraryDynamicList(t *testing.T) {
 	t.Parallel()
 	result := PrepareForIntegrationTestWithCc.RunTestWithBp(t, `
@@ -588,3 +649,67 @@ func TestWholeStaticLibPrebuilts(t *testing.T) {
 	android.AssertStringDoesContain(t, "missing flag for baz.o",
 		libtransitiveWithSrcs.Args["arObjs"], bazObj.Output.String())
 }
+
+func TestLibraryIdentityNote(t *testing.T) {
+	t.Parallel()
+	bp := `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			min_sdk_version: "29",
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddTextFile("system/foo/Android.bp", bp),
+		android.FixtureAddFile("system/foo/foo.c", nil),
+	)
+
+	result := preparer.RunTest(t)
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	note := android.ContentFromFileRuleForTests(t, libfoo.Output("identity_note/identity_note.S"))
+	android.AssertStringDoesContain(t, "identity note", note, `.section .note.android.ident,"a",%note`)
+	android.AssertStringDoesContain(t, "identity note", note, `.ascii "module=libfoo\0"`)
+	android.AssertStringDoesContain(t, "identity note", note, `.ascii "apexes=\0"`)
+	android.AssertStringDoesContain(t, "identity note", note, `.ascii "min_sdk=29\0"`)
+
+	noteObj := libfoo.Output("obj/identity_note/identity_note.o")
+	android.AssertStringListContains(t, "link inputs", libfoo.Rule("ld").Inputs.Strings(), noteObj.Output.String())
+
+	t.Run("disabled", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparer,
+			android.FixtureMergeEnv(map[string]string{"SOONG_DISABLE_IDENTITY_NOTES": "true"}),
+		).RunTest(t)
+		libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+		android.AssertBoolEquals(t, "has identity note", false, libfoo.MaybeOutput("identity_note/identity_note.S").Rule != nil)
+	})
+
+	t.Run("outside of the identity note dirs", func(t *testing.T) {
+		result := prepareForCcTest.RunTestWithBp(t, bp)
+		libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+		android.AssertBoolEquals(t, "has identity note", false, libfoo.MaybeOutput("identity_note/identity_note.S").Rule != nil)
+	})
+}
+
+func TestLibraryIfuncVerify(t *testing.T) {
+	t.Parallel()
+	result := prepareForCcTest.RunTestWithBp(t, `
+		cc_library_shared {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			sdk_version: "current",
+		}
+	`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared")
+	verify := libfoo.Rule("verifyIfunc")
+	ld := libfoo.Rule("ld")
+	android.AssertPathRelativeToTopEquals(t, "verified library", ld.Output.String(), verify.Input)
+	android.AssertStringListContains(t, "link validations", ld.Validations.Strings(), verify.Output.String())
+
+	for _, variant := range []string{"android_arm_armv7-a-neon_shared", "android_arm64_armv8-a_sdk_shared"} {
+		m := result.ModuleForTests("libfoo", variant)
+		android.AssertBoolEquals(t, variant+" verifies ifunc resolvers", false, m.MaybeRule("verifyIfunc").Rule != nil)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"regexp"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
+)
+
+// This file contains support for versioned shared libraries, i.e. shared libraries installed as
+// libfoo.so.1.2.3 with the soname libfoo.so.1, and the libfoo.so.1 and libfoo.so symlinks to it,
+// as expected by host tools and vendor libraries that are also built outside of the platform.
+
+type LibraryVersionProperties struct {
+	// The version of the shared library, e.g. "1.2.3". The library is installed as
+	// libfoo.so.<version>. Versioned libraries are only supported on Linux hosts and for the vendor
+	// and product variants, the other variants are built and installed without a version.
+	Version *string
+
+	// The version in the soname of the library, i.e. libfoo.so.<soname_version>. It must be a
+	// prefix of version, and defaults to the first component of version.
+	Soname_version *string
+
+	// Whether to install the libfoo.so symlink used to link against the library outside of the
+	// build. Defaults to true.
+	Dev_symlink *bool
+}
+
+var libraryVersionRegexp = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// libraryVersion returns the version and the soname version of the library, or empty strings if
+// the library is not versioned in this variant. It reports invalid versions, so it is only called
+// once per variant, by linkerFlags, which records the result for installVersioned.
+func (library *libraryDecorator) libraryVersion(ctx BaseModuleContext) (version, sonameVersion string) {
+	props := library.Properties.Library_version
+	version = proptools.String(props.Version)
+	if version == "" || !library.shared() || library.buildStubs() {
+		return "", ""
+	}
+	if !(ctx.Host() && ctx.Os().Linux()) && !(ctx.Device() && (ctx.inVendor() || ctx.inProduct())) {
+		return "", ""
+	}
+
+	if !libraryVersionRegexp.MatchString(version) {
+		ctx.PropertyErrorf("library_version.version", "%q is not a valid version, expected e.g. \"1.2.3\"", version)
+		return "", ""
+	}
+
+	sonameVersion = proptools.String(props.Soname_version)
+	if sonameVersion == "" {
+		sonameVersion = strings.SplitN(version, ".", 2)[0]
+	} else if sonameVersion != version && !strings.HasPrefix(version, sonameVersion+".") {
+		ctx.PropertyErrorf("library_version.soname_version", "%q is not a prefix of the version %q", sonameVersion, version)
+		return "", ""
+	}
+
+	return version, sonameVersion
+}
+
+// installVersioned installs the versioned library as libfoo.so.<version>, with the soname
+// symlink and, unless disabled, the development symlink to it. It returns false if the library is
+// not versioned in this variant.
+func (library *libraryDecorator) installVersioned(ctx ModuleContext, file android.Path) bool {
+	version, sonameVersion := library.version, library.sonameVersion
+	if version == "" {
+		return false
+	}
+
+	dir := library.baseInstaller.installDir(ctx)
+	installed := ctx.InstallFile(dir, file.Base()+"."+version, file)
+	if sonameVersion != version {
+		ctx.InstallSymlink(dir, file.Base()+"."+sonameVersion, installed)
+	}
+	if proptools.BoolDefault(library.Properties.Library_version.Dev_symlink, true) {
+		ctx.InstallSymlink(dir, file.Base(), installed)
+	}
+	library.baseInstaller.path = installed
+
+	return true
+}
@@ -244,6 +244,9 @@ type BaseLinkerProperties struct {
 
 	// list of shared libs that should not be used to build this module
 	Exclude_shared_libs []string `android:"arch_variant"`
+
+	// post-link transform applied to the linked output of binaries and shared libraries.
+	Linker_wrapper LinkerWrapperProperties
 }
 
 func (blp *BaseLinkerProperties) crt() bool {
@@ -313,6 +316,8 @@ func (linker *baseLinker) linkerDeps(ctx DepsContext, deps Deps) Deps {
 		deps.WholeStaticLibs = append(deps.WholeStaticLibs, "libbuildversion")
 	}
 
+	deps.LinkerWrapper = String(linker.Properties.Linker_wrapper.Tool)
+
 	if ctx.inVendor() {
 		deps.SharedLibs = append(deps.SharedLibs, linker.Properties.Target.Vendor.Shared_libs...)
 		deps.SharedLibs = removeListFromList(deps.SharedLibs, linker.Properties.Target.Vendor.Exclude_shared_libs)
@@ -482,6 +487,10 @@ func (linker *baseLinker) linkerFlags(ctx ModuleContext, flags Flags) Flags {
 	} else {
 		flags.Global.LdFlags = append(flags.Global.LdFlags, fmt.Sprintf("${config.%sGlobalLdflags}", hod))
 	}
+	if ctx.Device() {
+		flags.Global.LdFlags = append(flags.Global.LdFlags,
+			config.DeviceBuildIdLdflag(ctx.Config(), buildIdPartition(ctx)))
+	}
 	if Bool(linker.Properties.Allow_undefined_symbols) {
 		if ctx.Darwin() {
 			// darwin defaults to treating undefined symbols as errors
@@ -679,3 +688,18 @@ func (linker *baseLinker) injectVersionSymbol(ctx ModuleContext, in android.Path
 		},
 	})
 }
+
+// buildIdPartition returns the partition whose build id style applies to the module.
+func buildIdPartition(ctx BaseModuleContext) string {
+	partition := ctx.Module().PartitionTag(ctx.DeviceConfig())
+	if partition == "system" {
+		// The vendor and product variants of the modules available to vendor or product are
+		// installed in those partitions.
+		if ctx.inVendor() {
+			partition = "vendor"
+		} else if ctx.inProduct() {
+			partition = "product"
+		}
+	}
+	return partition
+}
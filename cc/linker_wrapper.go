@@ -0,0 +1,112 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+// This file contains support for the linker_wrapper property, which runs a host tool on the
+// output of the link step before it is stripped, e.g. for prelink-like optimizers, pac-ret
+// rewriters or vendor signing tools.
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+type LinkerWrapperProperties struct {
+	// name of a host tool module that transforms the linked output. The tool runs on the
+	// unstripped output, so the stripped output and the symbols both contain its changes.
+	Tool *string
+
+	// arguments passed to the tool. $(in) expands to the linked input file, $(out) to the file
+	// that the tool must write and $(location <file>) to the path of a file listed in
+	// tool_files. If neither $(in) nor $(out) is used, the input and output files are appended
+	// to the arguments.
+	Args []string
+
+	// extra files read by the tool, e.g. configuration or signing keys.
+	Tool_files []string `android:"path"`
+}
+
+type linkerWrapperDependencyTag struct {
+	blueprint.BaseDependencyTag
+	android.LicenseAnnotationToolchainDependencyTag
+}
+
+// The tool is not a part of the module's runtime, so it must not end up in an APEX.
+func (linkerWrapperDependencyTag) ExcludeFromApexContents() {}
+
+var linkerWrapperDepTag = linkerWrapperDependencyTag{}
+
+var _ android.ExcludeFromApexContentsTag = linkerWrapperDepTag
+
+// linkerWrapperToolPath returns the path to the host tool used as the linker wrapper.
+func linkerWrapperToolPath(ctx android.ModuleContext, dep android.Module) android.OptionalPath {
+	if h, ok := dep.(android.HostToolProvider); ok && h.HostToolPath().Valid() {
+		return h.HostToolPath()
+	}
+	ctx.PropertyErrorf("linker_wrapper.tool", "module %q is not a host tool", ctx.OtherModuleName(dep))
+	return android.OptionalPath{}
+}
+
+// transformLinkerWrapper registers a build statement that runs the linker wrapper tool on the
+// linked file in inputFile and writes the transformed file to outputFile.
+func transformLinkerWrapper(ctx ModuleContext, tool android.Path, props *LinkerWrapperProperties,
+	inputFile android.Path, outputFile android.WritablePath) {
+
+	toolFiles := android.PathsForModuleSrc(ctx, props.Tool_files)
+
+	usesInOrOut := false
+	args := make([]string, 0, len(props.Args))
+	for _, arg := range props.Args {
+		expanded, err := android.Expand(arg, func(name string) (string, error) {
+			switch name {
+			case "in":
+				usesInOrOut = true
+				return inputFile.String(), nil
+			case "out":
+				usesInOrOut = true
+				return outputFile.String(), nil
+			}
+			if strings.HasPrefix(name, "location ") {
+				label := strings.TrimSpace(strings.TrimPrefix(name, "location "))
+				if !android.InList(label, props.Tool_files) {
+					return "", fmt.Errorf("%q is not listed in linker_wrapper.tool_files", label)
+				}
+				return android.PathForModuleSrc(ctx, label).String(), nil
+			}
+			return "", fmt.Errorf("unknown variable '$(%s)'", name)
+		})
+		if err != nil {
+			ctx.PropertyErrorf("linker_wrapper.args", "%s", err.Error())
+			return
+		}
+		args = append(args, expanded)
+	}
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	cmd := rule.Command().
+		Tool(tool).
+		Flags(args).
+		Implicits(toolFiles)
+	if usesInOrOut {
+		cmd.Implicit(inputFile).ImplicitOutput(outputFile)
+	} else {
+		cmd.Input(inputFile).Output(outputFile)
+	}
+	rule.Build("linker_wrapper", "linker wrapper "+outputFile.Base())
+}
@@ -105,7 +105,8 @@ func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
 		if lto.ThinLTO() {
 			// TODO(b/129607781) sdclang does not currently support
 			// the "-fsplit-lto-unit" option
-			if flags.Sdclang && !strings.Contains(config.SDClangPath, "9.0") {
+			sdclangConfig, _ := config.SDClang(ctx.Config())
+			if flags.Sdclang && !strings.Contains(sdclangConfig.Path, "9.0") {
 				ltoCFlag = "-flto=thin"
 			} else {
 				ltoCFlag = "-flto=thin -fsplit-lto-unit"
@@ -143,17 +144,8 @@ func (lto *lto) flags(ctx BaseModuleContext, flags Flags) Flags {
 			flags.Local.CFlags = append(flags.Local.CFlags, "-fwhole-program-vtables")
 		}
 
-		if (lto.DefaultThinLTO(ctx) || lto.ThinLTO()) && ctx.Config().IsEnvTrue("USE_THINLTO_CACHE") && lto.useClangLld(ctx) {
-			// Set appropriate ThinLTO cache policy
-			cacheDirFormat := "-Wl,--thinlto-cache-dir="
-			cacheDir := android.PathForOutput(ctx, "thinlto-cache").String()
-			flags.Local.LdFlags = append(flags.Local.LdFlags, cacheDirFormat+cacheDir)
-
-			// Limit the size of the ThinLTO cache to the lesser of 10% of available
-			// disk space and 10GB.
-			cachePolicyFormat := "-Wl,--thinlto-cache-policy="
-			policy := "cache_size=10%:cache_size_bytes=10g"
-			flags.Local.LdFlags = append(flags.Local.LdFlags, cachePolicyFormat+policy)
+		if (lto.DefaultThinLTO(ctx) || lto.ThinLTO()) && config.ThinLTOCacheEnabled(ctx.Config()) && lto.useClangLld(ctx) {
+			flags.Local.LdFlags = append(flags.Local.LdFlags, "${config.ThinLTOCacheLdflags}")
 		}
 
 		// If the module does not have a profile, be conservative and limit cross TU inline
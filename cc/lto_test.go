@@ -237,3 +237,56 @@ func TestLtoDoesNotPropagateToRuntimeLibs(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "got flag for LTO in runtime_lib",
 		libBar.Args["ldFlags"], "-flto=thin")
 }
+
+func TestThinLtoCache(t *testing.T) {
+	t.Parallel()
+	bp := `
+	cc_library_shared {
+		name: "libfoo",
+		srcs: ["foo.c"],
+		lto: {
+			thin: true,
+		},
+	}`
+
+	enableCache := android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.ThinLTOCache = BoolPtr(true)
+	})
+
+	for _, tc := range []struct {
+		name      string
+		preparers []android.FixturePreparer
+		cache     bool
+	}{
+		{
+			name: "disabled",
+		},
+		{
+			name:      "enabled by the product",
+			preparers: []android.FixturePreparer{enableCache},
+			cache:     true,
+		},
+		{
+			name:      "enabled by the environment",
+			preparers: []android.FixturePreparer{android.FixtureMergeEnv(map[string]string{"USE_THINLTO_CACHE": "true"})},
+			cache:     true,
+		},
+		{
+			name: "disabled by the environment",
+			preparers: []android.FixturePreparer{
+				enableCache,
+				android.FixtureMergeEnv(map[string]string{"USE_THINLTO_CACHE": "false"}),
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := android.GroupFixturePreparers(
+				append([]android.FixturePreparer{NoGlobalThinLTOPreparer}, tc.preparers...)...,
+			).RunTestWithBp(t, bp)
+
+			libFoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("ld")
+			android.AssertStringContainsEquals(t, "ThinLTO cache flags", libFoo.Args["ldFlags"],
+				"${config.ThinLTOCacheLdflags}", tc.cache)
+		})
+	}
+}
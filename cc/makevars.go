@@ -16,6 +16,7 @@ package cc
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -52,17 +53,6 @@ func makeStringOfKeys(ctx android.MakeVarsContext, key android.OnceKey) string {
 	return strings.Join(keys, " ")
 }
 
-func makeStringOfWarningAllowedProjects() string {
-	allProjects := append([]string{}, config.WarningAllowedProjects...)
-	sort.Strings(allProjects)
-	// Makefile rules use pattern "path/%" to match module paths.
-	if len(allProjects) > 0 {
-		return strings.Join(allProjects, "% ") + "%"
-	} else {
-		return ""
-	}
-}
-
 type notOnHostContext struct {
 }
 
@@ -98,7 +88,10 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 	ctx.Strict("GLOBAL_CLANG_CFLAGS_NO_OVERRIDE", "${config.NoOverrideGlobalCflags}")
 	ctx.Strict("GLOBAL_CLANG_CFLAGS_64_NO_OVERRIDE", "${config.NoOverride64GlobalCflags}")
 	ctx.Strict("GLOBAL_CLANG_CPPFLAGS_NO_OVERRIDE", "")
+	ctx.Strict("GLOBAL_CLANG_VENDOR_CFLAGS_NO_OVERRIDE", "${config.NoOverrideVendorGlobalCflags}")
 	ctx.Strict("GLOBAL_CLANG_EXTERNAL_CFLAGS_NO_OVERRIDE", "${config.NoOverrideExternalGlobalCflags}")
+	ctx.Strict("THINLTO_CACHE_DIR", "${config.ThinLTOCacheDir}")
+	ctx.Strict("THINLTO_CACHE_LDFLAGS", "${config.ThinLTOCacheLdflags}")
 
 	ctx.Strict("BOARD_VNDK_VERSION", ctx.DeviceConfig().VndkVersion())
 	ctx.Strict("RECOVERY_SNAPSHOT_VERSION", ctx.DeviceConfig().RecoverySnapshotVersion())
@@ -122,7 +115,27 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 	sort.Strings(lsdumpPaths)
 	ctx.Strict("LSDUMP_PATHS", strings.Join(lsdumpPaths, " "))
 
-	ctx.Strict("ANDROID_WARNING_ALLOWED_PROJECTS", makeStringOfWarningAllowedProjects())
+	// The files of the warning overrides are read outside of the build actions.
+	overrides, err := warningOverrides(ctx.Config())
+	if err != nil {
+		ctx.Errorf("warning overrides: %s", err)
+	}
+	if android.ExistentPathForSource(ctx, config.WarningOverridesFile).Valid() {
+		ctx.AddNinjaFileDeps(config.WarningOverridesFile)
+	}
+	ctx.AddNinjaFileDeps(ctx.Config().WarningOverridesFiles()...)
+
+	if file := ctx.Config().ClangConfigFile(); file != "" {
+		// The options of the file are read by Soong, which reruns when it changes.
+		ctx.AddNinjaFileDeps(file)
+		if !config.ClangConfigFile(ctx).Valid() {
+			ctx.Errorf("ClangConfigFile: %s does not exist", file)
+		} else if _, err := config.ClangConfigFileFlags(ctx.Config()); err != nil {
+			ctx.Errorf("%s", err)
+		}
+	}
+	ctx.Strict("ANDROID_WARNING_ALLOWED_PROJECTS", makeStringOfWarningAllowedProjects(overrides))
+	ctx.Strict("CLANG_WARNING_POLICY_EXCEPTIONS", strings.Join(config.WarningPolicyExceptions(overrides), ", "))
 	ctx.Strict("SOONG_MODULES_WARNINGS_ALLOWED", makeStringOfKeys(ctx, modulesWarningsAllowedKey))
 	ctx.Strict("SOONG_MODULES_USING_WNO_ERROR", makeStringOfKeys(ctx, modulesUsingWnoErrorKey))
 	ctx.Strict("SOONG_MODULES_MISSING_PGO_PROFILE_FILE", makeStringOfKeys(ctx, modulesMissingProfileFileKey))
@@ -184,11 +197,24 @@ func makeVarsProvider(ctx android.MakeVarsContext) {
 }
 
 func sdclangMakeVars(ctx android.MakeVarsContext) {
-	if config.ForceSDClangOff {
-		ctx.Strict("FORCE_SDCLANG_OFF", strconv.FormatBool(config.ForceSDClangOff))
+	// An invalid SD Clang config file disables SDClang, the error is reported once here rather than
+	// by each module.
+	sdclangConfig, err := config.SDClang(ctx.Config())
+	if err != nil {
+		ctx.Errorf("%s", err)
+	}
+	if sdclangConfig.ForceOff {
+		ctx.Strict("FORCE_SDCLANG_OFF", strconv.FormatBool(sdclangConfig.ForceOff))
+	}
+	if sdclangConfig.SDClang {
+		ctx.Strict("SDCLANG", strconv.FormatBool(sdclangConfig.SDClang))
 	}
-	if config.SDClang {
-		ctx.Strict("SDCLANG", strconv.FormatBool(config.SDClang))
+	for _, env := range []string{"SDCLANG_CONFIG", "SDCLANG_AE_CONFIG"} {
+		if configPath := ctx.Config().Getenv(env); configPath != "" {
+			if _, err := os.Stat(configPath); err == nil {
+				ctx.AddNinjaFileDeps(configPath)
+			}
+		}
 	}
 	ctx.Strict("SDCLANG_PATH", "${config.SDClangBin}")
 	ctx.Strict("SDCLANG_COMMON_FLAGS", "${config.SDClangFlags}")
@@ -244,11 +270,18 @@ func makeVarsToolchain(ctx android.MakeVarsContext, secondPrefix string,
 	clangPrefix := secondPrefix + "CLANG_" + typePrefix
 
 	ctx.Strict(clangPrefix+"TRIPLE", toolchain.ClangTriple())
+	clangConfigFlags := ""
+	buildIdLdflags := ""
+	if target.Os.Class == android.Device {
+		clangConfigFlags = "${config.DeviceClangConfigFlags}"
+		buildIdLdflags = "${config.DeviceBuildIdLdflags}"
+	}
 	ctx.Strict(clangPrefix+"GLOBAL_CFLAGS", strings.Join([]string{
 		toolchain.Cflags(),
 		"${config.CommonGlobalCflags}",
 		fmt.Sprintf("${config.%sGlobalCflags}", hod),
 		toolchain.ToolchainCflags(),
+		clangConfigFlags,
 		productExtraCflags,
 	}, " "))
 	ctx.Strict(clangPrefix+"GLOBAL_CPPFLAGS", strings.Join([]string{
@@ -258,12 +291,14 @@ func makeVarsToolchain(ctx android.MakeVarsContext, secondPrefix string,
 	}, " "))
 	ctx.Strict(clangPrefix+"GLOBAL_LDFLAGS", strings.Join([]string{
 		fmt.Sprintf("${config.%sGlobalLdflags}", hod),
+		buildIdLdflags,
 		toolchain.Ldflags(),
 		toolchain.ToolchainLdflags(),
 		productExtraLdflags,
 	}, " "))
 	ctx.Strict(clangPrefix+"GLOBAL_LLDFLAGS", strings.Join([]string{
 		fmt.Sprintf("${config.%sGlobalLldflags}", hod),
+		buildIdLdflags,
 		toolchain.Lldflags(),
 		toolchain.ToolchainLdflags(),
 		productExtraLdflags,
@@ -319,7 +354,7 @@ func makeVarsToolchain(ctx android.MakeVarsContext, secondPrefix string,
 		ctx.Strict(makePrefix+"OTOOL", "${config.MacToolPath}/otool")
 		ctx.Strict(makePrefix+"STRIP", "${config.MacStripPath}")
 	} else {
-		if config.SDClang {
+		if sdclangConfig, _ := config.SDClang(ctx.Config()); sdclangConfig.SDClang {
 			ctx.Strict(makePrefix+"AR", "${config.SDClangBin}/llvm-ar")
 			ctx.Strict(makePrefix+"READELF", "${config.SDClangBin}/llvm-readelf")
 			ctx.Strict(makePrefix+"NM", "${config.SDClangBin}/llvm-nm")
@@ -0,0 +1,130 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
+)
+
+// This file contains support for selecting the memory tagging mode of the heap per module with
+// sanitize.memtag_heap_mode, and the report of the modules whose mode is still selected by the
+// MemtagHeapSyncIncludePaths and MemtagHeapAsyncIncludePaths lists of the product, which the
+// property replaces.
+
+const (
+	memtagHeapModeSync  = "sync"
+	memtagHeapModeAsync = "async"
+	memtagHeapModeOff   = "off"
+)
+
+var memtagHeapModes = []string{memtagHeapModeSync, memtagHeapModeAsync, memtagHeapModeOff}
+
+func init() {
+	registerMemtagHeapBuildComponents(android.InitRegistrationContext)
+}
+
+func registerMemtagHeapBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("memtag_heap_report", memtagHeapReportSingletonFactory)
+}
+
+var prepareForTestWithMemtagHeapReport = android.FixtureRegisterWithContext(registerMemtagHeapBuildComponents)
+
+// setMemtagHeapMode sets the memtag_heap and diag.memtag_heap properties for the given mode. It
+// returns false if the mode is not known, which for the product default mode is reported once by
+// memtagHeapReportSingleton. An empty mode leaves the properties untouched.
+func setMemtagHeapMode(s *sanitizeMutatedProperties, mode string) bool {
+	switch mode {
+	case "":
+	case memtagHeapModeSync:
+		s.Memtag_heap = proptools.BoolPtr(true)
+		s.Diag.Memtag_heap = proptools.BoolPtr(true)
+	case memtagHeapModeAsync:
+		s.Memtag_heap = proptools.BoolPtr(true)
+		s.Diag.Memtag_heap = proptools.BoolPtr(false)
+	case memtagHeapModeOff:
+		s.Memtag_heap = proptools.BoolPtr(false)
+		s.Diag.Memtag_heap = proptools.BoolPtr(false)
+	default:
+		return false
+	}
+	return true
+}
+
+// applyMemtagHeapMode applies the sanitize.memtag_heap_mode property.
+func (sanitize *sanitize) applyMemtagHeapMode(ctx BaseModuleContext) {
+	userProps := &sanitize.Properties.Sanitize
+	if userProps.Memtag_heap_mode == nil {
+		return
+	}
+	if userProps.Memtag_heap != nil || userProps.Diag.Memtag_heap != nil {
+		ctx.PropertyErrorf("sanitize.memtag_heap_mode",
+			"cannot be combined with sanitize.memtag_heap or sanitize.diag.memtag_heap")
+		return
+	}
+	if !setMemtagHeapMode(&sanitize.Properties.SanitizeMutated, *userProps.Memtag_heap_mode) {
+		ctx.PropertyErrorf("sanitize.memtag_heap_mode", "unknown mode %q, expected one of %s",
+			*userProps.Memtag_heap_mode, strings.Join(memtagHeapModes, ", "))
+	}
+}
+
+func memtagHeapReportSingletonFactory() android.Singleton {
+	return &memtagHeapReportSingleton{}
+}
+
+type memtagHeapReportSingleton struct {
+	report android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*memtagHeapReportSingleton)(nil)
+
+// GenerateBuildActions validates the product default mode and writes the list of modules, with
+// their directory and mode, that are still governed by the memtag heap path lists of the product
+// to out/soong/memtag_heap_path_lists_report.txt, to help with the migration to
+// sanitize.memtag_heap_mode.
+func (s *memtagHeapReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if mode := ctx.Config().MemtagHeapDefaultMode(); mode != "" && !android.InList(mode, memtagHeapModes) {
+		ctx.Errorf("unknown MemtagHeapDefaultMode %q, expected one of %s", mode, strings.Join(memtagHeapModes, ", "))
+		return
+	}
+
+	lines := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || c.sanitize == nil || !c.sanitize.Properties.MemtagHeapFromPathLists {
+			return
+		}
+		mode := memtagHeapModeAsync
+		if Bool(c.sanitize.Properties.SanitizeMutated.Diag.Memtag_heap) {
+			mode = memtagHeapModeSync
+		}
+		lines[fmt.Sprintf("%s %s %s", ctx.ModuleDir(module), ctx.ModuleName(module), mode)] = true
+	})
+
+	report := android.PathForOutput(ctx, "memtag_heap_path_lists_report.txt")
+	android.WriteFileRule(ctx, report, strings.Join(android.SortedKeys(lines), "\n"))
+	s.report = report
+}
+
+func (s *memtagHeapReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report == nil {
+		return
+	}
+	ctx.DistForGoal("droidcore", s.report)
+}
@@ -264,6 +264,10 @@ type SanitizeUserProps struct {
 	// Memory-tagging, only available on arm64
 	// if diag.memtag unset or false, enables async memory tagging
 	Memtag_heap *bool `android:"arch_variant"`
+	// Memory-tagging mode, only available on arm64. One of "sync", "async" or "off".
+	// Takes precedence over the product defaults and cannot be combined with memtag_heap or
+	// diag.memtag_heap.
+	Memtag_heap_mode *string `android:"arch_variant"`
 	// Memory-tagging stack instrumentation, only available on arm64
 	// Adds instrumentation to detect stack buffer overflows and use-after-scope using MTE.
 	Memtag_stack *bool `android:"arch_variant"`
@@ -306,6 +310,10 @@ type SanitizeUserProps struct {
 
 	// value to pass to -fsanitize-ignorelist
 	Blocklist *string
+
+	// UBSan checks of the UBSan minimal runtime policy of the partition of the module that are not
+	// enabled for this module, e.g. ["bounds"].
+	Ubsan_policy_exclude []string `android:"arch_variant"`
 }
 
 type sanitizeMutatedProperties struct {
@@ -374,6 +382,12 @@ type SanitizeProperties struct {
 	InSanitizerDir    bool     `blueprint:"mutated"`
 	Sanitizers        []string `blueprint:"mutated"`
 	DiagSanitizers    []string `blueprint:"mutated"`
+
+	// Whether the memory tagging mode was set by the legacy memtag heap path lists of the product.
+	MemtagHeapFromPathLists bool `blueprint:"mutated"`
+
+	// The partition whose UBSan minimal runtime policy applies to the module, if any.
+	UbsanPolicyPartition string `blueprint:"mutated"`
 }
 
 type sanitize struct {
@@ -462,6 +476,8 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		return
 	}
 
+	sanitize.applyMemtagHeapMode(ctx)
+
 	// cc_test targets default to SYNC MemTag unless explicitly set to ASYNC (via diag: {memtag_heap: false}).
 	if ctx.testBinary() {
 		if s.Memtag_heap == nil {
@@ -582,6 +598,7 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		if ctx.Config().MemtagHeapSyncEnabledForPath(ctx.ModuleDir()) {
 			if s.Memtag_heap == nil {
 				s.Memtag_heap = proptools.BoolPtr(true)
+				sanitize.Properties.MemtagHeapFromPathLists = true
 			}
 			if s.Diag.Memtag_heap == nil {
 				s.Diag.Memtag_heap = proptools.BoolPtr(true)
@@ -589,7 +606,10 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		} else if ctx.Config().MemtagHeapAsyncEnabledForPath(ctx.ModuleDir()) {
 			if s.Memtag_heap == nil {
 				s.Memtag_heap = proptools.BoolPtr(true)
+				sanitize.Properties.MemtagHeapFromPathLists = true
 			}
+		} else if s.Memtag_heap == nil && !ctx.Config().MemtagHeapDisabledForPath(ctx.ModuleDir()) {
+			setMemtagHeapMode(s, ctx.Config().MemtagHeapDefaultMode())
 		}
 	}
 
@@ -599,6 +619,9 @@ func (sanitize *sanitize) begin(ctx BaseModuleContext) {
 		s.Hwaddress = proptools.BoolPtr(true)
 	}
 
+	sanitize.Properties.UbsanPolicyPartition = applyUbsanMinimalRuntimePolicy(ctx, s,
+		sanitize.Properties.Sanitize.Ubsan_policy_exclude)
+
 	if s.Integer_overflow == nil && ctx.Config().IntegerOverflowEnabledForPath(ctx.ModuleDir()) && ctx.Arch().ArchType == android.Arm64 {
 		s.Integer_overflow = proptools.BoolPtr(true)
 	}
@@ -23,6 +23,7 @@ import (
 	"android/soong/android"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 var prepareForAsanTest = android.FixtureAddFile("asan/Android.bp", []byte(`
@@ -1017,6 +1018,119 @@ func TestSanitizeMemtagHeap(t *testing.T) {
 	checkHasMemtagNote(t, ctx.ModuleForTests("unset_test_override_default_sync", variant), Sync)
 }
 
+func TestSanitizeMemtagHeapMode(t *testing.T) {
+	t.Parallel()
+	variant := "android_arm64_armv8-a"
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddTextFile("subdir_sync/Android.bp", `
+			cc_binary {
+				name: "path_list_binary",
+			}
+
+			cc_binary {
+				name: "path_list_mode_off_binary",
+				sanitize: { memtag_heap_mode: "off" },
+			}
+		`),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.MemtagHeapSyncIncludePaths = []string{"subdir_sync"}
+			variables.MemtagHeapDefaultMode = proptools.StringPtr("async")
+		}),
+	).RunTestWithBp(t, `
+		cc_binary {
+			name: "sync_binary",
+			sanitize: { memtag_heap_mode: "sync" },
+		}
+
+		cc_binary {
+			name: "off_binary",
+			sanitize: { memtag_heap_mode: "off" },
+		}
+
+		cc_binary {
+			name: "default_binary",
+		}
+	`)
+
+	checkMode := func(name string, expectedMemtag, expectedDiag, expectedFromPathLists bool) {
+		t.Helper()
+		s := result.ModuleForTests(name, variant).Module().(*Module).sanitize.Properties
+		android.AssertBoolEquals(t, name+" memtag_heap", expectedMemtag, Bool(s.SanitizeMutated.Memtag_heap))
+		android.AssertBoolEquals(t, name+" diag.memtag_heap", expectedDiag, Bool(s.SanitizeMutated.Diag.Memtag_heap))
+		android.AssertBoolEquals(t, name+" from path lists", expectedFromPathLists, s.MemtagHeapFromPathLists)
+	}
+
+	checkMode("sync_binary", true, true, false)
+	checkMode("off_binary", false, false, false)
+	checkMode("default_binary", true, false, false)
+	checkMode("path_list_binary", true, true, true)
+	checkMode("path_list_mode_off_binary", false, false, false)
+}
+
+func TestSanitizeMemtagHeapModeErrors(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`"conflicting_binary" .*: sanitize.memtag_heap_mode: cannot be combined with sanitize.memtag_heap`,
+		`"unknown_binary" .*: sanitize.memtag_heap_mode: unknown mode "strict"`,
+	})).RunTestWithBp(t, `
+		cc_binary {
+			name: "conflicting_binary",
+			sanitize: { memtag_heap: true, memtag_heap_mode: "sync" },
+		}
+
+		cc_binary {
+			name: "unknown_binary",
+			sanitize: { memtag_heap_mode: "strict" },
+		}
+	`)
+}
+
+func TestSanitizeMemtagHeapDefaultModeError(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithMemtagHeapReport,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.MemtagHeapDefaultMode = proptools.StringPtr("strict")
+		}),
+	).ExtendWithErrorHandler(android.FixtureExpectsOneErrorPattern(
+		`unknown MemtagHeapDefaultMode "strict", expected one of sync, async, off`,
+	)).RunTestWithBp(t, `
+		cc_binary {
+			name: "default_binary",
+		}
+	`)
+}
+
+func TestSanitizeMemtagHeapPathListsReport(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithMemtagHeapReport,
+		android.FixtureAddTextFile("subdir_async/Android.bp", `
+			cc_binary {
+				name: "path_list_binary",
+			}
+		`),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.MemtagHeapAsyncIncludePaths = []string{"subdir_async"}
+		}),
+	).RunTestWithBp(t, `
+		cc_binary {
+			name: "mode_binary",
+			sanitize: { memtag_heap_mode: "sync" },
+		}
+	`)
+
+	report := result.SingletonForTests("memtag_heap_report").Output("memtag_heap_path_lists_report.txt")
+	android.AssertStringEquals(t, "report", "subdir_async path_list_binary async",
+		android.ContentFromFileRuleForTests(t, report))
+}
+
 func TestSanitizeMemtagHeapWithSanitizeDevice(t *testing.T) {
 	t.Skip("TODO(b/249094918) re-enable after clang version brought back in-line with upstream")
 	t.Parallel()
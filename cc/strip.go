@@ -41,12 +41,23 @@ type StripProperties struct {
 
 		// keep_symbols_and_debug_frame enables stripping but keeps all symbols and debug frames.
 		Keep_symbols_and_debug_frame *bool `android:"arch_variant"`
+
+		// split_debug enables stripping of a host module, and moves its debug info into a separate
+		// <file>.debug file that the stripped file points to with a .gnu_debuglink section. The
+		// .debug file is installed in the parallel debug directory of the host out, which mirrors
+		// the layout of the host out as /usr/lib/debug mirrors the root directory, e.g.
+		// debug/bin/foo.debug for bin/foo, so that the host tools can be symbolized without
+		// installing them unstripped. Only supported for the Linux host modules.
+		Split_debug *bool `android:"arch_variant"`
 	} `android:"arch_variant"`
 }
 
 // Stripper defines the stripping actions and properties for a module.
 type Stripper struct {
 	StripProperties StripProperties
+
+	// The split debug file of the stripped executable or shared library, if split_debug is set.
+	splitDebugFile android.OptionalPath
 }
 
 // NeedsStrip determines if stripping is required for a module.
@@ -55,17 +66,33 @@ func (stripper *Stripper) NeedsStrip(actx android.ModuleContext) bool {
 	defaultEnable := (!actx.Config().KatiEnabled() || actx.Device())
 	forceEnable := Bool(stripper.StripProperties.Strip.All) ||
 		Bool(stripper.StripProperties.Strip.Keep_symbols) ||
-		Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame)
+		Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame) ||
+		Bool(stripper.StripProperties.Strip.Split_debug)
 	return !forceDisable && (forceEnable || defaultEnable)
 }
 
 // Keep this consistent with //build/bazel/rules/stripped_shared_library.bzl.
 func (stripper *Stripper) strip(actx android.ModuleContext, in android.Path, out android.ModuleOutPath,
 	flags StripFlags, isStaticLib bool) {
+	splitDebug := Bool(stripper.StripProperties.Strip.Split_debug)
+	if splitDebug && actx.Device() {
+		actx.PropertyErrorf("strip.split_debug", "split debug files are only supported for host modules")
+		splitDebug = false
+	}
+	if splitDebug && actx.Darwin() {
+		actx.PropertyErrorf("strip.split_debug", "split debug files are not supported for Darwin")
+		splitDebug = false
+	}
+
 	if actx.Darwin() {
 		transformDarwinStrip(actx, in, out)
 	} else {
-		if Bool(stripper.StripProperties.Strip.Keep_symbols) {
+		var splitDebugFile android.WritablePath
+		if splitDebug && !isStaticLib {
+			debugFile := out.InSameDir(actx, out.Base()+".debug")
+			stripper.splitDebugFile = android.OptionalPathForPath(debugFile)
+			splitDebugFile = debugFile
+		} else if Bool(stripper.StripProperties.Strip.Keep_symbols) {
 			flags.StripKeepSymbols = true
 		} else if Bool(stripper.StripProperties.Strip.Keep_symbols_and_debug_frame) {
 			flags.StripKeepSymbolsAndDebugFrame = true
@@ -74,11 +101,22 @@ func (stripper *Stripper) strip(actx android.ModuleContext, in android.Path, out
 		} else if !Bool(stripper.StripProperties.Strip.All) {
 			flags.StripKeepMiniDebugInfo = true
 		}
-		if actx.Config().Debuggable() && !flags.StripKeepMiniDebugInfo && !isStaticLib {
+		if actx.Config().Debuggable() && !flags.StripKeepMiniDebugInfo && !isStaticLib && splitDebugFile == nil {
 			flags.StripAddGnuDebuglink = true
 		}
-		transformStrip(actx, in, out, flags)
+		transformStrip(actx, in, out, flags, splitDebugFile)
+	}
+}
+
+// installSplitDebugFile installs the split debug file of the stripped executable or shared library,
+// if any, in the parallel debug directory of the host out, under the same relative directory as the
+// executable or shared library that is installed in installDir.
+func (stripper *Stripper) installSplitDebugFile(ctx android.ModuleContext, installDir android.InstallPath) {
+	if !stripper.splitDebugFile.Valid() {
+		return
 	}
+	debugFile := stripper.splitDebugFile.Path()
+	ctx.InstallFile(android.PathForModuleInstall(ctx, "debug", installDir.Rel()), debugFile.Base(), debugFile)
 }
 
 // StripExecutableOrSharedLib strips a binary or shared library from its debug
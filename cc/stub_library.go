@@ -22,13 +22,23 @@ import (
 )
 
 func init() {
+	registerStubLibrariesBuildComponents(android.InitRegistrationContext)
+}
+
+func registerStubLibrariesBuildComponents(ctx android.RegistrationContext) {
 	// Use singleton type to gather all generated soong modules.
-	android.RegisterSingletonType("stublibraries", stubLibrariesSingleton)
+	ctx.RegisterSingletonType("stublibraries", stubLibrariesSingleton)
 }
 
+var prepareForTestWithStubLibraries = android.FixtureRegisterWithContext(registerStubLibrariesBuildComponents)
+
 type stubLibraries struct {
 	stubLibraryMap map[string]bool
 
+	// The file names of the libraries of the system partition that declare stubs or LLNDK stubs and
+	// that the product installs, except the bootstrap libraries of the runtime APEX.
+	systemProvideLibMap map[string]bool
+
 	apiListCoverageXmlPaths []string
 }
 
@@ -37,6 +47,23 @@ func IsStubTarget(m *Module) bool {
 	return m.IsStubs() || m.HasStubsVariants()
 }
 
+// declaresSystemStubs returns true if the module is the implementation of a library that declares
+// stubs or LLNDK stubs, i.e. a library that the system partition provides to the other linker
+// namespaces.
+func declaresSystemStubs(m *Module) bool {
+	return !m.IsStubs() && (m.HasStubsVariants() || m.HasLlndkStubs())
+}
+
+var systemProvideLibsPathKey = android.NewOnceKey("systemProvideLibsPath")
+
+// SystemProvideLibsPath returns the path of the list of the file names of the libraries of the
+// system partition that declare stubs or LLNDK stubs and that the product installs, one per line.
+func SystemProvideLibsPath(ctx android.PathContext) android.OutputPath {
+	return ctx.Config().Once(systemProvideLibsPathKey, func() interface{} {
+		return android.PathForOutput(ctx, "linkerconfig", "system_provide_libs.txt")
+	}).(android.OutputPath)
+}
+
 // Get target file name to be installed from this module
 func getInstalledFileName(m *Module) string {
 	for _, ps := range m.PackagingSpecs() {
@@ -48,14 +75,36 @@ func getInstalledFileName(m *Module) string {
 }
 
 func (s *stubLibraries) GenerateBuildActions(ctx android.SingletonContext) {
+	// Only the libraries that the product installs are provided, as with the systemprovide command
+	// of conv_linker_config. Make sets the PRODUCT_PACKAGES of the product, without them, e.g. in
+	// tests, all the libraries are provided.
+	productPackages := ctx.Config().ProductPackages()
+	installed := make(map[string]bool)
+	var systemStubLibs []android.PackagingSpec
+
 	// Visit all generated soong modules and store stub library file names.
 	ctx.VisitAllModules(func(module android.Module) {
+		if productPackages != nil && module.Enabled() && android.IsModulePreferred(module) &&
+			android.InList(android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName(module)), productPackages) {
+			for _, ps := range module.TransitivePackagingSpecs() {
+				installed[ps.Partition()+":"+ps.RelPathInPackage()] = true
+			}
+		}
 		if m, ok := module.(*Module); ok {
 			if IsStubTarget(m) {
 				if name := getInstalledFileName(m); name != "" {
 					s.stubLibraryMap[name] = true
 				}
 			}
+			// The bootstrap libraries of the system partition are only used by the processes that
+			// start before the runtime APEX is mounted, the other processes use the ones of the APEX.
+			if declaresSystemStubs(m) && !InstallToBootstrap(m.BaseModuleName(), ctx.Config()) {
+				for _, ps := range m.PackagingSpecs() {
+					if ps.FileName() != "" && ps.Partition() == "system" {
+						systemStubLibs = append(systemStubLibs, ps)
+					}
+				}
+			}
 			if m.library != nil {
 				if p := m.library.getAPIListCoverageXMLPath().String(); p != "" {
 					s.apiListCoverageXmlPaths = append(s.apiListCoverageXmlPaths, p)
@@ -63,11 +112,22 @@ func (s *stubLibraries) GenerateBuildActions(ctx android.SingletonContext) {
 			}
 		}
 	})
+
+	for _, ps := range systemStubLibs {
+		if productPackages == nil || installed[ps.Partition()+":"+ps.RelPathInPackage()] {
+			s.systemProvideLibMap[ps.FileName()] = true
+		}
+	}
+
+	// The list is read by the linker_config modules that generate their provideLibs from it.
+	android.WriteFileRule(ctx, SystemProvideLibsPath(ctx),
+		strings.Join(android.SortedKeys(s.systemProvideLibMap), "\n"))
 }
 
 func stubLibrariesSingleton() android.Singleton {
 	return &stubLibraries{
-		stubLibraryMap: make(map[string]bool),
+		stubLibraryMap:      make(map[string]bool),
+		systemProvideLibMap: make(map[string]bool),
 	}
 }
 
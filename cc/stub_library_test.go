@@ -0,0 +1,59 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSystemProvideLibs(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithStubLibraries,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.ProductPackages = []string{"foo_bin", "libc"}
+		}),
+	).RunTestWithBp(t, `
+		cc_library {
+			name: "libfoo",
+			srcs: ["foo.c"],
+			stubs: {
+				versions: ["29"],
+			},
+		}
+
+		cc_library {
+			name: "libbar",
+			srcs: ["bar.c"],
+			stubs: {
+				versions: ["29"],
+			},
+		}
+
+		cc_binary {
+			name: "foo_bin",
+			srcs: ["main.c"],
+			shared_libs: ["libfoo"],
+		}
+	`)
+
+	// libbar is not installed by the product, and libc is a bootstrap library of the runtime APEX.
+	libs := result.SingletonForTests("stublibraries").Output("linkerconfig/system_provide_libs.txt")
+	android.AssertStringEquals(t, "system provide libs", "libfoo.so",
+		android.ContentFromFileRuleForTests(t, libs))
+}
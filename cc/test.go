@@ -19,6 +19,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
@@ -129,6 +130,8 @@ func init() {
 	android.RegisterModuleType("cc_benchmark", BenchmarkFactory)
 	android.RegisterModuleType("cc_test_host", TestHostFactory)
 	android.RegisterModuleType("cc_benchmark_host", BenchmarkHostFactory)
+
+	pctx.HostBinToolVariable("benchmarkResultsCmd", "benchmark_results")
 }
 
 // cc_test generates a test config file and an executable binary file to test
@@ -192,6 +195,14 @@ func (test *testBinary) dataPaths() []android.DataPath {
 	return test.data
 }
 
+func (test *testBinary) testSuites() []string {
+	return test.testDecorator.InstallerProperties.Test_suites
+}
+
+func (test *testBinary) testConfigPath() android.Path {
+	return test.testConfig
+}
+
 func (test *testBinary) isAllTestsVariation() bool {
 	stem := test.binaryDecorator.Properties.Stem
 	return stem != nil && *stem == ""
@@ -392,6 +403,11 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 	useVendor := ctx.inVendor() || ctx.useVndk()
 	testInstallBase := getTestInstallBase(useVendor)
 	configs := getTradefedConfigOptions(ctx, &test.Properties, test.isolated(ctx))
+	runtime := ctx.Config().HostTestRuntime(ctx.Target())
+	if runtime != "" {
+		// Record the host runtime so that the test configs of the runtimes are distinct.
+		configs = append(configs, tradefed.Option{Name: "config-descriptor:metadata", Key: "host-runtime", Value: runtime})
+	}
 
 	test.testConfig = tradefed.AutoGenTestConfig(ctx, tradefed.AutoGenTestConfigOptions{
 		TestConfigProp:         test.Properties.Test_config,
@@ -419,6 +435,14 @@ func (test *testBinary) install(ctx ModuleContext, file android.Path) {
 		test.Properties.Test_options.Unit_test = proptools.BoolPtr(true)
 	}
 	test.binaryDecorator.baseInstaller.install(ctx, file)
+
+	if runtime != "" {
+		ctx.SetProvider(HostTestRuntimeInfoProvider, HostTestRuntimeInfo{
+			Runtime:    runtime,
+			Binary:     file,
+			TestConfig: test.testConfig,
+		})
+	}
 }
 
 func getTestInstallBase(useVendor bool) string {
@@ -570,6 +594,12 @@ type BenchmarkProperties struct {
 	// doesn't exist next to the Android.bp, this attribute doesn't need to be set to true
 	// explicitly.
 	Auto_gen_config *bool
+
+	// JSON file of the golden ranges of the real time of the benchmarks, keyed by the names of the
+	// benchmarks, e.g. {"BM_foo": {"min_real_time_ns": 100, "max_real_time_ns": 200}}. The golden
+	// ranges are validated at build time and installed with the benchmark, and the test harness
+	// fails the benchmark if its real time is out of its golden range.
+	Golden_ranges *string `android:"path"`
 }
 
 type benchmarkDecorator struct {
@@ -583,6 +613,14 @@ func (benchmark *benchmarkDecorator) benchmarkBinary() bool {
 	return true
 }
 
+func (benchmark *benchmarkDecorator) testSuites() []string {
+	return benchmark.Properties.Test_suites
+}
+
+func (benchmark *benchmarkDecorator) testConfigPath() android.Path {
+	return benchmark.testConfig
+}
+
 func (benchmark *benchmarkDecorator) linkerProps() []interface{} {
 	props := benchmark.binaryDecorator.linkerProps()
 	props = append(props, &benchmark.Properties)
@@ -602,6 +640,7 @@ func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Pat
 	if Bool(benchmark.Properties.Require_root) {
 		configs = append(configs, tradefed.Object{"target_preparer", "com.android.tradefed.targetprep.RootTargetPreparer", nil})
 	}
+	configs = append(configs, benchmark.resultsConfig(ctx))
 	benchmark.testConfig = tradefed.AutoGenTestConfig(ctx, tradefed.AutoGenTestConfigOptions{
 		TestConfigProp:         benchmark.Properties.Test_config,
 		TestConfigTemplateProp: benchmark.Properties.Test_config_template,
@@ -617,6 +656,47 @@ func (benchmark *benchmarkDecorator) install(ctx ModuleContext, file android.Pat
 	benchmark.binaryDecorator.baseInstaller.install(ctx, file)
 }
 
+var benchmarkResultsSchema = pctx.AndroidStaticRule("benchmarkResultsSchema",
+	blueprint.RuleParams{
+		Command:     "$benchmarkResultsCmd -schema $out $goldenFlag",
+		CommandDeps: []string{"$benchmarkResultsCmd"},
+	}, "goldenFlag")
+
+// resultsConfig generates the JSON schema of the results of the benchmark, and returns the tradefed
+// config of the post processor that converts the output of the benchmark into results in that
+// schema, and compares them against the golden ranges of the module if it has any. The schema and
+// the golden ranges are installed with the benchmark, the golden ranges are validated when the
+// schema is generated.
+func (benchmark *benchmarkDecorator) resultsConfig(ctx ModuleContext) tradefed.Config {
+	schema := android.PathForModuleOut(ctx, "benchmark_results_schema.json")
+	options := []tradefed.Option{
+		{Name: "results-tool", Value: "benchmark_results"},
+		{Name: "results-schema", Value: schema.Base()},
+	}
+
+	var implicits android.Paths
+	goldenFlag := ""
+	if benchmark.Properties.Golden_ranges != nil {
+		golden := android.PathForModuleSrc(ctx, *benchmark.Properties.Golden_ranges)
+		implicits = append(implicits, golden)
+		goldenFlag = "-golden " + golden.String()
+		benchmark.data = append(benchmark.data, golden)
+		options = append(options, tradefed.Option{Name: "golden-ranges", Value: golden.Rel()})
+	}
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        benchmarkResultsSchema,
+		Description: "benchmark results schema " + ctx.ModuleName(),
+		Implicits:   implicits,
+		Output:      schema,
+		Args: map[string]string{
+			"goldenFlag": goldenFlag,
+		},
+	})
+	benchmark.data = append(benchmark.data, schema)
+
+	return tradefed.Object{"metric_post_processor", "com.android.tradefed.postprocessor.BenchmarkResultsPostProcessor", options}
+}
+
 func NewBenchmark(hod android.HostOrDeviceSupported) *Module {
 	module, binary := newBinary(hod, false)
 	module.multilib = android.MultilibBoth
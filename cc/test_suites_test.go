@@ -0,0 +1,85 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestSoongPackagedTestSuites(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.PrepareForTestWithTestSuites,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.SoongPackagedTestSuites = []string{"general-tests"}
+		}),
+	).RunTestWithBp(t, `
+		cc_test {
+			name: "foo",
+			srcs: ["foo.cpp"],
+			test_suites: ["general-tests"],
+			gtest: false,
+		}
+
+		cc_test_host {
+			name: "bar",
+			srcs: ["bar.cpp"],
+			test_suites: ["general-tests"],
+			gtest: false,
+		}
+
+		cc_test {
+			name: "baz",
+			srcs: ["baz.cpp"],
+			test_suites: ["device-tests"],
+			gtest: false,
+		}
+	`)
+
+	singleton := result.SingletonForTests("testsuites")
+
+	zip := singleton.Output("out/soong/target/product/test_device/general-tests.zip")
+	android.AssertStringDoesContain(t, "general-tests.zip packages foo in its arch directory", zip.RuleParams.Command,
+		"-P target/testcases/foo/arm64 -C out/soong/target/product/test_device/data/nativetest64/foo "+
+			"-r out/soong/packaging/general-tests_rsp/target/testcases/foo/arm64.rsp")
+	android.AssertStringDoesContain(t, "general-tests.zip packages bar in its arch directory", zip.RuleParams.Command,
+		"-P host/testcases/bar/x86_64 -C out/soong/host/linux-x86/nativetest64/bar "+
+			"-r out/soong/packaging/general-tests_rsp/host/testcases/bar/x86_64.rsp")
+	android.AssertStringDoesContain(t, "general-tests.zip packages the test config of foo", zip.RuleParams.Command,
+		"-P target/testcases/foo -C out/soong/packaging/general-tests_configs/target/testcases/foo "+
+			"-f out/soong/packaging/general-tests_configs/target/testcases/foo/foo.config")
+	android.AssertStringDoesNotContain(t, "general-tests.zip does not package baz", zip.RuleParams.Command,
+		"testcases/baz")
+
+	config := singleton.Output("packaging/general-tests_configs/host/testcases/bar/bar.config")
+	android.AssertStringEquals(t, "test config of bar", "bar.config", config.Input.Base())
+
+	configs := singleton.Output("out/soong/target/product/test_device/general-tests_configs.zip")
+	android.AssertStringDoesContain(t, "general-tests_configs.zip packages the configs", configs.RuleParams.Command,
+		"-C out/soong/packaging/general-tests_configs -r out/soong/packaging/general-tests_configs.rsp")
+	android.AssertPathsRelativeToTopEquals(t, "general-tests_configs.zip rsp file inputs", []string{
+		"out/soong/packaging/general-tests_configs/host/testcases/bar/bar.config",
+		"out/soong/packaging/general-tests_configs/target/testcases/foo/foo.config",
+	}, configs.Inputs)
+
+	list := singleton.Output("packaging/general-tests_list/general-tests_list")
+	android.AssertStringEquals(t, "general-tests_list",
+		"host/testcases/bar/bar.config\ntarget/testcases/foo/foo.config\n",
+		android.ContentFromFileRuleForTests(t, list))
+	singleton.Output("out/soong/target/product/test_device/general-tests_list.zip")
+}
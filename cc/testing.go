@@ -38,6 +38,7 @@ func RegisterRequiredBuildComponentsForTest(ctx android.RegistrationContext) {
 	ctx.RegisterModuleType("cc_benchmark", BenchmarkFactory)
 	ctx.RegisterModuleType("cc_object", ObjectFactory)
 	ctx.RegisterModuleType("cc_genrule", GenRuleFactory)
+	ctx.RegisterModuleType("cc_config_header", ConfigHeaderFactory)
 	ctx.RegisterModuleType("ndk_prebuilt_shared_stl", NdkPrebuiltSharedStlFactory)
 	ctx.RegisterModuleType("ndk_prebuilt_static_stl", NdkPrebuiltStaticStlFactory)
 	ctx.RegisterModuleType("ndk_library", NdkLibraryFactory)
@@ -437,6 +438,7 @@ func commonDefaultModules() string {
 
 		cc_library_static {
 			name: "libgoogle-benchmark",
+			host_supported: true,
 			sdk_version: "current",
 			stl: "none",
 			system_shared_libs: [],
@@ -650,7 +652,11 @@ var PrepareForTestWithCcIncludeVndk = android.GroupFixturePreparers(
 var PrepareForTestWithHostMusl = android.GroupFixturePreparers(
 	android.FixtureModifyConfig(android.ModifyTestConfigForMusl),
 	android.PrepareForSkipTestOnMac,
-	android.FixtureAddTextFile("external/musl/Android.bp", `
+	prepareForTestWithMuslModules,
+)
+
+// prepareForTestWithMuslModules adds the musl modules that are needed to link against musl libc.
+var prepareForTestWithMuslModules = android.FixtureAddTextFile("external/musl/Android.bp", `
 		cc_defaults {
 			name: "libc_musl_crt_defaults",
 			host_supported: true,
@@ -681,8 +687,7 @@ var PrepareForTestWithHostMusl = android.GroupFixturePreparers(
 			name: "libc_musl_crtend",
 			defaults: ["libc_musl_crt_defaults"],
 		}
-	`),
-)
+	`)
 
 // PrepareForTestWithFdoProfile registers module types to test with fdo_profile
 var PrepareForTestWithFdoProfile = android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
@@ -0,0 +1,159 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+// This file contains support for rolling out clang's thread-safety analysis incrementally: the
+// analysis is enabled as an error for the modules in the ThreadSafetyAnalysisIncludePaths of the
+// product, and the thread_safety_report target lists, for the modules in those paths and in the
+// ThreadSafetyReportPaths, how many lines declare or take locks and how many lines carry
+// thread-safety annotations. Modules with locks but no annotations are the candidates for the next
+// paths to enforce the analysis for.
+
+var (
+	threadSafetyAnalysisFlags = []string{
+		"-Wthread-safety",
+		"-Werror=thread-safety",
+	}
+
+	// Lines that declare a lock or take one.
+	threadSafetyLockPattern = `std::(mutex|shared_mutex|recursive_mutex|timed_mutex|lock_guard|unique_lock|scoped_lock)|` +
+		`pthread_(mutex|rwlock)_(t|lock|rdlock|wrlock)\b|\b(Mutex|RWLock)(::Autolock)?\b|\bAutoMutex\b`
+
+	// Lines that carry a thread-safety annotation, either with the macros of
+	// android-base/thread_annotations.h or with the attributes themselves.
+	threadSafetyAnnotationPattern = `\b(PT_)?GUARDED_BY\(|` +
+		`\b(REQUIRES|REQUIRES_SHARED|ACQUIRE|ACQUIRE_SHARED|RELEASE|RELEASE_SHARED|EXCLUDES|` +
+		`CAPABILITY|SCOPED_CAPABILITY|RETURN_CAPABILITY|NO_THREAD_SAFETY_ANALYSIS)\b|` +
+		`__attribute__\(\((pt_)?guarded_by|_capability\(|locks_excluded\(`
+
+	threadSafetyReportRule = pctx.AndroidStaticRule("threadSafetyReport",
+		blueprint.RuleParams{
+			Command: `echo "$dir $module $analysis ` +
+				`$$(cat $in | grep -cE '` + threadSafetyLockPattern + `') ` +
+				`$$(cat $in | grep -cE '` + threadSafetyAnnotationPattern + `')" > $out`,
+		},
+		"dir", "module", "analysis")
+
+	threadSafetyReportSrcExts = []string{".c", ".cc", ".cpp", ".cxx", ".c++", ".h", ".hh", ".hpp"}
+)
+
+func init() {
+	android.RegisterSingletonType("thread_safety_report", threadSafetyReportSingletonFactory)
+}
+
+// threadSafetyFlags enables the thread-safety analysis as an error if it is enforced for the
+// directory of the module.
+func threadSafetyFlags(ctx ModuleContext, flags Flags) Flags {
+	if ctx.Config().ThreadSafetyAnalysisEnforcedForPath(ctx.ModuleDir()) {
+		flags.Local.CFlags = append(flags.Local.CFlags, threadSafetyAnalysisFlags...)
+	}
+	return flags
+}
+
+// buildThreadSafetyReport writes the line of the module in the thread-safety annotation coverage
+// report, if the report covers the directory of the module.
+func buildThreadSafetyReport(ctx ModuleContext, srcs android.Paths) android.Path {
+	if !ctx.Config().ThreadSafetyReportEnabledForPath(ctx.ModuleDir()) {
+		return nil
+	}
+	var inputs android.Paths
+	for _, src := range srcs {
+		if android.InList(src.Ext(), threadSafetyReportSrcExts) {
+			inputs = append(inputs, src)
+		}
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	analysis := "unenforced"
+	if ctx.Config().ThreadSafetyAnalysisEnforcedForPath(ctx.ModuleDir()) {
+		analysis = "enforced"
+	}
+
+	report := android.PathForModuleOut(ctx, "thread_safety_report.txt")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        threadSafetyReportRule,
+		Description: "thread-safety report " + ctx.ModuleName(),
+		Inputs:      inputs,
+		Output:      report,
+		Args: map[string]string{
+			"dir":      ctx.ModuleDir(),
+			"module":   ctx.ModuleName(),
+			"analysis": analysis,
+		},
+	})
+	return report
+}
+
+func threadSafetyReportSingletonFactory() android.Singleton {
+	return &threadSafetyReportSingleton{}
+}
+
+type threadSafetyReportSingleton struct {
+	report android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*threadSafetyReportSingleton)(nil)
+
+// GenerateBuildActions merges the lines of the modules into out/soong/thread_safety_report.txt,
+// built by the thread_safety_report target. Each line contains the directory and the name of a
+// module, whether the analysis is enforced for it, and its numbers of lock lines and of annotated
+// lines. The variants of a module with the same sources share a line.
+func (s *threadSafetyReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var reports android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() {
+			return
+		}
+		if compiler, ok := c.compiler.(interface{ threadSafetyReport() android.Path }); ok {
+			if report := compiler.threadSafetyReport(); report != nil {
+				reports = append(reports, report)
+			}
+		}
+	})
+	if len(reports) == 0 {
+		return
+	}
+
+	report := android.PathForOutput(ctx, "thread_safety_report.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("(echo").
+		Text(proptools.ShellEscape("# directory module analysis lock_lines annotated_lines")).
+		Text("&& cat").
+		Inputs(android.SortedUniquePaths(reports)).
+		Text("| sort -u)").
+		FlagWithOutput("> ", report)
+	rule.Build("thread_safety_report", "thread-safety annotation coverage report")
+
+	ctx.Phony("thread_safety_report", report)
+	s.report = report
+}
+
+func (s *threadSafetyReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report == nil {
+		return
+	}
+	ctx.DistForGoal("thread_safety_report", s.report)
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestThreadSafetyAnalysis(t *testing.T) {
+	t.Parallel()
+	variant := "android_arm64_armv8-a_shared"
+
+	bp := `
+		cc_library_shared {
+			name: "%s",
+			srcs: ["foo.cpp", "foo.s"],
+		}
+	`
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureAddTextFile("enforced/Android.bp", fmt.Sprintf(bp, "libenforced")),
+		android.FixtureAddTextFile("enforced/excluded/Android.bp", fmt.Sprintf(bp, "libexcluded")),
+		android.FixtureAddTextFile("reported/Android.bp", fmt.Sprintf(bp, "libreported")),
+		android.FixtureAddTextFile("other/Android.bp", fmt.Sprintf(bp, "libother")),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.ThreadSafetyAnalysisIncludePaths = []string{"enforced"}
+			variables.ThreadSafetyAnalysisExcludePaths = []string{"enforced/excluded"}
+			variables.ThreadSafetyReportPaths = []string{"reported"}
+		}),
+	).RunTest(t)
+
+	checkModule := func(dir, name string, enforced bool, analysis string) {
+		t.Helper()
+		module := result.ModuleForTests(name, variant)
+		cFlags := module.Rule("cc").Args["cFlags"]
+		if enforced {
+			android.AssertStringDoesContain(t, name+" cflags", cFlags, "-Werror=thread-safety")
+		} else {
+			android.AssertStringDoesNotContain(t, name+" cflags", cFlags, "-Wthread-safety")
+		}
+
+		report := module.MaybeRule("threadSafetyReport")
+		if analysis == "" {
+			android.AssertBoolEquals(t, name+" has report", false, report.Rule != nil)
+			return
+		}
+		android.AssertStringEquals(t, name+" report analysis", analysis, report.Args["analysis"])
+		android.AssertPathsRelativeToTopEquals(t, name+" report inputs",
+			[]string{dir + "/foo.cpp"}, report.Inputs)
+	}
+
+	checkModule("enforced", "libenforced", true, "enforced")
+	checkModule("enforced/excluded", "libexcluded", false, "")
+	checkModule("reported", "libreported", false, "unenforced")
+	checkModule("other", "libother", false, "")
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"encoding/json"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+// This singleton records the values of the environment variables that affect the toolchain in
+// $OUT_DIR/soong/toolchain_env.json. Reading them through the config makes soong_build rerun when
+// they change, and as ninja rebuilds the manifest only when its contents change, the compile and
+// link rules that depend on it are rebuilt exactly when one of the values changes. The manifest
+// also records what was used to build an image.
+
+func init() {
+	registerToolchainEnvBuildComponents(android.InitRegistrationContext)
+}
+
+func registerToolchainEnvBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("toolchain_env", toolchainEnvSingletonFactory)
+}
+
+var prepareForTestWithToolchainEnv = android.FixtureRegisterWithContext(registerToolchainEnvBuildComponents)
+
+func toolchainEnvSingletonFactory() android.Singleton {
+	return &toolchainEnvSingleton{}
+}
+
+type toolchainEnvSingleton struct {
+	manifest android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*toolchainEnvSingleton)(nil)
+
+func (s *toolchainEnvSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	env := make(map[string]string, len(config.ToolchainEnvVars))
+	for _, name := range config.ToolchainEnvVars {
+		env[name] = ctx.Config().Getenv(name)
+	}
+
+	// json.Marshal sorts the keys of maps, so the contents are stable.
+	content, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the toolchain environment: %s", err)
+		return
+	}
+
+	manifest := config.ToolchainEnvManifestPath(ctx)
+	android.WriteFileRule(ctx, manifest, string(content))
+	s.manifest = manifest
+}
+
+func (s *toolchainEnvSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.manifest == nil {
+		return
+	}
+	ctx.DistForGoal("droidcore", s.manifest)
+}
@@ -0,0 +1,206 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// This file contains support for enabling UBSan checks in production builds with the
+// UbsanMinimalRuntimePolicies of the product, which select the checks per partition. The checks
+// are compiled in the release mode, so the modules link the minimal UBSan runtime, which aborts on
+// the first error instead of printing diagnostics. The ubsan_minimal_runtime_report target lists
+// the binaries and shared libraries covered by a policy, with the checks they are compiled with and
+// the UBSan runtime they link.
+
+var ubsanMinimalRuntimeChecks = []string{"bounds", "integer-overflow"}
+
+func init() {
+	registerUbsanMinimalRuntimeBuildComponents(android.InitRegistrationContext)
+}
+
+func registerUbsanMinimalRuntimeBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("ubsan_minimal_runtime_report", ubsanMinimalRuntimeReportSingletonFactory)
+}
+
+var prepareForTestWithUbsanMinimalRuntimeReport = android.FixtureRegisterWithContext(registerUbsanMinimalRuntimeBuildComponents)
+
+// parseUbsanMinimalRuntimePolicies parses the <partition>:<check>[,<check>...] entries of the
+// UbsanMinimalRuntimePolicies of the product, and returns the UBSan checks to enable keyed by
+// partition, where * is the policy of all the partitions.
+func parseUbsanMinimalRuntimePolicies(entries []string) (map[string][]string, error) {
+	policies := make(map[string][]string)
+	for _, entry := range entries {
+		partition, value, ok := strings.Cut(entry, ":")
+		if !ok || partition == "" || value == "" {
+			return nil, fmt.Errorf("invalid UBSan policy %q, expected <partition>:<check>[,<check>...]", entry)
+		}
+		checks := strings.Split(value, ",")
+		for _, check := range checks {
+			if !android.InList(check, ubsanMinimalRuntimeChecks) {
+				return nil, fmt.Errorf("invalid check %q in UBSan policy %q, expected one of %s",
+					check, entry, strings.Join(ubsanMinimalRuntimeChecks, ", "))
+			}
+		}
+		if _, exists := policies[partition]; exists {
+			return nil, fmt.Errorf("more than one UBSan policy for partition %q", partition)
+		}
+		policies[partition] = checks
+	}
+	return policies, nil
+}
+
+var ubsanMinimalRuntimePoliciesKey = android.NewOnceKey("ubsanMinimalRuntimePolicies")
+
+type ubsanMinimalRuntimePolicies struct {
+	policies map[string][]string
+	err      error
+}
+
+// getUbsanMinimalRuntimePolicies returns the UbsanMinimalRuntimePolicies of the product, which are
+// only parsed once.
+func getUbsanMinimalRuntimePolicies(config android.Config) (map[string][]string, error) {
+	p := config.Once(ubsanMinimalRuntimePoliciesKey, func() interface{} {
+		policies, err := parseUbsanMinimalRuntimePolicies(config.UbsanMinimalRuntimePolicies())
+		return ubsanMinimalRuntimePolicies{policies: policies, err: err}
+	}).(ubsanMinimalRuntimePolicies)
+	return p.policies, p.err
+}
+
+// applyUbsanMinimalRuntimePolicy enables the UBSan checks of the policy of the partition of the
+// device module, except those that the module excludes with ubsan_policy_exclude or, for
+// integer-overflow, disables with integer_overflow: false, and returns the partition, or an empty
+// string if no policy applies to the module.
+func applyUbsanMinimalRuntimePolicy(ctx BaseModuleContext, s *sanitizeMutatedProperties, exclude []string) string {
+	for _, check := range exclude {
+		if !android.InList(check, ubsanMinimalRuntimeChecks) {
+			ctx.PropertyErrorf("sanitize.ubsan_policy_exclude", "invalid check %q, expected one of %s",
+				check, strings.Join(ubsanMinimalRuntimeChecks, ", "))
+		}
+	}
+	if ctx.Host() || !ctx.toolchain().Bionic() {
+		return ""
+	}
+	// Invalid policies are reported by the ubsan_minimal_runtime_report singleton.
+	policies, err := getUbsanMinimalRuntimePolicies(ctx.Config())
+	if err != nil {
+		return ""
+	}
+	partition := buildIdPartition(ctx)
+	checks, ok := policies[partition]
+	if !ok {
+		checks, ok = policies["*"]
+	}
+	if !ok {
+		return ""
+	}
+
+	for _, check := range checks {
+		if android.InList(check, exclude) {
+			continue
+		}
+		switch check {
+		case "bounds":
+			if !inList("bounds", s.Misc_undefined) {
+				s.Misc_undefined = append(s.Misc_undefined, "bounds")
+			}
+		case "integer-overflow":
+			if s.Integer_overflow == nil {
+				s.Integer_overflow = proptools.BoolPtr(true)
+			}
+		}
+	}
+	return partition
+}
+
+// ubsanMinimalRuntimeStatus returns the UBSan checks of the policy that are enabled for the module
+// and the UBSan runtime that it links.
+func ubsanMinimalRuntimeStatus(s *sanitize) (checks []string, runtime string) {
+	props := &s.Properties.SanitizeMutated
+	if inList("bounds", props.Misc_undefined) {
+		checks = append(checks, "bounds")
+	}
+	if Bool(props.Integer_overflow) {
+		checks = append(checks, "integer-overflow")
+	}
+
+	switch {
+	case enableMinimalRuntime(s):
+		runtime = "minimal"
+	case enableUbsanRuntime(s):
+		runtime = "standalone"
+	default:
+		runtime = "none"
+	}
+	return checks, runtime
+}
+
+func ubsanMinimalRuntimeReportSingletonFactory() android.Singleton {
+	return &ubsanMinimalRuntimeReportSingleton{}
+}
+
+type ubsanMinimalRuntimeReportSingleton struct {
+	report android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*ubsanMinimalRuntimeReportSingleton)(nil)
+
+// GenerateBuildActions validates the UBSan policies of the product and writes the list of the
+// binaries and shared libraries covered by a policy, with their directory, partition, enabled
+// checks and UBSan runtime, to out/soong/ubsan_minimal_runtime_report.txt.
+func (s *ubsanMinimalRuntimeReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if len(ctx.Config().UbsanMinimalRuntimePolicies()) == 0 {
+		return
+	}
+	if _, err := getUbsanMinimalRuntimePolicies(ctx.Config()); err != nil {
+		ctx.Errorf("UbsanMinimalRuntimePolicies: %s", err)
+		return
+	}
+
+	lines := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		c, ok := module.(*Module)
+		if !ok || !c.Enabled() || c.sanitize == nil || !(c.Binary() || c.Shared()) {
+			return
+		}
+		partition := c.sanitize.Properties.UbsanPolicyPartition
+		if partition == "" {
+			return
+		}
+		checks, runtime := ubsanMinimalRuntimeStatus(c.sanitize)
+		if len(checks) == 0 {
+			checks = []string{"none"}
+		}
+		lines[fmt.Sprintf("%s %s %s %s %s", ctx.ModuleDir(module), ctx.ModuleName(module), partition,
+			strings.Join(checks, ","), runtime)] = true
+	})
+
+	report := android.PathForOutput(ctx, "ubsan_minimal_runtime_report.txt")
+	android.WriteFileRule(ctx, report, strings.Join(android.SortedKeys(lines), "\n"))
+	ctx.Phony("ubsan_minimal_runtime_report", report)
+	s.report = report
+}
+
+func (s *ubsanMinimalRuntimeReportSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.report == nil {
+		return
+	}
+	ctx.DistForGoal("droidcore", s.report)
+}
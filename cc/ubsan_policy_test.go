@@ -0,0 +1,155 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestUbsanMinimalRuntimePolicies(t *testing.T) {
+	t.Parallel()
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithUbsanMinimalRuntimeReport,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.UbsanMinimalRuntimePolicies = []string{"*:bounds", "vendor:bounds,integer-overflow"}
+		}),
+		android.FixtureAddTextFile("foo/Android.bp", `
+			cc_binary {
+				name: "bin_system",
+				srcs: ["foo.cpp"],
+			}
+
+			cc_library_shared {
+				name: "libvendor",
+				srcs: ["foo.cpp"],
+				vendor: true,
+			}
+
+			cc_library_shared {
+				name: "libvendor_no_overflow",
+				srcs: ["foo.cpp"],
+				vendor: true,
+				sanitize: {
+					integer_overflow: false,
+				},
+			}
+
+			cc_library_shared {
+				name: "libvendor_no_bounds",
+				srcs: ["foo.cpp"],
+				vendor: true,
+				sanitize: {
+					ubsan_policy_exclude: ["bounds"],
+				},
+			}
+
+			cc_binary {
+				name: "bin_never",
+				srcs: ["foo.cpp"],
+				sanitize: {
+					never: true,
+				},
+			}
+		`),
+	).RunTest(t)
+
+	minimalRuntime := result.ModuleForTests("libclang_rt.ubsan_minimal", "android_arm64_armv8-a_static").
+		OutputFiles(t, "")[0].String()
+
+	checkModule := func(name, variant string, expectedChecks, unexpectedChecks []string) {
+		t.Helper()
+		module := result.ModuleForTests(name, variant)
+		cFlags := module.Rule("cc").Args["cFlags"]
+		libFlags := strings.Split(module.Rule("ld").Args["libFlags"], " ")
+		for _, check := range expectedChecks {
+			android.AssertStringDoesContain(t, name+" cflags", cFlags, check)
+		}
+		for _, check := range unexpectedChecks {
+			android.AssertStringDoesNotContain(t, name+" cflags", cFlags, check)
+		}
+		if len(expectedChecks) > 0 {
+			android.AssertStringDoesContain(t, name+" cflags", cFlags, "-fsanitize-minimal-runtime")
+			android.AssertStringListContains(t, name+" static libs", libFlags, minimalRuntime)
+		} else {
+			android.AssertStringListDoesNotContain(t, name+" static libs", libFlags, minimalRuntime)
+		}
+	}
+
+	checkModule("bin_system", "android_arm64_armv8-a", []string{"bounds"}, []string{"integer-overflow"})
+	checkModule("libvendor", vendorVariant, []string{"bounds", "signed-integer-overflow"}, nil)
+	checkModule("libvendor_no_overflow", vendorVariant, []string{"bounds"}, []string{"integer-overflow"})
+	checkModule("libvendor_no_bounds", vendorVariant, []string{"signed-integer-overflow"}, []string{"bounds"})
+	checkModule("bin_never", "android_arm64_armv8-a", nil, []string{"bounds", "integer-overflow"})
+
+	report := result.SingletonForTests("ubsan_minimal_runtime_report").Output("ubsan_minimal_runtime_report.txt")
+	var lines []string
+	for _, line := range strings.Split(android.ContentFromFileRuleForTests(t, report), "\n") {
+		if strings.HasPrefix(line, "foo ") {
+			lines = append(lines, line)
+		}
+	}
+	android.AssertArrayString(t, "report", []string{
+		"foo bin_system system bounds minimal",
+		"foo libvendor vendor bounds,integer-overflow minimal",
+		"foo libvendor_no_bounds vendor integer-overflow minimal",
+		"foo libvendor_no_overflow vendor bounds minimal",
+	}, lines)
+}
+
+func TestUbsanMinimalRuntimePoliciesErrors(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		policy string
+		err    string
+	}{
+		{"system", `invalid UBSan policy "system"`},
+		{"system:", `invalid UBSan policy "system:"`},
+		{"system:bounds,null", `invalid check "null" in UBSan policy "system:bounds,null"`},
+	} {
+		t.Run(tc.policy, func(t *testing.T) {
+			android.GroupFixturePreparers(
+				prepareForCcTest,
+				prepareForTestWithUbsanMinimalRuntimeReport,
+				android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+					variables.UbsanMinimalRuntimePolicies = []string{tc.policy}
+				}),
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+				"UbsanMinimalRuntimePolicies: "+tc.err)).
+				RunTestWithBp(t, "")
+		})
+	}
+}
+
+func TestUbsanMinimalRuntimePolicyExcludeErrors(t *testing.T) {
+	t.Parallel()
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		prepareForTestWithUbsanMinimalRuntimeReport,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`sanitize.ubsan_policy_exclude: invalid check "null"`)).
+		RunTestWithBp(t, `
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["foo.cpp"],
+				sanitize: {
+					ubsan_policy_exclude: ["null"],
+				},
+			}
+		`)
+}
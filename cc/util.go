@@ -74,6 +74,8 @@ func flagsToBuilderFlags(in Flags) builderFlags {
 
 		assemblerWithCpp: in.AssemblerWithCpp,
 
+		precompiledHeader: in.PrecompiledHeader,
+
 		proto:            in.proto,
 		protoC:           in.protoC,
 		protoOptionsFile: in.protoOptionsFile,
@@ -0,0 +1,105 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/cc/config"
+)
+
+var warningOverridesKey = android.NewOnceKey("warningOverrides")
+
+type warningOverridesResult struct {
+	overrides []config.WarningOverride
+	err       error
+}
+
+// warningOverrides returns the warning overrides of the checked-in file, followed by the ones of
+// the files of the product. An error in one of the files is reported by makeVarsProvider, and
+// leaves the overrides of the files before it only.
+func warningOverrides(cfg android.Config) ([]config.WarningOverride, error) {
+	result := cfg.Once(warningOverridesKey, func() interface{} {
+		var overrides []config.WarningOverride
+		files := append([]string{config.WarningOverridesFile}, cfg.WarningOverridesFiles()...)
+		for i, file := range files {
+			path := file
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(android.AbsSrcDirForExistingUseCases(), path)
+			}
+			data, err := os.ReadFile(path)
+			if i == 0 && os.IsNotExist(err) {
+				// The checked-in file is not in the source tree of the tests.
+				continue
+			} else if err != nil {
+				return warningOverridesResult{overrides, err}
+			}
+			fileOverrides, err := config.ParseWarningOverrides(data)
+			if err != nil {
+				return warningOverridesResult{overrides, fmt.Errorf("%s: %s", file, err)}
+			}
+			overrides = append(overrides, fileOverrides...)
+		}
+		return warningOverridesResult{overrides, nil}
+	}).(warningOverridesResult)
+	return result.overrides, result.err
+}
+
+// warningOverridesForDir returns the overrides that apply to the modules in the directory.
+func warningOverridesForDir(cfg android.Config, dir string) []config.WarningOverride {
+	overrides, _ := warningOverrides(cfg)
+	return config.WarningOverridesForDir(overrides, dir)
+}
+
+// warningsAreAllowed returns true if the most specific of the overrides that sets allow_warnings
+// allows the warnings.
+func warningsAreAllowed(overrides []config.WarningOverride) bool {
+	allowed := false
+	for _, o := range overrides {
+		if o.AllowWarnings != nil {
+			allowed = *o.AllowWarnings
+		}
+	}
+	return allowed
+}
+
+// warningOverridesCflags returns the extra cflags of the overrides. The actions on the warnings of
+// the overrides are exceptions to the warning policy, which come after the global flags, see
+// transformSourceToObj.
+func warningOverridesCflags(overrides []config.WarningOverride) []string {
+	var flags []string
+	for _, o := range overrides {
+		flags = append(flags, o.Cflags...)
+	}
+	return flags
+}
+
+// makeStringOfWarningAllowedProjects returns the path prefixes of the overrides that allow the
+// warnings, as Make patterns.
+func makeStringOfWarningAllowedProjects(overrides []config.WarningOverride) string {
+	var allProjects []string
+	for _, o := range overrides {
+		if warningsAreAllowed(config.WarningOverridesForDir(overrides, strings.TrimSuffix(o.PathPrefix, "/"))) {
+			allProjects = append(allProjects, o.PathPrefix+"%")
+		}
+	}
+	sort.Strings(allProjects)
+	return strings.Join(android.FirstUniqueStrings(allProjects), " ")
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestWarningOverridesFiles(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "warning_overrides.json")
+	if err := os.WriteFile(file, []byte(`{
+		"overrides": [
+			{"path_prefix": "vendor/acme/", "allow_warnings": true, "warnings": {"unused-parameter": "error"}, "bug": "b/1"}
+		]
+	}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	result := android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.WarningOverridesFiles = []string{file}
+		}),
+		android.FixtureAddTextFile("vendor/acme/Android.bp", `
+			cc_library_shared {
+				name: "libacme",
+				srcs: ["acme.c"],
+			}
+		`),
+		android.FixtureAddTextFile("frameworks/foo/Android.bp", `
+			cc_library_shared {
+				name: "libfoo",
+				srcs: ["foo.c"],
+			}
+		`),
+	).RunTest(t)
+
+	acme := result.ModuleForTests("libacme", "android_arm64_armv8-a_shared").Rule("cc").Args["cFlags"]
+	android.AssertStringDoesContain(t, "libacme cflags", acme, "-Werror=unused-parameter")
+	// The actions on the warnings come after the global flags of the vendor projects, which disable
+	// -Wunused-parameter.
+	if strings.Index(acme, "-Werror=unused-parameter") < strings.Index(acme, "${config.NoOverrideVendorGlobalCflags}") {
+		t.Errorf("libacme cflags: expected -Werror=unused-parameter after the vendor global flags, got %q", acme)
+	}
+	android.AssertStringListDoesNotContain(t, "libacme cflags", strings.Fields(acme), "-Werror")
+
+	foo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_shared").Rule("cc").Args["cFlags"]
+	android.AssertStringDoesNotContain(t, "libfoo cflags", foo, "-Werror=unused-parameter")
+	android.AssertStringDoesNotContain(t, "libfoo cflags", foo, "${config.NoOverrideVendorGlobalCflags}")
+	android.AssertStringListContains(t, "libfoo cflags", strings.Fields(foo), "-Werror")
+}
+
+func TestWarningOverridesFilesErrors(t *testing.T) {
+	t.Parallel()
+	file := filepath.Join(t.TempDir(), "warning_overrides.json")
+	if err := os.WriteFile(file, []byte(`{"overrides": [{"path_prefix": "/vendor/"}]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	android.GroupFixturePreparers(
+		prepareForCcTest,
+		android.PrepareForTestWithMakevars,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.WarningOverridesFiles = []string{file}
+		}),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`warning overrides: .*warning_overrides.json: invalid path_prefix "/vendor/"`)).
+		RunTestWithBp(t, "")
+}
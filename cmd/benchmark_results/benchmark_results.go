@@ -0,0 +1,263 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// This tool converts the JSON output of a google-benchmark binary, as written with
+// --benchmark_format=json, into the results of a cc_benchmark module, and optionally compares them
+// against the golden ranges checked in next to the module. It fails if the real time of a
+// benchmark is out of its golden range, or if a benchmark with a golden range did not run. It is
+// run by the test harness after the benchmark, on the host.
+//
+// At build time, it writes the JSON schema of the results with -schema, and validates the golden
+// ranges of the module if they are given with -golden.
+
+// schemaVersion is the version of the results schema, incremented on incompatible changes.
+const schemaVersion = 1
+
+// benchmarkOutput is the JSON output of a google-benchmark binary.
+type benchmarkOutput struct {
+	Benchmarks []struct {
+		Name       string  `json:"name"`
+		RunType    string  `json:"run_type"`
+		Iterations int64   `json:"iterations"`
+		RealTime   float64 `json:"real_time"`
+		CpuTime    float64 `json:"cpu_time"`
+		TimeUnit   string  `json:"time_unit"`
+	} `json:"benchmarks"`
+}
+
+// benchmarkResults is the results of a cc_benchmark module.
+type benchmarkResults struct {
+	SchemaVersion int               `json:"schema_version"`
+	Module        string            `json:"module"`
+	Results       []benchmarkResult `json:"results"`
+
+	// The benchmarks whose real time is out of their golden range, or that did not run.
+	Regressions []string `json:"regressions,omitempty"`
+}
+
+type benchmarkResult struct {
+	Name       string  `json:"name"`
+	Iterations int64   `json:"iterations"`
+	RealTimeNs float64 `json:"real_time_ns"`
+	CpuTimeNs  float64 `json:"cpu_time_ns"`
+}
+
+// resultsSchema is the JSON schema of the results of a cc_benchmark module.
+const resultsSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "cc_benchmark results",
+  "type": "object",
+  "required": ["schema_version", "module", "results"],
+  "properties": {
+    "schema_version": {"const": 1},
+    "module": {"type": "string"},
+    "results": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name", "iterations", "real_time_ns", "cpu_time_ns"],
+        "properties": {
+          "name": {"type": "string"},
+          "iterations": {"type": "integer"},
+          "real_time_ns": {"type": "number"},
+          "cpu_time_ns": {"type": "number"}
+        }
+      }
+    },
+    "regressions": {"type": "array", "items": {"type": "string"}}
+  }
+}
+`
+
+// goldenRange is the range of the real time of a benchmark in a golden ranges file, a JSON object
+// keyed by the names of the benchmarks.
+type goldenRange struct {
+	MinRealTimeNs float64 `json:"min_real_time_ns"`
+	MaxRealTimeNs float64 `json:"max_real_time_ns"`
+}
+
+var timeUnits = map[string]float64{
+	"":   1,
+	"ns": 1,
+	"us": 1e3,
+	"ms": 1e6,
+	"s":  1e9,
+}
+
+func main() {
+	input := flag.String("i", "", "JSON output of the benchmark binary")
+	output := flag.String("o", "", "output results file")
+	module := flag.String("module", "", "name of the cc_benchmark module")
+	golden := flag.String("golden", "", "optional golden ranges file to compare the results against")
+	schema := flag.String("schema", "", "write the JSON schema of the results to this file, and validate the golden ranges")
+	flag.Parse()
+
+	if *schema != "" {
+		if err := writeSchema(*schema, *golden); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *input == "" || *output == "" {
+		fmt.Fprintln(os.Stderr, "-i and -o, or -schema, are required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*input, *output, *module, *golden); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// writeSchema validates the golden ranges, if any, and writes the JSON schema of the results.
+func writeSchema(schema, golden string) error {
+	if golden != "" {
+		if _, err := readGoldenRanges(golden); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(schema, []byte(resultsSchema), 0666)
+}
+
+// readGoldenRanges reads a golden ranges file, and checks that the ranges are valid.
+func readGoldenRanges(golden string) (map[string]goldenRange, error) {
+	data, err := ioutil.ReadFile(golden)
+	if err != nil {
+		return nil, err
+	}
+	var ranges map[string]goldenRange
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", golden, err)
+	}
+	if err := checkGoldenRanges(ranges); err != nil {
+		return nil, fmt.Errorf("%s: %s", golden, err)
+	}
+	return ranges, nil
+}
+
+func checkGoldenRanges(ranges map[string]goldenRange) error {
+	var names []string
+	for name := range ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		r := ranges[name]
+		if r.MinRealTimeNs < 0 || r.MaxRealTimeNs < r.MinRealTimeNs {
+			return fmt.Errorf("invalid golden range [%gns, %gns] of %s", r.MinRealTimeNs, r.MaxRealTimeNs, name)
+		}
+	}
+	return nil
+}
+
+func run(input, output, module, golden string) error {
+	data, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	var out benchmarkOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", input, err)
+	}
+
+	var ranges map[string]goldenRange
+	if golden != "" {
+		ranges, err = readGoldenRanges(golden)
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := newResults(out, module, ranges)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(output, buf, 0666); err != nil {
+		return err
+	}
+	if len(results.Regressions) > 0 {
+		return fmt.Errorf("%s: benchmarks out of their golden range in %s:\n  %s", module, golden,
+			strings.Join(results.Regressions, "\n  "))
+	}
+	return nil
+}
+
+// newResults converts the output of the benchmark binary into results, and compares them against
+// the golden ranges.
+func newResults(out benchmarkOutput, module string, ranges map[string]goldenRange) (*benchmarkResults, error) {
+	results := &benchmarkResults{
+		SchemaVersion: schemaVersion,
+		Module:        module,
+		Results:       []benchmarkResult{},
+	}
+
+	ran := make(map[string]bool)
+	for _, b := range out.Benchmarks {
+		// Skip the aggregates, e.g. the mean and the stddev of repeated benchmarks.
+		if b.RunType == "aggregate" {
+			continue
+		}
+		unit, ok := timeUnits[b.TimeUnit]
+		if !ok {
+			return nil, fmt.Errorf("unknown time unit %q of benchmark %q", b.TimeUnit, b.Name)
+		}
+		result := benchmarkResult{
+			Name:       b.Name,
+			Iterations: b.Iterations,
+			RealTimeNs: b.RealTime * unit,
+			CpuTimeNs:  b.CpuTime * unit,
+		}
+		results.Results = append(results.Results, result)
+		ran[b.Name] = true
+
+		if r, ok := ranges[b.Name]; ok {
+			if result.RealTimeNs < r.MinRealTimeNs || result.RealTimeNs > r.MaxRealTimeNs {
+				results.Regressions = append(results.Regressions, fmt.Sprintf("%s: %gns not in [%gns, %gns]",
+					b.Name, result.RealTimeNs, r.MinRealTimeNs, r.MaxRealTimeNs))
+			}
+		}
+	}
+
+	var names []string
+	for name := range ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !ran[name] {
+			results.Regressions = append(results.Regressions, name+": did not run")
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const testBenchmarkOutput = `{
+  "context": {"num_cpus": 8},
+  "benchmarks": [
+    {"name": "BM_foo", "run_type": "iteration", "iterations": 1000, "real_time": 2.5, "cpu_time": 2.0, "time_unit": "us"},
+    {"name": "BM_bar", "run_type": "iteration", "iterations": 10, "real_time": 300, "cpu_time": 290, "time_unit": "ns"},
+    {"name": "BM_bar_mean", "run_type": "aggregate", "iterations": 10, "real_time": 300, "cpu_time": 290, "time_unit": "ns"}
+  ]
+}`
+
+func TestNewResults(t *testing.T) {
+	var out benchmarkOutput
+	if err := json.Unmarshal([]byte(testBenchmarkOutput), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name                string
+		ranges              map[string]goldenRange
+		expectedRegressions []string
+	}{
+		{
+			name: "no golden ranges",
+		},
+		{
+			name: "in range",
+			ranges: map[string]goldenRange{
+				"BM_foo": {MinRealTimeNs: 2000, MaxRealTimeNs: 3000},
+				"BM_bar": {MinRealTimeNs: 0, MaxRealTimeNs: 300},
+			},
+		},
+		{
+			name: "out of range",
+			ranges: map[string]goldenRange{
+				"BM_foo": {MinRealTimeNs: 1000, MaxRealTimeNs: 2000},
+				"BM_baz": {MinRealTimeNs: 0, MaxRealTimeNs: 100},
+			},
+			expectedRegressions: []string{
+				"BM_foo: 2500ns not in [1000ns, 2000ns]",
+				"BM_baz: did not run",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := newResults(out, "foo_benchmark", tc.ranges)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedResults := []benchmarkResult{
+				{Name: "BM_foo", Iterations: 1000, RealTimeNs: 2500, CpuTimeNs: 2000},
+				{Name: "BM_bar", Iterations: 10, RealTimeNs: 300, CpuTimeNs: 290},
+			}
+			if !reflect.DeepEqual(results.Results, expectedResults) {
+				t.Errorf("expected results %v, got %v", expectedResults, results.Results)
+			}
+			if results.SchemaVersion != schemaVersion || results.Module != "foo_benchmark" {
+				t.Errorf("unexpected schema version %d or module %q", results.SchemaVersion, results.Module)
+			}
+			if !reflect.DeepEqual(results.Regressions, tc.expectedRegressions) {
+				t.Errorf("expected regressions %q, got %q", tc.expectedRegressions, results.Regressions)
+			}
+		})
+	}
+}
+
+func TestResultsSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(resultsSchema), &schema); err != nil {
+		t.Fatalf("invalid results schema: %s", err)
+	}
+	if schema["properties"].(map[string]interface{})["schema_version"].(map[string]interface{})["const"] != float64(schemaVersion) {
+		t.Errorf("the results schema does not match schemaVersion %d", schemaVersion)
+	}
+}
+
+func TestCheckGoldenRanges(t *testing.T) {
+	if err := checkGoldenRanges(map[string]goldenRange{"BM_foo": {MinRealTimeNs: 1, MaxRealTimeNs: 2}}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := checkGoldenRanges(map[string]goldenRange{"BM_foo": {MinRealTimeNs: 2, MaxRealTimeNs: 1}})
+	if err == nil || err.Error() != "invalid golden range [2ns, 1ns] of BM_foo" {
+		t.Errorf("expected an invalid golden range error, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"android/soong/response"
+)
+
+// This tool aggregates the provenance of the actions recorded by Soong in rule_provenance.json into
+// an SLSA provenance document, an in-toto statement with an SLSA v0.2 predicate, for a list of
+// subject files. The digests of the subjects and of the tools run by the actions are computed when
+// the tool runs.
+
+const (
+	statementType = "https://in-toto.io/Statement/v0.1"
+	predicateType = "https://slsa.dev/provenance/v0.2"
+	buildType     = "https://android.googlesource.com/platform/build/soong/rule-provenance/v1"
+)
+
+// ruleProvenanceFile is the content of rule_provenance.json, see android.RuleProvenanceFile.
+type ruleProvenanceFile struct {
+	Parameters map[string]string
+	Actions    []ruleProvenance
+}
+
+// ruleProvenance is the provenance of an action, see android.RuleProvenance.
+type ruleProvenance struct {
+	Rule    string
+	Module  string            `json:",omitempty"`
+	Outputs []string          `json:",omitempty"`
+	Tools   []string          `json:",omitempty"`
+	Env     map[string]string `json:",omitempty"`
+}
+
+type digestSet map[string]string
+
+type subject struct {
+	Name   string    `json:"name"`
+	Digest digestSet `json:"digest"`
+}
+
+type material struct {
+	Uri    string    `json:"uri"`
+	Digest digestSet `json:"digest"`
+}
+
+type builder struct {
+	Id string `json:"id"`
+}
+
+type invocation struct {
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+type buildConfig struct {
+	Actions []ruleProvenance `json:"actions"`
+}
+
+type predicate struct {
+	Builder     builder     `json:"builder"`
+	BuildType   string      `json:"buildType"`
+	Invocation  invocation  `json:"invocation"`
+	BuildConfig buildConfig `json:"buildConfig"`
+	Materials   []material  `json:"materials"`
+}
+
+type statement struct {
+	Type          string    `json:"_type"`
+	Subject       []subject `json:"subject"`
+	PredicateType string    `json:"predicateType"`
+	Predicate     predicate `json:"predicate"`
+}
+
+func main() {
+	actionsFile := flag.String("actions", "", "rule_provenance.json file written by Soong")
+	subjectsFile := flag.String("subjects", "", "response file listing the subjects of the provenance")
+	builderId := flag.String("builder_id", "https://android.googlesource.com/platform/build/soong",
+		"id of the builder in the provenance")
+	output := flag.String("output", "", "output SLSA provenance file")
+	flag.Parse()
+
+	if *actionsFile == "" || *output == "" {
+		fmt.Fprintln(os.Stderr, "-actions and -output are required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*actionsFile, *subjectsFile, *builderId, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(actionsFile, subjectsFile, builderId, output string) error {
+	data, err := ioutil.ReadFile(actionsFile)
+	if err != nil {
+		return err
+	}
+	var provenance ruleProvenanceFile
+	if err := json.Unmarshal(data, &provenance); err != nil {
+		return fmt.Errorf("failed to parse %s: %s", actionsFile, err)
+	}
+
+	var subjects []string
+	if subjectsFile != "" {
+		f, err := os.Open(subjectsFile)
+		if err != nil {
+			return err
+		}
+		subjects, err = response.ReadRspFile(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	s, err := newStatement(provenance, subjects, builderId)
+	if err != nil {
+		return err
+	}
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, buf, 0666)
+}
+
+// newStatement returns the provenance of the subjects, with the tools run by the actions as the
+// materials.
+func newStatement(provenance ruleProvenanceFile, subjects []string, builderId string) (*statement, error) {
+	s := &statement{
+		Type:          statementType,
+		Subject:       []subject{},
+		PredicateType: predicateType,
+		Predicate: predicate{
+			Builder:     builder{Id: builderId},
+			BuildType:   buildType,
+			Invocation:  invocation{Parameters: provenance.Parameters},
+			BuildConfig: buildConfig{Actions: provenance.Actions},
+			Materials:   []material{},
+		},
+	}
+
+	for _, name := range subjects {
+		digest, err := sha256File(name)
+		if err != nil {
+			return nil, err
+		}
+		s.Subject = append(s.Subject, subject{Name: name, Digest: digestSet{"sha256": digest}})
+	}
+
+	tools := make(map[string]bool)
+	for _, action := range provenance.Actions {
+		for _, tool := range action.Tools {
+			tools[tool] = true
+		}
+	}
+	var toolList []string
+	for tool := range tools {
+		toolList = append(toolList, tool)
+	}
+	sort.Strings(toolList)
+	for _, tool := range toolList {
+		digest, err := sha256File(tool)
+		if err != nil {
+			return nil, err
+		}
+		s.Predicate.Materials = append(s.Predicate.Materials, material{Uri: tool, Digest: digestSet{"sha256": digest}})
+	}
+
+	return s, nil
+}
+
+func sha256File(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %s", name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
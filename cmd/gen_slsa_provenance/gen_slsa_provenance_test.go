@@ -0,0 +1,77 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewStatement(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gen_slsa_provenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	libfoo := filepath.Join(dir, "libfoo.so")
+	clang := filepath.Join(dir, "clang")
+	if err := ioutil.WriteFile(libfoo, []byte("foo"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(clang, []byte("bar"), 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	provenance := ruleProvenanceFile{
+		Parameters: map[string]string{"product": "aosp_arm64"},
+		Actions: []ruleProvenance{
+			{Rule: "link", Module: "libfoo", Outputs: []string{libfoo}, Tools: []string{clang}},
+			{Rule: "compile", Module: "libfoo", Outputs: []string{"foo.o"}, Tools: []string{clang}},
+		},
+	}
+
+	s, err := newStatement(provenance, []string{libfoo}, "builder")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSubject := []subject{{
+		Name:   libfoo,
+		Digest: digestSet{"sha256": "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"},
+	}}
+	if !reflect.DeepEqual(s.Subject, expectedSubject) {
+		t.Errorf("expected subject %v, got %v", expectedSubject, s.Subject)
+	}
+
+	expectedMaterials := []material{{
+		Uri:    clang,
+		Digest: digestSet{"sha256": "fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9"},
+	}}
+	if !reflect.DeepEqual(s.Predicate.Materials, expectedMaterials) {
+		t.Errorf("expected materials %v, got %v", expectedMaterials, s.Predicate.Materials)
+	}
+
+	if s.Predicate.Builder.Id != "builder" || s.Predicate.Invocation.Parameters["product"] != "aosp_arm64" {
+		t.Errorf("unexpected predicate %+v", s.Predicate)
+	}
+
+	if _, err := newStatement(provenance, []string{filepath.Join(dir, "missing")}, "builder"); err == nil {
+		t.Errorf("expected an error for a missing subject")
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Verifies that the ifunc resolvers of a bionic ELF file don't call functions through the PLT.
+//
+// The bionic linker calls the ifunc resolvers of a library while relocating it, before the PLT
+// entries of the library are resolved, so a resolver that calls a function of another library, or
+// an exported function of its own library, jumps to an unresolved PLT entry. Depending on the
+// order in which the linker processes the relocations, that crashes the process at startup on some
+// devices only.
+package main
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// sttGnuIfunc is the symbol type of the ifunc symbols, STT_GNU_IFUNC.
+const sttGnuIfunc = elf.SymType(10)
+
+func main() {
+	var inputFile string
+
+	flag.StringVar(&inputFile, "i", "", "Input file")
+	flag.Parse()
+
+	if inputFile == "" || flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	r, err := os.Open(inputFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	defer r.Close()
+
+	violations, err := checkElf(r)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(3)
+	}
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", inputFile, v)
+	}
+	if len(violations) > 0 {
+		fmt.Fprintln(os.Stderr, "ifunc resolvers run before the PLT is resolved, they must only call "+
+			"functions with hidden or internal visibility of the same library")
+		os.Exit(5)
+	}
+}
+
+// addrRange is a range of addresses [start, end).
+type addrRange struct {
+	start, end uint64
+}
+
+func (r addrRange) contains(addr uint64) bool {
+	return addr >= r.start && addr < r.end
+}
+
+// checkElf returns the calls through the PLT of the ifunc resolvers of the ELF file.
+func checkElf(r io.ReaderAt) ([]string, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var plt []addrRange
+	for _, section := range file.Sections {
+		if section.Name == ".plt" || section.Name == ".iplt" {
+			plt = append(plt, addrRange{section.Addr, section.Addr + section.Size})
+		}
+	}
+	if len(plt) == 0 {
+		return nil, nil
+	}
+
+	symbols, err := file.Symbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return nil, err
+	}
+	dynamicSymbols, err := file.DynamicSymbols()
+	if err != nil && !errors.Is(err, elf.ErrNoSymbols) {
+		return nil, err
+	}
+	symbols = append(symbols, dynamicSymbols...)
+
+	type symbolKey struct {
+		name  string
+		value uint64
+	}
+	var violations []string
+	seen := make(map[symbolKey]bool)
+	for _, ifunc := range symbols {
+		key := symbolKey{ifunc.Name, ifunc.Value}
+		if elf.ST_TYPE(ifunc.Info) != sttGnuIfunc || ifunc.Section == elf.SHN_UNDEF || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		resolver := findResolver(symbols, ifunc)
+		code, err := readCode(file, resolver.Value, resolver.Size)
+		if err != nil {
+			return nil, fmt.Errorf("ifunc %s: %s", ifunc.Name, err)
+		}
+		for _, call := range pltCalls(file.Machine, code, resolver.Value, plt) {
+			violations = append(violations, fmt.Sprintf(
+				"the resolver %s of the ifunc %s calls the PLT entry at 0x%x from 0x%x",
+				resolver.Name, ifunc.Name, call.target, call.pc))
+		}
+	}
+	return violations, nil
+}
+
+// findResolver returns the function symbol of the resolver of the ifunc, or the ifunc symbol
+// itself if the resolver has no symbol.
+func findResolver(symbols []elf.Symbol, ifunc elf.Symbol) elf.Symbol {
+	for _, sym := range symbols {
+		if elf.ST_TYPE(sym.Info) == elf.STT_FUNC && sym.Value == ifunc.Value && sym.Size > 0 {
+			return sym
+		}
+	}
+	return ifunc
+}
+
+// readCode returns the contents of the executable section at the address.
+func readCode(file *elf.File, addr, size uint64) ([]byte, error) {
+	for _, section := range file.Sections {
+		if section.Flags&elf.SHF_EXECINSTR == 0 || section.Type == elf.SHT_NOBITS {
+			continue
+		}
+		if !(addrRange{section.Addr, section.Addr + section.Size}).contains(addr) {
+			continue
+		}
+		data, err := section.Data()
+		if err != nil {
+			return nil, err
+		}
+		start := addr - section.Addr
+		end := start + size
+		if end > uint64(len(data)) {
+			return nil, fmt.Errorf("resolver at 0x%x overflows the section %s", addr, section.Name)
+		}
+		return data[start:end], nil
+	}
+	return nil, fmt.Errorf("resolver at 0x%x not found in an executable section", addr)
+}
+
+// pltCall is a call, or a tail call, to a PLT entry.
+type pltCall struct {
+	pc, target uint64
+}
+
+// pltCalls returns the direct calls to the PLT of the code loaded at the address. Only arm64 and
+// riscv64 are decoded, the code of other architectures is not checked.
+func pltCalls(machine elf.Machine, code []byte, addr uint64, plt []addrRange) []pltCall {
+	var targets []pltCall
+	switch machine {
+	case elf.EM_AARCH64:
+		targets = arm64Calls(code, addr)
+	case elf.EM_RISCV:
+		targets = riscv64Calls(code, addr)
+	}
+
+	var calls []pltCall
+	for _, call := range targets {
+		for _, r := range plt {
+			if r.contains(call.target) {
+				calls = append(calls, call)
+				break
+			}
+		}
+	}
+	return calls
+}
+
+// arm64Calls returns the targets of the B and BL instructions of the code.
+func arm64Calls(code []byte, addr uint64) []pltCall {
+	var calls []pltCall
+	for i := 0; i+4 <= len(code); i += 4 {
+		insn := binary.LittleEndian.Uint32(code[i:])
+		// B is 000101 imm26, BL is 100101 imm26.
+		if insn&0x7c000000 != 0x14000000 {
+			continue
+		}
+		offset := int64(int32(insn<<6)>>6) * 4
+		pc := addr + uint64(i)
+		calls = append(calls, pltCall{pc, pc + uint64(offset)})
+	}
+	return calls
+}
+
+// riscv64Calls returns the targets of the JAL instructions, and of the AUIPC and JALR pairs, of the
+// code. The compressed jumps are not decoded.
+func riscv64Calls(code []byte, addr uint64) []pltCall {
+	var calls []pltCall
+	var auipcReg uint32
+	var auipcPC, auipcTarget uint64
+	for i := 0; i+2 <= len(code); {
+		pc := addr + uint64(i)
+		if binary.LittleEndian.Uint16(code[i:])&0x3 != 0x3 {
+			// Compressed instruction.
+			auipcReg = 0
+			i += 2
+			continue
+		}
+		if i+4 > len(code) {
+			break
+		}
+		insn := binary.LittleEndian.Uint32(code[i:])
+		i += 4
+
+		switch insn & 0x7f {
+		case 0x17: // AUIPC
+			auipcReg = (insn >> 7) & 0x1f
+			auipcPC = pc
+			auipcTarget = pc + uint64(int64(int32(insn&0xfffff000)))
+			continue
+		case 0x6f: // JAL
+			imm := (insn>>31&0x1)<<20 | (insn>>21&0x3ff)<<1 | (insn>>20&0x1)<<11 | (insn>>12&0xff)<<12
+			offset := int64(int32(imm<<11) >> 11)
+			calls = append(calls, pltCall{pc, pc + uint64(offset)})
+		case 0x67: // JALR
+			if rs1 := (insn >> 15) & 0x1f; rs1 != 0 && rs1 == auipcReg && auipcPC+4 == pc {
+				offset := int64(int32(insn) >> 20)
+				calls = append(calls, pltCall{auipcPC, auipcTarget + uint64(offset)})
+			}
+		}
+		auipcReg = 0
+	}
+	return calls
+}
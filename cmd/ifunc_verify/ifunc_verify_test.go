@@ -0,0 +1,111 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// code returns the little endian encoding of the instructions, 16-bit values are compressed
+// instructions.
+func code(insns ...interface{}) []byte {
+	var ret []byte
+	for _, insn := range insns {
+		switch insn := insn.(type) {
+		case uint16:
+			ret = binary.LittleEndian.AppendUint16(ret, insn)
+		case uint32:
+			ret = binary.LittleEndian.AppendUint32(ret, insn)
+		}
+	}
+	return ret
+}
+
+func TestPltCalls(t *testing.T) {
+	plt := []addrRange{{0x1000, 0x1100}}
+
+	testCases := []struct {
+		name    string
+		machine elf.Machine
+		code    []byte
+		addr    uint64
+		want    []pltCall
+	}{
+		{
+			name:    "arm64 no calls",
+			machine: elf.EM_AARCH64,
+			// adrp x0, 0; add x0, x0, #0; ret
+			code: code(uint32(0x90000000), uint32(0x91000000), uint32(0xd65f03c0)),
+			addr: 0x2000,
+		},
+		{
+			name:    "arm64 bl to plt",
+			machine: elf.EM_AARCH64,
+			// bl #-0xff0; ret
+			code: code(uint32(0x97fffc04), uint32(0xd65f03c0)),
+			addr: 0x2000,
+			want: []pltCall{{0x2000, 0x1010}},
+		},
+		{
+			name:    "arm64 tail call to plt",
+			machine: elf.EM_AARCH64,
+			// nop; b #-0xfe4
+			code: code(uint32(0xd503201f), uint32(0x17fffc07)),
+			addr: 0x2000,
+			want: []pltCall{{0x2004, 0x1020}},
+		},
+		{
+			name:    "arm64 bl to local function",
+			machine: elf.EM_AARCH64,
+			// bl #8; ret
+			code: code(uint32(0x94000002), uint32(0xd65f03c0)),
+			addr: 0x2000,
+		},
+		{
+			name:    "riscv64 call to plt",
+			machine: elf.EM_RISCV,
+			// c.nop; auipc ra, 0xfffff; jalr ra, 0x12(ra); c.ret
+			code: code(uint16(0x0001), uint32(0xfffff097), uint32(0x012080e7), uint16(0x8082)),
+			addr: 0x2000,
+			want: []pltCall{{0x2002, 0x1014}},
+		},
+		{
+			name:    "riscv64 jal to local function",
+			machine: elf.EM_RISCV,
+			// jal ra, 8; c.ret
+			code: code(uint32(0x008000ef), uint16(0x8082)),
+			addr: 0x2000,
+		},
+		{
+			name:    "x86_64 is not checked",
+			machine: elf.EM_X86_64,
+			// call -0xff5
+			code: code(uint16(0xe8), uint32(0xfffff00b)),
+			addr: 0x2000,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := pltCalls(tc.machine, tc.code, tc.addr, plt)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+}
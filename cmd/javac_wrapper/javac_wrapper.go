@@ -18,6 +18,11 @@
 // It also hides the unhelpful and unhideable "warning there is a warning"
 // messages.
 //
+// With --diagnostics <file>, it also writes the errors and warnings of the
+// command to the file as JSON, one diagnostic per line, so that they can be
+// ingested by IDEs and CI without parsing the console output. The file is
+// written even if there are no diagnostics, and when the command fails.
+//
 // Each javac build statement has an order-only dependency on the
 // soong_javac_wrapper tool, which means the javac command will not be rerun
 // if soong_javac_wrapper changes.  That means that soong_javac_wrapper must
@@ -26,12 +31,15 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -43,6 +51,7 @@ var (
 	warningRe      = regexp.MustCompile(filelinePrefix + `?(warning:) .*$`)
 	errorRe        = regexp.MustCompile(filelinePrefix + `(.*?:) .*$`)
 	markerRe       = regexp.MustCompile(`()\s*(\^)\s*$`)
+	diagnosticRe   = regexp.MustCompile(`^(?:([-.\w/\\]+\.java):([0-9]+): )?(error|warning): (.*)$`)
 
 	escape  = "\x1b"
 	reset   = escape + "[0m"
@@ -61,8 +70,17 @@ func main() {
 }
 
 func Main(out io.Writer, name string, args []string) (int, error) {
+	var diagnosticsFile string
+	if len(args) > 0 && args[0] == "--diagnostics" {
+		if len(args) < 2 {
+			return 1, fmt.Errorf("usage: %s [--diagnostics <file>] javac ...", name)
+		}
+		diagnosticsFile = args[1]
+		args = args[2:]
+	}
+
 	if len(args) < 1 {
-		return 1, fmt.Errorf("usage: %s javac ...", name)
+		return 1, fmt.Errorf("usage: %s [--diagnostics <file>] javac ...", name)
 	}
 
 	pr, pw, err := os.Pipe()
@@ -94,6 +112,12 @@ func Main(out io.Writer, name string, args []string) (int, error) {
 	// Wait for asynchronous stdout processing to finish
 	err = <-errCh
 
+	if diagnosticsFile != "" {
+		if writeErr := proc.writeDiagnostics(diagnosticsFile); writeErr != nil {
+			return 1, writeErr
+		}
+	}
+
 	// Check for subprocess exit code
 	if cmdErr != nil {
 		if exitErr, ok := cmdErr.(*exec.ExitError); ok {
@@ -121,6 +145,24 @@ func Main(out io.Writer, name string, args []string) (int, error) {
 
 type processor struct {
 	silencedWarnings int
+
+	// The errors and warnings of the output, and the state of the parsing of the one that is
+	// being read, which is followed by the source line and a marker under the column, and then
+	// by indented details.
+	diagnostics []diagnostic
+	current     *diagnostic
+	sawSource   bool
+	sawMarker   bool
+}
+
+// diagnostic is an error or a warning of javac, as written to the diagnostics file.
+type diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Details  []string `json:"details,omitempty"`
 }
 
 func (proc *processor) process(r io.Reader, w io.Writer) error {
@@ -148,9 +190,11 @@ func (proc *processor) processLine(w io.Writer, line string) {
 	}
 	for _, f := range filters {
 		if f.MatchString(line) {
+			proc.endDiagnostic()
 			return
 		}
 	}
+	proc.collectDiagnostic(line)
 	if match := warningCount.FindStringSubmatch(line); match != nil {
 		c, err := strconv.Atoi(match[1])
 		if err == nil {
@@ -174,6 +218,61 @@ func (proc *processor) processLine(w io.Writer, line string) {
 	fmt.Fprintln(w, line)
 }
 
+// collectDiagnostic records the line as a new diagnostic if it is the first line of an error or a
+// warning, or as a part of the current diagnostic if it follows it.
+func (proc *processor) collectDiagnostic(line string) {
+	if match := diagnosticRe.FindStringSubmatch(line); match != nil {
+		proc.endDiagnostic()
+		d := diagnostic{File: match[1], Severity: match[3], Message: match[4]}
+		if match[2] != "" {
+			d.Line, _ = strconv.Atoi(match[2])
+		}
+		proc.current = &d
+		return
+	}
+	if proc.current == nil {
+		return
+	}
+	switch {
+	case !proc.sawMarker && markerRe.MatchString(line):
+		proc.current.Column = strings.Index(line, "^") + 1
+		proc.sawMarker = true
+	case !proc.sawMarker && !proc.sawSource && proc.current.File != "":
+		proc.sawSource = true
+	case proc.sawMarker && strings.TrimSpace(line) != "" && strings.TrimLeft(line, " \t") != line:
+		proc.current.Details = append(proc.current.Details, strings.TrimSpace(line))
+	default:
+		proc.endDiagnostic()
+	}
+}
+
+func (proc *processor) endDiagnostic() {
+	if proc.current != nil {
+		proc.diagnostics = append(proc.diagnostics, *proc.current)
+	}
+	proc.current = nil
+	proc.sawSource = false
+	proc.sawMarker = false
+}
+
+// writeDiagnostics writes the diagnostics to the file as JSON, one per line.
+func (proc *processor) writeDiagnostics(file string) error {
+	proc.endDiagnostic()
+	var buf strings.Builder
+	for _, d := range proc.diagnostics {
+		b, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("encoding diagnostic: %s", err)
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	if err := ioutil.WriteFile(file, []byte(buf.String()), 0666); err != nil {
+		return fmt.Errorf("writing diagnostics: %s", err)
+	}
+	return nil
+}
+
 // If line matches re, make it bold and apply color to the first submatch
 // Returns line, modified if it matched, and true if it matched.
 func applyColor(line, color string, re *regexp.Regexp) (string, bool) {
@@ -17,6 +17,8 @@ package main
 import (
 	"bytes"
 	"io/ioutil"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -109,6 +111,93 @@ func TestJavacColorize(t *testing.T) {
 	}
 }
 
+func TestJavacDiagnostics(t *testing.T) {
+	in := `File.java:40: error: cannot find symbol
+    Foo foo;
+    ^
+  symbol:   class Foo
+  location: class File
+dir/Other.java:3: warning: [deprecation] bar() in Bar has been deprecated
+        bar();
+           ^
+warning: [options] bootstrap class path not set in conjunction with -source 1.7
+warning: [options] source value 8 is obsolete
+Note: Some input files use or override a deprecated API.
+1 error
+2 warnings
+`
+	expected := []diagnostic{
+		{
+			File:     "File.java",
+			Line:     40,
+			Column:   5,
+			Severity: "error",
+			Message:  "cannot find symbol",
+			Details:  []string{"symbol:   class Foo", "location: class File"},
+		},
+		{
+			File:     "dir/Other.java",
+			Line:     3,
+			Column:   12,
+			Severity: "warning",
+			Message:  "[deprecation] bar() in Bar has been deprecated",
+		},
+		{
+			Severity: "warning",
+			Message:  "[options] source value 8 is obsolete",
+		},
+	}
+
+	proc := processor{}
+	if err := proc.process(bytes.NewReader([]byte(in)), ioutil.Discard); err != nil {
+		t.Fatalf("error: %q", err)
+	}
+	proc.endDiagnostic()
+	if !reflect.DeepEqual(proc.diagnostics, expected) {
+		t.Errorf("expected %#v got %#v", expected, proc.diagnostics)
+	}
+}
+
+func TestDiagnosticsFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "diagnostics.jsonl")
+
+	t.Run("diagnostics", func(t *testing.T) {
+		exitCode, err := Main(ioutil.Discard, "test", []string{"--diagnostics", file,
+			"sh", "-c", "echo 'A.java:1: error: oops' && exit 1"})
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if exitCode != 1 {
+			t.Fatal("expected exit code 1, got", exitCode)
+		}
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `{"file":"A.java","line":1,"severity":"error","message":"oops"}` + "\n"
+		if string(got) != expected {
+			t.Errorf("expected %q got %q", expected, string(got))
+		}
+	})
+
+	t.Run("no diagnostics", func(t *testing.T) {
+		exitCode, err := Main(ioutil.Discard, "test", []string{"--diagnostics", file, "true"})
+		if err != nil {
+			t.Fatal("unexpected error", err)
+		}
+		if exitCode != 0 {
+			t.Fatal("expected exit code 0, got", exitCode)
+		}
+		got, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 0 {
+			t.Errorf("expected an empty file, got %q", string(got))
+		}
+	})
+}
+
 func TestSubprocess(t *testing.T) {
 	t.Run("failure", func(t *testing.T) {
 		exitCode, err := Main(ioutil.Discard, "test", []string{"sh", "-c", "exit 9"})
@@ -46,6 +46,9 @@ var (
 	delveListen string
 	delvePath   string
 
+	mutatorProfileFile string
+	mutatorProfileTop  int
+
 	cmdlineArgs android.CmdArgs
 )
 
@@ -67,6 +70,8 @@ func init() {
 	flag.StringVar(&cmdlineArgs.TraceFile, "trace", "", "write trace to file")
 	flag.StringVar(&cmdlineArgs.Memprofile, "memprofile", "", "write memory profile to file")
 	flag.BoolVar(&cmdlineArgs.NoGC, "nogc", false, "turn off GC for debugging")
+	flag.StringVar(&mutatorProfileFile, "mutator_profile", "", "write an HTML report of the time spent in each mutator and package to file, and label the -cpuprofile samples with them")
+	flag.IntVar(&mutatorProfileTop, "mutator_profile_top", 0, "print the N slowest mutators and packages")
 
 	// Flags representing various modes soong_build can run in
 	flag.StringVar(&cmdlineArgs.ModuleGraphFile, "module_graph_file", "", "JSON module graph file to output")
@@ -201,11 +206,11 @@ func runApiBp2build(ctx *android.Context, extraNinjaDeps []string) string {
 	// If we don't generate f/b/api/BUILD, foo.contribution will be unbuildable.
 	err := createBazelWorkspace(codegenContext, absoluteApiBp2buildDir, true)
 	maybeQuit(err, "")
-	ninjaDeps = append(ninjaDeps, codegenContext.AdditionalNinjaDeps()...)
 
 	// Create soong_injection repository
 	soongInjectionFiles, err := bp2build.CreateSoongInjectionDirFiles(codegenContext, bp2build.CreateCodegenMetrics())
 	maybeQuit(err, "")
+	ninjaDeps = append(ninjaDeps, codegenContext.AdditionalNinjaDeps()...)
 	absoluteSoongInjectionDir := shared.JoinPath(topDir, ctx.Config().SoongOutDir(), bazel.SoongInjectionDirName)
 	for _, file := range soongInjectionFiles {
 		// The API targets in api_bp2build workspace do not have any dependency on api_bp2build.
@@ -432,12 +437,16 @@ func main() {
 		finalOutputFile = runApiBp2build(ctx, extraNinjaDeps)
 		writeMetrics(configuration, ctx.EventHandler, metricsDir)
 	default:
+		if mutatorProfileFile != "" || mutatorProfileTop > 0 {
+			ctx.EnableMutatorProfile()
+		}
 		ctx.Register()
 		if configuration.IsMixedBuildsEnabled() {
 			finalOutputFile = runMixedModeBuild(ctx, extraNinjaDeps)
 		} else {
 			finalOutputFile = runSoongOnlyBuild(ctx, extraNinjaDeps)
 		}
+		writeMutatorProfile(ctx)
 		if ctx.Config().IsEnvTrue("SOONG_GENERATES_NINJA_HINT") {
 			writeNinjaHint(ctx)
 		}
@@ -452,6 +461,21 @@ func main() {
 	touch(shared.JoinPath(topDir, finalOutputFile))
 }
 
+// writeMutatorProfile writes the report of the mutator profile to the --mutator_profile file and
+// prints the --mutator_profile_top slowest mutators and packages.
+func writeMutatorProfile(ctx *android.Context) {
+	if mutatorProfileFile != "" {
+		f, err := os.Create(shared.JoinPath(topDir, mutatorProfileFile))
+		maybeQuit(err, "error creating mutator profile %s", mutatorProfileFile)
+		defer f.Close()
+		err = ctx.WriteMutatorProfile(f)
+		maybeQuit(err, "error writing mutator profile %s", mutatorProfileFile)
+	}
+	if mutatorProfileTop > 0 {
+		fmt.Fprint(os.Stderr, ctx.MutatorProfileSummary(mutatorProfileTop))
+	}
+}
+
 func writeUsedEnvironmentFile(configuration android.Config) {
 	if usedEnvFile == "" {
 		return
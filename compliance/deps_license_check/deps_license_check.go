@@ -0,0 +1,322 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// deps_license_check walks the dependency closure of the license metadata files of modules and
+// reports the license conditions of their dependencies that conflict with the distribution declared
+// by the license conditions of the modules, e.g. a GPL library in a proprietary vendor APEX, as a
+// JSON list of reports, one per module.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"android/soong/compliance/license_metadata_proto"
+)
+
+// The license conditions that make a module private, i.e. whose sources must not be shared.
+var privateConditions = []string{"proprietary"}
+
+// The license conditions that require sharing the sources of the modules that link against a
+// module, however they link against it.
+var restrictedConditions = []string{"restricted"}
+
+// The license conditions that require sharing the sources of the modules that statically link
+// against a module.
+var staticallyRestrictedConditions = []string{"restricted_if_statically_linked",
+	"restricted_with_classpath_exception", "restricted_allows_dynamic_linking"}
+
+// The annotations of the dependencies, see android.LicenseAnnotation.
+const (
+	dynamicAnnotation   = "dynamic"
+	toolchainAnnotation = "toolchain"
+)
+
+type conflict struct {
+	// The name of the module of the dependency with the conflicting condition.
+	Module string `json:"module"`
+
+	// The license metadata file of the dependency.
+	LicenseMetadata string `json:"license_metadata"`
+
+	// The conflicting license condition.
+	Condition string `json:"condition"`
+
+	// The shortest dependency path from the checked module to the dependency, as names of modules.
+	DependencyPath []string `json:"dependency_path"`
+}
+
+type report struct {
+	Module             string     `json:"module"`
+	LicenseMetadata    string     `json:"license_metadata"`
+	DeclaredConditions []string   `json:"declared_conditions"`
+	Conflicts          []conflict `json:"conflicts"`
+}
+
+func main() {
+	flags := flag.NewFlagSet("flags", flag.ExitOnError)
+	outFile := flags.String("o", "", "output JSON file")
+	depFile := flags.String("d", "", "output deps file listing the license metadata files that were read")
+	failOnConflict := flags.Bool("fail_on_conflict", false, "exit with an error if there is a conflict")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: deps_license_check [-fail_on_conflict] [-d <deps file>] -o <out.json> <meta_lic|@rsp file>...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	inputs, err := expandArgs(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+		os.Exit(2)
+	}
+	if *outFile == "" || len(inputs) == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	c := newChecker()
+	reports := []*report{}
+	for _, input := range inputs {
+		r, err := c.check(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+			os.Exit(2)
+		}
+		reports = append(reports, r)
+	}
+
+	buf, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+		os.Exit(2)
+	}
+	if err := ioutil.WriteFile(*outFile, append(buf, '\n'), 0666); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	if *depFile != "" {
+		deps := *outFile + ": \\\n  " + strings.Join(c.readFiles(), " \\\n  ") + "\n"
+		if err := ioutil.WriteFile(*depFile, []byte(deps), 0666); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	conflicts := false
+	for _, r := range reports {
+		for _, dep := range r.Conflicts {
+			fmt.Fprintf(os.Stderr, "%s: %q is %s but depends on %q which is %s, via %s\n",
+				r.LicenseMetadata, r.Module, strings.Join(r.DeclaredConditions, ", "), dep.Module, dep.Condition,
+				strings.Join(dep.DependencyPath, " -> "))
+			conflicts = true
+		}
+	}
+	if *failOnConflict && conflicts {
+		os.Exit(1)
+	}
+}
+
+// expandArgs returns the arguments with the @<rsp file> arguments replaced by the whitespace
+// separated contents of the rsp files.
+func expandArgs(args []string) ([]string, error) {
+	var ret []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			ret = append(ret, arg)
+			continue
+		}
+		buf, err := ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, strings.Fields(string(buf))...)
+	}
+	return ret, nil
+}
+
+// node is a license metadata file reached through a dependency path in which all the edges are
+// static if static is true.
+type node struct {
+	file   string
+	static bool
+}
+
+// checker checks the license metadata files of modules, and caches the license metadata files that
+// it reads as the modules share most of their dependencies.
+type checker struct {
+	metadata map[string]*license_metadata_proto.LicenseMetadata
+}
+
+func newChecker() *checker {
+	return &checker{metadata: make(map[string]*license_metadata_proto.LicenseMetadata)}
+}
+
+func (c *checker) read(file string) (*license_metadata_proto.LicenseMetadata, error) {
+	if m, ok := c.metadata[file]; ok {
+		return m, nil
+	}
+	m := &license_metadata_proto.LicenseMetadata{}
+	if err := readMetadata(file, m); err != nil {
+		return nil, err
+	}
+	c.metadata[file] = m
+	return m, nil
+}
+
+// readFiles returns the license metadata files that were read by the checker.
+func (c *checker) readFiles() []string {
+	var files []string
+	for f := range c.metadata {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// check returns the report of the license metadata file of a module, along with the license
+// metadata files that were read to create it.
+func check(file string) (*report, []string, error) {
+	c := newChecker()
+	r, err := c.check(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, c.readFiles(), nil
+}
+
+// check returns the report of the license metadata file of a module.
+func (c *checker) check(file string) (*report, error) {
+	read := c.read
+	root, err := read(file)
+	if err != nil {
+		return nil, err
+	}
+	r := &report{
+		Module:             moduleName(file, root),
+		LicenseMetadata:    file,
+		DeclaredConditions: intersect(root.LicenseConditions, privateConditions),
+		Conflicts:          []conflict{},
+	}
+	if len(r.DeclaredConditions) == 0 {
+		// Nothing conflicts with a distribution that does not restrict the sharing of the sources.
+		return r, nil
+	}
+
+	// Walk the dependencies breadth first so that the reported dependency paths are the shortest.
+	parents := map[node]*node{{file, true}: nil}
+	queue := []node{{file, true}}
+	reported := make(map[string]bool)
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		m, err := read(n.file)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.file != file {
+			conditions := intersect(m.LicenseConditions, restrictedConditions)
+			if n.static {
+				conditions = append(conditions, intersect(m.LicenseConditions, staticallyRestrictedConditions)...)
+			}
+			for _, condition := range conditions {
+				if reported[n.file+":"+condition] {
+					continue
+				}
+				reported[n.file+":"+condition] = true
+				var path []string
+				for p := &n; p != nil; p = parents[*p] {
+					pm, _ := read(p.file)
+					path = append([]string{moduleName(p.file, pm)}, path...)
+				}
+				r.Conflicts = append(r.Conflicts, conflict{
+					Module:          moduleName(n.file, m),
+					LicenseMetadata: n.file,
+					Condition:       condition,
+					DependencyPath:  path,
+				})
+			}
+		}
+
+		for _, dep := range m.Deps {
+			if contains(dep.Annotations, toolchainAnnotation) {
+				continue
+			}
+			next := node{dep.GetFile(), n.static && !contains(dep.Annotations, dynamicAnnotation)}
+			if _, ok := parents[next]; ok {
+				continue
+			}
+			parent := n
+			parents[next] = &parent
+			queue = append(queue, next)
+		}
+	}
+
+	sort.SliceStable(r.Conflicts, func(i, j int) bool {
+		if r.Conflicts[i].Module != r.Conflicts[j].Module {
+			return r.Conflicts[i].Module < r.Conflicts[j].Module
+		}
+		return r.Conflicts[i].Condition < r.Conflicts[j].Condition
+	})
+	return r, nil
+}
+
+func moduleName(file string, m *license_metadata_proto.LicenseMetadata) string {
+	if m != nil && m.GetModuleName() != "" {
+		return m.GetModuleName()
+	}
+	return file
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// intersect returns the elements of list that are in set, in the order of list.
+func intersect(list, set []string) []string {
+	var ret []string
+	for _, s := range list {
+		if contains(set, s) && !contains(ret, s) {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func readMetadata(file string, metadata *license_metadata_proto.LicenseMetadata) error {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("error reading textproto %q: %w", file, err)
+	}
+
+	err = prototext.Unmarshal(buf, metadata)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling textproto %q: %w", file, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	testCases := []struct {
+		name      string
+		metadata  map[string]string
+		conflicts []conflict
+		files     []string
+	}{
+		{
+			name: "not private",
+			metadata: map[string]string{
+				"app.meta_lic": `module_name: "app" license_conditions: "notice" deps { file: "gpl.meta_lic" }`,
+				"gpl.meta_lic": `module_name: "gpl" license_conditions: "restricted"`,
+			},
+			conflicts: []conflict{},
+			files:     []string{"app.meta_lic"},
+		},
+		{
+			name: "restricted",
+			metadata: map[string]string{
+				"app.meta_lic": `module_name: "app" license_conditions: "proprietary" deps { file: "lib.meta_lic" annotations: "dynamic" }`,
+				"lib.meta_lic": `module_name: "lib" license_conditions: "notice" deps { file: "gpl.meta_lic" }`,
+				"gpl.meta_lic": `module_name: "gpl" license_conditions: "restricted"`,
+			},
+			conflicts: []conflict{
+				{
+					Module:          "gpl",
+					LicenseMetadata: "gpl.meta_lic",
+					Condition:       "restricted",
+					DependencyPath:  []string{"app", "lib", "gpl"},
+				},
+			},
+			files: []string{"app.meta_lic", "gpl.meta_lic", "lib.meta_lic"},
+		},
+		{
+			name: "statically restricted",
+			metadata: map[string]string{
+				"app.meta_lic":     `module_name: "app" license_conditions: "proprietary" deps { file: "static.meta_lic" } deps { file: "dynamic.meta_lic" annotations: "dynamic" }`,
+				"static.meta_lic":  `module_name: "static" license_conditions: "restricted_if_statically_linked"`,
+				"dynamic.meta_lic": `module_name: "dynamic" license_conditions: "restricted_if_statically_linked"`,
+			},
+			conflicts: []conflict{
+				{
+					Module:          "static",
+					LicenseMetadata: "static.meta_lic",
+					Condition:       "restricted_if_statically_linked",
+					DependencyPath:  []string{"app", "static"},
+				},
+			},
+			files: []string{"app.meta_lic", "dynamic.meta_lic", "static.meta_lic"},
+		},
+		{
+			name: "toolchain",
+			metadata: map[string]string{
+				"app.meta_lic":      `module_name: "app" license_conditions: "proprietary" deps { file: "compiler.meta_lic" annotations: "toolchain" }`,
+				"compiler.meta_lic": `module_name: "compiler" license_conditions: "restricted"`,
+			},
+			conflicts: []conflict{},
+			files:     []string{"app.meta_lic"},
+		},
+		{
+			name: "shortest path",
+			metadata: map[string]string{
+				"app.meta_lic": `module_name: "app" license_conditions: "proprietary" deps { file: "a.meta_lic" } deps { file: "b.meta_lic" }`,
+				"a.meta_lic":   `module_name: "a" license_conditions: "notice" deps { file: "b.meta_lic" }`,
+				"b.meta_lic":   `license_conditions: "restricted"`,
+			},
+			conflicts: []conflict{
+				{
+					Module:          "b.meta_lic",
+					LicenseMetadata: "b.meta_lic",
+					Condition:       "restricted",
+					DependencyPath:  []string{"app", "b.meta_lic"},
+				},
+			},
+			files: []string{"a.meta_lic", "app.meta_lic", "b.meta_lic"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for file, metadata := range tt.metadata {
+				if err := ioutil.WriteFile(filepath.Join(dir, file), []byte(metadata), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// Run from the directory so that the dependencies are found by their relative paths.
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+
+			r, files, err := check("app.meta_lic")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if r.Module != "app" {
+				t.Errorf("expected module %q, got %q", "app", r.Module)
+			}
+			if !reflect.DeepEqual(r.Conflicts, tt.conflicts) {
+				t.Errorf("expected conflicts %#v, got %#v", tt.conflicts, r.Conflicts)
+			}
+			if !reflect.DeepEqual(files, tt.files) {
+				t.Errorf("expected files %q, got %q", tt.files, files)
+			}
+		})
+	}
+}
+
+func TestCheckMissingDependency(t *testing.T) {
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.meta_lic")
+	metadata := `module_name: "app" license_conditions: "proprietary" deps { file: "missing.meta_lic" }`
+	if err := ioutil.WriteFile(app, []byte(metadata), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := check(app); err == nil {
+		t.Error("expected an error for the missing dependency")
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	dir := t.TempDir()
+	rsp := filepath.Join(dir, "inputs.rsp")
+	if err := ioutil.WriteFile(rsp, []byte("b.meta_lic\nc.meta_lic d.meta_lic\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	args, err := expandArgs([]string{"a.meta_lic", "@" + rsp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a.meta_lic", "b.meta_lic", "c.meta_lic", "d.meta_lic"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+}
@@ -39,6 +39,15 @@ type GlobalConfig struct {
 	HasSystemOther        bool     // store odex files that match PatternsOnSystemOther on the system_other partition
 	PatternsOnSystemOther []string // patterns (using '%' to denote a prefix match) to put odex on the system_other partition
 
+	// The partition, "odm" or "oem", to install the odex files of the apps and jars whose dex location
+	// matches PatternsOnPreoptPartition into, for the storage layouts that keep them out of the
+	// partition of the dex files. They are installed in /<partition>/preopt with the path they have
+	// next to the dex files, e.g. /odm/preopt/system/app/Foo/oat/arm64/Foo.odex for
+	// /system/app/Foo/Foo.apk. The locations of the odex files of the modules defined in Soong are
+	// listed in out/soong/dexpreopt/<partition>_preopt_locations.txt, see java/dexpreopt_partition.go.
+	PreoptPartition           string
+	PatternsOnPreoptPartition []string // patterns (using '%' to denote a prefix match) of the dex locations to put odex on the preopt partition
+
 	DisableGenerateProfile bool   // don't generate profiles
 	ProfileDir             string // directory to find profiles in
 
@@ -88,6 +97,39 @@ type GlobalConfig struct {
 	Dex2oatImageXmx   string        // max heap size for dex2oat for the boot image
 	Dex2oatImageXms   string        // initial heap size for dex2oat for the boot image
 
+	// Paths to binary boot image profiles sampled on devices, which are merged with the text boot
+	// image profiles without being converted to text first.
+	BootImageSampledProfiles android.Paths
+
+	// Paths to lists of the objects that are likely to be dirtied at runtime, which dex2oat groups
+	// together in the boot images, from PRODUCT_DIRTY_IMAGE_OBJECTS. They are concatenated with
+	// frameworks/base/config/dirty-image-objects, if it exists, e.g. so that vendors can add the
+	// dirty objects collected from the field data of their devices.
+	DirtyImageObjects android.Paths
+
+	// Path to the API fingerprint of the boot jars that the boot image profiles were last refreshed
+	// against, checked in along with the profiles. If set, the build warns when the API of more than
+	// BootImageProfileMaxApiDrift percent of the boot jar classes has changed since then, as a
+	// reminder to refresh the profiles.
+	BootImageProfileFingerprint android.Path
+	BootImageProfileMaxApiDrift int // defaults to 10 percent
+
+	// Path to a checked-in manifest of the digests of the dexpreopt tools, e.g. for release branches
+	// that must be reproducible. If set, the build fails when the digests of the tools differ from
+	// the manifest. The manifest of the current tools is written to dexpreopt_tools.manifest.
+	PinnedToolsManifest android.Path
+
+	// Path to a zip of a prebuilt primary ART boot image, with the layout of the boot image zip built
+	// from the ART sources. If set, the boot image files for the device are extracted from it instead
+	// of being compiled, e.g. on unbundled branches that do not have the ART sources. A
+	// prebuilt_bootclasspath_fragment can override it with its prebuilt_boot_image property.
+	PrebuiltArtBootImage android.Path
+
+	// Path to a zip of a reference boot image, e.g. a prebuilt one, with the layout of the boot image
+	// zip. If set, the diff-boot-image target diffs the compiled methods and the class statuses of the
+	// framework boot image with those of the reference boot image.
+	ReferenceBootImage android.Path
+
 	// If true, downgrade the compiler filter of dexpreopt to "verify" when verify_uses_libraries
 	// check fails, instead of failing the build. This will disable any AOT-compilation.
 	//
@@ -98,6 +140,49 @@ type GlobalConfig struct {
 	RelaxUsesLibraryCheck bool
 
 	EnableUffdGc bool // preopt with the assumption that userfaultfd GC will be used on device.
+
+	// Overrides of EnableUffdGc for the boot images, keyed by the name of the boot image config
+	// (e.g. "art", "boot" or "mainline"). The boot images not listed here use EnableUffdGc.
+	BootImageEnableUffdGc map[string]bool
+
+	// If true, compile all the jars of a multi-image boot image by a single dex2oat invocation. By
+	// default each jar of the framework boot image and of the multi-image boot image extensions is
+	// compiled by its own dex2oat invocation, so that changing one boot jar does not recompile the
+	// whole image.
+	MonolithicBootImageDexpreopt bool
+
+	// If true, compile the boot jars declared as system_ext:<jar> in PRODUCT_BOOT_JARS into their
+	// own boot image extension installed in /system_ext/framework, instead of into the boot image
+	// in /system/framework. The system_ext jars must come after all the other boot jars.
+	SystemExtBootImage bool
+
+	// If true, validate the device boot images with oatdump, which fails if the checksums recorded
+	// by a boot image extension do not match the images it extends, e.g. when ART and the framework
+	// are out of sync. It can also be enabled with ART_VERIFY_BOOT_IMAGE=true.
+	VerifyBootImage bool
+
+	// If true, dump the names and the code offsets of the compiled methods of the device boot images
+	// with oatdump, for profilers like simpleperf, and install them along with the unstripped oat
+	// files. It can also be enabled with ART_DUMP_BOOT_IMAGE_SYMBOLS=true.
+	DumpBootImageSymbols bool
+
+	// If true, make the boot images reproducible, so that two trees built from the same sources
+	// produce identical boot image files: dex2oat runs with SOURCE_DATE_EPOCH, or 0 if it is not
+	// set in the environment, the build ids of the oat files are always generated from their
+	// contents even if the boot flags disable them, and the profiles are passed in a sorted order.
+	ReproducibleBootImages bool
+
+	// If true, install a metadata file next to the first file of each device boot image, e.g.
+	// /system/framework/arm64/boot.metadata, that lists the build fingerprint and the SHA-256 digests
+	// of dex2oat and of the jars of the image, so that on-device diagnostics and bugreport tooling can
+	// confirm which inputs produced the installed image.
+	BootImageMetadata bool
+
+	// Path to the file that contains the build fingerprint, e.g.
+	// $(SOONG_OUT_DIR)/build_fingerprint.txt, for the boot image metadata. Make only rewrites the
+	// file when the fingerprint changes, so it is an input of the metadata, which is regenerated when
+	// the fingerprint changes without recompiling the boot image.
+	BuildFingerprintFile android.Path
 }
 
 var allPlatformSystemServerJarsKey = android.NewOnceKey("allPlatformSystemServerJars")
@@ -189,15 +274,27 @@ type ModuleConfig struct {
 	ForceCreateAppImage bool
 
 	PresignedPrebuilt bool
+
+	// Whether the boot image that the module is compiled against assumes that userfaultfd GC is
+	// used on device, or nil to follow the EnableUffdGc of the global config, e.g. for the modules
+	// defined in Make.
+	EnableUffdGc *bool `json:",omitempty"`
 }
 
-type globalSoongConfigSingleton struct{}
+type globalSoongConfigSingleton struct {
+	toolsManifest      android.Path
+	toolsManifestCheck android.Path
+}
 
 var pctx = android.NewPackageContext("android/soong/dexpreopt")
 
 func init() {
 	pctx.Import("android/soong/android")
-	android.RegisterSingletonType("dexpreopt-soong-config", func() android.Singleton {
+	RegisterGlobalSoongConfigBuildComponents(android.InitRegistrationContext)
+}
+
+func RegisterGlobalSoongConfigBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("dexpreopt-soong-config", func() android.Singleton {
 		return &globalSoongConfigSingleton{}
 	})
 }
@@ -236,7 +333,14 @@ func ParseGlobalConfig(ctx android.PathContext, data []byte) (*GlobalConfig, err
 
 		// Copies of entries in GlobalConfig that are not constructable without extra parameters.  They will be
 		// used to construct the real value manually below.
-		BootImageProfiles []string
+		BootImageProfiles           []string
+		BootImageSampledProfiles    []string
+		DirtyImageObjects           []string
+		BootImageProfileFingerprint string
+		PinnedToolsManifest         string
+		PrebuiltArtBootImage        string
+		ReferenceBootImage          string
+		BuildFingerprintFile        string
 	}
 
 	config := GlobalJSONConfig{}
@@ -247,6 +351,13 @@ func ParseGlobalConfig(ctx android.PathContext, data []byte) (*GlobalConfig, err
 
 	// Construct paths that require a PathContext.
 	config.GlobalConfig.BootImageProfiles = constructPaths(ctx, config.BootImageProfiles)
+	config.GlobalConfig.BootImageSampledProfiles = constructPaths(ctx, config.BootImageSampledProfiles)
+	config.GlobalConfig.DirtyImageObjects = constructPaths(ctx, config.DirtyImageObjects)
+	config.GlobalConfig.BootImageProfileFingerprint = constructPath(ctx, config.BootImageProfileFingerprint)
+	config.GlobalConfig.PinnedToolsManifest = constructPath(ctx, config.PinnedToolsManifest)
+	config.GlobalConfig.PrebuiltArtBootImage = constructPath(ctx, config.PrebuiltArtBootImage)
+	config.GlobalConfig.ReferenceBootImage = constructPath(ctx, config.ReferenceBootImage)
+	config.GlobalConfig.BuildFingerprintFile = constructPath(ctx, config.BuildFingerprintFile)
 
 	return config.GlobalConfig, nil
 }
@@ -310,11 +421,11 @@ func getGlobalConfigRaw(ctx android.PathContext) globalConfigAndRaw {
 
 	// Avoid non-deterministic errors by reporting cached path errors on all callers.
 	for _, err := range config.pathErrors {
-		if ctx.Config().AllowMissingDependencies() {
-			// When AllowMissingDependencies it set, report errors through AddMissingDependencies.
-			// If AddMissingDependencies doesn't exist on the current context (for example when
-			// called with a SingletonContext), just swallow the errors since there is no way to
-			// report them.
+		if android.AllowMissingDependencies(ctx) {
+			// When the module is allowed to have missing dependencies, report errors through
+			// AddMissingDependencies. If AddMissingDependencies doesn't exist on the current context
+			// (for example when called with a SingletonContext), just swallow the errors since there
+			// is no way to report them.
 			if missingDepsCtx, ok := ctx.(interface {
 				AddMissingDependencies(missingDeps []string)
 			}); ok {
@@ -664,6 +775,69 @@ func (s *globalSoongConfigSingleton) GenerateBuildActions(ctx android.SingletonC
 	}
 
 	android.WriteFileRule(ctx, android.PathForOutput(ctx, "dexpreopt_soong.config"), string(data))
+
+	s.buildToolsManifest(ctx, config)
+}
+
+// buildToolsManifest writes the digests of the dexpreopt tools to dexpreopt_tools.manifest, and
+// checks them against the pinned manifest of the product if there is one.
+func (s *globalSoongConfigSingleton) buildToolsManifest(ctx android.SingletonContext, config *GlobalSoongConfig) {
+	tools := []struct {
+		name string
+		path android.Path
+	}{
+		{"dex2oat", config.Dex2oat},
+		{"profman", config.Profman},
+		{"aapt", config.Aapt},
+		{"soong_zip", config.SoongZip},
+		{"zip2zip", config.Zip2zip},
+		{"manifest_check", config.ManifestCheck},
+		{"construct_context", config.ConstructContext},
+	}
+
+	manifest := android.PathForOutput(ctx, "dexpreopt_tools.manifest")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("rm -f").Output(manifest)
+	for _, tool := range tools {
+		rule.Command().
+			Textf("echo %s $(sha256sum <", tool.name).
+			Input(tool.path).
+			Text("| cut -d ' ' -f 1)").
+			Text(">>").Text(manifest.String())
+	}
+	rule.Build("dexpreopt_tools_manifest", "dexpreopt tools manifest")
+	s.toolsManifest = manifest
+
+	pinned := GetGlobalConfig(ctx).PinnedToolsManifest
+	if pinned == nil {
+		return
+	}
+	check := toolsManifestCheckPath(ctx)
+	rule = android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("if ! diff -u").Input(pinned).Input(manifest).Text("; then").
+		Textf("echo 'error: the dexpreopt tools differ from the pinned manifest %s.' >&2;", pinned).
+		Textf("echo 'If the change is expected, update it with: cp %s %s' >&2;", manifest, pinned).
+		Text("exit 1; fi")
+	rule.Command().Text("touch").Output(check)
+	rule.Build("dexpreopt_tools_manifest_check", "check dexpreopt tools manifest")
+	s.toolsManifestCheck = check
+
+	ctx.Phony("checkbuild", check)
+}
+
+// ToolsManifestCheck returns the stamp file of the check of the dexpreopt tools against the pinned
+// manifest of the product, or an invalid path if the product does not pin them. The rules that run
+// dex2oat take it as a validation, so that building any dexpreopted module runs the check.
+func ToolsManifestCheck(ctx android.PathContext, global *GlobalConfig) android.OptionalPath {
+	if global.PinnedToolsManifest == nil {
+		return android.OptionalPath{}
+	}
+	return android.OptionalPathForPath(toolsManifestCheckPath(ctx))
+}
+
+func toolsManifestCheckPath(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, "dexpreopt_tools.manifest.check")
 }
 
 func (s *globalSoongConfigSingleton) MakeVars(ctx android.MakeVarsContext) {
@@ -676,8 +850,7 @@ func (s *globalSoongConfigSingleton) MakeVars(ctx android.MakeVarsContext) {
 		return
 	}
 
-	ctx.Strict("DEX2OAT", config.Dex2oat.String())
-	ctx.Strict("DEXPREOPT_GEN_DEPS", strings.Join([]string{
+	deps := []string{
 		config.Profman.String(),
 		config.Dex2oat.String(),
 		config.Aapt.String(),
@@ -685,7 +858,18 @@ func (s *globalSoongConfigSingleton) MakeVars(ctx android.MakeVarsContext) {
 		config.Zip2zip.String(),
 		config.ManifestCheck.String(),
 		config.ConstructContext.String(),
-	}, " "))
+	}
+	if s.toolsManifestCheck != nil {
+		// Dexpreopting in Make must not use tools that differ from the pinned ones.
+		deps = append(deps, s.toolsManifestCheck.String())
+	}
+
+	ctx.Strict("DEX2OAT", config.Dex2oat.String())
+	ctx.Strict("DEXPREOPT_GEN_DEPS", strings.Join(deps, " "))
+
+	if s.toolsManifest != nil {
+		ctx.DistForGoal("droidcore", s.toolsManifest)
+	}
 }
 
 func GlobalConfigForTests(ctx android.PathContext) *GlobalConfig {
@@ -725,6 +909,8 @@ func GlobalConfigForTests(ctx android.PathContext) *GlobalConfig {
 		CpuVariant:                         nil,
 		InstructionSetFeatures:             nil,
 		BootImageProfiles:                  nil,
+		BootImageSampledProfiles:           nil,
+		DirtyImageObjects:                  nil,
 		BootFlags:                          "",
 		Dex2oatImageXmx:                    "",
 		Dex2oatImageXms:                    "",
@@ -42,6 +42,7 @@ import (
 	"android/soong/android"
 
 	"github.com/google/blueprint/pathtools"
+	"github.com/google/blueprint/proptools"
 )
 
 const SystemPartition = "/system/"
@@ -248,7 +249,9 @@ func dexpreoptCommand(ctx android.PathContext, globalSoong *GlobalSoongConfig, g
 
 	odexPath := module.BuildPath.InSameDir(ctx, "oat", arch.String(), pathtools.ReplaceExtension(base, "odex"))
 	odexInstallPath := ToOdexPath(module.DexLocation, arch)
-	if odexOnSystemOther(module, global) {
+	if OdexOnPreoptPartition(module.DexLocation, global) {
+		odexInstallPath = filepath.Join(PreoptPartitionInstallDir(global), odexInstallPath)
+	} else if odexOnSystemOther(module, global) {
 		odexInstallPath = filepath.Join(SystemOtherPartition, odexInstallPath)
 	}
 
@@ -393,6 +396,10 @@ func dexpreoptCommand(ctx android.PathContext, globalSoong *GlobalSoongConfig, g
 		Flag("--force-determinism").
 		FlagWithArg("--no-inline-from=", "core-oj.jar")
 
+	if check := ToolsManifestCheck(ctx, global); check.Valid() {
+		cmd.Validation(check.Path())
+	}
+
 	var preoptFlags []string
 	if len(module.PreoptFlags) > 0 {
 		preoptFlags = module.PreoptFlags
@@ -507,7 +514,7 @@ func dexpreoptCommand(ctx android.PathContext, globalSoong *GlobalSoongConfig, g
 		cmd.FlagWithInput("--profile-file=", profile)
 	}
 
-	if global.EnableUffdGc {
+	if proptools.BoolDefault(module.EnableUffdGc, global.EnableUffdGc) {
 		cmd.Flag("--runtime-arg").Flag("-Xgc:CMC")
 	}
 
@@ -548,6 +555,28 @@ func odexOnSystemOther(module *ModuleConfig, global *GlobalConfig) bool {
 	return OdexOnSystemOtherByName(module.Name, module.DexLocation, global)
 }
 
+// OdexOnPreoptPartition returns true if the odex files of the dex location are installed on the
+// preopt partition of the product, see GlobalConfig.PreoptPartition.
+func OdexOnPreoptPartition(dexLocation string, global *GlobalConfig) bool {
+	if global.PreoptPartition == "" {
+		return false
+	}
+
+	for _, f := range global.PatternsOnPreoptPartition {
+		if makefileMatch(f, dexLocation) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PreoptPartitionInstallDir returns the directory that the odex files on the preopt partition are
+// installed into, e.g. /odm/preopt.
+func PreoptPartitionInstallDir(global *GlobalConfig) string {
+	return filepath.Join("/", global.PreoptPartition, "preopt")
+}
+
 // PathToLocation converts .../system/framework/arm64/boot.art to .../system/framework/boot.art
 func PathToLocation(path android.Path, arch android.ArchType) string {
 	return PathStringToLocation(path.String(), arch)
@@ -176,6 +176,46 @@ func TestDexPreoptSystemOther(t *testing.T) {
 
 }
 
+func TestDexPreoptPreoptPartition(t *testing.T) {
+	config := android.TestConfig("out", nil, "", nil)
+	ctx := android.BuilderContextForTesting(config)
+	globalSoong := globalSoongConfigForTests()
+	global := GlobalConfigForTests(ctx)
+	systemModule := testSystemModuleConfig(ctx, "Stest")
+	productModule := testProductModuleConfig(ctx, "Ptest")
+
+	// The preopt partition takes precedence over system_other.
+	global.HasSystemOther = true
+	global.PatternsOnSystemOther = []string{"app/%"}
+	global.PreoptPartition = "odm"
+	global.PatternsOnPreoptPartition = []string{"/system/app/%", "/product/app/%"}
+
+	tests := []struct {
+		module            *ModuleConfig
+		expectedPartition string
+	}{
+		{module: systemModule, expectedPartition: "odm/preopt/system"},
+		{module: productModule, expectedPartition: "odm/preopt/product"},
+	}
+
+	for _, test := range tests {
+		rule, err := GenerateDexpreoptRule(ctx, globalSoong, global, test.module)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := test.module.Name
+		wantInstalls := android.RuleBuilderInstalls{
+			{android.PathForOutput(ctx, name+"/oat/arm/package.odex"), fmt.Sprintf("/%s/app/test/oat/arm/%s.odex", test.expectedPartition, name)},
+			{android.PathForOutput(ctx, name+"/oat/arm/package.vdex"), fmt.Sprintf("/%s/app/test/oat/arm/%s.vdex", test.expectedPartition, name)},
+		}
+
+		if rule.Installs().String() != wantInstalls.String() {
+			t.Errorf("\nwant installs:\n   %v\ngot:\n   %v", wantInstalls, rule.Installs())
+		}
+	}
+}
+
 func TestDexPreoptApexSystemServerJars(t *testing.T) {
 	config := android.TestConfig("out", nil, "", nil)
 	ctx := android.BuilderContextForTesting(config)
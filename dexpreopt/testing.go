@@ -45,6 +45,10 @@ func BpToolModulesForTest() string {
 		fake_tool_binary {
 			name: "dex2oatd",
 		}
+
+		fake_tool_binary {
+			name: "profman",
+		}
 	`
 }
 
@@ -168,6 +172,63 @@ func FixtureSetBootImageProfiles(profiles ...string) android.FixturePreparer {
 	})
 }
 
+// FixtureSetBootImageSampledProfiles sets the BootImageSampledProfiles property in the global
+// config.
+func FixtureSetBootImageSampledProfiles(profiles ...string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.BootImageSampledProfiles = android.PathsForSource(ctx, profiles)
+	})
+}
+
+// FixtureSetBootImageProfileFingerprint sets the BootImageProfileFingerprint and
+// BootImageProfileMaxApiDrift properties in the global config.
+func FixtureSetBootImageProfileFingerprint(fingerprint string, maxApiDrift int) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.BootImageProfileFingerprint = android.PathForSource(ctx, fingerprint)
+		dexpreoptConfig.BootImageProfileMaxApiDrift = maxApiDrift
+	})
+}
+
+// FixtureSetPrebuiltArtBootImage sets the PrebuiltArtBootImage property in the global config.
+func FixtureSetPrebuiltArtBootImage(zip string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.PrebuiltArtBootImage = android.PathForSource(ctx, zip)
+	})
+}
+
+// FixtureSetReferenceBootImage sets the ReferenceBootImage property in the global config.
+func FixtureSetReferenceBootImage(zip string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.ReferenceBootImage = android.PathForSource(ctx, zip)
+	})
+}
+
+// FixtureSetBootImageEnableUffdGc sets the override of the EnableUffdGc property in the global
+// config for a boot image.
+func FixtureSetBootImageEnableUffdGc(imageName string, enable bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		if dexpreoptConfig.BootImageEnableUffdGc == nil {
+			dexpreoptConfig.BootImageEnableUffdGc = make(map[string]bool)
+		}
+		dexpreoptConfig.BootImageEnableUffdGc[imageName] = enable
+	})
+}
+
+// FixtureSetMonolithicBootImageDexpreopt sets the MonolithicBootImageDexpreopt property in the
+// global config.
+func FixtureSetMonolithicBootImageDexpreopt(monolithic bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.MonolithicBootImageDexpreopt = monolithic
+	})
+}
+
+// FixtureSetSystemExtBootImage sets the SystemExtBootImage property in the global config.
+func FixtureSetSystemExtBootImage(enable bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.SystemExtBootImage = enable
+	})
+}
+
 // FixtureDisableGenerateProfile sets the DisableGenerateProfile property in the global config.
 func FixtureDisableGenerateProfile(disable bool) android.FixturePreparer {
 	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
@@ -182,9 +243,55 @@ func FixtureDisableDexpreoptBootImages(disable bool) android.FixturePreparer {
 	})
 }
 
+// FixtureSetDirtyImageObjects sets the DirtyImageObjects property in the global config.
+func FixtureSetDirtyImageObjects(files ...string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.DirtyImageObjects = android.PathsForSource(ctx, files)
+	})
+}
+
 // FixtureDisableDexpreopt sets the DisablePreopt property in the global config.
 func FixtureDisableDexpreopt(disable bool) android.FixturePreparer {
 	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
 		dexpreoptConfig.DisablePreopt = disable
 	})
 }
+
+// FixtureSetVerifyBootImage sets the VerifyBootImage property in the global config.
+func FixtureSetVerifyBootImage(enable bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.VerifyBootImage = enable
+	})
+}
+
+// FixtureSetDumpBootImageSymbols sets the DumpBootImageSymbols property in the global config.
+func FixtureSetDumpBootImageSymbols(enable bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.DumpBootImageSymbols = enable
+	})
+}
+
+// FixtureSetReproducibleBootImages sets the ReproducibleBootImages property in the global config.
+func FixtureSetReproducibleBootImages(enable bool) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.ReproducibleBootImages = enable
+	})
+}
+
+// FixtureSetPreoptPartition sets the PreoptPartition and PatternsOnPreoptPartition properties in
+// the global config.
+func FixtureSetPreoptPartition(partition string, patterns ...string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(_ android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.PreoptPartition = partition
+		dexpreoptConfig.PatternsOnPreoptPartition = patterns
+	})
+}
+
+// FixtureSetBootImageMetadata sets the BootImageMetadata and BuildFingerprintFile properties in the
+// global config. The build fingerprint file is relative to the Soong output directory.
+func FixtureSetBootImageMetadata(enable bool, buildFingerprintFile string) android.FixturePreparer {
+	return FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *GlobalConfig) {
+		dexpreoptConfig.BootImageMetadata = enable
+		dexpreoptConfig.BuildFingerprintFile = android.PathForOutput(ctx, buildFingerprintFile)
+	})
+}
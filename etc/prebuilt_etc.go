@@ -316,7 +316,7 @@ func (p *PrebuiltEtc) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		} else {
 			filename = ctx.ModuleName()
 		}
-	} else if ctx.Config().AllowMissingDependencies() {
+	} else if ctx.AllowMissingDependencies() {
 		// If no srcs was set and AllowMissingDependencies is enabled then
 		// mark the module as missing dependencies and set a fake source path
 		// and file name.
@@ -176,6 +176,28 @@ func (f *filesystem) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	f.installDir = android.PathForModuleInstall(ctx, "etc")
 	ctx.InstallFile(f.installDir, f.installFileName(), f.output)
+
+	if verityMetadata := f.apexVerityMetadata(ctx); len(verityMetadata) > 0 {
+		ctx.Phony(f.BaseModuleName()+"-apex-verity-metadata", verityMetadata...)
+	}
+}
+
+// apexVerityMetadata returns the dm-verity metadata of the APEXes in the filesystem that are built
+// without their hashtree. The metadata is verified against the payloads of the APEXes when it is
+// built, so it validates the image, and it is also built by the <filesystem>-apex-verity-metadata
+// target, e.g. for the release pipeline to add it to the image later.
+func (f *filesystem) apexVerityMetadata(ctx android.ModuleContext) android.Paths {
+	var verityMetadata android.Paths
+	ctx.VisitDirectDepsWithTag(dependencyTag, func(child android.Module) {
+		if !ctx.OtherModuleHasProvider(child, android.ApexHashtreeInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(child, android.ApexHashtreeInfoProvider).(android.ApexHashtreeInfo)
+		if !info.GenerateHashtree && info.VerityMetadata != nil {
+			verityMetadata = append(verityMetadata, info.VerityMetadata)
+		}
+	})
+	return android.SortedUniquePaths(verityMetadata)
 }
 
 // root zip will contain extra files/dirs that are not from the `deps` property.
@@ -282,7 +304,8 @@ func (f *filesystem) buildImageUsingBuildImage(ctx android.ModuleContext) androi
 		Input(propFile).
 		Implicits(toolDeps).
 		Output(output).
-		Text(rootDir.String()) // directory where to find fs_config_files|dirs
+		Text(rootDir.String()). // directory where to find fs_config_files|dirs
+		Validations(f.apexVerityMetadata(ctx))
 
 	// rootDir is not deleted. Might be useful for quick inspection.
 	builder.Build("build_filesystem_image", fmt.Sprintf("Creating filesystem %s", f.BaseModuleName()))
@@ -414,6 +437,7 @@ func (f *filesystem) buildCpioImage(ctx android.ModuleContext, compressed bool)
 	cmd := builder.Command().
 		BuiltTool("mkbootfs").
 		Text(rootDir.String()) // input directory
+	cmd.Validations(f.apexVerityMetadata(ctx))
 	if compressed {
 		cmd.Text("|").
 			BuiltTool("lz4").
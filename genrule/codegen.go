@@ -0,0 +1,210 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genrule
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+// This file contains the supported API for the code generators of the Soong plugins outside of
+// build/soong, e.g. the IDL compilers of the vendor projects. A plugin implements CodegenPlugin
+// and registers a module type for it with RegisterCodegenModuleType, and the modules of that type
+// can then be used like genrule modules:
+//   - in the generated_sources and generated_headers of cc modules,
+//   - in the srcs of java and rust modules, as ":module", and ":module{.hdrs}" for the headers.
+//
+// The modules of a plugin go through the following steps, in this order, once per module:
+//   1. CodegenProperties is called when the module is created, before its Android.bp properties
+//      are read into the returned structs.
+//   2. CodegenDeps is called from the deps mutator, which is the only step that can add
+//      dependencies.
+//   3. GenerateCode is called from GenerateAndroidBuildActions, after the build actions of all the
+//      dependencies have been generated, so that their providers and outputs can be used. It
+//      returns the outputs of the module, which are validated and then can't change.
+//
+// The outputs are available to the other modules through CodegenInfoProvider, and through the
+// SourceFileGenerator and android.OutputFileProducer interfaces, only after step 3. A plugin can
+// also pass other information to the modules of its own module types through its own providers,
+// which it returns in CodegenInfo.Providers. The plugins must not use any other mutator, or the
+// internal state of the modules that consume their outputs.
+
+// CodegenPlugin is the code generator of the modules of a module type registered with
+// RegisterCodegenModuleType.
+type CodegenPlugin interface {
+	// CodegenProperties returns the property structs of the module, which are filled from its
+	// Android.bp definition.
+	CodegenProperties() []interface{}
+
+	// CodegenDeps adds the dependencies of the module, e.g. on the host tool of the generator.
+	CodegenDeps(ctx android.BottomUpMutatorContext)
+
+	// GenerateCode generates the build actions of the module, and returns its outputs.
+	GenerateCode(ctx android.ModuleContext) CodegenInfo
+}
+
+// CodegenInfo is the outputs of a module of a code generator.
+type CodegenInfo struct {
+	// The generated sources, compiled by the modules that use the module in their
+	// generated_sources or srcs.
+	Srcs android.Paths
+
+	// The generated headers, included by the modules that use the module in their
+	// generated_sources or generated_headers.
+	Headers android.Paths
+
+	// The include directories of the generated headers. Defaults to the gen directory of the
+	// module if there are headers.
+	HeaderDirs android.Paths
+
+	// The providers of the plugin, which are set on the module along with CodegenInfoProvider
+	// once the outputs are validated.
+	Providers []CodegenProvider
+}
+
+// CodegenProvider is the value of a provider of a plugin. The key must be created with
+// blueprint.NewProvider from a package-level variable of the plugin, like the providers of Soong.
+type CodegenProvider struct {
+	Key   blueprint.ProviderKey
+	Value interface{}
+}
+
+var CodegenInfoProvider = blueprint.NewProvider(CodegenInfo{})
+
+// RegisterCodegenModuleType registers a module type for a code generator. The factory is called
+// once per module, and must return a new CodegenPlugin each time.
+func RegisterCodegenModuleType(ctx android.RegistrationContext, name string, factory func() CodegenPlugin) {
+	ctx.RegisterModuleType(name, func() android.Module {
+		return newCodegenModule(factory())
+	})
+}
+
+func newCodegenModule(plugin CodegenPlugin) *CodegenModule {
+	module := &CodegenModule{plugin: plugin}
+	module.AddProperties(plugin.CodegenProperties()...)
+	android.InitAndroidModule(module)
+	return module
+}
+
+// CodegenModule is a module of a code generator registered with RegisterCodegenModuleType.
+type CodegenModule struct {
+	android.ModuleBase
+
+	plugin CodegenPlugin
+	info   CodegenInfo
+}
+
+var _ SourceFileGenerator = (*CodegenModule)(nil)
+var _ android.SourceFileProducer = (*CodegenModule)(nil)
+var _ android.OutputFileProducer = (*CodegenModule)(nil)
+
+func (m *CodegenModule) DepsMutator(ctx android.BottomUpMutatorContext) {
+	m.plugin.CodegenDeps(ctx)
+}
+
+func (m *CodegenModule) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	info := m.plugin.GenerateCode(ctx)
+	if len(info.HeaderDirs) == 0 && len(info.Headers) > 0 {
+		info.HeaderDirs = android.Paths{android.PathForModuleGen(ctx)}
+	}
+	if !validateCodegenInfo(ctx, info) {
+		return
+	}
+	m.info = CodegenInfo{
+		Srcs:       android.CopyOfPaths(info.Srcs),
+		Headers:    android.CopyOfPaths(info.Headers),
+		HeaderDirs: android.CopyOfPaths(info.HeaderDirs),
+	}
+	ctx.SetProvider(CodegenInfoProvider, m.info)
+	for _, provider := range info.Providers {
+		ctx.SetProvider(provider.Key, provider.Value)
+	}
+}
+
+// validateCodegenInfo reports the outputs of a code generator that are not in the gen directory of
+// the module, the headers that are not in one of its include directories, and the providers of the
+// plugin that replace CodegenInfoProvider.
+func validateCodegenInfo(ctx android.ModuleContext, info CodegenInfo) bool {
+	genDir := android.PathForModuleGen(ctx).String()
+	inDir := func(path android.Path, dir string) bool {
+		return path.String() == dir || strings.HasPrefix(path.String(), dir+"/")
+	}
+
+	valid := true
+	for _, list := range []struct {
+		name  string
+		paths android.Paths
+	}{
+		{"source", info.Srcs},
+		{"header", info.Headers},
+		{"header directory", info.HeaderDirs},
+	} {
+		for _, path := range list.paths {
+			if !inDir(path, genDir) {
+				ctx.ModuleErrorf("generated %s %s is not in the gen directory of the module %s",
+					list.name, path, genDir)
+				valid = false
+			}
+		}
+	}
+headers:
+	for _, header := range info.Headers {
+		for _, dir := range info.HeaderDirs {
+			if inDir(header, dir.String()) {
+				continue headers
+			}
+		}
+		ctx.ModuleErrorf("generated header %s is not in one of the header directories %s",
+			header, info.HeaderDirs)
+		valid = false
+	}
+	for _, provider := range info.Providers {
+		if provider.Key == CodegenInfoProvider {
+			ctx.ModuleErrorf("CodegenInfoProvider can't be one of the providers of the plugin")
+			valid = false
+		}
+	}
+	return valid
+}
+
+func (m *CodegenModule) GeneratedSourceFiles() android.Paths {
+	return append(android.CopyOfPaths(m.info.Srcs), m.info.Headers...)
+}
+
+func (m *CodegenModule) GeneratedHeaderDirs() android.Paths {
+	return m.info.HeaderDirs
+}
+
+func (m *CodegenModule) GeneratedDeps() android.Paths {
+	return m.info.Headers
+}
+
+func (m *CodegenModule) Srcs() android.Paths {
+	return android.CopyOfPaths(m.info.Srcs)
+}
+
+func (m *CodegenModule) OutputFiles(tag string) (android.Paths, error) {
+	switch tag {
+	case "":
+		return android.CopyOfPaths(m.info.Srcs), nil
+	case ".hdrs":
+		return android.CopyOfPaths(m.info.Headers), nil
+	}
+	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+}
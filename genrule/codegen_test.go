@@ -0,0 +1,148 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genrule
+
+import (
+	"testing"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+// testCodegenInterfacesInfo is the provider of a plugin, with the interfaces of its modules.
+type testCodegenInterfacesInfo struct {
+	Interfaces []string
+}
+
+var testCodegenInterfacesInfoProvider = blueprint.NewProvider(testCodegenInterfacesInfo{})
+
+// testCodegenProviderPlugin is a testCodegenPlugin that also sets its own provider.
+type testCodegenProviderPlugin struct {
+	testCodegenPlugin
+	key blueprint.ProviderKey
+}
+
+func (p *testCodegenProviderPlugin) GenerateCode(ctx android.ModuleContext) CodegenInfo {
+	info := p.testCodegenPlugin.GenerateCode(ctx)
+	info.Providers = append(info.Providers, CodegenProvider{
+		Key:   p.key,
+		Value: testCodegenInterfacesInfo{Interfaces: p.properties.Interfaces},
+	})
+	return info
+}
+
+func prepareForTestWithCodegenProviderPlugin(key blueprint.ProviderKey) android.FixturePreparer {
+	return android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+		RegisterCodegenModuleType(ctx, "test_codegen_provider", func() CodegenPlugin {
+			return &testCodegenProviderPlugin{key: key}
+		})
+	})
+}
+
+func TestCodegenModule(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		PrepareForTestWithCodegenPlugin,
+	).RunTestWithBp(t, testGenruleBp()+`
+		test_codegen {
+			name: "foo_idl",
+			interfaces: ["foo", "bar"],
+			tool: "tool",
+		}
+
+		use_source {
+			name: "foo_srcs",
+			srcs: [":foo_idl"],
+		}
+
+		use_source {
+			name: "foo_headers",
+			srcs: [":foo_idl{.hdrs}"],
+		}
+	`)
+
+	idl := result.ModuleForTests("foo_idl", "").Module().(*CodegenModule)
+	info := result.ModuleProvider(idl, CodegenInfoProvider).(CodegenInfo)
+	android.AssertPathsRelativeToTopEquals(t, "srcs", []string{
+		"out/soong/.intermediates/foo_idl/gen/foo.c",
+		"out/soong/.intermediates/foo_idl/gen/bar.c",
+	}, info.Srcs)
+	android.AssertPathsRelativeToTopEquals(t, "headers", []string{
+		"out/soong/.intermediates/foo_idl/gen/include/foo.h",
+		"out/soong/.intermediates/foo_idl/gen/include/bar.h",
+	}, info.Headers)
+	android.AssertPathsRelativeToTopEquals(t, "header dirs", []string{
+		"out/soong/.intermediates/foo_idl/gen",
+	}, idl.GeneratedHeaderDirs())
+
+	android.AssertPathsRelativeToTopEquals(t, "srcs of use_source", []string{
+		"out/soong/.intermediates/foo_idl/gen/foo.c",
+		"out/soong/.intermediates/foo_idl/gen/bar.c",
+	}, result.ModuleForTests("foo_srcs", "").Module().(*useSource).srcs)
+	android.AssertPathsRelativeToTopEquals(t, "headers of use_source", []string{
+		"out/soong/.intermediates/foo_idl/gen/include/foo.h",
+		"out/soong/.intermediates/foo_idl/gen/include/bar.h",
+	}, result.ModuleForTests("foo_headers", "").Module().(*useSource).srcs)
+}
+
+func TestCodegenModuleErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		PrepareForTestWithCodegenPlugin,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`module "foo_idl": generated source .*/foo_idl/out/foo.c is not in the gen directory of the module`)).
+		RunTestWithBp(t, `
+			test_codegen {
+				name: "foo_idl",
+				interfaces: ["foo"],
+				out_dir: "out",
+			}
+		`)
+}
+
+func TestCodegenModuleProviders(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		prepareForTestWithCodegenProviderPlugin(testCodegenInterfacesInfoProvider),
+	).RunTestWithBp(t, `
+		test_codegen_provider {
+			name: "foo_idl",
+			interfaces: ["foo", "bar"],
+		}
+	`)
+
+	idl := result.ModuleForTests("foo_idl", "").Module()
+	info := result.ModuleProvider(idl, testCodegenInterfacesInfoProvider).(testCodegenInterfacesInfo)
+	android.AssertArrayString(t, "interfaces", []string{"foo", "bar"}, info.Interfaces)
+	android.AssertPathsRelativeToTopEquals(t, "srcs", []string{
+		"out/soong/.intermediates/foo_idl/gen/foo.c",
+		"out/soong/.intermediates/foo_idl/gen/bar.c",
+	}, result.ModuleProvider(idl, CodegenInfoProvider).(CodegenInfo).Srcs)
+}
+
+func TestCodegenModuleProviderErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		prepareForTestWithCodegenProviderPlugin(CodegenInfoProvider),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`module "foo_idl": CodegenInfoProvider can't be one of the providers of the plugin`)).
+		RunTestWithBp(t, `
+			test_codegen_provider {
+				name: "foo_idl",
+				interfaces: ["foo"],
+			}
+		`)
+}
@@ -151,6 +151,11 @@ type generatorProperties struct {
 
 	// input files to exclude
 	Exclude_srcs []string `android:"path,arch_variant"`
+
+	// Run the command inside nsjail, where it can only read the tools and inputs that are declared
+	// in tools, tool_files and srcs, and cannot reach the network. The outputs of the hermetic
+	// command can then be cached remotely with RBE.
+	Use_nsjail *bool
 }
 
 type Module struct {
@@ -238,8 +243,19 @@ func (g *Module) OutputFiles(tag string) (android.Paths, error) {
 	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 }
 
+// OutputFileTags returns the paths of the outputs relative to the gen directory, which can be used
+// as tags to refer to a single output.
+func (g *Module) OutputFileTags() []string {
+	var tags []string
+	for _, outputFile := range g.outputFiles {
+		tags = append(tags, outputFile.Rel())
+	}
+	return tags
+}
+
 var _ android.SourceFileProducer = (*Module)(nil)
 var _ android.OutputFileProducer = (*Module)(nil)
+var _ android.OutputFileTagsProducer = (*Module)(nil)
 
 func toolDepsMutator(ctx android.BottomUpMutatorContext) {
 	if g, ok := ctx.Module().(*Module); ok {
@@ -285,6 +301,8 @@ func (g *Module) ProcessBazelQueryResponse(ctx android.ModuleContext) {
 func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 	g.subName = ctx.ModuleSubDir()
 
+	g.checkNsjail(ctx)
+
 	// Collect the module directory for IDE info in java/jdeps.go.
 	g.modulePaths = append(g.modulePaths, ctx.ModuleDir())
 
@@ -333,7 +351,7 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 					// A HostToolProvider provides the path to a tool, which will be copied
 					// into the sandbox.
 					if !t.(android.Module).Enabled() {
-						if ctx.Config().AllowMissingDependencies() {
+						if ctx.AllowMissingDependencies() {
 							ctx.AddMissingDependencies([]string{tool})
 						} else {
 							ctx.ModuleErrorf("depends on disabled module %q", tool)
@@ -376,7 +394,7 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 		// "cmd: unknown location label ..." errors later.  Add a placeholder file to the local label.
 		// The command that uses this placeholder file will never be executed because the rule will be
 		// replaced with an android.Error rule reporting the missing dependencies.
-		if ctx.Config().AllowMissingDependencies() {
+		if ctx.AllowMissingDependencies() {
 			for _, tool := range g.properties.Tools {
 				if !seenTools[tool] {
 					addLocationLabel(tool, errorLocation{"***missing tool " + tool + "***"})
@@ -402,7 +420,7 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 			Context: ctx, Paths: []string{in}, ExcludePaths: g.properties.Exclude_srcs, IncludeDirs: includeDirInPaths,
 		})
 		if len(missingDeps) > 0 {
-			if !ctx.Config().AllowMissingDependencies() {
+			if !ctx.AllowMissingDependencies() {
 				panic(fmt.Errorf("should never get here, the missing dependencies %q should have been reported in DepsMutator",
 					missingDeps))
 			}
@@ -452,7 +470,13 @@ func (g *Module) generateCommonBuildActions(ctx android.ModuleContext) {
 		manifestPath := android.PathForModuleOut(ctx, manifestName)
 
 		// Use a RuleBuilder to create a rule that runs the command inside an sbox sandbox.
-		rule := android.NewRuleBuilder(pctx, ctx).Sbox(task.genDir, manifestPath).SandboxTools()
+		rule := android.NewRuleBuilder(pctx, ctx).Sbox(task.genDir, manifestPath)
+		if g.useNsjail() {
+			rule.SandboxInputs().Nsjail()
+			nsjailRemoteExec(ctx, rule)
+		} else {
+			rule.SandboxTools()
+		}
 		cmd := rule.Command()
 
 		for _, out := range task.out {
@@ -737,7 +761,12 @@ func NewGenSrcs() *Module {
 			// TODO(ccross): this RuleBuilder is a hack to be able to call
 			// rule.Command().PathForOutput.  Replace this with passing the rule into the
 			// generator.
-			rule := android.NewRuleBuilder(pctx, ctx).Sbox(genDir, nil).SandboxTools()
+			rule := android.NewRuleBuilder(pctx, ctx).Sbox(genDir, nil)
+			if ctx.Module().(*Module).useNsjail() {
+				rule.SandboxInputs()
+			} else {
+				rule.SandboxTools()
+			}
 
 			for _, in := range shard {
 				outFile := android.GenPathWithExt(ctx, finalSubDir, in, String(properties.Output_extension))
@@ -758,7 +787,7 @@ func NewGenSrcs() *Module {
 				command, err := android.Expand(rawCommand, func(name string) (string, error) {
 					switch name {
 					case "in":
-						return in.String(), nil
+						return rule.Command().PathForInput(in), nil
 					case "out":
 						return rule.Command().PathForOutput(outFile), nil
 					case "depfile":
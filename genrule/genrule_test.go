@@ -1052,6 +1052,115 @@ func TestGenruleWithGlobPaths(t *testing.T) {
 	}
 }
 
+func TestGenruleNsjail(t *testing.T) {
+	bp := `
+		genrule {
+			name: "gen",
+			tools: ["tool"],
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "$(location) $(in) > $(out)",
+			use_nsjail: true,
+		}
+	`
+	result := prepareForGenRuleTest.RunTestWithBp(t, testGenruleBp()+bp)
+
+	gen := result.ModuleForTests("gen", "")
+	manifest := android.RuleBuilderSboxProtoForTests(t, gen.Output("genrule.sbox.textproto"))
+	cmd := manifest.Commands[0].GetCommand()
+	android.AssertStringDoesContain(t, "command", cmd,
+		`__SBOX_SANDBOX_DIR__/tools/src/prebuilts/build-tools/linux-x86/bin/nsjail -B "$PWD" --cwd "$PWD"`)
+	android.AssertStringDoesContain(t, "command", cmd,
+		`-- /bin/bash -c '__SBOX_SANDBOX_DIR__/tools/out/bin/tool in1 > __SBOX_SANDBOX_DIR__/out/out'`)
+	android.AssertBoolEquals(t, "chdir", true, manifest.Commands[0].GetChdir())
+
+	var copied []string
+	for _, copy := range manifest.Commands[0].GetCopyBefore() {
+		copied = append(copied, copy.GetTo())
+	}
+	android.AssertStringListContains(t, "copied into the sandbox", copied, "in1")
+	android.AssertStringListContains(t, "copied into the sandbox", copied,
+		"tools/src/prebuilts/build-tools/linux-x86/bin/nsjail")
+}
+
+func TestGenruleNsjailRemoteExec(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.UseRBE = proptools.BoolPtr(true)
+		}),
+		android.FixtureMergeEnv(map[string]string{
+			"RBE_GENRULE_NSJAIL":               "true",
+			"RBE_GENRULE_NSJAIL_EXEC_STRATEGY": "local",
+		}),
+	).RunTestWithBp(t, testGenruleBp()+`
+		genrule {
+			name: "gen",
+			tools: ["tool"],
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "$(location) $(in) > $(out)",
+			use_nsjail: true,
+		}
+	`)
+
+	gen := result.ModuleForTests("gen", "").Output("out")
+	android.AssertStringDoesContain(t, "command", gen.RuleParams.Command, "--exec_strategy=local")
+	android.AssertStringDoesContain(t, "command", gen.RuleParams.Command, "Pool=default")
+}
+
+func TestGenruleNsjailErrors(t *testing.T) {
+	prepareForGenRuleTest.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`use_nsjail: cannot run the command inside nsjail, depfile: the command reads dependencies that are not declared`)).
+		RunTestWithBp(t, testGenruleBp()+`
+			genrule {
+				name: "gen",
+				tools: ["tool"],
+				out: ["out"],
+				depfile: true,
+				cmd: "$(location) > $(out) && touch $(depfile)",
+				use_nsjail: true,
+			}
+		`)
+}
+
+func TestGenruleNsjailReport(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForGenRuleTest,
+		PrepareForTestWithNsjailReport,
+		android.FixtureAddTextFile("vendor/acme/Android.bp", `
+			genrule {
+				name: "gen_depfile",
+				tools: ["tool"],
+				out: ["out"],
+				depfile: true,
+				cmd: "$(location) > $(out) && touch $(depfile)",
+			}
+
+			genrule {
+				name: "gen_sandboxable",
+				tools: ["tool"],
+				out: ["out"],
+				cmd: "$(location) > $(out)",
+			}
+
+			genrule {
+				name: "gen_nsjail",
+				tools: ["tool"],
+				out: ["out"],
+				cmd: "$(location) > $(out)",
+				use_nsjail: true,
+			}
+		`),
+	).RunTestWithBp(t, testGenruleBp())
+
+	report := result.SingletonForTests("genrule_nsjail_report").Output("genrule_nsjail_report.txt")
+	android.AssertStringEquals(t, "report",
+		"//vendor/acme:gen_depfile: depfile: the command reads dependencies that are not declared\n",
+		android.ContentFromFileRuleForTests(t, report))
+}
+
 type testTool struct {
 	android.ModuleBase
 	outputFile android.Path
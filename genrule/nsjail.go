@@ -0,0 +1,124 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genrule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+	"android/soong/remoteexec"
+)
+
+// The genrules with use_nsjail: true run their command inside nsjail, with only the tools and
+// inputs that they declare, so that e.g. the proprietary code generators of vendor genrules are
+// run hermetically and can be run remotely and cached with RBE. The genrule_nsjail_report
+// singleton lists the other genrules that cannot be run inside nsjail yet, and why.
+
+func init() {
+	registerNsjailReportBuildComponents(android.InitRegistrationContext)
+}
+
+func registerNsjailReportBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("genrule_nsjail_report", nsjailReportSingletonFactory)
+}
+
+var PrepareForTestWithNsjailReport = android.FixtureRegisterWithContext(registerNsjailReportBuildComponents)
+
+// NsjailCompatibilityInfo is provided by the genrules that do not run their command inside nsjail.
+type NsjailCompatibilityInfo struct {
+	// The reasons why the genrule cannot run its command inside nsjail, empty if it can.
+	Blockers []string
+}
+
+var NsjailCompatibilityInfoProvider = blueprint.NewProvider(NsjailCompatibilityInfo{})
+
+func (g *Module) useNsjail() bool {
+	return Bool(g.properties.Use_nsjail)
+}
+
+// nsjailBlockers returns the reasons why the genrule cannot run its command inside nsjail, where
+// it can only read the tools and inputs that it declares.
+func (g *Module) nsjailBlockers(ctx android.ModuleContext) []string {
+	var blockers []string
+	if Bool(g.properties.Depfile) {
+		blockers = append(blockers, "depfile: the command reads dependencies that are not declared")
+	}
+	if ctx.DeviceConfig().BuildBrokenInputDir(g.Name()) {
+		blockers = append(blockers, "srcs: directories are used as inputs (BUILD_BROKEN_INPUT_DIR_MODULES)")
+	}
+	return blockers
+}
+
+// checkNsjail reports an error if the genrule uses nsjail but cannot run its command inside
+// nsjail, or provides the reasons why it cannot if it does not use nsjail.
+func (g *Module) checkNsjail(ctx android.ModuleContext) {
+	blockers := g.nsjailBlockers(ctx)
+	if !g.useNsjail() {
+		ctx.SetProvider(NsjailCompatibilityInfoProvider, NsjailCompatibilityInfo{Blockers: blockers})
+		return
+	}
+	for _, blocker := range blockers {
+		ctx.PropertyErrorf("use_nsjail", "cannot run the command inside nsjail, %s", blocker)
+	}
+}
+
+// nsjailRemoteExec runs the rule of a genrule that uses nsjail with RBE if RBE_GENRULE_NSJAIL is
+// set, the rule is hermetic so its outputs are cached remotely.
+func nsjailRemoteExec(ctx android.ModuleContext, rule *android.RuleBuilder) {
+	if !ctx.Config().UseRBEGenruleNsjail() {
+		return
+	}
+	rule.Remoteable(android.RemoteRuleSupports{RBE: true})
+	rule.Rewrapper(&remoteexec.REParams{
+		Labels:       map[string]string{"type": "tool", "name": "genrule"},
+		ExecStrategy: ctx.Config().RBEGenruleNsjailExecStrategy(),
+		Platform: map[string]string{
+			remoteexec.PoolKey: ctx.Config().RBEGenruleNsjailPool(),
+		},
+	})
+}
+
+func nsjailReportSingletonFactory() android.Singleton {
+	return &nsjailReportSingleton{}
+}
+
+// nsjailReportSingleton writes the report of the genrules that cannot run their command inside
+// nsjail yet, built by the genrule-nsjail-report phony target.
+type nsjailReportSingleton struct{}
+
+func (s *nsjailReportSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	blockers := make(map[string][]string)
+	ctx.VisitAllModules(func(module android.Module) {
+		if !module.Enabled() || !ctx.ModuleHasProvider(module, NsjailCompatibilityInfoProvider) {
+			return
+		}
+		info := ctx.ModuleProvider(module, NsjailCompatibilityInfoProvider).(NsjailCompatibilityInfo)
+		if len(info.Blockers) > 0 {
+			blockers["//"+ctx.ModuleDir(module)+":"+ctx.ModuleName(module)] = info.Blockers
+		}
+	})
+
+	var b strings.Builder
+	for _, name := range android.SortedKeys(blockers) {
+		fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(blockers[name], "; "))
+	}
+
+	report := android.PathForOutput(ctx, "genrule_nsjail_report.txt")
+	android.WriteFileRule(ctx, report, b.String())
+	ctx.Phony("genrule-nsjail-report", report)
+}
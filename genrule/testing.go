@@ -0,0 +1,74 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package genrule
+
+import (
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+type testCodegenProperties struct {
+	Interfaces []string
+	Out_dir    *string
+	Tool       *string
+
+	// The extension of the generated sources, defaults to "c".
+	Src_extension *string
+}
+
+// testCodegenPlugin generates a source and a header for each of its interfaces.
+type testCodegenPlugin struct {
+	properties testCodegenProperties
+}
+
+type testCodegenToolDepTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (p *testCodegenPlugin) CodegenProperties() []interface{} {
+	return []interface{}{&p.properties}
+}
+
+func (p *testCodegenPlugin) CodegenDeps(ctx android.BottomUpMutatorContext) {
+	if tool := proptools.String(p.properties.Tool); tool != "" {
+		ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(), testCodegenToolDepTag{}, tool)
+	}
+}
+
+func (p *testCodegenPlugin) GenerateCode(ctx android.ModuleContext) CodegenInfo {
+	var info CodegenInfo
+	ext := proptools.StringDefault(p.properties.Src_extension, "c")
+	for _, name := range p.properties.Interfaces {
+		var src android.WritablePath = android.PathForModuleGen(ctx, name+"."+ext)
+		header := android.PathForModuleGen(ctx, "include", name+".h")
+		if dir := proptools.String(p.properties.Out_dir); dir != "" {
+			src = android.PathForModuleOut(ctx, dir, name+"."+ext)
+		}
+		android.WriteFileRule(ctx, src, "#include \""+name+".h\"")
+		android.WriteFileRule(ctx, header, "void "+name+"();")
+		info.Srcs = append(info.Srcs, src)
+		info.Headers = append(info.Headers, header)
+	}
+	return info
+}
+
+// PrepareForTestWithCodegenPlugin registers the test_codegen module type, the module type of a
+// code generator plugin that generates a source and a header for each of its interfaces, for the
+// tests of the modules that use the outputs of code generators.
+var PrepareForTestWithCodegenPlugin = android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+	RegisterCodegenModuleType(ctx, "test_codegen", func() CodegenPlugin { return &testCodegenPlugin{} })
+})
@@ -113,6 +113,7 @@ type aapt struct {
 	hasNoCode               bool
 	LoggingParent           string
 	resourceFiles           android.Paths
+	resourceOverlayDirs     android.Paths
 
 	splitNames []string
 	splits     []split
@@ -182,6 +183,15 @@ func (a *aapt) aapt2Flags(ctx android.ModuleContext, sdkContext android.SdkConte
 	resourceDirs := android.PathsWithOptionalDefaultForModuleSrc(ctx, a.aaptProperties.Resource_dirs, "res")
 	resourceZips := android.PathsForModuleSrc(ctx, a.aaptProperties.Resource_zips)
 
+	// The resource overlays of the module come before the ones of the product, so that the product
+	// can overlay them too.
+	for _, dir := range a.resourceOverlayDirs {
+		overlayDirs = append(overlayDirs, globbedResourceDir{
+			dir:   dir,
+			files: androidResourceGlob(ctx, dir),
+		})
+	}
+
 	// Glob directories into lists of paths
 	for _, dir := range resourceDirs {
 		resDirs = append(resDirs, globbedResourceDir{
@@ -532,6 +542,10 @@ func (a *AndroidLibrary) OutputFiles(tag string) (android.Paths, error) {
 	}
 }
 
+func (a *AndroidLibrary) OutputFileTags() []string {
+	return append(a.Library.OutputFileTags(), ".aar")
+}
+
 func (a *AndroidLibrary) ExportedStaticPackages() android.Paths {
 	return a.exportedStaticPackages
 }
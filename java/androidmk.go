@@ -631,6 +631,15 @@ func (dstubs *Droidstubs) AndroidMkEntries() []android.AndroidMkEntries {
 					fmt.Fprintln(w, "checkapi:",
 						dstubs.checkLastReleasedApiTimestamp.String())
 
+					// The frozen API check is only done with the last released API check.
+					if dstubs.checkFrozenApiTimestamp != nil {
+						fmt.Fprintln(w, ".PHONY:", dstubs.Name()+"-check-frozen-api")
+						fmt.Fprintln(w, dstubs.Name()+"-check-frozen-api:",
+							dstubs.checkFrozenApiTimestamp.String())
+						fmt.Fprintln(w, "checkapi:",
+							dstubs.checkFrozenApiTimestamp.String())
+					}
+
 					fmt.Fprintln(w, ".PHONY: droidcore")
 					fmt.Fprintln(w, "droidcore: checkapi")
 				}
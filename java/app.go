@@ -65,6 +65,10 @@ type appProperties struct {
 	// list of resource labels to generate individual resource packages
 	Package_splits []string
 
+	// Product flavors of the app, each flavor listed in the soong config variable
+	// app_flavors.<module name> is built as an override of the app, see app_flavor.go.
+	Flavors []appFlavorProperties
+
 	// list of native libraries that will be provided in or alongside the resulting jar
 	Jni_libs []string `android:"arch_variant"`
 
@@ -122,7 +126,8 @@ type appProperties struct {
 // android_app properties that can be overridden by override_android_app
 type overridableAppProperties struct {
 	// The name of a certificate in the default certificate directory, blank to use the default product certificate,
-	// or an android_app_certificate module name in the form ":module".
+	// an android_app_certificate module name in the form ":module", or a certificate alias of the product in the
+	// form "@alias", see AppCertificateAliases.
 	Certificate *string
 
 	// Name of the signing certificate lineage file or filegroup module.
@@ -140,6 +145,10 @@ type overridableAppProperties struct {
 	// Whether to rename the package in resources to the override name rather than the base name. Defaults to true.
 	Rename_resources_package *bool
 
+	// List of directories relative to the Blueprints file containing resources that overlay the
+	// resources of the app.
+	Resource_overlay_dirs []string `android:"path"`
+
 	// Names of modules to be overridden. Listed modules can only be other binaries
 	// (in Make or Soong).
 	// This does not completely prevent installation of the overridden binaries, but if both
@@ -181,6 +190,8 @@ type AndroidApp struct {
 	android.ApexBundleDepsInfo
 
 	javaApiUsedByOutputFile android.ModuleOutPath
+
+	appInstallState
 }
 
 func (a *AndroidApp) IsInstallable() bool {
@@ -263,6 +274,8 @@ func (a *AndroidApp) DepsMutator(ctx android.BottomUpMutatorContext) {
 	}
 
 	a.usesLibrary.deps(ctx, sdkDep.hasFrameworkLibs())
+
+	manifestMergerPolicyDeps(ctx)
 }
 
 func (a *AndroidApp) OverridablePropertiesDepsMutator(ctx android.BottomUpMutatorContext) {
@@ -304,6 +317,7 @@ func (a *AndroidApp) checkAppSdkVersions(ctx android.ModuleContext) {
 		if minSdkVersion, err := a.MinSdkVersion(ctx).EffectiveVersion(ctx); err == nil {
 			a.checkJniLibsSdkVersion(ctx, minSdkVersion)
 			android.CheckMinSdkVersion(ctx, minSdkVersion, a.WalkPayloadDeps)
+			android.CheckUpdatableConsistency(ctx, minSdkVersion, a.WalkPayloadDeps)
 		} else {
 			ctx.PropertyErrorf("min_sdk_version", "%s", err.Error())
 		}
@@ -442,6 +456,7 @@ func (a *AndroidApp) aaptBuildActions(ctx android.ModuleContext) {
 
 	a.aapt.splitNames = a.appProperties.Package_splits
 	a.aapt.LoggingParent = String(a.overridableAppProperties.Logging_parent)
+	a.aapt.resourceOverlayDirs = android.PathsForModuleSrc(ctx, a.overridableAppProperties.Resource_overlay_dirs)
 	if a.Updatable() {
 		a.aapt.defaultManifestVersion = android.DefaultUpdatableModuleVersion
 	}
@@ -570,7 +585,7 @@ func processMainCert(m android.ModuleBase, certPropValue string, certificates []
 	} else {
 		// This can be reached with an empty certificate list if AllowMissingDependencies is set
 		// and the certificate property for this module is a module reference to a missing module.
-		if !ctx.Config().AllowMissingDependencies() && len(ctx.GetMissingDependencies()) > 0 {
+		if !ctx.AllowMissingDependencies() && len(ctx.GetMissingDependencies()) > 0 {
 			panic("Should only get here if AllowMissingDependencies set and there are missing dependencies")
 		}
 		// Set a certificate to avoid panics later when accessing it.
@@ -664,6 +679,9 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 		apkDeps = append(apkDeps, manifestCheckFile)
 	}
 
+	// Check that the manifest complies with the manifest policies of the app.
+	apkDeps = append(apkDeps, a.checkManifestMergerPolicies(ctx)...)
+
 	a.proguardBuildActions(ctx)
 
 	a.linter.mergedManifest = a.aapt.mergedManifestFile
@@ -680,7 +698,13 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 		return
 	}
 
-	a.certificate, certificates = processMainCert(a.ModuleBase, a.getCertString(ctx), certificates, ctx)
+	certString := a.getCertString(ctx)
+	if strings.HasPrefix(certString, certificateAliasPrefix) {
+		ctx.PropertyErrorf("certificate", "unknown certificate alias %q, it must be in the AppCertificateAliases of the product",
+			strings.TrimPrefix(certString, certificateAliasPrefix))
+		return
+	}
+	a.certificate, certificates = processMainCert(a.ModuleBase, certString, certificates, ctx)
 
 	// Build a final signed app package.
 	packageFile := android.PathForModuleOut(ctx, a.installApkName+".apk")
@@ -692,6 +716,8 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 	var lineageFile android.Path
 	if lineage := String(a.overridableAppProperties.Lineage); lineage != "" {
 		lineageFile = android.PathForModuleSrc(ctx, lineage)
+	} else if lineage := a.getCertAliasLineage(ctx); lineage != "" {
+		lineageFile = android.PathForSource(ctx, lineage)
 	}
 	rotationMinSdkVersion := String(a.overridableAppProperties.RotationMinSdkVersion)
 
@@ -747,7 +773,8 @@ func (a *AndroidApp) generateAndroidBuildActions(ctx android.ModuleContext) {
 			installed := ctx.InstallFile(a.installDir, extra.Base(), extra)
 			extraInstalledPaths = append(extraInstalledPaths, installed)
 		}
-		ctx.InstallFile(a.installDir, a.outputFile.Base(), a.outputFile, extraInstalledPaths...)
+		installed := ctx.InstallFile(a.installDir, a.outputFile.Base(), a.outputFile, extraInstalledPaths...)
+		a.recordInstallState(ctx, installed)
 	}
 
 	a.buildAppDependencyInfo(ctx)
@@ -857,7 +884,7 @@ func collectAppDeps(ctx android.ModuleContext, app appDepsInterface,
 						unstrippedFile: dep.UnstrippedOutputFile(),
 						partition:      dep.Partition(),
 					})
-				} else if ctx.Config().AllowMissingDependencies() {
+				} else if ctx.AllowMissingDependencies() {
 					ctx.AddMissingDependencies([]string{otherName})
 				} else {
 					ctx.ModuleErrorf("dependency %q missing output file", otherName)
@@ -962,12 +989,38 @@ func (a *AndroidApp) SetUpdatable(val bool) {
 	a.appProperties.Updatable = &val
 }
 
+// certificateAliasPrefix is the prefix of the certificate aliases of the product in the
+// certificate property.
+const certificateAliasPrefix = "@"
+
+// getCertString returns the certificate of the app, with its certificate alias, if any, resolved.
+// An unknown certificate alias is returned as is.
 func (a *AndroidApp) getCertString(ctx android.BaseModuleContext) string {
 	certificate, overridden := ctx.DeviceConfig().OverrideCertificateFor(ctx.ModuleName())
 	if overridden {
 		return ":" + certificate
 	}
-	return String(a.overridableAppProperties.Certificate)
+	certificate = String(a.overridableAppProperties.Certificate)
+	if alias := strings.TrimPrefix(certificate, certificateAliasPrefix); alias != certificate {
+		if resolved, _, ok := ctx.DeviceConfig().AppCertificateAlias(alias); ok {
+			return resolved
+		}
+	}
+	return certificate
+}
+
+// getCertAliasLineage returns the signing certificate lineage file of the certificate alias of the
+// app, if any.
+func (a *AndroidApp) getCertAliasLineage(ctx android.BaseModuleContext) string {
+	if _, overridden := ctx.DeviceConfig().OverrideCertificateFor(ctx.ModuleName()); overridden {
+		return ""
+	}
+	certificate := String(a.overridableAppProperties.Certificate)
+	if alias := strings.TrimPrefix(certificate, certificateAliasPrefix); alias != certificate {
+		_, lineage, _ := ctx.DeviceConfig().AppCertificateAlias(alias)
+		return lineage
+	}
+	return ""
 }
 
 func (a *AndroidApp) DepIsInSameApex(ctx android.BaseModuleContext, dep android.Module) bool {
@@ -988,6 +1041,10 @@ func (a *AndroidApp) OutputFiles(tag string) (android.Paths, error) {
 	return a.Library.OutputFiles(tag)
 }
 
+func (a *AndroidApp) OutputFileTags() []string {
+	return append(a.Library.OutputFileTags(), ".aapt.srcjar", ".export-package.apk")
+}
+
 func (a *AndroidApp) Privileged() bool {
 	return Bool(a.appProperties.Privileged)
 }
@@ -1023,12 +1080,16 @@ func AndroidAppFactory() android.Module {
 	module.AddProperties(
 		&module.aaptProperties,
 		&module.appProperties,
-		&module.overridableAppProperties)
+		&module.overridableAppProperties,
+		&module.installStateProperties)
 
 	module.usesLibrary.enforce = true
 
 	android.InitAndroidMultiTargetsArchModule(module, android.DeviceSupported, android.MultilibCommon)
 	android.InitDefaultableModule(module)
+	module.SetDefaultableHook(func(ctx android.DefaultableHookContext) {
+		createAppFlavors(ctx, module)
+	})
 	android.InitOverridableModule(module, &module.overridableAppProperties.Overrides)
 	android.InitApexModule(module)
 	android.InitBazelModule(module)
@@ -1439,7 +1500,7 @@ func (u *usesLibrary) classLoaderContextForUsesLibDeps(ctx android.ModuleContext
 			clcMap.AddContext(ctx, tag.sdkVersion, libName, tag.optional,
 				lib.DexJarBuildPath().PathOrNil(), lib.DexJarInstallPath(),
 				lib.ClassLoaderContexts())
-		} else if ctx.Config().AllowMissingDependencies() {
+		} else if ctx.AllowMissingDependencies() {
 			ctx.AddMissingDependencies([]string{dep})
 		} else {
 			ctx.ModuleErrorf("module %q in uses_libs or optional_uses_libs must be a java library", dep)
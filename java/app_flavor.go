@@ -0,0 +1,127 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+// This file contains the product flavors of android_app, which build variants of an app with a
+// different package name, resources and certificate from a single module, for example:
+//
+//	android_app {
+//	    name: "Launcher",
+//	    package_name: "com.android.launcher",
+//	    flavors: [
+//	        {
+//	            name: "acme",
+//	            package_name_suffix: ".acme",
+//	            resource_overlay_dirs: ["flavors/acme/res"],
+//	            certificate: ":acme_certificate",
+//	        },
+//	    ],
+//	}
+//
+// The flavors built by a product are listed in the soong config variable app_flavors.<module name>,
+// for example with SOONG_CONFIG_app_flavors_Launcher := acme. Each of them creates an
+// override_android_app named <module name>_<flavor name>, so the flavors of an app are built and
+// installed like any other override of the app.
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// appFlavorsConfigNamespace is the soong config namespace of the variables that list the flavors
+// built for each app.
+const appFlavorsConfigNamespace = "app_flavors"
+
+var appFlavorNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+
+type appFlavorProperties struct {
+	// Name of the flavor, appended to the name of the app to name the module of the flavor.
+	Name *string
+
+	// Suffix appended to the package_name of the app in the flavor. Requires package_name.
+	Package_name_suffix *string
+
+	// List of directories relative to the Blueprints file containing resources that overlay the
+	// resources of the app in the flavor. The overlays of the product still apply on top of them.
+	Resource_overlay_dirs []string
+
+	// The certificate of the flavor, in any of the forms supported by the certificate property.
+	// Defaults to the certificate of the app.
+	Certificate *string
+}
+
+// createAppFlavors creates an override_android_app for each of the flavors of the app that are
+// enabled by the soong config variable app_flavors.<module name>. It runs once the properties of
+// the defaults of the app are applied, as they can set the flavors and the package_name.
+func createAppFlavors(ctx android.DefaultableHookContext, a *AndroidApp) {
+	flavors := make(map[string]appFlavorProperties)
+	for _, flavor := range a.appProperties.Flavors {
+		name := proptools.String(flavor.Name)
+		if !appFlavorNameRegexp.MatchString(name) {
+			ctx.PropertyErrorf("flavors", "invalid flavor name %q, must only contain letters, digits and underscores", name)
+			continue
+		}
+		if _, exists := flavors[name]; exists {
+			ctx.PropertyErrorf("flavors", "duplicate flavor %q", name)
+			continue
+		}
+		if flavor.Package_name_suffix != nil && a.overridableAppProperties.Package_name == nil {
+			ctx.PropertyErrorf("flavors", "flavor %q sets package_name_suffix but the app does not set package_name", name)
+			continue
+		}
+		flavors[name] = flavor
+	}
+
+	enabled := ctx.Config().VendorConfig(appFlavorsConfigNamespace).String(ctx.ModuleName())
+	for _, name := range android.FirstUniqueStrings(strings.Fields(enabled)) {
+		flavor, ok := flavors[name]
+		if !ok {
+			if !android.InList(name, flavorNames(a.appProperties.Flavors)) {
+				ctx.PropertyErrorf("flavors", "unknown flavor %q in the soong config variable %s.%s",
+					name, appFlavorsConfigNamespace, ctx.ModuleName())
+			}
+			continue
+		}
+
+		props := struct {
+			Name                  *string
+			Base                  *string
+			Package_name          *string
+			Resource_overlay_dirs []string
+			Certificate           *string
+		}{
+			Name:                  proptools.StringPtr(ctx.ModuleName() + "_" + name),
+			Base:                  proptools.StringPtr(ctx.ModuleName()),
+			Resource_overlay_dirs: flavor.Resource_overlay_dirs,
+			Certificate:           flavor.Certificate,
+		}
+		if flavor.Package_name_suffix != nil {
+			props.Package_name = proptools.StringPtr(*a.overridableAppProperties.Package_name + *flavor.Package_name_suffix)
+		}
+		ctx.CreateModule(OverrideAndroidAppModuleFactory, &props)
+	}
+}
+
+func flavorNames(flavors []appFlavorProperties) []string {
+	var names []string
+	for _, flavor := range flavors {
+		names = append(names, proptools.String(flavor.Name))
+	}
+	return names
+}
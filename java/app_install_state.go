@@ -0,0 +1,146 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// The install state of a preinstalled app, e.g. whether it is installed as stopped or disabled, is
+// declared on its android_app or android_app_import module with the install_state property, so
+// that the products that want to reduce the bloat of the preinstalled apps do not need XML
+// overlays for it. The apps publish their install state with AppInstallStateInfoProvider, and the
+// app_install_state singleton collects the install state of the apps that the product installs,
+// i.e. the ones in its PRODUCT_PACKAGES, into out/soong/app_install_state.json, exported to Make
+// as SOONG_APP_INSTALL_STATE for the product packaging.
+
+func init() {
+	registerAppInstallStateBuildComponents(android.InitRegistrationContext)
+}
+
+func registerAppInstallStateBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("app_install_state", appInstallStateSingletonFactory)
+}
+
+var PrepareForTestWithAppInstallState = android.FixtureRegisterWithContext(registerAppInstallStateBuildComponents)
+
+type appInstallStateProperties struct {
+	// The state in which the app is installed when it is preinstalled on the device.
+	Install_state struct {
+		// If true, the app is installed in the stopped state, so it does not run until the user
+		// launches it.
+		Stopped *bool
+
+		// If true, the app is installed disabled, so it is not visible until it is enabled.
+		Disabled *bool
+
+		// If true, the app is installed with a demoted priority, e.g. its intent filters do not
+		// take precedence over the ones of the apps that are not demoted.
+		Demoted_priority *bool
+	}
+}
+
+// AppInstallStateInfo is the install state of an installed app, as written to
+// app_install_state.json.
+type AppInstallStateInfo struct {
+	Module          string `json:"module"`
+	Path            string `json:"path"`
+	Stopped         bool   `json:"stopped"`
+	Disabled        bool   `json:"disabled"`
+	DemotedPriority bool   `json:"demoted_priority"`
+}
+
+// AppInstallStateInfoProvider is set by the installed apps that set an install state.
+var AppInstallStateInfoProvider = blueprint.NewProvider(AppInstallStateInfo{})
+
+// appInstallState is embedded in the app modules that support the install_state property.
+type appInstallState struct {
+	installStateProperties appInstallStateProperties
+}
+
+// recordInstallState publishes the install state of the app installed at installedApk, if it sets
+// any.
+func (s *appInstallState) recordInstallState(ctx android.ModuleContext, installedApk android.InstallPath) {
+	props := s.installStateProperties.Install_state
+	info := AppInstallStateInfo{
+		Module:          android.ModuleNameWithPossibleOverride(ctx),
+		Path:            android.InstallPathToOnDevicePath(ctx, installedApk),
+		Stopped:         proptools.Bool(props.Stopped),
+		Disabled:        proptools.Bool(props.Disabled),
+		DemotedPriority: proptools.Bool(props.Demoted_priority),
+	}
+	if !info.Stopped && !info.Disabled && !info.DemotedPriority {
+		return
+	}
+	ctx.SetProvider(AppInstallStateInfoProvider, info)
+}
+
+func appInstallStatePath(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, "app_install_state.json")
+}
+
+func appInstallStateSingletonFactory() android.Singleton {
+	return &appInstallStateSingleton{}
+}
+
+type appInstallStateSingleton struct {
+	installState android.Path
+}
+
+func (s *appInstallStateSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	// Only the apps that the product installs are listed. Make sets the PRODUCT_PACKAGES of the
+	// product, without them, e.g. in tests, all the installed apps are listed.
+	productPackages := ctx.Config().ProductPackages()
+	inProduct := func(name string) bool {
+		return productPackages == nil || android.InList(name, productPackages)
+	}
+
+	entries := []AppInstallStateInfo{}
+	ctx.VisitAllModules(func(module android.Module) {
+		if !module.Enabled() || !android.IsModulePreferred(module) || module.IsSkipInstall() {
+			return
+		}
+		if !ctx.ModuleHasProvider(module, AppInstallStateInfoProvider) {
+			return
+		}
+		info := ctx.ModuleProvider(module, AppInstallStateInfoProvider).(AppInstallStateInfo)
+		if inProduct(info.Module) {
+			entries = append(entries, info)
+		}
+	})
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal the app install state: %s", err)
+		return
+	}
+
+	installState := appInstallStatePath(ctx)
+	android.WriteFileRule(ctx, installState, string(content))
+	s.installState = installState
+}
+
+func (s *appInstallStateSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.installState != nil {
+		ctx.Strict("SOONG_APP_INSTALL_STATE", s.installState.String())
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+const appInstallStateTestBp = `
+	android_app {
+		name: "foo",
+		srcs: ["a.java"],
+		sdk_version: "current",
+		privileged: true,
+		install_state: {
+			stopped: true,
+			demoted_priority: true,
+		},
+	}
+
+	android_app {
+		name: "bar",
+		srcs: ["a.java"],
+		sdk_version: "current",
+	}
+
+	android_app_import {
+		name: "baz",
+		apk: "prebuilts/apk/app.apk",
+		certificate: "platform",
+		install_state: {
+			disabled: true,
+		},
+	}
+`
+
+func TestAppInstallState(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithAppInstallState,
+	).RunTestWithBp(t, appInstallStateTestBp)
+
+	output := result.SingletonForTests("app_install_state").Output("app_install_state.json")
+	android.AssertStringEquals(t, "app install state", `[
+  {
+    "module": "baz",
+    "path": "/system/app/baz/baz.apk",
+    "stopped": false,
+    "disabled": true,
+    "demoted_priority": false
+  },
+  {
+    "module": "foo",
+    "path": "/system/priv-app/foo/foo.apk",
+    "stopped": true,
+    "disabled": false,
+    "demoted_priority": true
+  }
+]`, android.ContentFromFileRuleForTests(t, output))
+}
+
+func TestAppInstallStateProductPackages(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithAppInstallState,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.ProductPackages = []string{"foo", "bar"}
+		}),
+	).RunTestWithBp(t, appInstallStateTestBp)
+
+	// baz is not installed by the product.
+	output := result.SingletonForTests("app_install_state").Output("app_install_state.json")
+	android.AssertStringEquals(t, "app install state", `[
+  {
+    "module": "foo",
+    "path": "/system/priv-app/foo/foo.apk",
+    "stopped": true,
+    "disabled": false,
+    "demoted_priority": true
+  }
+]`, android.ContentFromFileRuleForTests(t, output))
+}
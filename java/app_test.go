@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 	"testing"
@@ -1506,6 +1507,8 @@ func TestCertificates(t *testing.T) {
 		bp                       string
 		allowMissingDependencies bool
 		certificateOverride      string
+		certificateAliases       map[string]string
+		certificateLineages      map[string]string
 		expectedCertSigningFlags string
 		expectedCertificate      string
 	}{
@@ -1621,6 +1624,56 @@ func TestCertificates(t *testing.T) {
 			expectedCertSigningFlags: "--lineage lineage.bin --rotation-min-sdk-version 32",
 			expectedCertificate:      "cert/new_cert",
 		},
+		{
+			name: "certificate alias",
+			bp: `
+				android_app {
+					name: "foo",
+					srcs: ["a.java"],
+					certificate: "@release",
+					sdk_version: "current",
+				}
+
+				android_app_certificate {
+					name: "new_certificate",
+					certificate: "cert/new_cert",
+				}
+			`,
+			certificateAliases:       map[string]string{"release": ":new_certificate"},
+			certificateLineages:      map[string]string{"release": "cert/release.lineage"},
+			expectedCertSigningFlags: "--lineage cert/release.lineage",
+			expectedCertificate:      "cert/new_cert",
+		},
+		{
+			name: "certificate alias in the default certificate directory",
+			bp: `
+				android_app {
+					name: "foo",
+					srcs: ["a.java"],
+					certificate: "@release",
+					sdk_version: "current",
+				}
+			`,
+			certificateAliases:       map[string]string{"release": "platform"},
+			expectedCertSigningFlags: "",
+			expectedCertificate:      "build/make/target/product/security/platform",
+		},
+		{
+			name: "certificate alias with lineage property",
+			bp: `
+				android_app {
+					name: "foo",
+					srcs: ["a.java"],
+					certificate: "@release",
+					lineage: "lineage.bin",
+					sdk_version: "current",
+				}
+			`,
+			certificateAliases:       map[string]string{"release": "platform"},
+			certificateLineages:      map[string]string{"release": "cert/release.lineage"},
+			expectedCertSigningFlags: "--lineage lineage.bin",
+			expectedCertificate:      "build/make/target/product/security/platform",
+		},
 		{
 			name: "missing with AllowMissingDependencies",
 			bp: `
@@ -1644,6 +1697,8 @@ func TestCertificates(t *testing.T) {
 					if test.certificateOverride != "" {
 						variables.CertificateOverrides = []string{test.certificateOverride}
 					}
+					variables.AppCertificateAliases = test.certificateAliases
+					variables.AppCertificateAliasLineages = test.certificateLineages
 					if test.allowMissingDependencies {
 						variables.Allow_missing_dependencies = proptools.BoolPtr(true)
 					}
@@ -1651,6 +1706,7 @@ func TestCertificates(t *testing.T) {
 				android.FixtureModifyContext(func(ctx *android.TestContext) {
 					ctx.SetAllowMissingDependencies(test.allowMissingDependencies)
 				}),
+				android.FixtureAddFile("cert/release.lineage", nil),
 			).RunTestWithBp(t, test.bp)
 
 			foo := result.ModuleForTests("foo", "android_common")
@@ -1674,6 +1730,24 @@ func TestCertificates(t *testing.T) {
 	}
 }
 
+func TestCertificateAliasErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.AppCertificateAliases = map[string]string{"release": "platform"}
+		}),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`certificate: unknown certificate alias "debug", it must be in the AppCertificateAliases of the product`)).
+		RunTestWithBp(t, `
+			android_app {
+				name: "foo",
+				srcs: ["a.java"],
+				certificate: "@debug",
+				sdk_version: "current",
+			}
+		`)
+}
+
 func TestRequestV4SigningFlag(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -2030,6 +2104,183 @@ func TestOverrideAndroidApp(t *testing.T) {
 	}
 }
 
+func TestAndroidAppFlavors(t *testing.T) {
+	bp := `
+		android_app {
+			name: "foo",
+			srcs: ["a.java"],
+			package_name: "com.android.foo",
+			sdk_version: "current",
+			flavors: [
+				{
+					name: "acme",
+					package_name_suffix: ".acme",
+					resource_overlay_dirs: ["flavors/acme/res"],
+					certificate: ":acme_certificate",
+				},
+				{
+					name: "beta",
+					package_name_suffix: ".beta",
+				},
+				{
+					name: "unused",
+				},
+			],
+		}
+
+		android_app_certificate {
+			name: "acme_certificate",
+			certificate: "cert/acme",
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureMergeMockFs(android.MockFS{
+			"flavors/acme/res/values/strings.xml": nil,
+		}),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.VendorVars = map[string]map[string]string{
+				"app_flavors": {"foo": "acme beta"},
+			}
+		}),
+	).RunTestWithBp(t, bp)
+
+	expectedVariants := []struct {
+		variantName string
+		apkName     string
+		certFlag    string
+		packageFlag string
+		overlays    []string
+	}{
+		{
+			variantName: "android_common",
+			apkName:     "foo.apk",
+			certFlag:    "build/make/target/product/security/testkey.x509.pem build/make/target/product/security/testkey.pk8",
+			packageFlag: "com.android.foo",
+		},
+		{
+			variantName: "android_common_foo_acme",
+			apkName:     "foo_acme.apk",
+			certFlag:    "cert/acme.x509.pem cert/acme.pk8",
+			packageFlag: "com.android.foo.acme",
+			overlays:    []string{"flavors/acme/res/values/strings.xml"},
+		},
+		{
+			variantName: "android_common_foo_beta",
+			apkName:     "foo_beta.apk",
+			certFlag:    "build/make/target/product/security/testkey.x509.pem build/make/target/product/security/testkey.pk8",
+			packageFlag: "com.android.foo.beta",
+		},
+	}
+	for _, expected := range expectedVariants {
+		t.Run(expected.variantName, func(t *testing.T) {
+			variant := result.ModuleForTests("foo", expected.variantName)
+
+			signapk := variant.Output(expected.apkName)
+			android.AssertStringEquals(t, "certificates flags", expected.certFlag, signapk.Args["certificates"])
+
+			res := variant.Output("package-res.apk")
+			checkAapt2LinkFlag(t, res.Args["flags"], "rename-manifest-package", expected.packageFlag)
+
+			var overlays []string
+			if overlayList := variant.MaybeOutput("aapt2/overlay.list"); overlayList.Rule != nil {
+				for _, o := range android.PathsRelativeToTop(overlayList.Inputs) {
+					overlays = append(overlays, variant.Output(o).Inputs.Strings()...)
+				}
+			}
+			android.AssertDeepEquals(t, "overlay files", expected.overlays, overlays)
+		})
+	}
+
+	if variants := result.ModuleVariantsForTests("foo"); android.InList("android_common_foo_unused", variants) {
+		t.Errorf("unexpected variant of the flavor that is not enabled, found %q", variants)
+	}
+}
+
+func TestAndroidAppFlavorsFromDefaults(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.VendorVars = map[string]map[string]string{
+				"app_flavors": {"foo": "acme"},
+			}
+		}),
+	).RunTestWithBp(t, `
+		java_defaults {
+			name: "foo_defaults",
+			package_name: "com.android.foo",
+			flavors: [
+				{
+					name: "acme",
+					package_name_suffix: ".acme",
+				},
+			],
+		}
+
+		android_app {
+			name: "foo",
+			defaults: ["foo_defaults"],
+			srcs: ["a.java"],
+			sdk_version: "current",
+		}
+	`)
+
+	res := result.ModuleForTests("foo", "android_common_foo_acme").Output("package-res.apk")
+	checkAapt2LinkFlag(t, res.Args["flags"], "rename-manifest-package", "com.android.foo.acme")
+}
+
+func TestAndroidAppFlavorsErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		flavors       string
+		enabled       string
+		expectedError string
+	}{
+		{
+			name:          "package_name_suffix without package_name",
+			flavors:       `{ name: "acme", package_name_suffix: ".acme" }`,
+			expectedError: `flavor "acme" sets package_name_suffix but the app does not set package_name`,
+		},
+		{
+			name:          "duplicate flavor",
+			flavors:       `{ name: "acme" }, { name: "acme" }`,
+			expectedError: `duplicate flavor "acme"`,
+		},
+		{
+			name:          "invalid name",
+			flavors:       `{ name: "acme.beta" }`,
+			expectedError: `invalid flavor name "acme.beta"`,
+		},
+		{
+			name:          "unknown flavor",
+			flavors:       `{ name: "acme" }`,
+			enabled:       "acme beta",
+			expectedError: `unknown flavor "beta" in the soong config variable app_flavors.foo`,
+		},
+	}
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			android.GroupFixturePreparers(
+				PrepareForTestWithJavaDefaultModules,
+				android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+					variables.VendorVars = map[string]map[string]string{
+						"app_flavors": {"foo": test.enabled},
+					}
+				}),
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(regexp.QuoteMeta(test.expectedError))).
+				RunTestWithBp(t, `
+					android_app {
+						name: "foo",
+						srcs: ["a.java"],
+						sdk_version: "current",
+						flavors: [`+test.flavors+`],
+					}
+				`)
+		})
+	}
+}
+
 func TestOverrideAndroidAppOverrides(t *testing.T) {
 	ctx, _ := testJava(
 		t, `
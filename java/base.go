@@ -182,6 +182,16 @@ type CommonProperties struct {
 	// If true, package the kotlin stdlib into the jar.  Defaults to true.
 	Static_kotlin_stdlib *bool `android:"arch_variant"`
 
+	// The version of the prebuilt kotlinc toolchain in prebuilts/kotlinc that compiles the Kotlin
+	// sources, instead of the toolchain in external/kotlinc.
+	Kotlin_version *string
+
+	// The version of the kotlin stdlib to compile and package the Kotlin sources against, instead of
+	// the default kotlin stdlib. It must be one of the versions of the prebuilt kotlinc toolchains,
+	// whose stdlib jars are used, and the libs and static_libs of the module must use the same
+	// version, i.e. they must not pin another version or use the default kotlin stdlib.
+	Kotlin_stdlib_version *string
+
 	// A list of java_library instances that provide additional hiddenapi annotations for the library.
 	Hiddenapi_additional_annotations []string
 }
@@ -404,6 +414,9 @@ type Module struct {
 	protoProperties  android.ProtoProperties
 	deviceProperties DeviceProperties
 
+	// The gRPC properties of java_grpc_library modules, nil for the other module types.
+	grpcProperties *GrpcProperties
+
 	overridableDeviceProperties OverridableDeviceProperties
 
 	// jar file containing header classes including static library dependencies, suitable for
@@ -428,6 +441,10 @@ type Module struct {
 	// output file containing classes.dex and resources
 	dexJarFile OptionalDexJarPath
 
+	// output file containing classes.dex and resources built from the uninstrumented classes, only
+	// set for platform boot jars in coverage builds
+	uninstrumentedDexJarFile OptionalDexJarPath
+
 	// output file containing uninstrumented classes that will be instrumented by jacoco
 	jacocoReportClassesFile android.Path
 
@@ -604,7 +621,12 @@ func (j *Module) OutputFiles(tag string) (android.Paths, error) {
 	}
 }
 
+func (j *Module) OutputFileTags() []string {
+	return []string{".jar", ".hjar", ".proguard_map"}
+}
+
 var _ android.OutputFileProducer = (*Module)(nil)
+var _ android.OutputFileTagsProducer = (*Module)(nil)
 
 func InitJavaModule(module android.DefaultableModule, hod android.HostOrDeviceSupported) {
 	initJavaModule(module, hod, false)
@@ -723,8 +745,14 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 		}
 	}
 
-	libDeps := ctx.AddVariationDependencies(nil, libTag, j.properties.Libs...)
-	ctx.AddVariationDependencies(nil, staticLibTag, j.properties.Static_libs...)
+	// Depend on the java backend of the interfaces, e.g. aidl_interface, listed by name. The
+	// modules outside of the system partitions must use a frozen version of the interfaces.
+	requireFrozen := ctx.SocSpecific() || ctx.DeviceSpecific() || ctx.ProductSpecific()
+	libs := android.ResolveInterfaceBackends(ctx, android.InterfaceBackendJava, requireFrozen, j.properties.Libs)
+	staticLibs := android.ResolveInterfaceBackends(ctx, android.InterfaceBackendJava, requireFrozen, j.properties.Static_libs)
+
+	libDeps := ctx.AddVariationDependencies(nil, libTag, libs...)
+	ctx.AddVariationDependencies(nil, staticLibTag, staticLibs...)
 
 	// Add dependency on libraries that provide additional hidden api annotations.
 	ctx.AddVariationDependencies(nil, hiddenApiAnnotationsTag, j.properties.Hiddenapi_additional_annotations...)
@@ -739,7 +767,7 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 		//      if true, enable enforcement
 		//    PRODUCT_INTER_PARTITION_JAVA_LIBRARY_ALLOWLIST
 		//      exception list of java_library names to allow inter-partition dependency
-		for idx := range j.properties.Libs {
+		for idx := range libs {
 			if libDeps[idx] == nil {
 				continue
 			}
@@ -778,15 +806,22 @@ func (j *Module) deps(ctx android.BottomUpMutatorContext) {
 	ctx.AddFarVariationDependencies(ctx.Config().BuildOSCommonTarget.Variations(), exportedPluginTag, j.properties.Exported_plugins...)
 
 	android.ProtoDeps(ctx, &j.protoProperties)
-	if j.hasSrcExt(".proto") {
+	// The srcs of java_grpc_library modules are typically filegroups of protos, so they always depend
+	// on the proto and gRPC runtime libraries.
+	if j.hasSrcExt(".proto") || j.grpcProperties != nil {
 		protoDeps(ctx, &j.protoProperties)
 	}
+	if j.grpcProperties != nil {
+		grpcDeps(ctx, j.grpcProperties, &j.protoProperties)
+	}
 
 	if j.hasSrcExt(".kt") {
 		// TODO(ccross): move this to a mutator pass that can tell if generated sources contain
 		// Kotlin files
-		ctx.AddVariationDependencies(nil, kotlinStdlibTag,
-			"kotlin-stdlib", "kotlin-stdlib-jdk7", "kotlin-stdlib-jdk8")
+		if j.kotlinStdlibVersion(ctx) == "" {
+			ctx.AddVariationDependencies(nil, kotlinStdlibTag,
+				"kotlin-stdlib", "kotlin-stdlib-jdk7", "kotlin-stdlib-jdk8")
+		}
 		ctx.AddVariationDependencies(nil, kotlinAnnotationsTag, "kotlin-annotations")
 	}
 
@@ -1041,6 +1076,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 
 	deps := j.collectDeps(ctx)
 	flags := j.collectBuilderFlags(ctx, deps)
+	j.checkKotlinStdlibVersions(ctx)
 
 	if flags.javaVersion.usesJavaModules() {
 		j.properties.Srcs = append(j.properties.Srcs, j.properties.Openjdk9.Srcs...)
@@ -1055,6 +1091,9 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 	}
 	if hasSrcExt(srcFiles.Strings(), ".proto") {
 		flags = protoFlags(ctx, &j.properties, &j.protoProperties, flags)
+		if j.grpcProperties != nil {
+			flags = grpcFlags(ctx, j.grpcProperties, &j.protoProperties, flags)
+		}
 	}
 
 	kotlinCommonSrcFiles := android.PathsForModuleSrcExcludes(ctx, j.properties.Common_srcs, nil)
@@ -1144,6 +1183,10 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		}
 		flags.kotlincDeps = append(flags.kotlincDeps, deps.kotlinPlugins...)
 
+		var toolchainDeps android.Paths
+		flags.kotlincToolchain, toolchainDeps = j.kotlincToolchain(ctx)
+		flags.kotlincDeps = append(flags.kotlincDeps, toolchainDeps...)
+
 		if len(kotlincFlags) > 0 {
 			// optimization.
 			ctx.Variable(pctx, "kotlincFlags", strings.Join(kotlincFlags, " "))
@@ -1153,6 +1196,9 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		// Collect common .kt files for AIDEGen
 		j.expandIDEInfoCompiledSrcs = append(j.expandIDEInfoCompiledSrcs, kotlinCommonSrcFiles.Strings()...)
 
+		if version := j.kotlinStdlibVersion(ctx); version != "" {
+			deps.kotlinStdlib = kotlinPrebuiltStdlibJars(ctx, version)
+		}
 		flags.classpath = append(flags.classpath, deps.kotlinStdlib...)
 		flags.classpath = append(flags.classpath, deps.kotlinAnnotations...)
 
@@ -1461,6 +1507,7 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 		return
 	}
 
+	uninstrumentedJar := outputFile
 	if j.shouldInstrument(ctx) {
 		outputFile = j.instrument(ctx, flags, outputFile, jarName, specs)
 	}
@@ -1533,6 +1580,9 @@ func (j *Module) compile(ctx android.ModuleContext, aaptSrcJar android.Path) {
 
 			j.dexJarFile = makeDexJarPathFromPath(dexOutputFile)
 
+			// Build the dex jar for the non-instrumented boot image, if needed.
+			j.uninstrumentedDexJarFile = j.buildUninstrumentedBootDexJar(ctx, params, uninstrumentedJar)
+
 			// Dexpreopting
 			j.dexpreopt(ctx, dexOutputFile)
 
@@ -1802,6 +1852,10 @@ func (j *Module) DexJarBuildPath() OptionalDexJarPath {
 	return j.dexJarFile
 }
 
+func (j *Module) UninstrumentedDexJarBuildPath() OptionalDexJarPath {
+	return j.uninstrumentedDexJarFile
+}
+
 func (j *Module) DexJarInstallPath() android.Path {
 	return j.installFile
 }
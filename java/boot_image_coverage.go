@@ -0,0 +1,123 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+)
+
+// In coverage builds, i.e. when EMMA_INSTRUMENT_FRAMEWORK=true, the platform boot jars are
+// instrumented with jacoco, so the framework boot image is compiled from instrumented code. The
+// framework boot image is then also built from the non-instrumented dex jars of the platform boot
+// jars, as the boot_uninstrumented image under its own dex_boot_uninstrumentedjars directories.
+// Both sets of Make variables are exported, see dexpreoptBootJars.MakeVars, so the
+// non-instrumented image can be used on the host, but it is not installed: the device boots the
+// instrumented framework boot image, so the system server jars and the apps are still dexpreopted
+// against it, as ART would reject code compiled against another image.
+//
+// The non-instrumented image has the same bootclasspath as the framework boot image, including
+// jacocoagent, so the dex locations of both images are the same. The jars in apexes, e.g. the ART
+// jars, are encoded with their hidden API flags by their bootclasspath_fragment, so the image uses
+// their instrumented dex jars.
+
+// uninstrumentedDexDir is the subdirectory of the module output directory in which the
+// non-instrumented dex jar of a platform boot jar is built.
+const uninstrumentedDexDir = "uninstrumented"
+
+// uninstrumentedBootImageInstallDir is the directory of the non-instrumented boot image on device,
+// relative to the root of the device. The image is not installed, but its locations are recorded in
+// its files, so they differ from the ones of the framework boot image.
+const uninstrumentedBootImageInstallDir = "system/framework/uninstrumented"
+
+// isUninstrumentedBootImageEnabled returns true if the non-instrumented copy of the framework boot
+// image is built.
+func isUninstrumentedBootImageEnabled(config android.Config) bool {
+	return config.IsEnvTrue("EMMA_INSTRUMENT_FRAMEWORK")
+}
+
+// uninstrumentedBootImageConfig returns the raw config of the non-instrumented boot image. It is a
+// deep copy of the raw config of the framework boot image, so that the two configs do not share
+// any state; only the image that they extend, if any, is shared. The paths and the variants are
+// not set in the raw configs, genBootImageConfigs sets them for each config.
+func uninstrumentedBootImageConfig(framework *bootImageConfig) *bootImageConfig {
+	c := *framework
+	c.name = uninstrumentedBootImageName
+	c.installDir = uninstrumentedBootImageInstallDir
+	c.modules = framework.modules.AppendList(&android.ConfiguredJarList{})
+	if framework.enableUffdGc != nil {
+		c.enableUffdGc = proptools.BoolPtr(*framework.enableUffdGc)
+	}
+	return &c
+}
+
+// buildUninstrumentedBootDexJar builds the dex jar of an instrumented platform boot jar from its
+// classes before instrumentation, encoded with the same hidden API flags as its instrumented dex
+// jar. It returns an unset path if the module does not need one.
+func (j *Module) buildUninstrumentedBootDexJar(ctx android.ModuleContext, params *compileDexParams, classesJar android.Path) OptionalDexJarPath {
+	if !isUninstrumentedBootImageEnabled(ctx.Config()) || !j.shouldInstrument(ctx) || !j.hiddenAPI.active {
+		return OptionalDexJarPath{}
+	}
+	apexInfo := ctx.Provider(android.ApexInfoProvider).(android.ApexInfo)
+	if !apexInfo.IsForPlatform() {
+		return OptionalDexJarPath{}
+	}
+
+	dexParams := *params
+	dexParams.classesJar = classesJar
+	dexParams.outputSubdir = uninstrumentedDexDir
+	dexOutputFile := j.dexer.compileDex(ctx, &dexParams)
+
+	// merge dex jar with resources if necessary
+	uncompressDex := proptools.Bool(j.dexProperties.Uncompress_dex)
+	if j.resourceJar != nil {
+		jars := android.Paths{dexOutputFile, j.resourceJar}
+		combinedJar := android.PathForModuleOut(ctx, uninstrumentedDexDir, "dex-withres", params.jarName).OutputPath
+		TransformJarsToJar(ctx, combinedJar, "for uninstrumented dex resources", jars, android.OptionalPath{},
+			false, nil, nil)
+		if uncompressDex {
+			combinedAlignedJar := android.PathForModuleOut(ctx, uninstrumentedDexDir, "dex-withres-aligned", params.jarName).OutputPath
+			TransformZipAlign(ctx, combinedAlignedJar, combinedJar)
+			dexOutputFile = combinedAlignedJar
+		} else {
+			dexOutputFile = combinedJar
+		}
+	}
+
+	flagsCSV := hiddenAPISingletonPaths(ctx).flags
+	outputDir := android.PathForModuleOut(ctx, uninstrumentedDexDir, "hiddenapi").OutputPath
+	encodedDex := hiddenAPIEncodeDex(ctx, dexOutputFile, flagsCSV, uncompressDex, android.NoneApiLevel, outputDir)
+	return makeDexJarPathFromPath(encodedDex)
+}
+
+// extractUninstrumentedDexJarsFromModules extracts the non-instrumented dex jars from the supplied
+// modules, or their encoded dex jars if they are not instrumented, e.g. prebuilts, jacocoagent or
+// jars in apexes.
+func extractUninstrumentedDexJarsFromModules(ctx android.ModuleContext, contents []android.Module) bootDexJarByModule {
+	dexJarsByModuleName := bootDexJarByModule{}
+	for _, module := range contents {
+		if m, ok := module.(interface {
+			UninstrumentedDexJarBuildPath() OptionalDexJarPath
+		}); ok {
+			if dexJar := m.UninstrumentedDexJarBuildPath(); dexJar.Valid() {
+				dexJarsByModuleName.addPath(module, dexJar.Path())
+				continue
+			}
+		}
+		dexJarsByModuleName.addPath(module, retrieveEncodedBootDexJarFromModule(ctx, module))
+	}
+	return dexJarsByModuleName
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"reflect"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint"
+)
+
+// The modules that declare a boot image, i.e. the boot_image_extension modules, the
+// bootclasspath_fragment modules with an image_name and the platform_bootclasspath module for the
+// framework boot image, can override the CPU variant and the
+// instruction set features that dex2oat compiles the device variants of the image for, which
+// otherwise come from the global dexpreopt config. This allows e.g. the ART boot image to be
+// compiled for a generic CPU while the extensions are compiled for the exact cores of the SoC.
+//
+// The overrides are provided by the modules that declare the boot image to the module that builds
+// it, which is either the module itself or a module that depends on it, e.g. the
+// platform_bootclasspath module for the boot image extensions.
+type bootImageCpuProperties struct {
+	// The dex2oat --instruction-set-variant of the device variants of the boot image, as
+	// <arch>:<variant> pairs, e.g. "arm64:cortex-a76". Defaults to the CPU variant of the arch in
+	// the global dexpreopt config.
+	Instruction_set_variants []string
+
+	// The dex2oat --instruction-set-features of the device variants of the boot image, as
+	// <arch>:<features> pairs, e.g. "arm64:default". Defaults to the instruction set features of
+	// the arch in the global dexpreopt config.
+	Instruction_set_features []string
+}
+
+func (p *bootImageCpuProperties) empty() bool {
+	return len(p.Instruction_set_variants) == 0 && len(p.Instruction_set_features) == 0
+}
+
+// parseBootImageCpuOverrides parses a list of <arch>:<value> pairs into a map. It returns the
+// invalid pairs along with the map, the invalid pairs are otherwise ignored.
+func parseBootImageCpuOverrides(pairs []string) (map[android.ArchType]string, []string) {
+	overrides := make(map[android.ArchType]string)
+	var invalid []string
+	for _, pair := range pairs {
+		i := strings.Index(pair, ":")
+		if i < 0 || i == len(pair)-1 {
+			invalid = append(invalid, pair)
+			continue
+		}
+		found := false
+		for _, arch := range android.ArchTypeList() {
+			if arch.String() == pair[:i] {
+				overrides[arch] = pair[i+1:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			invalid = append(invalid, pair)
+		}
+	}
+	return overrides, invalid
+}
+
+// checkBootImageCpuProperties reports the invalid overrides of the module.
+func checkBootImageCpuProperties(ctx android.ModuleContext, p *bootImageCpuProperties) {
+	check := func(property string, pairs []string) {
+		if _, invalid := parseBootImageCpuOverrides(pairs); len(invalid) > 0 {
+			ctx.PropertyErrorf(property, "expected <arch>:<value> pairs with one of the archs %q, got %q",
+				android.ArchTypeList(), invalid)
+		}
+	}
+	check("instruction_set_variants", p.Instruction_set_variants)
+	check("instruction_set_features", p.Instruction_set_features)
+}
+
+// BootImageCpuOverridesInfo is provided by the modules that declare a boot image and override the
+// CPU variant or the instruction set features of its device variants.
+type BootImageCpuOverridesInfo struct {
+	// The name of the boot image.
+	ImageName string
+
+	// The overrides of the --instruction-set-variant and --instruction-set-features arguments of
+	// dex2oat, by arch.
+	InstructionSetVariants map[android.ArchType]string
+	InstructionSetFeatures map[android.ArchType]string
+}
+
+var BootImageCpuOverridesInfoProvider = blueprint.NewProvider(BootImageCpuOverridesInfo{})
+
+// provideBootImageCpuOverrides provides the CPU overrides of the boot image declared by the module,
+// if any, for the module that builds the boot image.
+func provideBootImageCpuOverrides(ctx android.ModuleContext, imageName string, p *bootImageCpuProperties) {
+	if p.empty() {
+		return
+	}
+	variants, _ := parseBootImageCpuOverrides(p.Instruction_set_variants)
+	features, _ := parseBootImageCpuOverrides(p.Instruction_set_features)
+	ctx.SetProvider(BootImageCpuOverridesInfoProvider, BootImageCpuOverridesInfo{
+		ImageName:              imageName,
+		InstructionSetVariants: variants,
+		InstructionSetFeatures: features,
+	})
+}
+
+// bootImageCpuFlags returns the --instruction-set-variant and --instruction-set-features arguments
+// of dex2oat for the device variant of the boot image, i.e. those of the arch in the global
+// dexpreopt config unless they are overridden by the module that builds the image, e.g. a
+// bootclasspath_fragment, or by one of its direct dependencies that declares the image, e.g. a
+// boot_image_extension. It reports an error if the overrides of the image conflict.
+func bootImageCpuFlags(ctx android.ModuleContext, image *bootImageVariant) (variant, features string) {
+	arch := image.target.Arch.ArchType
+	global := dexpreopt.GetGlobalConfig(ctx)
+	variant = global.CpuVariant[arch]
+	features = global.InstructionSetFeatures[arch]
+
+	var overrides *BootImageCpuOverridesInfo
+	var overridesModule string
+	add := func(module string, info BootImageCpuOverridesInfo) {
+		if info.ImageName != image.name {
+			return
+		}
+		if overrides != nil && !reflect.DeepEqual(*overrides, info) {
+			ctx.ModuleErrorf("the CPU overrides of the %q boot image declared by %q conflict with those of %q",
+				image.name, module, overridesModule)
+			return
+		}
+		overrides, overridesModule = &info, module
+	}
+	if ctx.HasProvider(BootImageCpuOverridesInfoProvider) {
+		add(ctx.ModuleName(), ctx.Provider(BootImageCpuOverridesInfoProvider).(BootImageCpuOverridesInfo))
+	}
+	ctx.VisitDirectDeps(func(child android.Module) {
+		if android.IsModulePreferred(child) && ctx.OtherModuleHasProvider(child, BootImageCpuOverridesInfoProvider) {
+			add(ctx.OtherModuleName(child), ctx.OtherModuleProvider(child, BootImageCpuOverridesInfoProvider).(BootImageCpuOverridesInfo))
+		}
+	})
+
+	if overrides != nil {
+		if v, ok := overrides.InstructionSetVariants[arch]; ok {
+			variant = v
+		}
+		if f, ok := overrides.InstructionSetFeatures[arch]; ok {
+			features = f
+		}
+	}
+	return variant, features
+}
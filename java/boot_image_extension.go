@@ -0,0 +1,281 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+	"sync"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	registerBootImageExtensionBuildComponents(android.InitRegistrationContext)
+}
+
+func registerBootImageExtensionBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("boot_image_extension", bootImageExtensionFactory)
+}
+
+// A boot_image_extension module declares a boot image extension in addition to the hard-coded art,
+// boot, mainline and system_ext boot images, e.g. for jars that a product adds to the bootclasspath. The
+// extension is added to the boot image configs by genBootImageConfigRaw, so that the
+// platform_bootclasspath module generates the dex2oat rules for it and the dex_bootjars singleton
+// exports it to Make along with the other boot images.
+//
+// The extensions are registered from a load hook, as the boot image configs are created once and
+// must be complete before they are first used. Only the extensions listed in the
+// BootImageExtensions product variable are registered, the others are ignored.
+type bootImageExtensionProperties struct {
+	// The name of the boot image, defaults to the name of the module.
+	Image_name *string
+
+	// The stem of the boot image files, defaults to "boot".
+	Stem *string
+
+	// The directory on the device where the boot image is installed, defaults to
+	// "system/framework".
+	Install_dir *string
+
+	// The name of the boot image that this boot image extends, defaults to "boot".
+	Extends *string
+
+	// The jars of the boot image, in the order of the bootclasspath, as <apex>:<jar> pairs or as
+	// the names of platform jars. They must not be part of another boot image.
+	Jars []string
+
+	// The dex2oat compiler filter of the boot image, defaults to "verify".
+	Compiler_filter *string
+
+	// Whether to compile all the jars into a single image file, defaults to true. Otherwise each jar
+	// gets its own image file, and is compiled by its own dex2oat invocation unless
+	// MonolithicBootImageDexpreopt is set in the global dexpreopt config.
+	Single_image *bool
+}
+
+type bootImageExtension struct {
+	android.ModuleBase
+
+	properties    bootImageExtensionProperties
+	cpuProperties bootImageCpuProperties
+
+	// The name of the module qualified with the path of its namespace, for the platform_bootclasspath
+	// module to depend on it.
+	qualifiedName string
+}
+
+func bootImageExtensionFactory() android.Module {
+	m := &bootImageExtension{}
+	m.AddProperties(&m.properties, &m.cpuProperties)
+	android.InitAndroidModule(m)
+	android.AddLoadHook(m, func(ctx android.LoadHookContext) {
+		// Only the enabled property set in the module itself is known here, the extensions that are
+		// disabled through it are not part of the boot image configs.
+		if !m.Enabled() || !m.requested(ctx) {
+			return
+		}
+		m.qualifiedName = qualifiedBootImageModuleName(ctx, ctx.ModuleName())
+		registerBootImageExtension(ctx.Config(), m.imageName(ctx), m)
+	})
+	return m
+}
+
+// requested returns true if the product builds the boot image of the extension.
+func (m *bootImageExtension) requested(ctx android.EarlyModuleContext) bool {
+	return android.InList(ctx.ModuleName(), ctx.Config().BootImageExtensions())
+}
+
+func (m *bootImageExtension) imageName(ctx android.EarlyModuleContext) string {
+	return proptools.StringDefault(m.properties.Image_name, ctx.ModuleName())
+}
+
+func (m *bootImageExtension) extends() string {
+	return proptools.StringDefault(m.properties.Extends, frameworkBootImageName)
+}
+
+// modules returns the jars of the boot image extension.
+func (m *bootImageExtension) modules() android.ConfiguredJarList {
+	modules := android.EmptyConfiguredJarList()
+	for _, jar := range m.properties.Jars {
+		apex := "platform"
+		if i := strings.Index(jar, ":"); i >= 0 {
+			apex, jar = jar[:i], jar[i+1:]
+		}
+		modules = modules.Append(apex, jar)
+	}
+	return modules
+}
+
+// config returns the raw boot image config of the boot image extension, without the config it
+// extends.
+func (m *bootImageExtension) config(name string) *bootImageConfig {
+	return &bootImageConfig{
+		name:           name,
+		stem:           proptools.StringDefault(m.properties.Stem, bootImageStem),
+		installDir:     proptools.StringDefault(m.properties.Install_dir, "system/framework"),
+		modules:        m.modules(),
+		compilerFilter: proptools.StringDefault(m.properties.Compiler_filter, "verify"),
+		singleImage:    proptools.BoolDefault(m.properties.Single_image, true),
+	}
+}
+
+func (m *bootImageExtension) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if !m.requested(ctx) {
+		return
+	}
+
+	name := m.imageName(ctx)
+	if isPredefinedBootImageName(name) {
+		ctx.PropertyErrorf("image_name", "%q is the name of a predefined boot image", name)
+		return
+	}
+	if len(m.properties.Jars) == 0 {
+		ctx.PropertyErrorf("jars", "a boot image extension must have at least one jar")
+		return
+	}
+	checkBootImageCpuProperties(ctx, &m.cpuProperties)
+	provideBootImageCpuOverrides(ctx, name, &m.cpuProperties)
+
+	configs := genBootImageConfigRaw(ctx)
+	if _, ok := configs[m.extends()]; !ok {
+		ctx.PropertyErrorf("extends", "unknown boot image %q, expected one of %q",
+			m.extends(), android.SortedKeys(configs))
+		return
+	}
+	if _, ok := configs[name]; !ok {
+		ctx.PropertyErrorf("extends", "boot image %q cannot extend %q, the boot images it extends "+
+			"must lead to a predefined boot image", name, m.extends())
+		return
+	}
+
+	// The jars must not already be compiled into another boot image.
+	modules := m.modules()
+	for _, other := range android.SortedKeys(configs) {
+		if other == name {
+			continue
+		}
+		for i := 0; i < modules.Len(); i++ {
+			if configs[other].modules.ContainsJar(modules.Jar(i)) {
+				ctx.PropertyErrorf("jars", "%q is already part of the %q boot image", modules.Jar(i), other)
+			}
+		}
+	}
+	global := dexpreopt.GetGlobalConfig(ctx)
+	for i := 0; i < modules.Len(); i++ {
+		if global.ApexBootJars.ContainsJar(modules.Jar(i)) {
+			ctx.PropertyErrorf("jars", "%q is an apex boot jar, which is not part of any boot image", modules.Jar(i))
+		}
+	}
+}
+
+type bootImageExtensionsMap struct {
+	sync.Mutex
+	extensions map[string]*bootImageExtension
+}
+
+var bootImageExtensionsKey = android.NewOnceKey("bootImageExtensions")
+
+func getBootImageExtensions(config android.Config) *bootImageExtensionsMap {
+	return config.Once(bootImageExtensionsKey, func() interface{} {
+		return &bootImageExtensionsMap{extensions: make(map[string]*bootImageExtension)}
+	}).(*bootImageExtensionsMap)
+}
+
+func registerBootImageExtension(config android.Config, name string, m *bootImageExtension) {
+	e := getBootImageExtensions(config)
+	e.Lock()
+	defer e.Unlock()
+	e.extensions[name] = m
+}
+
+// addBootImageExtensionConfigs adds the configs of the boot image extensions declared with
+// boot_image_extension modules to the predefined boot image configs. The extensions with the name
+// of a predefined boot image, or that do not extend a predefined boot image through a chain of
+// extensions, are skipped and reported by the modules.
+func addBootImageExtensionConfigs(config android.Config, configs map[string]*bootImageConfig) {
+	e := getBootImageExtensions(config)
+	e.Lock()
+	defer e.Unlock()
+
+	var added []string
+	for _, name := range android.SortedKeys(e.extensions) {
+		if _, ok := configs[name]; ok {
+			continue
+		}
+		configs[name] = e.extensions[name].config(name)
+		added = append(added, name)
+	}
+	for _, name := range added {
+		configs[name].extends = configs[e.extensions[name].extends()]
+	}
+
+	// Remove the extensions that do not lead to a predefined boot image, i.e. those that extend an
+	// unknown boot image or that are part of a cycle.
+	for _, name := range added {
+		c := configs[name]
+		for i := 0; i <= len(added); i++ {
+			if c.extends == nil {
+				break
+			}
+			c = c.extends
+		}
+		if c.extends != nil || android.InList(c.name, added) {
+			delete(configs, name)
+		}
+	}
+}
+
+// isPredefinedBootImageName returns true if name is the name of a boot image that is not declared
+// with a boot_image_extension module.
+func isPredefinedBootImageName(name string) bool {
+	return name == artBootImageName || name == frameworkBootImageName || name == mainlineBootImageName ||
+		name == systemExtBootImageName || name == uninstrumentedBootImageName
+}
+
+// bootImageExtensionNames returns the names of the boot images declared with boot_image_extension
+// modules.
+func bootImageExtensionNames(ctx android.PathContext) []string {
+	var names []string
+	for _, name := range android.SortedKeys(genBootImageConfigs(ctx)) {
+		if !isPredefinedBootImageName(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// The tag used for the dependencies of the platform_bootclasspath module onto the
+// boot_image_extension modules, which provide the CPU overrides of the boot images that it builds.
+var bootImageExtensionDepTag = dependencyTag{name: "boot-image-extension"}
+
+// bootImageExtensionModuleNames returns the qualified names of the boot_image_extension modules
+// that declare the boot images returned by bootImageExtensionNames.
+func bootImageExtensionModuleNames(ctx android.PathContext) []string {
+	// The boot image configs are generated before the lock is taken, as they take it themselves.
+	imageNames := bootImageExtensionNames(ctx)
+
+	e := getBootImageExtensions(ctx.Config())
+	e.Lock()
+	defer e.Unlock()
+
+	var names []string
+	for _, name := range imageNames {
+		names = append(names, e.extensions[name].qualifiedName)
+	}
+	return names
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"android/soong/android"
+)
+
+// BootImageLocationInfo contains the locations of a variant of a boot image, for the packages that
+// need to pass a boot image to dex2oat or to the runtime, so that they do not depend on the
+// internals of the boot image configs.
+type BootImageLocationInfo struct {
+	// The name of the boot image, e.g. "art" or "boot".
+	Name string
+
+	// The target of the variant.
+	Target android.Target
+
+	// The path of the first image file of the variant on the host, and on the device.
+	ImagePathOnHost   android.Path
+	ImagePathOnDevice string
+
+	// The locations of the image files of the variant, preceded by those of the images that it
+	// extends, on the host and on the device, as passed to dex2oat with --boot-image.
+	ImageLocationsOnHost   []string
+	ImageLocationsOnDevice []string
+
+	// The locations on the device of the jars of the image.
+	DexLocations []string
+
+	// The locations on the device of the jars of the image and of the images that it extends.
+	DexLocationsDeps []string
+
+	// The names of the images that the image extends, starting with the primary boot image.
+	DependencyImages []string
+
+	// All the files of the variant, i.e. its .art, .oat and .vdex files.
+	ImagesDeps android.OutputPaths
+}
+
+// BootImageLocations returns the locations of the variants of the named boot image of the
+// product, for the device targets followed by the host targets, or nil if the product has no such
+// boot image.
+func BootImageLocations(ctx android.PathContext, name string) []BootImageLocationInfo {
+	// The boot image configs have variants for the device targets, which may not be configured,
+	// e.g. for host-only builds.
+	if len(ctx.Config().Targets[android.Android]) == 0 {
+		return nil
+	}
+	image, ok := genBootImageConfigs(ctx)[name]
+	if !ok {
+		return nil
+	}
+
+	var dependencyImages []string
+	for c := image.extends; c != nil; c = c.extends {
+		dependencyImages = append([]string{c.name}, dependencyImages...)
+	}
+
+	var infos []BootImageLocationInfo
+	for _, variant := range image.variants {
+		imageLocationsOnHost, imageLocationsOnDevice := variant.imageLocations()
+		infos = append(infos, BootImageLocationInfo{
+			Name:                   image.name,
+			Target:                 variant.target,
+			ImagePathOnHost:        variant.imagePathOnHost,
+			ImagePathOnDevice:      variant.imagePathOnDevice,
+			ImageLocationsOnHost:   imageLocationsOnHost,
+			ImageLocationsOnDevice: imageLocationsOnDevice,
+			DexLocations:           android.CopyOf(variant.dexLocations),
+			DexLocationsDeps:       android.CopyOf(variant.dexLocationsDeps),
+			DependencyImages:       android.CopyOf(dependencyImages),
+			ImagesDeps:             append(android.OutputPaths(nil), variant.imagesDeps...),
+		})
+	}
+	return infos
+}
+
+// BootImageLocationForTarget returns the locations of the variant of the named boot image for the
+// OS and the arch of the given target, and false if the product has no such variant.
+func BootImageLocationForTarget(ctx android.PathContext, name string, target android.Target) (BootImageLocationInfo, bool) {
+	for _, info := range BootImageLocations(ctx, name) {
+		if info.Target.Os == target.Os && info.Target.Arch.ArchType == target.Arch.ArchType {
+			return info, true
+		}
+	}
+	return BootImageLocationInfo{}, false
+}
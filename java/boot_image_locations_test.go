@@ -0,0 +1,55 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestBootImageLocations(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForBootImageConfigTest,
+		PrepareApexBootJarConfigs,
+	).RunTest(t)
+
+	pathCtx := &android.TestPathContext{TestResult: result}
+	android.AssertIntEquals(t, "unknown image", 0, len(BootImageLocations(pathCtx, "unknown")))
+
+	infos := BootImageLocations(pathCtx, "mainline")
+	android.AssertStringEquals(t, "first variant os", android.Android.String(), infos[0].Target.Os.String())
+
+	info, ok := BootImageLocationForTarget(pathCtx, "mainline", result.Config.Targets[android.Android][0])
+	android.AssertBoolEquals(t, "found", true, ok)
+	android.AssertStringEquals(t, "name", "mainline", info.Name)
+	android.AssertStringEquals(t, "arch", "arm64", info.Target.Arch.ArchType.String())
+	android.AssertArrayString(t, "dependency images", []string{"boot"}, info.DependencyImages)
+	android.AssertArrayString(t, "image locations on device",
+		[]string{"/system/framework/boot.art", "/system/framework/boot-core2.art",
+			"/system/framework/boot-framework.art", "/system/framework/boot-framework-foo.art"},
+		info.ImageLocationsOnDevice)
+	android.AssertStringEquals(t, "image path on device",
+		"/system/framework/arm64/boot-framework-foo.art", info.ImagePathOnDevice)
+	android.AssertArrayString(t, "dex locations",
+		[]string{"/apex/com.android.foo/javalib/framework-foo.jar", "/apex/com.android.bar/javalib/framework-bar.jar"},
+		info.DexLocations)
+
+	// The returned locations are copies that the callers may modify.
+	info.DexLocations[0] = "modified"
+	info, _ = BootImageLocationForTarget(pathCtx, "mainline", result.Config.Targets[android.Android][0])
+	android.AssertStringEquals(t, "dex location after modification",
+		"/apex/com.android.foo/javalib/framework-foo.jar", info.DexLocations[0])
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+func init() {
+	registerBootImageProfileBuildComponents(android.InitRegistrationContext)
+}
+
+func registerBootImageProfileBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("boot_image_profile", bootImageProfileFactory)
+}
+
+// A boot_image_profile module provides profiles that are merged into the profile of the framework
+// boot image, in addition to the boot-image-profile.txt of frameworks/base and the profiles of the
+// global dexpreopt config, e.g. the profiles of a product or the profiles dumped by profman on
+// devices. The platform_bootclasspath module depends on the boot_image_profile modules listed in
+// the BootImageProfileModules product variable.
+type bootImageProfileProperties struct {
+	// The profiles to merge into the boot image profile. The files with a .txt extension are text
+	// profiles, the others are binary profiles, e.g. dumped by profman on a device.
+	Srcs []string `android:"path"`
+
+	// The dex files that the binary profiles reference in addition to the boot jars, e.g. the jars
+	// of the bootclasspath_fragment modules that the profiles were collected with.
+	Apks []string `android:"path"`
+
+	// The locations on the device of the dex files in apks, in the same order.
+	Dex_locations []string
+}
+
+type bootImageProfile struct {
+	android.ModuleBase
+
+	properties bootImageProfileProperties
+}
+
+// BootImageProfileInfo is provided by the boot_image_profile modules.
+type BootImageProfileInfo struct {
+	// The text profiles to merge into the boot image profile.
+	TextProfiles android.Paths
+
+	// The binary profiles to merge into the boot image profile.
+	BinaryProfiles android.Paths
+
+	// The dex files referenced by the profiles in addition to the boot jars, and their locations on
+	// the device.
+	Apks         android.Paths
+	DexLocations []string
+}
+
+var BootImageProfileInfoProvider = blueprint.NewProvider(BootImageProfileInfo{})
+
+var bootImageProfileDepTag = dependencyTag{name: "boot-image-profile"}
+
+func bootImageProfileFactory() android.Module {
+	m := &bootImageProfile{}
+	m.AddProperties(&m.properties)
+	android.InitAndroidModule(m)
+	return m
+}
+
+func (m *bootImageProfile) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if len(m.properties.Srcs) == 0 {
+		ctx.PropertyErrorf("srcs", "a boot image profile must have at least one profile")
+		return
+	}
+	if len(m.properties.Apks) != len(m.properties.Dex_locations) {
+		ctx.PropertyErrorf("dex_locations", "must have one location for each of the %d apks, got %d",
+			len(m.properties.Apks), len(m.properties.Dex_locations))
+		return
+	}
+
+	var info BootImageProfileInfo
+	for _, src := range android.PathsForModuleSrc(ctx, m.properties.Srcs) {
+		if src.Ext() == ".txt" {
+			info.TextProfiles = append(info.TextProfiles, src)
+		} else {
+			info.BinaryProfiles = append(info.BinaryProfiles, src)
+		}
+	}
+	info.Apks = android.PathsForModuleSrc(ctx, m.properties.Apks)
+	info.DexLocations = m.properties.Dex_locations
+	ctx.SetProvider(BootImageProfileInfoProvider, info)
+}
+
+// gatherBootImageProfiles returns the profiles provided by the boot_image_profile modules that the
+// module depends on.
+func gatherBootImageProfiles(ctx android.ModuleContext) BootImageProfileInfo {
+	var ret BootImageProfileInfo
+	ctx.VisitDirectDepsWithTag(bootImageProfileDepTag, func(module android.Module) {
+		if !ctx.OtherModuleHasProvider(module, BootImageProfileInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(module, BootImageProfileInfoProvider).(BootImageProfileInfo)
+		ret.TextProfiles = append(ret.TextProfiles, info.TextProfiles...)
+		ret.BinaryProfiles = append(ret.BinaryProfiles, info.BinaryProfiles...)
+		ret.Apks = append(ret.Apks, info.Apks...)
+		ret.DexLocations = append(ret.DexLocations, info.DexLocations...)
+	})
+	return ret
+}
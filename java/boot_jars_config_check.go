@@ -0,0 +1,213 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+)
+
+func init() {
+	registerBootJarsConfigCheckBuildComponents(android.InitRegistrationContext)
+}
+
+func registerBootJarsConfigCheckBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("boot_jars_config_check", bootJarsConfigCheckSingletonFactory)
+}
+
+var PrepareForTestWithBootJarsConfigCheck = android.FixtureRegisterWithContext(registerBootJarsConfigCheckBuildComponents)
+
+func bootJarsConfigCheckSingletonFactory() android.Singleton {
+	return &bootJarsConfigCheckSingleton{}
+}
+
+// bootJarsConfigCheckSingleton cross-validates the lists of boot jars and system server jars of the
+// product, and the classpath fragments that declare the jars of the apexes. The inconsistencies
+// would otherwise be reported by different modules at different stages of the build, or not at
+// all, so they are all listed in a single report instead, out/soong/boot_jars_config_check.txt.
+// The check fails if the report is not empty, and it is a validation of the rules that build the
+// boot images, so that an inconsistent configuration fails the build without failing the analysis
+// of the modules that do not depend on the boot images.
+//
+// The only ordering that is checked is that of the ART jars, the orderings of the other jars are
+// checked by the modules that rely on them, e.g. the system_ext jars by checkSystemExtBootImage.
+type bootJarsConfigCheckSingleton struct{}
+
+// namedJarList is a list of jars along with the name of the product variable that defines it.
+type namedJarList struct {
+	name string
+	jars android.ConfiguredJarList
+}
+
+// bootJarsConfigCheckTimestamp returns the path of the output of the check of the boot jars
+// configuration, which the rules that build the boot images use as a validation.
+func bootJarsConfigCheckTimestamp(ctx android.PathContext) android.WritablePath {
+	return android.PathForOutput(ctx, "boot_jars_config_check.timestamp")
+}
+
+func isPlatformApex(apex string) bool {
+	return apex == "platform" || apex == "system_ext"
+}
+
+func (bootJarsConfigCheckSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	global := dexpreopt.GetGlobalConfig(ctx)
+
+	art := namedJarList{"ART_APEX_JARS", global.ArtApexJars}
+	bootJars := namedJarList{"PRODUCT_BOOT_JARS", global.BootJars}
+	apexBootJars := namedJarList{"PRODUCT_APEX_BOOT_JARS", global.ApexBootJars}
+	systemServerJars := namedJarList{"PRODUCT_SYSTEM_SERVER_JARS", global.SystemServerJars}
+	apexSystemServerJars := namedJarList{"PRODUCT_APEX_SYSTEM_SERVER_JARS", global.ApexSystemServerJars}
+	standaloneSystemServerJars := namedJarList{"PRODUCT_STANDALONE_SYSTEM_SERVER_JARS", global.StandaloneSystemServerJars}
+	apexStandaloneSystemServerJars := namedJarList{"PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS", global.ApexStandaloneSystemServerJars}
+
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Every jar must be listed at most once. The ART jars are also boot jars, so they are only
+	// checked for duplicates within their own list.
+	checkDuplicates := func(lists ...namedJarList) {
+		seen := make(map[string]string)
+		for _, list := range lists {
+			for i := 0; i < list.jars.Len(); i++ {
+				jar := list.jars.Jar(i)
+				where := fmt.Sprintf("%s as %q", list.name, list.jars.Apex(i)+":"+jar)
+				if other, ok := seen[jar]; ok {
+					report("jar %q is listed more than once, in %s and in %s", jar, other, where)
+				} else {
+					seen[jar] = where
+				}
+			}
+		}
+	}
+	checkDuplicates(art)
+	checkDuplicates(bootJars, apexBootJars, systemServerJars, apexSystemServerJars,
+		standaloneSystemServerJars, apexStandaloneSystemServerJars)
+
+	// The ART jars come first in the boot classpath, in the same order as in ART_APEX_JARS.
+	for i := 0; i < art.jars.Len(); i++ {
+		jar := art.jars.Jar(i)
+		index := bootJars.jars.IndexOfJar(jar)
+		if index < 0 {
+			report("jar %q of %s is missing from %s", jar, art.name, bootJars.name)
+		} else if index != i {
+			report("jar %q of %s is at index %d of %s, expected %d as the %s must come first, in the same order",
+				jar, art.name, index, bootJars.name, i, art.name)
+		}
+	}
+	if art.jars.Len() > 0 {
+		artApex := art.jars.Apex(0)
+		for i := 0; i < bootJars.jars.Len(); i++ {
+			if bootJars.jars.Apex(i) == artApex && !art.jars.ContainsJar(bootJars.jars.Jar(i)) {
+				report("jar %q of %s is in the %q apex but not in %s", bootJars.jars.Jar(i), bootJars.name,
+					artApex, art.name)
+			}
+		}
+	}
+
+	// The jars of the apex lists must belong to an apex, and those of the platform lists must not.
+	for _, list := range []namedJarList{apexBootJars, apexSystemServerJars, apexStandaloneSystemServerJars} {
+		for i := 0; i < list.jars.Len(); i++ {
+			if isPlatformApex(list.jars.Apex(i)) {
+				report("jar %q of %s must be in an apex, not %q", list.jars.Jar(i), list.name, list.jars.Apex(i))
+			}
+		}
+	}
+	for _, list := range []namedJarList{systemServerJars, standaloneSystemServerJars} {
+		for i := 0; i < list.jars.Len(); i++ {
+			if !isPlatformApex(list.jars.Apex(i)) {
+				report("jar %q of %s must be in \"platform\" or \"system_ext\", not in the %q apex",
+					list.jars.Jar(i), list.name, list.jars.Apex(i))
+			}
+		}
+	}
+
+	// The jars of the apex lists must be declared by a classpath fragment in their apex. The
+	// fragments may legitimately be missing from unbundled builds, and from the partial source trees
+	// of the jars whose modules are allowed to have missing dependencies.
+	if !ctx.Config().UnbundledBuild() {
+		bootFragments := make(map[string]bool)
+		systemServerFragments := make(map[string]bool)
+		standaloneFragments := make(map[string]bool)
+		moduleDirs := make(map[string]string)
+		ctx.VisitAllModules(func(module android.Module) {
+			moduleDirs[android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName(module))] = ctx.ModuleDir(module)
+			switch m := module.(type) {
+			case *BootclasspathFragmentModule:
+				addActiveClasspathFragmentJars(ctx, bootFragments, module, m.properties.Contents)
+			case *PrebuiltBootclasspathFragmentModule:
+				addActiveClasspathFragmentJars(ctx, bootFragments, module, m.properties.Contents)
+			case *SystemServerClasspathModule:
+				addActiveClasspathFragmentJars(ctx, systemServerFragments, module, m.properties.Contents)
+				addActiveClasspathFragmentJars(ctx, standaloneFragments, module, m.properties.Standalone_contents)
+			case *prebuiltSystemServerClasspathModule:
+				addActiveClasspathFragmentJars(ctx, systemServerFragments, module, m.properties.Contents)
+				addActiveClasspathFragmentJars(ctx, standaloneFragments, module, m.properties.Standalone_contents)
+			}
+		})
+
+		checkFragments := func(list namedJarList, fragments map[string]bool, fragmentType, property string) {
+			for i := 0; i < list.jars.Len(); i++ {
+				apex, jar := list.jars.Apex(i), list.jars.Jar(i)
+				if isPlatformApex(apex) || ctx.Config().AllowMissingDependenciesForDir(moduleDirs[jar]) {
+					continue
+				}
+				if !fragments[apex+":"+jar] {
+					report("jar %q of %s is not in the %s of any %s in the %q apex", jar, list.name, property,
+						fragmentType, apex)
+				}
+			}
+		}
+		checkFragments(apexBootJars, bootFragments, "bootclasspath_fragment", "contents")
+		checkFragments(apexSystemServerJars, systemServerFragments, "systemserverclasspath_fragment", "contents")
+		checkFragments(apexStandaloneSystemServerJars, standaloneFragments, "systemserverclasspath_fragment",
+			"standalone_contents")
+	}
+
+	out := android.PathForOutput(ctx, "boot_jars_config_check.txt")
+	android.WriteFileRule(ctx, out, strings.Join(problems, "\n"))
+
+	timestamp := bootJarsConfigCheckTimestamp(ctx)
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("if [ -s").Input(out).Text("]; then").
+		Text("echo 'error: inconsistent configuration of the boot jars and the system server jars:' &&").
+		Text("cat").Input(out).Text("&& exit 1; fi &&").
+		Text("touch").Output(timestamp)
+	rule.Build("boot_jars_config_check", "check the boot jars configuration")
+
+	ctx.Phony("boot_jars_config_check", timestamp)
+}
+
+// addActiveClasspathFragmentJars adds the contents of the classpath fragment to the set of the jars of
+// the apexes that it is in, as <apex>:<jar>, if the fragment is active. The apexes are named both
+// after their apex variations, which are shared by the override_apex modules and by the
+// prebuilt_apex modules with an apex_name, and after their modules, without the prebuilt_ prefix,
+// as the jars may be listed under either name.
+func addActiveClasspathFragmentJars(ctx android.SingletonContext, contents map[string]bool, fragment android.Module, jars []string) {
+	if !isActiveModule(fragment) {
+		return
+	}
+	apexInfo := ctx.ModuleProvider(fragment, android.ApexInfoProvider).(android.ApexInfo)
+	for _, apex := range android.Concat(apexInfo.InApexVariants, apexInfo.InApexModules) {
+		for _, jar := range jars {
+			contents[apex+":"+android.RemoveOptionalPrebuiltPrefix(jar)] = true
+		}
+	}
+}
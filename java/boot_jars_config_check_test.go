@@ -0,0 +1,163 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+)
+
+func TestBootJarsConfigCheck(t *testing.T) {
+	testCases := []struct {
+		name     string
+		preparer android.FixturePreparer
+		errors   []string
+	}{
+		{
+			name: "consistent",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetArtBootJars("com.android.art:core1", "com.android.art:core2"),
+				dexpreopt.FixtureSetBootJars("com.android.art:core1", "com.android.art:core2", "platform:framework"),
+				dexpreopt.FixtureSetSystemServerJars("platform:services"),
+				dexpreopt.FixtureSetStandaloneSystemServerJars("system_ext:standalone"),
+			),
+		},
+		{
+			name: "duplicates",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetBootJars("platform:framework", "platform:foo"),
+				dexpreopt.FixtureSetSystemServerJars("platform:services", "system_ext:foo"),
+			),
+			errors: []string{
+				`jar "foo" is listed more than once, in PRODUCT_BOOT_JARS as "platform:foo" and in PRODUCT_SYSTEM_SERVER_JARS as "system_ext:foo"`,
+			},
+		},
+		{
+			name: "art ordering",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetArtBootJars("com.android.art:core1", "com.android.art:core2"),
+				dexpreopt.FixtureSetBootJars("com.android.art:core1", "platform:framework", "com.android.art:core2",
+					"com.android.art:core3"),
+				android.PrepareForTestWithAllowMissingDependencies,
+			),
+			errors: []string{
+				`jar "core2" of ART_APEX_JARS is at index 2 of PRODUCT_BOOT_JARS, expected 1`,
+				`jar "core3" of PRODUCT_BOOT_JARS is in the "com.android.art" apex but not in ART_APEX_JARS`,
+			},
+		},
+		{
+			name: "apex owners",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetApexBootJars("platform:foo"),
+				dexpreopt.FixtureSetSystemServerJars("com.android.bar:bar"),
+				android.PrepareForTestWithAllowMissingDependencies,
+			),
+			errors: []string{
+				`jar "foo" of PRODUCT_APEX_BOOT_JARS must be in an apex, not "platform"`,
+				`jar "bar" of PRODUCT_SYSTEM_SERVER_JARS must be in "platform" or "system_ext", not in the "com.android.bar" apex`,
+			},
+		},
+		{
+			name: "missing fragments",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetApexBootJars("com.android.foo:foo"),
+				dexpreopt.FixtureSetApexSystemServerJars("com.android.bar:bar"),
+				dexpreopt.FixtureSetApexStandaloneSystemServerJars("com.android.bar:baz"),
+			),
+			errors: []string{
+				`jar "foo" of PRODUCT_APEX_BOOT_JARS is not in the contents of any bootclasspath_fragment in the "com.android.foo" apex`,
+				`jar "bar" of PRODUCT_APEX_SYSTEM_SERVER_JARS is not in the contents of any systemserverclasspath_fragment in the "com.android.bar" apex`,
+				`jar "baz" of PRODUCT_APEX_STANDALONE_SYSTEM_SERVER_JARS is not in the standalone_contents of any systemserverclasspath_fragment in the "com.android.bar" apex`,
+			},
+		},
+		{
+			name: "missing fragments allowed",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetApexBootJars("com.android.foo:foo"),
+				android.PrepareForTestWithAllowMissingDependencies,
+			),
+		},
+		{
+			name: "missing fragments allowed in dir",
+			preparer: android.GroupFixturePreparers(
+				dexpreopt.FixtureSetApexBootJars("com.android.foo:foo", "com.android.bar:bar"),
+				android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+					variables.Allow_missing_dependencies_dirs = []string{"vendor/unbundled"}
+				}),
+				android.FixtureAddTextFile("vendor/unbundled/Android.bp", `
+					java_library {
+						name: "foo",
+					}
+				`),
+			),
+			errors: []string{
+				`jar "bar" of PRODUCT_APEX_BOOT_JARS is not in the contents of any bootclasspath_fragment in the "com.android.bar" apex`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := android.GroupFixturePreparers(
+				PrepareForTestWithJavaDefaultModules,
+				PrepareForTestWithBootJarsConfigCheck,
+				tc.preparer,
+			).RunTest(t)
+
+			check := result.SingletonForTests("boot_jars_config_check")
+			report := android.ContentFromFileRuleForTests(t, check.Output("boot_jars_config_check.txt"))
+			if tc.errors == nil {
+				android.AssertStringEquals(t, "report", "", report)
+			}
+			for _, e := range tc.errors {
+				android.AssertStringDoesContain(t, "report", report, e)
+			}
+		})
+	}
+}
+
+func TestBootJarsConfigCheckValidation(t *testing.T) {
+	// The check fails if the report is not empty, and it validates the rules that build the boot
+	// images.
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		PrepareForTestWithBootJarsConfigCheck,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	check := result.SingletonForTests("boot_jars_config_check").Output("boot_jars_config_check.timestamp")
+	android.AssertStringDoesContain(t, "check", check.RuleParams.Command,
+		"if [ -s out/soong/boot_jars_config_check.txt ]; then")
+	android.AssertStringDoesContain(t, "check", check.RuleParams.Command, "&& exit 1; fi")
+
+	dex2oat := result.ModuleForTests("platform-bootclasspath", "android_common").Rule("bootJarsDexpreopt_android_arm64")
+	android.AssertStringListContains(t, "dex2oat validations", dex2oat.Validations.Strings(),
+		"out/soong/boot_jars_config_check.timestamp")
+}
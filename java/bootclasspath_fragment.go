@@ -123,6 +123,22 @@ type bootclasspathFragmentProperties struct {
 	// If specified then it must be one of "art" or "boot".
 	Image_name *string
 
+	// Whether the profile of the boot image of this fragment is exported, so that it is merged into
+	// the profiles of the profile guided boot images built by the modules that depend on this
+	// fragment, e.g. the framework boot image built by the platform_bootclasspath module. This allows
+	// an APEX to ship an updated profile for its boot jars.
+	//
+	// A fragment without an image_name exports the profile in its profile property instead.
+	//
+	// Defaults to true for the "art" image, whose profile is always merged into the framework boot
+	// image profile, and to false otherwise.
+	Export_profile *bool
+
+	// The boot image profile of the contents of a fragment without an image_name, in the text format
+	// of frameworks/base/config/boot-image-profile.txt. It is converted by profman against the dex
+	// jars of the contents and exported if export_profile is true.
+	Profile *string `android:"path"`
+
 	// Properties whose values need to differ with and without coverage.
 	BootclasspathFragmentCoverageAffectedProperties
 	Coverage BootclasspathFragmentCoverageAffectedProperties
@@ -238,11 +254,14 @@ type BootclasspathFragmentModule struct {
 
 	sourceOnlyProperties SourceOnlyBootclasspathProperties
 
+	// The CPU overrides of the boot image, if the fragment has an image_name.
+	cpuProperties bootImageCpuProperties
+
+	// The profile property of a fragment without an image_name that exports it, for the sdk snapshot.
+	exportedProfileSrc android.Path
+
 	// Collect the module directory for IDE info in java/jdeps.go.
 	modulePaths []string
-
-	// Path to the boot image profile.
-	profilePath android.Path
 }
 
 // commonBootclasspathFragment defines the methods that are implemented by both source and prebuilt
@@ -265,16 +284,13 @@ type commonBootclasspathFragment interface {
 
 	// getImageName returns the `image_name` property of this fragment.
 	getImageName() *string
-
-	// getProfilePath returns the path to the boot image profile.
-	getProfilePath() android.Path
 }
 
 var _ commonBootclasspathFragment = (*BootclasspathFragmentModule)(nil)
 
 func bootclasspathFragmentFactory() android.Module {
 	m := &BootclasspathFragmentModule{}
-	m.AddProperties(&m.properties, &m.sourceOnlyProperties)
+	m.AddProperties(&m.properties, &m.sourceOnlyProperties, &m.cpuProperties)
 	android.InitApexModule(m)
 	initClasspathFragment(m, BOOTCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
@@ -298,6 +314,7 @@ func bootclasspathFragmentFactory() android.Module {
 
 		// Initialize the contents property from the image_name.
 		bootclasspathFragmentInitContentsFromImage(ctx, m)
+		bootclasspathFragmentRegisterBootImage(ctx, m)
 	})
 	return m
 }
@@ -308,6 +325,14 @@ func testBootclasspathFragmentFactory() android.Module {
 	return m
 }
 
+// bootclasspathFragmentRegisterBootImage registers the fragment for the dex_bootjars module to
+// depend on if it has an image_name, as it may build the boot image.
+func bootclasspathFragmentRegisterBootImage(ctx android.LoadHookContext, m *BootclasspathFragmentModule) {
+	if m.properties.Image_name != nil {
+		registerBootImageModule(ctx, *m.properties.Image_name)
+	}
+}
+
 // bootclasspathFragmentInitContentsFromImage will initialize the contents property from the image_name if
 // necessary.
 func bootclasspathFragmentInitContentsFromImage(ctx android.EarlyModuleContext, m *BootclasspathFragmentModule) {
@@ -384,6 +409,24 @@ func (b *BootclasspathFragmentModule) bootclasspathImageNameContentsConsistencyC
 	}
 }
 
+// BootclasspathFragmentProfileInfo is provided by the bootclasspath_fragment modules with a boot
+// image, whether they export its profile or not, and by the fragments without a boot image that
+// export a profile, see the export_profile property.
+type BootclasspathFragmentProfileInfo struct {
+	// The name of the boot image of the fragment, or an empty string if it does not have one.
+	ImageName string
+
+	// The profile of the boot image or the profile property of the fragment converted by profman,
+	// nil if the fragment does not build one.
+	Profile android.Path
+
+	// Whether the profile is merged into the profiles of the boot images built by the modules that
+	// depend on the fragment.
+	Exported bool
+}
+
+var BootclasspathFragmentProfileInfoProvider = blueprint.NewProvider(BootclasspathFragmentProfileInfo{})
+
 var BootclasspathFragmentApexContentInfoProvider = blueprint.NewProvider(BootclasspathFragmentApexContentInfo{})
 
 // BootclasspathFragmentApexContentInfo contains the bootclasspath_fragments contributions to the
@@ -493,6 +536,11 @@ func (b *BootclasspathFragmentModule) DepsMutator(ctx android.BottomUpMutatorCon
 	// Add a dependency onto the dex2oat tool which is needed for creating the boot image. The
 	// path is retrieved from the dependency by GetGlobalSoongConfig(ctx).
 	dexpreopt.RegisterToolDeps(ctx)
+	addBootImageRbeToolDeps(ctx)
+
+	if b.properties.Image_name != nil {
+		addBootImageDex2oatConfigDeps(ctx, *b.properties.Image_name)
+	}
 }
 
 func (b *BootclasspathFragmentModule) BootclasspathDepsMutator(ctx android.BottomUpMutatorContext) {
@@ -506,6 +554,10 @@ func (b *BootclasspathFragmentModule) GenerateAndroidBuildActions(ctx android.Mo
 	// build.
 	if isActiveModule(ctx.Module()) {
 		b.bootclasspathImageNameContentsConsistencyCheck(ctx)
+		checkBootImageCpuProperties(ctx, &b.cpuProperties)
+	}
+	if b.properties.Image_name != nil {
+		provideBootImageCpuOverrides(ctx, *b.properties.Image_name, &b.cpuProperties)
 	}
 
 	// Generate classpaths.proto config
@@ -537,16 +589,39 @@ func (b *BootclasspathFragmentModule) GenerateAndroidBuildActions(ctx android.Mo
 		// Delegate the production of the boot image files to a module type specific method.
 		common := ctx.Module().(commonBootclasspathFragment)
 		bootImageFiles = common.produceBootImageFiles(ctx, imageConfig)
-		b.profilePath = bootImageFiles.profile
 
+		// Provide the profile of the boot image for the modules that depend on this fragment to merge
+		// it into the profiles of their boot images if it is exported.
+		ctx.SetProvider(BootclasspathFragmentProfileInfoProvider, BootclasspathFragmentProfileInfo{
+			ImageName: imageConfig.name,
+			Profile:   bootImageFiles.profile,
+			Exported:  b.exportsProfile(),
+		})
+
+		installInfo := &bootImageInstallInfo{}
 		if shouldCopyBootFilesToPredefinedLocations(ctx, imageConfig) {
 			// Zip the boot image files up, if available. This will generate the zip file in a
 			// predefined location.
-			buildBootImageZipInPredefinedLocation(ctx, imageConfig, bootImageFiles.byArch)
+			installInfo.zip, installInfo.deltaMetadata = buildBootImageZipInPredefinedLocation(ctx, imageConfig, bootImageFiles.byArch)
 
 			// Copy the dex jars of this fragment's content modules to their predefined locations.
 			copyBootJarsToPredefinedLocations(ctx, hiddenAPIOutput.EncodedBootDexFilesByModule, imageConfig.dexPathsByModule)
 		}
+
+		// Provide the installs of the boot image, for the dex_bootjars module to export to Make.
+		if len(bootImageFiles.variants) > 0 {
+			installInfo.addVariants(ctx, bootImageFiles)
+			ctx.SetProvider(BootImageInfoProvider, BootImageInfo{
+				images: map[string]*bootImageInstallInfo{imageConfig.name: installInfo},
+			})
+		}
+	} else if b.exportsProfile() && !dexpreopt.GetGlobalConfig(ctx).DisableGenerateProfile {
+		// Provide the profile of the fragment for the modules that depend on it to merge it into the
+		// profiles of their boot images.
+		ctx.SetProvider(BootclasspathFragmentProfileInfoProvider, BootclasspathFragmentProfileInfo{
+			Profile:  b.buildExportedProfile(ctx, hiddenAPIOutput.EncodedBootDexFilesByModule),
+			Exported: true,
+		})
 	}
 
 	// A prebuilt fragment cannot contribute to an apex.
@@ -881,7 +956,9 @@ func (b *BootclasspathFragmentModule) generateBootImageBuildActions(ctx android.
 	}
 
 	// Build a profile for the image config and then use that to build the boot image.
-	profile := bootImageProfileRule(ctx, imageConfig)
+	// The profile of a boot image built by a fragment is not installed on its own, it is packaged in
+	// the APEX instead.
+	profile, _ := bootImageProfileRule(ctx, imageConfig)
 
 	// If dexpreopt of boot image jars should be skipped, generate only a profile.
 	if SkipDexpreoptBootJars(ctx) {
@@ -890,11 +967,14 @@ func (b *BootclasspathFragmentModule) generateBootImageBuildActions(ctx android.
 		}
 	}
 
-	// Build boot image files for the host variants.
-	buildBootImageVariantsForBuildOs(ctx, imageConfig, profile)
+	// Build boot image files for the host variants. The image of a fragment only contains the jars
+	// of its APEX, so it is compiled without the updatable-bcp-packages.txt file generated by the
+	// platform_bootclasspath module.
+	hostBootImageFiles := buildBootImageVariantsForBuildOs(ctx, imageConfig, profile, android.OptionalPath{})
 
 	// Build boot image files for the android variants.
-	bootImageFiles := buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile)
+	bootImageFiles := buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile, android.OptionalPath{})
+	bootImageFiles.variants = append(bootImageFiles.variants, hostBootImageFiles.variants...)
 
 	// Return the boot image files for the android variants for inclusion in an APEX and to be zipped
 	// up for the dist.
@@ -925,8 +1005,52 @@ func (b *BootclasspathFragmentModule) getImageName() *string {
 	return b.properties.Image_name
 }
 
-func (b *BootclasspathFragmentModule) getProfilePath() android.Path {
-	return b.profilePath
+// exportsProfile returns true if the profile of the boot image of the fragment is merged into the
+// profiles of the boot images built by the modules that depend on it.
+func (b *BootclasspathFragmentModule) exportsProfile() bool {
+	return proptools.BoolDefault(b.properties.Export_profile,
+		proptools.String(b.properties.Image_name) == artBootImageName)
+}
+
+// buildExportedProfile converts the profile property of a fragment without a boot image into a
+// binary boot image profile for the given dex jars of its contents, and returns it. It returns nil
+// if the fragment does not provide the dex jars, e.g. for the platform variant.
+func (b *BootclasspathFragmentModule) buildExportedProfile(ctx android.ModuleContext, dexJarsByModule bootDexJarByModule) android.Path {
+	if b.properties.Profile == nil {
+		ctx.PropertyErrorf("export_profile", "requires the profile property to be set for a bootclasspath_fragment without an image_name")
+		return nil
+	}
+	textProfile := android.PathForModuleSrc(ctx, *b.properties.Profile)
+	b.exportedProfileSrc = textProfile
+
+	apexInfo := ctx.Provider(android.ApexInfoProvider).(android.ApexInfo)
+	if apexInfo.IsForPlatform() {
+		return nil
+	}
+
+	jars := b.configuredJars(ctx)
+	var apks android.Paths
+	for i := 0; i < jars.Len(); i++ {
+		if dexJar := dexJarsByModule[jars.Jar(i)]; dexJar != nil {
+			apks = append(apks, dexJar)
+		}
+	}
+	if len(apks) != jars.Len() {
+		return nil
+	}
+
+	profile := android.PathForModuleOut(ctx, "profile", "boot.prof")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text(`ANDROID_LOG_TAGS="*:e"`).
+		Tool(dexpreopt.GetGlobalSoongConfig(ctx).Profman).
+		Flag("--output-profile-type=boot").
+		FlagWithInput("--create-profile-from=", textProfile).
+		FlagForEachInput("--apk=", apks).
+		FlagForEachArg("--dex-location=", jars.DevicePaths(ctx.Config(), android.Android)).
+		FlagWithOutput("--reference-profile-file=", profile)
+	rule.Build("exported_profile", "exported boot image profile")
+	return profile
 }
 
 // Collect information for opening IDE project files in java/jdeps.go.
@@ -966,6 +1090,12 @@ type bootclasspathFragmentSdkMemberProperties struct {
 	// The image name
 	Image_name *string
 
+	// Whether the profile of the boot image is exported
+	Export_profile *bool
+
+	// The profile exported by a fragment without an image name
+	Profile android.OptionalPath
+
 	// Contents of the bootclasspath fragment
 	Contents []string
 
@@ -1008,6 +1138,8 @@ func (b *bootclasspathFragmentSdkMemberProperties) PopulateFromVariant(ctx andro
 	module := variant.(*BootclasspathFragmentModule)
 
 	b.Image_name = module.properties.Image_name
+	b.Export_profile = module.properties.Export_profile
+	b.Profile = android.OptionalPathForPath(module.exportedProfileSrc)
 	b.Contents = module.properties.Contents
 
 	// Get the hidden API information from the module.
@@ -1040,7 +1172,16 @@ func (b *bootclasspathFragmentSdkMemberProperties) AddToPropertySet(ctx android.
 		propertySet.AddProperty("image_name", *b.Image_name)
 	}
 
+	if b.Export_profile != nil {
+		propertySet.AddProperty("export_profile", *b.Export_profile)
+	}
+
 	builder := ctx.SnapshotBuilder()
+	if b.Profile.Valid() {
+		dest := filepath.Join("profile", b.Profile.Path().Base())
+		builder.CopyToSnapshot(b.Profile.Path(), dest)
+		propertySet.AddProperty("profile", dest)
+	}
 	requiredMemberDependency := builder.SdkMemberReferencePropertyTag(true)
 
 	if len(b.Contents) > 0 {
@@ -1105,6 +1246,12 @@ var _ android.SdkMemberType = (*bootclasspathFragmentMemberType)(nil)
 // prebuiltBootclasspathFragmentProperties contains additional prebuilt_bootclasspath_fragment
 // specific properties.
 type prebuiltBootclasspathFragmentProperties struct {
+	// The path to a zip of the prebuilt primary boot image, with the layout of the boot image zip
+	// built by the bootclasspath_fragment. If set, the boot image files for the device are extracted
+	// from it instead of being compiled from the dex files provided by the contents. Defaults to the
+	// PrebuiltArtBootImage of the dexpreopt config. Only supported for the primary boot image.
+	Prebuilt_boot_image *string `android:"path"`
+
 	Hidden_api struct {
 		// The path to the annotation-flags.csv file created by the bootclasspath_fragment.
 		Annotation_flags *string `android:"path"`
@@ -1214,23 +1361,44 @@ func (module *PrebuiltBootclasspathFragmentModule) produceBootImageFiles(ctx and
 	// provided by the contents of this module as prebuilt versions of the host boot image files are
 	// not available, i.e. there is no host specific prebuilt apex containing them. This has to be
 	// built without a profile as the prebuilt modules do not provide a profile.
-	buildBootImageVariantsForBuildOs(ctx, imageConfig, profile)
+	hostBootImageFiles := buildBootImageVariantsForBuildOs(ctx, imageConfig, profile, android.OptionalPath{})
 
-	if profile == nil && imageConfig.isProfileGuided() {
-		ctx.ModuleErrorf("Unable to produce boot image files: profiles not found in the prebuilt apex")
-		return bootImageOutputs{}
+	// Extract the boot image files for the android variants from the prebuilt boot image, if any.
+	// It does not need the profile, as the boot image was compiled with it.
+	var bootImageFiles bootImageOutputs
+	if prebuiltBootImage := module.prebuiltBootImage(ctx, imageConfig); prebuiltBootImage != nil {
+		bootImageFiles = extractPrebuiltBootImageForAndroidOs(ctx, imageConfig, prebuiltBootImage, profile)
+	} else {
+		if profile == nil && imageConfig.isProfileGuided() {
+			ctx.ModuleErrorf("Unable to produce boot image files: profiles not found in the prebuilt apex")
+			return bootImageOutputs{}
+		}
+		// Build boot image files for the android variants from the dex files provided by the contents
+		// of this module.
+		bootImageFiles = buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile, android.OptionalPath{})
 	}
-	// Build boot image files for the android variants from the dex files provided by the contents
-	// of this module.
-	return buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile)
+	bootImageFiles.variants = append(bootImageFiles.variants, hostBootImageFiles.variants...)
+	return bootImageFiles
 }
 
-func (b *PrebuiltBootclasspathFragmentModule) getImageName() *string {
-	return b.properties.Image_name
+// prebuiltBootImage returns the path to the zip of the prebuilt boot image that the boot image
+// files for the device are extracted from, or nil if they must be built.
+func (module *PrebuiltBootclasspathFragmentModule) prebuiltBootImage(ctx android.ModuleContext, imageConfig *bootImageConfig) android.Path {
+	var zip android.Path
+	if module.prebuiltProperties.Prebuilt_boot_image != nil {
+		zip = android.PathForModuleSrc(ctx, *module.prebuiltProperties.Prebuilt_boot_image)
+	} else if imageConfig.name == artBootImageName {
+		zip = dexpreopt.GetGlobalConfig(ctx).PrebuiltArtBootImage
+	}
+	if zip != nil && imageConfig.extends != nil {
+		ctx.PropertyErrorf("prebuilt_boot_image", "is not supported for the boot image extension %q", imageConfig.name)
+		return nil
+	}
+	return zip
 }
 
-func (b *PrebuiltBootclasspathFragmentModule) getProfilePath() android.Path {
-	return b.profilePath
+func (b *PrebuiltBootclasspathFragmentModule) getImageName() *string {
+	return b.properties.Image_name
 }
 
 var _ commonBootclasspathFragment = (*PrebuiltBootclasspathFragmentModule)(nil)
@@ -1257,7 +1425,7 @@ var _ android.RequiredFilesFromPrebuiltApex = (*PrebuiltBootclasspathFragmentMod
 
 func prebuiltBootclasspathFragmentFactory() android.Module {
 	m := &PrebuiltBootclasspathFragmentModule{}
-	m.AddProperties(&m.properties, &m.prebuiltProperties)
+	m.AddProperties(&m.properties, &m.prebuiltProperties, &m.cpuProperties)
 	// This doesn't actually have any prebuilt files of its own so pass a placeholder for the srcs
 	// array.
 	android.InitPrebuiltModule(m, &[]string{"placeholder"})
@@ -1267,6 +1435,7 @@ func prebuiltBootclasspathFragmentFactory() android.Module {
 	// Initialize the contents property from the image_name.
 	android.AddLoadHook(m, func(ctx android.LoadHookContext) {
 		bootclasspathFragmentInitContentsFromImage(ctx, &m.BootclasspathFragmentModule)
+		bootclasspathFragmentRegisterBootImage(ctx, &m.BootclasspathFragmentModule)
 	})
 	return m
 }
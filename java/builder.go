@@ -43,9 +43,9 @@ var (
 	javac, javacRE = pctx.MultiCommandRemoteStaticRules("javac",
 		blueprint.RuleParams{
 			Command: `rm -rf "$outDir" "$annoDir" "$srcJarDir" "$out" && mkdir -p "$outDir" "$annoDir" "$srcJarDir" && ` +
-				`${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
+				`$emptyJavacDiagnostics${config.ZipSyncCmd} -d $srcJarDir -l $srcJarDir/list -f "*.java" $srcJars && ` +
 				`(if [ -s $srcJarDir/list ] || [ -s $out.rsp ] ; then ` +
-				`${config.SoongJavacWrapper} $javaTemplate${config.JavacCmd} ` +
+				`${config.SoongJavacWrapper} $javacDiagnostics$javaTemplate${config.JavacCmd} ` +
 				`${config.JavacHeapFlags} ${config.JavacVmFlags} ${config.CommonJdkFlags} ` +
 				`$processorpath $processor $javacFlags $bootClasspath $classpath ` +
 				`-source $javaVersion -target $javaVersion ` +
@@ -74,7 +74,7 @@ var (
 				Platform:     map[string]string{remoteexec.PoolKey: "${config.REJavaPool}"},
 			},
 		}, []string{"javacFlags", "bootClasspath", "classpath", "processorpath", "processor", "srcJars", "srcJarDir",
-			"outDir", "annoDir", "javaVersion"}, nil)
+			"outDir", "annoDir", "javaVersion", "javacDiagnostics", "emptyJavacDiagnostics"}, nil)
 
 	_ = pctx.VariableFunc("kytheCorpus",
 		func(ctx android.PackageVarContext) string { return ctx.Config().XrefCorpusName() })
@@ -294,8 +294,12 @@ type javaBuilderFlags struct {
 	kotlincFlags     string
 	kotlincClasspath classpath
 	kotlincDeps      android.Paths
+	kotlincToolchain kotlincToolchain
 
 	proto android.ProtoFlags
+
+	// Options of the gRPC plugin, nil if the gRPC stubs are not generated.
+	grpcOutParams []string
 }
 
 func TransformJavaToClasses(ctx android.ModuleContext, outputFile android.WritablePath, shardIdx int,
@@ -527,23 +531,39 @@ func transformJavaToClasses(ctx android.ModuleContext, outputFile android.Writab
 	if ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_JAVAC") {
 		rule = javacRE
 	}
+
+	var implicitOutputs android.WritablePaths
+	javacDiagnostics := ""
+	emptyJavacDiagnostics := ""
+	if javacDiagnosticsEnabled(ctx.Config()) {
+		diagnosticsFile := outputFile.ReplaceExtension(ctx, "diagnostics.jsonl")
+		implicitOutputs = append(implicitOutputs, diagnosticsFile)
+		javacDiagnostics = "--diagnostics " + diagnosticsFile.String() + " "
+		// The file is empty if there is nothing to compile, otherwise it is rewritten by javac.
+		emptyJavacDiagnostics = ": > " + diagnosticsFile.String() + " && "
+		registerJavacDiagnosticsFile(ctx.Config(), diagnosticsFile)
+	}
+
 	ctx.Build(pctx, android.BuildParams{
-		Rule:        rule,
-		Description: desc,
-		Output:      outputFile,
-		Inputs:      srcFiles,
-		Implicits:   deps,
+		Rule:            rule,
+		Description:     desc,
+		Output:          outputFile,
+		ImplicitOutputs: implicitOutputs,
+		Inputs:          srcFiles,
+		Implicits:       deps,
 		Args: map[string]string{
-			"javacFlags":    flags.javacFlags,
-			"bootClasspath": bootClasspath,
-			"classpath":     classpath.FormJavaClassPath("-classpath"),
-			"processorpath": flags.processorPath.FormJavaClassPath("-processorpath"),
-			"processor":     processor,
-			"srcJars":       strings.Join(srcJars.Strings(), " "),
-			"srcJarDir":     android.PathForModuleOut(ctx, intermediatesDir, srcJarDir).String(),
-			"outDir":        android.PathForModuleOut(ctx, intermediatesDir, outDir).String(),
-			"annoDir":       android.PathForModuleOut(ctx, intermediatesDir, annoDir).String(),
-			"javaVersion":   flags.javaVersion.String(),
+			"javacFlags":            flags.javacFlags,
+			"bootClasspath":         bootClasspath,
+			"classpath":             classpath.FormJavaClassPath("-classpath"),
+			"processorpath":         flags.processorPath.FormJavaClassPath("-processorpath"),
+			"processor":             processor,
+			"srcJars":               strings.Join(srcJars.Strings(), " "),
+			"srcJarDir":             android.PathForModuleOut(ctx, intermediatesDir, srcJarDir).String(),
+			"outDir":                android.PathForModuleOut(ctx, intermediatesDir, outDir).String(),
+			"annoDir":               android.PathForModuleOut(ctx, intermediatesDir, annoDir).String(),
+			"javaVersion":           flags.javaVersion.String(),
+			"javacDiagnostics":      javacDiagnostics,
+			"emptyJavacDiagnostics": emptyJavacDiagnostics,
 		},
 	})
 }
@@ -14,7 +14,10 @@
 
 package config
 
-import "strings"
+import (
+	"path/filepath"
+	"strings"
+)
 
 var (
 	KotlinStdlibJar     = "external/kotlinc/lib/kotlin-stdlib.jar"
@@ -22,8 +25,21 @@ var (
 		"-no-jdk",
 		"-no-stdlib",
 	}
+
+	// KotlincPrebuiltVersions are the versions of the prebuilt kotlinc toolchains in
+	// prebuilts/kotlinc that modules can select with kotlin_version instead of the toolchain in
+	// external/kotlinc, e.g. to stay on a vetted version while the platform toolchain is updated.
+	KotlincPrebuiltVersions = []string{
+		"1.7.20",
+		"1.8.10",
+	}
 )
 
+// KotlincPrebuiltDir returns the directory of the prebuilt kotlinc toolchain of the given version.
+func KotlincPrebuiltDir(version string) string {
+	return filepath.Join("prebuilts/kotlinc", version)
+}
+
 func init() {
 	pctx.SourcePathVariable("KotlincCmd", "external/kotlinc/bin/kotlinc")
 	pctx.SourcePathVariable("KotlinCompilerJar", "external/kotlinc/lib/kotlin-compiler.jar")
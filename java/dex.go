@@ -346,14 +346,19 @@ type compileDexParams struct {
 	minSdkVersion android.ApiLevel
 	classesJar    android.Path
 	jarName       string
+
+	// The subdirectory of the module output directory in which the dex jar is built, empty for the
+	// dex jar of the module. Only the dex jar of the module sets the proguard outputs of the dexer.
+	outputSubdir string
 }
 
 func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParams) android.OutputPath {
 
 	// Compile classes.jar into classes.dex and then javalib.jar
-	javalibJar := android.PathForModuleOut(ctx, "dex", dexParams.jarName).OutputPath
-	outDir := android.PathForModuleOut(ctx, "dex")
-	tmpJar := android.PathForModuleOut(ctx, "withres-withoutdex", dexParams.jarName)
+	subdir := dexParams.outputSubdir
+	javalibJar := android.PathForModuleOut(ctx, subdir, "dex", dexParams.jarName).OutputPath
+	outDir := android.PathForModuleOut(ctx, subdir, "dex")
+	tmpJar := android.PathForModuleOut(ctx, subdir, "withres-withoutdex", dexParams.jarName)
 
 	zipFlags := "--ignore_missing_files"
 	if proptools.Bool(d.dexProperties.Uncompress_dex) {
@@ -370,15 +375,17 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 
 	useR8 := d.effectiveOptimizeEnabled()
 	if useR8 {
-		proguardDictionary := android.PathForModuleOut(ctx, "proguard_dictionary")
-		d.proguardDictionary = android.OptionalPathForPath(proguardDictionary)
-		proguardConfiguration := android.PathForModuleOut(ctx, "proguard_configuration")
-		d.proguardConfiguration = android.OptionalPathForPath(proguardConfiguration)
-		proguardUsageDir := android.PathForModuleOut(ctx, "proguard_usage")
+		proguardDictionary := android.PathForModuleOut(ctx, subdir, "proguard_dictionary")
+		proguardConfiguration := android.PathForModuleOut(ctx, subdir, "proguard_configuration")
+		proguardUsageDir := android.PathForModuleOut(ctx, subdir, "proguard_usage")
 		proguardUsage := proguardUsageDir.Join(ctx, ctx.Namespace().Path,
 			android.ModuleNameWithPossibleOverride(ctx), "unused.txt")
-		proguardUsageZip := android.PathForModuleOut(ctx, "proguard_usage.zip")
-		d.proguardUsageZip = android.OptionalPathForPath(proguardUsageZip)
+		proguardUsageZip := android.PathForModuleOut(ctx, subdir, "proguard_usage.zip")
+		if subdir == "" {
+			d.proguardDictionary = android.OptionalPathForPath(proguardDictionary)
+			d.proguardConfiguration = android.OptionalPathForPath(proguardConfiguration)
+			d.proguardUsageZip = android.OptionalPathForPath(proguardUsageZip)
+		}
 		r8Flags, r8Deps := d.r8Flags(ctx, dexParams.flags)
 		r8Deps = append(r8Deps, commonDeps...)
 		rule := r8
@@ -430,7 +437,7 @@ func (d *dexer) compileDex(ctx android.ModuleContext, dexParams *compileDexParam
 		})
 	}
 	if proptools.Bool(d.dexProperties.Uncompress_dex) {
-		alignedJavalibJar := android.PathForModuleOut(ctx, "aligned", dexParams.jarName).OutputPath
+		alignedJavalibJar := android.PathForModuleOut(ctx, subdir, "aligned", dexParams.jarName).OutputPath
 		TransformZipAlign(ctx, alignedJavalibJar, javalibJar)
 		javalibJar = alignedJavalibJar
 	}
@@ -0,0 +1,194 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	registerDex2oatConfigBuildComponents(android.InitRegistrationContext)
+}
+
+func registerDex2oatConfigBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("dex2oat_config", dex2oatConfigFactory)
+}
+
+var PrepareForTestWithDex2oatConfig = android.FixtureRegisterWithContext(registerDex2oatConfigBuildComponents)
+
+// A dex2oat_config module declares a set of dex2oat flags, so that the flags that a product
+// compiles its boot images or its apps and libraries with are version controlled along with the
+// modules, instead of being passed in ART_BOOT_IMAGE_EXTRA_ARGS. A product selects the config of
+// a boot image with the BootImageDex2oatConfigs product variable, and a module selects its config
+// with the dex_preopt.dex2oat_config property.
+type dex2oatConfigProperties struct {
+	// The dex2oat --compiler-filter, e.g. "speed-profile". Defaults to the compiler filter that the
+	// boot image or the module is otherwise compiled with.
+	Compiler_filter *string
+
+	// The dex2oat --inline-max-code-units, the maximum size in code units of the methods that are
+	// inlined.
+	Inline_max_code_units *int64
+
+	// The runtime arguments of dex2oat, each passed with --runtime-arg, e.g. "-Xgc:CMC".
+	Runtime_args []string
+
+	// Other dex2oat flags, e.g. "--resolve-startup-const-strings=true".
+	Flags []string
+}
+
+// dex2oatCompilerFilters are the compiler filters that dex2oat accepts.
+var dex2oatCompilerFilters = []string{
+	"assume-verified",
+	"extract",
+	"verify",
+	"space-profile",
+	"space",
+	"speed-profile",
+	"speed",
+	"everything-profile",
+	"everything",
+}
+
+type dex2oatConfig struct {
+	android.ModuleBase
+
+	properties dex2oatConfigProperties
+}
+
+func dex2oatConfigFactory() android.Module {
+	m := &dex2oatConfig{}
+	m.AddProperties(&m.properties)
+	android.InitAndroidModule(m)
+	return m
+}
+
+// Dex2oatConfigInfo contains the dex2oat flags declared by a dex2oat_config module.
+type Dex2oatConfigInfo struct {
+	// The dex2oat compiler filter, or an empty string if the config does not set one.
+	CompilerFilter string
+
+	// The other dex2oat flags of the config.
+	Flags []string
+}
+
+var Dex2oatConfigInfoProvider = blueprint.NewProvider(Dex2oatConfigInfo{})
+
+// dex2oatConfigDepTag is the tag of the dependencies onto dex2oat_config modules, from the
+// dexpreopted modules that select one and from the modules that build the boot images.
+var dex2oatConfigDepTag = dependencyTag{name: "dex2oat-config"}
+
+func (m *dex2oatConfig) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if filter := m.properties.Compiler_filter; filter != nil && !android.InList(*filter, dex2oatCompilerFilters) {
+		ctx.PropertyErrorf("compiler_filter", "unknown compiler filter %q, expected one of %q",
+			*filter, dex2oatCompilerFilters)
+	}
+	if units := m.properties.Inline_max_code_units; units != nil && *units < 0 {
+		ctx.PropertyErrorf("inline_max_code_units", "must not be negative, got %d", *units)
+	}
+	for _, flag := range m.properties.Flags {
+		if !strings.HasPrefix(flag, "--") {
+			ctx.PropertyErrorf("flags", "expected a dex2oat flag starting with --, got %q", flag)
+		} else if strings.HasPrefix(flag, "--compiler-filter") {
+			ctx.PropertyErrorf("flags", "use compiler_filter instead of %q", flag)
+		} else if flag == "--runtime-arg" {
+			ctx.PropertyErrorf("flags", "use runtime_args instead of %q", flag)
+		}
+	}
+
+	ctx.SetProvider(Dex2oatConfigInfoProvider, Dex2oatConfigInfo{
+		CompilerFilter: proptools.String(m.properties.Compiler_filter),
+		Flags:          m.properties.flags(),
+	})
+}
+
+// flags returns the dex2oat flags of the config, other than the compiler filter.
+func (p *dex2oatConfigProperties) flags() []string {
+	var flags []string
+	if p.Inline_max_code_units != nil {
+		flags = append(flags, fmt.Sprintf("--inline-max-code-units=%d", *p.Inline_max_code_units))
+	}
+	for _, arg := range p.Runtime_args {
+		flags = append(flags, "--runtime-arg", arg)
+	}
+	return append(flags, p.Flags...)
+}
+
+// addBootImageDex2oatConfigDeps adds dependencies onto the dex2oat_config modules that the product
+// sets for the given boot images. Unknown modules are reported as missing dependencies.
+func addBootImageDex2oatConfigDeps(ctx android.BottomUpMutatorContext, images ...string) {
+	for _, name := range images {
+		if module := ctx.Config().BootImageDex2oatConfig(name); module != "" {
+			ctx.AddDependency(ctx.Module(), dex2oatConfigDepTag, module)
+		}
+	}
+}
+
+// checkBootImageDex2oatConfigs reports the boot images that the product sets a dex2oat_config
+// module for that do not exist.
+func checkBootImageDex2oatConfigs(ctx android.ModuleContext) {
+	configs := genBootImageConfigRaw(ctx)
+	for _, name := range ctx.Config().BootImageDex2oatConfigImages() {
+		if _, ok := configs[name]; !ok {
+			ctx.ModuleErrorf("BootImageDex2oatConfigs: unknown boot image %q, expected one of %q",
+				name, android.SortedKeys(configs))
+		}
+	}
+}
+
+// dex2oatConfigFromDep returns the flags of the dex2oat_config module with the given name that the
+// module depends on, or an empty Dex2oatConfigInfo if the name is empty or the dependency is
+// missing.
+func dex2oatConfigFromDep(ctx android.ModuleContext, name string) Dex2oatConfigInfo {
+	var info Dex2oatConfigInfo
+	if name == "" {
+		return info
+	}
+	ctx.VisitDirectDepsWithTag(dex2oatConfigDepTag, func(m android.Module) {
+		if ctx.OtherModuleName(m) == name && ctx.OtherModuleHasProvider(m, Dex2oatConfigInfoProvider) {
+			info = ctx.OtherModuleProvider(m, Dex2oatConfigInfoProvider).(Dex2oatConfigInfo)
+		}
+	})
+	return info
+}
+
+// bootImageDex2oatConfig returns the flags of the dex2oat_config module that the product sets for
+// the boot image.
+func bootImageDex2oatConfig(ctx android.ModuleContext, image *bootImageConfig) Dex2oatConfigInfo {
+	return dex2oatConfigFromDep(ctx, ctx.Config().BootImageDex2oatConfig(image.name))
+}
+
+// dexpreoptDex2oatConfigName returns the name of the dex2oat_config module selected by the
+// dex_preopt.dex2oat_config property of the module, or an empty string if it does not set one.
+func (d *dexpreopter) dexpreoptDex2oatConfigName() string {
+	return String(d.dexpreoptProperties.Dex_preopt.Dex2oat_config)
+}
+
+// dexpreoptDex2oatFlags returns the dex2oat flags of the dex2oat_config module selected by the
+// dex_preopt.dex2oat_config property of the module, or nil if it does not set one.
+func (d *dexpreopter) dexpreoptDex2oatFlags(ctx android.ModuleContext) []string {
+	info := dex2oatConfigFromDep(ctx, d.dexpreoptDex2oatConfigName())
+	var flags []string
+	if info.CompilerFilter != "" {
+		flags = append(flags, "--compiler-filter="+info.CompilerFilter)
+	}
+	return append(flags, info.Flags...)
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+var prepareForTestWithDex2oatConfig = android.GroupFixturePreparers(
+	PrepareForTestWithDex2oatConfig,
+	android.FixtureAddTextFile("perf/Android.bp", `
+		dex2oat_config {
+			name: "perf-flags",
+			compiler_filter: "speed-profile",
+			inline_max_code_units: 20,
+			runtime_args: ["-Xjitthreshold:1000"],
+			flags: ["--resolve-startup-const-strings=true"],
+		}
+	`),
+)
+
+func TestDex2oatConfig_BootImage(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		prepareForTestWithDex2oatConfig,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootImageDex2oatConfigs = map[string]string{
+				"boot": "perf-flags",
+			}
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--compiler-filter=speed-profile")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--inline-max-code-units=20")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--runtime-arg -Xjitthreshold:1000")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--resolve-startup-const-strings=true")
+}
+
+func TestDex2oatConfig_Module(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		PrepareForTestWithFakeApexMutator,
+		prepareForTestWithDex2oatConfig,
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			installable: true,
+			srcs: ["a.java"],
+			dex_preopt: {
+				dex2oat_config: "perf-flags",
+			},
+		}`)
+
+	dexpreopt := result.ModuleForTests("foo", "android_common").Rule("dexpreopt")
+	android.AssertStringDoesContain(t, "dexpreopt command", dexpreopt.RuleParams.Command,
+		"--compiler-filter=speed-profile --inline-max-code-units=20 --runtime-arg -Xjitthreshold:1000 --resolve-startup-const-strings=true")
+}
+
+func TestDex2oatConfig_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		preparer      android.FixturePreparer
+		bp            string
+		expectedError string
+	}{
+		{
+			name: "unknown compiler filter",
+			bp: `
+				dex2oat_config {
+					name: "bad-flags",
+					compiler_filter: "fast",
+				}`,
+			expectedError: `compiler_filter: unknown compiler filter "fast"`,
+		},
+		{
+			name: "compiler filter in flags",
+			bp: `
+				dex2oat_config {
+					name: "bad-flags",
+					flags: ["--compiler-filter=speed"],
+				}`,
+			expectedError: `flags: use compiler_filter instead of "--compiler-filter=speed"`,
+		},
+		{
+			name: "unknown module config",
+			bp: `
+				java_library {
+					name: "foo",
+					installable: true,
+					srcs: ["a.java"],
+					dex_preopt: {
+						dex2oat_config: "missing-flags",
+					},
+				}`,
+			expectedError: `"foo" depends on undefined module "missing-flags"`,
+		},
+		{
+			name: "unknown boot image config",
+			preparer: android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+				variables.BootImageDex2oatConfigs = map[string]string{
+					"boot": "missing-flags",
+				}
+			}),
+			bp: `
+				platform_bootclasspath {
+					name: "platform-bootclasspath",
+				}`,
+			expectedError: `"platform-bootclasspath" depends on undefined module "missing-flags"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			preparer := tc.preparer
+			if preparer == nil {
+				preparer = android.NullFixturePreparer
+			}
+			android.GroupFixturePreparers(
+				PrepareForTestWithJavaDefaultModules,
+				PrepareForTestWithFakeApexMutator,
+				PrepareForTestWithDex2oatConfig,
+				preparer,
+			).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.expectedError)).
+				RunTestWithBp(t, tc.bp)
+		})
+	}
+}
@@ -20,6 +20,8 @@ import (
 
 	"android/soong/android"
 	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint/proptools"
 )
 
 type DexpreopterInterface interface {
@@ -41,6 +43,9 @@ type DexpreopterInterface interface {
 
 	// See `dexpreopter.outputProfilePathOnHost`.
 	OutputProfilePathOnHost() android.Path
+
+	// The name of the dex2oat_config module selected by the java module, if any.
+	dexpreoptDex2oatConfigName() string
 }
 
 type dexpreopterInstall struct {
@@ -111,6 +116,10 @@ type dexpreopter struct {
 	builtInstalled        string
 	builtInstalledForApex []dexpreopterInstall
 
+	// The locations on the device of the dexpreopt outputs installed on the preopt partition of the
+	// product, see dexpreopt.GlobalConfig.PreoptPartition.
+	preoptPartitionInstalls []string
+
 	// The config is used for two purposes:
 	// - Passing dexpreopt information about libraries from Soong to Make. This is needed when
 	//   a <uses-library> is defined in Android.bp, but used in Android.mk (see dex_preopt_config_merger.py).
@@ -145,6 +154,10 @@ type DexpreoptProperties struct {
 		// defaults to searching for a file that matches the name of this module in the default
 		// profile location set by PRODUCT_DEX_PREOPT_PROFILE_DIR, or empty if not found.
 		Profile *string `android:"path"`
+
+		// If set, the name of a dex2oat_config module whose flags are passed to dex2oat instead of
+		// the PreoptFlags of the global dexpreopt config.
+		Dex2oat_config *string
 	}
 
 	Dex_preopt_result struct {
@@ -226,10 +239,14 @@ func (d *dexpreopter) dexpreoptDisabled(ctx android.BaseModuleContext) bool {
 }
 
 func dexpreoptToolDepsMutator(ctx android.BottomUpMutatorContext) {
-	if d, ok := ctx.Module().(DexpreopterInterface); !ok || d.dexpreoptDisabled(ctx) || !dexpreopt.IsDex2oatNeeded(ctx) {
+	d, ok := ctx.Module().(DexpreopterInterface)
+	if !ok || d.dexpreoptDisabled(ctx) || !dexpreopt.IsDex2oatNeeded(ctx) {
 		return
 	}
 	dexpreopt.RegisterToolDeps(ctx)
+	if name := d.dexpreoptDex2oatConfigName(); name != "" {
+		ctx.AddDependency(ctx.Module(), dex2oatConfigDepTag, name)
+	}
 }
 
 func (d *dexpreopter) odexOnSystemOther(ctx android.ModuleContext, installPath android.InstallPath) bool {
@@ -286,7 +303,7 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 
 	isSystemServerJar := global.AllSystemServerJars(ctx).ContainsJar(moduleName(ctx))
 
-	bootImage := defaultBootImageConfig(ctx)
+	bootImage := nonUpdatableBootImageConfig(ctx)
 	// When `global.PreoptWithUpdatableBcp` is true, `bcpForDexpreopt` below includes the mainline
 	// boot jars into bootclasspath, so we should include the mainline boot image as well because it's
 	// generated from those jars.
@@ -314,14 +331,20 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 	var archs []android.ArchType
 	var images android.Paths
 	var imagesDeps []android.OutputPaths
+	var hostImageLocations, deviceImageLocations []string
 	for _, target := range targets {
 		archs = append(archs, target.Arch.ArchType)
-		variant := bootImage.getVariant(target)
-		images = append(images, variant.imagePathOnHost)
-		imagesDeps = append(imagesDeps, variant.imagesDeps)
+		location, ok := BootImageLocationForTarget(ctx, bootImage.name, target)
+		if !ok {
+			ctx.ModuleErrorf("no %s variant of the %q boot image to dexpreopt against",
+				target.Arch.ArchType, bootImage.name)
+			return
+		}
+		images = append(images, location.ImagePathOnHost)
+		imagesDeps = append(imagesDeps, location.ImagesDeps)
+		// The image locations for all Android variants are identical.
+		hostImageLocations, deviceImageLocations = location.ImageLocationsOnHost, location.ImageLocationsOnDevice
 	}
-	// The image locations for all Android variants are identical.
-	hostImageLocations, deviceImageLocations := bootImage.getAnyAndroidVariant().imageLocations()
 
 	var profileClassListing android.OptionalPath
 	var profileBootListing android.OptionalPath
@@ -354,7 +377,7 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 		ManifestPath:    android.OptionalPathForPath(d.manifestFile),
 		UncompressedDex: d.uncompressedDex,
 		HasApkLibraries: false,
-		PreoptFlags:     nil,
+		PreoptFlags:     d.dexpreoptDex2oatFlags(ctx),
 
 		ProfileClassListing:  profileClassListing,
 		ProfileIsTextListing: profileIsTextListing,
@@ -379,6 +402,8 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 		ForceCreateAppImage: BoolDefault(d.dexpreoptProperties.Dex_preopt.App_image, false),
 
 		PresignedPrebuilt: d.isPresignedPrebuilt,
+
+		EnableUffdGc: proptools.BoolPtr(bootImage.uffdGcEnabled(global)),
 	}
 
 	d.configPath = android.PathForModuleOut(ctx, "dexpreopt", "dexpreopt.config")
@@ -431,6 +456,9 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 			}
 		} else if !d.preventInstall {
 			ctx.InstallFile(installPath, installBase, install.From)
+			if global.PreoptPartition != "" && strings.HasPrefix(install.To, dexpreopt.PreoptPartitionInstallDir(global)+"/") {
+				d.preoptPartitionInstalls = append(d.preoptPartitionInstalls, install.To)
+			}
 		}
 	}
 
@@ -439,6 +467,12 @@ func (d *dexpreopter) dexpreopt(ctx android.ModuleContext, dexJarFile android.Wr
 	}
 }
 
+// PreoptPartitionInstalls returns the locations on the device of the dexpreopt outputs of the module
+// that are installed on the preopt partition of the product.
+func (d *dexpreopter) PreoptPartitionInstalls() []string {
+	return d.preoptPartitionInstalls
+}
+
 func (d *dexpreopter) DexpreoptBuiltInstalledForApex() []dexpreopterInstall {
 	return d.builtInstalledForApex
 }
@@ -15,13 +15,18 @@
 package java
 
 import (
+	"fmt"
+	"io"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"android/soong/android"
 	"android/soong/dexpreopt"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 )
 
@@ -42,6 +47,10 @@ import (
 //    respectively.
 // 5) Each boot_image retrieves the appropriate boot image configuration from the map returned by
 //    genBootImageConfigs() using the image_name specified in the boot_image module.
+// 6) dex_bootjars depends on the modules that build the boot images, i.e. the
+//    platform_bootclasspath module and the bootclasspath_fragment modules with an image_name,
+//    instead of looping over all the modules, and defines the DEXPREOPT_IMAGE_* variables in its
+//    Android-*.mk entry instead of in make_vars-*.mk.
 // =================================================================================================
 
 // This comment describes:
@@ -175,7 +184,8 @@ import (
 // One exception to the above rules are "coverage" builds (a special build flavor which requires
 // setting environment variable EMMA_INSTRUMENT_FRAMEWORK=true). In coverage builds the Java code in
 // boot image libraries is instrumented, which means that the instrumentation library (jacocoagent)
-// needs to be added to the list of bootclasspath DEX jars.
+// needs to be added to the list of bootclasspath DEX jars. The framework boot image is then also
+// built from the non-instrumented platform boot jars, see boot_image_coverage.go.
 //
 // In general, there is a requirement that the source code for a boot image library must be
 // available at build time (e.g. it cannot be a stub that has a separate implementation library).
@@ -198,11 +208,12 @@ import (
 // names. Then it generates build rules that copy DEX jars from their intermediate module-specific
 // locations to the hard-coded locations predefined in the boot image configs.
 //
-// It would be possible to use a module with proper dependencies instead, but that would require
-// changes in the way Soong generates variables for Make: a singleton can use one MakeVars() method
-// that writes variables to out/soong/make_vars-*.mk, which is included early by the main makefile,
-// but module(s) would have to use out/soong/Android-*.mk which has a group of LOCAL_* variables
-// for each module, and is included later.
+// The dex_bootjars singleton module that exports the boot images to Make uses proper dependencies
+// instead: it depends on the modules that build the boot images, which register themselves from
+// load hooks, and collects their BootImageInfo. The variables that only depend on the dexpreopt
+// config are still written to out/soong/make_vars-*.mk by its MakeVars() method, as they are
+// needed early by the main makefile, but the variables for the boot images are written to
+// out/soong/Android-*.mk, which is included later.
 //
 // 2.4. Install rules
 // ------------------
@@ -232,8 +243,8 @@ func init() {
 //
 // WARNING: All fields in this struct should be initialized in the genBootImageConfigs function.
 // Failure to do so can lead to data races if there is no synchronization enforced ordering between
-// the writer and the reader. Fields which break this rule are marked as deprecated and should be
-// removed and replaced with something else, e.g. providers.
+// the writer and the reader. The outputs of the modules that build the boot images must be
+// published with a provider instead, see BootImageInfo.
 type bootImageConfig struct {
 	// If this image is an extension, the image that it extends.
 	extends *bootImageConfig
@@ -244,10 +255,11 @@ type bootImageConfig struct {
 	// Basename of the image: the resulting filenames are <stem>[-<jar>].{art,oat,vdex}.
 	stem string
 
-	// Output directory for the image files.
+	// Output directory for the image files. Each dex2oat rule that compiles the image writes into
+	// a directory of its own under it, see componentDir and imageDir.
 	dir android.OutputPath
 
-	// Output directory for the image files with debug symbols.
+	// Output directory for the symbols dumped from the image files with debug symbols.
 	symbolsDir android.OutputPath
 
 	// The relative location where the image files are installed. On host, the location is relative to
@@ -274,15 +286,9 @@ type bootImageConfig struct {
 	// File path to a zip archive with all image files (or nil, if not needed).
 	zip android.WritablePath
 
-	// Rules which should be used in make to install the outputs.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	profileInstalls android.RuleBuilderInstalls
-
-	// Path to the license metadata file for the module that built the profile.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	profileLicenseMetadataFile android.OptionalPath
+	// File path to the delta metadata of the zip archive, which lists the SHA-256 hash of each file
+	// in the zip archive, so that the OTA tooling can tell which files changed between builds.
+	deltaMetadata android.WritablePath
 
 	// Target-dependent fields.
 	variants []*bootImageVariant
@@ -296,10 +302,20 @@ type bootImageConfig struct {
 	// The "--single-image" argument.
 	singleImage bool
 
-	// Profiles imported from other boot image configs. Each element must represent a
-	// `bootclasspath_fragment` of an APEX (i.e., the `name` field of each element must refer to the
-	// `image_name` property of a `bootclasspath_fragment`).
-	profileImports []*bootImageConfig
+	// Whether each jar of the image is compiled by its own dex2oat invocation, as a single image
+	// component that extends the components before it. It is set for the multi-image boot images
+	// other than the ART boot image, unless MonolithicBootImageDexpreopt is set in the global config.
+	dex2oatPerJar bool
+
+	// Whether to compile the image with the assumption that userfaultfd GC will be used on device,
+	// or nil to follow the EnableUffdGc of the global config.
+	enableUffdGc *bool
+
+	// The "-Xms" and "-Xmx" runtime arguments of dex2oat, or empty to use the Dex2oatImageXms and
+	// Dex2oatImageXmx of the global config. The product can set them per image, as e.g. a boot image
+	// extension can be compiled with much less memory than the framework boot image.
+	dex2oatXms string
+	dex2oatXmx string
 }
 
 // Target-dependent description of a boot image.
@@ -311,6 +327,10 @@ type bootImageVariant struct {
 	// Target for which the image is generated.
 	target android.Target
 
+	// The "--compiler-filter" argument, which is the compiler filter of the image config unless the
+	// product overrides it for the architecture of the target.
+	compilerFilter string
+
 	// The "locations" of jars.
 	dexLocations     []string // for this image
 	dexLocationsDeps []string // for the dependency images and in this image
@@ -319,10 +339,15 @@ type bootImageVariant struct {
 	imagePathOnHost   android.OutputPath // first image file path on host
 	imagePathOnDevice string             // first image file path on device
 
+	// Paths to the image files that are listed in the image locations, i.e. the first image file,
+	// or the image file of every component if the jars are compiled by a dex2oat invocation each.
+	componentImagePathsOnHost   android.OutputPaths
+	componentImagePathsOnDevice []string
+
 	// All the files that constitute this image variant, i.e. .art, .oat and .vdex files.
 	imagesDeps android.OutputPaths
 
-	// The path to the base image variant's imagePathOnHost field, where base image variant
+	// The paths in the base image variant's componentImagePathsOnHost field, where base image variant
 	// means the image variant that this extends.
 	//
 	// This is only set for a variant of an image that extends another image.
@@ -333,26 +358,6 @@ type bootImageVariant struct {
 	//
 	// This is only set for a variant of an image that extends another image.
 	baseImagesDeps android.Paths
-
-	// Rules which should be used in make to install the outputs on host.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	installs android.RuleBuilderInstalls
-
-	// Rules which should be used in make to install the vdex outputs on host.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	vdexInstalls android.RuleBuilderInstalls
-
-	// Rules which should be used in make to install the unstripped outputs on host.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	unstrippedInstalls android.RuleBuilderInstalls
-
-	// Path to the license metadata file for the module that built the image.
-	//
-	// Deprecated: Not initialized correctly, see struct comment.
-	licenseMetadataFile android.OptionalPath
 }
 
 // Get target-specific boot image variant for the given boot image config and target.
@@ -400,12 +405,12 @@ func (image bootImageConfig) firstModuleNameOrStem(ctx android.PathContext) stri
 
 // Return filenames for the given boot image component, given the output directory and a list of
 // extensions.
-func (image bootImageConfig) moduleFiles(ctx android.PathContext, dir android.OutputPath, exts ...string) android.OutputPaths {
+func (image bootImageConfig) moduleFiles(ctx android.PathContext, target android.Target, exts ...string) android.OutputPaths {
 	ret := make(android.OutputPaths, 0, image.modules.Len()*len(exts))
 	for i := 0; i < image.modules.Len(); i++ {
 		name := image.moduleName(ctx, i)
 		for _, ext := range exts {
-			ret = append(ret, dir.Join(ctx, name+ext))
+			ret = append(ret, image.imageDir(ctx, target, i).Join(ctx, name+ext))
 		}
 		if image.singleImage {
 			break
@@ -414,6 +419,38 @@ func (image bootImageConfig) moduleFiles(ctx android.PathContext, dir android.Ou
 	return ret
 }
 
+// componentDir returns the directory of the image files of the jar at the given index for the OS
+// type, without the architecture subdirectory, i.e. the directory of the image location of the
+// component of the image that contains the jar, e.g. <dir>/android/boot/system/framework. Each
+// dex2oat rule compiles a component of the image, or the whole image unless its jars are compiled
+// one by one, into the architecture subdirectory of its component directory.
+func (image bootImageConfig) componentDir(ctx android.PathContext, os android.OsType, idx int) android.OutputPath {
+	return image.dir.Join(ctx, os.String(), image.componentName(ctx, idx), image.installDir)
+}
+
+// componentName returns the name of the first image file of the component of the image that
+// contains the jar at the given index, without extension.
+func (image bootImageConfig) componentName(ctx android.PathContext, idx int) string {
+	if image.dex2oatPerJar {
+		return image.moduleName(ctx, idx)
+	}
+	return image.firstModuleNameOrStem(ctx)
+}
+
+// imageDir returns the directory of the image files of the jar at the given index for the target,
+// which is the output directory of the dex2oat rule that compiles it. The rule runs in sbox, which
+// clears the output directory before running dex2oat, so each rule has a directory of its own,
+// and the image files cannot be mixed with stale files or with the files of other rules.
+func (image bootImageConfig) imageDir(ctx android.PathContext, target android.Target, idx int) android.OutputPath {
+	return image.componentDir(ctx, target.Os, idx).Join(ctx, target.Arch.ArchType.String())
+}
+
+// unstrippedDir returns the directory of the unstripped oat file of the jar at the given index for
+// the target, in the output directory of the dex2oat rule that compiles it.
+func (image bootImageConfig) unstrippedDir(ctx android.PathContext, target android.Target, idx int) android.OutputPath {
+	return image.imageDir(ctx, target, idx).Join(ctx, "symbols")
+}
+
 // apexVariants returns a list of all *bootImageVariant that could be included in an apex.
 func (image *bootImageConfig) apexVariants() []*bootImageVariant {
 	variants := []*bootImageVariant{}
@@ -437,7 +474,8 @@ func (image *bootImageConfig) apexVariants() []*bootImageVariant {
 // For example a physical file /apex/com.android.art/javalib/x86/boot.art has "image location"
 // /apex/com.android.art/javalib/boot.art (which is not an actual file).
 //
-// For a primary boot image the list of locations has a single element.
+// For a primary boot image the list of locations has a single element, unless its jars are
+// compiled one by one, in which case it has the location of each component.
 //
 // For a boot image extension the list of locations contains a location for all dependency images
 // (including the primary image) and the location of the extension itself. For example, for the
@@ -450,8 +488,13 @@ func (image *bootImageVariant) imageLocations() (imageLocationsOnHost []string,
 	if image.extends != nil {
 		imageLocationsOnHost, imageLocationsOnDevice = image.extends.getVariant(image.target).imageLocations()
 	}
-	return append(imageLocationsOnHost, dexpreopt.PathToLocation(image.imagePathOnHost, image.target.Arch.ArchType)),
-		append(imageLocationsOnDevice, dexpreopt.PathStringToLocation(image.imagePathOnDevice, image.target.Arch.ArchType))
+	for _, path := range image.componentImagePathsOnHost {
+		imageLocationsOnHost = append(imageLocationsOnHost, dexpreopt.PathToLocation(path, image.target.Arch.ArchType))
+	}
+	for _, path := range image.componentImagePathsOnDevice {
+		imageLocationsOnDevice = append(imageLocationsOnDevice, dexpreopt.PathStringToLocation(path, image.target.Arch.ArchType))
+	}
+	return imageLocationsOnHost, imageLocationsOnDevice
 }
 
 func (image *bootImageConfig) isProfileGuided() bool {
@@ -459,6 +502,31 @@ func (image *bootImageConfig) isProfileGuided() bool {
 	return image.compilerFilter == "everything"
 }
 
+// uffdGcEnabled returns whether the image is compiled with the assumption that userfaultfd GC will
+// be used on device.
+func (image *bootImageConfig) uffdGcEnabled(global *dexpreopt.GlobalConfig) bool {
+	if image.enableUffdGc != nil {
+		return *image.enableUffdGc
+	}
+	return global.EnableUffdGc
+}
+
+// dex2oatImageXms returns the initial heap size of dex2oat when it compiles the image.
+func (image *bootImageConfig) dex2oatImageXms(global *dexpreopt.GlobalConfig) string {
+	if image.dex2oatXms != "" {
+		return image.dex2oatXms
+	}
+	return global.Dex2oatImageXms
+}
+
+// dex2oatImageXmx returns the maximum heap size of dex2oat when it compiles the image.
+func (image *bootImageConfig) dex2oatImageXmx(global *dexpreopt.GlobalConfig) string {
+	if image.dex2oatXmx != "" {
+		return image.dex2oatXmx
+	}
+	return global.Dex2oatImageXmx
+}
+
 func dexpreoptBootJarsFactory() android.SingletonModule {
 	m := &dexpreoptBootJars{}
 	android.InitAndroidModule(m)
@@ -469,6 +537,97 @@ func RegisterDexpreoptBootJarsComponents(ctx android.RegistrationContext) {
 	ctx.RegisterSingletonModuleType("dex_bootjars", dexpreoptBootJarsFactory)
 }
 
+// bootImageModule is a module that may build a boot image.
+type bootImageModule struct {
+	// The image name of the bootclasspath_fragment module, or an empty string for the
+	// platform_bootclasspath modules.
+	imageName string
+
+	// The names of the source module and of its prebuilt, qualified with the namespace of the module
+	// if it is not in the root namespace.
+	deps []string
+}
+
+// bootImageModulesList contains the modules that may build boot images, i.e. the
+// platform_bootclasspath modules and the bootclasspath_fragment modules with an image_name, keyed
+// by their qualified source module name, so that the modules with the same name in different
+// namespaces are all recorded.
+type bootImageModulesList struct {
+	sync.Mutex
+	modules map[string]bootImageModule
+}
+
+var bootImageModulesKey = android.NewOnceKey("bootImageModules")
+
+func getBootImageModules(config android.Config) *bootImageModulesList {
+	return config.Once(bootImageModulesKey, func() interface{} {
+		return &bootImageModulesList{modules: make(map[string]bootImageModule)}
+	}).(*bootImageModulesList)
+}
+
+// qualifiedBootImageModuleName returns the name of the module qualified with the path of its
+// namespace, e.g. //vendor/foo:name, or the name itself if the module is in the root namespace.
+func qualifiedBootImageModuleName(ctx android.LoadHookContext, name string) string {
+	if ns := ctx.Namespace(); ns != nil && ns.Path != "." {
+		return "//" + ns.Path + ":" + name
+	}
+	return name
+}
+
+// registerBootImageModule records a module that may build a boot image, so that the dex_bootjars
+// module depends on it. It must be called from a load hook.
+func registerBootImageModule(ctx android.LoadHookContext, imageName string) {
+	name := android.RemoveOptionalPrebuiltPrefix(ctx.ModuleName())
+	module := bootImageModule{
+		imageName: imageName,
+		deps: []string{
+			qualifiedBootImageModuleName(ctx, name),
+			qualifiedBootImageModuleName(ctx, android.PrebuiltNameFromSource(name)),
+		},
+	}
+
+	l := getBootImageModules(ctx.Config())
+	l.Lock()
+	defer l.Unlock()
+	l.modules[module.deps[0]] = module
+}
+
+var dexBootJarsBootImageDepTag = bootclasspathDependencyTag{name: "dex-bootjars-boot-image"}
+
+// BootclasspathDepsMutator adds dependencies onto the modules that may build boot images, i.e.
+// onto the platform variant of the platform_bootclasspath modules and onto the APEX variant of the
+// bootclasspath_fragment modules, or their prebuilts. There is no dependency onto the modules that
+// do not exist, e.g. on unbundled branches.
+func (d *dexpreoptBootJars) BootclasspathDepsMutator(ctx android.BottomUpMutatorContext) {
+	l := getBootImageModules(ctx.Config())
+	l.Lock()
+	defer l.Unlock()
+
+	imageConfigs := genBootImageConfigs(ctx)
+	for _, name := range android.SortedKeys(l.modules) {
+		module := l.modules[name]
+		var variations []blueprint.Variation
+		if imageName := module.imageName; imageName != "" {
+			config := imageConfigs[imageName]
+			if config == nil || config.modules.Len() == 0 {
+				continue
+			}
+			if apex := config.modules.Apex(0); apex != "platform" && apex != "system_ext" {
+				variations = append(variations, blueprint.Variation{Mutator: "apex", Variation: apex})
+			}
+		}
+		variations = append(variations, ctx.Config().AndroidCommonTarget.Variations()...)
+
+		for _, dep := range module.deps {
+			if ctx.OtherModuleDependencyVariantExists(variations, dep) {
+				ctx.AddFarVariationDependencies(variations, dexBootJarsBootImageDepTag, dep)
+			}
+		}
+	}
+}
+
+var _ BootclasspathDepsMutator = (*dexpreoptBootJars)(nil)
+
 func SkipDexpreoptBootJars(ctx android.PathContext) bool {
 	return dexpreopt.GetGlobalConfig(ctx).DisablePreoptBootImages
 }
@@ -488,22 +647,83 @@ type dexpreoptBootJars struct {
 	// the future other boot image extensions may be added.
 	otherImages []*bootImageConfig
 
+	// The install information of the boot images, keyed by the name of the boot image config, as
+	// provided by the active modules that built them.
+	bootImageInstalls map[string]*bootImageInstallInfo
+
+	// The Make variables for the boot images, written to the Android-*.mk entry of the module.
+	bootImageMakeVars map[string]string
+
 	// Build path to a config file that Soong writes for Make (to be used in makefiles that install
 	// the default boot image).
 	dexpreoptConfigForMake android.WritablePath
+
+	// Path to the manifest of the boot images, boot_images.json.
+	bootImagesManifest android.WritablePath
+
+	// The outputs of the default boot image that can be referenced with tags, as provided by the
+	// module that built it, or nil if they were not built.
+	bootImageProfile android.Path
+	bootImageBprof   android.Path
+	bootImageZip     android.Path
 }
 
-// Provide paths to boot images for use by modules that depend upon them.
+// Provide paths to boot images for use by modules that depend upon them, and collect the boot
+// images built by the modules it depends on.
 //
 // The build rules are created in GenerateSingletonBuildActions().
 func (d *dexpreoptBootJars) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	// Placeholder for now.
+	// The manifest of the boot images is written by GenerateSingletonBuildActions, but its path must
+	// be known by the modules that depend on it.
+	d.bootImagesManifest = bootImagesManifestPath(ctx)
+
+	// Collect the installs of the boot images from the modules that built them. Only the active
+	// modules are considered, e.g. not a source bootclasspath_fragment that is replaced by a
+	// prebuilt.
+	d.bootImageInstalls = make(map[string]*bootImageInstallInfo)
+	ctx.VisitDirectDepsWithTag(dexBootJarsBootImageDepTag, func(module android.Module) {
+		if !isActiveModule(module) || !ctx.OtherModuleHasProvider(module, BootImageInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(module, BootImageInfoProvider).(BootImageInfo)
+		for name, installInfo := range info.images {
+			d.bootImageInstalls[name] = installInfo
+		}
+	})
+
+	// The tagged outputs are set here rather than in GenerateSingletonBuildActions, so that they are
+	// available to the modules that reference them, e.g. in their dist properties.
+	defaultImageConfig := defaultBootImageConfig(ctx)
+	if installInfo := d.bootImageInstalls[defaultImageConfig.name]; installInfo != nil {
+		d.bootImageProfile = installInfo.profile
+		d.bootImageBprof = installInfo.bprof
+		d.bootImageZip = installInfo.zip
+	}
+
+	global := dexpreopt.GetGlobalConfig(ctx)
+	if !dexpreopt.IsDex2oatNeeded(ctx) || !shouldBuildBootImages(ctx.Config(), global) {
+		return
+	}
+
+	d.defaultBootImage = defaultImageConfig
+	imageConfigs := genBootImageConfigs(ctx)
+	d.otherImages = make([]*bootImageConfig, 0, len(imageConfigs)-1)
+	for _, name := range android.SortedKeys(imageConfigs) {
+		if config := imageConfigs[name]; config != defaultImageConfig {
+			d.otherImages = append(d.otherImages, config)
+		}
+	}
+
+	d.bootImageMakeVars = d.generateBootImageMakeVars(ctx)
 }
 
 // Generate build rules for boot images.
 func (d *dexpreoptBootJars) GenerateSingletonBuildActions(ctx android.SingletonContext) {
+	d.checkMissingBootDexJars(ctx)
+
 	if dexpreopt.GetCachedGlobalSoongConfig(ctx) == nil {
 		// No module has enabled dexpreopting, so we assume there will be no boot image to make.
+		d.writeBootImagesManifest(ctx, nil)
 		return
 	}
 	archType := ctx.Config().Targets[android.Android][0].Arch.ArchType
@@ -512,18 +732,18 @@ func (d *dexpreoptBootJars) GenerateSingletonBuildActions(ctx android.SingletonC
 
 	global := dexpreopt.GetGlobalConfig(ctx)
 	if !shouldBuildBootImages(ctx.Config(), global) {
+		d.writeBootImagesManifest(ctx, nil)
 		return
 	}
 
-	defaultImageConfig := defaultBootImageConfig(ctx)
-	d.defaultBootImage = defaultImageConfig
 	imageConfigs := genBootImageConfigs(ctx)
-	d.otherImages = make([]*bootImageConfig, 0, len(imageConfigs)-1)
-	for _, config := range imageConfigs {
-		if config != defaultImageConfig {
-			d.otherImages = append(d.otherImages, config)
+	var images []*bootImageConfig
+	if !SkipDexpreoptBootJars(ctx) {
+		for _, name := range android.SortedKeys(imageConfigs) {
+			images = append(images, imageConfigs[name])
 		}
 	}
+	d.writeBootImagesManifest(ctx, images)
 }
 
 // shouldBuildBootImages determines whether boot images should be built.
@@ -561,8 +781,18 @@ func copyBootJarsToPredefinedLocations(ctx android.ModuleContext, srcBootDexJars
 			// prebuilt_(boot|systemserver)classpath_fragment module, which in turn lists the prebuilt
 			// java module in the contents property. If that chain is broken then this dependency will
 			// fail.
-			if !ctx.Config().AllowMissingDependencies() {
-				ctx.ModuleErrorf("module %s does not provide a dex boot jar (see comment next to this message in Soong for details)", name)
+			//
+			// The error is reported by the dex_bootjars singleton, which can find the reason among all
+			// the modules named after the jar, see checkMissingBootDexJars.
+			if !ctx.AllowMissingDependencies() {
+				registerMissingBootDexJar(ctx.Config(), ctx.ModuleName(), name)
+				ctx.Build(pctx, android.BuildParams{
+					Rule:   android.ErrorRule,
+					Output: dst,
+					Args: map[string]string{
+						"error": fmt.Sprintf("module %s does not provide a dex boot jar", name),
+					},
+				})
 			} else {
 				ctx.AddMissingDependencies([]string{name})
 			}
@@ -583,8 +813,9 @@ func copyBootJarsToPredefinedLocations(ctx android.ModuleContext, srcBootDexJars
 // boot image files.
 //
 // The paths are returned because they are needed elsewhere in Soong, e.g. for populating an APEX.
-func buildBootImageVariantsForAndroidOs(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath) bootImageOutputs {
-	return buildBootImageForOsType(ctx, image, profile, android.Android)
+func buildBootImageVariantsForAndroidOs(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath,
+	updatableBcpPackages android.OptionalPath) bootImageOutputs {
+	return buildBootImageForOsType(ctx, image, profile, updatableBcpPackages, android.Android)
 }
 
 // buildBootImageVariantsForBuildOs generates rules to build the boot image variants for the
@@ -593,9 +824,11 @@ func buildBootImageVariantsForAndroidOs(ctx android.ModuleContext, image *bootIm
 // The files need to be generated into their predefined location because they are used from there
 // both within Soong and outside, e.g. for ART based host side testing and also for use by some
 // cloud based tools. However, they are not needed by callers of this function and so the paths do
-// not need to be returned from this func, unlike the buildBootImageVariantsForAndroidOs func.
-func buildBootImageVariantsForBuildOs(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath) {
-	buildBootImageForOsType(ctx, image, profile, ctx.Config().BuildOS)
+// not need to be returned from this func, unlike the buildBootImageVariantsForAndroidOs func. The
+// outputs are only returned for their installs.
+func buildBootImageVariantsForBuildOs(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath,
+	updatableBcpPackages android.OptionalPath) bootImageOutputs {
+	return buildBootImageForOsType(ctx, image, profile, updatableBcpPackages, ctx.Config().BuildOS)
 }
 
 // bootImageFilesByArch is a map from android.ArchType to the paths to the boot image files.
@@ -610,18 +843,111 @@ type bootImageOutputs struct {
 	// Map from arch to the paths to the boot image files created/obtained for that arch.
 	byArch bootImageFilesByArch
 
+	// The outputs of the variants that were created/obtained, including those of the host variants
+	// for their installs.
 	variants []bootImageVariantOutputs
 
 	// The path to the profile file created/obtained for the boot image.
 	profile android.WritablePath
 }
 
-// buildBootImageForOsType takes a bootImageConfig, a profile file and an android.OsType
-// boot image files are required for and it creates rules to build the boot image
-// files for all the required architectures for them.
+// BootImageInfo contains the files of the boot images built by a module, i.e. by the
+// platform_bootclasspath module or by the bootclasspath_fragment module of the ART APEX, that are
+// installed by Make.
+//
+// It is the supported way to query the installed boot image outputs, instead of fields of the
+// shared bootImageConfig that are written by whichever module builds the boot image. The
+// dex_bootjars singleton module collects it from the active modules and exports it to Make.
+type BootImageInfo struct {
+	// The install information of the boot images, keyed by the name of the boot image config.
+	images map[string]*bootImageInstallInfo
+}
+
+var BootImageInfoProvider = blueprint.NewProvider(BootImageInfo{})
+
+// bootImageInstallInfo contains the files of a boot image that are installed by Make.
+type bootImageInstallInfo struct {
+	// Rules which should be used in make to install the profiles.
+	profileInstalls android.RuleBuilderInstalls
+
+	// Path to the license metadata file for the module that built the profiles.
+	profileLicenseMetadataFile android.OptionalPath
+
+	// The boot image profile, the boot framework profile and the zip of the android variants of the
+	// boot image, along with its delta metadata, if they were built.
+	profile       android.Path
+	bprof         android.Path
+	zip           android.Path
+	deltaMetadata android.Path
+
+	// The install information of the variants of the boot image that were built.
+	variants []*bootImageVariantInstallInfo
+}
+
+// bootImageVariantInstallInfo contains the files of a boot image variant that are installed by
+// Make.
+type bootImageVariantInstallInfo struct {
+	// Target for which the image is generated.
+	target android.Target
+
+	// Rules which should be used in make to install the outputs.
+	installs android.RuleBuilderInstalls
+
+	// Rules which should be used in make to install the vdex outputs.
+	vdexInstalls android.RuleBuilderInstalls
+
+	// Rules which should be used in make to install the unstripped outputs.
+	unstrippedInstalls android.RuleBuilderInstalls
+
+	// Path to the license metadata file for the module that built the image.
+	licenseMetadataFile android.OptionalPath
+}
+
+// addProfileInstalls records the installs of the profiles built by the current module.
+func (i *bootImageInstallInfo) addProfileInstalls(ctx android.ModuleContext, installs android.RuleBuilderInstalls) {
+	if len(installs) == 0 {
+		return
+	}
+	i.profileInstalls = append(i.profileInstalls, installs...)
+	i.profileLicenseMetadataFile = android.OptionalPathForPath(ctx.LicenseMetadataFile())
+}
+
+// addVariants records the installs of the boot image variants built by the current module.
+func (i *bootImageInstallInfo) addVariants(ctx android.ModuleContext, outputs bootImageOutputs) {
+	for _, variant := range outputs.variants {
+		if variant.config == nil {
+			continue
+		}
+		i.variants = append(i.variants, &bootImageVariantInstallInfo{
+			target:              variant.config.target,
+			installs:            variant.installs,
+			vdexInstalls:        variant.vdexInstalls,
+			unstrippedInstalls:  variant.unstrippedInstalls,
+			licenseMetadataFile: android.OptionalPathForPath(ctx.LicenseMetadataFile()),
+		})
+	}
+}
+
+// variant returns the install information of the boot image variant for the given target, or an
+// empty one if the variant was not built.
+func (i *bootImageInstallInfo) variant(target android.Target) *bootImageVariantInstallInfo {
+	if i != nil {
+		for _, variant := range i.variants {
+			if variant.target.Os == target.Os && variant.target.Arch.ArchType == target.Arch.ArchType {
+				return variant
+			}
+		}
+	}
+	return &bootImageVariantInstallInfo{target: target}
+}
+
+// buildBootImageForOsType takes a bootImageConfig, a profile file, the optional
+// updatable-bcp-packages.txt file and an android.OsType boot image files are required for and it
+// creates rules to build the boot image files for all the required architectures for them.
 //
 // It returns a map from android.ArchType to the predefined paths of the boot image files.
-func buildBootImageForOsType(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath, requiredOsType android.OsType) bootImageOutputs {
+func buildBootImageForOsType(ctx android.ModuleContext, image *bootImageConfig, profile android.WritablePath,
+	updatableBcpPackages android.OptionalPath, requiredOsType android.OsType) bootImageOutputs {
 	filesByArch := bootImageFilesByArch{}
 	imageOutputs := bootImageOutputs{
 		byArch:  filesByArch,
@@ -629,7 +955,7 @@ func buildBootImageForOsType(ctx android.ModuleContext, image *bootImageConfig,
 	}
 	for _, variant := range image.variants {
 		if variant.target.Os == requiredOsType {
-			variantOutputs := buildBootImageVariant(ctx, variant, profile)
+			variantOutputs := buildBootImageVariant(ctx, variant, profile, updatableBcpPackages)
 			imageOutputs.variants = append(imageOutputs.variants, variantOutputs)
 			filesByArch[variant.target.Arch.ArchType] = variant.imagesDeps.Paths()
 		}
@@ -638,13 +964,126 @@ func buildBootImageForOsType(ctx android.ModuleContext, image *bootImageConfig,
 	return imageOutputs
 }
 
-// buildBootImageZipInPredefinedLocation generates a zip file containing all the boot image files.
+// extractPrebuiltBootImageForAndroidOs generates rules to extract the android.Android variants of
+// the boot image from the zip of a prebuilt boot image, instead of building them from the dex
+// files, and returns a map from the architectures to the paths of the extracted boot image files.
+//
+// The files are extracted to the same predefined locations as the built files, so the boot image
+// extensions can be compiled against them as usual.
+func extractPrebuiltBootImageForAndroidOs(ctx android.ModuleContext, image *bootImageConfig, zip android.Path, profile android.WritablePath) bootImageOutputs {
+	filesByArch := bootImageFilesByArch{}
+	imageOutputs := bootImageOutputs{
+		byArch:  filesByArch,
+		profile: profile,
+	}
+	for _, variant := range image.variants {
+		if variant.target.Os == android.Android {
+			variantOutputs := extractPrebuiltBootImageVariant(ctx, variant, zip)
+			imageOutputs.variants = append(imageOutputs.variants, variantOutputs)
+			filesByArch[variant.target.Arch.ArchType] = variant.imagesDeps.Paths()
+		}
+	}
+
+	return imageOutputs
+}
+
+// Generate the rules to extract the prebuilt boot image files for a specific target.
+func extractPrebuiltBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, zip android.Path) bootImageVariantOutputs {
+	arch := image.target.Arch.ArchType
+	archDir := filepath.Join(image.installDir, arch.String())
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+
+	// Extract the image files into the directories that the dex2oat rules would have written them
+	// to, which is where the consumers of the image look for them.
+	rules := 1
+	if image.dex2oatPerJar {
+		rules = image.modules.Len()
+	}
+	var cmd *android.RuleBuilderCommand
+	for i := 0; i < rules; i++ {
+		zipDir := image.dir.Join(ctx, image.target.Os.String(), image.componentName(ctx, i))
+		pattern := archDir + "/*"
+		if image.dex2oatPerJar {
+			pattern = archDir + "/" + image.moduleName(ctx, i) + ".*"
+		}
+		rule.Command().Text("rm").Flag("-rf").Flag(image.imageDir(ctx, image.target, i).String())
+		cmd = rule.Command().
+			Text("unzip").Flag("-qoDD").
+			FlagWithArg("-d ", zipDir.String()).
+			Input(zip).
+			Text(proptools.ShellEscape(pattern))
+	}
+
+	installDir := filepath.Dir(image.imagePathOnDevice)
+
+	var vdexInstalls android.RuleBuilderInstalls
+
+	for _, artOrOat := range image.moduleFiles(ctx, image.target, ".art", ".oat") {
+		cmd.ImplicitOutput(artOrOat)
+
+		// Install the .oat and .art files
+		rule.Install(artOrOat, filepath.Join(installDir, artOrOat.Base()))
+	}
+
+	for _, vdex := range image.moduleFiles(ctx, image.target, ".vdex") {
+		cmd.ImplicitOutput(vdex)
+
+		// Note that the vdex files are identical between architectures.
+		// Make rules will create symlinks to share them between architectures.
+		vdexInstalls = append(vdexInstalls,
+			android.RuleBuilderInstall{vdex, filepath.Join(installDir, vdex.Base())})
+	}
+
+	// The extracted image is always checked against the dex files of the boot classpath.
+	cmd.Validation(checkPrebuiltBootImageVariant(ctx, image))
+
+	rule.Build(image.name+"PrebuiltBootImage_"+image.target.String(), "extract prebuilt "+image.name+" image "+arch.String())
+
+	return bootImageVariantOutputs{
+		config:       image,
+		installs:     rule.Installs(),
+		vdexInstalls: vdexInstalls,
+		// The prebuilt boot image only contains the stripped oat files.
+		unstrippedInstalls: nil,
+	}
+}
+
+// checkPrebuiltBootImageVariant generates the rule that checks that the extracted prebuilt boot
+// image matches the dex files of the boot classpath, and returns the header dumped by oatdump. The
+// runtime only loads a boot image whose recorded dex locations and checksums match the boot
+// classpath, so this fails early, and with a clear message, if the prebuilt image was compiled
+// from other dex files than the ones provided by the contents of the fragment.
+func checkPrebuiltBootImageVariant(ctx android.ModuleContext, image *bootImageVariant) android.WritablePath {
+	arch := image.target.Arch.ArchType
+	output := android.PathForModuleOut(ctx, "prebuilt_boot_image_check", image.name, arch.String()+".oatdump.txt")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	imageLocationsOnHost, _ := image.imageLocations()
+	rule.Command().
+		BuiltTool("oatdump").
+		Flag("--header-only").
+		FlagWithInputList("--runtime-arg -Xbootclasspath:", image.dexPathsDeps.Paths(), ":").
+		FlagWithList("--runtime-arg -Xbootclasspath-locations:", image.dexLocationsDeps, ":").
+		FlagWithArg("--image=", strings.Join(imageLocationsOnHost, ":")).
+		Implicits(image.imagesDeps.Paths()).
+		FlagWithArg("--instruction-set=", arch.String()).
+		FlagWithOutput("--output=", output).
+		Textf(`|| ( echo %s ; false )`, proptools.ShellEscape(prebuiltBootImageFailureMessage))
+	rule.Build("check_prebuilt_"+image.name+"_"+image.target.String(), "check prebuilt "+image.name+" image "+arch.String())
+	return output
+}
+
+// buildBootImageZipInPredefinedLocation generates a zip file containing all the boot image files
+// and returns its path, or nil if it is not generated. It also generates the delta metadata of the
+// zip file, with a "<sha256>  <path in the zip file>" line for each file, sorted by path, and
+// returns its path along with the zip file.
 //
 // The supplied filesByArch is nil when the boot image files have not been generated. Otherwise, it
 // is a map from android.ArchType to the predefined locations.
-func buildBootImageZipInPredefinedLocation(ctx android.ModuleContext, image *bootImageConfig, filesByArch bootImageFilesByArch) {
+func buildBootImageZipInPredefinedLocation(ctx android.ModuleContext, image *bootImageConfig, filesByArch bootImageFilesByArch) (zip, deltaMetadata android.Path) {
 	if filesByArch == nil {
-		return
+		return nil, nil
 	}
 
 	// Compute the list of files from all the architectures.
@@ -653,46 +1092,350 @@ func buildBootImageZipInPredefinedLocation(ctx android.ModuleContext, image *boo
 		zipFiles = append(zipFiles, filesByArch[archType]...)
 	}
 
+	// The files are in the output directories of the dex2oat rules that compiled them, under their
+	// paths in the zip file, i.e. <install dir>/<arch>/<file>.
 	rule := android.NewRuleBuilder(pctx, ctx)
-	rule.Command().
+	zipCmd := rule.Command().
 		BuiltTool("soong_zip").
-		FlagWithOutput("-o ", image.zip).
-		FlagWithArg("-C ", image.dir.Join(ctx, android.Android.String()).String()).
-		FlagWithInputList("-f ", zipFiles, " -f ")
+		FlagWithOutput("-o ", image.zip)
+	var outputDirs []string
+	for _, file := range zipFiles {
+		rel := filepath.Join(image.installDir, filepath.Base(filepath.Dir(file.String())), file.Base())
+		outputDir := strings.TrimSuffix(file.String(), "/"+rel)
+		if len(outputDirs) == 0 || outputDirs[len(outputDirs)-1] != outputDir {
+			zipCmd.FlagWithArg("-C ", outputDir)
+			outputDirs = append(outputDirs, outputDir)
+		}
+		zipCmd.FlagWithInput("-f ", file)
+	}
+
+	// The hashes are processed in a temporary file rather than through a pipe, so that a failure of
+	// any of the commands fails the rule.
+	hashes := image.deltaMetadata.ReplaceExtension(ctx, "sha256.tmp")
+	relHashes := image.deltaMetadata.ReplaceExtension(ctx, "rel.tmp")
+	rule.Command().
+		Text("sha256sum").
+		Inputs(zipFiles).
+		FlagWithOutput("> ", hashes)
+	sedCmd := rule.Command().Text("sed")
+	for _, outputDir := range android.FirstUniqueStrings(outputDirs) {
+		sedCmd.FlagWithArg("-e ", "'s|  "+outputDir+"/|  |'")
+	}
+	sedCmd.Input(hashes).FlagWithOutput("> ", relHashes)
+	rule.Command().
+		Text("LC_ALL=C sort -k 2").
+		FlagWithOutput("-o ", image.deltaMetadata).
+		Input(relHashes)
+	rule.Temporary(hashes)
+	rule.Temporary(relHashes)
+	rule.DeleteTemporaryFiles()
 
 	rule.Build("zip_"+image.name, "zip "+image.name+" image")
+	return image.zip, image.deltaMetadata
 }
 
 type bootImageVariantOutputs struct {
 	config *bootImageVariant
+
+	// The outputs of the variant to install, for makevars.
+	installs           android.RuleBuilderInstalls
+	vdexInstalls       android.RuleBuilderInstalls
+	unstrippedInstalls android.RuleBuilderInstalls
 }
 
-// Generate boot image build rules for a specific target.
-func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, profile android.Path) bootImageVariantOutputs {
+// Generate boot image build rules for a specific target. The updatable-bcp-packages.txt file, if
+// valid, is passed to dex2oat with --updatable-bcp-packages-file.
+func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, profile android.Path,
+	updatableBcpPackages android.OptionalPath) bootImageVariantOutputs {
+	profiles, ok := bootImageVariantProfiles(ctx, image, profile)
+	if !ok {
+		return bootImageVariantOutputs{}
+	}
+
+	// The verification of the image is a validation of the rule that compiles its last component,
+	// so that it runs whenever the image is built.
+	var verification android.WritablePath
+	if verifyBootImage(ctx, image) {
+		verification = buildBootImageVerification(ctx, image)
+	}
+
+	dirtyImageObjects := buildDirtyImageObjects(ctx, image)
+
+	var outputs bootImageVariantOutputs
+	if !image.dex2oatPerJar {
+		outputs = buildBootImageComponents(ctx, image, profiles, dirtyImageObjects, updatableBcpPackages, 0, image.modules.Len(), verification)
+	} else {
+		// Compile each jar of the image by its own dex2oat invocation, against the base images and
+		// the components of the image compiled before it, so that changing one jar only recompiles
+		// it and the components that follow it.
+		for i := 0; i < image.modules.Len(); i++ {
+			var validation android.WritablePath
+			if i == image.modules.Len()-1 {
+				validation = verification
+			}
+			componentOutputs := buildBootImageComponents(ctx, image, profiles, dirtyImageObjects, updatableBcpPackages, i, i+1, validation)
+			outputs.installs = append(outputs.installs, componentOutputs.installs...)
+			outputs.vdexInstalls = append(outputs.vdexInstalls, componentOutputs.vdexInstalls...)
+			outputs.unstrippedInstalls = append(outputs.unstrippedInstalls, componentOutputs.unstrippedInstalls...)
+		}
+	}
+	outputs.config = image
+
+	if dumpBootImageSymbols(ctx, image) {
+		symbolsInstalls := buildBootImageSymbols(ctx, image, outputs.unstrippedInstalls)
+		outputs.unstrippedInstalls = append(outputs.unstrippedInstalls, symbolsInstalls...)
+
+		if dexpreopt.GetGlobalConfig(ctx).BootImageMetadata {
+			outputs.installs = append(outputs.installs, buildBootImageMetadata(ctx, image))
+		}
+	}
+	return outputs
+}
+
+// buildBootImageMetadata generates the metadata file of the boot image variant, which lists the
+// build fingerprint, the version of dex2oat and the SHA-256 digest of each jar of the image by its
+// location on the device, e.g.:
+//
+//	fingerprint=<build fingerprint>
+//	dex2oat_version=<version>
+//	/system/framework/framework.jar=<sha256>
+//
+// The dex2oat version is the image version that dex2oat writes after the magic in the header of
+// the image, which changes whenever the output of dex2oat becomes incompatible. Each value is read
+// into a shell variable before it is written, so that a failure of the command fails the rule.
+//
+// It returns the install of the metadata file, which is installed next to the first file of the
+// image, e.g. boot.metadata next to boot.art.
+func buildBootImageMetadata(ctx android.ModuleContext, image *bootImageVariant) android.RuleBuilderInstall {
+	global := dexpreopt.GetGlobalConfig(ctx)
+	// The metadata file is written next to the directories of the dex2oat rules rather than in their
+	// output directories, which sbox clears.
+	metadata := image.dir.Join(ctx, image.target.Os.String(), image.target.Arch.ArchType.String(),
+		strings.TrimSuffix(image.imagePathOnHost.Base(), ".art")+".metadata")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("rm -f").Output(metadata)
+	if global.BuildFingerprintFile != nil {
+		rule.Command().
+			Text("fingerprint=$(cat").
+			Input(global.BuildFingerprintFile).
+			Text(")")
+		rule.Command().Text("echo fingerprint=${fingerprint} >>").Text(metadata.String())
+	}
+	rule.Command().
+		Text("version=$(dd").
+		FlagWithInput("if=", image.imagePathOnHost).
+		Text("bs=1 skip=4 count=3 status=none)")
+	rule.Command().Text(`test -n "${version}"`)
+	rule.Command().Text("echo dex2oat_version=${version} >>").Text(metadata.String())
+	for i, dexPath := range image.dexPaths {
+		rule.Command().
+			Text("sha256=$(sha256sum <").
+			Input(dexPath).
+			Text(")")
+		rule.Command().
+			Textf("echo %s=${sha256%%%% *} >>", image.dexLocations[i]).
+			Text(metadata.String())
+	}
+	rule.Build("metadata_"+image.name+"_"+image.target.String(),
+		"boot image metadata "+image.name+" "+image.target.Arch.ArchType.String())
+
+	installDir := filepath.Dir(image.imagePathOnDevice)
+	return android.RuleBuilderInstall{metadata, filepath.Join(installDir, metadata.Base())}
+}
+
+// dumpBootImageSymbols returns whether the symbols of the boot image variant are dumped, i.e. it
+// is a device boot image and either the DumpBootImageSymbols property of the global dexpreopt
+// config is set or ART_DUMP_BOOT_IMAGE_SYMBOLS is true. Like the verification of the boot images,
+// it is opt-in, as oatdump is built from the ART sources, which are not available in the branches
+// that use a prebuilt ART module.
+func dumpBootImageSymbols(ctx android.ModuleContext, image *bootImageVariant) bool {
+	if image.target.Os != android.Android {
+		return false
+	}
+	return dexpreopt.GetGlobalConfig(ctx).DumpBootImageSymbols || ctx.Config().IsEnvTrue("ART_DUMP_BOOT_IMAGE_SYMBOLS")
+}
+
+// buildBootImageSymbols generates the rule that dumps the names and the code offsets of the
+// compiled methods of the unstripped oat files of the boot image variant with oatdump, so that
+// profilers like simpleperf can symbolize the boot classpath code without running oatdump for
+// each architecture themselves. It returns the installs of the symbols files, which are installed
+// along with the unstripped oat files, e.g. boot-framework.symbols.json next to
+// boot-framework.oat.
+func buildBootImageSymbols(ctx android.ModuleContext, image *bootImageVariant, unstrippedOats android.RuleBuilderInstalls) android.RuleBuilderInstalls {
+	if len(unstrippedOats) == 0 {
+		return nil
+	}
+
+	arch := image.target.Arch.ArchType
+	symbolsDir := image.symbolsDir.Join(ctx, image.target.Os.String(), image.installDir, arch.String())
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	var installs android.RuleBuilderInstalls
+	for _, oat := range unstrippedOats {
+		name := strings.TrimSuffix(oat.From.Base(), ".oat") + ".symbols.json"
+		symbols := symbolsDir.Join(ctx, name)
+		rule.Command().
+			BuiltTool("oatdump").
+			FlagWithInput("--oat-file=", oat.From).
+			Flag("--dump-method-and-offset-as-json").
+			FlagWithOutput("--output=", symbols)
+		installs = append(installs, android.RuleBuilderInstall{symbols, filepath.Join(filepath.Dir(oat.To), name)})
+	}
+	rule.Build("symbols_"+image.name+"_"+image.target.String(), "dump "+image.name+" boot image symbols "+arch.String())
+	return installs
+}
+
+// bootImageVariantProfiles returns the profiles to compile the boot image variant with, i.e. the
+// given profile and, for a profile guided image, the profiles exported by the
+// bootclasspath_fragment dependencies, see their export_profile property. It reports an error and
+// returns false if an exported profile is missing.
+func bootImageVariantProfiles(ctx android.ModuleContext, image *bootImageVariant, profile android.Path) (android.Paths, bool) {
+	var profiles android.Paths
+	if profile != nil {
+		profiles = append(profiles, profile)
+	}
+	if !image.isProfileGuided() {
+		return profiles, true
+	}
+
+	// The exported profiles keyed by the name of the fragments.
+	fragments := make(map[string]BootclasspathFragmentProfileInfo)
+	ctx.VisitDirectDepsWithTag(bootclasspathFragmentDepTag, func(child android.Module) {
+		if !android.IsModulePreferred(child) || !ctx.OtherModuleHasProvider(child, BootclasspathFragmentProfileInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(child, BootclasspathFragmentProfileInfoProvider).(BootclasspathFragmentProfileInfo)
+		if info.Exported && (info.ImageName == "" || info.ImageName != image.name) {
+			fragments[android.RemoveOptionalPrebuiltPrefix(ctx.OtherModuleName(child))] = info
+		}
+	})
+
+	for _, name := range android.SortedKeys(fragments) {
+		info := fragments[name]
+		if info.Profile == nil {
+			ctx.ModuleErrorf("Boot image config '%s' imports the profile exported by the "+
+				"bootclasspath_fragment %q, but it doesn't provide a profile",
+				image.name,
+				name)
+			return nil, false
+		}
+		profiles = append(profiles, info.Profile)
+	}
+
+	return profiles, true
+}
+
+// buildDirtyImageObjects returns the list of the dirty image objects to compile the boot image
+// variant with, i.e. frameworks/base/config/dirty-image-objects, if it exists, followed by the
+// DirtyImageObjects of the global dexpreopt config. If there is more than one list they are
+// concatenated by a rule of the variant. It returns an invalid path if there is no list.
+func buildDirtyImageObjects(ctx android.ModuleContext, image *bootImageVariant) android.OptionalPath {
+	var files android.Paths
+	if path := android.ExistentPathForSource(ctx, "frameworks/base/config/dirty-image-objects"); path.Valid() {
+		files = append(files, path.Path())
+	}
+	files = append(files, dexpreopt.GetGlobalConfig(ctx).DirtyImageObjects...)
+
+	switch len(files) {
+	case 0:
+		return android.OptionalPath{}
+	case 1:
+		return android.OptionalPathForPath(files[0])
+	}
+
+	output := android.PathForModuleOut(ctx, "dirty_image_objects", image.name, image.target.Arch.ArchType.String(),
+		"dirty-image-objects")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("cat").Inputs(files).Text(">").Output(output)
+	rule.Build("dirty_image_objects_"+image.name+"_"+image.target.String(),
+		"dirty image objects "+image.name+" "+image.target.Arch.ArchType.String())
+	return android.OptionalPathForPath(output)
+}
+
+// verifyBootImage returns true if the boot image variant must be verified, i.e. if it is a device
+// boot image and either the VerifyBootImage property of the global dexpreopt config is set or
+// ART_VERIFY_BOOT_IMAGE=true.
+func verifyBootImage(ctx android.ModuleContext, image *bootImageVariant) bool {
+	if image.target.Os != android.Android {
+		return false
+	}
+	return dexpreopt.GetGlobalConfig(ctx).VerifyBootImage || ctx.Config().IsEnvTrue("ART_VERIFY_BOOT_IMAGE")
+}
+
+// buildBootImageVerification generates the rule that loads the boot image variant, along with the
+// boot images it extends, with oatdump, which fails if the checksums recorded by the image do not
+// match the images it extends or the boot class path, and returns the header dumped by oatdump.
+func buildBootImageVerification(ctx android.ModuleContext, image *bootImageVariant) android.WritablePath {
+	arch := image.target.Arch.ArchType
+	output := android.PathForModuleOut(ctx, "boot_image_verify", image.name, arch.String()+".oatdump.txt")
+	failureMessage := fmt.Sprintf("ERROR: The %s boot image for %s does not match the boot images "+
+		"it extends or the boot class path. ART and the boot jars are probably out of sync.",
+		image.name, arch.String())
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	imageLocationsOnHost, _ := image.imageLocations()
+	rule.Command().
+		BuiltTool("oatdump").
+		FlagWithInputList("--runtime-arg -Xbootclasspath:", image.dexPathsDeps.Paths(), ":").
+		FlagWithList("--runtime-arg -Xbootclasspath-locations:", image.dexLocationsDeps, ":").
+		FlagWithArg("--image=", strings.Join(imageLocationsOnHost, ":")).
+		Implicits(image.imagesDeps.Paths()).
+		Implicits(image.baseImagesDeps).
+		Flag("--header-only").
+		FlagWithOutput("--output=", output).
+		FlagWithArg("--instruction-set=", arch.String()).
+		Textf(`|| ( echo %s ; false )`, proptools.ShellEscape(failureMessage))
+	rule.Build("verify_"+image.name+"_"+image.target.String(), "verify "+image.name+" boot image "+arch.String())
+	return output
+}
+
+// buildBootImageComponents generates the dex2oat rule that compiles the jars of the boot image
+// variant from index first up to, but excluding, index last. The jars before first must be
+// compiled by other rules, they are passed to dex2oat as boot image components that the compiled
+// jars extend. The validation, if not nil, is added to the rule.
+func buildBootImageComponents(ctx android.ModuleContext, image *bootImageVariant, profiles android.Paths,
+	dirtyImageObjects, updatableBcpPackages android.OptionalPath, first, last int, validation android.WritablePath) bootImageVariantOutputs {
 
 	globalSoong := dexpreopt.GetGlobalSoongConfig(ctx)
 	global := dexpreopt.GetGlobalConfig(ctx)
 
 	arch := image.target.Arch.ArchType
-	os := image.target.Os.String() // We need to distinguish host-x86 and device-x86.
-	symbolsDir := image.symbolsDir.Join(ctx, os, image.installDir, arch.String())
-	symbolsFile := symbolsDir.Join(ctx, image.stem+".oat")
-	outputDir := image.dir.Join(ctx, os, image.installDir, arch.String())
+	unstrippedDir := image.unstrippedDir(ctx, image.target, first)
+	symbolsFile := unstrippedDir.Join(ctx, image.stem+".oat")
+	outputDir := image.imageDir(ctx, image.target, first)
 	outputPath := outputDir.Join(ctx, image.stem+".oat")
 	oatLocation := dexpreopt.PathToLocation(outputPath, arch)
 	imagePath := outputPath.ReplaceExtension(ctx, "art")
 
+	// The names of the image files written by the rule, without extension.
+	var names []string
+	for i := first; i < last; i++ {
+		names = append(names, image.moduleName(ctx, i))
+		if image.singleImage {
+			break
+		}
+	}
+
+	invocationPath := outputPath.ReplaceExtension(ctx, "invocation")
+	if image.dex2oatPerJar {
+		invocationPath = outputDir.Join(ctx, names[0]+".invocation")
+	}
+
+	// dex2oat runs in sbox, with its inputs copied into the sandbox, so that it cannot pick up stale
+	// image files or files that are not declared as inputs, and only the declared outputs are kept
+	// in the output directory of the rule. The command runs from the sandbox directory and only
+	// refers to paths relative to it, so the invocation written by dex2oat does not depend on the
+	// location of the sandbox.
 	rule := android.NewRuleBuilder(pctx, ctx)
+	manifest := image.componentDir(ctx, image.target.Os, first).Join(ctx, arch.String()+".sbox.textproto")
+	rule.Sbox(outputDir, manifest).SandboxInputs()
+	if dex2oatUseRbe(ctx) {
+		rewrapBootImageRule(rule, "dex2oat")
+	}
 
-	rule.Command().Text("mkdir").Flag("-p").Flag(symbolsDir.String())
-	rule.Command().Text("rm").Flag("-f").
-		Flag(symbolsDir.Join(ctx, "*.art").String()).
-		Flag(symbolsDir.Join(ctx, "*.oat").String()).
-		Flag(symbolsDir.Join(ctx, "*.invocation").String())
-	rule.Command().Text("rm").Flag("-f").
-		Flag(outputDir.Join(ctx, "*.art").String()).
-		Flag(outputDir.Join(ctx, "*.oat").String()).
-		Flag(outputDir.Join(ctx, "*.invocation").String())
+	// The empty directory passed as the android root must exist in the sandbox.
+	rule.Command().Text("mkdir").Flag("-p").Flag(global.EmptyDirectory)
 
 	cmd := rule.Command()
 
@@ -705,70 +1448,67 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 		cmd.Text(`ANDROID_LOG_TAGS="*:v"`)
 	}
 
-	invocationPath := outputPath.ReplaceExtension(ctx, "invocation")
+	if global.ReproducibleBootImages {
+		cmd.Text("SOURCE_DATE_EPOCH=" + ctx.Config().GetenvWithDefault("SOURCE_DATE_EPOCH", "0"))
+		// The order of the profiles does not matter to dex2oat, but the order of the dex files
+		// defines the boot classpath so it is kept.
+		profiles = android.SortedUniquePaths(android.CopyOfPaths(profiles))
+	}
 
-	cmd.Tool(globalSoong.Dex2oat).
+	addBootImageDex2oat(ctx, cmd, globalSoong.Dex2oat)
+	cmd.
 		Flag("--avoid-storing-invocation").
-		FlagWithOutput("--write-invocation-to=", invocationPath).ImplicitOutput(invocationPath).
-		Flag("--runtime-arg").FlagWithArg("-Xms", global.Dex2oatImageXms).
-		Flag("--runtime-arg").FlagWithArg("-Xmx", global.Dex2oatImageXmx)
+		FlagWithOutput("--write-invocation-to=", invocationPath).
+		Flag("--runtime-arg").FlagWithArg("-Xms", image.dex2oatImageXms(global)).
+		Flag("--runtime-arg").FlagWithArg("-Xmx", image.dex2oatImageXmx(global))
 
-	if profile != nil {
+	for _, profile := range profiles {
 		cmd.FlagWithInput("--profile-file=", profile)
 	}
 
-	fragments := make(map[string]commonBootclasspathFragment)
-	ctx.VisitDirectDepsWithTag(bootclasspathFragmentDepTag, func(child android.Module) {
-		fragment := child.(commonBootclasspathFragment)
-		if fragment.getImageName() != nil && android.IsModulePreferred(child) {
-			fragments[*fragment.getImageName()] = fragment
-		}
-	})
-
-	for _, profileImport := range image.profileImports {
-		fragment := fragments[profileImport.name]
-		if fragment == nil {
-			ctx.ModuleErrorf("Boot image config '%[1]s' imports profile from '%[2]s', but a "+
-				"bootclasspath_fragment with image name '%[2]s' doesn't exist or is not added as a "+
-				"dependency of '%[1]s'",
-				image.name,
-				profileImport.name)
-			return bootImageVariantOutputs{}
-		}
-		if fragment.getProfilePath() == nil {
-			ctx.ModuleErrorf("Boot image config '%[1]s' imports profile from '%[2]s', but '%[2]s' "+
-				"doesn't provide a profile",
-				image.name,
-				profileImport.name)
-			return bootImageVariantOutputs{}
-		}
-		cmd.FlagWithInput("--profile-file=", fragment.getProfilePath())
+	if dirtyImageObjects.Valid() {
+		cmd.FlagWithInput("--dirty-image-objects=", dirtyImageObjects.Path())
 	}
 
-	dirtyImageFile := "frameworks/base/config/dirty-image-objects"
-	dirtyImagePath := android.ExistentPathForSource(ctx, dirtyImageFile)
-	if dirtyImagePath.Valid() {
-		cmd.FlagWithInput("--dirty-image-objects=", dirtyImagePath.Path())
+	if updatableBcpPackages.Valid() {
+		cmd.FlagWithInput("--updatable-bcp-packages-file=", updatableBcpPackages.Path())
 	}
 
-	if image.extends != nil {
-		// It is a boot image extension, so it needs the boot images that it depends on.
-		baseImageLocations := make([]string, 0, len(image.baseImages))
-		for _, image := range image.baseImages {
-			baseImageLocations = append(baseImageLocations, dexpreopt.PathToLocation(image, arch))
+	if image.extends != nil || first > 0 {
+		// It is a boot image extension, or a component of an image compiled jar by jar, so it needs the
+		// boot images that it depends on, followed by the components of this image that are compiled by
+		// other rules.
+		baseImages := append(android.OutputPaths{}, image.baseImages...)
+		baseImagesDeps := append(android.Paths{}, image.baseImagesDeps...)
+		for i := 0; i < first; i++ {
+			name := image.moduleName(ctx, i)
+			componentDir := image.imageDir(ctx, image.target, i)
+			baseImages = append(baseImages, componentDir.Join(ctx, name+".art"))
+			for _, ext := range []string{".art", ".oat", ".vdex"} {
+				baseImagesDeps = append(baseImagesDeps, componentDir.Join(ctx, name+ext))
+			}
+		}
+		// The base images are copied into the sandbox, so they are passed by their paths in the
+		// sandbox.
+		baseImageLocations := make([]string, 0, len(baseImages))
+		for _, image := range baseImages {
+			baseImageLocations = append(baseImageLocations, dexpreopt.PathStringToLocation(cmd.PathForInput(image), arch))
 		}
+		// The boot classpath ends with the compiled jars, the jars of the image that follow them are
+		// not loaded.
+		numDeps := len(image.dexPathsDeps) - image.modules.Len() + last
 		cmd.
-			Flag("--runtime-arg").FlagWithInputList("-Xbootclasspath:", image.dexPathsDeps.Paths(), ":").
-			Flag("--runtime-arg").FlagWithList("-Xbootclasspath-locations:", image.dexLocationsDeps, ":").
+			Flag("--runtime-arg").FlagWithInputList("-Xbootclasspath:", image.dexPathsDeps[:numDeps].Paths(), ":").
+			Flag("--runtime-arg").FlagWithList("-Xbootclasspath-locations:", image.dexLocationsDeps[:numDeps], ":").
 			// Add the path to the first file in the boot image with the arch specific directory removed,
 			// dex2oat will reconstruct the path to the actual file when it needs it. As the actual path
 			// to the file cannot be passed to the command make sure to add the actual path as an Implicit
 			// dependency to ensure that it is built before the command runs.
-			FlagWithList("--boot-image=", baseImageLocations, ":").Implicits(image.baseImages.Paths()).
+			FlagWithList("--boot-image=", baseImageLocations, ":").Implicits(baseImages.Paths()).
 			// Similarly, the dex2oat tool will automatically find the paths to other files in the base
 			// boot image so make sure to add them as implicit dependencies to ensure that they are built
 			// before this command is run.
-			Implicits(image.baseImagesDeps)
+			Implicits(baseImagesDeps)
 	} else {
 		// It is a primary image, so it needs a base address.
 		cmd.FlagWithArg("--base=", ctx.Config().LibartImgDeviceBaseAddress())
@@ -784,16 +1524,16 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 	}
 
 	cmd.
-		FlagForEachInput("--dex-file=", image.dexPaths.Paths()).
-		FlagForEachArg("--dex-location=", image.dexLocations).
+		FlagForEachInput("--dex-file=", image.dexPaths[first:last].Paths()).
+		FlagForEachArg("--dex-location=", image.dexLocations[first:last]).
 		Flag("--generate-debug-info").
 		Flag("--generate-build-id").
 		Flag("--image-format=lz4hc").
-		FlagWithArg("--oat-symbols=", symbolsFile.String()).
+		FlagWithArg("--oat-symbols=", cmd.PathForOutput(symbolsFile)).
 		Flag("--strip").
-		FlagWithArg("--oat-file=", outputPath.String()).
+		FlagWithArg("--oat-file=", cmd.PathForOutput(outputPath)).
 		FlagWithArg("--oat-location=", oatLocation).
-		FlagWithArg("--image=", imagePath.String()).
+		FlagWithArg("--image=", cmd.PathForOutput(imagePath)).
 		FlagWithArg("--instruction-set=", arch.String()).
 		FlagWithArg("--android-root=", global.EmptyDirectory).
 		FlagWithArg("--no-inline-from=", "core-oj.jar").
@@ -801,23 +1541,34 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 		Flag("--abort-on-hard-verifier-error")
 
 	// If the image is profile-guided but the profile is disabled, we omit "--compiler-filter" to
-	// leave the decision to dex2oat to pick the compiler filter.
-	if !(image.isProfileGuided() && global.DisableGenerateProfile) {
-		cmd.FlagWithArg("--compiler-filter=", image.compilerFilter)
+	// leave the decision to dex2oat to pick the compiler filter, unless the product overrides the
+	// compiler filter for the architecture.
+	// The compiler filter of the dex2oat_config module of the image applies unless the product
+	// overrides the compiler filter for the architecture.
+	dex2oatConfig := bootImageDex2oatConfig(ctx, image.bootImageConfig)
+	compilerFilter := image.compilerFilter
+	_, productFilter := ctx.Config().BootImageCompilerFilter(image.name, arch)
+	if dex2oatConfig.CompilerFilter != "" && !(productFilter && image.target.Os == android.Android) {
+		compilerFilter = dex2oatConfig.CompilerFilter
+	}
+	overridden := compilerFilter != image.bootImageConfig.compilerFilter
+	if !(image.isProfileGuided() && global.DisableGenerateProfile) || overridden {
+		cmd.FlagWithArg("--compiler-filter=", compilerFilter)
 	}
 
-	if image.singleImage {
+	if image.singleImage || image.dex2oatPerJar {
 		cmd.Flag("--single-image")
 	}
 
 	// Use the default variant/features for host builds.
 	// The map below contains only device CPU info (which might be x86 on some devices).
 	if image.target.Os == android.Android {
-		cmd.FlagWithArg("--instruction-set-variant=", global.CpuVariant[arch])
-		cmd.FlagWithArg("--instruction-set-features=", global.InstructionSetFeatures[arch])
+		variant, features := bootImageCpuFlags(ctx, image)
+		cmd.FlagWithArg("--instruction-set-variant=", variant)
+		cmd.FlagWithArg("--instruction-set-features=", features)
 	}
 
-	if global.EnableUffdGc {
+	if image.uffdGcEnabled(global) {
 		cmd.Flag("--runtime-arg").Flag("-Xgc:CMC")
 	}
 
@@ -825,25 +1576,49 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 		cmd.Flag(global.BootFlags)
 	}
 
+	cmd.Flags(dex2oatConfig.Flags)
+
 	if extraFlags != "" {
 		cmd.Flag(extraFlags)
 	}
 
+	if global.ReproducibleBootImages {
+		// The last flag wins, so make sure that the boot flags do not disable the build ids, which
+		// dex2oat generates from the contents of the oat files, or the deterministic compilation.
+		cmd.Flag("--generate-build-id").Flag("--force-determinism")
+	}
+
 	cmd.Textf(`|| ( echo %s ; false )`, proptools.ShellEscape(failureMessage))
 
+	if validation != nil {
+		cmd.Validation(validation)
+	}
+
+	if check := dexpreopt.ToolsManifestCheck(ctx, global); check.Valid() {
+		cmd.Validation(check.Path())
+	}
+
+	cmd.Validation(bootJarsConfigCheckTimestamp(ctx))
+
 	installDir := filepath.Dir(image.imagePathOnDevice)
 
 	var vdexInstalls android.RuleBuilderInstalls
 	var unstrippedInstalls android.RuleBuilderInstalls
 
-	for _, artOrOat := range image.moduleFiles(ctx, outputDir, ".art", ".oat") {
-		cmd.ImplicitOutput(artOrOat)
+	cmd.ImplicitOutput(invocationPath)
 
-		// Install the .oat and .art files
-		rule.Install(artOrOat, filepath.Join(installDir, artOrOat.Base()))
+	for _, name := range names {
+		for _, ext := range []string{".art", ".oat"} {
+			artOrOat := outputDir.Join(ctx, name+ext)
+			cmd.ImplicitOutput(artOrOat)
+
+			// Install the .oat and .art files
+			rule.Install(artOrOat, filepath.Join(installDir, artOrOat.Base()))
+		}
 	}
 
-	for _, vdex := range image.moduleFiles(ctx, outputDir, ".vdex") {
+	for _, name := range names {
+		vdex := outputDir.Join(ctx, name+".vdex")
 		cmd.ImplicitOutput(vdex)
 
 		// Note that the vdex files are identical between architectures.
@@ -852,7 +1627,8 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 			android.RuleBuilderInstall{vdex, filepath.Join(installDir, vdex.Base())})
 	}
 
-	for _, unstrippedOat := range image.moduleFiles(ctx, symbolsDir, ".oat") {
+	for _, name := range names {
+		unstrippedOat := unstrippedDir.Join(ctx, name+".oat")
 		cmd.ImplicitOutput(unstrippedOat)
 
 		// Install the unstripped oat files.  The Make rules will put these in $(TARGET_OUT_UNSTRIPPED)
@@ -860,38 +1636,58 @@ func buildBootImageVariant(ctx android.ModuleContext, image *bootImageVariant, p
 			android.RuleBuilderInstall{unstrippedOat, filepath.Join(installDir, unstrippedOat.Base())})
 	}
 
-	rule.Build(image.name+"JarsDexpreopt_"+image.target.String(), "dexpreopt "+image.name+" jars "+arch.String())
-
-	// save output and installed files for makevars
-	// TODO - these are always the same and so should be initialized in genBootImageConfigs
-	image.installs = rule.Installs()
-	image.vdexInstalls = vdexInstalls
-	image.unstrippedInstalls = unstrippedInstalls
-
-	// Only set the licenseMetadataFile from the active module.
-	if isActiveModule(ctx.Module()) {
-		image.licenseMetadataFile = android.OptionalPathForPath(ctx.LicenseMetadataFile())
+	if image.dex2oatPerJar {
+		jar := image.modules.Jar(first)
+		rule.Build(image.name+"JarsDexpreopt_"+image.target.String()+"_"+jar,
+			"dexpreopt "+image.name+" jar "+jar+" "+arch.String())
+	} else {
+		rule.Build(image.name+"JarsDexpreopt_"+image.target.String(), "dexpreopt "+image.name+" jars "+arch.String())
 	}
 
 	return bootImageVariantOutputs{
-		image,
+		installs:           rule.Installs(),
+		vdexInstalls:       vdexInstalls,
+		unstrippedInstalls: unstrippedInstalls,
+	}
+}
+
+// addBootImageDex2oat adds dex2oat to the command of a boot image rule, along with the shared
+// libraries that it is installed with, so that it can run from the sandbox of the rule.
+func addBootImageDex2oat(ctx android.ModuleContext, cmd *android.RuleBuilderCommand, dex2oat android.Path) {
+	var specs []android.PackagingSpec
+	ctx.VisitDirectDepsWithTag(dexpreopt.Dex2oatDepTag, func(dep android.Module) {
+		specs = append(specs, dep.TransitivePackagingSpecs()...)
+	})
+	for _, spec := range specs {
+		if spec.FileName() == dex2oat.Base() {
+			cmd.Text(cmd.PathForPackagedTool(spec)).ImplicitPackagedTools(specs)
+			return
+		}
 	}
+	// dex2oat is not built by a module of the tree, e.g. it is a prebuilt tool.
+	cmd.Tool(dex2oat)
 }
 
 const failureMessage = `ERROR: Dex2oat failed to compile a boot image.
 It is likely that the boot classpath is inconsistent.
 Rebuild with ART_BOOT_IMAGE_EXTRA_ARGS="--runtime-arg -verbose:verifier" to see verification errors.`
 
-func bootImageProfileRule(ctx android.ModuleContext, image *bootImageConfig) android.WritablePath {
+const prebuiltBootImageFailureMessage = `ERROR: The prebuilt boot image does not match the boot classpath.
+It must be compiled from the same dex files, at the same locations, as the ones provided by the
+contents of the prebuilt_bootclasspath_fragment.`
+
+// bootImageProfileRule generates the rule to create the boot image profile and returns the path to
+// the generated file, along with the installs of the profile if it is installed on the device.
+func bootImageProfileRule(ctx android.ModuleContext, image *bootImageConfig) (android.WritablePath, android.RuleBuilderInstalls) {
 	if !image.isProfileGuided() {
-		return nil
+		return nil, nil
 	}
 
 	globalSoong := dexpreopt.GetGlobalSoongConfig(ctx)
 	global := dexpreopt.GetGlobalConfig(ctx)
 
 	if global.DisableGenerateProfile {
-		return nil
+		return nil, nil
 	}
 
 	defaultProfile := "frameworks/base/config/boot-image-profile.txt"
@@ -904,48 +1700,184 @@ func bootImageProfileRule(ctx android.ModuleContext, image *bootImageConfig) and
 		profiles = append(profiles, global.BootImageProfiles...)
 	} else if path := android.ExistentPathForSource(ctx, defaultProfile); path.Valid() {
 		profiles = append(profiles, path.Path())
-	} else {
+	}
+	if len(profiles) > 0 {
+		if path := android.ExistentPathForSource(ctx, extraProfile); path.Valid() {
+			profiles = append(profiles, path.Path())
+		}
+	}
+	sampledProfiles := global.BootImageSampledProfiles
+
+	// The profiles of the boot_image_profile modules are merged into the framework boot image
+	// profile, along with the dex files that they reference.
+	apks := image.dexPathsDeps.Paths()
+	dexLocations := image.getAnyAndroidVariant().dexLocationsDeps
+	if image == defaultBootImageConfig(ctx) {
+		extra := gatherBootImageProfiles(ctx)
+		profiles = append(profiles, extra.TextProfiles...)
+		sampledProfiles = append(append(android.Paths(nil), sampledProfiles...), extra.BinaryProfiles...)
+		apks = append(apks, extra.Apks...)
+		dexLocations = append(append([]string(nil), dexLocations...), extra.DexLocations...)
+	}
+	if global.ReproducibleBootImages {
+		// The order of the text and the binary profiles does not matter to profman, but the order of
+		// the apks has to match the order of the dex locations so it is kept.
+		profiles = android.SortedUniquePaths(android.CopyOfPaths(profiles))
+		sampledProfiles = android.SortedUniquePaths(android.CopyOfPaths(sampledProfiles))
+	}
+	if len(profiles) == 0 && len(sampledProfiles) == 0 {
 		// No profile (not even a default one, which is the case on some branches
 		// like master-art-host that don't have frameworks/base).
 		// Return nil and continue without profile.
-		return nil
-	}
-	if path := android.ExistentPathForSource(ctx, extraProfile); path.Valid() {
-		profiles = append(profiles, path.Path())
+		return nil, nil
 	}
-	bootImageProfile := image.dir.Join(ctx, "boot-image-profile.txt")
-	rule.Command().Text("cat").Inputs(profiles).Text(">").Output(bootImageProfile)
 
 	profile := image.dir.Join(ctx, "boot.prof")
 
-	rule.Command().
-		Text(`ANDROID_LOG_TAGS="*:e"`).
-		Tool(globalSoong.Profman).
-		Flag("--output-profile-type=boot").
-		FlagWithInput("--create-profile-from=", bootImageProfile).
-		FlagForEachInput("--apk=", image.dexPathsDeps.Paths()).
-		FlagForEachArg("--dex-location=", image.getAnyAndroidVariant().dexLocationsDeps).
-		FlagWithOutput("--reference-profile-file=", profile)
+	// The profiles that are merged into the boot image profile in their binary format, i.e. the
+	// profiles sampled on devices and, if there are any, the text profiles converted by profman.
+	binaryProfiles := append(android.Paths(nil), sampledProfiles...)
+
+	// The profman commands share the list of the inputs to upload when they run with RBE.
+	var rbeInputsList android.WritablePath
+	var rbeCmd *android.RuleBuilderCommand
+	var rbeExtraInputs android.Paths
+	if dex2oatUseRbe(ctx) {
+		rbeInputsList = image.dir.Join(ctx, "boot.prof.rbe_inputs.list")
+	}
+
+	if len(profiles) > 0 {
+		bootImageProfile := image.dir.Join(ctx, "boot-image-profile.txt")
+		rule.Command().Text("cat").Inputs(profiles).Text(">").Output(bootImageProfile)
+
+		textProfile := profile
+		if len(sampledProfiles) > 0 {
+			textProfile = image.dir.Join(ctx, "boot-text.prof")
+			binaryProfiles = append(android.Paths{textProfile}, binaryProfiles...)
+		}
+
+		cmd := rule.Command().Text(`ANDROID_LOG_TAGS="*:e"`)
+		if rbeInputsList != nil {
+			rewrapBootImageCommand(ctx, rule, cmd, "profman", globalSoong.Profman, rbeInputsList,
+				android.WritablePaths{textProfile})
+			rbeCmd = cmd
+			rbeExtraInputs = append(rbeExtraInputs, bootImageProfile)
+		}
+		cmd.
+			Tool(globalSoong.Profman).
+			Flag("--output-profile-type=boot").
+			FlagWithInput("--create-profile-from=", bootImageProfile).
+			FlagForEachInput("--apk=", apks).
+			FlagForEachArg("--dex-location=", dexLocations).
+			FlagWithOutput("--reference-profile-file=", textProfile)
+	}
+
+	if len(sampledProfiles) > 0 {
+		// profman merges the profiles into the reference profile if it exists, so start from an
+		// empty one.
+		rule.Command().Text("rm -f").Text(profile.String())
+		cmd := rule.Command().Text(`ANDROID_LOG_TAGS="*:e"`)
+		if rbeInputsList != nil {
+			rewrapBootImageCommand(ctx, rule, cmd, "profman", globalSoong.Profman, rbeInputsList,
+				android.WritablePaths{profile})
+			rbeCmd = cmd
+			if len(profiles) > 0 {
+				rbeExtraInputs = append(rbeExtraInputs, binaryProfiles[0])
+			}
+		}
+		cmd.
+			Tool(globalSoong.Profman).
+			Flag("--output-profile-type=boot").
+			Flag("--boot-image-merge").
+			Flag("--force-merge").
+			FlagForEachInput("--profile-file=", binaryProfiles).
+			FlagForEachInput("--apk=", apks).
+			FlagForEachArg("--dex-location=", dexLocations).
+			FlagWithOutput("--reference-profile-file=", profile)
+	}
 
 	if image == defaultBootImageConfig(ctx) {
 		rule.Install(profile, "/system/etc/boot-image.prof")
-		image.profileInstalls = append(image.profileInstalls, rule.Installs()...)
-		image.profileLicenseMetadataFile = android.OptionalPathForPath(ctx.LicenseMetadataFile())
+
+		if len(profiles) > 0 {
+			bootImageProfileFreshnessRule(ctx, image)
+		}
+	}
+
+	if rbeInputsList != nil {
+		writeBootImageRemoteInputs(ctx, rule, rbeCmd, rbeInputsList, rbeExtraInputs...)
+	}
+
+	rule.Build(image.name+"JarsProfile", "profile "+image.name+" jars")
+
+	return profile, rule.Installs()
+}
+
+// bootImageProfileFreshnessDexdumpTag is the dependency tag of the dexdump tool that is used by the
+// boot image profile freshness check.
+var bootImageProfileFreshnessDexdumpTag = dependencyTag{name: "dexdump", toolchain: true}
+
+// addBootImageProfileFreshnessDeps adds the dependency on the dexdump tool that is used by
+// bootImageProfileFreshnessRule, if the check is enabled.
+func addBootImageProfileFreshnessDeps(ctx android.BottomUpMutatorContext) {
+	if dexpreopt.GetGlobalConfig(ctx).BootImageProfileFingerprint == nil {
+		return
+	}
+	ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(),
+		bootImageProfileFreshnessDexdumpTag, "dexdump")
+}
+
+// bootImageProfileFreshnessRule generates the rule that compares the API fingerprint of the boot
+// jars with the fingerprint recorded when the checked-in boot image profiles were last refreshed,
+// and warns when the API has drifted too far from it. The up-to-date fingerprint is written to
+// boot-image-profile.fingerprint, to be checked in along with the refreshed profiles.
+func bootImageProfileFreshnessRule(ctx android.ModuleContext, image *bootImageConfig) {
+	global := dexpreopt.GetGlobalConfig(ctx)
+	if global.BootImageProfileFingerprint == nil {
+		return
+	}
+
+	maxApiDrift := global.BootImageProfileMaxApiDrift
+	if maxApiDrift == 0 {
+		maxApiDrift = 10
 	}
 
-	rule.Build("bootJarsProfile", "profile boot jars")
+	var dexdump android.Path
+	ctx.VisitDirectDepsWithTag(bootImageProfileFreshnessDexdumpTag, func(dep android.Module) {
+		if hostTool, ok := dep.(android.HostToolProvider); ok && hostTool.HostToolPath().Valid() {
+			dexdump = hostTool.HostToolPath().Path()
+		} else {
+			ctx.ModuleErrorf("module %q is not a host tool provider", ctx.OtherModuleName(dep))
+		}
+	})
+	if dexdump == nil {
+		return
+	}
 
-	return profile
+	fingerprint := image.dir.Join(ctx, "boot-image-profile.fingerprint")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("boot_image_profile_freshness").
+		FlagWithInput("--dexdump ", dexdump).
+		FlagWithInput("--recorded-fingerprint ", global.BootImageProfileFingerprint).
+		FlagWithArg("--max-api-drift ", strconv.Itoa(maxApiDrift)).
+		FlagWithOutput("--output-fingerprint ", fingerprint).
+		Inputs(image.dexPathsDeps.Paths())
+	rule.Build("bootImageProfileFreshness", "check boot image profile freshness")
+
+	// The check runs as part of droidcore, and on its own with the
+	// check-boot-image-profile-freshness phony target.
+	ctx.Phony("check-boot-image-profile-freshness", fingerprint)
+	ctx.Phony("droidcore", android.PathForPhony(ctx, "check-boot-image-profile-freshness"))
 }
 
 // bootFrameworkProfileRule generates the rule to create the boot framework profile and
-// returns a path to the generated file.
-func bootFrameworkProfileRule(ctx android.ModuleContext, image *bootImageConfig) android.WritablePath {
+// returns a path to the generated file, along with the installs of the profile.
+func bootFrameworkProfileRule(ctx android.ModuleContext, image *bootImageConfig) (android.WritablePath, android.RuleBuilderInstalls) {
 	globalSoong := dexpreopt.GetGlobalSoongConfig(ctx)
 	global := dexpreopt.GetGlobalConfig(ctx)
 
 	if global.DisableGenerateProfile || ctx.Config().UnbundledBuild() {
-		return nil
+		return nil, nil
 	}
 
 	defaultProfile := "frameworks/base/config/boot-profile.txt"
@@ -965,14 +1897,13 @@ func bootFrameworkProfileRule(ctx android.ModuleContext, image *bootImageConfig)
 
 	rule.Install(profile, "/system/etc/boot-image.bprof")
 	rule.Build("bootFrameworkProfile", "profile boot framework jars")
-	image.profileInstalls = append(image.profileInstalls, rule.Installs()...)
-	image.profileLicenseMetadataFile = android.OptionalPathForPath(ctx.LicenseMetadataFile())
 
-	return profile
+	return profile, rule.Installs()
 }
 
 func dumpOatRules(ctx android.ModuleContext, image *bootImageConfig) {
-	var allPhonies android.Paths
+	reference := dexpreopt.GetGlobalConfig(ctx).ReferenceBootImage
+	var allPhonies, allDiffPhonies android.Paths
 	for _, image := range image.variants {
 		arch := image.target.Arch.ArchType
 		suffix := arch.String()
@@ -1003,6 +1934,11 @@ func dumpOatRules(ctx android.ModuleContext, image *bootImageConfig) {
 		rule.Build("phony-dump-oat-boot-"+suffix, "dump oat boot "+arch.String())
 
 		allPhonies = append(allPhonies, phony)
+
+		// The reference boot image is a device boot image.
+		if reference != nil && image.target.Os == android.Android {
+			allDiffPhonies = append(allDiffPhonies, diffBootImageRules(ctx, image, reference, output, suffix))
+		}
 	}
 
 	phony := android.PathForPhony(ctx, "dump-oat-boot")
@@ -1012,6 +1948,75 @@ func dumpOatRules(ctx android.ModuleContext, image *bootImageConfig) {
 		Inputs:      allPhonies,
 		Description: "dump-oat-boot",
 	})
+
+	if len(allDiffPhonies) > 0 {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:        android.Phony,
+			Output:      android.PathForPhony(ctx, "diff-boot-image"),
+			Inputs:      allDiffPhonies,
+			Description: "diff-boot-image",
+		})
+	}
+}
+
+// diffBootImageRules generates the rules of the diff-boot-image-<suffix> target, which diffs the
+// compiled methods and the class statuses of the boot image variant, dumped by oatdump to
+// currentDump, with those of the reference boot image, and returns the phony path of the target.
+func diffBootImageRules(ctx android.ModuleContext, image *bootImageVariant, reference android.Path,
+	currentDump android.Path, suffix string) android.WritablePath {
+	arch := image.target.Arch.ArchType
+
+	// The reference boot image, along with the boot images it extends, is extracted with the layout
+	// of the boot image zip files, so its locations are <os>/<install dir>/<file> under the reference
+	// directory.
+	referenceDir := android.PathForOutput(ctx, "diff_boot_image", suffix)
+	imageLocationsOnHost, _ := image.imageLocations()
+	var referenceLocations []string
+	for _, location := range imageLocationsOnHost {
+		for c := image.bootImageConfig; c != nil; c = c.extends {
+			if strings.HasPrefix(location, c.dir.String()+"/") {
+				location = filepath.Join(referenceDir.String(), image.target.Os.String(), c.installDir,
+					filepath.Base(location))
+				break
+			}
+		}
+		referenceLocations = append(referenceLocations, location)
+	}
+
+	referenceDump := android.PathForOutput(ctx, "boot."+suffix+".reference.oatdump.txt")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("rm").Flag("-rf").Flag(referenceDir.String())
+	rule.Command().
+		Text("unzip").Flag("-qoDD").
+		FlagWithArg("-d ", referenceDir.Join(ctx, image.target.Os.String()).String()).
+		Input(reference).
+		Text(proptools.ShellEscape("*/" + arch.String() + "/*"))
+	rule.Command().
+		BuiltTool("oatdump").
+		FlagWithInputList("--runtime-arg -Xbootclasspath:", image.dexPathsDeps.Paths(), ":").
+		FlagWithList("--runtime-arg -Xbootclasspath-locations:", image.dexLocationsDeps, ":").
+		FlagWithArg("--image=", strings.Join(referenceLocations, ":")).
+		FlagWithOutput("--output=", referenceDump).
+		FlagWithArg("--instruction-set=", arch.String())
+	rule.Build("dump-oat-reference-boot-"+suffix, "dump oat reference boot "+arch.String())
+
+	diff := android.PathForOutput(ctx, "boot."+suffix+".diff.json")
+	rule = android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("diff_boot_image").
+		FlagWithInput("--reference ", referenceDump).
+		FlagWithInput("--current ", currentDump).
+		FlagWithOutput("--output ", diff)
+	rule.Build("diff-boot-image-"+suffix, "diff boot image "+arch.String())
+
+	phony := android.PathForPhony(ctx, "diff-boot-image-"+suffix)
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        android.Phony,
+		Output:      phony,
+		Input:       diff,
+		Description: "diff-boot-image-" + suffix,
+	})
+	return phony
 }
 
 func writeGlobalConfigForMake(ctx android.SingletonContext, path android.WritablePath) {
@@ -1020,58 +2025,98 @@ func writeGlobalConfigForMake(ctx android.SingletonContext, path android.Writabl
 	android.WriteFileRule(ctx, path, string(data))
 }
 
-// Define Make variables for boot image names, paths, etc. These variables are used in makefiles
-// (make/core/dex_preopt_libart.mk) to generate install rules that copy boot image files to the
-// correct output directories.
+// Define the Make variables for the dexpreopt config files. They are written to make_vars-*.mk as
+// they are needed before Android-*.mk is included.
 func (d *dexpreoptBootJars) MakeVars(ctx android.MakeVarsContext) {
 	if d.dexpreoptConfigForMake != nil && !SkipDexpreoptBootJars(ctx) {
 		ctx.Strict("DEX_PREOPT_CONFIG_FOR_MAKE", d.dexpreoptConfigForMake.String())
 		ctx.Strict("DEX_PREOPT_SOONG_CONFIG_FOR_MAKE", android.PathForOutput(ctx, "dexpreopt_soong.config").String())
 	}
+}
 
+// generateBootImageMakeVars returns the Make variables for boot image names, paths, etc. These
+// variables are used in makefiles (make/core/dex_preopt_libart.mk) to generate install rules that
+// copy boot image files to the correct output directories.
+func (d *dexpreoptBootJars) generateBootImageMakeVars(ctx android.ModuleContext) map[string]string {
+	vars := make(map[string]string)
 	image := d.defaultBootImage
-	if image != nil {
-		ctx.Strict("DEXPREOPT_IMAGE_PROFILE_BUILT_INSTALLED", image.profileInstalls.String())
-		if image.profileLicenseMetadataFile.Valid() {
-			ctx.Strict("DEXPREOPT_IMAGE_PROFILE_LICENSE_METADATA", image.profileLicenseMetadataFile.String())
-		}
+	if image == nil {
+		return vars
+	}
 
-		if SkipDexpreoptBootJars(ctx) {
-			return
-		}
+	var profileInstalls android.RuleBuilderInstalls
+	var profileLicenseMetadataFile android.OptionalPath
+	if installInfo := d.bootImageInstalls[image.name]; installInfo != nil {
+		profileInstalls = installInfo.profileInstalls
+		profileLicenseMetadataFile = installInfo.profileLicenseMetadataFile
+	}
+	vars["DEXPREOPT_IMAGE_PROFILE_BUILT_INSTALLED"] = profileInstalls.String()
+	if profileLicenseMetadataFile.Valid() {
+		vars["DEXPREOPT_IMAGE_PROFILE_LICENSE_METADATA"] = profileLicenseMetadataFile.String()
+	}
+
+	if SkipDexpreoptBootJars(ctx) {
+		return vars
+	}
 
-		global := dexpreopt.GetGlobalConfig(ctx)
-		dexPaths, dexLocations := bcpForDexpreopt(ctx, global.PreoptWithUpdatableBcp)
-		ctx.Strict("DEXPREOPT_BOOTCLASSPATH_DEX_FILES", strings.Join(dexPaths.Strings(), " "))
-		ctx.Strict("DEXPREOPT_BOOTCLASSPATH_DEX_LOCATIONS", strings.Join(dexLocations, " "))
-
-		var imageNames []string
-		// The primary ART boot image is exposed to Make for testing (gtests) and benchmarking
-		// (golem) purposes.
-		for _, current := range append(d.otherImages, image) {
-			imageNames = append(imageNames, current.name)
-			for _, variant := range current.variants {
-				suffix := ""
-				if variant.target.Os.Class == android.Host {
-					suffix = "_host"
-				}
-				sfx := variant.name + suffix + "_" + variant.target.Arch.ArchType.String()
-				ctx.Strict("DEXPREOPT_IMAGE_VDEX_BUILT_INSTALLED_"+sfx, variant.vdexInstalls.String())
-				ctx.Strict("DEXPREOPT_IMAGE_"+sfx, variant.imagePathOnHost.String())
-				ctx.Strict("DEXPREOPT_IMAGE_DEPS_"+sfx, strings.Join(variant.imagesDeps.Strings(), " "))
-				ctx.Strict("DEXPREOPT_IMAGE_BUILT_INSTALLED_"+sfx, variant.installs.String())
-				ctx.Strict("DEXPREOPT_IMAGE_UNSTRIPPED_BUILT_INSTALLED_"+sfx, variant.unstrippedInstalls.String())
-				if variant.licenseMetadataFile.Valid() {
-					ctx.Strict("DEXPREOPT_IMAGE_LICENSE_METADATA_"+sfx, variant.licenseMetadataFile.String())
-				}
+	global := dexpreopt.GetGlobalConfig(ctx)
+	dexPaths, dexLocations := bcpForDexpreopt(ctx, global.PreoptWithUpdatableBcp)
+	vars["DEXPREOPT_BOOTCLASSPATH_DEX_FILES"] = strings.Join(dexPaths.Strings(), " ")
+	vars["DEXPREOPT_BOOTCLASSPATH_DEX_LOCATIONS"] = strings.Join(dexLocations, " ")
+
+	var imageNames []string
+	// The primary ART boot image is exposed to Make for testing (gtests) and benchmarking
+	// (golem) purposes.
+	for _, current := range append(d.otherImages, image) {
+		imageNames = append(imageNames, current.name)
+		installInfo := d.bootImageInstalls[current.name]
+		for _, variant := range current.variants {
+			suffix := ""
+			if variant.target.Os.Class == android.Host {
+				suffix = "_host"
+			}
+			sfx := variant.name + suffix + "_" + variant.target.Arch.ArchType.String()
+			variantInstallInfo := installInfo.variant(variant.target)
+			vars["DEXPREOPT_IMAGE_VDEX_BUILT_INSTALLED_"+sfx] = variantInstallInfo.vdexInstalls.String()
+			vars["DEXPREOPT_IMAGE_"+sfx] = variant.imagePathOnHost.String()
+			vars["DEXPREOPT_IMAGE_DEPS_"+sfx] = strings.Join(variant.imagesDeps.Strings(), " ")
+			vars["DEXPREOPT_IMAGE_BUILT_INSTALLED_"+sfx] = variantInstallInfo.installs.String()
+			vars["DEXPREOPT_IMAGE_UNSTRIPPED_BUILT_INSTALLED_"+sfx] = variantInstallInfo.unstrippedInstalls.String()
+			if variantInstallInfo.licenseMetadataFile.Valid() {
+				vars["DEXPREOPT_IMAGE_LICENSE_METADATA_"+sfx] = variantInstallInfo.licenseMetadataFile.String()
 			}
-			imageLocationsOnHost, imageLocationsOnDevice := current.getAnyAndroidVariant().imageLocations()
-			ctx.Strict("DEXPREOPT_IMAGE_LOCATIONS_ON_HOST"+current.name, strings.Join(imageLocationsOnHost, ":"))
-			ctx.Strict("DEXPREOPT_IMAGE_LOCATIONS_ON_DEVICE"+current.name, strings.Join(imageLocationsOnDevice, ":"))
-			ctx.Strict("DEXPREOPT_IMAGE_ZIP_"+current.name, current.zip.String())
 		}
-		// Ensure determinism.
-		sort.Strings(imageNames)
-		ctx.Strict("DEXPREOPT_IMAGE_NAMES", strings.Join(imageNames, " "))
+		imageLocationsOnHost, imageLocationsOnDevice := current.getAnyAndroidVariant().imageLocations()
+		vars["DEXPREOPT_IMAGE_LOCATIONS_ON_HOST"+current.name] = strings.Join(imageLocationsOnHost, ":")
+		vars["DEXPREOPT_IMAGE_LOCATIONS_ON_DEVICE"+current.name] = strings.Join(imageLocationsOnDevice, ":")
+		vars["DEXPREOPT_IMAGE_ZIP_"+current.name] = current.zip.String()
+		if installInfo != nil && installInfo.deltaMetadata != nil {
+			vars["DEXPREOPT_IMAGE_DELTA_"+current.name] = installInfo.deltaMetadata.String()
+		}
 	}
+	// Ensure determinism.
+	sort.Strings(imageNames)
+	vars["DEXPREOPT_IMAGE_NAMES"] = strings.Join(imageNames, " ")
+	return vars
 }
+
+// AndroidMk defines the Make variables for the boot images in the Android-*.mk entry of the
+// module, see generateBootImageMakeVars. The variables are read-only, so that the makefiles that
+// are included later cannot redefine them.
+func (d *dexpreoptBootJars) AndroidMk() android.AndroidMkData {
+	return android.AndroidMkData{
+		Custom: func(w io.Writer, name, prefix, moduleDir string, data android.AndroidMkData) {
+			if len(d.bootImageMakeVars) == 0 {
+				return
+			}
+			names := android.SortedKeys(d.bootImageMakeVars)
+			fmt.Fprintln(w)
+			for _, v := range names {
+				fmt.Fprintln(w, v, ":=", d.bootImageMakeVars[v])
+			}
+			fmt.Fprintln(w, ".KATI_READONLY :=", strings.Join(names, " "))
+		},
+	}
+}
+
+var _ android.AndroidMkDataProvider = (*dexpreoptBootJars)(nil)
@@ -0,0 +1,204 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+)
+
+// A boot jar that is listed in PRODUCT_BOOT_JARS, ART_APEX_JARS or PRODUCT_APEX_BOOT_JARS but
+// whose dex jar is not provided by any module cannot be diagnosed by the module that copies the
+// boot jars to their predefined locations, as it only sees its own dependencies. The copy is
+// replaced by an error rule and the jar is recorded instead, and the dex_bootjars singleton
+// reports the reason once all the modules named after the jar are known, e.g. that the jar is not
+// in the apex that the list expects, or that its prebuilt is not preferred.
+
+type missingBootDexJarsMap struct {
+	sync.Mutex
+
+	// The modules that needed the dex jars, keyed by the names of the jars.
+	jars map[string][]string
+}
+
+var missingBootDexJarsKey = android.NewOnceKey("missingBootDexJars")
+
+func getMissingBootDexJars(config android.Config) *missingBootDexJarsMap {
+	return config.Once(missingBootDexJarsKey, func() interface{} {
+		return &missingBootDexJarsMap{jars: make(map[string][]string)}
+	}).(*missingBootDexJarsMap)
+}
+
+// registerMissingBootDexJar records that the module needed the dex jar of the given boot jar but
+// that no module provided it.
+func registerMissingBootDexJar(config android.Config, module, jar string) {
+	m := getMissingBootDexJars(config)
+	m.Lock()
+	defer m.Unlock()
+	if !android.InList(module, m.jars[jar]) {
+		m.jars[jar] = append(m.jars[jar], module)
+	}
+}
+
+// bootJarModuleVariant is a variant of a source or prebuilt module named after a boot jar.
+type bootJarModuleVariant struct {
+	name     string
+	active   bool
+	prebuilt bool
+	apexInfo android.ApexInfo
+
+	// False if the module is a library that is neither installable nor compiled to dex.
+	compilesDex bool
+}
+
+// checkMissingBootDexJars reports the boot jars whose dex jars were needed but not provided by any
+// module, with the reason why they were not.
+func (d *dexpreoptBootJars) checkMissingBootDexJars(ctx android.SingletonContext) {
+	m := getMissingBootDexJars(ctx.Config())
+	m.Lock()
+	defer m.Unlock()
+	if len(m.jars) == 0 {
+		return
+	}
+
+	variants := make(map[string][]bootJarModuleVariant)
+	fragmentContents := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		switch f := module.(type) {
+		case *BootclasspathFragmentModule:
+			addActiveFragmentContents(ctx, fragmentContents, module, f.properties.Contents)
+		case *PrebuiltBootclasspathFragmentModule:
+			addActiveFragmentContents(ctx, fragmentContents, module, f.properties.Contents)
+		}
+
+		name := ctx.ModuleName(module)
+		jar := android.RemoveOptionalPrebuiltPrefix(name)
+		if _, ok := m.jars[jar]; !ok {
+			return
+		}
+		variant := bootJarModuleVariant{
+			name:        name,
+			active:      isActiveModule(module),
+			prebuilt:    android.IsModulePrebuilt(module),
+			apexInfo:    ctx.ModuleProvider(module, android.ApexInfoProvider).(android.ApexInfo),
+			compilesDex: true,
+		}
+		if lib, ok := module.(*Library); ok {
+			variant.compilesDex = Bool(lib.properties.Installable) || Bool(lib.dexProperties.Compile_dex)
+		}
+		variants[jar] = append(variants[jar], variant)
+	})
+
+	global := dexpreopt.GetGlobalConfig(ctx)
+	for _, jar := range android.SortedKeys(m.jars) {
+		listName, apex := "PRODUCT_BOOT_JARS", "platform"
+		if i := global.BootJars.IndexOfJar(jar); i >= 0 {
+			apex = global.BootJars.Apex(i)
+		} else if i := global.ApexBootJars.IndexOfJar(jar); i >= 0 {
+			listName, apex = "PRODUCT_APEX_BOOT_JARS", global.ApexBootJars.Apex(i)
+		}
+		reason := missingBootDexJarReason(jar, apex, listName, variants[jar], fragmentContents)
+		ctx.Errorf("module %s does not provide a dex boot jar for %s as %q, needed by %s: %s",
+			jar, listName, apex+":"+jar, strings.Join(m.jars[jar], ", "), reason)
+	}
+}
+
+// addActiveFragmentContents adds the contents of the bootclasspath fragment to the set of the jars
+// of the apexes that it is in, as <apex>:<jar>, if the fragment is active.
+func addActiveFragmentContents(ctx android.SingletonContext, contents map[string]bool, fragment android.Module, jars []string) {
+	if !isActiveModule(fragment) {
+		return
+	}
+	apexInfo := ctx.ModuleProvider(fragment, android.ApexInfoProvider).(android.ApexInfo)
+	for _, apex := range apexInfo.InApexVariants {
+		for _, jar := range jars {
+			contents[apex+":"+android.RemoveOptionalPrebuiltPrefix(jar)] = true
+		}
+	}
+}
+
+// missingBootDexJarReason returns an actionable explanation of why no module provides the dex jar
+// of the boot jar.
+func missingBootDexJarReason(jar, apex, listName string, variants []bootJarModuleVariant, fragmentContents map[string]bool) string {
+	if len(variants) == 0 {
+		return fmt.Sprintf("there is no module named %q or %q", jar, android.PrebuiltNameFromSource(jar))
+	}
+
+	var active, inactive []bootJarModuleVariant
+	for _, v := range variants {
+		if v.active {
+			active = append(active, v)
+		} else {
+			inactive = append(inactive, v)
+		}
+	}
+	if len(active) == 0 {
+		return fmt.Sprintf("none of the modules named %q is active, i.e. enabled and preferred over its source or prebuilt counterpart", jar)
+	}
+
+	var apexes []string
+	for _, v := range active {
+		apexes = append(apexes, v.apexInfo.InApexVariants...)
+	}
+	apexes = android.SortedUniqueStrings(apexes)
+
+	if isPlatformApex(apex) {
+		for _, v := range active {
+			if !v.apexInfo.IsForPlatform() {
+				continue
+			}
+			if !v.compilesDex {
+				return fmt.Sprintf("%q is not compiled to dex, set \"compile_dex: true\" on it", v.name)
+			}
+			return fmt.Sprintf("the platform variant of %q does not provide a dex jar", v.name)
+		}
+		for _, v := range active {
+			if v.apexInfo.Updatable {
+				return fmt.Sprintf("%q is only in the updatable apexes %q, jars of updatable apexes must be listed in PRODUCT_APEX_BOOT_JARS as <apex>:%s",
+					v.name, apexes, jar)
+			}
+		}
+		return fmt.Sprintf("%q is only in the apexes %q, list it as <apex>:%s instead of %s:%s", active[0].name, apexes, jar, apex, jar)
+	}
+
+	if !android.InList(apex, apexes) {
+		for _, v := range inactive {
+			if android.InList(apex, v.apexInfo.InApexVariants) {
+				preferred := "set \"prefer: true\" on it"
+				if !v.prebuilt {
+					preferred = "remove \"prefer: true\" from the prebuilt"
+				}
+				return fmt.Sprintf("the %q apex contains %q, which is not the active module: %s, or disable %q",
+					apex, v.name, preferred, active[0].name)
+			}
+		}
+		if len(apexes) == 0 {
+			return fmt.Sprintf("%q is not in any apex, add it to the %q apex or list it as platform:%s", active[0].name, apex, jar)
+		}
+		return fmt.Sprintf("%q is not in the %q apex but in %q, fix the apex of the jar in %s", active[0].name, apex, apexes, listName)
+	}
+
+	if !fragmentContents[apex+":"+jar] {
+		return fmt.Sprintf("%q is not in the contents of an active bootclasspath_fragment of the %q apex, so its dex jar is not encoded with its hidden API flags; add it to the contents of the bootclasspath_fragment",
+			jar, apex)
+	}
+
+	return fmt.Sprintf("the bootclasspath_fragment of the %q apex does not export the dex jar of %q; if the apex is a prebuilt_apex, check that it exports the prebuilt_bootclasspath_fragment and that the prebuilt modules are preferred",
+		apex, jar)
+}
@@ -0,0 +1,115 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestMissingBootDexJarReason(t *testing.T) {
+	inApexes := func(updatable bool, apexes ...string) android.ApexInfo {
+		return android.ApexInfo{ApexVariationName: apexes[0], InApexVariants: apexes, Updatable: updatable}
+	}
+
+	testCases := []struct {
+		name             string
+		apex             string
+		variants         []bootJarModuleVariant
+		fragmentContents map[string]bool
+		expected         string
+	}{
+		{
+			name:     "no module",
+			apex:     "myapex",
+			expected: `there is no module named "foo" or "prebuilt_foo"`,
+		},
+		{
+			name: "no active module",
+			apex: "myapex",
+			variants: []bootJarModuleVariant{
+				{name: "foo"},
+				{name: "prebuilt_foo", prebuilt: true},
+			},
+			expected: `none of the modules named "foo" is active, i.e. enabled and preferred over its source or prebuilt counterpart`,
+		},
+		{
+			name: "platform jar not compiled to dex",
+			apex: "platform",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true},
+			},
+			expected: `"foo" is not compiled to dex, set "compile_dex: true" on it`,
+		},
+		{
+			name: "platform jar in an updatable apex",
+			apex: "platform",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true, compilesDex: true, apexInfo: inApexes(true, "myapex")},
+			},
+			expected: `"foo" is only in the updatable apexes ["myapex"], jars of updatable apexes must be listed in PRODUCT_APEX_BOOT_JARS as <apex>:foo`,
+		},
+		{
+			name: "platform jar in a non-updatable apex",
+			apex: "platform",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true, compilesDex: true, apexInfo: inApexes(false, "myapex")},
+			},
+			expected: `"foo" is only in the apexes ["myapex"], list it as <apex>:foo instead of platform:foo`,
+		},
+		{
+			name: "prebuilt in the apex is not preferred",
+			apex: "myapex",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true, compilesDex: true},
+				{name: "prebuilt_foo", prebuilt: true, compilesDex: true, apexInfo: inApexes(true, "myapex")},
+			},
+			expected: `the "myapex" apex contains "prebuilt_foo", which is not the active module: set "prefer: true" on it, or disable "foo"`,
+		},
+		{
+			name: "source in the apex is not preferred",
+			apex: "myapex",
+			variants: []bootJarModuleVariant{
+				{name: "foo", compilesDex: true, apexInfo: inApexes(true, "myapex")},
+				{name: "prebuilt_foo", active: true, prebuilt: true, compilesDex: true},
+			},
+			expected: `the "myapex" apex contains "foo", which is not the active module: remove "prefer: true" from the prebuilt, or disable "prebuilt_foo"`,
+		},
+		{
+			name: "jar in another apex",
+			apex: "myapex",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true, compilesDex: true, apexInfo: inApexes(true, "otherapex")},
+			},
+			expected: `"foo" is not in the "myapex" apex but in ["otherapex"], fix the apex of the jar in PRODUCT_APEX_BOOT_JARS`,
+		},
+		{
+			name: "jar not in a fragment",
+			apex: "myapex",
+			variants: []bootJarModuleVariant{
+				{name: "foo", active: true, compilesDex: true, apexInfo: inApexes(true, "myapex")},
+			},
+			expected: `"foo" is not in the contents of an active bootclasspath_fragment of the "myapex" apex, so its dex jar is not encoded with its hidden API flags; add it to the contents of the bootclasspath_fragment`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := missingBootDexJarReason("foo", tc.apex, "PRODUCT_APEX_BOOT_JARS", tc.variants, tc.fragmentContents)
+			android.AssertStringEquals(t, "reason", tc.expected, actual)
+		})
+	}
+}
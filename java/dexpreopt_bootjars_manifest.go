@@ -0,0 +1,150 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"android/soong/android"
+)
+
+// The dex_bootjars singleton module writes out/soong/dexpreopt/boot_images.json, a manifest of the
+// boot images that it exports to Make, so that the CI tooling and the device flashing scripts can
+// find the boot image files without scraping the DEXPREOPT_IMAGE_* Make variables. The manifest is
+// the default output file of the dex_bootjars module.
+
+// bootImagesManifest is the content of boot_images.json.
+type bootImagesManifest struct {
+	Images []bootImageManifest `json:"images"`
+}
+
+type bootImageManifest struct {
+	Name           string                     `json:"name"`
+	Stem           string                     `json:"stem"`
+	Extends        string                     `json:"extends,omitempty"`
+	InstallDir     string                     `json:"install_dir"`
+	Modules        []string                   `json:"modules"`
+	CompilerFilter string                     `json:"compiler_filter"`
+	Zip            string                     `json:"zip"`
+	DeltaMetadata  string                     `json:"delta_metadata,omitempty"`
+	Variants       []bootImageVariantManifest `json:"variants"`
+}
+
+type bootImageVariantManifest struct {
+	Os                string `json:"os"`
+	Arch              string `json:"arch"`
+	CompilerFilter    string `json:"compiler_filter"`
+	ImagePath         string `json:"image_path"`
+	ImagePathOnDevice string `json:"image_path_on_device"`
+
+	// The locations on the device of the jars of the image.
+	DexLocations []string `json:"dex_locations"`
+
+	// The .art, .oat and .vdex files of the image.
+	Outputs []string `json:"outputs"`
+
+	// The files whose checksums the image depends on, i.e. the dex files of the image and of the
+	// images it extends, and the files of the images it extends.
+	ChecksumInputs []string `json:"checksum_inputs"`
+
+	// The files that Make installs, as <built file>:<install path>.
+	Installs []string `json:"installs,omitempty"`
+}
+
+// bootImagesManifestPath returns the path of boot_images.json.
+func bootImagesManifestPath(ctx android.PathContext) android.OutputPath {
+	return android.PathForOutput(ctx, "dexpreopt", "boot_images.json")
+}
+
+// writeBootImagesManifest writes boot_images.json for the given boot images, which may be empty if
+// the boot images are not built.
+func (d *dexpreoptBootJars) writeBootImagesManifest(ctx android.SingletonContext, images []*bootImageConfig) {
+	manifest := bootImagesManifest{Images: []bootImageManifest{}}
+	for _, image := range images {
+		m := bootImageManifest{
+			Name:           image.name,
+			Stem:           image.stem,
+			InstallDir:     image.installDir,
+			Modules:        image.modules.CopyOfApexJarPairs(),
+			CompilerFilter: image.compilerFilter,
+			Zip:            image.zip.String(),
+		}
+		if image.extends != nil {
+			m.Extends = image.extends.name
+		}
+		installInfo := d.bootImageInstalls[image.name]
+		if installInfo != nil && installInfo.deltaMetadata != nil {
+			m.DeltaMetadata = installInfo.deltaMetadata.String()
+		}
+		for _, variant := range image.variants {
+			checksumInputs := append(variant.dexPathsDeps.Paths(), variant.baseImagesDeps...)
+			v := bootImageVariantManifest{
+				Os:                variant.target.Os.String(),
+				Arch:              variant.target.Arch.ArchType.String(),
+				CompilerFilter:    variant.compilerFilter,
+				ImagePath:         variant.imagePathOnHost.String(),
+				ImagePathOnDevice: variant.imagePathOnDevice,
+				DexLocations:      variant.dexLocations,
+				Outputs:           variant.imagesDeps.Strings(),
+				ChecksumInputs:    checksumInputs.Strings(),
+			}
+			for _, install := range installInfo.variant(variant.target).installs {
+				v.Installs = append(v.Installs, install.From.String()+":"+install.To)
+			}
+			m.Variants = append(m.Variants, v)
+		}
+		manifest.Images = append(manifest.Images, m)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to write boot_images.json: %s", err)
+		return
+	}
+	android.WriteFileRule(ctx, bootImagesManifestPath(ctx), string(data))
+}
+
+// OutputFiles returns boot_images.json as the default output file of the dex_bootjars module, and
+// the profile, the boot framework profile and the zip of the default boot image with the .profile,
+// .bprof and .zip tags, so that they can be disted from Android.bp. The tagged outputs are empty if
+// they are not built.
+func (d *dexpreoptBootJars) OutputFiles(tag string) (android.Paths, error) {
+	var path android.Path
+	switch tag {
+	case "":
+		if d.bootImagesManifest != nil {
+			path = d.bootImagesManifest
+		}
+	case ".profile":
+		path = d.bootImageProfile
+	case ".bprof":
+		path = d.bootImageBprof
+	case ".zip":
+		path = d.bootImageZip
+	default:
+		return nil, fmt.Errorf("unsupported module reference tag %q", tag)
+	}
+	if path == nil {
+		return nil, nil
+	}
+	return android.Paths{path}, nil
+}
+
+func (d *dexpreoptBootJars) OutputFileTags() []string {
+	return []string{".profile", ".bprof", ".zip"}
+}
+
+var _ android.OutputFileProducer = (*dexpreoptBootJars)(nil)
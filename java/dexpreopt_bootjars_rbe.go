@@ -0,0 +1,125 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+	"android/soong/remoteexec"
+)
+
+// The dex2oat commands that compile the boot images, and the profman commands that generate the
+// boot image profile, run with RBE if RBE_DEX2OAT is set, so that the large boot images can be
+// compiled remotely. The dex2oat rules run in sbox with their inputs and tools sandboxed, so
+// RuleBuilder wraps them with rewrapper and uploads the inputs and tools it knows of, including
+// the shared libraries of dex2oat. The profman rules are not sandboxed, so the rewrapper command
+// line is added to the command directly and the inputs are uploaded from a list written next to
+// the outputs of the rule.
+
+// dex2oatUseRbe returns true if the dex2oat and profman commands of the boot image rules run with
+// RBE.
+func dex2oatUseRbe(ctx android.PathContext) bool {
+	return ctx.Config().UseRBE() && ctx.Config().IsEnvTrue("RBE_DEX2OAT")
+}
+
+type bootImageRbeToolDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (t bootImageRbeToolDependencyTag) ExcludeFromVisibilityEnforcement() {}
+
+func (t bootImageRbeToolDependencyTag) ExcludeFromApexContents() {}
+
+// bootImageRbeToolDepTag is the tag of the dependency onto profman, which is only added when the
+// boot image rules run with RBE, so that its shared libraries can be uploaded along with it.
+var bootImageRbeToolDepTag = bootImageRbeToolDependencyTag{}
+
+var _ android.ExcludeFromVisibilityEnforcementTag = bootImageRbeToolDepTag
+var _ android.ExcludeFromApexContentsTag = bootImageRbeToolDepTag
+
+// addBootImageRbeToolDeps adds the dependencies onto the tools of the boot image rules that are
+// needed to run them with RBE, in addition to the ones added by dexpreopt.RegisterToolDeps.
+func addBootImageRbeToolDeps(ctx android.BottomUpMutatorContext) {
+	if dex2oatUseRbe(ctx) {
+		ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(), bootImageRbeToolDepTag, "profman")
+	}
+}
+
+// bootImageRbeToolchainInputs returns the tool of a boot image rule along with the shared
+// libraries of profman, which are installed in the lib64 directory next to the bin directory of
+// the tool, and have to be uploaded with it for the tool to run remotely.
+func bootImageRbeToolchainInputs(ctx android.ModuleContext, tool android.Path) []string {
+	inputs := []string{tool.String()}
+	ctx.VisitDirectDeps(func(dep android.Module) {
+		tag := ctx.OtherModuleDependencyTag(dep)
+		if tag != bootImageRbeToolDepTag {
+			return
+		}
+		for _, spec := range dep.TransitivePackagingSpecs() {
+			if filepath.Dir(spec.RelPathInPackage()) != "lib64" {
+				continue
+			}
+			lib := strings.TrimSuffix(spec.FileName(), filepath.Ext(spec.FileName()))
+			inputs = append(inputs, ctx.Config().HostJNIToolPath(ctx, lib).String())
+		}
+	})
+	return android.FirstUniqueStrings(inputs)
+}
+
+// rewrapBootImageRule runs the sandboxed boot image rule with RBE.
+func rewrapBootImageRule(rule *android.RuleBuilder, name string) {
+	rule.Remoteable(android.RemoteRuleSupports{RBE: true})
+	rule.Rewrapper(&remoteexec.REParams{
+		Labels:       map[string]string{"type": "tool", "name": name},
+		ExecStrategy: rule.NinjaVariable("config.REDex2oatExecStrategy"),
+		Platform:     map[string]string{remoteexec.PoolKey: rule.NinjaVariable("config.REDex2oatPool")},
+	})
+}
+
+// rewrapBootImageCommand adds the rewrapper command line that runs the rest of cmd with RBE, so it
+// must be called before the tool is added to the command. The inputs of the command are uploaded
+// from inputsList, which is written by writeBootImageRemoteInputs, and the shared libraries of the
+// tool are uploaded as toolchain inputs.
+func rewrapBootImageCommand(ctx android.ModuleContext, rule *android.RuleBuilder, cmd *android.RuleBuilderCommand,
+	name string, tool android.Path, inputsList android.WritablePath, outputs android.WritablePaths) {
+
+	rule.Remoteable(android.RemoteRuleSupports{RBE: true})
+	params := &remoteexec.REParams{
+		Labels:               map[string]string{"type": "tool", "name": name},
+		ExecStrategy:         rule.NinjaVariable("config.REDex2oatExecStrategy"),
+		Platform:             map[string]string{remoteexec.PoolKey: rule.NinjaVariable("config.REDex2oatPool")},
+		RSPFiles:             []string{inputsList.String()},
+		OutputFiles:          outputs.Strings(),
+		ToolchainInputs:      bootImageRbeToolchainInputs(ctx, tool),
+		EnvironmentVariables: []string{"ANDROID_LOG_TAGS"},
+	}
+	cmd.Text(params.NoVarTemplate(ctx.Config().RBEWrapper()))
+}
+
+// writeBootImageRemoteInputs writes the list of the inputs and tools of the rule, along with the
+// extra inputs that are written by earlier commands of the rule, to inputsList, and adds it as an
+// input of cmd. It must be called once the rule is complete.
+func writeBootImageRemoteInputs(ctx android.ModuleContext, rule *android.RuleBuilder, cmd *android.RuleBuilderCommand,
+	inputsList android.WritablePath, extraInputs ...android.Path) {
+
+	inputs := append(rule.Inputs(), rule.Tools()...)
+	inputs = append(inputs, extraInputs...)
+	android.WriteFileRule(ctx, inputsList, strings.Join(android.FirstUniquePaths(inputs).Strings(), "\n"))
+	cmd.Implicit(inputsList)
+}
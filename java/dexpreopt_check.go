@@ -15,6 +15,7 @@
 package java
 
 import (
+	"path/filepath"
 	"strings"
 
 	"android/soong/android"
@@ -76,6 +77,9 @@ func (m *dexpreoptSystemserverCheck) GenerateAndroidBuildActions(ctx android.Mod
 	for _, jar := range systemServerJars.CopyOfJars() {
 		dexLocation := dexpreopt.GetSystemServerDexLocation(ctx, global, jar)
 		odexLocation := dexpreopt.ToOdexPath(dexLocation, targets[0].Arch.ArchType)
+		if dexpreopt.OdexOnPreoptPartition(dexLocation, global) {
+			odexLocation = filepath.Join(dexpreopt.PreoptPartitionInstallDir(global), odexLocation)
+		}
 		odexPath := getInstallPath(ctx, odexLocation)
 		vdexPath := getInstallPath(ctx, pathtools.ReplaceExtension(odexLocation, "vdex"))
 		m.artifactsByModuleName[jar] = []string{odexPath.String(), vdexPath.String()}
@@ -20,6 +20,8 @@ import (
 
 	"android/soong/android"
 	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint/proptools"
 )
 
 // dexpreoptTargets returns the list of targets that are relevant to dexpreopting, which excludes architectures
@@ -45,7 +47,12 @@ var (
 	artBootImageName       = "art"
 	frameworkBootImageName = "boot"
 	mainlineBootImageName  = "mainline"
+	systemExtBootImageName = "system_ext"
 	bootImageStem          = "boot"
+
+	// The name of the copy of the framework boot image built from the non-instrumented jars in
+	// coverage builds, see boot_image_coverage.go.
+	uninstrumentedBootImageName = "boot_uninstrumented"
 )
 
 func genBootImageConfigRaw(ctx android.PathContext) map[string]*bootImageConfig {
@@ -57,6 +64,12 @@ func genBootImageConfigRaw(ctx android.PathContext) map[string]*bootImageConfig
 		mainlineBcpModules := global.ApexBootJars
 		frameworkSubdir := "system/framework"
 
+		// The system_ext jars are compiled into their own boot image extension if requested.
+		systemExtModules := android.EmptyConfiguredJarList()
+		if global.SystemExtBootImage {
+			frameworkModules, systemExtModules = splitSystemExtBootJars(global.BootJars)
+		}
+
 		// ART config for the primary boot image in the ART apex.
 		// It includes the Core Libraries.
 		artCfg := bootImageConfig{
@@ -80,7 +93,6 @@ func genBootImageConfigRaw(ctx android.PathContext) map[string]*bootImageConfig
 			preloadedClassesFile: "frameworks/base/config/preloaded-classes",
 			compilerFilter:       "everything",
 			singleImage:          false,
-			profileImports:       []*bootImageConfig{&artCfg},
 		}
 
 		mainlineCfg := bootImageConfig{
@@ -93,14 +105,83 @@ func genBootImageConfigRaw(ctx android.PathContext) map[string]*bootImageConfig
 			singleImage:    true,
 		}
 
-		return map[string]*bootImageConfig{
+		configs := map[string]*bootImageConfig{
 			artBootImageName:       &artCfg,
 			frameworkBootImageName: &frameworkCfg,
 			mainlineBootImageName:  &mainlineCfg,
 		}
+
+		// System_ext config for the boot image extension of the system_ext jars, installed in the
+		// system_ext partition so that the partner boot jars do not add files to /system. It extends
+		// the framework config, and the mainline config extends it as the apex boot jars come after the
+		// system_ext jars on the bootclasspath.
+		if systemExtModules.Len() > 0 {
+			systemExtCfg := bootImageConfig{
+				extends:        &frameworkCfg,
+				name:           systemExtBootImageName,
+				stem:           bootImageStem,
+				installDir:     "system_ext/framework",
+				modules:        systemExtModules,
+				compilerFilter: "verify",
+				singleImage:    true,
+			}
+			mainlineCfg.extends = &systemExtCfg
+			configs[systemExtBootImageName] = &systemExtCfg
+		}
+
+		// In coverage builds the framework boot image is also built from the non-instrumented jars,
+		// under its own directories.
+		if isUninstrumentedBootImageEnabled(ctx.Config()) {
+			configs[uninstrumentedBootImageName] = uninstrumentedBootImageConfig(&frameworkCfg)
+		}
+		addBootImageExtensionConfigs(ctx.Config(), configs)
+
+		// Apply the product overrides of EnableUffdGc, unknown image names are reported by
+		// checkBootImageEnableUffdGc.
+		for name, enable := range global.BootImageEnableUffdGc {
+			if c, ok := configs[name]; ok {
+				c.enableUffdGc = proptools.BoolPtr(enable)
+			}
+		}
+
+		for _, c := range configs {
+			// The ART boot image is compiled as a whole, as its components are found on device from the
+			// header of its primary component.
+			c.dex2oatPerJar = !c.singleImage && c.name != artBootImageName && !global.MonolithicBootImageDexpreopt
+			// Unknown image names are reported by checkBootImageDex2oatHeapSizes.
+			c.dex2oatXms = ctx.Config().BootImageDex2oatXms(c.name)
+			c.dex2oatXmx = ctx.Config().BootImageDex2oatXmx(c.name)
+		}
+
+		return configs
 	}).(map[string]*bootImageConfig)
 }
 
+// checkBootImageEnableUffdGc reports the overrides of EnableUffdGc in the global config for boot
+// images that do not exist.
+func checkBootImageEnableUffdGc(ctx android.ModuleContext) {
+	global := dexpreopt.GetGlobalConfig(ctx)
+	configs := genBootImageConfigRaw(ctx)
+	for _, name := range android.SortedKeys(global.BootImageEnableUffdGc) {
+		if _, ok := configs[name]; !ok {
+			ctx.ModuleErrorf("BootImageEnableUffdGc: unknown boot image %q, expected one of %q",
+				name, android.SortedKeys(configs))
+		}
+	}
+}
+
+// checkBootImageDex2oatHeapSizes reports the product overrides of the dex2oat heap sizes for boot
+// images that do not exist.
+func checkBootImageDex2oatHeapSizes(ctx android.ModuleContext) {
+	configs := genBootImageConfigRaw(ctx)
+	for _, name := range ctx.Config().BootImageDex2oatHeapSizeImages() {
+		if _, ok := configs[name]; !ok {
+			ctx.ModuleErrorf("BootImageDex2oatXms/BootImageDex2oatXmx: unknown boot image %q, expected one of %q",
+				name, android.SortedKeys(configs))
+		}
+	}
+}
+
 // Construct the global boot image configs.
 func genBootImageConfigs(ctx android.PathContext) map[string]*bootImageConfig {
 	return ctx.Config().Once(bootImageConfigKey, func() interface{} {
@@ -129,20 +210,36 @@ func genBootImageConfigs(ctx android.PathContext) map[string]*bootImageConfig {
 			// Create target-specific variants.
 			for _, target := range targets {
 				arch := target.Arch.ArchType
-				imageDir := c.dir.Join(ctx, target.Os.String(), c.installDir, arch.String())
+				imageDir := c.imageDir(ctx, target, 0)
 				variant := &bootImageVariant{
 					bootImageConfig:   c,
 					target:            target,
+					compilerFilter:    c.compilerFilter,
 					imagePathOnHost:   imageDir.Join(ctx, imageName),
 					imagePathOnDevice: filepath.Join("/", c.installDir, arch.String(), imageName),
-					imagesDeps:        c.moduleFiles(ctx, imageDir, ".art", ".oat", ".vdex"),
+					imagesDeps:        c.moduleFiles(ctx, target, ".art", ".oat", ".vdex"),
 					dexLocations:      c.modules.DevicePaths(ctx.Config(), target.Os),
 				}
 				variant.dexLocationsDeps = variant.dexLocations
+				if target.Os == android.Android {
+					if filter, ok := ctx.Config().BootImageCompilerFilter(c.name, arch); ok {
+						variant.compilerFilter = filter
+					}
+				}
+				if c.dex2oatPerJar {
+					variant.componentImagePathsOnHost = c.moduleFiles(ctx, target, ".art")
+				} else {
+					variant.componentImagePathsOnHost = android.OutputPaths{variant.imagePathOnHost}
+				}
+				for _, path := range variant.componentImagePathsOnHost {
+					variant.componentImagePathsOnDevice = append(variant.componentImagePathsOnDevice,
+						filepath.Join("/", c.installDir, arch.String(), path.Base()))
+				}
 				c.variants = append(c.variants, variant)
 			}
 
 			c.zip = c.dir.Join(ctx, c.name+".zip")
+			c.deltaMetadata = c.dir.Join(ctx, c.name+".delta.txt")
 		}
 
 		visited := make(map[string]bool)
@@ -175,12 +272,43 @@ func calculateDepsRecursive(c *bootImageConfig, targets []android.Target, visite
 	visited[c.name] = true
 	c.dexPathsDeps = android.Concat(c.extends.dexPathsDeps, c.dexPathsDeps)
 	for i := range targets {
-		c.variants[i].baseImages = android.Concat(c.extends.variants[i].baseImages, android.OutputPaths{c.extends.variants[i].imagePathOnHost})
+		c.variants[i].baseImages = android.Concat(c.extends.variants[i].baseImages, c.extends.variants[i].componentImagePathsOnHost)
 		c.variants[i].baseImagesDeps = android.Concat(c.extends.variants[i].baseImagesDeps, c.extends.variants[i].imagesDeps.Paths())
 		c.variants[i].dexLocationsDeps = android.Concat(c.extends.variants[i].dexLocationsDeps, c.variants[i].dexLocationsDeps)
 	}
 }
 
+// splitSystemExtBootJars splits the boot jars into the system_ext jars and the other boot jars.
+func splitSystemExtBootJars(bootJars android.ConfiguredJarList) (android.ConfiguredJarList, android.ConfiguredJarList) {
+	others := android.EmptyConfiguredJarList()
+	systemExt := android.EmptyConfiguredJarList()
+	for i := 0; i < bootJars.Len(); i++ {
+		if bootJars.Apex(i) == "system_ext" {
+			systemExt = systemExt.Append(bootJars.Apex(i), bootJars.Jar(i))
+		} else {
+			others = others.Append(bootJars.Apex(i), bootJars.Jar(i))
+		}
+	}
+	return others, systemExt
+}
+
+// checkSystemExtBootImage reports the system_ext boot jars that come before other boot jars when
+// they are compiled into their own boot image extension, as the boot image extensions must follow
+// the order of the bootclasspath.
+func checkSystemExtBootImage(ctx android.ModuleContext) {
+	global := dexpreopt.GetGlobalConfig(ctx)
+	if !global.SystemExtBootImage {
+		return
+	}
+	for i := 1; i < global.BootJars.Len(); i++ {
+		if global.BootJars.Apex(i-1) == "system_ext" && global.BootJars.Apex(i) != "system_ext" {
+			ctx.ModuleErrorf("SystemExtBootImage: the system_ext boot jar %q must come after the "+
+				"other boot jars, but it comes before %q", global.BootJars.Jar(i-1), global.BootJars.Jar(i))
+			return
+		}
+	}
+}
+
 func artBootImageConfig(ctx android.PathContext) *bootImageConfig {
 	return genBootImageConfigs(ctx)[artBootImageName]
 }
@@ -193,6 +321,16 @@ func mainlineBootImageConfig(ctx android.PathContext) *bootImageConfig {
 	return genBootImageConfigs(ctx)[mainlineBootImageName]
 }
 
+// nonUpdatableBootImageConfig returns the config of the last boot image of the non-updatable boot
+// jars, i.e. the system_ext boot image if the system_ext jars have their own boot image, or the
+// framework boot image otherwise.
+func nonUpdatableBootImageConfig(ctx android.PathContext) *bootImageConfig {
+	if c, ok := genBootImageConfigs(ctx)[systemExtBootImageName]; ok {
+		return c
+	}
+	return defaultBootImageConfig(ctx)
+}
+
 // Apex boot config allows to access build/install paths of apex boot jars without going
 // through the usual trouble of registering dependencies on those modules and extracting build paths
 // from those dependencies.
@@ -233,7 +371,7 @@ func GetApexBootConfig(ctx android.PathContext) apexBootConfig {
 // passed in -Xbootclasspath and -Xbootclasspath-locations arguments for dex2oat).
 func bcpForDexpreopt(ctx android.PathContext, withUpdatable bool) (android.WritablePaths, []string) {
 	// Non-updatable boot jars (they are used both in the boot image and in dexpreopt).
-	bootImage := defaultBootImageConfig(ctx)
+	bootImage := nonUpdatableBootImageConfig(ctx)
 	dexPaths := bootImage.dexPathsDeps
 	// The dex locations for all Android variants are identical.
 	dexLocations := bootImage.getAnyAndroidVariant().dexLocationsDeps
@@ -257,7 +395,7 @@ func init() {
 }
 
 func dexpreoptConfigMakevars(ctx android.MakeVarsContext) {
-	ctx.Strict("DEXPREOPT_BOOT_JARS_MODULES", strings.Join(defaultBootImageConfig(ctx).modules.CopyOfApexJarPairs(), ":"))
+	ctx.Strict("DEXPREOPT_BOOT_JARS_MODULES", strings.Join(dexpreopt.GetGlobalConfig(ctx).BootJars.CopyOfApexJarPairs(), ":"))
 }
 
 func toDexpreoptDirName(arch android.ArchType) string {
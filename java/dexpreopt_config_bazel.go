@@ -0,0 +1,115 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"strings"
+
+	"android/soong/android"
+)
+
+// The boot image configs are exported to the soong_injection directory, as a JSON file and as a
+// Starlark constants file, so that the Bazel implementation of dexpreopt in mixed builds uses the
+// same names, stems, install directories, jars and variants as Soong instead of duplicating them.
+
+type bazelBootImageConfig struct {
+	Name                 string                        `json:"name"`
+	Stem                 string                        `json:"stem"`
+	Extends              string                        `json:"extends,omitempty"`
+	InstallDir           string                        `json:"install_dir"`
+	Modules              []string                      `json:"modules"`
+	Jars                 []string                      `json:"jars"`
+	CompilerFilter       string                        `json:"compiler_filter"`
+	PreloadedClassesFile string                        `json:"preloaded_classes_file"`
+	SingleImage          bool                          `json:"single_image"`
+	Variants             []bazelBootImageVariantConfig `json:"variants"`
+}
+
+type bazelBootImageVariantConfig struct {
+	Os                string `json:"os"`
+	Arch              string `json:"arch"`
+	CompilerFilter    string `json:"compiler_filter"`
+	ImagePathOnDevice string `json:"image_path_on_device"`
+
+	// The locations on the device of the jars of the image.
+	DexLocations []string `json:"dex_locations"`
+
+	// The locations on the device of the image and of the images it extends, as passed to
+	// dex2oat with --boot-image.
+	ImageLocationsOnDevice []string `json:"image_locations_on_device"`
+}
+
+// BazelBootImageConfigsJson returns the boot image configs of the product as JSON. The boot image
+// configs are computed from the dexpreopt config, which is added as a ninja file dependency of ctx.
+func BazelBootImageConfigsJson(ctx android.PathContext) ([]byte, error) {
+	cfg := ctx.Config()
+	// The dexpreopt config is only read once per config, which may have happened before with another
+	// context, so add the dependency explicitly.
+	if path := cfg.DexpreoptGlobalConfigPath(ctx); path.Valid() {
+		ctx.AddNinjaFileDeps(path.String())
+	}
+
+	configs := []bazelBootImageConfig{}
+	// The boot image configs have variants for the device targets, which may not be configured,
+	// e.g. for host-only builds.
+	if len(cfg.Targets[android.Android]) > 0 {
+		imageConfigs := genBootImageConfigs(ctx)
+		for _, name := range android.SortedKeys(imageConfigs) {
+			image := imageConfigs[name]
+			c := bazelBootImageConfig{
+				Name:                 image.name,
+				Stem:                 image.stem,
+				InstallDir:           image.installDir,
+				Modules:              image.modules.CopyOfApexJarPairs(),
+				Jars:                 image.modules.CopyOfJars(),
+				CompilerFilter:       image.compilerFilter,
+				PreloadedClassesFile: image.preloadedClassesFile,
+				SingleImage:          image.singleImage,
+				Variants:             []bazelBootImageVariantConfig{},
+			}
+			if image.extends != nil {
+				c.Extends = image.extends.name
+			}
+			for _, variant := range image.variants {
+				_, imageLocationsOnDevice := variant.imageLocations()
+				c.Variants = append(c.Variants, bazelBootImageVariantConfig{
+					Os:                     variant.target.Os.String(),
+					Arch:                   variant.target.Arch.ArchType.String(),
+					CompilerFilter:         variant.compilerFilter,
+					ImagePathOnDevice:      variant.imagePathOnDevice,
+					DexLocations:           variant.dexLocations,
+					ImageLocationsOnDevice: imageLocationsOnDevice,
+				})
+			}
+			configs = append(configs, c)
+		}
+	}
+	return json.MarshalIndent(configs, "", "  ")
+}
+
+// BazelBootImageConfigVars returns the Starlark constants file with the boot image configs of the
+// product.
+func BazelBootImageConfigVars(ctx android.PathContext) (string, error) {
+	configs, err := BazelBootImageConfigsJson(ctx)
+	if err != nil {
+		return "", err
+	}
+	// Escape the backslashes of the JSON escape sequences, which would otherwise be interpreted by
+	// the Starlark string literal.
+	content := "# GENERATED BY SOONG. DO NOT EDIT.\n" +
+		"boot_image_configs = json.decode('''" + strings.ReplaceAll(string(configs), "\\", "\\\\") + "''')\n"
+	return content, nil
+}
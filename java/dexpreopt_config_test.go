@@ -15,10 +15,14 @@
 package java
 
 import (
+	"encoding/json"
 	"runtime"
 	"testing"
 
 	"android/soong/android"
+	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func TestBootImageConfig(t *testing.T) {
@@ -35,3 +39,77 @@ func TestBootImageConfig(t *testing.T) {
 	CheckFrameworkBootImageConfig(t, result)
 	CheckMainlineBootImageConfig(t, result)
 }
+
+func TestBootImageConfigEnableUffdGc(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForBootImageConfigTest,
+		PrepareApexBootJarConfigs,
+		dexpreopt.FixtureModifyGlobalConfig(func(_ android.PathContext, global *dexpreopt.GlobalConfig) {
+			global.EnableUffdGc = true
+		}),
+		dexpreopt.FixtureSetBootImageEnableUffdGc("art", false),
+	).RunTest(t)
+
+	pathCtx := &android.TestPathContext{TestResult: result}
+	global := dexpreopt.GetGlobalConfig(pathCtx)
+	android.AssertBoolEquals(t, "art", false, getArtImageConfig(result).uffdGcEnabled(global))
+	android.AssertBoolEquals(t, "boot", true, getFrameworkImageConfig(result).uffdGcEnabled(global))
+	android.AssertBoolEquals(t, "mainline", true, getMainlineImageConfig(result).uffdGcEnabled(global))
+}
+
+func TestBazelBootImageConfigs(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForBootImageConfigTest,
+		PrepareApexBootJarConfigs,
+	).RunTest(t)
+
+	// The dexpreopt config of the test is already loaded, so set its path only to check the ninja
+	// file dependency.
+	result.Config.TestProductVariables.DexpreoptGlobalConfig = proptools.StringPtr("out/soong/dexpreopt.config")
+	ctx := &bazelBootImageConfigsTestContext{config: result.Config}
+	data, err := BazelBootImageConfigsJson(ctx)
+	android.AssertSame(t, "error", nil, err)
+	var configs []bazelBootImageConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		t.Fatalf("invalid boot image configs: %s", err)
+	}
+
+	var names []string
+	for _, c := range configs {
+		names = append(names, c.Name)
+	}
+	android.AssertArrayString(t, "names", []string{"art", "boot", "mainline"}, names)
+
+	mainline := configs[2]
+	android.AssertStringEquals(t, "extends", "boot", mainline.Extends)
+	android.AssertStringEquals(t, "install dir", "system/framework", mainline.InstallDir)
+	android.AssertArrayString(t, "modules",
+		[]string{"com.android.foo:framework-foo", "com.android.bar:framework-bar"}, mainline.Modules)
+	android.AssertArrayString(t, "jars", []string{"framework-foo", "framework-bar"}, mainline.Jars)
+
+	variant := mainline.Variants[0]
+	android.AssertStringEquals(t, "arch", "arm64", variant.Arch)
+	android.AssertArrayString(t, "image locations on device",
+		[]string{"/system/framework/boot.art", "/system/framework/boot-core2.art",
+			"/system/framework/boot-framework.art", "/system/framework/boot-framework-foo.art"},
+		variant.ImageLocationsOnDevice)
+
+	// The generated files must be regenerated when the dexpreopt config changes.
+	android.AssertArrayString(t, "ninja deps", []string{"out/soong/dexpreopt.config"}, ctx.deps)
+
+	vars, err := BazelBootImageConfigVars(ctx)
+	android.AssertSame(t, "error", nil, err)
+	android.AssertStringDoesContain(t, "constants", vars, "boot_image_configs = json.decode('''")
+}
+
+// bazelBootImageConfigsTestContext is a PathContext that records the ninja file dependencies.
+type bazelBootImageConfigsTestContext struct {
+	config android.Config
+	deps   []string
+}
+
+func (ctx *bazelBootImageConfigsTestContext) Config() android.Config { return ctx.config }
+
+func (ctx *bazelBootImageConfigsTestContext) AddNinjaFileDeps(deps ...string) {
+	ctx.deps = append(ctx.deps, deps...)
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+)
+
+// The dexpreopt outputs of the apps and jars that match the PatternsOnPreoptPartition of the global
+// dexpreopt config are installed on the preopt partition of the product, see
+// dexpreopt.GlobalConfig.PreoptPartition. This singleton records where they are, relative to the
+// root of the partition so that the records do not depend on where the partition is mounted, in
+// out/soong/dexpreopt/<partition>_preopt_locations.txt. Each line is the path of a dexpreopt output
+// in the partition followed by the location it would have next to its dex file, e.g.:
+//
+//	preopt/system/app/Foo/oat/arm64/Foo.odex /system/app/Foo/oat/arm64/Foo.odex
+//
+// Currently, only the dexpreopt outputs of modules defined in Soong are recorded. The dexpreopt
+// outputs of the apps and jars defined in Makefiles are also relocated to the preopt partition, by
+// the script that dexpreopt_gen generates for each of them, but they are only known to that script
+// and not to Soong, so they are missing from the locations file.
+
+func init() {
+	registerDexpreoptPartitionBuildComponents(android.InitRegistrationContext)
+}
+
+func registerDexpreoptPartitionBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("dexpreopt_preopt_partition", dexpreoptPartitionSingletonFactory)
+}
+
+var prepareForTestWithDexpreoptPartition = android.FixtureRegisterWithContext(registerDexpreoptPartitionBuildComponents)
+
+// The partitions that the dexpreopt outputs can be installed on instead of next to the dex files.
+var preoptPartitions = []string{"odm", "oem"}
+
+func dexpreoptPartitionSingletonFactory() android.Singleton {
+	return &dexpreoptPartitionSingleton{}
+}
+
+type dexpreoptPartitionSingleton struct {
+	locations android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*dexpreoptPartitionSingleton)(nil)
+
+func (s *dexpreoptPartitionSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	global := dexpreopt.GetGlobalConfig(ctx)
+	if global.PreoptPartition == "" {
+		return
+	}
+	if !android.InList(global.PreoptPartition, preoptPartitions) {
+		ctx.Errorf("PreoptPartition: unsupported partition %q, expected one of %s",
+			global.PreoptPartition, strings.Join(preoptPartitions, ", "))
+		return
+	}
+
+	partitionDir := "/" + global.PreoptPartition + "/"
+	installDir := dexpreopt.PreoptPartitionInstallDir(global)
+	lines := make(map[string]bool)
+	ctx.VisitAllModules(func(module android.Module) {
+		d, ok := module.(interface{ PreoptPartitionInstalls() []string })
+		if !ok || !module.Enabled() {
+			return
+		}
+		for _, install := range d.PreoptPartitionInstalls() {
+			lines[fmt.Sprintf("%s %s", strings.TrimPrefix(install, partitionDir),
+				strings.TrimPrefix(install, installDir))] = true
+		}
+	})
+
+	locations := android.PathForOutput(ctx, "dexpreopt", global.PreoptPartition+"_preopt_locations.txt")
+	android.WriteFileRule(ctx, locations, strings.Join(android.SortedKeys(lines), "\n"))
+	ctx.Phony("dexpreopt-preopt-partition-locations", locations)
+	s.locations = locations
+}
+
+func (s *dexpreoptPartitionSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.locations == nil {
+		return
+	}
+	ctx.Strict("DEXPREOPT_PREOPT_PARTITION_LOCATIONS", s.locations.String())
+	ctx.DistForGoal("droidcore", s.locations)
+}
@@ -297,6 +297,54 @@ func TestDex2oatToolDeps(t *testing.T) {
 	testDex2oatToolDep(false, true, false, prebuiltDex2oatPath)
 }
 
+func TestDexpreoptToolsManifest(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		// The host binary paths checked below are build OS dependent.
+		t.Skipf("Unsupported build OS %s", runtime.GOOS)
+	}
+
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		android.FixtureRegisterWithContext(dexpreopt.RegisterGlobalSoongConfigBuildComponents),
+		android.FixtureAddFile("build/dexpreopt_tools.manifest", nil),
+		dexpreopt.FixtureModifyGlobalConfig(func(ctx android.PathContext, config *dexpreopt.GlobalConfig) {
+			config.PinnedToolsManifest = android.PathForSource(ctx, "build/dexpreopt_tools.manifest")
+		}),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			installable: true,
+		}
+	`)
+
+	singleton := result.SingletonForTests("dexpreopt-soong-config")
+	manifest := singleton.Output("dexpreopt_tools.manifest")
+	android.AssertStringDoesContain(t, "digest of profman", manifest.RuleParams.Command,
+		"echo profman $(sha256sum < out/soong/host/linux-x86/bin/profman | cut -d ' ' -f 1) >>")
+	android.AssertPathsRelativeToTopEquals(t, "manifest inputs", []string{
+		"dex2oat",
+		"out/soong/host/linux-x86/bin/aapt2",
+		"out/soong/host/linux-x86/bin/construct_context",
+		"out/soong/host/linux-x86/bin/manifest_check",
+		"out/soong/host/linux-x86/bin/profman",
+		"out/soong/host/linux-x86/bin/soong_zip",
+		"out/soong/host/linux-x86/bin/zip2zip",
+	}, manifest.Implicits)
+
+	check := singleton.Output("dexpreopt_tools.manifest.check")
+	android.AssertPathsRelativeToTopEquals(t, "check inputs", []string{
+		"build/dexpreopt_tools.manifest",
+		"out/soong/dexpreopt_tools.manifest",
+	}, check.Implicits)
+
+	// Building a dexpreopted module runs the check, not only checkbuild.
+	dexpreopt := result.ModuleForTests("foo", "android_common").Rule("dexpreopt")
+	android.AssertPathsRelativeToTopEquals(t, "dexpreopt validations", []string{
+		"out/soong/dexpreopt_tools.manifest.check",
+	}, dexpreopt.Validations)
+}
+
 func TestDexpreoptBuiltInstalledForApex(t *testing.T) {
 	preparers := android.GroupFixturePreparers(
 		PrepareForTestWithDexpreopt,
@@ -463,3 +511,67 @@ func TestGenerateProfileEvenIfDexpreoptIsDisabled(t *testing.T) {
 
 	android.AssertArrayString(t, "outputs", expected, dexpreopt.AllOutputs())
 }
+
+func TestDexpreoptPreoptPartition(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		PrepareForTestWithFakeApexMutator,
+		prepareForTestWithDexpreoptPartition,
+		dexpreopt.FixtureSetPreoptPartition("odm", "/system/framework/foo.jar"),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			installable: true,
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "bar",
+			installable: true,
+			srcs: ["a.java"],
+		}`)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	android.AssertStringDoesContain(t, "foo installs",
+		foo.Module().(*Library).dexpreopter.builtInstalled, ":/odm/preopt/system/framework/oat/arm64/foo.odex")
+	bar := result.ModuleForTests("bar", "android_common")
+	android.AssertStringDoesContain(t, "bar installs",
+		bar.Module().(*Library).dexpreopter.builtInstalled, ":/system/framework/oat/arm64/bar.odex")
+
+	// The locations are relative to the root of the partition, and only list the outputs on it.
+	locations := android.ContentFromFileRuleForTests(t,
+		result.SingletonForTests("dexpreopt_preopt_partition").Output("dexpreopt/odm_preopt_locations.txt"))
+	android.AssertStringDoesContain(t, "locations", locations,
+		"preopt/system/framework/oat/arm64/foo.odex /system/framework/oat/arm64/foo.odex\n"+
+			"preopt/system/framework/oat/arm64/foo.vdex /system/framework/oat/arm64/foo.vdex")
+	android.AssertStringDoesNotContain(t, "locations", locations, "bar")
+}
+
+func TestDexpreoptPreoptPartitionErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		prepareForTestWithDexpreoptPartition,
+		dexpreopt.FixtureSetPreoptPartition("vendor", "/system/framework/%"),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`PreoptPartition: unsupported partition "vendor", expected one of odm, oem`)).
+		RunTestWithBp(t, "")
+}
+
+func TestDexpreoptBootImageEnableUffdGc(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		PrepareForTestWithFakeApexMutator,
+		dexpreopt.FixtureSetBootImageEnableUffdGc("boot", true),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			installable: true,
+			srcs: ["a.java"],
+		}`)
+
+	// The module is compiled against a boot image that uses userfaultfd GC, even though the
+	// global config does not enable it.
+	dexpreopt := result.ModuleForTests("foo", "android_common").Rule("dexpreopt")
+	android.AssertStringDoesContain(t, "dex2oat command", dexpreopt.RuleParams.Command,
+		"--runtime-arg -Xgc:CMC")
+}
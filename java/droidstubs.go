@@ -57,6 +57,7 @@ type Droidstubs struct {
 	checkCurrentApiTimestamp      android.WritablePath
 	updateCurrentApiTimestamp     android.WritablePath
 	checkLastReleasedApiTimestamp android.WritablePath
+	checkFrozenApiTimestamp       android.WritablePath
 	apiLintTimestamp              android.WritablePath
 	apiLintReport                 android.WritablePath
 
@@ -727,6 +728,42 @@ func (d *Droidstubs) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 	rule.Build("metalava", "metalava merged")
 
+	if doCheckReleased && ctx.Config().FrozenInterfaces() {
+		// The interfaces are frozen on this branch, so the API must be identical to the last
+		// released API rather than only compatible with it.
+		apiFile := android.PathForModuleSrc(ctx, String(d.properties.Check_api.Last_released.Api_file))
+		removedApiFile := android.PathForModuleSrc(ctx, String(d.properties.Check_api.Last_released.Removed_api_file))
+
+		d.checkFrozenApiTimestamp = android.PathForModuleOut(ctx, "metalava", "check_frozen_api.timestamp")
+
+		rule := android.NewRuleBuilder(pctx, ctx)
+
+		diff := `diff -u -F '{ *$'`
+
+		rule.Command().Text("( true")
+		rule.Command().
+			Text(diff).
+			Input(apiFile).Input(d.apiFile)
+
+		rule.Command().
+			Text(diff).
+			Input(removedApiFile).Input(d.removedApiFile)
+
+		msg := fmt.Sprintf(`\n******************************\n`+
+			`You have tried to change the API of %s from what has been previously released.\n`+
+			`The interfaces are frozen on this branch, no API change is allowed.\n`+
+			`******************************\n`, ctx.ModuleName())
+
+		rule.Command().
+			Text("touch").Output(d.checkFrozenApiTimestamp).
+			Text(") || (").
+			Text("echo").Flag("-e").Flag(`"` + msg + `"`).
+			Text("; exit 38").
+			Text(")")
+
+		rule.Build("metalavaFrozenApiCheck", "check frozen API")
+	}
+
 	if apiCheckEnabled(ctx, d.properties.Check_api.Current, "current") {
 
 		if len(d.Javadoc.properties.Out) > 0 {
@@ -779,31 +816,34 @@ func (d *Droidstubs) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 
 		rule.Build("metalavaCurrentApiCheck", "check current API")
 
-		d.updateCurrentApiTimestamp = android.PathForModuleOut(ctx, "metalava", "update_current_api.timestamp")
+		// The API files must not be regenerated if the interfaces are frozen on this branch.
+		if !ctx.Config().FrozenInterfaces() {
+			d.updateCurrentApiTimestamp = android.PathForModuleOut(ctx, "metalava", "update_current_api.timestamp")
 
-		// update API rule
-		rule = android.NewRuleBuilder(pctx, ctx)
+			// update API rule
+			rule = android.NewRuleBuilder(pctx, ctx)
 
-		rule.Command().Text("( true")
+			rule.Command().Text("( true")
 
-		rule.Command().
-			Text("cp").Flag("-f").
-			Input(d.apiFile).Flag(apiFile.String())
+			rule.Command().
+				Text("cp").Flag("-f").
+				Input(d.apiFile).Flag(apiFile.String())
 
-		rule.Command().
-			Text("cp").Flag("-f").
-			Input(d.removedApiFile).Flag(removedApiFile.String())
+			rule.Command().
+				Text("cp").Flag("-f").
+				Input(d.removedApiFile).Flag(removedApiFile.String())
 
-		msg = "failed to update public API"
+			msg = "failed to update public API"
 
-		rule.Command().
-			Text("touch").Output(d.updateCurrentApiTimestamp).
-			Text(") || (").
-			Text("echo").Flag("-e").Flag(`"` + msg + `"`).
-			Text("; exit 38").
-			Text(")")
+			rule.Command().
+				Text("touch").Output(d.updateCurrentApiTimestamp).
+				Text(") || (").
+				Text("echo").Flag("-e").Flag(`"` + msg + `"`).
+				Text("; exit 38").
+				Text(")")
 
-		rule.Build("metalavaCurrentApiUpdate", "update current API")
+			rule.Build("metalavaCurrentApiUpdate", "update current API")
+		}
 	}
 
 	if String(d.properties.Check_nullability_warnings) != "" {
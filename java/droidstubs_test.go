@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
 )
 
 func TestDroidstubs(t *testing.T) {
@@ -305,6 +307,63 @@ func TestDroidstubsWithSdkExtensions(t *testing.T) {
 	android.AssertStringDoesContain(t, "sdk-extensions-info present", cmdline, "--sdk-extensions-info sdk/extensions/info.txt")
 }
 
+func TestDroidstubsFrozenInterfaces(t *testing.T) {
+	bp := `
+		droidstubs {
+			name: "bar-stubs",
+			srcs: ["bar-doc/a.java"],
+			check_api: {
+				current: {
+					api_file: "api/current.txt",
+					removed_api_file: "api/removed.txt",
+				},
+				last_released: {
+					api_file: "released/api.txt",
+					removed_api_file: "released/removed.txt",
+				},
+			},
+		}
+	`
+	preparer := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		android.FixtureMergeMockFs(android.MockFS{
+			"bar-doc/a.java":       nil,
+			"api/current.txt":      nil,
+			"api/removed.txt":      nil,
+			"released/api.txt":     nil,
+			"released/removed.txt": nil,
+		}),
+	)
+
+	result := preparer.RunTestWithBp(t, bp)
+	m := result.ModuleForTests("bar-stubs", "android_common")
+	android.AssertBoolEquals(t, "frozen API check", false, m.MaybeRule("metalavaFrozenApiCheck").Rule != nil)
+	android.AssertBoolEquals(t, "update API rule", true, m.MaybeRule("metalavaCurrentApiUpdate").Rule != nil)
+
+	result = android.GroupFixturePreparers(
+		preparer,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Frozen_interfaces = proptools.BoolPtr(true)
+		}),
+	).RunTestWithBp(t, bp)
+	m = result.ModuleForTests("bar-stubs", "android_common")
+	check := m.Rule("metalavaFrozenApiCheck")
+	android.AssertStringDoesContain(t, "frozen API check", check.RuleParams.Command,
+		"released/api.txt out/soong/.intermediates/bar-stubs/android_common/metalava/bar-stubs_api.txt")
+	android.AssertStringDoesContain(t, "frozen API check", check.RuleParams.Command,
+		"released/removed.txt out/soong/.intermediates/bar-stubs/android_common/metalava/bar-stubs_removed.txt")
+
+	// The API files are not regenerated, and the frozen API check is part of checkapi.
+	android.AssertBoolEquals(t, "update API rule", false, m.MaybeRule("metalavaCurrentApiUpdate").Rule != nil)
+	entries := android.AndroidMkEntriesForTest(t, result.TestContext, m.Module())[0]
+	mk := &strings.Builder{}
+	for _, extra := range entries.ExtraFooters {
+		extra(mk, "bar-stubs", "", "")
+	}
+	android.AssertStringDoesContain(t, "Android.mk", android.StringRelativeToTop(result.Config, mk.String()),
+		"checkapi: out/soong/.intermediates/bar-stubs/android_common/metalava/check_frozen_api.timestamp")
+}
+
 func TestApiSurfaceFromDroidStubsName(t *testing.T) {
 	testCases := []struct {
 		desc               string
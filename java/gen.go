@@ -173,7 +173,7 @@ func (j *Module) genSources(ctx android.ModuleContext, srcFiles android.Paths,
 
 	// Process all proto files together to support sharding them into one or more rules that produce srcjars.
 	if len(protoSrcs) > 0 {
-		srcJarFiles := genProto(ctx, protoSrcs, flags.proto)
+		srcJarFiles := genProto(ctx, protoSrcs, flags.proto, flags.grpcOutParams)
 		outSrcFiles = append(outSrcFiles, srcJarFiles...)
 	}
 
@@ -271,24 +271,7 @@ func hiddenAPIEncodeDex(ctx android.ModuleContext, dexInput, flagsCSV android.Pa
 		encodeRuleOutput = outputDir.Join(ctx, "unaligned", dexInput.Base())
 	}
 
-	// b/149353192: when a module is instrumented, jacoco adds synthetic members
-	// $jacocoData and $jacocoInit. Since they don't exist when building the hidden API flags,
-	// don't complain when we don't find hidden API flags for the synthetic members.
-	hiddenapiFlags := ""
-	if j, ok := ctx.Module().(interface {
-		shouldInstrument(android.BaseModuleContext) bool
-	}); ok && j.shouldInstrument(ctx) {
-		hiddenapiFlags = "--no-force-assign-all"
-	}
-
-	// If the library is targeted for Q and/or R then make sure that they do not
-	// have any S+ flags encoded as that will break the runtime.
-	minApiLevel := minSdkVersion
-	if !minApiLevel.IsNone() {
-		if minApiLevel.LessThanOrEqualTo(android.ApiLevelOrPanic(ctx, "R")) {
-			hiddenapiFlags = hiddenapiFlags + " --max-hiddenapi-level=max-target-r"
-		}
-	}
+	hiddenapiFlags := hiddenAPIEncodeFlags(ctx, minSdkVersion)
 
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        hiddenAPIEncodeDexRule,
@@ -311,6 +294,30 @@ func hiddenAPIEncodeDex(ctx android.ModuleContext, dexInput, flagsCSV android.Pa
 	return output
 }
 
+// hiddenAPIEncodeFlags returns the flags passed to hiddenapi encode for a dex jar that will be used
+// on the supplied minimum sdk version.
+func hiddenAPIEncodeFlags(ctx android.ModuleContext, minSdkVersion android.ApiLevel) string {
+	// b/149353192: when a module is instrumented, jacoco adds synthetic members
+	// $jacocoData and $jacocoInit. Since they don't exist when building the hidden API flags,
+	// don't complain when we don't find hidden API flags for the synthetic members.
+	hiddenapiFlags := ""
+	if j, ok := ctx.Module().(interface {
+		shouldInstrument(android.BaseModuleContext) bool
+	}); ok && j.shouldInstrument(ctx) {
+		hiddenapiFlags = "--no-force-assign-all"
+	}
+
+	// If the library is targeted for Q and/or R then make sure that they do not
+	// have any S+ flags encoded as that will break the runtime.
+	minApiLevel := minSdkVersion
+	if !minApiLevel.IsNone() {
+		if minApiLevel.LessThanOrEqualTo(android.ApiLevelOrPanic(ctx, "R")) {
+			hiddenapiFlags = hiddenapiFlags + " --max-hiddenapi-level=max-target-r"
+		}
+	}
+	return hiddenapiFlags
+}
+
 type hiddenApiAnnotationsDependencyTag struct {
 	blueprint.BaseDependencyTag
 	android.LicenseAnnotationSharedDependencyTag
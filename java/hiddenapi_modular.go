@@ -967,9 +967,12 @@ func pathForValidation(ctx android.PathContext, path android.WritablePath) andro
 //
 // hiddenAPIInfo is a struct containing paths to files that augment the information provided by
 // the annotationFlags.
+//
+// validations are the paths to files generated by additional rules that validate the output.
 func buildRuleToGenerateHiddenApiFlags(ctx android.BuilderContext, name, desc string,
 	outputPath android.WritablePath, baseFlagsPath android.Path, annotationFlagPaths android.Paths,
-	flagFilesByCategory FlagFilesByCategory, flagSubsets SignatureCsvSubsets, generatedRemovedDexSignatures android.OptionalPath) {
+	flagFilesByCategory FlagFilesByCategory, flagSubsets SignatureCsvSubsets, generatedRemovedDexSignatures android.OptionalPath,
+	validations android.Paths) {
 
 	// Create the rule that will generate the flag files.
 	tempPath := tempPathForRestat(ctx, outputPath)
@@ -1008,6 +1011,7 @@ func buildRuleToGenerateHiddenApiFlags(ctx android.BuilderContext, name, desc st
 		// changes but the validation will run in parallel with other rules that depend on this file.
 		command.Validation(validFile)
 	}
+	command.Validations(validations)
 
 	rule.Build(name, desc)
 }
@@ -1136,6 +1140,68 @@ func buildRuleValidateOverlappingCsvFiles(ctx android.BuilderContext, name strin
 	return validFile
 }
 
+// hiddenAPIMaxTargetFlags maps the max-target-* flags in the hidden API flags files to the highest
+// targetSdkVersion at which the members with those flags are still accessible.
+var hiddenAPIMaxTargetFlags = []struct {
+	flag       string
+	sdkVersion int
+}{
+	{"max-target-o", 27},
+	{"max-target-p", 28},
+	{"max-target-q", 29},
+	{"max-target-r", 30},
+}
+
+// expiredHiddenAPIMaxTargetFlags returns the max-target-* flags which have been supported for more
+// than the given number of releases before the platformSdkVersion.
+func expiredHiddenAPIMaxTargetFlags(platformSdkVersion, releases int) []string {
+	var expired []string
+	for _, maxTarget := range hiddenAPIMaxTargetFlags {
+		if maxTarget.sdkVersion+releases < platformSdkVersion {
+			expired = append(expired, maxTarget.flag)
+		}
+	}
+	return expired
+}
+
+// buildRuleValidateMaxTargetExpiry creates a rule that checks that the monolithic flags file does
+// not contain any member whose max-target-* flag has expired, unless the signature of that member
+// is listed in one of the allowlist files.
+//
+// Members with such flags are only accessible to apps that target an old SDK version and should be
+// removed from the flag files once that support window is over. Returns the path to the file that
+// records that the flags file is valid.
+func buildRuleValidateMaxTargetExpiry(ctx android.BuilderContext, name string, desc string,
+	monolithicFilePath android.WritablePath, expiredFlags []string, allowlists android.Paths) android.WritablePath {
+	validFile := monolithicFilePath.ReplaceExtension(ctx, "max-target-expiry.valid")
+	expiredSignatures := monolithicFilePath.ReplaceExtension(ctx, "max-target-expired.txt")
+	allowedSignatures := monolithicFilePath.ReplaceExtension(ctx, "max-target-allowed.txt")
+	unexpectedSignatures := monolithicFilePath.ReplaceExtension(ctx, "max-target-unexpected.txt")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		Text("grep -E").
+		Textf("'^[^,]+,(.*,)?(%s)(,|$)'", strings.Join(expiredFlags, "|")).
+		Input(monolithicFilePath).
+		// The exit code of grep, which is 1 when nothing matches, is ignored by the pipeline.
+		Text("| cut -d, -f1 | sort -u >").Output(expiredSignatures)
+	rule.Command().
+		Text("cat /dev/null").Inputs(allowlists).
+		Text("| sort -u >").Output(allowedSignatures)
+	rule.Command().
+		Text("comm -23").Input(expiredSignatures).Input(allowedSignatures).
+		Text(">").Output(unexpectedSignatures)
+	rule.Command().
+		Textf(`if [ -s %s ]; then`, unexpectedSignatures).
+		Textf(`echo "error: the following members have expired %s flags and must be removed from the hidden API flag files, or be added to the hidden_api_max_target_expiry.allowlist:" >&2;`,
+			strings.Join(expiredFlags, ", ")).
+		Textf(`cat %s >&2; exit 1; fi`, unexpectedSignatures)
+	rule.Command().Text("touch").Output(validFile)
+	rule.Build(name+"MaxTargetExpiry", desc+" max-target expiry")
+
+	return validFile
+}
+
 // hiddenAPIFlagRulesForBootclasspathFragment will generate all the flags for a fragment of the
 // bootclasspath.
 //
@@ -1183,7 +1249,7 @@ func hiddenAPIFlagRulesForBootclasspathFragment(ctx android.ModuleContext, bootD
 	// Generate the all-flags.csv which are the flags that will, in future, be encoded into the dex
 	// files.
 	allFlagsCSV := android.PathForModuleOut(ctx, hiddenApiSubDir, "all-flags.csv")
-	buildRuleToGenerateHiddenApiFlags(ctx, "modularHiddenApiAllFlags"+suffix, "modular hiddenapi all flags"+suffix, allFlagsCSV, stubFlagsCSV, android.Paths{annotationFlagsCSV}, input.FlagFilesByCategory, nil, removedDexSignatures)
+	buildRuleToGenerateHiddenApiFlags(ctx, "modularHiddenApiAllFlags"+suffix, "modular hiddenapi all flags"+suffix, allFlagsCSV, stubFlagsCSV, android.Paths{annotationFlagsCSV}, input.FlagFilesByCategory, nil, removedDexSignatures, nil)
 
 	// Generate the filtered-stub-flags.csv file which contains the filtered stub flags that will be
 	// compared against the monolithic stub flags.
@@ -1211,18 +1277,99 @@ func hiddenAPIFlagRulesForBootclasspathFragment(ctx android.ModuleContext, bootD
 	}
 }
 
-// hiddenAPIEncodeRulesForBootclasspathFragment generates rules to encode hidden API flags into the
+// hiddenAPIEncodeRulesForBootclasspathFragment generates a rule to encode hidden API flags into the
 // dex jars in bootDexInfoByModule.
+//
+// All the dex jars of the fragment are encoded by a single rule that runs hiddenapi once on all the
+// dex files, e.g. classes.dex, classes2.dex, etc., of the jars that need the same encoding flags,
+// instead of once per jar. As a change to any of the boot jars changes the all-flags.csv file, and
+// so requires all the jars to be encoded again, the rule only updates the encoded jars whose
+// contents changed, so that the users of the other encoded jars are not rebuilt.
 func hiddenAPIEncodeRulesForBootclasspathFragment(ctx android.ModuleContext, bootDexInfoByModule bootDexInfoByModule, allFlagsCSV android.Path) bootDexJarByModule {
-	// Encode the flags into the boot dex files.
 	encodedBootDexJarsByModule := bootDexJarByModule{}
+	if len(bootDexInfoByModule) == 0 {
+		return encodedBootDexJarsByModule
+	}
+
 	outputDir := android.PathForModuleOut(ctx, "hiddenapi-modular/encoded").OutputPath
-	for _, name := range android.SortedKeys(bootDexInfoByModule) {
+	tmpDir := android.PathForModuleOut(ctx, "hiddenapi-modular/encoded-tmp").OutputPath
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("rm -rf").Text(tmpDir.String())
+
+	// Extract the dex files of each jar into its own directory, and group the jars by the flags
+	// passed to hiddenapi.
+	names := android.SortedKeys(bootDexInfoByModule)
+	jarDirsByFlags := map[string][]string{}
+	for _, name := range names {
 		bootDexInfo := bootDexInfoByModule[name]
-		unencodedDex := bootDexInfo.path
-		encodedDex := hiddenAPIEncodeDex(ctx, unencodedDex, allFlagsCSV, bootDexInfo.uncompressDex, bootDexInfo.minSdkVersion, outputDir)
-		encodedBootDexJarsByModule[name] = encodedDex
+		jarDir := tmpDir.Join(ctx, name).String()
+		rule.Command().Text("mkdir -p").Text(jarDir + "/dex-input").Text(jarDir + "/dex-output")
+		rule.Command().
+			Text("unzip -qoDD").Input(bootDexInfo.path).
+			Text("'classes*.dex'").
+			FlagWithArg("-d ", jarDir+"/dex-input")
+
+		flags := hiddenAPIEncodeFlags(ctx, bootDexInfo.minSdkVersion)
+		jarDirsByFlags[flags] = append(jarDirsByFlags[flags], jarDir)
 	}
+
+	// Encode the dex files of all the jars that need the same flags in a single pass.
+	for _, flags := range android.SortedKeys(jarDirsByFlags) {
+		rule.Command().
+			Textf("for JAR_DIR in %s; do", strings.Join(jarDirsByFlags[flags], " ")).
+			Text(`for INPUT_DEX in $(find ${JAR_DIR}/dex-input -maxdepth 1 -name 'classes*.dex' | sort); do`).
+			Text(`echo "--input-dex=${INPUT_DEX}";`).
+			Text(`echo "--output-dex=${JAR_DIR}/dex-output/$(basename ${INPUT_DEX})";`).
+			Text("done; done |").
+			Text("xargs").BuiltTool("hiddenapi").Text("encode").
+			FlagWithInput("--api-flags=", allFlagsCSV).
+			Text(flags)
+	}
+
+	// Merge the encoded dex files with the other files of each jar, and only replace the encoded jar
+	// if it changed.
+	for _, name := range names {
+		bootDexInfo := bootDexInfoByModule[name]
+		jarDir := tmpDir.Join(ctx, name).String()
+		output := outputDir.Join(ctx, bootDexInfo.path.Base())
+
+		soongZip := rule.Command().BuiltTool("soong_zip")
+		if bootDexInfo.uncompressDex {
+			soongZip.Flag("-L 0")
+		}
+		soongZip.
+			FlagWithArg("-o ", jarDir+"/dex.jar").
+			FlagWithArg("-C ", jarDir+"/dex-output").
+			FlagWithArg("-f ", "'"+jarDir+"/dex-output/classes*.dex'")
+
+		encodedJar := jarDir + "/encoded.jar"
+		rule.Command().BuiltTool("merge_zips").
+			Flag("-j -D").
+			FlagWithArg("-zipToNotStrip ", jarDir+"/dex.jar").
+			Flag(`-stripFile "classes*.dex" -stripFile "**/*.uau"`).
+			Text(encodedJar).
+			Text(jarDir + "/dex.jar").
+			Input(bootDexInfo.path)
+
+		// If the input is uncompressed then the encoded jar needs aligning.
+		if bootDexInfo.uncompressDex {
+			alignedJar := jarDir + "/aligned.jar"
+			rule.Command().BuiltTool("zipalign").Flag("-f -p 4").Text(encodedJar).Text(alignedJar)
+			encodedJar = alignedJar
+		}
+
+		rule.Command().
+			Textf("if ! cmp -s %s", encodedJar).Output(output).Text(";").
+			Textf("then mv %s", encodedJar).Text(output.String()).Text("; fi")
+
+		encodedBootDexJarsByModule[name] = output
+	}
+
+	rule.Command().Text("rm -rf").Text(tmpDir.String())
+
+	rule.Restat()
+	rule.Build("hiddenAPIEncodeBootDexJars", "hiddenapi encode boot dex jars")
 	return encodedBootDexJarsByModule
 }
 
@@ -1348,7 +1495,7 @@ func retrieveClassesJarsFromModule(module android.Module) android.Paths {
 // Soong but should instead only be reported in ninja if the file is actually built.
 func deferReportingMissingBootDexJar(ctx android.ModuleContext, module android.Module) bool {
 	// Any missing dependency should be allowed.
-	if ctx.Config().AllowMissingDependencies() {
+	if ctx.AllowMissingDependencies() {
 		return true
 	}
 
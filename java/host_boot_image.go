@@ -0,0 +1,180 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"path/filepath"
+
+	"android/soong/android"
+	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint/proptools"
+)
+
+func init() {
+	registerHostBootImageBuildComponents(android.InitRegistrationContext)
+}
+
+func registerHostBootImageBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("host_boot_image", hostBootImageFactory)
+}
+
+// A host_boot_image module builds a primary boot image for the host from an arbitrary list of java
+// libraries, e.g. for the ART host gtests that test experimental bootclasspath layouts, instead of
+// the host variants of the boot images of the product, which mirror the device boot image configs.
+// The boot image files are installed in $ANDROID_HOST_OUT/<install_dir>/<arch>, along with the dex
+// jars that they are compiled from in $ANDROID_HOST_OUT/<install_dir>.
+type hostBootImageProperties struct {
+	// The java libraries to compile into the boot image, in the order of the bootclasspath. They
+	// must provide a dex jar, i.e. be installable or set compile_dex.
+	Jars []string
+
+	// The stem of the boot image files, defaults to "boot".
+	Stem *string
+
+	// The directory, relative to $ANDROID_HOST_OUT, where the boot image and its jars are installed,
+	// defaults to "framework/<name>".
+	Install_dir *string
+
+	// The dex2oat compiler filter of the boot image, defaults to "everything".
+	Compiler_filter *string
+}
+
+type hostBootImage struct {
+	android.ModuleBase
+
+	properties hostBootImageProperties
+}
+
+var hostBootImageJarDepTag = dependencyTag{name: "host-boot-image-jar"}
+
+func hostBootImageFactory() android.Module {
+	m := &hostBootImage{}
+	m.AddProperties(&m.properties)
+	android.InitAndroidArchModule(m, android.HostSupported, android.MultilibCommon)
+	return m
+}
+
+func (m *hostBootImage) installDir(ctx android.BaseModuleContext) string {
+	return proptools.StringDefault(m.properties.Install_dir, filepath.Join("framework", ctx.ModuleName()))
+}
+
+func (m *hostBootImage) DepsMutator(ctx android.BottomUpMutatorContext) {
+	// The boot image is compiled from the dex jars of the device variants of the libraries, like the
+	// host variants of the boot images of the product.
+	ctx.AddFarVariationDependencies(ctx.Config().AndroidCommonTarget.Variations(), hostBootImageJarDepTag,
+		m.properties.Jars...)
+
+	if dexpreopt.IsDex2oatNeeded(ctx) {
+		// Add a dependency onto the dex2oat tool which is needed for creating the boot image. The
+		// path is retrieved from the dependency by GetGlobalSoongConfig(ctx).
+		dexpreopt.RegisterToolDeps(ctx)
+	}
+}
+
+// config returns the boot image config of the module, for all the host targets, with the jars
+// expected at the given locations.
+func (m *hostBootImage) config(ctx android.ModuleContext, dexLocations []string) *bootImageConfig {
+	modules := android.EmptyConfiguredJarList()
+	for _, jar := range m.properties.Jars {
+		modules = modules.Append("platform", jar)
+	}
+
+	c := &bootImageConfig{
+		name:           ctx.ModuleName(),
+		stem:           proptools.StringDefault(m.properties.Stem, bootImageStem),
+		dir:            android.PathForModuleOut(ctx, "boot_image").OutputPath,
+		symbolsDir:     android.PathForModuleOut(ctx, "boot_image_unstripped").OutputPath,
+		installDir:     m.installDir(ctx),
+		modules:        modules,
+		compilerFilter: proptools.StringDefault(m.properties.Compiler_filter, "everything"),
+	}
+	c.dexPaths = modules.BuildPaths(ctx, android.PathForModuleOut(ctx, "boot_image_input").OutputPath)
+	c.dexPathsDeps = c.dexPaths
+
+	imageName := c.firstModuleNameOrStem(ctx) + ".art"
+	for _, target := range ctx.Config().Targets[ctx.Config().BuildOS] {
+		arch := target.Arch.ArchType
+		imageDir := c.imageDir(ctx, target, 0)
+		variant := &bootImageVariant{
+			bootImageConfig:   c,
+			target:            target,
+			compilerFilter:    c.compilerFilter,
+			dexLocations:      dexLocations,
+			dexLocationsDeps:  dexLocations,
+			imagePathOnHost:   imageDir.Join(ctx, imageName),
+			imagePathOnDevice: filepath.Join("/", c.installDir, arch.String(), imageName),
+			imagesDeps:        c.moduleFiles(ctx, target, ".art", ".oat", ".vdex"),
+		}
+		variant.componentImagePathsOnHost = android.OutputPaths{variant.imagePathOnHost}
+		variant.componentImagePathsOnDevice = []string{variant.imagePathOnDevice}
+		c.variants = append(c.variants, variant)
+	}
+	return c
+}
+
+func (m *hostBootImage) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	if len(m.properties.Jars) == 0 {
+		ctx.PropertyErrorf("jars", "a host boot image must have at least one jar")
+		return
+	}
+	if dup := android.FirstUniqueStrings(m.properties.Jars); len(dup) != len(m.properties.Jars) {
+		ctx.PropertyErrorf("jars", "a jar must not be listed more than once")
+		return
+	}
+	if !dexpreopt.IsDex2oatNeeded(ctx) {
+		return
+	}
+
+	dexJars := make(map[string]android.Path)
+	missing := false
+	ctx.VisitDirectDepsWithTag(hostBootImageJarDepTag, func(module android.Module) {
+		name := ctx.OtherModuleName(module)
+		if lib, ok := module.(UsesLibraryDependency); ok && lib.DexJarBuildPath().Valid() {
+			dexJars[android.RemoveOptionalPrebuiltPrefix(name)] = lib.DexJarBuildPath().Path()
+		} else {
+			ctx.PropertyErrorf("jars", "%q does not provide a dex jar, it must be installable or set compile_dex", name)
+			missing = true
+		}
+	})
+	if missing {
+		return
+	}
+
+	// Install the dex jars where the boot image expects them at runtime.
+	installDir := android.PathForModuleInstall(ctx, m.installDir(ctx))
+	var dexLocations []string
+	for _, jar := range m.properties.Jars {
+		installed := ctx.InstallFile(installDir, android.ModuleStem(jar)+".jar", dexJars[jar])
+		dexLocations = append(dexLocations, installed.String())
+	}
+
+	image := m.config(ctx, dexLocations)
+	for i, jar := range m.properties.Jars {
+		ctx.Build(pctx, android.BuildParams{
+			Rule:   android.Cp,
+			Input:  dexJars[jar],
+			Output: image.dexPaths[i],
+		})
+	}
+
+	for _, variant := range image.variants {
+		buildBootImageVariant(ctx, variant, nil, android.OptionalPath{})
+		variantInstallDir := installDir.Join(ctx, variant.target.Arch.ArchType.String())
+		for _, file := range variant.imagesDeps {
+			ctx.InstallFile(variantInstallDir, file.Base(), file)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestHostBootImage(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		android.FixtureWithRootAndroidBp(`
+			host_boot_image {
+				name: "test-boot-image",
+				jars: ["foo", "bar"],
+				compiler_filter: "speed",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	buildOS := result.Config.BuildOSTarget
+	image := result.ModuleForTests("test-boot-image", buildOS.Os.String()+"_common")
+
+	dex2oat := BootImageDex2oatCommandForTests(t, image, "test-boot-imageJarsDexpreopt_"+buildOS.String())
+	inputDir := "__SBOX_SANDBOX_DIR__/out/.intermediates/test-boot-image/" + buildOS.Os.String() + "_common/boot_image_input/"
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--dex-file="+inputDir+"foo.jar --dex-file="+inputDir+"bar.jar")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--dex-location=out/soong/host/linux-x86/framework/test-boot-image/foo.jar")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--compiler-filter=speed")
+
+	arch := buildOS.Arch.ArchType.String()
+	image.Output("out/soong/host/linux-x86/framework/test-boot-image/foo.jar")
+	image.Output("out/soong/host/linux-x86/framework/test-boot-image/" + arch + "/boot.art")
+	image.Output("out/soong/host/linux-x86/framework/test-boot-image/" + arch + "/boot-bar.oat")
+}
+
+func TestHostBootImageWithoutDexJar(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`jars: "foo" does not provide a dex jar`)).
+		RunTestWithBp(t, `
+			host_boot_image {
+				name: "test-boot-image",
+				jars: ["foo"],
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				installable: false,
+			}
+		`)
+}
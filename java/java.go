@@ -49,6 +49,7 @@ func registerJavaBuildComponents(ctx android.RegistrationContext) {
 	ctx.RegisterModuleType("java_library", LibraryFactory)
 	ctx.RegisterModuleType("java_library_static", LibraryStaticFactory)
 	ctx.RegisterModuleType("java_library_host", LibraryHostFactory)
+	ctx.RegisterModuleType("java_grpc_library", GrpcLibraryFactory)
 	ctx.RegisterModuleType("java_binary", BinaryFactory)
 	ctx.RegisterModuleType("java_binary_host", BinaryHostFactory)
 	ctx.RegisterModuleType("java_test", TestFactory)
@@ -791,8 +792,7 @@ type librarySdkMemberProperties struct {
 	JarToExport     android.Path `android:"arch_variant"`
 	AidlIncludeDirs android.Paths
 
-	// The list of permitted packages that need to be passed to the prebuilts as they are used to
-	// create the updatable-bcp-packages.txt file.
+	// The list of permitted packages that need to be passed to the prebuilts.
 	PermittedPackages []string
 
 	// The value of the min_sdk_version property, translated into a number where possible.
@@ -1436,6 +1436,10 @@ type binaryProperties struct {
 	// Names of modules containing JNI libraries that should be installed alongside the host
 	// variant of the binary.
 	Jni_libs []string `android:"arch_variant"`
+
+	// If true, build a Windows launcher bundle of the host binary, a zip with a .bat launcher
+	// and the jar, and distribute it with the win_sdk goal. Requires main_class or manifest.
+	Windows_launcher *bool
 }
 
 type Binary struct {
@@ -1466,6 +1470,10 @@ func (j *Binary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		}
 
 		j.Library.GenerateAndroidBuildActions(ctx)
+
+		if Bool(j.binaryProperties.Windows_launcher) {
+			j.buildWindowsLauncher(ctx)
+		}
 	} else {
 		// Handle the binary wrapper
 		j.isWrapperVariant = true
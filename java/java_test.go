@@ -942,6 +942,34 @@ func TestGeneratedSources(t *testing.T) {
 	}
 }
 
+func TestCodegenModuleSources(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		genrule.PrepareForTestWithCodegenPlugin,
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: [
+				"a.java",
+				":foo_idl",
+			],
+		}
+
+		test_codegen {
+			name: "foo_idl",
+			interfaces: ["foo", "bar"],
+			src_extension: "java",
+		}
+	`)
+
+	javac := result.ModuleForTests("foo", "android_common").Rule("javac")
+	android.AssertPathsRelativeToTopEquals(t, "javac inputs", []string{
+		"a.java",
+		"out/soong/.intermediates/foo_idl/gen/foo.java",
+		"out/soong/.intermediates/foo_idl/gen/bar.java",
+	}, javac.Inputs)
+}
+
 func TestTurbine(t *testing.T) {
 	result := android.GroupFixturePreparers(
 		prepareForJavaTest, FixtureWithPrebuiltApis(map[string][]string{"14": {"foo"}})).
@@ -0,0 +1,119 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// When SOONG_JAVAC_DIAGNOSTICS=true, each javac action writes its errors and warnings next to its
+// output jar as <jar name>.diagnostics.jsonl, with soong_javac_wrapper --diagnostics, one JSON
+// object per line, including when the compilation fails. The javac_diagnostics singleton
+// concatenates the files of all the actions into out/soong/javac_diagnostics.jsonl, built by
+// `m javac-diagnostics`, for CI annotations and IDE problem views. The report does not depend on
+// the javac actions, so that it also contains the errors of the actions that failed: it is rebuilt
+// every time it is requested, from the files that exist, and so must be requested after the build,
+// e.g. with `m javac-diagnostics` once `m` has finished or failed.
+//
+// Only the javac actions are covered. The kotlinc and turbine actions do not run through
+// soong_javac_wrapper, and the format of their output differs from that of javac.
+
+var javacDiagnosticsReportRule = pctx.AndroidStaticRule("javacDiagnosticsReport",
+	blueprint.RuleParams{
+		Command: `while read f; do if [ -f "$$f" ]; then cat "$$f"; fi; done < $in > $out`,
+	})
+
+func init() {
+	registerJavacDiagnosticsBuildComponents(android.InitRegistrationContext)
+}
+
+func registerJavacDiagnosticsBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterSingletonType("javac_diagnostics", javacDiagnosticsSingletonFactory)
+}
+
+var PrepareForTestWithJavacDiagnostics = android.FixtureRegisterWithContext(registerJavacDiagnosticsBuildComponents)
+
+func javacDiagnosticsEnabled(config android.Config) bool {
+	return config.IsEnvTrue("SOONG_JAVAC_DIAGNOSTICS")
+}
+
+type javacDiagnosticsFiles struct {
+	sync.Mutex
+	files android.Paths
+}
+
+var javacDiagnosticsFilesKey = android.NewOnceKey("javacDiagnosticsFiles")
+
+func getJavacDiagnosticsFiles(config android.Config) *javacDiagnosticsFiles {
+	return config.Once(javacDiagnosticsFilesKey, func() interface{} {
+		return &javacDiagnosticsFiles{}
+	}).(*javacDiagnosticsFiles)
+}
+
+// registerJavacDiagnosticsFile records the diagnostics file of a javac action for the build-level
+// report.
+func registerJavacDiagnosticsFile(config android.Config, file android.Path) {
+	d := getJavacDiagnosticsFiles(config)
+	d.Lock()
+	defer d.Unlock()
+	d.files = append(d.files, file)
+}
+
+func javacDiagnosticsSingletonFactory() android.Singleton {
+	return &javacDiagnosticsSingleton{}
+}
+
+type javacDiagnosticsSingleton struct{}
+
+func (javacDiagnosticsSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if !javacDiagnosticsEnabled(ctx.Config()) {
+		return
+	}
+
+	d := getJavacDiagnosticsFiles(ctx.Config())
+	d.Lock()
+	files := android.CopyOfPaths(d.files)
+	d.Unlock()
+	if len(files) == 0 {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].String() < files[j].String() })
+
+	list := android.PathForOutput(ctx, "javac_diagnostics.list")
+	android.WriteFileRule(ctx, list, strings.Join(files.Strings(), "\n"))
+
+	// A phony without inputs is always out of date, and so are the actions that depend on it.
+	force := android.PathForPhony(ctx, "javac-diagnostics-force")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:   blueprint.Phony,
+		Output: force,
+	})
+
+	report := android.PathForOutput(ctx, "javac_diagnostics.jsonl")
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        javacDiagnosticsReportRule,
+		Input:       list,
+		Implicit:    force,
+		Output:      report,
+		Description: "javac diagnostics report",
+	})
+	ctx.Phony("javac-diagnostics", report)
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+func TestJavacDiagnostics(t *testing.T) {
+	bp := `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["b.java"],
+		}
+	`
+
+	t.Run("enabled", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			prepareForJavaTest,
+			PrepareForTestWithJavacDiagnostics,
+			android.FixtureMergeEnv(map[string]string{
+				"SOONG_JAVAC_DIAGNOSTICS": "true",
+			}),
+		).RunTestWithBp(t, bp)
+
+		javac := result.ModuleForTests("foo", "android_common").Rule("javac")
+		diagnostics := "out/soong/.intermediates/foo/android_common/javac/foo.diagnostics.jsonl"
+		android.AssertPathsRelativeToTopEquals(t, "javac implicit outputs", []string{diagnostics},
+			javac.ImplicitOutputs.Paths())
+		android.AssertStringEquals(t, "javac diagnostics flag", "--diagnostics "+diagnostics+" ",
+			android.StringRelativeToTop(result.Config, javac.Args["javacDiagnostics"]))
+		android.AssertStringEquals(t, "empty javac diagnostics", ": > "+diagnostics+" && ",
+			android.StringRelativeToTop(result.Config, javac.Args["emptyJavacDiagnostics"]))
+
+		// The report is created from the list of the diagnostics files rather than from the files, so
+		// that it does not depend on the javac actions.
+		singleton := result.SingletonForTests("javac_diagnostics")
+		list := singleton.Output("javac_diagnostics.list")
+		android.AssertStringEquals(t, "diagnostics files",
+			"out/soong/.intermediates/bar/android_common/javac/bar.diagnostics.jsonl\n"+diagnostics,
+			android.StringRelativeToTop(result.Config, android.ContentFromFileRuleForTests(t, list)))
+		report := singleton.Output("javac_diagnostics.jsonl")
+		android.AssertPathsRelativeToTopEquals(t, "report inputs", []string{"out/soong/javac_diagnostics.list"},
+			report.Inputs)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			prepareForJavaTest,
+			PrepareForTestWithJavacDiagnostics,
+		).RunTestWithBp(t, bp)
+
+		javac := result.ModuleForTests("foo", "android_common").Rule("javac")
+		android.AssertIntEquals(t, "javac implicit outputs", 0, len(javac.ImplicitOutputs))
+		android.AssertStringEquals(t, "javac diagnostics flag", "", javac.Args["javacDiagnostics"])
+		android.AssertStringEquals(t, "empty javac diagnostics", "", javac.Args["emptyJavacDiagnostics"])
+
+		report := result.SingletonForTests("javac_diagnostics").MaybeOutput("javac_diagnostics.jsonl")
+		if report.Rule != nil {
+			t.Errorf("unexpected javac diagnostics report")
+		}
+	})
+}
@@ -34,11 +34,11 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
 			` --out_dir "$classesDir" --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
 			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
-			`${config.KotlincCmd} ${config.KotlincGlobalFlags} ` +
+			`$kotlincCmd ${config.KotlincGlobalFlags} ` +
 			` ${config.KotlincSuppressJDK9Warnings} ${config.JavacHeapFlags} ` +
 			` $kotlincFlags -jvm-target $kotlinJvmTarget -Xbuild-file=$kotlinBuildFile ` +
 			` -kotlin-home $emptyDir ` +
-			` -Xplugin=${config.KotlinAbiGenPluginJar} ` +
+			` -Xplugin=$kotlinAbiGenPluginJar ` +
 			` -P plugin:org.jetbrains.kotlin.jvm.abi:outputDir=$headerClassesDir && ` +
 			`${config.SoongZipCmd} -jar -o $out -C $classesDir -D $classesDir -write_if_changed && ` +
 			`${config.SoongZipCmd} -jar -o $headerJar -C $headerClassesDir -D $headerClassesDir -write_if_changed && ` +
@@ -62,7 +62,8 @@ var kotlinc = pctx.AndroidRemoteStaticRule("kotlinc", android.RemoteRuleSupports
 		Restat:         true,
 	},
 	"kotlincFlags", "classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "classesDir",
-	"headerClassesDir", "headerJar", "kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name")
+	"headerClassesDir", "headerJar", "kotlinJvmTarget", "kotlinBuildFile", "emptyDir", "name",
+	"kotlincCmd", "kotlinAbiGenPluginJar")
 
 func kotlinCommonSrcsList(ctx android.ModuleContext, commonSrcFiles android.Paths) android.OptionalPath {
 	if len(commonSrcFiles) > 0 {
@@ -109,18 +110,20 @@ func kotlinCompile(ctx android.ModuleContext, outputFile, headerOutputFile andro
 		Inputs:         srcFiles,
 		Implicits:      deps,
 		Args: map[string]string{
-			"classpath":         flags.kotlincClasspath.FormJavaClassPath(""),
-			"kotlincFlags":      flags.kotlincFlags,
-			"commonSrcFilesArg": commonSrcFilesArg,
-			"srcJars":           strings.Join(srcJars.Strings(), " "),
-			"classesDir":        android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
-			"headerClassesDir":  android.PathForModuleOut(ctx, "kotlinc", "header_classes").String(),
-			"headerJar":         headerOutputFile.String(),
-			"srcJarDir":         android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
-			"kotlinBuildFile":   android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
-			"emptyDir":          android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
-			"kotlinJvmTarget":   flags.javaVersion.StringForKotlinc(),
-			"name":              kotlinName,
+			"classpath":             flags.kotlincClasspath.FormJavaClassPath(""),
+			"kotlincFlags":          flags.kotlincFlags,
+			"commonSrcFilesArg":     commonSrcFilesArg,
+			"srcJars":               strings.Join(srcJars.Strings(), " "),
+			"classesDir":            android.PathForModuleOut(ctx, "kotlinc", "classes").String(),
+			"headerClassesDir":      android.PathForModuleOut(ctx, "kotlinc", "header_classes").String(),
+			"headerJar":             headerOutputFile.String(),
+			"srcJarDir":             android.PathForModuleOut(ctx, "kotlinc", "srcJars").String(),
+			"kotlinBuildFile":       android.PathForModuleOut(ctx, "kotlinc-build.xml").String(),
+			"emptyDir":              android.PathForModuleOut(ctx, "kotlinc", "empty").String(),
+			"kotlinJvmTarget":       flags.javaVersion.StringForKotlinc(),
+			"name":                  kotlinName,
+			"kotlincCmd":            flags.kotlincToolchain.cmd,
+			"kotlinAbiGenPluginJar": flags.kotlincToolchain.abiGenPluginJar,
 		},
 	})
 }
@@ -133,9 +136,9 @@ var kaptStubs = pctx.AndroidRemoteStaticRule("kaptStubs", android.RemoteRuleSupp
 			`${config.GenKotlinBuildFileCmd} --classpath "$classpath" --name "$name"` +
 			` --srcs "$out.rsp" --srcs "$srcJarDir/list"` +
 			` $commonSrcFilesArg --out "$kotlinBuildFile" && ` +
-			`${config.KotlincCmd} ${config.KotlincGlobalFlags} ` +
+			`$kotlincCmd ${config.KotlincGlobalFlags} ` +
 			`${config.KaptSuppressJDK9Warnings} ${config.KotlincSuppressJDK9Warnings} ` +
-			`${config.JavacHeapFlags} $kotlincFlags -Xplugin=${config.KotlinKaptJar} ` +
+			`${config.JavacHeapFlags} $kotlincFlags -Xplugin=$kotlinKaptJar ` +
 			`-P plugin:org.jetbrains.kotlin.kapt3:sources=$kaptDir/sources ` +
 			`-P plugin:org.jetbrains.kotlin.kapt3:classes=$kaptDir/classes ` +
 			`-P plugin:org.jetbrains.kotlin.kapt3:stubs=$kaptDir/stubs ` +
@@ -160,7 +163,7 @@ var kaptStubs = pctx.AndroidRemoteStaticRule("kaptStubs", android.RemoteRuleSupp
 	},
 	"kotlincFlags", "encodedJavacFlags", "kaptProcessorPath", "kaptProcessor",
 	"classpath", "srcJars", "commonSrcFilesArg", "srcJarDir", "kaptDir", "kotlinJvmTarget",
-	"kotlinBuildFile", "name", "classesJarOut")
+	"kotlinBuildFile", "name", "classesJarOut", "kotlincCmd", "kotlinKaptJar")
 
 // kotlinKapt performs Kotlin-compatible annotation processing.  It takes .kt and .java sources and srcjars, and runs
 // annotation processors over all of them, producing a srcjar of generated code in outputFile.  The srcjar should be
@@ -225,6 +228,8 @@ func kotlinKapt(ctx android.ModuleContext, srcJarOutputFile, resJarOutputFile an
 			"encodedJavacFlags": encodedJavacFlags,
 			"name":              kotlinName,
 			"classesJarOut":     resJarOutputFile.String(),
+			"kotlincCmd":        flags.kotlincToolchain.cmd,
+			"kotlinKaptJar":     flags.kotlincToolchain.kaptJar,
 		},
 	})
 
@@ -389,3 +389,97 @@ func TestKotlinCompose(t *testing.T) {
 	android.AssertStringDoesNotContain(t, "unexpected compose compiler plugin",
 		noCompose.VariablesForTestsRelativeToTop()["kotlincFlags"], "-Xplugin="+composeCompiler.String())
 }
+
+func TestKotlinVersion(t *testing.T) {
+	ctx, _ := testJava(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.kt"],
+			kotlin_version: "1.7.20",
+		}
+
+		java_library {
+			name: "bar",
+			srcs: ["a.kt"],
+		}
+	`)
+
+	fooKotlinc := ctx.ModuleForTests("foo", "android_common").Rule("kotlinc")
+	android.AssertStringEquals(t, "foo kotlinc", "prebuilts/kotlinc/1.7.20/bin/kotlinc",
+		fooKotlinc.Args["kotlincCmd"])
+	android.AssertStringEquals(t, "foo jvm-abi-gen", "prebuilts/kotlinc/1.7.20/lib/jvm-abi-gen.jar",
+		fooKotlinc.Args["kotlinAbiGenPluginJar"])
+	android.AssertStringListContains(t, "foo kotlinc implicits", fooKotlinc.Implicits.Strings(),
+		"prebuilts/kotlinc/1.7.20/lib/kotlin-compiler.jar")
+
+	barKotlinc := ctx.ModuleForTests("bar", "android_common").Rule("kotlinc")
+	android.AssertStringEquals(t, "bar kotlinc", "${config.KotlincCmd}", barKotlinc.Args["kotlincCmd"])
+
+	testJavaError(t, `module "foo".*kotlin_version: "1.2.3" is not one of the prebuilt kotlinc versions`, `
+		java_library {
+			name: "foo",
+			srcs: ["a.kt"],
+			kotlin_version: "1.2.3",
+		}
+	`)
+}
+
+func TestKotlinStdlibVersion(t *testing.T) {
+	t.Run("pinned", func(t *testing.T) {
+		ctx, _ := testJava(t, `
+			java_library {
+				name: "foo",
+				srcs: ["a.kt"],
+				kotlin_stdlib_version: "1.7.20",
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["a.kt"],
+				static_libs: ["foo"],
+				kotlin_stdlib_version: "1.7.20",
+			}
+		`)
+
+		stdlib := ctx.ModuleForTests("kotlin-stdlib", "android_common").
+			Output("turbine-combined/kotlin-stdlib.jar").Output
+
+		fooJar := ctx.ModuleForTests("foo", "android_common").Output("combined/foo.jar")
+		android.AssertStringListContains(t, "foo jar inputs", fooJar.Inputs.Strings(),
+			"prebuilts/kotlinc/1.7.20/lib/kotlin-stdlib.jar")
+		android.AssertStringListDoesNotContain(t, "foo jar inputs", fooJar.Inputs.Strings(), stdlib.String())
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		testJavaError(t, `module "bar".*conflicting kotlin stdlib versions on the classpath: 1.7.20 \(pinned by "foo"\), 1.8.10 \(pinned by "bar"\)`, `
+			java_library {
+				name: "foo",
+				srcs: ["a.kt"],
+				kotlin_stdlib_version: "1.7.20",
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["a.kt"],
+				libs: ["foo"],
+				kotlin_stdlib_version: "1.8.10",
+			}
+		`)
+	})
+
+	t.Run("conflict with the default stdlib", func(t *testing.T) {
+		testJavaError(t, `module "bar".*conflicting kotlin stdlib versions on the classpath: the default kotlin stdlib \(used by "bar"\), 1.7.20 \(pinned by "foo"\)`, `
+			java_library {
+				name: "foo",
+				srcs: ["a.kt"],
+				kotlin_stdlib_version: "1.7.20",
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["a.kt"],
+				static_libs: ["foo"],
+			}
+		`)
+	})
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+	"android/soong/java/config"
+)
+
+// This file contains support for the kotlin_version and kotlin_stdlib_version properties, which let
+// the libraries shared with unbundled builds stay on a vetted version of the Kotlin compiler and of
+// the Kotlin stdlib while the platform ones are updated. The versions are restricted to those of
+// the prebuilt toolchains in prebuilts/kotlinc, whose stdlib jars are used instead of the
+// kotlin-stdlib modules, and a module must not have more than one version of the stdlib on its
+// classpath, counting the default kotlin stdlib as a version of its own.
+
+// kotlincToolchain is the kotlinc toolchain that compiles the Kotlin sources of a module.
+type kotlincToolchain struct {
+	cmd             string
+	abiGenPluginJar string
+	kaptJar         string
+}
+
+var defaultKotlincToolchain = kotlincToolchain{
+	cmd:             "${config.KotlincCmd}",
+	abiGenPluginJar: "${config.KotlinAbiGenPluginJar}",
+	kaptJar:         "${config.KotlinKaptJar}",
+}
+
+// checkKotlinVersion reports an error on the property if the version is not one of the prebuilt
+// kotlinc versions.
+func checkKotlinVersion(ctx android.BaseModuleContext, property, version string) bool {
+	if !android.InList(version, config.KotlincPrebuiltVersions) {
+		ctx.PropertyErrorf(property, "%q is not one of the prebuilt kotlinc versions %s", version,
+			strings.Join(config.KotlincPrebuiltVersions, ", "))
+		return false
+	}
+	return true
+}
+
+// kotlincToolchain returns the kotlinc toolchain selected by the kotlin_version of the module, and
+// the files of the toolchain that the kotlinc rules depend on in addition to those of the default
+// toolchain.
+func (j *Module) kotlincToolchain(ctx android.ModuleContext) (kotlincToolchain, android.Paths) {
+	version := String(j.properties.Kotlin_version)
+	if version == "" || !checkKotlinVersion(ctx, "kotlin_version", version) {
+		return defaultKotlincToolchain, nil
+	}
+
+	dir := config.KotlincPrebuiltDir(version)
+	cmd := android.PathForSource(ctx, dir, "bin", "kotlinc")
+	abiGenPluginJar := android.PathForSource(ctx, dir, "lib", "jvm-abi-gen.jar")
+	kaptJar := android.PathForSource(ctx, dir, "lib", "kotlin-annotation-processing.jar")
+	deps := android.Paths{cmd, abiGenPluginJar, kaptJar}
+	for _, jar := range []string{"kotlin-compiler.jar", "kotlin-preloader.jar", "kotlin-reflect.jar",
+		"kotlin-script-runtime.jar", "kotlin-stdlib.jar", "trove4j.jar", "annotations-13.0.jar"} {
+		deps = append(deps, android.PathForSource(ctx, dir, "lib", jar))
+	}
+	return kotlincToolchain{
+		cmd:             cmd.String(),
+		abiGenPluginJar: abiGenPluginJar.String(),
+		kaptJar:         kaptJar.String(),
+	}, deps
+}
+
+// kotlinStdlibVersion returns the kotlin_stdlib_version of the module, or an empty string if the
+// module uses the default kotlin stdlib.
+func (j *Module) kotlinStdlibVersion(ctx android.BaseModuleContext) string {
+	version := String(j.properties.Kotlin_stdlib_version)
+	if version == "" || !checkKotlinVersion(ctx, "kotlin_stdlib_version", version) {
+		return ""
+	}
+	return version
+}
+
+// kotlinPrebuiltStdlibJars returns the jars of the kotlin stdlib of the prebuilt kotlinc toolchain of
+// the given version, which replace the kotlin-stdlib modules of the default kotlin stdlib.
+func kotlinPrebuiltStdlibJars(ctx android.PathContext, version string) android.Paths {
+	dir := config.KotlincPrebuiltDir(version)
+	var jars android.Paths
+	for _, jar := range []string{"kotlin-stdlib.jar", "kotlin-stdlib-jdk7.jar", "kotlin-stdlib-jdk8.jar"} {
+		jars = append(jars, android.PathForSource(ctx, dir, "lib", jar))
+	}
+	return jars
+}
+
+// KotlinStdlibInfo is provided by the java modules with Kotlin sources, or that statically include a
+// module that has some.
+type KotlinStdlibInfo struct {
+	// The versions of the kotlin stdlib, with the name of a module that uses each of them. The default
+	// kotlin stdlib is recorded with an empty version.
+	PinnedBy map[string]string
+}
+
+// kotlinStdlibDescription returns the description of the given version of the kotlin stdlib and of
+// the module that uses it, for error messages.
+func kotlinStdlibDescription(version, module string) string {
+	if version == "" {
+		return fmt.Sprintf("the default kotlin stdlib (used by %q)", module)
+	}
+	return fmt.Sprintf("%s (pinned by %q)", version, module)
+}
+
+var KotlinStdlibInfoProvider = blueprint.NewProvider(KotlinStdlibInfo{})
+
+// checkKotlinStdlibVersions reports an error if more than one version of the kotlin stdlib is on the
+// classpath of the module, i.e. used by the module itself or by its libs and static_libs, where the
+// default kotlin stdlib counts as a version of its own, and provides the versions used by the module
+// and its static_libs to its reverse dependencies.
+func (j *Module) checkKotlinStdlibVersions(ctx android.ModuleContext) {
+	exported := make(map[string]string)
+	if j.hasSrcExt(".kt") {
+		exported[j.kotlinStdlibVersion(ctx)] = ctx.ModuleName()
+	}
+
+	classpath := make(map[string]string)
+	for version, pinnedBy := range exported {
+		classpath[version] = pinnedBy
+	}
+	ctx.VisitDirectDeps(func(module android.Module) {
+		tag := ctx.OtherModuleDependencyTag(module)
+		if tag != libTag && tag != sdkLibTag && tag != staticLibTag {
+			return
+		}
+		if !ctx.OtherModuleHasProvider(module, KotlinStdlibInfoProvider) {
+			return
+		}
+		info := ctx.OtherModuleProvider(module, KotlinStdlibInfoProvider).(KotlinStdlibInfo)
+		for _, version := range android.SortedKeys(info.PinnedBy) {
+			if _, ok := classpath[version]; !ok {
+				classpath[version] = info.PinnedBy[version]
+			}
+			if _, ok := exported[version]; !ok && tag == staticLibTag {
+				exported[version] = info.PinnedBy[version]
+			}
+		}
+	})
+
+	if len(classpath) > 1 {
+		var pins []string
+		for _, version := range android.SortedKeys(classpath) {
+			pins = append(pins, kotlinStdlibDescription(version, classpath[version]))
+		}
+		ctx.ModuleErrorf("conflicting kotlin stdlib versions on the classpath: %s", strings.Join(pins, ", "))
+	}
+	if len(exported) > 0 {
+		ctx.SetProvider(KotlinStdlibInfoProvider, KotlinStdlibInfo{PinnedBy: exported})
+	}
+}
@@ -16,6 +16,7 @@ package java
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -99,6 +100,7 @@ type lintOutputs struct {
 	html              android.Path
 	text              android.Path
 	xml               android.Path
+	sarif             android.Path
 	referenceBaseline android.Path
 
 	depSets LintDepSets
@@ -117,25 +119,27 @@ type LintDepSetsIntf interface {
 }
 
 type LintDepSets struct {
-	HTML, Text, XML *android.DepSet
+	HTML, Text, XML, SARIF *android.DepSet
 }
 
 type LintDepSetsBuilder struct {
-	HTML, Text, XML *android.DepSetBuilder
+	HTML, Text, XML, SARIF *android.DepSetBuilder
 }
 
 func NewLintDepSetBuilder() LintDepSetsBuilder {
 	return LintDepSetsBuilder{
-		HTML: android.NewDepSetBuilder(android.POSTORDER),
-		Text: android.NewDepSetBuilder(android.POSTORDER),
-		XML:  android.NewDepSetBuilder(android.POSTORDER),
+		HTML:  android.NewDepSetBuilder(android.POSTORDER),
+		Text:  android.NewDepSetBuilder(android.POSTORDER),
+		XML:   android.NewDepSetBuilder(android.POSTORDER),
+		SARIF: android.NewDepSetBuilder(android.POSTORDER),
 	}
 }
 
-func (l LintDepSetsBuilder) Direct(html, text, xml android.Path) LintDepSetsBuilder {
+func (l LintDepSetsBuilder) Direct(html, text, xml, sarif android.Path) LintDepSetsBuilder {
 	l.HTML.Direct(html)
 	l.Text.Direct(text)
 	l.XML.Direct(xml)
+	l.SARIF.Direct(sarif)
 	return l
 }
 
@@ -149,14 +153,18 @@ func (l LintDepSetsBuilder) Transitive(depSets LintDepSets) LintDepSetsBuilder {
 	if depSets.XML != nil {
 		l.XML.Transitive(depSets.XML)
 	}
+	if depSets.SARIF != nil {
+		l.SARIF.Transitive(depSets.SARIF)
+	}
 	return l
 }
 
 func (l LintDepSetsBuilder) Build() LintDepSets {
 	return LintDepSets{
-		HTML: l.HTML.Build(),
-		Text: l.Text.Build(),
-		XML:  l.XML.Build(),
+		HTML:  l.HTML.Build(),
+		Text:  l.Text.Build(),
+		XML:   l.XML.Build(),
+		SARIF: l.SARIF.Build(),
 	}
 }
 
@@ -314,6 +322,12 @@ func (l *linter) writeLintProjectXML(ctx android.ModuleContext, rule *android.Ru
 	cmd.FlagWithInput("@",
 		android.PathForSource(ctx, "build/soong/java/lint_defaults.txt"))
 
+	// The severity policies of the tree go first, so that the checks that the module is required to
+	// treat as errors and its own checks override them.
+	for _, policy := range lintSeverityPoliciesForDir(ctx.Config(), ctx.ModuleDir()) {
+		cmd.FlagForEachArg("--"+policy.severity+"_check ", policy.checks)
+	}
+
 	if l.compileSdkKind == android.SdkPublic {
 		cmd.FlagForEachArg("--error_check ", l.extraMainlineLintErrors)
 	} else {
@@ -451,9 +465,10 @@ func (l *linter) lint(ctx android.ModuleContext) {
 	html := android.PathForModuleOut(ctx, "lint", "lint-report.html")
 	text := android.PathForModuleOut(ctx, "lint", "lint-report.txt")
 	xml := android.PathForModuleOut(ctx, "lint", "lint-report.xml")
+	sarif := android.PathForModuleOut(ctx, "lint", "lint-report.sarif")
 	referenceBaseline := android.PathForModuleOut(ctx, "lint", "lint-baseline.xml")
 
-	depSetsBuilder := NewLintDepSetBuilder().Direct(html, text, xml)
+	depSetsBuilder := NewLintDepSetBuilder().Direct(html, text, xml, sarif)
 
 	ctx.VisitDirectDepsWithTag(staticLibTag, func(dep android.Module) {
 		if depLint, ok := dep.(LintDepSetsIntf); ok {
@@ -463,7 +478,7 @@ func (l *linter) lint(ctx android.ModuleContext) {
 
 	rule.Command().Text("rm -rf").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
 	rule.Command().Text("mkdir -p").Flag(lintPaths.cacheDir.String()).Flag(lintPaths.homeDir.String())
-	rule.Command().Text("rm -f").Output(html).Output(text).Output(xml)
+	rule.Command().Text("rm -f").Output(html).Output(text).Output(xml).Output(sarif)
 
 	files, ok := allLintDatabasefiles[l.compileSdkKind]
 	if !ok {
@@ -492,6 +507,7 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		FlagWithOutput("--html ", html).
 		FlagWithOutput("--text ", text).
 		FlagWithOutput("--xml ", xml).
+		FlagWithOutput("--sarif ", sarif).
 		FlagWithArg("--compile-sdk-version ", strconv.Itoa(l.compileSdkVersion)).
 		FlagWithArg("--java-language-level ", l.javaLanguageLevel).
 		FlagWithArg("--kotlin-language-level ", l.kotlinLanguageLevel).
@@ -542,6 +558,7 @@ func (l *linter) lint(ctx android.ModuleContext) {
 		html:              html,
 		text:              text,
 		xml:               xml,
+		sarif:             sarif,
 		referenceBaseline: referenceBaseline,
 
 		depSets: depSetsBuilder.Build(),
@@ -550,14 +567,110 @@ func (l *linter) lint(ctx android.ModuleContext) {
 	if l.buildModuleReportZip {
 		l.reports = BuildModuleLintReportZips(ctx, l.LintDepSets())
 	}
+
+	l.lintBaselineRules(ctx, lintBaseline, referenceBaseline)
+}
+
+// lintBaselineRules generates the rules of the targets that manage the lint baseline of the module:
+// <module>-update-lint-baseline replaces the checked-in baseline with the issues that lint finds
+// (which also requires ANDROID_LINT_SUPPRESS_EXIT_CODE to baseline new errors), and
+// <module>-lint-baseline-ratchet, also built by lint-baseline-ratchet, fails if the checked-in
+// baseline lists issues that have been fixed since, so that the baselines only ever shrink.
+func (l *linter) lintBaselineRules(ctx android.ModuleContext, lintBaseline android.OptionalPath,
+	referenceBaseline android.Path) {
+
+	baselineFile := filepath.Join(ctx.ModuleDir(),
+		proptools.StringDefault(l.properties.Lint.Baseline_filename, "lint-baseline.xml"))
+	if lintBaseline.Valid() {
+		baselineFile = lintBaseline.String()
+	}
+
+	updateTimestamp := android.PathForModuleOut(ctx, "lint", "update_lint_baseline.timestamp")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().Text("cp").Flag("-f").Input(referenceBaseline).Flag(baselineFile)
+	rule.Command().Text("touch").Output(updateTimestamp)
+	rule.Build("lintBaselineUpdate", "update lint baseline")
+	ctx.Phony(ctx.ModuleName()+"-update-lint-baseline", updateTimestamp)
+
+	if !lintBaseline.Valid() {
+		return
+	}
+
+	ratchetTimestamp := android.PathForModuleOut(ctx, "lint", "lint_baseline_ratchet.timestamp")
+	rule = android.NewRuleBuilder(pctx, ctx)
+	rule.Command().BuiltTool("lint_baseline_ratchet").
+		FlagWithInput("--baseline ", lintBaseline.Path()).
+		FlagWithInput("--reference ", referenceBaseline).
+		FlagWithArg("--name ", ctx.ModuleName())
+	rule.Command().Text("touch").Output(ratchetTimestamp)
+	rule.Build("lintBaselineRatchet", "check lint baseline")
+	ctx.Phony(ctx.ModuleName()+"-lint-baseline-ratchet", ratchetTimestamp)
+	ctx.Phony("lint-baseline-ratchet", ratchetTimestamp)
+}
+
+// lintSeverityPolicy overrides the severity of lint checks for the modules in a tree.
+type lintSeverityPolicy struct {
+	dir      string
+	severity string
+	checks   []string
+}
+
+// lintSeverities maps the severities of the lint severity policies to the check flags of
+// lint_project_xml.
+var lintSeverities = map[string]string{
+	"fatal":    "fatal",
+	"error":    "error",
+	"warning":  "warning",
+	"disabled": "disable",
+}
+
+// parseLintSeverityPolicies parses the "<dir>:<severity>:<check>[,<check>...]" entries of the
+// Lint_severity_policies product variable, sorted so that the policies of the subdirectories of a
+// tree come after, and override, the policy of the tree.
+func parseLintSeverityPolicies(entries []string) ([]lintSeverityPolicy, error) {
+	var policies []lintSeverityPolicy
+	for _, entry := range entries {
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid lint severity policy %q, expected <dir>:<severity>:<check>[,<check>...]", entry)
+		}
+		severity, ok := lintSeverities[parts[1]]
+		if !ok {
+			return nil, fmt.Errorf("invalid severity %q in lint severity policy %q, expected one of %q",
+				parts[1], entry, android.SortedKeys(lintSeverities))
+		}
+		policies = append(policies, lintSeverityPolicy{
+			dir:      strings.TrimSuffix(parts[0], "/"),
+			severity: severity,
+			checks:   strings.Split(parts[2], ","),
+		})
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return len(policies[i].dir) < len(policies[j].dir)
+	})
+	return policies, nil
+}
+
+// lintSeverityPoliciesForDir returns the lint severity policies that apply to the modules in dir.
+// The invalid policies are reported by the lint singleton.
+func lintSeverityPoliciesForDir(config android.Config, dir string) []lintSeverityPolicy {
+	policies, _ := parseLintSeverityPolicies(config.LintSeverityPolicies())
+	var ret []lintSeverityPolicy
+	for _, policy := range policies {
+		if dir == policy.dir || strings.HasPrefix(dir, policy.dir+"/") {
+			ret = append(ret, policy)
+		}
+	}
+	return ret
 }
 
 func BuildModuleLintReportZips(ctx android.ModuleContext, depSets LintDepSets) android.Paths {
 	htmlList := depSets.HTML.ToSortedList()
 	textList := depSets.Text.ToSortedList()
 	xmlList := depSets.XML.ToSortedList()
+	sarifList := depSets.SARIF.ToSortedList()
 
-	if len(htmlList) == 0 && len(textList) == 0 && len(xmlList) == 0 {
+	if len(htmlList) == 0 && len(textList) == 0 && len(xmlList) == 0 && len(sarifList) == 0 {
 		return nil
 	}
 
@@ -570,17 +683,24 @@ func BuildModuleLintReportZips(ctx android.ModuleContext, depSets LintDepSets) a
 	xmlZip := android.PathForModuleOut(ctx, "lint-report-xml.zip")
 	lintZip(ctx, xmlList, xmlZip)
 
-	return android.Paths{htmlZip, textZip, xmlZip}
+	sarifZip := android.PathForModuleOut(ctx, "lint-report-sarif.zip")
+	lintZip(ctx, sarifList, sarifZip)
+
+	return android.Paths{htmlZip, textZip, xmlZip, sarifZip}
 }
 
 type lintSingleton struct {
 	htmlZip              android.WritablePath
 	textZip              android.WritablePath
 	xmlZip               android.WritablePath
+	sarifZip             android.WritablePath
 	referenceBaselineZip android.WritablePath
 }
 
 func (l *lintSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	if _, err := parseLintSeverityPolicies(ctx.Config().LintSeverityPolicies()); err != nil {
+		ctx.Errorf("Lint_severity_policies: %s", err)
+	}
 	l.generateLintReportZips(ctx)
 	l.copyLintDependencies(ctx)
 }
@@ -608,19 +728,30 @@ func (l *lintSingleton) copyLintDependencies(ctx android.SingletonContext) {
 	for _, sdk := range android.SortedKeys(allLintDatabasefiles) {
 		files := allLintDatabasefiles[sdk]
 		apiVersionsDb := findModuleOrErr(ctx, files.apiVersionsModule)
+		sdkAnnotations := findModuleOrErr(ctx, files.annotationsModule)
+		var missing []string
 		if apiVersionsDb == nil {
-			if !ctx.Config().AllowMissingDependencies() {
-				ctx.Errorf("lint: missing module api_versions_public")
-			}
-			return
+			missing = append(missing, files.apiVersionsModule)
 		}
-
-		sdkAnnotations := findModuleOrErr(ctx, files.annotationsModule)
 		if sdkAnnotations == nil {
-			if !ctx.Config().AllowMissingDependencies() {
-				ctx.Errorf("lint: missing module sdk-annotations.zip")
+			missing = append(missing, files.annotationsModule)
+		}
+		if len(missing) > 0 {
+			if !ctx.Config().DeferMissingModuleErrors() {
+				ctx.Errorf("lint: missing module(s) %q", missing)
+				return
 			}
-			return
+			// Fail the lint of the modules that use the databases instead.
+			for _, name := range []string{files.apiVersionsCopiedName, files.annotationCopiedName} {
+				ctx.Build(pctx, android.BuildParams{
+					Rule:   android.ErrorRule,
+					Output: copiedLintDatabaseFilesPath(ctx, name),
+					Args: map[string]string{
+						"error": fmt.Sprintf("lint: missing module(s) %q", missing),
+					},
+				})
+			}
+			continue
 		}
 
 		ctx.Build(pctx, android.BuildParams{
@@ -690,15 +821,18 @@ func (l *lintSingleton) generateLintReportZips(ctx android.SingletonContext) {
 	l.xmlZip = android.PathForOutput(ctx, "lint-report-xml.zip")
 	zip(l.xmlZip, func(l *lintOutputs) android.Path { return l.xml })
 
+	l.sarifZip = android.PathForOutput(ctx, "lint-report-sarif.zip")
+	zip(l.sarifZip, func(l *lintOutputs) android.Path { return l.sarif })
+
 	l.referenceBaselineZip = android.PathForOutput(ctx, "lint-report-reference-baselines.zip")
 	zip(l.referenceBaselineZip, func(l *lintOutputs) android.Path { return l.referenceBaseline })
 
-	ctx.Phony("lint-check", l.htmlZip, l.textZip, l.xmlZip, l.referenceBaselineZip)
+	ctx.Phony("lint-check", l.htmlZip, l.textZip, l.xmlZip, l.sarifZip, l.referenceBaselineZip)
 }
 
 func (l *lintSingleton) MakeVars(ctx android.MakeVarsContext) {
 	if !ctx.Config().UnbundledBuild() {
-		ctx.DistForGoal("lint-check", l.htmlZip, l.textZip, l.xmlZip, l.referenceBaselineZip)
+		ctx.DistForGoal("lint-check", l.htmlZip, l.textZip, l.xmlZip, l.sarifZip, l.referenceBaselineZip)
 	}
 }
 
@@ -282,3 +282,80 @@ func TestJavaLintDatabaseSelectionFull(t *testing.T) {
 		}
 	}
 }
+
+func TestJavaLintSarifAndBaselineTargets(t *testing.T) {
+	ctx, _ := testJavaWithFS(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			min_sdk_version: "29",
+			sdk_version: "system_current",
+		}
+       `, map[string][]byte{
+		"lint-baseline.xml": nil,
+	})
+
+	foo := ctx.ModuleForTests("foo", "android_common")
+
+	sboxProto := android.RuleBuilderSboxProtoForTests(t, foo.Output("lint.sbox.textproto"))
+	android.AssertStringDoesContain(t, "lint command", *sboxProto.Commands[0].Command, "--sarif ")
+	sarif := foo.Module().(*Library).LintDepSets().SARIF.ToList()
+	android.AssertPathsRelativeToTopEquals(t, "sarif reports",
+		[]string{"out/soong/.intermediates/foo/android_common/lint/lint-report.sarif"}, sarif)
+
+	update := foo.Rule("lintBaselineUpdate")
+	android.AssertStringDoesContain(t, "update command", update.RuleParams.Command,
+		"cp -f out/soong/.intermediates/foo/android_common/lint/lint-baseline.xml lint-baseline.xml")
+
+	ratchet := foo.Rule("lintBaselineRatchet")
+	android.AssertStringDoesContain(t, "ratchet command", ratchet.RuleParams.Command,
+		"--baseline lint-baseline.xml")
+	android.AssertStringDoesContain(t, "ratchet command", ratchet.RuleParams.Command, "--name foo")
+}
+
+func TestJavaLintSeverityPolicies(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Lint_severity_policies = []string{
+				"vendor/acme:fatal:MissingPermission,UnusedResources",
+				"vendor:disabled:UnusedResources",
+			}
+		}),
+		android.FixtureAddTextFile("vendor/acme/Android.bp", `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				min_sdk_version: "29",
+				sdk_version: "system_current",
+				lint: {
+					warning_checks: ["MissingPermission"],
+				},
+			}
+		`),
+	).RunTest(t)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	sboxProto := android.RuleBuilderSboxProtoForTests(t, foo.Output("lint.sbox.textproto"))
+	command := *sboxProto.Commands[0].Command
+
+	// The policy of vendor/acme overrides the policy of vendor, and the checks of the module override
+	// both.
+	disabled := strings.Index(command, "--disable_check UnusedResources")
+	fatal := strings.Index(command, "--fatal_check MissingPermission --fatal_check UnusedResources")
+	warning := strings.Index(command, "--warning_check MissingPermission")
+	if disabled < 0 || fatal < 0 || warning < 0 || !(disabled < fatal && fatal < warning) {
+		t.Errorf("expected the vendor, vendor/acme and module checks in order, got %q", command)
+	}
+}
+
+func TestJavaLintSeverityPoliciesErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithJavaDefaultModules,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Lint_severity_policies = []string{"vendor:informational:NewApi"}
+		}),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`invalid severity "informational" in lint severity policy "vendor:informational:NewApi"`)).
+		RunTest(t)
+}
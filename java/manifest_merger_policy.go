@@ -0,0 +1,250 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+func init() {
+	registerManifestMergerPolicyBuildComponents(android.InitRegistrationContext)
+}
+
+func registerManifestMergerPolicyBuildComponents(ctx android.RegistrationContext) {
+	ctx.RegisterModuleType("manifest_merger_policy", ManifestMergerPolicyFactory)
+	ctx.PreArchMutators(func(ctx android.RegisterMutatorsContext) {
+		ctx.BottomUp("manifest_merger_policies", manifestMergerPoliciesMutator).Parallel()
+	})
+}
+
+type manifestMergerPolicyProperties struct {
+	// Directories of the android_app modules that the policy applies to, relative to the root of
+	// the source tree. The policy also applies to the apps in their subdirectories.
+	Paths []string
+
+	// Permissions that the apps may request with <uses-permission> or <uses-permission-sdk-23>.
+	// If unset, the apps may request any permission.
+	Allowed_permissions []string
+
+	// Bounds of the min_sdk_version of the apps.
+	Min_sdk_version sdkVersionBounds
+
+	// Bounds of the target_sdk_version of the apps.
+	Target_sdk_version sdkVersionBounds
+
+	// Elements that the merged manifests of the apps may not contain, as paths of tags from the
+	// <manifest> tag, e.g. "application/provider".
+	Forbidden_elements []string
+}
+
+type sdkVersionBounds struct {
+	// The lowest allowed SDK version.
+	Min *string
+
+	// The highest allowed SDK version.
+	Max *string
+}
+
+// check reports an error on the property if the SDK version is out of bounds.
+func (b sdkVersionBounds) check(ctx android.ModuleContext, policy, property string, version android.ApiLevel) {
+	if b.Min != nil {
+		if min, err := android.ApiLevelFromUser(ctx, *b.Min); err == nil && version.LessThan(min) {
+			ctx.PropertyErrorf(property, "%s is lower than %s allowed by the manifest policy %q", version, min, policy)
+		}
+	}
+	if b.Max != nil {
+		if max, err := android.ApiLevelFromUser(ctx, *b.Max); err == nil && version.GreaterThan(max) {
+			ctx.PropertyErrorf(property, "%s is higher than %s allowed by the manifest policy %q", version, max, policy)
+		}
+	}
+}
+
+// validate reports an error on the property if the bounds are not valid API levels.
+func (b sdkVersionBounds) validate(ctx android.ModuleContext, property string) {
+	for _, bound := range []*string{b.Min, b.Max} {
+		if bound == nil {
+			continue
+		}
+		if _, err := android.ApiLevelFromUser(ctx, *bound); err != nil {
+			ctx.PropertyErrorf(property, "%s", err.Error())
+		}
+	}
+}
+
+// manifest_merger_policy declares the policy that the manifests of the android_app modules in the
+// given paths must comply with: the permissions they may request, the bounds of their
+// min_sdk_version and target_sdk_version and the elements they may not declare. The policy is
+// checked against the merged manifests of the apps, so it also covers the manifests of their
+// static libraries.
+//
+// Example:
+//
+//	manifest_merger_policy {
+//	    name: "vendor_apps_policy",
+//	    paths: ["vendor/acme/apps"],
+//	    allowed_permissions: ["android.permission.INTERNET"],
+//	    target_sdk_version: {
+//	        min: "33",
+//	    },
+//	    forbidden_elements: ["application/provider"],
+//	}
+func ManifestMergerPolicyFactory() android.Module {
+	module := &ManifestMergerPolicy{}
+	module.AddProperties(&module.properties)
+	android.InitAndroidModule(module)
+	return module
+}
+
+type ManifestMergerPolicy struct {
+	android.ModuleBase
+
+	properties manifestMergerPolicyProperties
+}
+
+// ManifestMergerPolicyInfo contains the policy of a manifest_merger_policy module, for the apps
+// that it applies to.
+type ManifestMergerPolicyInfo struct {
+	// The policy in the JSON format expected by manifest_check.
+	PolicyFile android.Path
+
+	minSdkVersion    sdkVersionBounds
+	targetSdkVersion sdkVersionBounds
+}
+
+var ManifestMergerPolicyInfoProvider = blueprint.NewProvider(ManifestMergerPolicyInfo{})
+
+func (p *ManifestMergerPolicy) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	p.properties.Min_sdk_version.validate(ctx, "min_sdk_version")
+	p.properties.Target_sdk_version.validate(ctx, "target_sdk_version")
+
+	policy := struct {
+		Name               string
+		AllowedPermissions []string
+		ForbiddenElements  []string
+	}{
+		Name:               ctx.ModuleName(),
+		AllowedPermissions: p.properties.Allowed_permissions,
+		ForbiddenElements:  p.properties.Forbidden_elements,
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		ctx.ModuleErrorf("failed to marshal the manifest policy: %s", err)
+		return
+	}
+
+	policyFile := android.PathForModuleOut(ctx, "manifest_policy.json")
+	android.WriteFileRule(ctx, policyFile, string(data))
+
+	ctx.SetProvider(ManifestMergerPolicyInfoProvider, ManifestMergerPolicyInfo{
+		PolicyFile:       policyFile,
+		minSdkVersion:    p.properties.Min_sdk_version,
+		targetSdkVersion: p.properties.Target_sdk_version,
+	})
+}
+
+var manifestMergerPoliciesKey = android.NewOnceKey("manifestMergerPolicies")
+
+// manifestMergerPolicies maps the directories in the paths property of the manifest_merger_policy
+// modules to the names of the modules.
+type manifestMergerPolicies struct {
+	sync.Mutex
+	dirs map[string][]string
+}
+
+func getManifestMergerPolicies(config android.Config) *manifestMergerPolicies {
+	return config.Once(manifestMergerPoliciesKey, func() interface{} {
+		return &manifestMergerPolicies{dirs: make(map[string][]string)}
+	}).(*manifestMergerPolicies)
+}
+
+// manifestMergerPoliciesMutator records the paths of the manifest_merger_policy modules, so that
+// the apps in the paths can add dependencies on them.
+func manifestMergerPoliciesMutator(ctx android.BottomUpMutatorContext) {
+	p, ok := ctx.Module().(*ManifestMergerPolicy)
+	if !ok {
+		return
+	}
+	policies := getManifestMergerPolicies(ctx.Config())
+	policies.Lock()
+	defer policies.Unlock()
+	for _, dir := range p.properties.Paths {
+		dir = filepath.Clean(dir)
+		policies.dirs[dir] = append(policies.dirs[dir], ctx.ModuleName())
+	}
+}
+
+// policiesForDir returns the names of the manifest_merger_policy modules that apply to the
+// modules in the directory.
+func (p *manifestMergerPolicies) policiesForDir(dir string) []string {
+	p.Lock()
+	defer p.Unlock()
+	var names []string
+	for policyDir, policyNames := range p.dirs {
+		if dir == policyDir || strings.HasPrefix(dir, policyDir+"/") {
+			names = append(names, policyNames...)
+		}
+	}
+	sort.Strings(names)
+	return android.FirstUniqueStrings(names)
+}
+
+type manifestMergerPolicyDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var manifestMergerPolicyTag = manifestMergerPolicyDependencyTag{}
+
+// manifestMergerPolicyDeps adds dependencies on the manifest_merger_policy modules that apply to
+// the app.
+func manifestMergerPolicyDeps(ctx android.BottomUpMutatorContext) {
+	policies := getManifestMergerPolicies(ctx.Config()).policiesForDir(ctx.ModuleDir())
+	ctx.AddDependency(ctx.Module(), manifestMergerPolicyTag, policies...)
+}
+
+// checkManifestMergerPolicies checks the app against the manifest_merger_policy modules that apply
+// to it, and returns the outputs of the checks of the merged manifest.
+func (a *AndroidApp) checkManifestMergerPolicies(ctx android.ModuleContext) android.Paths {
+	var checks android.Paths
+	rule := android.NewRuleBuilder(pctx, ctx)
+	ctx.VisitDirectDepsWithTag(manifestMergerPolicyTag, func(m android.Module) {
+		if !ctx.OtherModuleHasProvider(m, ManifestMergerPolicyInfoProvider) {
+			return
+		}
+		policy := ctx.OtherModuleName(m)
+		info := ctx.OtherModuleProvider(m, ManifestMergerPolicyInfoProvider).(ManifestMergerPolicyInfo)
+
+		info.minSdkVersion.check(ctx, policy, "min_sdk_version", a.MinSdkVersion(ctx))
+		info.targetSdkVersion.check(ctx, policy, "target_sdk_version", a.TargetSdkVersion(ctx))
+
+		check := android.PathForModuleOut(ctx, "manifest_policy", policy, "AndroidManifest.xml")
+		rule.Command().BuiltTool("manifest_check").
+			FlagWithInput("--manifest-policy ", info.PolicyFile).
+			Input(a.mergedManifestFile).
+			FlagWithOutput("-o ", check)
+		checks = append(checks, check)
+	})
+	if len(checks) > 0 {
+		rule.Build("manifest_policy", "check manifest policies")
+	}
+	return checks
+}
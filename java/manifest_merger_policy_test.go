@@ -0,0 +1,99 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+var prepareForManifestMergerPolicyTest = android.GroupFixturePreparers(
+	prepareForJavaTest,
+	android.FixtureAddTextFile("policies/Android.bp", `
+		manifest_merger_policy {
+			name: "acme_policy",
+			paths: ["vendor/acme"],
+			allowed_permissions: ["android.permission.INTERNET"],
+			min_sdk_version: {
+				min: "29",
+			},
+			target_sdk_version: {
+				min: "30",
+				max: "31",
+			},
+			forbidden_elements: ["application/provider"],
+		}
+	`),
+)
+
+func TestManifestMergerPolicy(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForManifestMergerPolicyTest,
+		android.FixtureAddTextFile("vendor/acme/apps/foo/Android.bp", `
+			android_app {
+				name: "foo",
+				srcs: ["a.java"],
+				sdk_version: "current",
+				min_sdk_version: "29",
+				target_sdk_version: "30",
+			}
+		`),
+		android.FixtureAddTextFile("other/Android.bp", `
+			android_app {
+				name: "bar",
+				srcs: ["a.java"],
+				sdk_version: "current",
+				min_sdk_version: "21",
+			}
+		`),
+	).RunTest(t)
+
+	foo := result.ModuleForTests("foo", "android_common")
+	check := foo.Output("manifest_policy/acme_policy/AndroidManifest.xml")
+	android.AssertStringDoesContain(t, "manifest policy check", check.RuleParams.Command,
+		"--manifest-policy out/soong/.intermediates/policies/acme_policy/manifest_policy.json")
+	android.AssertStringListContains(t, "apk deps", foo.Output("foo-unsigned.apk").Implicits.Strings(),
+		"out/soong/.intermediates/vendor/acme/apps/foo/foo/android_common/manifest_policy/acme_policy/AndroidManifest.xml")
+
+	policy := result.ModuleForTests("acme_policy", "").Output("manifest_policy.json")
+	android.AssertStringEquals(t, "policy",
+		`{"Name":"acme_policy","AllowedPermissions":["android.permission.INTERNET"],"ForbiddenElements":["application/provider"]}`,
+		android.ContentFromFileRuleForTests(t, policy))
+
+	// The policy does not apply to apps outside of its paths.
+	bar := result.ModuleForTests("bar", "android_common")
+	if check := bar.MaybeOutput("manifest_policy/acme_policy/AndroidManifest.xml"); check.Rule != nil {
+		t.Errorf("unexpected manifest policy check for bar")
+	}
+}
+
+func TestManifestMergerPolicySdkVersionBounds(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForManifestMergerPolicyTest,
+		android.FixtureAddTextFile("vendor/acme/Android.bp", `
+			android_app {
+				name: "foo",
+				srcs: ["a.java"],
+				sdk_version: "current",
+				min_sdk_version: "28",
+				target_sdk_version: "32",
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`min_sdk_version: 28 is lower than 29 allowed by the manifest policy "acme_policy"`,
+		`target_sdk_version: 32 is higher than 31 allowed by the manifest policy "acme_policy"`,
+	})).RunTest(t)
+}
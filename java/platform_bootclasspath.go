@@ -19,6 +19,8 @@ import (
 
 	"android/soong/android"
 	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint"
 )
 
 func init() {
@@ -35,6 +37,13 @@ var (
 	platformBootclasspathArtBootJarDepTag  = bootclasspathDependencyTag{name: "art-boot-jar"}
 	platformBootclasspathBootJarDepTag     = bootclasspathDependencyTag{name: "platform-boot-jar"}
 	platformBootclasspathApexBootJarDepTag = bootclasspathDependencyTag{name: "apex-boot-jar"}
+
+	// The jars of the boot image extensions declared with boot_image_extension modules.
+	platformBootclasspathBootImageExtensionJarDepTag = bootclasspathDependencyTag{name: "boot-image-extension-jar"}
+
+	// The system server jars delivered by APEXes, whose packages are listed in
+	// updatable-bcp-packages.txt along with those of the apex boot jars.
+	platformBootclasspathApexSystemServerJarDepTag = bootclasspathDependencyTag{name: "apex-system-server-jar"}
 )
 
 type platformBootclasspathModule struct {
@@ -43,12 +52,18 @@ type platformBootclasspathModule struct {
 
 	properties platformBootclasspathProperties
 
+	// The CPU overrides of the framework boot image.
+	cpuProperties bootImageCpuProperties
+
 	// The apex:module pairs obtained from the configured modules.
 	configuredModules []android.Module
 
 	// The apex:module pairs obtained from the fragments.
 	fragments []android.Module
 
+	// The modules of the boot image extensions declared with boot_image_extension modules.
+	bootImageExtensionModules []android.Module
+
 	// Path to the monolithic hiddenapi-flags.csv file.
 	hiddenAPIFlagsCSV android.OutputPath
 
@@ -63,13 +78,29 @@ type platformBootclasspathProperties struct {
 	BootclasspathFragmentsDepsProperties
 
 	HiddenAPIFlagFileProperties
+
+	Hidden_api_max_target_expiry struct {
+		// The number of platform releases for which members flagged as max-target-<X> remain in the
+		// hidden API flags. Once the platform SDK version exceeds the SDK version of <X> by more than
+		// this then the build fails until the members are removed from the flag files. If not set
+		// then the check is disabled.
+		Releases *int
+
+		// Files listing, one per line, the signatures of members that are allowed to keep an expired
+		// max-target-* flag.
+		Allowlist []string `android:"path"`
+	}
 }
 
 func platformBootclasspathFactory() android.SingletonModule {
 	m := &platformBootclasspathModule{}
-	m.AddProperties(&m.properties)
+	m.AddProperties(&m.properties, &m.cpuProperties)
 	initClasspathFragment(m, BOOTCLASSPATH)
 	android.InitAndroidArchModule(m, android.DeviceSupported, android.MultilibCommon)
+	android.AddLoadHook(m, func(ctx android.LoadHookContext) {
+		// The platform_bootclasspath module builds the framework boot images.
+		registerBootImageModule(ctx, "")
+	})
 	return m
 }
 
@@ -110,6 +141,14 @@ func (b *platformBootclasspathModule) DepsMutator(ctx android.BottomUpMutatorCon
 	// Add a dependency onto the dex2oat tool which is needed for creating the boot image. The
 	// path is retrieved from the dependency by GetGlobalSoongConfig(ctx).
 	dexpreopt.RegisterToolDeps(ctx)
+	addBootImageRbeToolDeps(ctx)
+
+	// Add dependencies onto the dex2oat_config modules that the product sets for the boot images.
+	addBootImageDex2oatConfigDeps(ctx, ctx.Config().BootImageDex2oatConfigImages()...)
+
+	// Add a dependency onto the dexdump tool which is needed for checking the freshness of the boot
+	// image profile.
+	addBootImageProfileFreshnessDeps(ctx)
 }
 
 func (b *platformBootclasspathModule) hiddenAPIDepsMutator(ctx android.BottomUpMutatorContext) {
@@ -135,6 +174,33 @@ func (b *platformBootclasspathModule) BootclasspathDepsMutator(ctx android.Botto
 	apexJars := dexpreopt.GetGlobalConfig(ctx).ApexBootJars
 	addDependenciesOntoBootImageModules(ctx, apexJars, platformBootclasspathApexBootJarDepTag)
 
+	// Add dependencies on the system server jars delivered by APEXes, for their packages. There is no
+	// dependency on the jars that do not exist, they are reported by the systemserverclasspath
+	// modules.
+	apexSystemServerJars := dexpreopt.GetGlobalConfig(ctx).AllApexSystemServerJars(ctx)
+	for i := 0; i < apexSystemServerJars.Len(); i++ {
+		variations := append([]blueprint.Variation{{Mutator: "apex", Variation: apexSystemServerJars.Apex(i)}},
+			ctx.Module().Target().Variations()...)
+		name := apexSystemServerJars.Jar(i)
+		for _, dep := range []string{name, android.PrebuiltNameFromSource(name)} {
+			if ctx.OtherModuleDependencyVariantExists(variations, dep) {
+				ctx.AddFarVariationDependencies(variations, platformBootclasspathApexSystemServerJarDepTag, dep)
+			}
+		}
+	}
+
+	// Add dependencies on all the jars of the boot image extensions.
+	imageConfigs := genBootImageConfigs(ctx)
+	for _, name := range bootImageExtensionNames(ctx) {
+		addDependenciesOntoBootImageModules(ctx, imageConfigs[name].modules, platformBootclasspathBootImageExtensionJarDepTag)
+	}
+
+	// Add dependencies on the modules that declare the boot image extensions, for their CPU overrides.
+	ctx.AddDependency(ctx.Module(), bootImageExtensionDepTag, bootImageExtensionModuleNames(ctx)...)
+
+	// Add dependencies on the profiles that the product merges into the framework boot image profile.
+	ctx.AddDependency(ctx.Module(), bootImageProfileDepTag, ctx.Config().BootImageProfileModules()...)
+
 	// Add dependencies on all the fragments.
 	b.properties.BootclasspathFragmentsDepsProperties.addDependenciesOntoFragments(ctx)
 }
@@ -175,6 +241,7 @@ func (b *platformBootclasspathModule) GenerateAndroidBuildActions(ctx android.Mo
 
 	// Gather all the fragments dependencies.
 	b.fragments = gatherApexModulePairDepsWithTag(ctx, bootclasspathFragmentDepTag)
+	b.bootImageExtensionModules = gatherApexModulePairDepsWithTag(ctx, platformBootclasspathBootImageExtensionJarDepTag)
 
 	// Check the configuration of the boot modules.
 	// ART modules are checked by the art-bootclasspath-fragment.
@@ -186,7 +253,11 @@ func (b *platformBootclasspathModule) GenerateAndroidBuildActions(ctx android.Mo
 	bootDexJarByModule := b.generateHiddenAPIBuildActions(ctx, b.configuredModules, b.fragments)
 	buildRuleForBootJarsPackageCheck(ctx, bootDexJarByModule)
 
-	b.generateBootImageBuildActions(ctx)
+	checkBootImageCpuProperties(ctx, &b.cpuProperties)
+	provideBootImageCpuOverrides(ctx, defaultBootImageConfig(ctx).name, &b.cpuProperties)
+
+	apexSystemServerModules := gatherApexModulePairDepsWithTag(ctx, platformBootclasspathApexSystemServerJarDepTag)
+	b.generateBootImageBuildActions(ctx, android.Concat(apexModules, apexSystemServerModules))
 	b.copyApexBootJarsForAppsDexpreopt(ctx, apexModules)
 }
 
@@ -218,7 +289,7 @@ func (b *platformBootclasspathModule) configuredJars(ctx android.ModuleContext)
 }
 
 func (b *platformBootclasspathModule) platformJars(ctx android.PathContext) android.ConfiguredJarList {
-	return defaultBootImageConfig(ctx).modules.RemoveList(artBootImageConfig(ctx).modules)
+	return dexpreopt.GetGlobalConfig(ctx).BootJars.RemoveList(artBootImageConfig(ctx).modules)
 }
 
 // checkPlatformModules ensures that the non-updatable modules supplied are not part of an
@@ -323,7 +394,8 @@ func (b *platformBootclasspathModule) generateHiddenAPIBuildActions(ctx android.
 	allAnnotationFlagFiles := android.Paths{annotationFlags}
 	allAnnotationFlagFiles = append(allAnnotationFlagFiles, monolithicInfo.AnnotationFlagsPaths...)
 	allFlags := hiddenAPISingletonPaths(ctx).flags
-	buildRuleToGenerateHiddenApiFlags(ctx, "hiddenAPIFlagsFile", "monolithic hidden API flags", allFlags, stubFlags, allAnnotationFlagFiles, monolithicInfo.FlagsFilesByCategory, monolithicInfo.FlagSubsets, android.OptionalPath{})
+	validations := b.maxTargetExpiryValidations(ctx, allFlags)
+	buildRuleToGenerateHiddenApiFlags(ctx, "hiddenAPIFlagsFile", "monolithic hidden API flags", allFlags, stubFlags, allAnnotationFlagFiles, monolithicInfo.FlagsFilesByCategory, monolithicInfo.FlagSubsets, android.OptionalPath{}, validations)
 
 	// Generate an intermediate monolithic hiddenapi-metadata.csv file directly from the annotations
 	// in the source code.
@@ -359,6 +431,29 @@ func (b *platformBootclasspathModule) generateHiddenAPIBuildActions(ctx android.
 	return bootDexJarByModule
 }
 
+// maxTargetExpiryValidations returns the validations that check that the monolithic hidden API
+// flags do not contain any expired max-target-* flags.
+func (b *platformBootclasspathModule) maxTargetExpiryValidations(ctx android.ModuleContext, allFlags android.WritablePath) android.Paths {
+	expiry := b.properties.Hidden_api_max_target_expiry
+	if expiry.Releases == nil {
+		return nil
+	}
+	if *expiry.Releases < 0 {
+		ctx.PropertyErrorf("hidden_api_max_target_expiry.releases", "must not be negative, found %d", *expiry.Releases)
+		return nil
+	}
+
+	platformSdkVersion := ctx.Config().PlatformSdkVersion().FinalOrFutureInt()
+	expiredFlags := expiredHiddenAPIMaxTargetFlags(platformSdkVersion, *expiry.Releases)
+	if len(expiredFlags) == 0 {
+		return nil
+	}
+
+	allowlists := android.PathsForModuleSrc(ctx, expiry.Allowlist)
+	validFile := buildRuleValidateMaxTargetExpiry(ctx, "hiddenAPIFlagsFile", "monolithic hidden API flags", allFlags, expiredFlags, allowlists)
+	return android.Paths{validFile}
+}
+
 // createAndProvideMonolithicHiddenAPIInfo creates a MonolithicHiddenAPIInfo and provides it for
 // testing.
 func (b *platformBootclasspathModule) createAndProvideMonolithicHiddenAPIInfo(ctx android.ModuleContext, classpathElements ClasspathElements) MonolithicHiddenAPIInfo {
@@ -399,8 +494,10 @@ func (b *platformBootclasspathModule) generateHiddenApiMakeVars(ctx android.Make
 	ctx.Strict("INTERNAL_PLATFORM_HIDDENAPI_FLAGS", b.hiddenAPIFlagsCSV.String())
 }
 
-// generateBootImageBuildActions generates ninja rules related to the boot image creation.
-func (b *platformBootclasspathModule) generateBootImageBuildActions(ctx android.ModuleContext) {
+// generateBootImageBuildActions generates ninja rules related to the boot image creation. The
+// packages of the updatable modules, i.e. the apex boot jars and system server jars, are passed to
+// dex2oat in updatable-bcp-packages.txt when compiling the framework boot image.
+func (b *platformBootclasspathModule) generateBootImageBuildActions(ctx android.ModuleContext, updatableModules []android.Module) {
 	// Force the GlobalSoongConfig to be created and cached for use by the dex_bootjars
 	// GenerateSingletonBuildActions method as it cannot create it for itself.
 	dexpreopt.GetGlobalSoongConfig(ctx)
@@ -410,24 +507,69 @@ func (b *platformBootclasspathModule) generateBootImageBuildActions(ctx android.
 		return
 	}
 
+	checkBootImageEnableUffdGc(ctx)
+	checkBootImageDex2oatHeapSizes(ctx)
+	checkBootImageDex2oatConfigs(ctx)
+	checkSystemExtBootImage(ctx)
+
+	imageNames := []string{frameworkBootImageName, mainlineBootImageName}
+	if _, ok := genBootImageConfigs(ctx)[systemExtBootImageName]; ok {
+		imageNames = append(imageNames, systemExtBootImageName)
+	}
+	if _, ok := genBootImageConfigs(ctx)[uninstrumentedBootImageName]; ok {
+		imageNames = append(imageNames, uninstrumentedBootImageName)
+	}
+	imageNames = append(imageNames, bootImageExtensionNames(ctx)...)
+	images := make(map[string]*bootImageInstallInfo)
+	for _, name := range imageNames {
+		images[name] = &bootImageInstallInfo{}
+	}
+
 	frameworkBootImageConfig := defaultBootImageConfig(ctx)
-	bootFrameworkProfileRule(ctx, frameworkBootImageConfig)
-	b.generateBootImage(ctx, frameworkBootImageName)
-	b.generateBootImage(ctx, mainlineBootImageName)
+	bootFrameworkProfile, bootFrameworkProfileInstalls := bootFrameworkProfileRule(ctx, frameworkBootImageConfig)
+	images[frameworkBootImageName].addProfileInstalls(ctx, bootFrameworkProfileInstalls)
+	if bootFrameworkProfile != nil {
+		images[frameworkBootImageName].bprof = bootFrameworkProfile
+	}
+	// updatable-bcp-packages.txt is installed along with the framework boot image profiles.
+	updatableBcpPackages, updatableBcpPackagesInstalls := updatableBcpPackagesRule(ctx, frameworkBootImageConfig, updatableModules)
+	images[frameworkBootImageName].addProfileInstalls(ctx, updatableBcpPackagesInstalls)
+	for _, name := range imageNames {
+		// Only the framework boot image is compiled with updatable-bcp-packages.txt.
+		imageUpdatableBcpPackages := android.OptionalPath{}
+		if name == frameworkBootImageName {
+			imageUpdatableBcpPackages = updatableBcpPackages
+		}
+		b.generateBootImage(ctx, name, images[name], imageUpdatableBcpPackages)
+	}
 	dumpOatRules(ctx, frameworkBootImageConfig)
+
+	ctx.SetProvider(BootImageInfoProvider, BootImageInfo{images: images})
 }
 
-func (b *platformBootclasspathModule) generateBootImage(ctx android.ModuleContext, imageName string) {
+// generateBootImage generates the rules to build the boot image and records the files to install
+// in installInfo.
+func (b *platformBootclasspathModule) generateBootImage(ctx android.ModuleContext, imageName string, installInfo *bootImageInstallInfo,
+	updatableBcpPackages android.OptionalPath) {
 	imageConfig := genBootImageConfigs(ctx)[imageName]
 
 	modules := b.getModulesForImage(ctx, imageConfig)
 
 	// Copy module dex jars to their predefined locations.
-	bootDexJarsByModule := extractEncodedDexJarsFromModules(ctx, modules)
+	var bootDexJarsByModule bootDexJarByModule
+	if imageName == uninstrumentedBootImageName {
+		bootDexJarsByModule = extractUninstrumentedDexJarsFromModules(ctx, modules)
+	} else {
+		bootDexJarsByModule = extractEncodedDexJarsFromModules(ctx, modules)
+	}
 	copyBootJarsToPredefinedLocations(ctx, bootDexJarsByModule, imageConfig.dexPathsByModule)
 
 	// Build a profile for the image config and then use that to build the boot image.
-	profile := bootImageProfileRule(ctx, imageConfig)
+	profile, profileInstalls := bootImageProfileRule(ctx, imageConfig)
+	installInfo.addProfileInstalls(ctx, profileInstalls)
+	if profile != nil {
+		installInfo.profile = profile
+	}
 
 	// If dexpreopt of boot image jars should be skipped, generate only a profile.
 	global := dexpreopt.GetGlobalConfig(ctx)
@@ -435,14 +577,24 @@ func (b *platformBootclasspathModule) generateBootImage(ctx android.ModuleContex
 		return
 	}
 
+	// The non-instrumented boot image of coverage builds is built but not installed, as the device
+	// boots the instrumented one, see boot_image_coverage.go.
+	installable := imageName != uninstrumentedBootImageName
+
 	// Build boot image files for the android variants.
-	androidBootImageFiles := buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile)
+	androidBootImageFiles := buildBootImageVariantsForAndroidOs(ctx, imageConfig, profile, updatableBcpPackages)
+	if installable {
+		installInfo.addVariants(ctx, androidBootImageFiles)
+	}
 
 	// Zip the android variant boot image files up.
-	buildBootImageZipInPredefinedLocation(ctx, imageConfig, androidBootImageFiles.byArch)
+	installInfo.zip, installInfo.deltaMetadata = buildBootImageZipInPredefinedLocation(ctx, imageConfig, androidBootImageFiles.byArch)
 
 	// Build boot image files for the host variants. There are use directly by ART host side tests.
-	buildBootImageVariantsForBuildOs(ctx, imageConfig, profile)
+	hostBootImageFiles := buildBootImageVariantsForBuildOs(ctx, imageConfig, profile, updatableBcpPackages)
+	if installable {
+		installInfo.addVariants(ctx, hostBootImageFiles)
+	}
 }
 
 // Copy apex module dex jars to their predefined locations. They will be used for dexpreopt for apps.
@@ -453,10 +605,11 @@ func (b *platformBootclasspathModule) copyApexBootJarsForAppsDexpreopt(ctx andro
 }
 
 func (b *platformBootclasspathModule) getModulesForImage(ctx android.ModuleContext, imageConfig *bootImageConfig) []android.Module {
+	candidates := android.Concat(b.configuredModules, b.bootImageExtensionModules)
 	modules := make([]android.Module, 0, imageConfig.modules.Len())
 	for i := 0; i < imageConfig.modules.Len(); i++ {
 		found := false
-		for _, module := range b.configuredModules {
+		for _, module := range candidates {
 			name := android.RemoveOptionalPrebuiltPrefix(module.Name())
 			if name == imageConfig.modules.Jar(i) {
 				modules = append(modules, module)
@@ -464,7 +617,7 @@ func (b *platformBootclasspathModule) getModulesForImage(ctx android.ModuleConte
 				break
 			}
 		}
-		if !found && !ctx.Config().AllowMissingDependencies() {
+		if !found && !ctx.AllowMissingDependencies() {
 			ctx.ModuleErrorf(
 				"Boot image '%s' module '%s' not added as a dependency of platform_bootclasspath",
 				imageConfig.name,
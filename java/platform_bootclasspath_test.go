@@ -15,9 +15,14 @@
 package java
 
 import (
+	"encoding/json"
 	"testing"
 
 	"android/soong/android"
+	"android/soong/dexpreopt"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 // Contains some simple tests for platform_bootclasspath.
@@ -275,6 +280,41 @@ func TestPlatformBootclasspath_Dist(t *testing.T) {
 	android.AssertStringEquals(t, "platform dist goals call", "$(call dist-for-goals,droidcore,out/soong/hiddenapi/hiddenapi-flags.csv:hiddenapi-flags.csv)\n", android.StringRelativeToTop(result.Config, goals[2]))
 }
 
+func TestPlatformBootclasspath_HiddenAPIMaxTargetExpiry(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		hiddenApiFixtureFactory,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Platform_sdk_version = proptools.IntPtr(31)
+		}),
+		android.FixtureAddFile("max-target-allowlist.txt", nil),
+	).RunTestWithBp(t, `
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			compile_dex: true,
+		}
+
+		platform_bootclasspath {
+			name: "myplatform-bootclasspath",
+			hidden_api_max_target_expiry: {
+				releases: 2,
+				allowlist: ["max-target-allowlist.txt"],
+			},
+		}
+	`)
+
+	platformBootclasspath := result.ModuleForTests("myplatform-bootclasspath", "android_common")
+
+	expiry := platformBootclasspath.Output("out/soong/hiddenapi/hiddenapi-flags.max-target-expiry.valid")
+	android.AssertStringDoesContain(t, "expired flags", expiry.RuleParams.Command, "(max-target-o|max-target-p)(,|$)")
+	android.AssertStringListContains(t, "allowlist", expiry.Inputs.Strings(), "max-target-allowlist.txt")
+
+	flags := platformBootclasspath.Output("out/soong/hiddenapi/hiddenapi-flags.csv")
+	android.AssertPathsRelativeToTopEquals(t, "validations",
+		[]string{"out/soong/hiddenapi/hiddenapi-flags.max-target-expiry.valid"}, flags.Validations)
+}
+
 func TestPlatformBootclasspath_HiddenAPIMonolithicFiles(t *testing.T) {
 	result := android.GroupFixturePreparers(
 		hiddenApiFixtureFactory,
@@ -357,3 +397,1290 @@ func TestPlatformBootclasspath_HiddenAPIMonolithicFiles(t *testing.T) {
 		out/soong/.intermediates/myplatform-bootclasspath/android_common/hiddenapi-monolithic/index-from-classes.csv
 	`, rule)
 }
+
+func TestPlatformBootclasspath_BootImageEnableUffdGc(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetBootImageEnableUffdGc("boot", true),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--runtime-arg -Xgc:CMC")
+}
+
+func TestPlatformBootclasspath_BootImageSbox(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--image=__SBOX_SANDBOX_DIR__/out/boot.art")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--oat-symbols=__SBOX_SANDBOX_DIR__/out/symbols/boot.oat")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--write-invocation-to=__SBOX_SANDBOX_DIR__/out/boot.invocation")
+	// The inputs are copied into the sandbox, so the command does not refer to the output directory.
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--dex-file=__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_bootjars_input/foo.jar")
+	android.AssertStringDoesNotContain(t, "dex2oat command", dex2oat, "out/soong/")
+	android.AssertStringDoesNotContain(t, "dex2oat command", dex2oat, "rm -f")
+
+	// The image files are written by the rule directly into their predefined locations.
+	rule := platformBootclasspath.Rule("bootJarsDexpreopt_android_arm64")
+	outputDir := "out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/"
+	android.AssertStringListContains(t, "outputs", android.PathsRelativeToTop(rule.ImplicitOutputs.Paths()),
+		outputDir+"boot.art")
+	android.AssertStringListContains(t, "outputs", android.PathsRelativeToTop(rule.ImplicitOutputs.Paths()),
+		outputDir+"symbols/boot.oat")
+	android.AssertStringEquals(t, "image rule", rule.Description,
+		platformBootclasspath.Output(outputDir+"boot.art").Description)
+}
+
+func TestPlatformBootclasspath_BootImageSymbols(t *testing.T) {
+	bp := `
+		platform_bootclasspath {
+			name: "platform-bootclasspath",
+		}
+
+		java_library {
+			name: "foo",
+			srcs: ["a.java"],
+			system_modules: "none",
+			sdk_version: "none",
+			compile_dex: true,
+		}
+	`
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetDumpBootImageSymbols(true),
+		android.FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	symbolsDir := "out/soong/dexpreopt_arm64/dex_bootjars_unstripped/android/system/framework/arm64/"
+	symbols := platformBootclasspath.Output(symbolsDir + "boot.symbols.json")
+	android.AssertStringDoesContain(t, "oatdump command",
+		android.StringRelativeToTop(result.Config, symbols.RuleParams.Command),
+		"--oat-file="+symbolsDir+"boot.oat --dump-method-and-offset-as-json --output="+symbolsDir+"boot.symbols.json")
+
+	// The symbols are only dumped for the device images.
+	android.AssertBoolEquals(t, "host symbols", false,
+		platformBootclasspath.MaybeRule("symbols_boot_linux_glibc_x86_64").Rule != nil)
+
+	// The symbols are not dumped by default.
+	result = android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(bp),
+	).RunTest(t)
+	platformBootclasspath = result.ModuleForTests("platform-bootclasspath", "android_common")
+	android.AssertBoolEquals(t, "default symbols", false,
+		platformBootclasspath.MaybeOutput(symbolsDir+"boot.symbols.json").Rule != nil)
+}
+
+func TestPlatformBootclasspath_BootImageMetadata(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetBootImageMetadata(true, "build_fingerprint.txt"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	metadata := platformBootclasspath.Output("out/soong/dexpreopt_arm64/dex_bootjars/android/arm64/boot.metadata")
+	command := android.StringRelativeToTop(result.Config, metadata.RuleParams.Command)
+	android.AssertStringDoesContain(t, "fingerprint", command,
+		"fingerprint=$(cat out/soong/build_fingerprint.txt ) && echo fingerprint=${fingerprint} >> "+
+			"out/soong/dexpreopt_arm64/dex_bootjars/android/arm64/boot.metadata")
+	android.AssertStringDoesContain(t, "dex2oat version", command,
+		"version=$(dd if=out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art "+
+			"bs=1 skip=4 count=3 status=none) && test -n \"${version}\" && echo dex2oat_version=${version} >> ")
+	android.AssertStringDoesContain(t, "jar digest", command,
+		"sha256=$(sha256sum < out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar ) && "+
+			"echo /system/framework/foo.jar=${sha256%% *} >> ")
+	android.AssertStringDoesNotContain(t, "hidden failures", command, "| cut")
+	android.AssertStringListContains(t, "metadata inputs", android.PathsRelativeToTop(metadata.Implicits),
+		"out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar")
+	android.AssertStringListContains(t, "metadata inputs", android.PathsRelativeToTop(metadata.Implicits),
+		"out/soong/build_fingerprint.txt")
+	android.AssertStringListContains(t, "metadata inputs", android.PathsRelativeToTop(metadata.Implicits),
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art")
+
+	// The metadata is installed next to the boot image, and only for the device images.
+	android.AssertStringDoesContain(t, "installs",
+		android.StringRelativeToTop(result.Config, bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_BUILT_INSTALLED_boot_arm64")),
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/arm64/boot.metadata:/system/framework/arm64/boot.metadata")
+	android.AssertBoolEquals(t, "host metadata", false,
+		platformBootclasspath.MaybeRule("metadata_boot_linux_glibc_x86_64").Rule != nil)
+}
+
+func TestPlatformBootclasspath_BootImageDeltaMetadata(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	delta := platformBootclasspath.Output("out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.txt")
+	android.AssertStringListContains(t, "delta inputs", android.PathsRelativeToTop(delta.Inputs),
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.oat")
+
+	// The hashes are listed by the path of the files in the zip, without pipes so that the failures
+	// of the commands are not masked.
+	command := android.StringRelativeToTop(result.Config, delta.RuleParams.Command)
+	android.AssertStringDoesContain(t, "delta command", command,
+		"sed -e 's|  out/soong/dexpreopt_arm64/dex_bootjars/android/boot/|  |' out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.sha256.tmp > out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.rel.tmp")
+	android.AssertStringDoesContain(t, "delta command", command,
+		"LC_ALL=C sort -k 2 -o out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.txt out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.rel.tmp")
+	android.AssertStringDoesNotContain(t, "delta command", command, " | ")
+}
+
+func TestPlatformBootclasspath_BootImageCompilerFilters(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootImageCompilerFilters = map[string]string{
+				"arm":        "verify",
+				"boot:arm64": "speed-profile",
+				"art:arm64":  "speed",
+			}
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	arm64 := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "arm64 compiler filter", arm64,
+		"--compiler-filter=speed-profile")
+	arm := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm_")
+	android.AssertStringDoesContain(t, "arm compiler filter", arm,
+		"--compiler-filter=verify")
+
+	// The host variants are not affected.
+	host := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_"+result.Config.BuildOSTarget.String())
+	android.AssertStringDoesContain(t, "host compiler filter", host,
+		"--compiler-filter=everything")
+}
+
+func TestPlatformBootclasspath_BootImageDex2oatHeapSizes(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureModifyGlobalConfig(func(_ android.PathContext, global *dexpreopt.GlobalConfig) {
+			global.Dex2oatImageXms = "64m"
+			global.Dex2oatImageXmx = "1024m"
+		}),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootImageDex2oatXmx = map[string]string{
+				"boot": "2048m",
+			}
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--runtime-arg -Xms64m")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat, "--runtime-arg -Xmx2048m")
+}
+
+func TestPlatformBootclasspath_BootImageDex2oatHeapSizesUnknownImage(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForTestWithPlatformBootclasspath,
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootImageDex2oatXms = map[string]string{
+				"foo": "64m",
+			}
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`unknown boot image "foo"`)).
+		RunTest(t)
+}
+
+func TestPlatformBootclasspath_UninstrumentedBootImage(t *testing.T) {
+	preparer := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:telephony-common"),
+		// Make sure that the frameworks/base/Android.bp file exists as otherwise hidden API encoding
+		// is disabled.
+		android.FixtureAddTextFile("frameworks/base/Android.bp", ""),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "telephony-common",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	)
+
+	t.Run("without coverage", func(t *testing.T) {
+		result := preparer.RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		copyUninstrumented := platformBootclasspath.MaybeOutput("out/soong/dexpreopt_arm64/dex_boot_uninstrumentedjars_input/telephony-common.jar")
+		if copyUninstrumented.Rule != nil {
+			t.Errorf("expected no uninstrumented boot image without coverage")
+		}
+	})
+
+	t.Run("with coverage", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			prepareForTestWithFrameworkJacocoInstrumentation,
+			preparer,
+			PrepareForTestWithFakeApexMutator,
+			dexpreopt.FixtureSetSystemServerJars("platform:services"),
+			android.FixtureAddTextFile("frameworks/base/services/Android.bp", `
+				java_library {
+					name: "services",
+					srcs: ["a.java"],
+					installable: true,
+				}
+			`),
+		).RunTest(t)
+
+		// The uninstrumented dex jar is compiled from the javac output, not the jacoco one.
+		lib := result.ModuleForTests("telephony-common", "android_common")
+		d8 := lib.Output("uninstrumented/dex/telephony-common.jar")
+		android.AssertPathRelativeToTopEquals(t, "uninstrumented d8 input",
+			"out/soong/.intermediates/telephony-common/android_common/javac/telephony-common.jar", d8.Input)
+		instrumented := lib.Output("dex/telephony-common.jar")
+		android.AssertPathRelativeToTopEquals(t, "instrumented d8 input",
+			"out/soong/.intermediates/telephony-common/android_common/jacoco/telephony-common.jar", instrumented.Input)
+		encode := lib.Output("uninstrumented/hiddenapi/telephony-common.jar")
+		android.AssertPathRelativeToTopEquals(t, "uninstrumented encode input",
+			"out/soong/.intermediates/telephony-common/android_common/uninstrumented/dex/telephony-common.jar", encode.Input)
+
+		// Both images are built from their own copies of the dex jars.
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		copyInstrumented := platformBootclasspath.Output("out/soong/dexpreopt_arm64/dex_bootjars_input/telephony-common.jar")
+		android.AssertPathRelativeToTopEquals(t, "instrumented boot jar",
+			"out/soong/.intermediates/telephony-common/android_common/hiddenapi/telephony-common.jar", copyInstrumented.Input)
+		copyUninstrumented := platformBootclasspath.Output("out/soong/dexpreopt_arm64/dex_boot_uninstrumentedjars_input/telephony-common.jar")
+		android.AssertPathRelativeToTopEquals(t, "uninstrumented boot jar",
+			"out/soong/.intermediates/telephony-common/android_common/uninstrumented/hiddenapi/telephony-common.jar", copyUninstrumented.Input)
+
+		dex2oat := platformBootclasspath.Rule("boot_uninstrumentedJarsDexpreopt_android_arm64")
+		android.AssertStringListContains(t, "dex2oat outputs", android.PathsRelativeToTop(dex2oat.ImplicitOutputs.Paths()),
+			"out/soong/dexpreopt_arm64/dex_boot_uninstrumentedjars/android/boot/system/framework/uninstrumented/arm64/boot.art")
+
+		// The Make variables of both images are exported.
+		android.AssertStringEquals(t, "image names", "art boot boot_uninstrumented mainline",
+			bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_NAMES"))
+		android.AssertStringDoesContain(t, "uninstrumented image",
+			bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_boot_uninstrumented_arm64"),
+			"dex_boot_uninstrumentedjars/android/boot/system/framework/uninstrumented/arm64/boot.art")
+		android.AssertStringDoesContain(t, "instrumented image", bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_boot_arm64"),
+			"dex_bootjars/android/boot/system/framework/arm64/boot.art")
+		// Only the instrumented image is installed.
+		android.AssertStringEquals(t, "uninstrumented installs", "",
+			bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_BUILT_INSTALLED_boot_uninstrumented_arm64"))
+		instrumentedInstalls := bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_BUILT_INSTALLED_boot_arm64")
+		android.AssertStringDoesContain(t, "instrumented installs", instrumentedInstalls,
+			":/system/framework/arm64/boot.art")
+		android.AssertStringDoesNotContain(t, "instrumented installs", instrumentedInstalls, "uninstrumented")
+
+		// The system server jars are dexpreopted against the instrumented image that the device boots.
+		services := result.ModuleForTests("services", "android_common").Rule("dexpreopt")
+		android.AssertStringDoesContain(t, "services dex2oat command", services.RuleParams.Command,
+			"--boot-image=out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/boot.art")
+		android.AssertStringDoesNotContain(t, "services dex2oat command", services.RuleParams.Command,
+			"uninstrumented")
+	})
+}
+
+func TestPlatformBootclasspath_VerifyBootImage(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetVerifyBootImage(true),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	verify := platformBootclasspath.Rule("verify_boot_android_arm64")
+	android.AssertStringDoesContain(t, "verify command", verify.RuleParams.Command, "oatdump")
+	android.AssertStringDoesContain(t, "verify command", verify.RuleParams.Command,
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/boot.art")
+	android.AssertStringDoesContain(t, "verify command", verify.RuleParams.Command, "--header-only")
+	android.AssertStringListContains(t, "verify depends on the image",
+		verify.Implicits.Strings(), "out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art")
+
+	expectedValidation := "out/soong/.intermediates/platform-bootclasspath/android_common/boot_image_verify/boot/arm64.oatdump.txt"
+	android.AssertPathRelativeToTopEquals(t, "verify output", expectedValidation, verify.Output)
+	dex2oat := platformBootclasspath.Rule("bootJarsDexpreopt_android_arm64")
+	android.AssertPathsRelativeToTopEquals(t, "dex2oat validations",
+		[]string{expectedValidation, "out/soong/boot_jars_config_check.timestamp"}, dex2oat.Validations)
+
+	// The host boot images are not verified.
+	host := platformBootclasspath.MaybeRule("verify_boot_" + result.Config.BuildOSTarget.String())
+	if host.Rule != nil {
+		t.Errorf("expected no verification of the host boot image")
+	}
+}
+
+func TestPlatformBootclasspath_ReproducibleBootImages(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetReproducibleBootImages(true),
+		dexpreopt.FixtureSetBootImageProfiles("vendor/boot-image-profile.txt",
+			"frameworks/base/config/boot-image-profile.txt"),
+		android.FixtureMergeMockFs(android.MockFS{
+			"frameworks/base/config/boot-image-profile.txt": nil,
+			"vendor/boot-image-profile.txt":                 nil,
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"SOURCE_DATE_EPOCH=0")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--generate-build-id --force-determinism ||")
+
+	profile := platformBootclasspath.Rule("bootJarsProfile")
+	android.AssertStringDoesContain(t, "profiles are sorted", profile.RuleParams.Command,
+		"cat frameworks/base/config/boot-image-profile.txt vendor/boot-image-profile.txt >")
+}
+
+func TestPlatformBootclasspath_DirtyImageObjects(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetDirtyImageObjects("device/vendor/dirty-image-objects"),
+		android.FixtureAddTextFile("frameworks/base/config/dirty-image-objects", ""),
+		android.FixtureAddTextFile("device/vendor/dirty-image-objects", ""),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	expected := "out/soong/.intermediates/platform-bootclasspath/android_common/dirty_image_objects/boot/arm64/dirty-image-objects"
+	concat := platformBootclasspath.Output(expected)
+	android.AssertStringDoesContain(t, "dirty image objects command", concat.RuleParams.Command,
+		"cat frameworks/base/config/dirty-image-objects device/vendor/dirty-image-objects > "+expected)
+
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--dirty-image-objects=__SBOX_SANDBOX_DIR__/out/.intermediates/platform-bootclasspath/android_common/dirty_image_objects/boot/arm64/dirty-image-objects")
+}
+
+func TestPlatformBootclasspath_BootImageEnableUffdGcUnknownImage(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForTestWithPlatformBootclasspath,
+		dexpreopt.FixtureSetBootImageEnableUffdGc("foo", true),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`BootImageEnableUffdGc: unknown boot image "foo"`)).
+		RunTest(t)
+}
+
+func TestPlatformBootclasspath_BootImageRbe(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetBootImageProfiles("frameworks/base/config/boot-image-profile.txt"),
+		android.FixtureMergeMockFs(android.MockFS{
+			"frameworks/base/config/boot-image-profile.txt": nil,
+		}),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.UseRBE = proptools.BoolPtr(true)
+		}),
+		android.FixtureMergeEnv(map[string]string{
+			"RBE_DEX2OAT": "true",
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := platformBootclasspath.Rule("bootJarsDexpreopt_android").RuleParams.Command
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--labels=name=dex2oat,type=tool")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		`--platform="Pool=${config.REDex2oatPool},`)
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"--exec_strategy=${config.REDex2oatExecStrategy}")
+
+	profile := platformBootclasspath.Rule("bootJarsProfile")
+	android.AssertStringDoesContain(t, "profman command", profile.RuleParams.Command,
+		"--labels=name=profman,type=tool")
+	android.AssertStringListContains(t, "profman inputs", profile.Implicits.Strings(),
+		"out/soong/dexpreopt_arm64/dex_bootjars/boot.prof.rbe_inputs.list")
+}
+
+func TestPlatformBootclasspath_DiffBootImage(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetReferenceBootImage("prebuilts/boot-image.zip"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dump := platformBootclasspath.Rule("dump-oat-reference-boot-arm64")
+	android.AssertStringDoesContain(t, "reference dump command", dump.RuleParams.Command,
+		"unzip -qoDD -d out/soong/diff_boot_image/arm64/android prebuilts/boot-image.zip '*/arm64/*'")
+	android.AssertStringDoesContain(t, "reference dump command", dump.RuleParams.Command,
+		"--image=out/soong/diff_boot_image/arm64/android/system/framework/boot.art")
+
+	diff := platformBootclasspath.Rule("diff-boot-image-arm64")
+	android.AssertStringDoesContain(t, "diff command", diff.RuleParams.Command,
+		"--reference out/soong/boot.arm64.reference.oatdump.txt --current out/soong/boot.arm64.oatdump.txt")
+
+	// The reference boot image is a device boot image, so there is no diff for the host.
+	android.AssertBoolEquals(t, "host diff", false,
+		platformBootclasspath.MaybeRule("diff-boot-image-host-").Rule != nil)
+}
+
+func TestPlatformBootclasspath_BootImageProfileFreshness(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetBootImageProfiles("frameworks/base/config/boot-image-profile.txt"),
+		dexpreopt.FixtureSetBootImageProfileFingerprint("frameworks/base/config/boot-image-profile.fingerprint", 5),
+		android.FixtureMergeMockFs(android.MockFS{
+			"frameworks/base/config/boot-image-profile.txt":         nil,
+			"frameworks/base/config/boot-image-profile.fingerprint": nil,
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_binary_host {
+				name: "dexdump",
+				srcs: ["a.java"],
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	rule := platformBootclasspath.Rule("bootImageProfileFreshness")
+	android.AssertStringDoesContain(t, "command", rule.RuleParams.Command,
+		"--recorded-fingerprint frameworks/base/config/boot-image-profile.fingerprint")
+	android.AssertStringDoesContain(t, "command", rule.RuleParams.Command, "--max-api-drift 5")
+	android.AssertStringListContains(t, "inputs", rule.Implicits.Strings(),
+		"out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar")
+	android.AssertPathRelativeToTopEquals(t, "output",
+		"out/soong/dexpreopt_arm64/dex_bootjars/boot-image-profile.fingerprint", rule.Output)
+	android.AssertStringListContains(t, "inputs", rule.Implicits.Strings(),
+		"out/soong/host/linux-x86/bin/dexdump")
+}
+
+func TestPlatformBootclasspath_BootImagesManifest(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	dexBootJars := result.ModuleForTests("dex_bootjars", "").Module().(*dexpreoptBootJars)
+	outputs, err := dexBootJars.OutputFiles("")
+	android.AssertSame(t, "error", nil, err)
+	android.AssertPathsRelativeToTopEquals(t, "output files", []string{"out/soong/dexpreopt/boot_images.json"}, outputs)
+
+	output := result.SingletonForTests("dex_bootjars").Output("dexpreopt/boot_images.json")
+	var manifest bootImagesManifest
+	if err := json.Unmarshal([]byte(android.ContentFromFileRuleForTests(t, output)), &manifest); err != nil {
+		t.Fatalf("invalid boot_images.json: %s", err)
+	}
+
+	var boot *bootImageManifest
+	for i := range manifest.Images {
+		if manifest.Images[i].Name == frameworkBootImageName {
+			boot = &manifest.Images[i]
+		}
+	}
+	if boot == nil {
+		t.Fatalf("boot image missing from boot_images.json: %#v", manifest)
+	}
+	android.AssertArrayString(t, "modules", []string{"platform:foo"}, boot.Modules)
+	android.AssertStringEquals(t, "install dir", "system/framework", boot.InstallDir)
+	android.AssertStringEquals(t, "delta metadata", "out/soong/dexpreopt_arm64/dex_bootjars/boot.delta.txt",
+		boot.DeltaMetadata)
+
+	variant := boot.Variants[0]
+	android.AssertStringEquals(t, "arch", "arm64", variant.Arch)
+	android.AssertStringEquals(t, "image path on device", "/system/framework/arm64/boot.art", variant.ImagePathOnDevice)
+	android.AssertArrayString(t, "dex locations", []string{"/system/framework/foo.jar"}, variant.DexLocations)
+	android.AssertStringListContains(t, "checksum inputs", variant.ChecksumInputs,
+		"out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar")
+	android.AssertStringListContains(t, "outputs", variant.Outputs,
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.oat")
+}
+
+func TestPlatformBootclasspath_DexBootJarsTaggedOutputs(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		dexpreopt.FixtureSetBootImageProfiles("frameworks/base/config/boot-image-profile.txt"),
+		android.FixtureMergeMockFs(android.MockFS{
+			"frameworks/base/config/boot-image-profile.txt": nil,
+			"frameworks/base/config/boot-profile.txt":       nil,
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_genrule {
+				name: "boot-image-outputs",
+				srcs: [
+					":dex_bootjars{.profile}",
+					":dex_bootjars{.bprof}",
+					":dex_bootjars{.zip}",
+				],
+				cmd: "cat $(in) > $(out)",
+				out: ["boot-image-outputs"],
+			}
+		`),
+	).RunTest(t)
+
+	// The tagged outputs are resolved by a module that references them, so they must be known
+	// before the singletons run.
+	gen := result.ModuleForTests("boot-image-outputs", "android_common").Output("boot-image-outputs")
+	android.AssertPathsRelativeToTopEquals(t, "tagged outputs of dex_bootjars", []string{
+		"out/soong/dexpreopt_arm64/dex_bootjars/boot.prof",
+		"out/soong/dexpreopt_arm64/dex_bootjars/boot.bprof",
+		"out/soong/dexpreopt_arm64/dex_bootjars/boot.zip",
+	}, gen.Implicits[:3])
+
+	dexBootJars := result.ModuleForTests("dex_bootjars", "").Module().(*dexpreoptBootJars)
+	_, err := dexBootJars.OutputFiles(".oat")
+	android.AssertStringDoesContain(t, "unsupported tag error", err.Error(), `unsupported module reference tag ".oat"`)
+}
+
+func TestPlatformBootclasspath_DexBootJarsDependencies(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	// dex_bootjars depends on the platform_bootclasspath module instead of visiting all the modules.
+	dexBootJars := result.ModuleForTests("dex_bootjars", "").Module()
+	var deps []string
+	result.TestContext.VisitDirectDeps(dexBootJars, func(m blueprint.Module) {
+		deps = append(deps, m.Name())
+	})
+	android.AssertStringListContains(t, "dependencies", deps, "platform-bootclasspath")
+
+	// The boot image variables are exported from the boot images built by the dependencies.
+	android.AssertStringEquals(t, "DEXPREOPT_IMAGE_boot_arm64",
+		"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.art",
+		android.StringRelativeToTop(result.Config, bootImageMakeVarForTests(t, result, "DEXPREOPT_IMAGE_boot_arm64")))
+}
+
+func TestPlatformBootclasspath_BootImageProfileModules(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		android.FixtureMergeMockFs(android.MockFS{
+			"vendor/oem/boot-image-profile.txt": nil,
+			"vendor/oem/device.prof":            nil,
+			"vendor/oem/oem.jar":                nil,
+			"vendor/oem/unused.txt":             nil,
+		}),
+		android.FixtureAddTextFile("vendor/oem/Android.bp", `
+			boot_image_profile {
+				name: "oem-boot-image-profile",
+				srcs: ["boot-image-profile.txt", "device.prof"],
+				apks: ["oem.jar"],
+				dex_locations: ["/system_ext/framework/oem.jar"],
+			}
+
+			boot_image_profile {
+				name: "unused-boot-image-profile",
+				srcs: ["unused.txt"],
+			}
+		`),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BootImageProfileModules = []string{"oem-boot-image-profile"}
+		}),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	profile := platformBootclasspath.Rule("bootJarsProfile")
+	android.AssertStringDoesContain(t, "text profiles", profile.RuleParams.Command,
+		"cat vendor/oem/boot-image-profile.txt >")
+	android.AssertStringDoesContain(t, "binary profiles", profile.RuleParams.Command,
+		"--profile-file=vendor/oem/device.prof")
+	android.AssertStringDoesContain(t, "apks", profile.RuleParams.Command,
+		"--apk=out/soong/dexpreopt_arm64/dex_bootjars_input/foo.jar --apk=vendor/oem/oem.jar")
+	android.AssertStringDoesContain(t, "dex locations", profile.RuleParams.Command,
+		"--dex-location=/system/framework/foo.jar --dex-location=/system_ext/framework/oem.jar")
+
+	// Only the profiles listed by the product are merged.
+	android.AssertStringDoesNotContain(t, "text profiles", profile.RuleParams.Command, "vendor/oem/unused.txt")
+}
+
+func TestPlatformBootclasspath_BootImageProfileModuleErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForTestWithPlatformBootclasspath,
+		android.FixtureMergeMockFs(android.MockFS{
+			"oem.jar": nil,
+		}),
+		android.FixtureWithRootAndroidBp(`
+			boot_image_profile {
+				name: "no-srcs",
+			}
+
+			boot_image_profile {
+				name: "missing-locations",
+				srcs: ["oem.jar"],
+				apks: ["oem.jar"],
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`module "no-srcs": srcs: a boot image profile must have at least one profile`,
+		`module "missing-locations": dex_locations: must have one location for each of the 1 apks, got 0`,
+	})).RunTest(t)
+}
+
+func TestPlatformBootclasspath_BootImageExtension(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		FixtureConfigureBootImageExtensions("vendor-boot-image", "disabled-boot-image"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			boot_image_extension {
+				name: "vendor-boot-image",
+				jars: ["bar"],
+			}
+
+			boot_image_extension {
+				name: "disabled-boot-image",
+				jars: ["baz"],
+				enabled: false,
+			}
+
+			boot_image_extension {
+				name: "unrequested-boot-image",
+				jars: ["baz"],
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	).RunTest(t)
+
+	configs := genBootImageConfigs(result)
+	android.AssertBoolEquals(t, "disabled extension registered", false, configs["disabled-boot-image"] != nil)
+	android.AssertBoolEquals(t, "unrequested extension registered", false, configs["unrequested-boot-image"] != nil)
+
+	config := configs["vendor-boot-image"]
+	android.AssertStringEquals(t, "extends", frameworkBootImageName, config.extends.name)
+	android.AssertStringEquals(t, "modules", "platform:bar", config.modules.String())
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "vendor-boot-imageJarsDexpreopt_android")
+	android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+		"/system/framework/arm64/boot-bar.art")
+}
+
+func TestPlatformBootclasspath_BootImageCpuOverrides(t *testing.T) {
+	preparer := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		FixtureConfigureBootImageExtensions("vendor-boot-image"),
+		dexpreopt.FixtureModifyGlobalConfig(func(ctx android.PathContext, dexpreoptConfig *dexpreopt.GlobalConfig) {
+			dexpreoptConfig.CpuVariant = map[android.ArchType]string{android.Arm64: "generic"}
+			dexpreoptConfig.InstructionSetFeatures = map[android.ArchType]string{android.Arm64: "default"}
+		}),
+		android.FixtureAddTextFile("java/Android.bp", `
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	)
+
+	t.Run("overridden", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparer,
+			android.FixtureWithRootAndroidBp(`
+				platform_bootclasspath {
+					name: "platform-bootclasspath",
+				}
+
+				boot_image_extension {
+					name: "vendor-boot-image",
+					jars: ["bar"],
+					instruction_set_variants: ["arm64:cortex-a76"],
+					instruction_set_features: ["arm64:dotprod"],
+				}
+			`),
+		).RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		vendor := BootImageDex2oatCommandForTests(t, platformBootclasspath, "vendor-boot-imageJarsDexpreopt_android_arm64")
+		android.AssertStringDoesContain(t, "extension cpu variant", vendor,
+			"--instruction-set-variant=cortex-a76")
+		android.AssertStringDoesContain(t, "extension instruction set features", vendor,
+			"--instruction-set-features=dotprod")
+
+		// The other boot images keep the flags of the global config.
+		boot := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+		android.AssertStringDoesContain(t, "boot cpu variant", boot,
+			"--instruction-set-variant=generic")
+		android.AssertStringDoesContain(t, "boot instruction set features", boot,
+			"--instruction-set-features=default")
+	})
+
+	t.Run("framework boot image", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparer,
+			android.FixtureWithRootAndroidBp(`
+				platform_bootclasspath {
+					name: "platform-bootclasspath",
+					instruction_set_variants: ["arm64:cortex-a55"],
+					instruction_set_features: ["arm64:crc"],
+				}
+			`),
+		).RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		boot := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64")
+		android.AssertStringDoesContain(t, "boot cpu variant", boot,
+			"--instruction-set-variant=cortex-a55")
+		android.AssertStringDoesContain(t, "boot instruction set features", boot,
+			"--instruction-set-features=crc")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		android.GroupFixturePreparers(
+			preparer,
+			android.FixtureWithRootAndroidBp(`
+				boot_image_extension {
+					name: "vendor-boot-image",
+					jars: ["bar"],
+					instruction_set_variants: ["cortex-a76"],
+					instruction_set_features: ["mips:default"],
+				}
+			`),
+		).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+			`instruction_set_variants: expected <arch>:<value> pairs with one of the archs .*, got \["cortex-a76"\]`,
+			`instruction_set_features: expected <arch>:<value> pairs with one of the archs .*, got \["mips:default"\]`,
+		})).RunTest(t)
+	})
+}
+
+func TestPlatformBootclasspath_BootImagePerJarDexpreopt(t *testing.T) {
+	preparer := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo", "platform:bar"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	)
+
+	t.Run("per jar", func(t *testing.T) {
+		result := preparer.RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		foo := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64_armv8-a_foo")
+		android.AssertStringDoesContain(t, "foo dex2oat command", foo,
+			"--dex-location=/system/framework/foo.jar")
+		android.AssertStringDoesNotContain(t, "foo dex2oat command", foo,
+			"--dex-location=/system/framework/bar.jar")
+		android.AssertStringDoesContain(t, "foo dex2oat command", foo, "--base=")
+		android.AssertStringDoesNotContain(t, "foo dex2oat command", foo, "--boot-image=")
+
+		// The jars after the first one are compiled as extensions of the components before them.
+		bar := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64_armv8-a_bar")
+		android.AssertStringDoesContain(t, "bar dex2oat command", bar,
+			"--dex-location=/system/framework/bar.jar")
+		android.AssertStringDoesNotContain(t, "bar dex2oat command", bar,
+			"--dex-location=/system/framework/foo.jar")
+		android.AssertStringDoesNotContain(t, "bar dex2oat command", bar, "--base=")
+		android.AssertStringDoesContain(t, "bar dex2oat command", bar,
+			"--boot-image=__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/boot.art ")
+		android.AssertStringDoesContain(t, "bar dex2oat command", bar,
+			"-Xbootclasspath-locations:/system/framework/foo.jar:/system/framework/bar.jar ")
+		android.AssertStringDoesContain(t, "bar dex2oat command", bar, "--single-image")
+		barRule := platformBootclasspath.Rule("bootJarsDexpreopt_android_arm64_armv8-a_bar")
+		android.AssertStringListContains(t, "bar implicits", barRule.Implicits.Strings(),
+			"out/soong/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/arm64/boot.oat")
+
+		_, deviceLocations := genBootImageConfigs(result)[frameworkBootImageName].getAnyAndroidVariant().imageLocations()
+		android.AssertArrayString(t, "image locations",
+			[]string{"/system/framework/boot.art", "/system/framework/boot-bar.art"}, deviceLocations)
+	})
+
+	t.Run("monolithic", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparer,
+			dexpreopt.FixtureSetMonolithicBootImageDexpreopt(true),
+		).RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "bootJarsDexpreopt_android_arm64_armv8-a")
+		android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+			"--dex-location=/system/framework/foo.jar --dex-location=/system/framework/bar.jar")
+		android.AssertBoolEquals(t, "per jar rule", false,
+			platformBootclasspath.MaybeRule("bootJarsDexpreopt_android_arm64_armv8-a_bar").Rule != nil)
+
+		_, deviceLocations := genBootImageConfigs(result)[frameworkBootImageName].getAnyAndroidVariant().imageLocations()
+		android.AssertArrayString(t, "image locations", []string{"/system/framework/boot.art"}, deviceLocations)
+	})
+}
+
+func TestPlatformBootclasspath_BootImageExtensionPerJarDexpreopt(t *testing.T) {
+	preparer := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo"),
+		FixtureConfigureBootImageExtensions("vendor-boot-image"),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			boot_image_extension {
+				name: "vendor-boot-image",
+				jars: ["bar", "baz"],
+				single_image: false,
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "baz",
+				srcs: ["c.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+		`),
+	)
+
+	t.Run("per jar", func(t *testing.T) {
+		result := preparer.RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		bar := BootImageDex2oatCommandForTests(t, platformBootclasspath, "vendor-boot-imageJarsDexpreopt_android_arm64_armv8-a_bar")
+		android.AssertStringDoesContain(t, "bar dex2oat command", bar,
+			"--dex-location=/system/framework/bar.jar")
+		android.AssertStringDoesNotContain(t, "bar dex2oat command", bar,
+			"--dex-location=/system/framework/baz.jar")
+
+		baz := BootImageDex2oatCommandForTests(t, platformBootclasspath, "vendor-boot-imageJarsDexpreopt_android_arm64_armv8-a_baz")
+		android.AssertStringDoesContain(t, "baz dex2oat command", baz,
+			"--dex-location=/system/framework/baz.jar")
+		android.AssertStringDoesNotContain(t, "baz dex2oat command", baz,
+			"--dex-location=/system/framework/bar.jar")
+		android.AssertStringDoesContain(t, "baz dex2oat command", baz,
+			"--boot-image=__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/boot.art:"+
+				"__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_vendor-boot-imagejars/android/boot-bar/system/framework/boot-bar.art ")
+		android.AssertStringDoesContain(t, "baz dex2oat command", baz, "--single-image")
+		bazRule := platformBootclasspath.Rule("vendor-boot-imageJarsDexpreopt_android_arm64_armv8-a_baz")
+		android.AssertStringListContains(t, "baz implicits", bazRule.Implicits.Strings(),
+			"out/soong/dexpreopt_arm64/dex_vendor-boot-imagejars/android/boot-bar/system/framework/arm64/boot-bar.oat")
+
+		_, deviceLocations := genBootImageConfigs(result)["vendor-boot-image"].getAnyAndroidVariant().imageLocations()
+		android.AssertStringListContains(t, "image locations", deviceLocations, "/system/framework/boot-bar.art")
+		android.AssertStringListContains(t, "image locations", deviceLocations, "/system/framework/boot-baz.art")
+	})
+
+	t.Run("monolithic", func(t *testing.T) {
+		result := android.GroupFixturePreparers(
+			preparer,
+			dexpreopt.FixtureSetMonolithicBootImageDexpreopt(true),
+		).RunTest(t)
+
+		platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+		dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "vendor-boot-imageJarsDexpreopt_android_arm64_armv8-a")
+		android.AssertStringDoesContain(t, "dex2oat command", dex2oat,
+			"--dex-location=/system/framework/bar.jar --dex-location=/system/framework/baz.jar")
+		android.AssertBoolEquals(t, "per jar rule", false,
+			platformBootclasspath.MaybeRule("vendor-boot-imageJarsDexpreopt_android_arm64_armv8-a_baz").Rule != nil)
+	})
+}
+
+func TestPlatformBootclasspath_SystemExtBootImage(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("platform:foo", "system_ext:bar"),
+		dexpreopt.FixtureSetSystemExtBootImage(true),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+				system_ext_specific: true,
+			}
+		`),
+	).RunTest(t)
+
+	configs := genBootImageConfigs(result)
+	android.AssertStringEquals(t, "framework modules", "platform:foo", configs[frameworkBootImageName].modules.String())
+	android.AssertStringEquals(t, "system_ext modules", "system_ext:bar", configs[systemExtBootImageName].modules.String())
+	android.AssertStringEquals(t, "system_ext extends", frameworkBootImageName, configs[systemExtBootImageName].extends.name)
+	android.AssertStringEquals(t, "mainline extends", systemExtBootImageName, configs[mainlineBootImageName].extends.name)
+
+	platformBootclasspath := result.ModuleForTests("platform-bootclasspath", "android_common")
+	dex2oat := BootImageDex2oatCommandForTests(t, platformBootclasspath, "system_extJarsDexpreopt_android")
+	android.AssertStringDoesContain(t, "dex location", dex2oat,
+		"--dex-location=/system_ext/framework/bar.jar")
+	android.AssertStringDoesContain(t, "oat location", dex2oat,
+		"--oat-location=/system_ext/framework/arm64/boot-bar.oat")
+	android.AssertStringDoesContain(t, "base image", dex2oat,
+		"--boot-image=__SBOX_SANDBOX_DIR__/out/dexpreopt_arm64/dex_bootjars/android/boot/system/framework/boot.art ")
+
+	// The jars compiled against the non-updatable boot images include the system_ext jars.
+	_, dexLocations := bcpForDexpreopt(result, false)
+	android.AssertArrayString(t, "bootclasspath locations",
+		[]string{"/system/framework/foo.jar", "/system_ext/framework/bar.jar"}, dexLocations)
+}
+
+func TestPlatformBootclasspath_SystemExtBootImageOrder(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForTestWithDexpreopt,
+		FixtureConfigureBootJars("system_ext:bar", "platform:foo"),
+		dexpreopt.FixtureSetSystemExtBootImage(true),
+		android.FixtureWithRootAndroidBp(`
+			platform_bootclasspath {
+				name: "platform-bootclasspath",
+			}
+
+			java_library {
+				name: "foo",
+				srcs: ["a.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+			}
+
+			java_library {
+				name: "bar",
+				srcs: ["b.java"],
+				system_modules: "none",
+				sdk_version: "none",
+				compile_dex: true,
+				system_ext_specific: true,
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`SystemExtBootImage: the system_ext boot jar "bar" must come after the other boot jars, but it comes before "foo"`,
+	)).RunTest(t)
+}
+
+func TestPlatformBootclasspath_BootImageExtensionErrors(t *testing.T) {
+	android.GroupFixturePreparers(
+		prepareForTestWithPlatformBootclasspath,
+		FixtureConfigureBootJars("platform:foo"),
+		FixtureConfigureBootImageExtensions("predefined", "unknown-base", "duplicate-jar"),
+		android.FixtureWithRootAndroidBp(`
+			boot_image_extension {
+				name: "predefined",
+				image_name: "boot",
+				jars: ["bar"],
+			}
+
+			boot_image_extension {
+				name: "unknown-base",
+				extends: "unknown",
+				jars: ["bar"],
+			}
+
+			boot_image_extension {
+				name: "duplicate-jar",
+				jars: ["foo"],
+			}
+		`),
+	).ExtendWithErrorHandler(android.FixtureExpectsAllErrorsToMatchAPattern([]string{
+		`module "predefined": image_name: "boot" is the name of a predefined boot image`,
+		`module "unknown-base": extends: unknown boot image "unknown"`,
+		`module "duplicate-jar": jars: "foo" is already part of the "boot" boot image`,
+	})).RunTest(t)
+}
+
+// bootImageMakeVarForTests returns the value of the Make variable for the boot images with the
+// given name, or an empty string if it is not defined.
+func bootImageMakeVarForTests(t *testing.T, result *android.TestResult, name string) string {
+	return bootImageMakeVarsForTests(t, result)[name]
+}
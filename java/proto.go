@@ -17,6 +17,7 @@ package java
 import (
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"android/soong/android"
 	"android/soong/bazel"
@@ -26,9 +27,14 @@ import (
 
 const (
 	protoTypeDefault = "lite"
+
+	// The host tool that generates the gRPC stubs by default.
+	grpcJavaPluginDefault = "protoc-gen-grpc-java-plugin"
 )
 
-func genProto(ctx android.ModuleContext, protoFiles android.Paths, flags android.ProtoFlags) android.Paths {
+// genProto generates the message classes of the proto files, and their gRPC stubs if grpcOutParams
+// is not nil.
+func genProto(ctx android.ModuleContext, protoFiles android.Paths, flags android.ProtoFlags, grpcOutParams []string) android.Paths {
 	// Shard proto files into groups of 100 to avoid having to recompile all of them if one changes and to avoid
 	// hitting command line length limits.
 	shards := android.ShardPaths(protoFiles, 50)
@@ -41,6 +47,12 @@ func genProto(ctx android.ModuleContext, protoFiles android.Paths, flags android
 
 		outDir := srcJarFile.ReplaceExtension(ctx, "tmp")
 
+		shardFlags := flags
+		if grpcOutParams != nil {
+			shardFlags.Flags = append(android.CopyOf(flags.Flags),
+				"--grpc-java_out="+strings.Join(grpcOutParams, ",")+":"+outDir.String())
+		}
+
 		rule := android.NewRuleBuilder(pctx, ctx)
 
 		rule.Command().Text("rm -rf").Flag(outDir.String())
@@ -49,7 +61,7 @@ func genProto(ctx android.ModuleContext, protoFiles android.Paths, flags android
 		for _, protoFile := range shard {
 			depFile := srcJarFile.InSameDir(ctx, protoFile.String()+".d")
 			rule.Command().Text("mkdir -p").Flag(filepath.Dir(depFile.String()))
-			android.ProtoRule(rule, protoFile, flags, flags.Deps, outDir, depFile, nil)
+			android.ProtoRule(rule, protoFile, shardFlags, flags.Deps, outDir, depFile, nil)
 		}
 
 		// Proto generated java files have an unknown package name in the path, so package the entire output directory
@@ -142,6 +154,103 @@ func protoFlags(ctx android.ModuleContext, j *CommonProperties, p *android.Proto
 	return flags
 }
 
+type GrpcProperties struct {
+	Grpc struct {
+		// The host tool that generates the gRPC stubs, e.g. to select a specific version of the
+		// plugin. Defaults to protoc-gen-grpc-java-plugin.
+		Plugin *string
+
+		// List of extra options that will be passed to the gRPC plugin.
+		Output_params []string
+
+		// The gRPC runtime libraries that the stubs are statically linked against. Defaults to
+		// grpc-java-lite for lite protos and to grpc-java for full protos.
+		Runtime_libs []string
+	}
+}
+
+// java_grpc_library generates the message classes and the gRPC stubs of the .proto files in its
+// srcs, typically a filegroup of protos, with the gRPC plugin of grpc-java, and compiles them into
+// a library like java_library. The proto.type property selects the lite (default) or the full
+// runtime of both the messages and the stubs, e.g.
+//
+//	java_grpc_library {
+//	    name: "foo-grpc-java",
+//	    srcs: [":foo-protos"],
+//	    proto: {
+//	        type: "lite",
+//	    },
+//	    grpc: {
+//	        plugin: "protoc-gen-grpc-java-plugin-1.51",
+//	    },
+//	    sdk_version: "current",
+//	}
+func GrpcLibraryFactory() android.Module {
+	module := &Library{}
+
+	module.addHostAndDeviceProperties()
+	module.grpcProperties = &GrpcProperties{}
+	module.AddProperties(module.grpcProperties)
+
+	module.initModuleAndImport(module)
+
+	android.InitApexModule(module)
+	InitJavaModule(module, android.HostAndDeviceSupported)
+	return module
+}
+
+var grpcPluginTag = dependencyTag{name: "grpc-plugin", toolchain: true}
+
+// grpcDeps adds the dependencies on the gRPC plugin and on the gRPC runtime libraries.
+func grpcDeps(ctx android.BottomUpMutatorContext, g *GrpcProperties, p *android.ProtoProperties) {
+	ctx.AddFarVariationDependencies(ctx.Config().BuildOSTarget.Variations(), grpcPluginTag,
+		proptools.StringDefault(g.Grpc.Plugin, grpcJavaPluginDefault))
+
+	libs := g.Grpc.Runtime_libs
+	if libs == nil {
+		switch String(p.Proto.Type) {
+		case "lite", "":
+			libs = []string{"grpc-java-lite"}
+		case "full":
+			libs = []string{"grpc-java"}
+		}
+	}
+	ctx.AddVariationDependencies(nil, staticLibTag, libs...)
+}
+
+func grpcFlags(ctx android.ModuleContext, g *GrpcProperties, p *android.ProtoProperties,
+	flags javaBuilderFlags) javaBuilderFlags {
+
+	if String(p.Proto.Plugin) != "" {
+		ctx.PropertyErrorf("proto.plugin", "not supported by java_grpc_library")
+		return flags
+	}
+
+	// Omit the @javax.annotation.Generated annotation, which is not available on Android.
+	flags.grpcOutParams = []string{"@generated=omit"}
+	switch String(p.Proto.Type) {
+	case "lite", "":
+		flags.grpcOutParams = append(flags.grpcOutParams, "lite")
+	case "full":
+	default:
+		ctx.PropertyErrorf("proto.type", "gRPC stubs are only supported for lite and full protos, not %q",
+			String(p.Proto.Type))
+	}
+	flags.grpcOutParams = append(flags.grpcOutParams, g.Grpc.Output_params...)
+
+	ctx.VisitDirectDepsWithTag(grpcPluginTag, func(dep android.Module) {
+		if hostTool, ok := dep.(android.HostToolProvider); !ok || !hostTool.HostToolPath().Valid() {
+			ctx.PropertyErrorf("grpc.plugin", "module %q is not a host tool provider", ctx.OtherModuleName(dep))
+		} else {
+			plugin := hostTool.HostToolPath().Path()
+			flags.proto.Deps = append(flags.proto.Deps, plugin)
+			flags.proto.Flags = append(flags.proto.Flags, "--plugin=protoc-gen-grpc-java="+plugin.String())
+		}
+	})
+
+	return flags
+}
+
 type protoAttributes struct {
 	Deps         bazel.LabelListAttribute
 	Sdk_version  bazel.StringAttribute
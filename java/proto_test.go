@@ -51,3 +51,81 @@ func TestProtoStream(t *testing.T) {
 		t.Errorf("expected '--javastream_out' in %q", cmd)
 	}
 }
+
+func TestGrpcLibrary(t *testing.T) {
+	bp := `
+		java_library_static {
+			name: "grpc-java-lite",
+		}
+
+		java_binary_host {
+			name: "protoc-gen-grpc-java-plugin-1.51",
+			srcs: ["a.java"],
+		}
+
+		filegroup {
+			name: "foo-protos",
+			srcs: ["foo.proto"],
+		}
+
+		java_grpc_library {
+			name: "foo-grpc",
+			srcs: [":foo-protos"],
+			grpc: {
+				plugin: "protoc-gen-grpc-java-plugin-1.51",
+				output_params: ["enable_deprecated=false"],
+			},
+			sdk_version: "current",
+		}
+	`
+
+	result := android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithJava,
+	).RunTestWithBp(t, protoModules+bp)
+
+	foo := result.ModuleForTests("foo-grpc", "android_common")
+	cmd := foo.Output("proto/proto0.srcjar").RuleParams.Command
+	android.AssertStringDoesContain(t, "message classes", cmd, "--java_out=lite:")
+	android.AssertStringDoesContain(t, "grpc stubs", cmd,
+		"--grpc-java_out=@generated=omit,lite,enable_deprecated=false:out/soong/.intermediates/foo-grpc/android_common/gen/proto/proto0.tmp")
+	android.AssertStringDoesContain(t, "grpc plugin", cmd,
+		"--plugin=protoc-gen-grpc-java=out/soong/host/linux-x86/bin/protoc-gen-grpc-java-plugin-1.51")
+	android.AssertStringListContains(t, "grpc plugin dependency",
+		foo.Output("proto/proto0.srcjar").Implicits.RelativeToTop().Strings(),
+		"out/soong/host/linux-x86/bin/protoc-gen-grpc-java-plugin-1.51")
+
+	// The library is compiled against the proto and gRPC runtime libraries.
+	classpath := foo.Output("javac/foo-grpc.jar").Args["classpath"]
+	android.AssertStringDoesContain(t, "proto runtime", classpath,
+		"out/soong/.intermediates/libprotobuf-java-lite/android_common/turbine-combined/libprotobuf-java-lite.jar")
+	android.AssertStringDoesContain(t, "grpc runtime", classpath,
+		"out/soong/.intermediates/grpc-java-lite/android_common/turbine-combined/grpc-java-lite.jar")
+}
+
+func TestGrpcLibraryUnsupportedProtoType(t *testing.T) {
+	android.GroupFixturePreparers(
+		PrepareForIntegrationTestWithJava,
+	).ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+		`proto.type: gRPC stubs are only supported for lite and full protos, not "nano"`,
+	)).RunTestWithBp(t, protoModules+`
+		java_library_static {
+			name: "libprotobuf-java-nano",
+		}
+
+		java_binary_host {
+			name: "protoc-gen-grpc-java-plugin",
+			srcs: ["a.java"],
+		}
+
+		java_grpc_library {
+			name: "foo-grpc",
+			srcs: ["foo.proto"],
+			proto: {
+				type: "nano",
+			},
+			grpc: {
+				runtime_libs: [],
+			},
+		}
+	`)
+}
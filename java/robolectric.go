@@ -100,6 +100,12 @@ func (r *robolectricTest) TestSuites() []string {
 
 var _ android.TestSuiteModule = (*robolectricTest)(nil)
 
+func (r *robolectricTest) TestConfig() android.Path {
+	return r.testConfig
+}
+
+var _ android.TestConfigModule = (*robolectricTest)(nil)
+
 func (r *robolectricTest) DepsMutator(ctx android.BottomUpMutatorContext) {
 	r.Library.DepsMutator(ctx)
 
@@ -436,7 +442,7 @@ func (r *robolectricRuntimes) GenerateAndroidBuildActions(ctx android.ModuleCont
 	if !ctx.Config().AlwaysUsePrebuiltSdks() && r.props.Lib != nil {
 		runtimeFromSourceModule := ctx.GetDirectDepWithTag(String(r.props.Lib), libTag)
 		if runtimeFromSourceModule == nil {
-			if ctx.Config().AllowMissingDependencies() {
+			if ctx.AllowMissingDependencies() {
 				ctx.AddMissingDependencies([]string{String(r.props.Lib)})
 			} else {
 				ctx.PropertyErrorf("lib", "missing dependency %q", String(r.props.Lib))
@@ -114,7 +114,7 @@ func decodeSdkDep(ctx android.EarlyModuleContext, sdkContext android.SdkContext)
 		aidlPath := android.ExistentPathForSource(ctx, aidl)
 		lambdaStubsPath := android.PathForSource(ctx, config.SdkLambdaStubsPath)
 
-		if (!jarPath.Valid() || !aidlPath.Valid()) && ctx.Config().AllowMissingDependencies() {
+		if (!jarPath.Valid() || !aidlPath.Valid()) && ctx.AllowMissingDependencies() {
 			return sdkDep{
 				invalidVersion: true,
 				bootclasspath:  []string{fmt.Sprintf("sdk_%s_%s_android", sdkVersion.Kind, sdkVersion.ApiLevel.String())},
@@ -316,7 +316,7 @@ func createFrameworkAidl(stubsModules []string, path android.WritablePath, ctx a
 
 	for i := range stubsJars {
 		if stubsJars[i] == nil {
-			if ctx.Config().AllowMissingDependencies() {
+			if ctx.Config().DeferMissingModuleErrors() {
 				missingDeps = append(missingDeps, stubsModules[i])
 			} else {
 				ctx.Errorf("failed to find dex jar path for module %q", stubsModules[i])
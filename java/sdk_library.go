@@ -931,6 +931,24 @@ func (c *commonToSdkLibraryAndImport) commonOutputFiles(tag string) (android.Pat
 	}
 }
 
+// commonOutputFileTags returns the tags supported by commonOutputFiles for the api scopes provided
+// by the module.
+func (c *commonToSdkLibraryAndImport) commonOutputFileTags() []string {
+	var tags []string
+	for _, name := range allScopeNames {
+		if c.findScopePaths(scopeByName[name]) == nil {
+			continue
+		}
+		for _, component := range []string{stubsSourceComponentName, apiTxtComponentName, removedApiTxtComponentName, annotationsComponentName} {
+			tags = append(tags, "."+name+"."+component)
+		}
+	}
+	if c.doctagPaths != nil {
+		tags = append(tags, ".doctags")
+	}
+	return tags
+}
+
 func (c *commonToSdkLibraryAndImport) getScopePathsCreateIfNeeded(scope *apiScope) *scopePaths {
 	if c.scopePaths == nil {
 		c.scopePaths = make(map[*apiScope]*scopePaths)
@@ -973,6 +991,12 @@ func (c *commonToSdkLibraryAndImport) selectHeaderJarsForSdkVersion(ctx android.
 		return PrebuiltJars(ctx, c.module.BaseModuleName(), sdkVersion)
 	}
 
+	// If the interfaces are frozen on this branch then use the latest frozen version of the sdk
+	// rather than the current one, which must be identical to it.
+	if frozen, ok := frozenSdkSpec(ctx, sdkVersion); ok {
+		return PrebuiltJars(ctx, c.module.BaseModuleName(), frozen)
+	}
+
 	paths := c.selectScopePaths(ctx, sdkVersion.Kind)
 	if paths == nil {
 		return nil
@@ -981,6 +1005,23 @@ func (c *commonToSdkLibraryAndImport) selectHeaderJarsForSdkVersion(ctx android.
 	return paths.stubsHeaderPath
 }
 
+// frozenSdkSpec returns the latest frozen version of sdkVersion if the interfaces are frozen on
+// this branch and sdkVersion is the current version of an sdk kind that has prebuilt versions.
+func frozenSdkSpec(ctx android.BaseModuleContext, sdkVersion android.SdkSpec) (android.SdkSpec, bool) {
+	if !ctx.Config().FrozenInterfaces() || !sdkVersion.ApiLevel.IsCurrent() {
+		return android.SdkSpec{}, false
+	}
+	switch sdkVersion.Kind {
+	case android.SdkPublic, android.SdkSystem, android.SdkTest, android.SdkModule, android.SdkSystemServer:
+		return android.SdkSpec{
+			Kind:     sdkVersion.Kind,
+			ApiLevel: ctx.Config().PlatformSdkVersion(),
+			Raw:      sdkVersion.Raw,
+		}, true
+	}
+	return android.SdkSpec{}, false
+}
+
 // selectScopePaths returns the *scopePaths appropriate for the specific kind.
 //
 // If the module does not support the specific kind then it will return the *scopePaths for the
@@ -1346,6 +1387,14 @@ func (module *SdkLibrary) OutputFiles(tag string) (android.Paths, error) {
 	return nil, fmt.Errorf("unsupported module reference tag %q", tag)
 }
 
+func (module *SdkLibrary) OutputFileTags() []string {
+	tags := module.commonOutputFileTags()
+	if module.requiresRuntimeImplementationLibrary() {
+		tags = append(tags, module.Library.OutputFileTags()...)
+	}
+	return tags
+}
+
 func (module *SdkLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	if proptools.String(module.deviceProperties.Min_sdk_version) != "" {
 		module.CheckMinSdkVersion(ctx)
@@ -1823,7 +1872,7 @@ func PrebuiltJars(ctx android.BaseModuleContext, baseName string, s android.SdkS
 	jar := filepath.Join(dir, baseName+".jar")
 	jarPath := android.ExistentPathForSource(ctx, jar)
 	if !jarPath.Valid() {
-		if ctx.Config().AllowMissingDependencies() {
+		if ctx.AllowMissingDependencies() {
 			return android.Paths{android.PathForSource(ctx, jar)}
 		} else {
 			ctx.PropertyErrorf("sdk_library", "invalid sdk version %q, %q does not exist", s.Raw, jar)
@@ -1923,7 +1972,7 @@ func (module *SdkLibrary) CreateInternalModules(mctx android.DefaultableHookCont
 			path := path.Join(mctx.ModuleDir(), apiDir, scope.apiFilePrefix+api)
 			p := android.ExistentPathForSource(mctx, path)
 			if !p.Valid() {
-				if mctx.Config().AllowMissingDependencies() {
+				if mctx.AllowMissingDependencies() {
 					mctx.AddMissingDependencies([]string{path})
 				} else {
 					mctx.ModuleErrorf("Current api file %#v doesn't exist", path)
@@ -2435,6 +2484,14 @@ func (module *SdkLibraryImport) OutputFiles(tag string) (android.Paths, error) {
 	}
 }
 
+func (module *SdkLibraryImport) OutputFileTags() []string {
+	tags := module.commonOutputFileTags()
+	if module.implLibraryModule != nil {
+		tags = append(tags, module.implLibraryModule.OutputFileTags()...)
+	}
+	return tags
+}
+
 func (module *SdkLibraryImport) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	module.generateCommonBuildActions(ctx)
 
@@ -178,6 +178,45 @@ func TestJavaSdkLibrary(t *testing.T) {
 	android.AssertStringDoesContain(t, "bar dex", barDexJar.BuildParams.Args["mergeZipsFlags"], "-stripFile META-INF/*.kotlin_module")
 }
 
+func TestJavaSdkLibrary_FrozenInterfaces(t *testing.T) {
+	result := android.GroupFixturePreparers(
+		prepareForJavaTest,
+		PrepareForTestWithJavaSdkLibraryFiles,
+		FixtureWithPrebuiltApis(map[string][]string{
+			"30": {"foo"},
+		}),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.Frozen_interfaces = proptools.BoolPtr(true)
+		}),
+	).RunTestWithBp(t, `
+		java_sdk_library {
+			name: "foo",
+			srcs: ["a.java"],
+			api_packages: ["foo"],
+		}
+		java_library {
+			name: "baz",
+			srcs: ["c.java"],
+			libs: ["foo"],
+			sdk_version: "system_current",
+		}
+		java_library {
+			name: "baz-platform",
+			srcs: ["c.java"],
+			libs: ["foo"],
+		}
+	`)
+
+	// The clients of the current API use the latest frozen version of it.
+	bazJavac := result.ModuleForTests("baz", "android_common").Rule("javac")
+	android.AssertStringDoesContain(t, "baz javac classpath", bazJavac.Args["classpath"], "prebuilts/sdk/30/system/foo.jar")
+	android.AssertStringDoesNotContain(t, "baz javac classpath", bazJavac.Args["classpath"], "foo.stubs.system.jar")
+
+	// The platform clients still use the implementation library.
+	bazPlatformJavac := result.ModuleForTests("baz-platform", "android_common").Rule("javac")
+	android.AssertStringDoesNotContain(t, "baz-platform javac classpath", bazPlatformJavac.Args["classpath"], "prebuilts/sdk/30/")
+}
+
 func TestJavaSdkLibrary_UpdatableLibrary(t *testing.T) {
 	result := android.GroupFixturePreparers(
 		prepareForJavaTest,
@@ -292,6 +292,14 @@ func FixtureConfigureBootJars(bootJars ...string) android.FixturePreparer {
 	)
 }
 
+// FixtureConfigureBootImageExtensions configures the boot_image_extension modules whose boot
+// images are built for the product.
+func FixtureConfigureBootImageExtensions(names ...string) android.FixturePreparer {
+	return android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.BootImageExtensions = names
+	})
+}
+
 // FixtureConfigureApexBootJars configures the apex boot jars in both the
 // dexpreopt.GlobalConfig and Config.productVariables structs. As a side effect that enables
 // dexpreopt.
@@ -340,10 +348,14 @@ func registerRequiredBuildComponentsForTest(ctx android.RegistrationContext) {
 	RegisterAppSetBuildComponents(ctx)
 	registerBootclasspathBuildComponents(ctx)
 	registerBootclasspathFragmentBuildComponents(ctx)
+	registerBootImageExtensionBuildComponents(ctx)
+	registerBootImageProfileBuildComponents(ctx)
 	RegisterDexpreoptBootJarsComponents(ctx)
 	RegisterDocsBuildComponents(ctx)
 	RegisterGenRuleBuildComponents(ctx)
+	registerHostBootImageBuildComponents(ctx)
 	registerJavaBuildComponents(ctx)
+	registerManifestMergerPolicyBuildComponents(ctx)
 	registerPlatformBootclasspathBuildComponents(ctx)
 	RegisterPrebuiltApisBuildComponents(ctx)
 	RegisterRuntimeResourceOverlayBuildComponents(ctx)
@@ -482,6 +494,21 @@ func CheckModuleDependencies(t *testing.T, ctx *android.TestContext, name, varia
 	}
 }
 
+// BootImageDex2oatCommandForTests returns the dex2oat command line of the boot image rule with the
+// given name, which is run by sbox from a manifest.
+func BootImageDex2oatCommandForTests(t *testing.T, module android.TestingModule, rule string) string {
+	t.Helper()
+	params := module.Rule(rule)
+	for _, input := range params.Implicits {
+		if input.Base() == "sbox.textproto" {
+			manifest := android.RuleBuilderSboxProtoForTests(t, module.Output(input.String()))
+			return manifest.Commands[0].GetCommand()
+		}
+	}
+	t.Fatalf("boot image rule %q is not run by sbox", rule)
+	return ""
+}
+
 // CheckPlatformBootclasspathModules returns the apex:module pair for the modules depended upon by
 // the platform-bootclasspath module.
 func CheckPlatformBootclasspathModules(t *testing.T, result *android.TestResult, name string, expected []string) {
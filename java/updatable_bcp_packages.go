@@ -0,0 +1,56 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+// The platform_bootclasspath module generates updatable-bcp-packages.txt, the list of the packages
+// of the jars delivered by APEXes on the bootclasspath and on the system server classpath, i.e. the
+// permitted_packages of the apex boot jars and of the apex system server jars. It is passed to
+// dex2oat with --updatable-bcp-packages-file when compiling the framework boot image, so that it
+// does not depend on classes of these packages, which may change with an APEX update, and installed
+// in /system/etc. The boot image extensions, e.g. the mainline image, are compiled without it.
+
+const updatableBcpPackagesName = "updatable-bcp-packages.txt"
+
+// updatableBcpPackagesRule generates updatable-bcp-packages.txt in the directory of the image from
+// the permitted packages of the given modules, and returns its path along with its installs. It
+// returns an invalid path if none of the modules has permitted packages, e.g. on builds without
+// APEXes.
+func updatableBcpPackagesRule(ctx android.ModuleContext, image *bootImageConfig, modules []android.Module) (android.OptionalPath, android.RuleBuilderInstalls) {
+	var packages []string
+	for _, module := range modules {
+		if j, ok := module.(PermittedPackagesForUpdatableBootJars); ok {
+			packages = append(packages, j.PermittedPackagesForUpdatableBootJars()...)
+		}
+	}
+	if len(packages) == 0 {
+		return android.OptionalPath{}, nil
+	}
+
+	// Sort the packages to ensure deterministic ordering.
+	packages = android.SortedUniqueStrings(packages)
+
+	path := image.dir.Join(ctx, updatableBcpPackagesName)
+	android.WriteFileRule(ctx, path, strings.Join(packages, "\n"))
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Install(path, "/system/etc/"+updatableBcpPackagesName)
+	return android.OptionalPathForPath(path), rule.Installs()
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint"
+)
+
+// Windows launcher bundles of the java host binaries with windows_launcher: true. A bundle is a
+// zip with a bin/<name>.bat launcher that runs the jar in lib/<stem>.jar, so the java host tools
+// that are shipped in the win_sdk dist are selected by the module graph instead of separate Make
+// packaging rules.
+
+func init() {
+	android.RegisterSingletonType("windows_launchers", windowsLaunchersSingletonFactory)
+}
+
+// WindowsLauncherInfo is provided by the java host binaries that build a Windows launcher bundle.
+type WindowsLauncherInfo struct {
+	// The zip of the bundle, with the launcher in bin/ and the jar in lib/.
+	Bundle android.Path
+}
+
+var WindowsLauncherInfoProvider = blueprint.NewProvider(WindowsLauncherInfo{})
+
+// windowsLauncherScript returns the content of the launcher of a binary, with Unix line endings.
+func windowsLauncherScript(name, jarName string) string {
+	return strings.Join([]string{
+		"@echo off",
+		fmt.Sprintf("rem Launcher of %s, generated by the build. Do not edit.", name),
+		"setlocal",
+		"set java_exe=java",
+		`if defined JAVA_HOME set "java_exe=%JAVA_HOME%\bin\java.exe"`,
+		fmt.Sprintf(`"%%java_exe%%" %%JAVA_OPTS%% -jar "%%~dp0..\lib\%s" %%*`, jarName),
+		"exit /b %ERRORLEVEL%",
+	}, "\n")
+}
+
+// buildWindowsLauncher builds the Windows launcher bundle of the host binary in the common variant.
+func (j *Binary) buildWindowsLauncher(ctx android.ModuleContext) {
+	if !ctx.Host() {
+		ctx.PropertyErrorf("windows_launcher", "windows_launcher is only supported for host binaries")
+		return
+	}
+	if j.binaryProperties.Main_class == nil && j.properties.Manifest == nil {
+		ctx.PropertyErrorf("windows_launcher", "windows_launcher requires main_class or manifest "+
+			"so that the jar can be run with java -jar")
+		return
+	}
+	if j.implementationAndResourcesJar == nil {
+		return
+	}
+
+	name := ctx.ModuleName()
+	jarName := j.Stem() + ".jar"
+	script := android.PathForModuleOut(ctx, "windows_launcher", name+".bat.sh")
+	android.WriteFileRule(ctx, script, windowsLauncherScript(name, jarName))
+
+	launcher := android.PathForModuleOut(ctx, "windows_launcher", name+".bat")
+	bundle := android.PathForModuleOut(ctx, "windows_launcher", name+"-windows.zip")
+
+	rule := android.NewRuleBuilder(pctx, ctx)
+	// Windows batch files need DOS line endings.
+	rule.Command().Text("sed -e 's/$/\\r/'").Input(script).Text(">").Output(launcher)
+	rule.Command().
+		BuiltTool("soong_zip").
+		FlagWithOutput("-o ", bundle).
+		FlagWithArg("-e ", "bin/"+name+".bat").
+		FlagWithInput("-f ", launcher).
+		FlagWithArg("-e ", "lib/"+jarName).
+		FlagWithInput("-f ", j.implementationAndResourcesJar)
+	rule.Build("windows_launcher", "Windows launcher bundle of "+name)
+
+	ctx.SetProvider(WindowsLauncherInfoProvider, WindowsLauncherInfo{
+		Bundle: bundle,
+	})
+}
+
+func windowsLaunchersSingletonFactory() android.Singleton {
+	return &windowsLaunchersSingleton{}
+}
+
+// windowsLaunchersSingleton merges the Windows launcher bundles of all the java host binaries
+// into a single zip that is distributed with the win_sdk goal.
+type windowsLaunchersSingleton struct {
+	outputPath android.Path
+}
+
+var _ android.SingletonMakeVarsProvider = (*windowsLaunchersSingleton)(nil)
+
+func (s *windowsLaunchersSingleton) GenerateBuildActions(ctx android.SingletonContext) {
+	var bundles android.Paths
+	ctx.VisitAllModules(func(module android.Module) {
+		if !module.Enabled() || !android.IsModulePreferred(module) {
+			return
+		}
+		if ctx.ModuleHasProvider(module, WindowsLauncherInfoProvider) {
+			info := ctx.ModuleProvider(module, WindowsLauncherInfoProvider).(WindowsLauncherInfo)
+			bundles = append(bundles, info.Bundle)
+		}
+	})
+	if len(bundles) == 0 {
+		return
+	}
+
+	output := android.PathForOutput(ctx, "windows_launchers", "java-host-tools-windows.zip")
+	rule := android.NewRuleBuilder(pctx, ctx)
+	rule.Command().
+		BuiltTool("merge_zips").
+		Output(output).
+		Inputs(android.SortedUniquePaths(bundles))
+	rule.Build("windows_launchers", "merge the Windows launcher bundles")
+
+	ctx.Phony("windows_java_launchers", output)
+	s.outputPath = output
+}
+
+func (s *windowsLaunchersSingleton) MakeVars(ctx android.MakeVarsContext) {
+	if s.outputPath == nil {
+		return
+	}
+
+	ctx.DistForGoal("win_sdk", s.outputPath)
+}
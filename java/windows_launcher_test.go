@@ -0,0 +1,80 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package java
+
+import (
+	"testing"
+
+	"android/soong/android"
+)
+
+var prepareForTestWithWindowsLaunchers = android.GroupFixturePreparers(
+	PrepareForTestWithJavaDefaultModules,
+	android.FixtureRegisterWithContext(func(ctx android.RegistrationContext) {
+		ctx.RegisterSingletonType("windows_launchers", windowsLaunchersSingletonFactory)
+	}),
+)
+
+func TestWindowsLauncher(t *testing.T) {
+	result := prepareForTestWithWindowsLaunchers.RunTestWithBp(t, `
+		java_binary_host {
+			name: "foo",
+			srcs: ["a.java"],
+			main_class: "com.android.Foo",
+			windows_launcher: true,
+		}
+
+		java_binary_host {
+			name: "bar",
+			srcs: ["b.java"],
+			main_class: "com.android.Bar",
+		}
+	`)
+
+	buildOS := result.Config.BuildOS.String()
+	foo := result.ModuleForTests("foo", buildOS+"_common")
+
+	script := android.ContentFromFileRuleForTests(t, foo.Output("windows_launcher/foo.bat.sh"))
+	android.AssertStringDoesContain(t, "launcher", script,
+		`"%java_exe%" %JAVA_OPTS% -jar "%~dp0..\lib\foo.jar" %*`)
+
+	bundle := foo.Output("windows_launcher/foo-windows.zip")
+	android.AssertStringDoesContain(t, "bundle command", bundle.RuleParams.Command,
+		"-e bin/foo.bat -f out/soong/.intermediates/foo/linux_glibc_common/windows_launcher/foo.bat")
+	android.AssertStringDoesContain(t, "bundle command", bundle.RuleParams.Command,
+		"-e lib/foo.jar -f out/soong/.intermediates/foo/linux_glibc_common/combined/foo.jar")
+
+	bar := result.ModuleForTests("bar", buildOS+"_common")
+	barInfo := result.ModuleProvider(bar.Module(), WindowsLauncherInfoProvider).(WindowsLauncherInfo)
+	android.AssertBoolEquals(t, "bar has no bundle", true, barInfo.Bundle == nil)
+
+	merged := result.SingletonForTests("windows_launchers").Output("windows_launchers/java-host-tools-windows.zip")
+	android.AssertPathsRelativeToTopEquals(t, "merged bundles",
+		[]string{"out/soong/.intermediates/foo/linux_glibc_common/windows_launcher/foo-windows.zip"},
+		merged.Inputs)
+}
+
+func TestWindowsLauncherRequiresMainClass(t *testing.T) {
+	prepareForTestWithWindowsLaunchers.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`windows_launcher: windows_launcher requires main_class or manifest`)).
+		RunTestWithBp(t, `
+			java_binary_host {
+				name: "foo",
+				srcs: ["a.java"],
+				windows_launcher: true,
+			}
+		`)
+}
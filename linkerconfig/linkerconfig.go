@@ -49,6 +49,16 @@ type linkerConfigProperties struct {
 	// Installable should be marked as false for APEX configuration to avoid
 	// conflicts of configuration on /system/etc directory.
 	Installable *bool
+
+	// How the provideLibs of the configuration are generated, one of:
+	// "stubs": the libraries of the system partition that declare stubs or LLNDK stubs and that the
+	//   product installs, the provideLibs of src are ignored. The default for the configurations
+	//   installed on the system partition.
+	// "src": the provideLibs of src. The default for the other configurations, e.g. the ones of the
+	//   APEXes or of the vendor partition.
+	// "diff": the provideLibs of src, with a warning listing their differences to the ones of
+	//   "stubs", to migrate the configuration to it.
+	Provide_libs *string
 }
 
 type linkerConfig struct {
@@ -91,7 +101,31 @@ func (l *linkerConfig) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	output := android.PathForModuleOut(ctx, "linker.config.pb").OutputPath
 
 	builder := android.NewRuleBuilder(pctx, ctx)
-	BuildLinkerConfig(ctx, builder, input, nil, output)
+	defaultProvideLibs := "src"
+	if l.installedOnSystem(ctx) {
+		defaultProvideLibs = "stubs"
+	}
+	switch provideLibs := proptools.StringDefault(l.properties.Provide_libs, defaultProvideLibs); provideLibs {
+	case "src":
+		BuildLinkerConfig(ctx, builder, input, nil, output)
+	case "stubs", "diff":
+		srcOutput := android.PathForModuleOut(ctx, "linker.config.src.pb").OutputPath
+		BuildLinkerConfig(ctx, builder, input, nil, srcOutput)
+		cmd := builder.Command().
+			BuiltTool("conv_linker_config").
+			Flag("provide").
+			FlagWithInput("-s ", srcOutput).
+			FlagWithOutput("-o ", output).
+			FlagWithInput("--libs ", cc.SystemProvideLibsPath(ctx))
+		if provideLibs == "diff" {
+			cmd.FlagWithOutput("--diff ", android.PathForModuleOut(ctx, "provide_libs.diff"))
+		}
+		builder.Temporary(srcOutput)
+		builder.DeleteTemporaryFiles()
+	default:
+		ctx.PropertyErrorf("provide_libs", "invalid value %q, expected one of src, stubs, diff", provideLibs)
+		return
+	}
 	builder.Build("conv_linker_config", "Generate linker config protobuf "+output.String())
 
 	l.outputFilePath = output
@@ -102,6 +136,13 @@ func (l *linkerConfig) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	ctx.InstallFile(l.installDirPath, l.outputFilePath.Base(), l.outputFilePath)
 }
 
+// installedOnSystem returns true if the configuration is installed on the system partition, which
+// provides the libraries listed by cc.SystemProvideLibsPath.
+func (l *linkerConfig) installedOnSystem(ctx android.ModuleContext) bool {
+	return proptools.BoolDefault(l.properties.Installable, true) && !ctx.SocSpecific() &&
+		!ctx.DeviceSpecific() && !ctx.ProductSpecific() && !ctx.SystemExtSpecific()
+}
+
 type linkerConfigAttributes struct {
 	Src bazel.LabelAttribute
 }
@@ -89,3 +89,70 @@ func TestUninstallableLinkerConfig(t *testing.T) {
 		t.Errorf("LOCAL_UNINSTALLABLE_MODULE is not defined")
 	}
 }
+
+func TestLinkerConfigProvideLibs(t *testing.T) {
+	for _, tc := range []struct {
+		provideLibs string
+		libs        bool
+		diff        bool
+	}{
+		{provideLibs: "src"},
+		{provideLibs: "stubs", libs: true},
+		{provideLibs: "diff", libs: true, diff: true},
+	} {
+		t.Run(tc.provideLibs, func(t *testing.T) {
+			result := prepareForLinkerConfigTest.RunTestWithBp(t, `
+				linker_config {
+					name: "linker-config-base",
+					src: "linker.config.json",
+					provide_libs: "`+tc.provideLibs+`",
+				}
+			`)
+
+			m := result.ModuleForTests("linker-config-base", "android_arm64_armv8-a")
+			command := m.Rule("conv_linker_config").RuleParams.Command
+			android.AssertStringContainsEquals(t, "provide libs", command,
+				"--libs out/soong/linkerconfig/system_provide_libs.txt", tc.libs)
+			android.AssertStringContainsEquals(t, "diff", command, "--diff ", tc.diff)
+		})
+	}
+}
+
+func TestLinkerConfigDefaultProvideLibs(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		props string
+		libs  bool
+	}{
+		{name: "system", libs: true},
+		{name: "vendor", props: "vendor: true,"},
+		{name: "uninstallable", props: "installable: false,"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result := prepareForLinkerConfigTest.RunTestWithBp(t, `
+				linker_config {
+					name: "linker-config-base",
+					src: "linker.config.json",
+					`+tc.props+`
+				}
+			`)
+
+			m := result.ModuleForTests("linker-config-base", "android_arm64_armv8-a")
+			android.AssertStringContainsEquals(t, "provide libs", m.Rule("conv_linker_config").RuleParams.Command,
+				"--libs out/soong/linkerconfig/system_provide_libs.txt", tc.libs)
+		})
+	}
+}
+
+func TestLinkerConfigInvalidProvideLibs(t *testing.T) {
+	prepareForLinkerConfigTest.
+		ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(
+			`provide_libs: invalid value "all", expected one of src, stubs, diff`)).
+		RunTestWithBp(t, `
+			linker_config {
+				name: "linker-config-base",
+				src: "linker.config.json",
+				provide_libs: "all",
+			}
+		`)
+}
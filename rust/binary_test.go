@@ -131,6 +131,34 @@ func TestBootstrap(t *testing.T) {
 	}
 }
 
+func TestBinaryBuildIdStyle(t *testing.T) {
+	skipTestIfOsNotSupported(t)
+	result := android.GroupFixturePreparers(
+		prepareForRustTest,
+		rustMockedFiles.AddToFixture(),
+		android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+			variables.BuildIdStyle = StringPtr("sha1")
+			variables.PartitionBuildIdStyles = map[string]string{"vendor": "uuid"}
+		}),
+	).RunTestWithBp(t, `
+		rust_binary {
+			name: "foo",
+			srcs: ["foo.rs"],
+		}
+
+		rust_binary {
+			name: "bar",
+			srcs: ["bar.rs"],
+			vendor: true,
+		}`)
+
+	foo := result.ModuleForTests("foo", "android_arm64_armv8-a").Rule("rustLink")
+	android.AssertStringDoesContain(t, "build id of foo", foo.Args["linkFlags"], "-Wl,--build-id=sha1")
+
+	bar := result.ModuleForTests("bar", "android_arm64_armv8-a").Rule("rustLink")
+	android.AssertStringDoesContain(t, "build id of bar", bar.Args["linkFlags"], "-Wl,--build-id=uuid")
+}
+
 func TestStaticBinaryFlags(t *testing.T) {
 	ctx := testRust(t, `
 		rust_binary {
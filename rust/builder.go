@@ -19,6 +19,7 @@ import (
 	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
 	"android/soong/rust/config"
@@ -189,7 +190,7 @@ func makeLibFlags(deps PathDeps) []string {
 	return libFlags
 }
 
-func rustEnvVars(ctx ModuleContext, deps PathDeps) []string {
+func rustEnvVars(ctx ModuleContext, deps PathDeps, flags Flags) []string {
 	var envVars []string
 
 	// libstd requires a specific environment variable to be set. This is
@@ -217,6 +218,11 @@ func rustEnvVars(ctx ModuleContext, deps PathDeps) []string {
 		envVars = append(envVars, "OUT_DIR=out")
 	}
 
+	for _, envVar := range flags.EnvVars {
+		i := strings.Index(envVar, "=")
+		envVars = append(envVars, envVar[:i+1]+proptools.NinjaAndShellEscape(envVar[i+1:]))
+	}
+
 	return envVars
 }
 
@@ -232,7 +238,7 @@ func transformSrctoCrate(ctx ModuleContext, main android.Path, deps PathDeps, fl
 	crateName := ctx.RustModule().CrateName()
 	targetTriple := ctx.toolchain().RustTriple()
 
-	envVars := rustEnvVars(ctx, deps)
+	envVars := rustEnvVars(ctx, deps, flags)
 
 	inputs = append(inputs, main)
 
@@ -450,7 +456,7 @@ func Rustdoc(ctx ModuleContext, main android.Path, deps PathDeps,
 		Args: map[string]string{
 			"rustdocFlags": strings.Join(rustdocFlags, " "),
 			"outDir":       docDir.String(),
-			"envVars":      strings.Join(rustEnvVars(ctx, deps), " "),
+			"envVars":      strings.Join(rustEnvVars(ctx, deps, flags), " "),
 		},
 	})
 
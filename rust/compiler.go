@@ -23,6 +23,7 @@ import (
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
+	cc_config "android/soong/cc/config"
 	"android/soong/rust/config"
 )
 
@@ -140,6 +141,19 @@ type BaseCompilerProperties struct {
 	// list of configuration options to enable for this crate. To enable features, use the "features" property.
 	Cfgs []string `android:"arch_variant"`
 
+	// list of configuration options set from product variables, as "<cfg>=<variable>" entries, where
+	// <variable> is the name of a product variable, e.g. "Platform_sdk_version", or of a soong config
+	// variable as "<namespace>:<variable>[:<type>]", where <type> is "string" (the default), "bool"
+	// or "value", as declared by soong_config_module_type. A boolean variable enables the cfg if it
+	// is true, the other variables set the cfg to their value, e.g. platform_sdk_version="34". The
+	// cfg is not set if the variable is unset.
+	Product_variable_cfgs []string `android:"arch_variant"`
+
+	// list of environment variables set for rustc from product variables, e.g. to be read with env!(),
+	// as "<name>=<variable>" entries like product_variable_cfgs. The environment variable is set to
+	// the empty string if the variable is unset.
+	Product_variable_envs []string `android:"arch_variant"`
+
 	// specific rust edition that should be used if the default version is not desired
 	Edition *string `android:"arch_variant"`
 
@@ -278,6 +292,15 @@ func (compiler *baseCompiler) cfgFlags(ctx ModuleContext, flags Flags) Flags {
 
 	flags.RustFlags = append(flags.RustFlags, compiler.cfgsToFlags()...)
 	flags.RustdocFlags = append(flags.RustdocFlags, compiler.cfgsToFlags()...)
+
+	if info := compiler.productVariableCfgs(ctx); len(info.Cfgs) > 0 || len(info.EnvVars) > 0 {
+		for _, cfg := range info.Cfgs {
+			flags.RustFlags = append(flags.RustFlags, "--cfg '"+cfg+"'")
+			flags.RustdocFlags = append(flags.RustdocFlags, "--cfg '"+cfg+"'")
+		}
+		flags.EnvVars = append(flags.EnvVars, info.EnvVars...)
+		ctx.SetProvider(ProductVariableCfgsInfoProvider, info)
+	}
 	return flags
 }
 
@@ -314,6 +337,10 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags) Flag
 	flags.GlobalRustFlags = append(flags.GlobalRustFlags, config.GlobalRustFlags...)
 	flags.GlobalRustFlags = append(flags.GlobalRustFlags, ctx.toolchain().ToolchainRustFlags())
 	flags.GlobalLinkFlags = append(flags.GlobalLinkFlags, ctx.toolchain().ToolchainLinkFlags())
+	if ctx.Device() {
+		flags.GlobalLinkFlags = append(flags.GlobalLinkFlags,
+			cc_config.DeviceBuildIdLdflag(ctx.Config(), buildIdPartition(ctx)))
+	}
 	flags.EmitXrefs = ctx.Config().EmitXrefRules()
 
 	if ctx.Host() && !ctx.Windows() {
@@ -323,6 +350,21 @@ func (compiler *baseCompiler) compilerFlags(ctx ModuleContext, flags Flags) Flag
 	return flags
 }
 
+// buildIdPartition returns the partition whose build id style applies to the module, as cc does.
+func buildIdPartition(ctx ModuleContext) string {
+	partition := ctx.Module().PartitionTag(ctx.DeviceConfig())
+	if partition == "system" {
+		// The vendor and product variants of the modules available to vendor or product are
+		// installed in those partitions.
+		if ctx.RustModule().InVendor() {
+			partition = "vendor"
+		} else if ctx.RustModule().InProduct() {
+			partition = "product"
+		}
+	}
+	return partition
+}
+
 func (compiler *baseCompiler) compile(ctx ModuleContext, flags Flags, deps PathDeps) buildOutput {
 	panic(fmt.Errorf("baseCrater doesn't know how to crate things!"))
 }
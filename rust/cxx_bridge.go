@@ -0,0 +1,147 @@
+// Copyright 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/google/blueprint/proptools"
+
+	"android/soong/android"
+	"android/soong/cc"
+	"android/soong/genrule"
+)
+
+const (
+	// The host tool that generates the C++ side of a cxx bridge.
+	cxxBridgeTool = "cxxbridge"
+
+	// The path of the header of the cxx runtime, as included by the generated sources.
+	cxxBridgeRuntimeHeader = "rust/cxx.h"
+)
+
+func init() {
+	android.RegisterModuleType("rust_cxx_bridge", RustCxxBridgeFactory)
+}
+
+type CxxBridgeProperties struct {
+	// The Rust source file that declares the #[cxx::bridge] module. The same file must be listed
+	// in the srcs of the rust module that implements the Rust side of the bridge.
+	Src *string `android:"path"`
+
+	// Additional headers to #include in the generated C++ source, e.g. the headers that declare
+	// the C++ types and functions used by the bridge.
+	Includes []string
+
+	// An annotation to apply to the C++ functions that are implemented in Rust, e.g. a visibility
+	// attribute.
+	Cxx_impl_annotations *string
+}
+
+// rust_cxx_bridge runs the cxx code generator on a Rust source file containing a #[cxx::bridge]
+// module, and generates the C++ side of the bridge: the <src>.h header, the <src>.cc source and
+// the rust/cxx.h header of the cxx runtime. The generated files are consumed by cc modules
+// through generated_headers and generated_sources.
+//
+// The module only generates the C++ side. The Rust side needs no generated code, as the
+// #[cxx::bridge] macro of the cxx crate expands it when the crate is compiled, so it is built by a
+// regular rust module that lists src in its srcs and depends on the cxx crate. autocxx is not
+// supported. For example:
+//
+//	rust_cxx_bridge {
+//	    name: "libfoo_bridge",
+//	    src: "src/lib.rs",
+//	}
+//
+//	cc_library_static {
+//	    name: "libfoo_bridge_cc",
+//	    srcs: ["foo.cc"],
+//	    generated_headers: ["libfoo_bridge"],
+//	    generated_sources: ["libfoo_bridge"],
+//	    export_generated_headers: ["libfoo_bridge"],
+//	}
+//
+//	rust_library {
+//	    name: "libfoo",
+//	    crate_name: "foo",
+//	    srcs: ["src/lib.rs"],
+//	    rustlibs: ["libcxx"],
+//	    static_libs: ["libfoo_bridge_cc"],
+//	}
+//
+// The module has the same variants as cc_genrule, so that it can be used by cc modules for any
+// image, including vendor and recovery.
+func RustCxxBridgeFactory() android.Module {
+	module := genrule.NewGenRule()
+
+	properties := &CxxBridgeProperties{}
+	extra := &cc.GenruleExtraProperties{}
+	module.Extra = extra
+	module.ImageInterface = extra
+	module.AddProperties(properties, extra)
+
+	android.AddLoadHook(module, func(ctx android.LoadHookContext) {
+		cxxBridgeLoadHook(ctx, properties)
+	})
+
+	android.InitAndroidArchModule(module, android.HostAndDeviceSupported, android.MultilibBoth)
+	android.InitApexModule(module)
+
+	return module
+}
+
+// cxxBridgeLoadHook sets the genrule properties that run the cxx code generator, so that the
+// generated header and source are always built with the same flags.
+func cxxBridgeLoadHook(ctx android.LoadHookContext, properties *CxxBridgeProperties) {
+	src := proptools.String(properties.Src)
+	if src == "" {
+		ctx.PropertyErrorf("src", "missing the Rust source file of the cxx bridge")
+		return
+	}
+	if filepath.Ext(src) != ".rs" {
+		ctx.PropertyErrorf("src", "%q is not a Rust source file", src)
+		return
+	}
+
+	var flags []string
+	for _, include := range properties.Includes {
+		flags = append(flags, "--include "+proptools.ShellEscape(include))
+	}
+	if annotations := proptools.String(properties.Cxx_impl_annotations); annotations != "" {
+		flags = append(flags, "--cxx-impl-annotations "+proptools.ShellEscape(annotations))
+	}
+
+	header := filepath.Base(src) + ".h"
+	source := filepath.Base(src) + ".cc"
+	tool := "$(location " + cxxBridgeTool + ")"
+	cmd := strings.Join([]string{
+		strings.Join(append([]string{tool, "$(in)", "--header", "-o $(genDir)/" + header}, flags...), " "),
+		strings.Join(append([]string{tool, "$(in)", "-o $(genDir)/" + source}, flags...), " "),
+		tool + " --header -o $(genDir)/" + cxxBridgeRuntimeHeader,
+	}, " && ")
+
+	ctx.AppendProperties(&struct {
+		Srcs  []string
+		Out   []string
+		Tools []string
+		Cmd   *string
+	}{
+		Srcs:  []string{src},
+		Out:   []string{header, source, cxxBridgeRuntimeHeader},
+		Tools: []string{cxxBridgeTool},
+		Cmd:   proptools.StringPtr(cmd),
+	})
+}
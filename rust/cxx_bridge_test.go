@@ -0,0 +1,92 @@
+// Copyright 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"testing"
+
+	"android/soong/android"
+	"android/soong/genrule"
+)
+
+func TestRustCxxBridge(t *testing.T) {
+	ctx := testRust(t, `
+		rust_binary_host {
+			name: "cxxbridge",
+			srcs: ["cxxbridge.rs"],
+		}
+		rust_cxx_bridge {
+			name: "libfoo_bridge",
+			src: "src/lib.rs",
+			includes: ["foo/foo.h"],
+			cxx_impl_annotations: "__attribute__((visibility(\"default\")))",
+		}
+		cc_library_static {
+			name: "libfoo_bridge_cc",
+			srcs: ["foo.cc"],
+			generated_headers: ["libfoo_bridge"],
+			generated_sources: ["libfoo_bridge"],
+		}
+		rust_library {
+			name: "libfoo",
+			crate_name: "foo",
+			srcs: ["src/lib.rs"],
+			static_libs: ["libfoo_bridge_cc"],
+		}
+	`)
+
+	bridge := ctx.ModuleForTests("libfoo_bridge", "android_arm64_armv8-a")
+	android.AssertPathsRelativeToTopEquals(t, "bridge outputs", []string{
+		"out/soong/.intermediates/libfoo_bridge/android_arm64_armv8-a/gen/lib.rs.h",
+		"out/soong/.intermediates/libfoo_bridge/android_arm64_armv8-a/gen/lib.rs.cc",
+		"out/soong/.intermediates/libfoo_bridge/android_arm64_armv8-a/gen/rust/cxx.h",
+	}, bridge.Module().(*genrule.Module).GeneratedSourceFiles())
+
+	manifest := android.RuleBuilderSboxProtoForTests(t, bridge.Output("genrule.sbox.textproto"))
+	cmd := manifest.Commands[0].GetCommand()
+	android.AssertStringDoesContain(t, "header command", cmd,
+		"src/lib.rs --header -o __SBOX_SANDBOX_DIR__/out/lib.rs.h --include foo/foo.h --cxx-impl-annotations")
+	android.AssertStringDoesContain(t, "source command", cmd,
+		"src/lib.rs -o __SBOX_SANDBOX_DIR__/out/lib.rs.cc --include foo/foo.h --cxx-impl-annotations")
+	android.AssertStringDoesContain(t, "runtime header command", cmd,
+		"--header -o __SBOX_SANDBOX_DIR__/out/rust/cxx.h")
+
+	// The generated source is compiled by the cc module that uses the bridge, with the generated
+	// headers in its include path.
+	ccLib := ctx.ModuleForTests("libfoo_bridge_cc", "android_arm64_armv8-a_static")
+	obj := ccLib.Output("obj/lib.rs.o")
+	android.AssertStringDoesContain(t, "cc module include dirs", obj.Args["cFlags"],
+		"-Iout/soong/.intermediates/libfoo_bridge/android_arm64_armv8-a/gen")
+
+	// The Rust side is built from the same source by a regular rust module, which links the C++
+	// side.
+	rustLib := ctx.ModuleForTests("libfoo", "android_arm64_armv8-a_dylib").Module().(*Module)
+	android.AssertStringListContains(t, "rust module links the C++ side",
+		rustLib.Properties.AndroidMkStaticLibs, "libfoo_bridge_cc")
+}
+
+func TestRustCxxBridgeErrors(t *testing.T) {
+	testRustError(t, `module "libfoo_bridge": src: missing the Rust source file`, `
+		rust_cxx_bridge {
+			name: "libfoo_bridge",
+		}
+	`)
+	testRustError(t, `module "libfoo_bridge": src: "foo.h" is not a Rust source file`, `
+		rust_cxx_bridge {
+			name: "libfoo_bridge",
+			src: "foo.h",
+		}
+	`)
+}
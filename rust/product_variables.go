@@ -0,0 +1,214 @@
+// Copyright 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+// The product_variable_cfgs and product_variable_envs properties set cfgs and environment
+// variables of rustc from product variables and soong config variables, so that the platform
+// crates do not need a build.rs, or to read the environment of the build, to depend on the
+// product. The variables are validated against their type, and the resulting values are provided
+// to the other modules with the ProductVariableCfgsInfoProvider.
+
+// ProductVariableCfgsInfo is provided by the Rust modules that set cfgs or environment variables
+// from product variables.
+type ProductVariableCfgsInfo struct {
+	// The cfgs set from product variables, as passed to --cfg, e.g. `platform_sdk_version="34"`.
+	Cfgs []string
+
+	// The environment variables set from product variables, as NAME=value.
+	EnvVars []string
+}
+
+var ProductVariableCfgsInfoProvider = blueprint.NewProvider(ProductVariableCfgsInfo{})
+
+var cfgNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// productVariableValue is the value of a product variable or soong config variable.
+type productVariableValue struct {
+	// Whether the variable is set.
+	set bool
+	// Whether the variable is a boolean, which sets a cfg without a value.
+	isBool bool
+	value  string
+}
+
+// lookupProductVariable returns the value of a product variable, or of a soong config variable
+// given as <namespace>:<variable>[:<type>]. It returns an error if there is no such product variable
+// or if its type cannot be used by Rust.
+func lookupProductVariable(config android.Config, name string) (productVariableValue, error) {
+	if strings.Contains(name, ":") {
+		return lookupSoongConfigVariable(config, name)
+	}
+
+	field, ok := config.ProductVariableValue(name)
+	if !ok {
+		return productVariableValue{}, fmt.Errorf("unknown product variable %q", name)
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			kind := field.Type().Elem().Kind()
+			return productVariableValue{isBool: kind == reflect.Bool}, checkProductVariableKind(name, kind)
+		}
+		field = field.Elem()
+	}
+	if err := checkProductVariableKind(name, field.Kind()); err != nil {
+		return productVariableValue{}, err
+	}
+
+	ret := productVariableValue{set: true}
+	switch field.Kind() {
+	case reflect.Bool:
+		ret.isBool = true
+		ret.value = strconv.FormatBool(field.Bool())
+	case reflect.Int, reflect.Int64:
+		ret.value = strconv.FormatInt(field.Int(), 10)
+	case reflect.String:
+		ret.value = field.String()
+	}
+	return ret, nil
+}
+
+// lookupSoongConfigVariable returns the value of a soong config variable given as
+// <namespace>:<variable>[:<type>], where <type> is the type the variable is declared with by
+// soong_config_module_type: "string" (the default), "bool" or "value". A bool variable is true if
+// it is set to one of the values accepted by soong config bool variables, e.g. "true" or "1".
+func lookupSoongConfigVariable(config android.Config, name string) (productVariableValue, error) {
+	parts := strings.Split(name, ":")
+	if len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return productVariableValue{}, fmt.Errorf("invalid soong config variable %q, expected <namespace>:<variable>[:<type>]", name)
+	}
+	namespace, variable, variableType := parts[0], parts[1], "string"
+	if len(parts) == 3 {
+		variableType = parts[2]
+	}
+
+	vendorConfig := config.VendorConfig(namespace)
+	ret := productVariableValue{
+		set:   vendorConfig.IsSet(variable),
+		value: vendorConfig.String(variable),
+	}
+	switch variableType {
+	case "string", "value":
+	case "bool":
+		ret.isBool = true
+		if !ret.set {
+			break
+		}
+		switch strings.ToLower(ret.value) {
+		case "", "0", "n", "no", "off", "false", "1", "y", "yes", "on", "true":
+		default:
+			return productVariableValue{}, fmt.Errorf("soong config variable %q is not a bool: %q", name, ret.value)
+		}
+		ret.value = strconv.FormatBool(vendorConfig.Bool(variable))
+	default:
+		return productVariableValue{}, fmt.Errorf("unknown type %q of soong config variable %q, expected one of \"string\", \"bool\" or \"value\"",
+			variableType, name)
+	}
+	return ret, nil
+}
+
+func checkProductVariableKind(name string, kind reflect.Kind) error {
+	switch kind {
+	case reflect.Bool, reflect.Int, reflect.Int64, reflect.String:
+		return nil
+	}
+	return fmt.Errorf("product variable %q is a %s, only booleans, integers and strings are supported",
+		name, kind)
+}
+
+// parseProductVariableEntry parses a "<name>=<variable>" entry of product_variable_cfgs or
+// product_variable_envs.
+func parseProductVariableEntry(entry string) (string, string, error) {
+	i := strings.Index(entry, "=")
+	if i < 0 || i == len(entry)-1 {
+		return "", "", fmt.Errorf("invalid entry %q, expected <name>=<variable>", entry)
+	}
+	name, variable := entry[:i], entry[i+1:]
+	if !cfgNameRegexp.MatchString(name) {
+		return "", "", fmt.Errorf("invalid name %q in %q, expected an identifier", name, entry)
+	}
+	return name, variable, nil
+}
+
+// productVariableCfgs returns the cfgs and environment variables set from product variables by
+// the product_variable_cfgs and product_variable_envs properties, and reports the invalid entries.
+func (compiler *baseCompiler) productVariableCfgs(ctx ModuleContext) ProductVariableCfgsInfo {
+	var info ProductVariableCfgsInfo
+
+	seen := make(map[string]bool)
+	for _, entry := range compiler.Properties.Product_variable_cfgs {
+		name, variable, err := parseProductVariableEntry(entry)
+		if err == nil && seen[name] {
+			err = fmt.Errorf("cfg %q is set more than once", name)
+		}
+		var value productVariableValue
+		if err == nil {
+			value, err = lookupProductVariable(ctx.Config(), variable)
+		}
+		if err == nil && strings.ContainsAny(value.value, `"'\$`) {
+			err = fmt.Errorf("the value %q of %q cannot be used in a cfg", value.value, variable)
+		}
+		if err != nil {
+			ctx.PropertyErrorf("product_variable_cfgs", "%s", err)
+			continue
+		}
+		seen[name] = true
+
+		if !value.set {
+			continue
+		}
+		if value.isBool {
+			if value.value == "true" {
+				info.Cfgs = append(info.Cfgs, name)
+			}
+		} else {
+			info.Cfgs = append(info.Cfgs, name+`="`+value.value+`"`)
+		}
+	}
+
+	seen = make(map[string]bool)
+	for _, entry := range compiler.Properties.Product_variable_envs {
+		name, variable, err := parseProductVariableEntry(entry)
+		if err == nil && seen[name] {
+			err = fmt.Errorf("environment variable %q is set more than once", name)
+		}
+		var value productVariableValue
+		if err == nil {
+			value, err = lookupProductVariable(ctx.Config(), variable)
+		}
+		if err != nil {
+			ctx.PropertyErrorf("product_variable_envs", "%s", err)
+			continue
+		}
+		seen[name] = true
+
+		// The environment variable is always set so that env!() does not fail for unset product
+		// variables.
+		info.EnvVars = append(info.EnvVars, name+"="+value.value)
+	}
+
+	return info
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 The Android Open Source Project
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rust
+
+import (
+	"testing"
+
+	"android/soong/android"
+
+	"github.com/google/blueprint/proptools"
+)
+
+var prepareForProductVariableCfgsTest = android.GroupFixturePreparers(
+	prepareForRustTest,
+	rustMockedFiles.AddToFixture(),
+	android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.Platform_sdk_version = proptools.IntPtr(33)
+		variables.Debuggable = proptools.BoolPtr(true)
+		variables.Eng = proptools.BoolPtr(false)
+		variables.VendorVars = map[string]map[string]string{
+			"acme": {"board": "alpha beta", "feature": "yes", "disabled": "0", "invalid": "maybe"},
+		}
+	}),
+)
+
+func TestProductVariableCfgs(t *testing.T) {
+	result := prepareForProductVariableCfgsTest.RunTestWithBp(t, `
+		rust_library_host {
+			name: "libfoo",
+			srcs: ["foo.rs"],
+			crate_name: "foo",
+			product_variable_cfgs: [
+				"sdk=Platform_sdk_version",
+				"debuggable=Debuggable",
+				"eng=Eng",
+				"unbundled=Unbundled_build",
+				"board=acme:board:value",
+				"feature=acme:feature:bool",
+				"disabled=acme:disabled:bool",
+				"unset=acme:unset",
+			],
+			product_variable_envs: [
+				"SDK_VERSION=Platform_sdk_version",
+				"BOARD=acme:board",
+				"FEATURE=acme:feature:bool",
+				"UNSET=acme:unset",
+			],
+		}`)
+
+	libfoo := result.ModuleForTests("libfoo", "linux_glibc_x86_64_dylib")
+	rustc := libfoo.Rule("rustc")
+	rustcFlags := rustc.Args["rustcFlags"]
+	android.AssertStringDoesContain(t, "rustcFlags", rustcFlags, `--cfg 'sdk="33"'`)
+	android.AssertStringDoesContain(t, "rustcFlags", rustcFlags, `--cfg 'debuggable'`)
+	android.AssertStringDoesContain(t, "rustcFlags", rustcFlags, `--cfg 'board="alpha beta"'`)
+	android.AssertStringDoesNotContain(t, "rustcFlags", rustcFlags, `--cfg 'eng'`)
+	android.AssertStringDoesNotContain(t, "rustcFlags", rustcFlags, `--cfg 'unbundled'`)
+	android.AssertStringDoesContain(t, "rustcFlags", rustcFlags, `--cfg 'feature'`)
+	android.AssertStringDoesNotContain(t, "rustcFlags", rustcFlags, `--cfg 'disabled`)
+	android.AssertStringDoesNotContain(t, "rustcFlags", rustcFlags, `--cfg 'unset`)
+
+	envVars := rustc.Args["envVars"]
+	android.AssertStringDoesContain(t, "envVars", envVars, "SDK_VERSION=33")
+	android.AssertStringDoesContain(t, "envVars", envVars, "BOARD='alpha beta'")
+	android.AssertStringDoesContain(t, "envVars", envVars, "FEATURE=true")
+	android.AssertStringDoesContain(t, "envVars", envVars, "UNSET=")
+
+	info := result.ModuleProvider(libfoo.Module(), ProductVariableCfgsInfoProvider).(ProductVariableCfgsInfo)
+	android.AssertArrayString(t, "cfgs", []string{`sdk="33"`, "debuggable", `board="alpha beta"`, "feature"}, info.Cfgs)
+	android.AssertArrayString(t, "env vars",
+		[]string{"SDK_VERSION=33", "BOARD=alpha beta", "FEATURE=true", "UNSET="}, info.EnvVars)
+}
+
+func TestProductVariableCfgsErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		entry string
+		err   string
+	}{
+		{
+			name:  "unknown variable",
+			entry: `product_variable_cfgs: ["foo=Not_a_product_variable"]`,
+			err:   `product_variable_cfgs: unknown product variable "Not_a_product_variable"`,
+		},
+		{
+			name:  "unsupported type",
+			entry: `product_variable_cfgs: ["foo=Unbundled_build_apps"]`,
+			err:   `product_variable_cfgs: product variable "Unbundled_build_apps" is a slice`,
+		},
+		{
+			name:  "invalid name",
+			entry: `product_variable_envs: ["FOO-BAR=Platform_sdk_version"]`,
+			err:   `product_variable_envs: invalid name "FOO-BAR"`,
+		},
+		{
+			name:  "missing variable",
+			entry: `product_variable_cfgs: ["foo"]`,
+			err:   `product_variable_cfgs: invalid entry "foo", expected <name>=<variable>`,
+		},
+		{
+			name:  "unknown soong config variable type",
+			entry: `product_variable_cfgs: ["foo=acme:board:int"]`,
+			err:   `product_variable_cfgs: unknown type "int" of soong config variable "acme:board:int"`,
+		},
+		{
+			name:  "invalid soong config bool",
+			entry: `product_variable_cfgs: ["foo=acme:invalid:bool"]`,
+			err:   `product_variable_cfgs: soong config variable "acme:invalid:bool" is not a bool: "maybe"`,
+		},
+		{
+			name:  "duplicate",
+			entry: `product_variable_cfgs: ["foo=Debuggable", "foo=Eng"]`,
+			err:   `product_variable_cfgs: cfg "foo" is set more than once`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prepareForProductVariableCfgsTest.
+				ExtendWithErrorHandler(android.FixtureExpectsAtLeastOneErrorMatchingPattern(tc.err)).
+				RunTestWithBp(t, `
+					rust_library_host {
+						name: "libfoo",
+						srcs: ["foo.rs"],
+						crate_name: "foo",
+						`+tc.entry+`,
+					}`)
+		})
+	}
+}
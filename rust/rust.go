@@ -55,6 +55,7 @@ type Flags struct {
 	LinkFlags       []string // Flags that apply to linker
 	ClippyFlags     []string // Flags that apply to clippy-driver, during the linting
 	RustdocFlags    []string // Flags that apply to rustdoc
+	EnvVars         []string // Environment variables set for rustc and rustdoc, as NAME=value
 	Toolchain       config.Toolchain
 	Coverage        bool
 	Clippy          bool
@@ -448,6 +449,10 @@ type PathDeps struct {
 	// Paths to generated source files
 	SrcDeps          android.Paths
 	srcProviderFiles android.Paths
+
+	// The output of the other arch variant of a Darwin universal binary, merged with the output of
+	// this variant by lipo.
+	DarwinSecondArchOutput android.OptionalPath
 }
 
 type RustLibraries []RustLibrary
@@ -1027,6 +1032,16 @@ func (mod *Module) deps(ctx DepsContext) Deps {
 		deps = mod.sanitize.deps(ctx, deps)
 	}
 
+	// Depend on the backend of the interfaces, e.g. aidl_interface, listed by name: the rust backend
+	// for the rust libraries, and the ndk backend for the cc libraries. The vendor and product
+	// variants must use a frozen version of the interfaces.
+	for _, libs := range []*[]string{&deps.Rlibs, &deps.Dylibs, &deps.Rustlibs} {
+		*libs = android.ResolveInterfaceBackends(ctx, android.InterfaceBackendRust, mod.UseVndk(), *libs)
+	}
+	for _, libs := range []*[]string{&deps.SharedLibs, &deps.StaticLibs} {
+		*libs = android.ResolveInterfaceBackends(ctx, android.InterfaceBackendNdk, mod.UseVndk(), *libs)
+	}
+
 	deps.Rlibs = android.LastUniqueStrings(deps.Rlibs)
 	deps.Dylibs = android.LastUniqueStrings(deps.Dylibs)
 	deps.Rustlibs = android.LastUniqueStrings(deps.Rustlibs)
@@ -1203,6 +1218,12 @@ func (mod *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 		if _, exists := skipModuleList[depName]; exists {
 			return
 		}
+
+		if depTag == android.DarwinUniversalVariantTag {
+			depPaths.DarwinSecondArchOutput = dep.(*Module).OutputFile()
+			return
+		}
+
 		if rustDep, ok := dep.(*Module); ok && !rustDep.CcLibraryInterface() {
 			//Handle Rust Modules
 			makeLibName := rustMakeLibName(ctx, mod, rustDep, depName+rustDep.Properties.RustSubName)
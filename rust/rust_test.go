@@ -390,6 +390,40 @@ func TestSourceProviderDeps(t *testing.T) {
 	}
 }
 
+func TestCodegenModuleSources(t *testing.T) {
+	skipTestIfOsNotSupported(t)
+	result := android.GroupFixturePreparers(
+		prepareForRustTest,
+		rustMockedFiles.AddToFixture(),
+		genrule.PrepareForTestWithCodegenPlugin,
+	).RunTestWithBp(t, `
+		rust_library {
+			name: "libfoo",
+			srcs: [
+				"foo.rs",
+				":foo_idl",
+			],
+			crate_name: "foo",
+		}
+
+		test_codegen {
+			name: "foo_idl",
+			interfaces: ["bar"],
+			src_extension: "rs",
+		}
+	`)
+
+	libfoo := result.ModuleForTests("libfoo", "android_arm64_armv8-a_rlib_dylib-std")
+	android.AssertPathsRelativeToTopEquals(t, "copied sources", []string{
+		"out/soong/.intermediates/foo_idl/gen/bar.rs",
+	}, libfoo.Rule("cp").Inputs)
+
+	rustc := libfoo.Rule("rustc")
+	android.AssertPathsRelativeToTopEquals(t, "crate root", []string{"foo.rs"}, rustc.Inputs)
+	android.AssertStringListContains(t, "implicits", android.PathsRelativeToTop(rustc.Implicits),
+		"out/soong/.intermediates/libfoo/android_arm64_armv8-a_rlib_dylib-std/out/bar.rs")
+}
+
 func TestSourceProviderTargetMismatch(t *testing.T) {
 	// This might error while building the dependency tree or when calling depsToPaths() depending on the lunched
 	// target, which results in two different errors. So don't check the error, just confirm there is one.
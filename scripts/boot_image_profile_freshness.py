@@ -0,0 +1,135 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Checks that the boot image profile is fresh against the boot jars.
+
+Computes an API fingerprint of the boot jars, i.e. a digest of the public and
+protected API of each of their classes, and compares it with the fingerprint
+recorded when the checked-in boot image profile was last refreshed. Prints a
+warning when the share of the classes whose API was added, removed or changed
+since then exceeds the given threshold, as the profile is then likely to miss
+hot code.
+"""
+
+from __future__ import print_function
+import argparse
+import hashlib
+import subprocess
+import sys
+import xml.etree.ElementTree
+
+FINGERPRINT_HEADER = '# Boot jars API fingerprint: <class> <digest of its API>'
+
+
+def class_api(class_elt):
+  """Returns the sorted signatures of the API of a dexdump class element."""
+  members = []
+  for member in class_elt:
+    params = ','.join(p.get('type', '') for p in member.iterfind('parameter'))
+    members.append('%s %s %s(%s) %s' % (
+        member.tag, member.get('visibility', ''), member.get('name', ''),
+        params, member.get('type', member.get('return', ''))))
+  return sorted(members)
+
+
+def fingerprint_from_xml(xml_text):
+  """Returns the fingerprint of the classes of a dexdump XML output."""
+  fingerprint = {}
+  root = xml.etree.ElementTree.fromstring(xml_text)
+  for package_elt in root.iterfind('package'):
+    package_name = package_elt.get('name', '')
+    for class_elt in package_elt:
+      name = class_elt.get('name', '')
+      if package_name:
+        name = package_name + '.' + name
+      api = [class_elt.tag, class_elt.get('extends', ''),
+             class_elt.get('visibility', '')] + class_api(class_elt)
+      fingerprint[name] = hashlib.sha1(
+          '\n'.join(api).encode('utf-8')).hexdigest()[:16]
+  return fingerprint
+
+
+def fingerprint_jar(dexdump_path, jar):
+  """Returns the fingerprint of the classes of a dex jar."""
+  xml_text = subprocess.check_output([dexdump_path, '-l', 'xml', jar])
+  return fingerprint_from_xml(xml_text)
+
+
+def read_fingerprint(path):
+  """Reads a fingerprint file written by write_fingerprint."""
+  fingerprint = {}
+  with open(path, 'r') as f:
+    for line in f:
+      line = line.strip()
+      if not line or line.startswith('#'):
+        continue
+      name, digest = line.split()
+      fingerprint[name] = digest
+  return fingerprint
+
+
+def write_fingerprint(path, fingerprint):
+  """Writes a fingerprint in the format of the checked-in fingerprint."""
+  with open(path, 'w') as f:
+    print(FINGERPRINT_HEADER, file=f)
+    for name in sorted(fingerprint):
+      print('%s %s' % (name, fingerprint[name]), file=f)
+
+
+def api_drift(recorded, current):
+  """Returns the percentage of the classes whose API differs."""
+  names = set(recorded) | set(current)
+  if not names:
+    return 0.0
+  changed = [n for n in names if recorded.get(n) != current.get(n)]
+  return 100.0 * len(changed) / len(names)
+
+
+def parse_args(argv):
+  parser = argparse.ArgumentParser(description=__doc__)
+  parser.add_argument('--dexdump', required=True,
+                      help='path to the dexdump host tool')
+  parser.add_argument('--recorded-fingerprint', required=True,
+                      help='fingerprint recorded with the boot image profile')
+  parser.add_argument('--max-api-drift', type=float, default=10.0,
+                      help='percentage of changed classes above which to warn')
+  parser.add_argument('--output-fingerprint', required=True,
+                      help='where to write the fingerprint of the boot jars')
+  parser.add_argument('jars', nargs='+', help='the boot dex jars')
+  return parser.parse_args(argv)
+
+
+def main(argv):
+  args = parse_args(argv)
+
+  current = {}
+  for jar in args.jars:
+    current.update(fingerprint_jar(args.dexdump, jar))
+  write_fingerprint(args.output_fingerprint, current)
+
+  drift = api_drift(read_fingerprint(args.recorded_fingerprint), current)
+  if drift > args.max_api_drift:
+    print(('Warning: the API of %.1f%% of the boot jar classes changed since '
+           'the boot image profile was last refreshed, more than the threshold '
+           'of %.1f%%. Consider refreshing the boot image profile and updating '
+           '%s with %s.'
+           % (drift, args.max_api_drift, args.recorded_fingerprint,
+              args.output_fingerprint)), file=sys.stderr)
+  return 0
+
+
+if __name__ == '__main__':
+  sys.exit(main(sys.argv[1:]))
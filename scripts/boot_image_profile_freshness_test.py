@@ -0,0 +1,71 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for boot_image_profile_freshness.py."""
+
+import unittest
+
+import boot_image_profile_freshness as freshness
+
+DEXDUMP_XML = """<api>
+<package name="android.app">
+<class name="Activity" extends="android.content.Context" visibility="public">
+<method name="onCreate" return="void" visibility="protected">
+<parameter name="arg0" type="android.os.Bundle"/>
+</method>
+</class>
+<class name="Dialog" extends="java.lang.Object" visibility="public">
+<field name="mTitle" type="java.lang.String" visibility="protected"/>
+</class>
+</package>
+</api>
+"""
+
+
+class FingerprintTest(unittest.TestCase):
+
+  def test_fingerprint_from_xml(self):
+    fingerprint = freshness.fingerprint_from_xml(DEXDUMP_XML)
+    self.assertEqual(['android.app.Activity', 'android.app.Dialog'],
+                     sorted(fingerprint))
+
+  def test_fingerprint_changes_with_api(self):
+    fingerprint = freshness.fingerprint_from_xml(DEXDUMP_XML)
+    changed = freshness.fingerprint_from_xml(
+        DEXDUMP_XML.replace('android.os.Bundle', 'android.os.PersistableBundle'))
+    self.assertNotEqual(fingerprint['android.app.Activity'],
+                        changed['android.app.Activity'])
+    self.assertEqual(fingerprint['android.app.Dialog'],
+                     changed['android.app.Dialog'])
+
+
+class ApiDriftTest(unittest.TestCase):
+
+  def test_no_drift(self):
+    self.assertEqual(0.0, freshness.api_drift({'a': '1'}, {'a': '1'}))
+
+  def test_empty(self):
+    self.assertEqual(0.0, freshness.api_drift({}, {}))
+
+  def test_changed_added_and_removed(self):
+    recorded = {'a': '1', 'b': '2', 'c': '3'}
+    current = {'a': '1', 'b': '4', 'd': '5'}
+    # b changed, c removed and d added out of a, b, c and d.
+    self.assertEqual(75.0, freshness.api_drift(recorded, current))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -94,6 +94,40 @@ def SystemProvide(args):
         f.write(pb.SerializeToString())
 
 
+def Provide(args):
+    """
+    Replaces the provideLibs of the source protobuf message (--source) with the libraries listed one
+    per line in --libs, and writes it to --output.
+    With --diff, writes the source unchanged to --output, and reports the differences between its
+    provideLibs and the listed libraries to the --diff file and to stderr instead, to migrate the
+    configuration to the listed libraries.
+    """
+    pb = linker_config_pb2.LinkerConfig()
+    with open(args.source, 'rb') as f:
+        pb.ParseFromString(f.read())
+    with open(args.libs) as f:
+        libraries = sorted(set(line.strip() for line in f if line.strip()))
+
+    if args.diff:
+        stale = sorted(set(pb.provideLibs) - set(libraries))
+        missing = sorted(set(libraries) - set(pb.provideLibs))
+        report = [f'-{lib}' for lib in stale] + [f'+{lib}' for lib in missing]
+        with open(args.diff, 'w') as f:
+            f.write(''.join(line + '\n' for line in report))
+        if report:
+            sys.stderr.write(
+                f'Warning: provideLibs of {args.source} differ from the libraries that declare '
+                'stubs, - is provided but declares no stubs, + declares stubs but is not '
+                'provided:\n')
+            sys.stderr.write(''.join(f'  {line}\n' for line in report))
+    else:
+        del pb.provideLibs[:]
+        pb.provideLibs.extend(libraries)
+
+    with open(args.output, 'wb') as f:
+        f.write(pb.SerializeToString())
+
+
 def Append(args):
     pb = linker_config_pb2.LinkerConfig()
     with open(args.source, 'rb') as f:
@@ -188,6 +222,33 @@ def GetArgParser():
         '--system', required=True, type=str, help='Path of the system image.')
     system_provide_libs.set_defaults(func=SystemProvide)
 
+    provide = subparsers.add_parser(
+        'provide',
+        help='Replace the provideLibs of the configuration with the listed libraries.')
+    provide.add_argument(
+        '-s',
+        '--source',
+        required=True,
+        type=str,
+        help='Source linker configuration file in protobuf.')
+    provide.add_argument(
+        '-o',
+        '--output',
+        required=True,
+        type=str,
+        help='Target linker configuration file to write in protobuf.')
+    provide.add_argument(
+        '--libs',
+        required=True,
+        type=str,
+        help='File listing the provided libraries, one per line.')
+    provide.add_argument(
+        '--diff',
+        type=str,
+        help='Keep the provideLibs of the source, and write their differences to the '
+        'listed libraries to this file.')
+    provide.set_defaults(func=Provide)
+
     append = subparsers.add_parser(
         'append', help='Append value(s) to given key.')
     append.add_argument(
@@ -122,6 +122,29 @@ class ConvLinkerConfigTest(TempDirTest):
     self.assertSetEqual(set(pb.provideLibs), set(['libbar.so']))
 
 
+  def test_Provide(self):
+    self.write('in.pb', LinkerConfig(provideLibs=['libstale.so', 'libfoo.so']).SerializeToString())
+    self.write('libs.txt', b'libfoo.so\nlibbar.so\n')
+    self.command(['provide', '-s', FileArg('in.pb'), '-o', FileArg('out.pb'), '--libs', FileArg('libs.txt')])
+    pb = LinkerConfig()
+    pb.ParseFromString(self.read('out.pb'))
+    self.assertSequenceEqual(pb.provideLibs, ['libbar.so', 'libfoo.so'])
+
+
+  def test_Provide_with_diff(self):
+    self.write('in.pb', LinkerConfig(provideLibs=['libstale.so', 'libfoo.so']).SerializeToString())
+    self.write('libs.txt', b'libfoo.so\nlibbar.so\n')
+    buf = io.StringIO()
+    with redirect_stderr(buf):
+      self.command(['provide', '-s', FileArg('in.pb'), '-o', FileArg('out.pb'), '--libs', FileArg('libs.txt'),
+                    '--diff', FileArg('diff.txt')])
+    pb = LinkerConfig()
+    pb.ParseFromString(self.read('out.pb'))
+    self.assertSequenceEqual(pb.provideLibs, ['libstale.so', 'libfoo.so'])
+    self.assertEqual(self.read('diff.txt'), b'-libstale.so\n+libbar.so\n')
+    self.assertRegex(buf.getvalue(), r'Warning: provideLibs of .*in\.pb differ')
+
+
   def command(self, args):
     parser = conv_linker_config.GetArgParser()
     parsed_args = parser.parse_args(self.resolve_paths(args))
@@ -0,0 +1,139 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Diffs the oatdump outputs of two boot images.
+
+Compares the status of the classes and the compiled methods of a boot image
+with those of a reference boot image, e.g. to check that a prebuilt boot image
+is identical to the boot image built from source. Writes the differences as
+JSON and prints a summary.
+"""
+
+from __future__ import print_function
+import argparse
+import json
+import re
+import sys
+
+CLASS_RE = re.compile(
+    r'^\d+: (\S+) \(offset=0x[0-9a-fA-F]+\) \(type_idx=\d+\) \((\w+)\) \((\w+)\)')
+METHOD_RE = re.compile(r'^\s+\d+: (.+) \(dex_method_idx=\d+\)$')
+CODE_RE = re.compile(r'^\s+CODE: \(code_offset=0x([0-9a-fA-F]+) size=(\d+)\)')
+NO_CODE = 'NO CODE!'
+
+
+def parse_oatdump(lines):
+  """Returns the class statuses and the compiled methods of an oatdump.
+
+  The class statuses map the class descriptors to their status and oat class
+  type, and the compiled methods map the method signatures to the size of
+  their compiled code, or to None if they are not compiled.
+  """
+  classes = {}
+  methods = {}
+  method = None
+  for line in lines:
+    line = line.rstrip('\n')
+    match = CLASS_RE.match(line)
+    if match:
+      classes[match.group(1)] = '%s %s' % (match.group(2), match.group(3))
+      method = None
+      continue
+    match = METHOD_RE.match(line)
+    if match:
+      method = match.group(1)
+      methods[method] = None
+      continue
+    if method is None:
+      continue
+    match = CODE_RE.match(line)
+    if match:
+      offset, size = int(match.group(1), 16), int(match.group(2))
+      methods[method] = size if offset != 0 and size != 0 else None
+      method = None
+    elif line.strip() == NO_CODE:
+      method = None
+  return classes, methods
+
+
+def diff_maps(reference, current):
+  """Returns the entries that differ, as [reference, current] pairs."""
+  diff = {}
+  for key in set(reference) | set(current):
+    if reference.get(key, 'missing') != current.get(key, 'missing'):
+      diff[key] = [reference.get(key, 'missing'), current.get(key, 'missing')]
+  return diff
+
+
+def diff_oatdumps(reference_lines, current_lines):
+  """Returns the structured diff of two oatdumps."""
+  ref_classes, ref_methods = parse_oatdump(reference_lines)
+  cur_classes, cur_methods = parse_oatdump(current_lines)
+
+  def compiled(methods):
+    return {m: 'compiled' if size is not None else 'not compiled'
+            for m, size in methods.items()}
+
+  def code_size(methods):
+    return {m: size for m, size in methods.items() if size is not None}
+
+  compiled_diff = diff_maps(compiled(ref_methods), compiled(cur_methods))
+  size_diff = {m: sizes for m, sizes in
+               diff_maps(code_size(ref_methods), code_size(cur_methods)).items()
+               if m not in compiled_diff}
+  return {
+      'class_status': diff_maps(ref_classes, cur_classes),
+      'compiled_methods': compiled_diff,
+      'code_size': size_diff,
+  }
+
+
+def parse_args(argv):
+  parser = argparse.ArgumentParser(description=__doc__)
+  parser.add_argument('--reference', required=True,
+                      help='oatdump of the reference boot image')
+  parser.add_argument('--current', required=True,
+                      help='oatdump of the current boot image')
+  parser.add_argument('--output', required=True,
+                      help='where to write the JSON diff')
+  return parser.parse_args(argv)
+
+
+def main(argv):
+  args = parse_args(argv)
+
+  with open(args.reference, 'r') as f:
+    reference = f.readlines()
+  with open(args.current, 'r') as f:
+    current = f.readlines()
+
+  diff = diff_oatdumps(reference, current)
+  with open(args.output, 'w') as f:
+    json.dump(diff, f, indent=2, sort_keys=True)
+    f.write('\n')
+
+  if any(diff.values()):
+    print(('The boot image differs from the reference boot image: %d class '
+           'statuses, %d compiled methods and %d code sizes differ. See %s.'
+           % (len(diff['class_status']), len(diff['compiled_methods']),
+              len(diff['code_size']), args.output)))
+  else:
+    print('The boot image is identical to the reference boot image.')
+  return 0
+
+
+if __name__ == '__main__':
+  sys.exit(main(sys.argv[1:]))
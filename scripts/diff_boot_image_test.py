@@ -0,0 +1,83 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for diff_boot_image.py."""
+
+import unittest
+
+import diff_boot_image
+
+OATDUMP = """OatDexFile:
+location: /apex/com.android.art/javalib/core-oj.jar
+0: Ljava/lang/Object; (offset=0x00001234) (type_idx=12) (Initialized) (OatClassAllCompiled)
+  0: void java.lang.Object.<init>() (dex_method_idx=1)
+    DEX CODE:
+      0x0000: 0e00                    | return-void
+    CODE: (code_offset=0x00010000 size=4)...
+  1: int java.lang.Object.hashCode() (dex_method_idx=2)
+    CODE: (code_offset=0x00010010 size=24)...
+1: Ljava/lang/Thread; (offset=0x00001300) (type_idx=20) (Verified) (OatClassSomeCompiled)
+  0: void java.lang.Thread.run() (dex_method_idx=7)
+    NO CODE!
+"""
+
+
+def lines(text):
+  return text.splitlines(True)
+
+
+class ParseOatdumpTest(unittest.TestCase):
+
+  def test_parse_oatdump(self):
+    classes, methods = diff_boot_image.parse_oatdump(lines(OATDUMP))
+    self.assertEqual({
+        'Ljava/lang/Object;': 'Initialized OatClassAllCompiled',
+        'Ljava/lang/Thread;': 'Verified OatClassSomeCompiled',
+    }, classes)
+    self.assertEqual({
+        'void java.lang.Object.<init>()': 4,
+        'int java.lang.Object.hashCode()': 24,
+        'void java.lang.Thread.run()': None,
+    }, methods)
+
+
+class DiffOatdumpsTest(unittest.TestCase):
+
+  def test_identical(self):
+    diff = diff_boot_image.diff_oatdumps(lines(OATDUMP), lines(OATDUMP))
+    self.assertEqual(
+        {'class_status': {}, 'compiled_methods': {}, 'code_size': {}}, diff)
+
+  def test_differences(self):
+    current = (OATDUMP
+               .replace('(Verified)', '(Initialized)')
+               .replace('size=24', 'size=32')
+               .replace('    NO CODE!', '    CODE: (code_offset=0x00010100 size=8)...'))
+    diff = diff_boot_image.diff_oatdumps(lines(OATDUMP), lines(current))
+    self.assertEqual({
+        'Ljava/lang/Thread;': ['Verified OatClassSomeCompiled',
+                               'Initialized OatClassSomeCompiled'],
+    }, diff['class_status'])
+    self.assertEqual({
+        'void java.lang.Thread.run()': ['not compiled', 'compiled'],
+    }, diff['compiled_methods'])
+    self.assertEqual({
+        'int java.lang.Object.hashCode()': [24, 32],
+    }, diff['code_size'])
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -0,0 +1,78 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Checks that the lint baseline of a module only lists issues that remain.
+
+Compares the checked-in lint baseline of a module with the reference baseline
+written by lint, which lists all the issues that lint currently finds. Fails
+when the checked-in baseline lists issues that have since been fixed, so that
+the baseline only ever shrinks and the fixed issues cannot come back unnoticed.
+"""
+
+from __future__ import print_function
+import argparse
+import sys
+import xml.etree.ElementTree
+
+
+def baseline_issues(xml_text):
+  """Returns the set of the (id, file, message) of the issues of a baseline."""
+  issues = set()
+  root = xml.etree.ElementTree.fromstring(xml_text)
+  for issue in root.iterfind('issue'):
+    location = issue.find('location')
+    path = location.get('file', '') if location is not None else ''
+    issues.add((issue.get('id', ''), path, issue.get('message', '')))
+  return issues
+
+
+def fixed_issues(baseline, reference):
+  """Returns the sorted issues of the baseline missing from the reference."""
+  return sorted(baseline - reference)
+
+
+def parse_args(argv):
+  parser = argparse.ArgumentParser(description=__doc__)
+  parser.add_argument('--baseline', required=True,
+                      help='the checked-in lint baseline of the module')
+  parser.add_argument('--reference', required=True,
+                      help='the reference baseline written by lint')
+  parser.add_argument('--name', required=True, help='the name of the module')
+  return parser.parse_args(argv)
+
+
+def main(argv):
+  args = parse_args(argv)
+
+  with open(args.baseline, 'r') as f:
+    baseline = baseline_issues(f.read())
+  with open(args.reference, 'r') as f:
+    reference = baseline_issues(f.read())
+
+  fixed = fixed_issues(baseline, reference)
+  if fixed:
+    print('%s lists %d lint issues that have been fixed:' %
+          (args.baseline, len(fixed)), file=sys.stderr)
+    for issue_id, path, message in fixed:
+      print('  %s: %s: %s' % (path, issue_id, message), file=sys.stderr)
+    print('Remove them from the baseline with:\n  m %s-update-lint-baseline'
+          % args.name, file=sys.stderr)
+    return 1
+  return 0
+
+
+if __name__ == '__main__':
+  sys.exit(main(sys.argv[1:]))
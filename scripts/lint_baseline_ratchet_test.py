@@ -0,0 +1,70 @@
+#!/usr/bin/env python
+#
+# Copyright (C) 2023 The Android Open Source Project
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#      http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+"""Unit tests for lint_baseline_ratchet.py."""
+
+import unittest
+
+import lint_baseline_ratchet as ratchet
+
+BASELINE_XML = """<?xml version="1.0" encoding="UTF-8"?>
+<issues format="6" by="lint 8.0.0">
+    <issue id="NewApi" message="Call requires API level 31">
+        <location file="src/com/android/Foo.java" line="12"/>
+    </issue>
+    <issue id="UnusedResources" message="The resource `R.string.bar` appears to be unused">
+        <location file="res/values/strings.xml" line="3"/>
+    </issue>
+</issues>
+"""
+
+
+class BaselineIssuesTest(unittest.TestCase):
+
+  def test_baseline_issues(self):
+    self.assertEqual(
+        sorted(ratchet.baseline_issues(BASELINE_XML)),
+        [('NewApi', 'src/com/android/Foo.java', 'Call requires API level 31'),
+         ('UnusedResources', 'res/values/strings.xml',
+          'The resource `R.string.bar` appears to be unused')])
+
+  def test_line_changes_are_ignored(self):
+    self.assertEqual(
+        ratchet.baseline_issues(BASELINE_XML),
+        ratchet.baseline_issues(BASELINE_XML.replace('line="12"', 'line="14"')))
+
+
+class FixedIssuesTest(unittest.TestCase):
+
+  def test_no_fixed_issues(self):
+    issues = ratchet.baseline_issues(BASELINE_XML)
+    self.assertEqual([], ratchet.fixed_issues(issues, issues))
+
+  def test_new_issues_are_not_fixed(self):
+    issues = ratchet.baseline_issues(BASELINE_XML)
+    reference = issues | {('Deprecated', 'a.java', 'deprecated')}
+    self.assertEqual([], ratchet.fixed_issues(issues, reference))
+
+  def test_fixed_issues(self):
+    issues = ratchet.baseline_issues(BASELINE_XML)
+    reference = {i for i in issues if i[0] != 'NewApi'}
+    self.assertEqual(
+        [('NewApi', 'src/com/android/Foo.java', 'Call requires API level 31')],
+        ratchet.fixed_issues(issues, reference))
+
+
+if __name__ == '__main__':
+  unittest.main(verbosity=2)
@@ -77,6 +77,10 @@ def parse_args():
         dest='dexpreopt_configs',
         action='append',
         help='a paths to a dexpreopt.config of some library')
+    parser.add_argument(
+        '--manifest-policy',
+        dest='manifest_policy',
+        help='path to a JSON manifest policy to check the manifest against')
     parser.add_argument('--aapt', dest='aapt', help='path to aapt executable')
     parser.add_argument(
         '--output', '-o', dest='output', help='output AndroidManifest.xml file')
@@ -295,6 +299,43 @@ def extract_target_sdk_version_xml(xml):
     return target_attr.value
 
 
+def enforce_manifest_policy(manifest, policy, path):
+    """Verify that the manifest complies with a manifest policy.
+
+  Args:
+    manifest: parsed XML manifest
+    policy:   the policy, as written by a manifest_merger_policy module
+    path:     the path to the manifest, for error messages
+    """
+    errors = []
+    root = parse_manifest(manifest)
+
+    allowed = policy.get('AllowedPermissions')
+    if allowed is not None:
+        for tag in ['uses-permission', 'uses-permission-sdk-23']:
+            for elem in get_children_with_tag(root, tag):
+                name = elem.getAttributeNodeNS(android_ns, 'name')
+                name = name.value if name is not None else ''
+                if name not in allowed:
+                    errors.append('<%s android:name="%s"> is not allowed' %
+                                  (tag, name))
+
+    for element in policy.get('ForbiddenElements') or []:
+        elems = [root]
+        for tag in element.split('/'):
+            elems = [c for e in elems for c in get_children_with_tag(e, tag)]
+        if elems:
+            errors.append('<%s> is forbidden' % element)
+
+    if not errors:
+        return
+
+    raise ManifestMismatchError(''.join(
+        ['manifest %s does not comply with the manifest policy %s:\n' %
+         (path, policy.get('Name', ''))] +
+        ['\t- %s\n' % e for e in errors]))
+
+
 def load_dexpreopt_configs(configs):
     """Load dexpreopt.config files and map module names to library names."""
     module_to_libname = {}
@@ -367,6 +408,14 @@ def main():
                     if errmsg is not None:
                         f.write('%s\n' % errmsg)
 
+        if args.manifest_policy:
+            if is_apk:
+                raise RuntimeError('cannot check the manifest policy of an APK')
+
+            with open(args.manifest_policy, 'r') as f:
+                policy = json.load(f)
+            enforce_manifest_policy(manifest, policy, args.input)
+
         if args.extract_target_sdk_version:
             try:
                 print(extract_target_sdk_version(manifest, is_apk))
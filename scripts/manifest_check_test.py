@@ -256,5 +256,51 @@ class ExtractTargetSdkVersionTest(unittest.TestCase):
         self.run_test(xml, apk, '29')
 
 
+class EnforceManifestPolicyTest(unittest.TestCase):
+    """Unit tests for enforce_manifest_policy function."""
+
+    def run_test(self, xml, policy):
+        doc = minidom.parseString(xml)
+        try:
+            manifest_check.enforce_manifest_policy(
+                doc, policy, 'path/to/X/AndroidManifest.xml')
+            return True
+        except manifest_check.ManifestMismatchError:
+            return False
+
+    xml_tmpl = (
+        '<?xml version="1.0" encoding="utf-8"?>\n<manifest '
+        'xmlns:android="http://schemas.android.com/apk/res/android">\n    '
+        '%s\n    <application>\n    %s\n    </application>\n</manifest>\n')
+
+    def test_allowed_permission(self):
+        xml = self.xml_tmpl % (
+            '<uses-permission android:name="android.permission.INTERNET" />',
+            '')
+        policy = {'AllowedPermissions': ['android.permission.INTERNET']}
+        self.assertTrue(self.run_test(xml, policy))
+
+    def test_disallowed_permission(self):
+        xml = self.xml_tmpl % (
+            '<uses-permission-sdk-23 android:name="android.permission.CAMERA" />',
+            '')
+        policy = {'AllowedPermissions': ['android.permission.INTERNET']}
+        self.assertFalse(self.run_test(xml, policy))
+
+    def test_no_permission_allowed(self):
+        xml = self.xml_tmpl % (
+            '<uses-permission android:name="android.permission.INTERNET" />',
+            '')
+        self.assertTrue(self.run_test(xml, {}))
+        self.assertFalse(self.run_test(xml, {'AllowedPermissions': []}))
+
+    def test_forbidden_element(self):
+        xml = self.xml_tmpl % ('', '<provider android:name="Foo" />')
+        self.assertFalse(
+            self.run_test(xml, {'ForbiddenElements': ['application/provider']}))
+        self.assertTrue(
+            self.run_test(xml, {'ForbiddenElements': ['application/service']}))
+
+
 if __name__ == '__main__':
     unittest.main(verbosity=2)
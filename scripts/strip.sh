@@ -29,6 +29,7 @@
 #   --keep-symbols
 #   --keep-symbols-and-debug-frame
 #   --remove-build-id
+#   --split-debug
 
 set -o pipefail
 
@@ -43,6 +44,8 @@ Options:
         --keep-symbols                  Keep symbols in out-file
         --keep-symbols-and-debug-frame  Keep symbols and .debug_frame in out-file
         --remove-build-id               Remove the gnu build-id section in out-file
+        --split-debug                   Move the debug info to out-file.debug and add a
+                                        gnu-debuglink section that points to it to out-file
 EOF
     exit 1
 }
@@ -122,6 +125,11 @@ do_add_gnu_debuglink() {
     "${CLANG_BIN}/llvm-objcopy" --add-gnu-debuglink="${infile}" "${outfile}.tmp"
 }
 
+do_split_debug() {
+    "${CLANG_BIN}/llvm-objcopy" --only-keep-debug "${infile}" "${outfile}.debug"
+    "${CLANG_BIN}/llvm-objcopy" --add-gnu-debuglink="${outfile}.debug" "${outfile}.tmp"
+}
+
 do_remove_build_id() {
     "${CLANG_BIN}/llvm-strip" --remove-section=.note.gnu.build-id "${outfile}.tmp" -o "${outfile}.tmp.no-build-id"
     rm -f "${outfile}.tmp"
@@ -141,6 +149,7 @@ while getopts $OPTSTRING opt; do
                 keep-symbols) keep_symbols=true ;;
                 keep-symbols-and-debug-frame) keep_symbols_and_debug_frame=true ;;
                 remove-build-id) remove_build_id=true ;;
+                split-debug) split_debug=true ;;
                 *) echo "Unknown option --${OPTARG}"; usage ;;
             esac;;
         ?) usage ;;
@@ -188,6 +197,16 @@ if [ ! -z "${add_gnu_debuglink}" -a ! -z "${keep_mini_debug_info}" ]; then
     usage
 fi
 
+if [ ! -z "${split_debug}" -a ! -z "${add_gnu_debuglink}" ]; then
+    echo "--split-debug cannot be used with --add-gnu-debuglink"
+    usage
+fi
+
+if [ ! -z "${split_debug}" -a ! -z "${keep_mini_debug_info}" ]; then
+    echo "--split-debug cannot be used with --keep-mini-debug-info"
+    usage
+fi
+
 rm -f "${outfile}.tmp"
 
 if [ ! -z "${keep_symbols}" ]; then
@@ -206,6 +225,10 @@ if [ ! -z "${add_gnu_debuglink}" ]; then
     do_add_gnu_debuglink
 fi
 
+if [ ! -z "${split_debug}" ]; then
+    do_split_debug
+fi
+
 if [ ! -z "${remove_build_id}" ]; then
     do_remove_build_id
 fi
@@ -322,6 +322,23 @@ func (m *syspropLibrary) GenerateAndroidBuildActions(ctx android.ModuleContext)
 		Text("; exit 38) )").
 		Implicits(apiFileList)
 
+	// 3. compares current.txt to latest.txt if the interfaces are frozen on this branch
+	// current.txt should be identical to latest.txt
+	if ctx.Config().FrozenInterfaces() {
+		msg = fmt.Sprintf(`\n******************************\n`+
+			`API of sysprop_library %s doesn't match with latest version\n`+
+			`The interfaces are frozen on this branch, no change is allowed.\n`+
+			`******************************\n`, baseModuleName)
+
+		rule.Command().
+			Text("( cmp").Flag("-s").
+			Text(latestApiArgument).
+			Text(currentApiArgument).
+			Text("|| ( echo").Flag("-e").
+			Flag(`"` + msg + `"`).
+			Text("; exit 38) )")
+	}
+
 	m.checkApiFileTimeStamp = android.PathForModuleOut(ctx, "check_api.timestamp")
 
 	rule.Command().
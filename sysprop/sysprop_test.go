@@ -30,7 +30,7 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func test(t *testing.T, bp string) *android.TestResult {
+func test(t *testing.T, bp string, preparers ...android.FixturePreparer) *android.TestResult {
 	t.Helper()
 
 	bp += `
@@ -127,6 +127,7 @@ func test(t *testing.T, bp string) *android.TestResult {
 		}),
 		mockFS.AddToFixture(),
 		android.FixtureWithRootAndroidBp(bp),
+		android.GroupFixturePreparers(preparers...),
 	).RunTest(t)
 
 	return result
@@ -368,3 +369,25 @@ func TestMinSdkVersionIsForwarded(t *testing.T) {
 	propFromJava := javaModule.MinSdkVersionString()
 	android.AssertStringEquals(t, "min_sdk_version forwarding to java module", "30", propFromJava)
 }
+
+func TestFrozenInterfaces(t *testing.T) {
+	bp := `
+		sysprop_library {
+			name: "sysprop-platform",
+			srcs: ["android/sysprop/PlatformProperties.sysprop"],
+			api_packages: ["android.sysprop"],
+			property_owner: "Platform",
+		}
+	`
+	frozenCheck := "cmp -s api/sysprop-platform-latest.txt api/sysprop-platform-current.txt"
+
+	result := test(t, bp)
+	checkApi := result.ModuleForTests("sysprop-platform_sysprop_library", "").Rule("sysprop-platform_check_api")
+	android.AssertStringDoesNotContain(t, "check api command", checkApi.RuleParams.Command, frozenCheck)
+
+	result = test(t, bp, android.FixtureModifyProductVariables(func(variables android.FixtureProductVariables) {
+		variables.Frozen_interfaces = proptools.BoolPtr(true)
+	}))
+	checkApi = result.ModuleForTests("sysprop-platform_sysprop_library", "").Rule("sysprop-platform_check_api")
+	android.AssertStringDoesContain(t, "check api command", checkApi.RuleParams.Command, frozenCheck)
+}
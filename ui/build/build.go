@@ -253,6 +253,10 @@ func Build(ctx Context, config Config) {
 
 	SetupPath(ctx, config)
 
+	// checkHostTools aborts the build if the build host does not meet the requirements of the host
+	// tools policy, unless ALLOW_UNSUPPORTED_BUILD_HOST=true.
+	checkHostTools(ctx, config)
+
 	what := evaluateWhatToRun(config, ctx.Verboseln)
 
 	if config.StartGoma() {
@@ -297,6 +301,11 @@ func Build(ctx Context, config Config) {
 		runSoong(ctx, config)
 	}
 
+	if inList(moduleOutputsGoal, config.Arguments()) {
+		printModuleOutputs(ctx, config)
+		return
+	}
+
 	if what&RunKati != 0 {
 		genKatiSuffix(ctx, config)
 		runKatiCleanSpec(ctx, config)
@@ -327,6 +336,7 @@ func Build(ctx Context, config Config) {
 			installCleanIfNecessary(ctx, config)
 		}
 		runNinjaForBuild(ctx, config)
+		installClangdConfigs(ctx, config)
 	}
 
 	if what&RunDistActions != 0 {
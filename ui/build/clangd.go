@@ -0,0 +1,41 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"path/filepath"
+)
+
+// The goal that generates the clangd configuration of the directories that follow it on the command
+// line, e.g. `m clangd-config frameworks/native system/core`. soong_build only generates the
+// configuration of the directories listed in SOONG_GEN_CLANGD_CONFIG, which is set for it when the
+// goal is requested.
+const clangdConfigGoal = "clangd-config"
+
+// The directory of the clangd configurations in the Soong output directory, see cc/clangd.go.
+const clangdConfigOutputDirectory = "development/ide/clangd"
+
+// installClangdConfigs copies the .clangd file that soong_build generated for each of the
+// directories of the clangd-config goal to the directory, where clangd looks for it.
+func installClangdConfigs(ctx Context, config Config) {
+	for _, dir := range config.ClangdConfigDirs() {
+		src := filepath.Join(config.SoongOutDir(), clangdConfigOutputDirectory, dir, ".clangd")
+		dst := filepath.Join(dir, ".clangd")
+		if _, err := copyFile(src, dst); err != nil {
+			ctx.Fatalf("failed to write the clangd configuration of %s: %s", dir, err)
+		}
+		ctx.Println("Wrote", dst)
+	}
+}
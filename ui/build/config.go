@@ -78,7 +78,8 @@ type configImpl struct {
 	queryview         bool
 	reportMkMetrics   bool // Collect and report mk2bp migration progress metrics.
 	soongDocs         bool
-	multitreeBuild    bool // This is a multitree build.
+	clangdConfigDirs  []string // The directories of the clangd-config goal.
+	multitreeBuild    bool     // This is a multitree build.
 	skipConfig        bool
 	skipKati          bool
 	skipKatiNinja     bool
@@ -927,6 +928,17 @@ func (c *configImpl) parseArgs(ctx Context, args []string) {
 			c.queryview = true
 		} else if arg == "soong_docs" {
 			c.soongDocs = true
+		} else if arg == clangdConfigGoal {
+			// The directories that follow the goal are the directories to generate the clangd
+			// configuration of, rather than goals.
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && !strings.Contains(args[i+1], "=") {
+				i++
+				c.clangdConfigDirs = append(c.clangdConfigDirs, filepath.Clean(strings.TrimSpace(args[i])))
+			}
+			if len(c.clangdConfigDirs) == 0 {
+				ctx.Fatalln("usage: m clangd-config <directory>...")
+			}
+			c.arguments = append(c.arguments, arg)
 		} else {
 			if arg == "checkbuild" {
 				c.checkbuild = true
@@ -1174,6 +1186,10 @@ func (c *configImpl) SoongDocs() bool {
 	return c.soongDocs
 }
 
+func (c *configImpl) ClangdConfigDirs() []string {
+	return c.clangdConfigDirs
+}
+
 func (c *configImpl) IsVerbose() bool {
 	return c.verbose
 }
@@ -186,6 +186,53 @@ func TestConfigParseArgsVars(t *testing.T) {
 	}
 }
 
+func TestConfigParseArgsClangdConfig(t *testing.T) {
+	ctx := testContext()
+
+	testCases := []struct {
+		args []string
+
+		expectedDirs []string
+		remaining    []string
+	}{
+		{
+			args: []string{"clangd-config", "frameworks/native/", "system/core"},
+
+			expectedDirs: []string{"frameworks/native", "system/core"},
+			remaining:    []string{"clangd-config"},
+		},
+		{
+			args: []string{"droid", "clangd-config", "system/core", "-j8", "dist"},
+
+			expectedDirs: []string{"system/core"},
+			remaining:    []string{"droid", "clangd-config"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.args, " "), func(t *testing.T) {
+			defer logger.Recover(func(err error) {
+				t.Fatal(err)
+			})
+
+			e := Environment([]string{})
+			c := &configImpl{
+				environ: &e,
+			}
+			c.parseArgs(ctx, tc.args)
+
+			if !reflect.DeepEqual(c.clangdConfigDirs, tc.expectedDirs) {
+				t.Errorf("for args=%q, clangd config directories:\nwant: %q\n got: %q\n",
+					tc.args, tc.expectedDirs, c.clangdConfigDirs)
+			}
+			if !reflect.DeepEqual(c.arguments, tc.remaining) {
+				t.Errorf("for args=%q, remaining arguments:\nwant: %q\n got: %q\n",
+					tc.args, tc.remaining, c.arguments)
+			}
+		})
+	}
+}
+
 func TestConfigCheckTopDir(t *testing.T) {
 	ctx := testContext()
 	buildRootDir := filepath.Dir(srcDirFileCheck)
@@ -0,0 +1,183 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"android/soong/ui/build/paths"
+)
+
+// The policy of the build host requirements, checked by checkHostTools before the build starts so
+// that unsupported build hosts fail early with actionable errors instead of obscure failures in
+// the middle of the build. The checks run with the PATH of the build, so they can only run the
+// tools that the path interposer allows.
+const hostToolsPolicyFile = "build/soong/ui/build/host_tools_policy.json"
+
+// hostToolsPolicy is the content of the host tools policy file.
+type hostToolsPolicy struct {
+	// The checks of the host tools and libraries.
+	Tools []hostToolCheck
+}
+
+// hostToolCheck checks the output of a command run on the build host.
+type hostToolCheck struct {
+	// The name of the checked tool or library, used in the error messages.
+	Name string
+
+	// The operating system that the check applies to, e.g. "linux". Applies to all of them if empty.
+	Os string
+
+	// The command to run, and its arguments.
+	Command []string
+
+	// A regular expression that the combined output of the command must match.
+	Output string
+
+	// The minimum version of the tool. If set, the first subexpression of Output is the version of
+	// the tool, a dot separated list of numbers.
+	Min_version string
+
+	// What to do to fix the build host if the check fails.
+	Help string
+}
+
+// loadHostToolsPolicy reads and parses the policy file.
+func loadHostToolsPolicy(file string) (*hostToolsPolicy, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return &hostToolsPolicy{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	policy := &hostToolsPolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", file, err)
+	}
+	for _, check := range policy.Tools {
+		if len(check.Command) == 0 {
+			return nil, fmt.Errorf("%s: the check of %q has no command", file, check.Name)
+		}
+		if _, err := regexp.Compile(check.Output); err != nil {
+			return nil, fmt.Errorf("%s: invalid output of the check of %q: %s", file, check.Name, err)
+		}
+		if !allowedInPath(check.Command[0]) {
+			return nil, fmt.Errorf("%s: the check of %q runs %q, which is not allowed in the PATH of the build",
+				file, check.Name, check.Command[0])
+		}
+	}
+	return policy, nil
+}
+
+// allowedInPath returns whether the build can run the tool, i.e. whether the path interposer
+// allows it or it is one of the prebuilt tools that replace the host tools on Linux.
+func allowedInPath(name string) bool {
+	config := paths.GetConfig(name)
+	return !config.Error || config.LinuxOnlyPrebuilt
+}
+
+// verify returns an error if the output of the command does not satisfy the check.
+func (check hostToolCheck) verify(output string) error {
+	output = strings.TrimSpace(output)
+	matches := regexp.MustCompile(check.Output).FindStringSubmatch(output)
+	if matches == nil {
+		return fmt.Errorf("unexpected output of %q: %q", strings.Join(check.Command, " "), output)
+	}
+	if check.Min_version == "" {
+		return nil
+	}
+	if len(matches) < 2 {
+		return fmt.Errorf("the output pattern %q does not capture the version", check.Output)
+	}
+	if compareVersions(matches[1], check.Min_version) < 0 {
+		return fmt.Errorf("version %s is older than the required version %s", matches[1], check.Min_version)
+	}
+	return nil
+}
+
+// compareVersions compares two dot separated lists of numbers, and returns -1, 0 or 1 if a is older,
+// equal or newer than b. Components that are not numbers compare as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+	return 0
+}
+
+// checkHostTools verifies the build host against the host tools policy, and aborts the build with
+// the list of the failed checks unless ALLOW_UNSUPPORTED_BUILD_HOST=true. It must run after
+// SetupPath, so that it checks the tools that the build uses rather than the ones in the PATH of
+// the user.
+func checkHostTools(ctx Context, config Config) {
+	policy, err := loadHostToolsPolicy(hostToolsPolicyFile)
+	if err != nil {
+		ctx.Fatalln("Failed to load the host tools policy:", err)
+	}
+
+	var failures []string
+	for _, check := range policy.Tools {
+		if check.Os != "" && check.Os != runtime.GOOS {
+			continue
+		}
+		cmd := Command(ctx, config, "host tools check", check.Command[0], check.Command[1:]...)
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			err = check.verify(string(output))
+		} else {
+			err = fmt.Errorf("failed to run %q: %s", strings.Join(check.Command, " "), err)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", check.Name, err))
+			if check.Help != "" {
+				failures = append(failures, "  "+check.Help)
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return
+	}
+	ctx.Println("************************************************************")
+	ctx.Println("Your build host does not meet the requirements of the build:")
+	for _, failure := range failures {
+		ctx.Println(failure)
+	}
+	ctx.Println("************************************************************")
+	if config.Environment().IsEnvTrue("ALLOW_UNSUPPORTED_BUILD_HOST") {
+		ctx.Println("Continuing because ALLOW_UNSUPPORTED_BUILD_HOST=true, the build may fail.")
+		return
+	}
+	ctx.Fatalln("Unsupported build host, set ALLOW_UNSUPPORTED_BUILD_HOST=true to build anyway")
+}
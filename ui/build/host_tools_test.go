@@ -0,0 +1,134 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.17", "2.17", 0},
+		{"2.17", "2.17.0", 0},
+		{"2.9", "2.17", -1},
+		{"2.31", "2.17", 1},
+		{"3", "2.99", 1},
+	}
+	for _, tc := range testCases {
+		if got := compareVersions(tc.a, tc.b); got != tc.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
+
+func TestHostToolCheckVerify(t *testing.T) {
+	glibc := hostToolCheck{
+		Name:        "glibc",
+		Command:     []string{"getconf", "GNU_LIBC_VERSION"},
+		Output:      "^glibc ([0-9.]+)$",
+		Min_version: "2.17",
+	}
+	noVersion := glibc
+	noVersion.Output = "^glibc [0-9.]+$"
+
+	testCases := []struct {
+		name     string
+		check    hostToolCheck
+		output   string
+		expected string
+	}{
+		{
+			name:   "newer version",
+			check:  glibc,
+			output: "glibc 2.35\n",
+		},
+		{
+			name:     "older version",
+			check:    glibc,
+			output:   "glibc 2.12\n",
+			expected: "version 2.12 is older than the required version 2.17",
+		},
+		{
+			name:     "unexpected output",
+			check:    glibc,
+			output:   "musl 1.2\n",
+			expected: `unexpected output of "getconf GNU_LIBC_VERSION": "musl 1.2"`,
+		},
+		{
+			name:     "version not captured",
+			check:    noVersion,
+			output:   "glibc 2.35\n",
+			expected: `the output pattern "^glibc [0-9.]+$" does not capture the version`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.check.verify(tc.output)
+			if tc.expected == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			} else if err == nil || err.Error() != tc.expected {
+				t.Errorf("expected error %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadHostToolsPolicy(t *testing.T) {
+	// The checked-in policy must be valid.
+	policy, err := loadHostToolsPolicy("host_tools_policy.json")
+	if err != nil {
+		t.Fatalf("failed to load the checked-in policy: %s", err)
+	}
+	if len(policy.Tools) == 0 {
+		t.Errorf("unexpected checked-in policy: %+v", policy)
+	}
+
+	dir, err := ioutil.TempDir("", "host_tools_policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// There are no checks without a policy file.
+	policy, err = loadHostToolsPolicy(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(policy.Tools) != 0 {
+		t.Errorf("unexpected default policy: %+v", policy)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte(`{"Tools": [{"Name": "foo", "Command": ["foo"], "Output": "("}]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHostToolsPolicy(invalid); err == nil {
+		t.Errorf("expected an error for an invalid output pattern")
+	}
+	if err := ioutil.WriteFile(invalid, []byte(`{"Tools": [{"Name": "foo", "Command": ["gcc", "--version"], "Output": "."}]}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadHostToolsPolicy(invalid); err == nil {
+		t.Errorf("expected an error for a tool that is not allowed in the PATH")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// The module_outputs.json file written by soong_build, with the output file tags supported by the
+// modules and the paths that they refer to.
+const moduleOutputsFileName = "module_outputs.json"
+
+// The goal that prints the output files of modules. soong_build only writes module_outputs.json
+// when SOONG_MODULE_OUTPUTS=true, which is set for it when the goal is requested.
+const moduleOutputsGoal = "module-outputs"
+
+// moduleOutputs matches the android.ModuleOutputs entries of module_outputs.json.
+type moduleOutputs struct {
+	Variant string
+	Tags    []moduleOutputTag
+}
+
+// moduleOutputTag matches the android.ModuleOutputTag entries of module_outputs.json.
+type moduleOutputTag struct {
+	Tag   string
+	Paths []string
+	Error string
+}
+
+// printModuleOutputs implements `m module-outputs <module>...`: it prints the ":module{.tag}" references
+// that can be used in the path properties of other modules for each of the given modules, and the
+// paths that they refer to.
+func printModuleOutputs(ctx Context, config Config) {
+	var names []string
+	for _, arg := range config.Arguments() {
+		if arg != moduleOutputsGoal {
+			names = append(names, arg)
+		}
+	}
+	if len(names) == 0 {
+		ctx.Fatalln("usage: m module-outputs <module>...")
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(config.SoongOutDir(), moduleOutputsFileName))
+	if err != nil {
+		ctx.Fatalf("failed to read the outputs of the modules: %s", err)
+	}
+	var outputs map[string][]moduleOutputs
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		ctx.Fatalf("failed to parse %s: %s", moduleOutputsFileName, err)
+	}
+
+	lines, err := formatModuleOutputs(outputs, names)
+	if err != nil {
+		ctx.Fatalln(err)
+	}
+	for _, line := range lines {
+		ctx.Println(line)
+	}
+}
+
+// formatModuleOutputs returns the lines printed by `m module-outputs` for the given modules.
+func formatModuleOutputs(outputs map[string][]moduleOutputs, names []string) ([]string, error) {
+	var lines []string
+	for _, name := range names {
+		variants, ok := outputs[name]
+		if !ok {
+			return nil, fmt.Errorf("module %q does not exist or does not have output files", name)
+		}
+		for _, variant := range variants {
+			if variant.Variant == "" {
+				lines = append(lines, name+":")
+			} else {
+				lines = append(lines, fmt.Sprintf("%s (%s):", name, variant.Variant))
+			}
+			for _, tag := range variant.Tags {
+				ref := ":" + name
+				if tag.Tag != "" {
+					ref += "{" + tag.Tag + "}"
+				}
+				switch {
+				case tag.Error != "":
+					lines = append(lines, fmt.Sprintf("  %s: error: %s", ref, tag.Error))
+				case len(tag.Paths) == 0:
+					lines = append(lines, fmt.Sprintf("  %s: no output files", ref))
+				default:
+					lines = append(lines, fmt.Sprintf("  %s: %s", ref, strings.Join(tag.Paths, " ")))
+				}
+			}
+		}
+	}
+	return lines, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"testing"
+)
+
+func TestFormatModuleOutputs(t *testing.T) {
+	outputs := map[string][]moduleOutputs{
+		"gen": {
+			{
+				Tags: []moduleOutputTag{
+					{Tag: "", Paths: []string{"out/soong/.intermediates/gen/gen/a.h", "out/soong/.intermediates/gen/gen/b.h"}},
+					{Tag: "a.h", Paths: []string{"out/soong/.intermediates/gen/gen/a.h"}},
+				},
+			},
+		},
+		"libfoo": {
+			{
+				Variant: "android_arm64_armv8-a_shared",
+				Tags: []moduleOutputTag{
+					{Tag: "", Paths: []string{"out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/libfoo.so"}},
+					{Tag: "unstripped"},
+					{Tag: ".broken", Error: "unsupported module reference tag \".broken\""},
+				},
+			},
+		},
+	}
+
+	lines, err := formatModuleOutputs(outputs, []string{"gen", "libfoo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertDeepEqual(t, []string{
+		"gen:",
+		"  :gen: out/soong/.intermediates/gen/gen/a.h out/soong/.intermediates/gen/gen/b.h",
+		"  :gen{a.h}: out/soong/.intermediates/gen/gen/a.h",
+		"libfoo (android_arm64_armv8-a_shared):",
+		"  :libfoo: out/soong/.intermediates/libfoo/android_arm64_armv8-a_shared/libfoo.so",
+		"  :libfoo{unstripped}: no output files",
+		"  :libfoo{.broken}: error: unsupported module reference tag \".broken\"",
+	}, lines)
+
+	if _, err := formatModuleOutputs(outputs, []string{"missing"}); err == nil {
+		t.Errorf("expected an error for a missing module")
+	}
+}
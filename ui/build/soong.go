@@ -288,6 +288,12 @@ func bootstrapBlueprint(ctx Context, config Config) {
 	if config.buildFromTextStub {
 		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--build-from-text-stub")
 	}
+	if profileMutators := os.Getenv("SOONG_PROFILE_MUTATORS"); profileMutators != "" {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--mutator_profile", profileMutators)
+	}
+	if profileMutatorsTop := os.Getenv("SOONG_PROFILE_MUTATORS_TOP"); profileMutatorsTop != "" {
+		mainSoongBuildExtraArgs = append(mainSoongBuildExtraArgs, "--mutator_profile_top", profileMutatorsTop)
+	}
 
 	queryviewDir := filepath.Join(config.SoongOutDir(), "queryview")
 	// The BUILD files will be generated in out/soong/.api_bp2build (no symlinks to src files)
@@ -477,6 +483,12 @@ func runSoong(ctx Context, config Config) {
 	if os.Getenv("ALLOW_MISSING_DEPENDENCIES") == "true" {
 		soongBuildEnv.Set("ALLOW_MISSING_DEPENDENCIES", "true")
 	}
+	if inList(moduleOutputsGoal, config.Arguments()) {
+		soongBuildEnv.Set("SOONG_MODULE_OUTPUTS", "true")
+	}
+	if dirs := config.ClangdConfigDirs(); len(dirs) > 0 {
+		soongBuildEnv.Set("SOONG_GEN_CLANGD_CONFIG", strings.Join(dirs, " "))
+	}
 
 	err := writeEnvironmentFile(ctx, envFile, soongBuildEnv.AsMap())
 	if err != nil {